@@ -20,8 +20,11 @@ import (
 	"flag"
 	"net/http"
 	"os"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -31,6 +34,7 @@ import (
 	kpackbuildv1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/kpack/build/v1alpha1"
 	"github.com/projectriff/system/pkg/controllers"
 	buildcontrollers "github.com/projectriff/system/pkg/controllers/build"
+	"github.com/projectriff/system/pkg/validation"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -55,6 +59,9 @@ func main() {
 	flag.StringVar(&probesAddr, "probes-addr", ":8081", "The address health probes bind to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	var imagePolicy string
+	flag.StringVar(&imagePolicy, "image-policy", "riff-image-policy",
+		"The name of the ConfigMap in the system namespace that restricts the images workloads may run. All images are allowed when the ConfigMap does not exist.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.Logger(true))
@@ -71,13 +78,35 @@ func main() {
 		os.Exit(1)
 	}
 
+	coreClient := kubernetes.NewForConfigOrDie(mgr.GetConfig()).CoreV1()
+
+	imagePolicyLoader := &controllers.ConfigMapLoader{
+		Client: coreClient,
+		Log:    ctrl.Log.WithName("webhooks").WithName("ImagePolicy"),
+		Key:    types.NamespacedName{Namespace: namespace, Name: imagePolicy},
+		Load:   validation.LoadImagePolicy,
+	}
+	if err = imagePolicyLoader.Sync(); err != nil {
+		setupLog.Error(err, "unable to load image policy")
+		os.Exit(1)
+	}
+	if err = mgr.Add(imagePolicyLoader); err != nil {
+		setupLog.Error(err, "unable to create image policy loader")
+		os.Exit(1)
+	}
+
+	podLogs := buildcontrollers.NewPodLogReader(coreClient)
+
 	if err = buildcontrollers.ApplicationReconciler(
 		controllers.Config{
-			Client:   mgr.GetClient(),
-			Recorder: mgr.GetEventRecorderFor("Application"),
-			Log:      ctrl.Log.WithName("controllers").WithName("Application"),
-			Scheme:   mgr.GetScheme(),
+			Client:    mgr.GetClient(),
+			APIReader: mgr.GetAPIReader(),
+			Recorder:  mgr.GetEventRecorderFor("Application"),
+			Log:       ctrl.Log.WithName("controllers").WithName("Application"),
+			Scheme:    mgr.GetScheme(),
 		},
+		podLogs,
+		buildcontrollers.NewGitRevisionResolver(&http.Client{Timeout: 30 * time.Second}),
 	).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Application")
 		os.Exit(1)
@@ -86,12 +115,14 @@ func main() {
 		setupLog.Error(err, "unable to create webhook", "webhook", "Application")
 		os.Exit(1)
 	}
-	if err = (&buildcontrollers.ContainerReconciler{
-		Client:   mgr.GetClient(),
-		Recorder: mgr.GetEventRecorderFor("Container"),
-		Log:      ctrl.Log.WithName("controllers").WithName("Container"),
-		Scheme:   mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
+	if err = buildcontrollers.ContainerReconciler(
+		controllers.Config{
+			Client:   mgr.GetClient(),
+			Recorder: mgr.GetEventRecorderFor("Container"),
+			Log:      ctrl.Log.WithName("controllers").WithName("Container"),
+			Scheme:   mgr.GetScheme(),
+		},
+	).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Container")
 		os.Exit(1)
 	}
@@ -101,11 +132,13 @@ func main() {
 	}
 	if err = buildcontrollers.FunctionReconciler(
 		controllers.Config{
-			Client:   mgr.GetClient(),
-			Recorder: mgr.GetEventRecorderFor("Function"),
-			Log:      ctrl.Log.WithName("controllers").WithName("Function"),
-			Scheme:   mgr.GetScheme(),
+			Client:    mgr.GetClient(),
+			APIReader: mgr.GetAPIReader(),
+			Recorder:  mgr.GetEventRecorderFor("Function"),
+			Log:       ctrl.Log.WithName("controllers").WithName("Function"),
+			Scheme:    mgr.GetScheme(),
 		},
+		podLogs,
 	).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Function")
 		os.Exit(1)
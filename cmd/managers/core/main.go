@@ -23,6 +23,8 @@ import (
 	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -30,9 +32,12 @@ import (
 
 	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	certmanagerv1alpha2 "github.com/projectriff/system/pkg/apis/thirdparty/certmanager/v1alpha2"
+	gatewayv1beta1 "github.com/projectriff/system/pkg/apis/thirdparty/gateway/v1beta1"
 	"github.com/projectriff/system/pkg/controllers"
 	corecontrollers "github.com/projectriff/system/pkg/controllers/core"
 	"github.com/projectriff/system/pkg/tracker"
+	"github.com/projectriff/system/pkg/validation"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -47,6 +52,8 @@ func init() {
 
 	_ = corev1alpha1.AddToScheme(scheme)
 	_ = buildv1alpha1.AddToScheme(scheme)
+	_ = certmanagerv1alpha2.AddToScheme(scheme)
+	_ = gatewayv1beta1.AddToScheme(scheme)
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -54,11 +61,30 @@ func main() {
 	var metricsAddr string
 	var probesAddr string
 	var enableLeaderElection bool
+	var rolloutMetricsURL string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probesAddr, "probes-addr", ":8081", "The address health probes bind to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&rolloutMetricsURL, "rollout-metrics-url", "",
+		"The Prometheus endpoint queried for the error rate of rollout candidates. Error rate checks are skipped when empty.")
+	var networkPolicy controllers.NetworkPolicyConfig
+	var namespaceSelectors, ingressNamespaceSelectors controllers.LabelSelectorsFlag
+	flag.BoolVar(&networkPolicy.Enabled, "network-policy", false,
+		"Restrict network traffic to deployers with NetworkPolicies according to their ingress policy. Requires a network plugin that enforces NetworkPolicies.")
+	flag.Var(&namespaceSelectors, "network-policy-namespace-selector",
+		"A label selector for namespaces admitted to all deployers, in addition to the deployer's namespace. May be repeated.")
+	flag.Var(&ingressNamespaceSelectors, "network-policy-ingress-namespace-selector",
+		"A label selector for the namespaces of ingress controllers admitted to External deployers. May be repeated.")
+	var podSpecAllowlist string
+	flag.StringVar(&podSpecAllowlist, "podspec-allowlist", "riff-core-podspec-allowlist",
+		"The name of the ConfigMap in the riff-system namespace that tightens or loosens the PodSpec fields allowed in deployer templates.")
+	var imagePolicy string
+	flag.StringVar(&imagePolicy, "image-policy", "riff-image-policy",
+		"The name of the ConfigMap in the riff-system namespace that restricts the images workloads may run. All images are allowed when the ConfigMap does not exist.")
 	flag.Parse()
+	networkPolicy.NamespaceSelectors = namespaceSelectors
+	networkPolicy.IngressNamespaceSelectors = ingressNamespaceSelectors
 
 	ctrl.SetLogger(zap.Logger(true))
 
@@ -75,6 +101,54 @@ func main() {
 		os.Exit(1)
 	}
 
+	coreClient := kubernetes.NewForConfigOrDie(mgr.GetConfig()).CoreV1()
+
+	imagePolicyLoader := &controllers.ConfigMapLoader{
+		Client: coreClient,
+		Log:    ctrl.Log.WithName("webhooks").WithName("ImagePolicy"),
+		Key:    types.NamespacedName{Namespace: "riff-system", Name: imagePolicy},
+		Load:   validation.LoadImagePolicy,
+	}
+	if err = imagePolicyLoader.Sync(); err != nil {
+		setupLog.Error(err, "unable to load image policy")
+		os.Exit(1)
+	}
+	if err = mgr.Add(imagePolicyLoader); err != nil {
+		setupLog.Error(err, "unable to create image policy loader")
+		os.Exit(1)
+	}
+
+	podSpecAllowlistLoader := &controllers.ConfigMapLoader{
+		Client: coreClient,
+		Log:    ctrl.Log.WithName("webhooks").WithName("PodSpecAllowlist"),
+		Key:    types.NamespacedName{Namespace: "riff-system", Name: podSpecAllowlist},
+		Load:   validation.LoadPodSpecAllowlist,
+	}
+	if err = podSpecAllowlistLoader.Sync(); err != nil {
+		setupLog.Error(err, "unable to load PodSpec allowlist")
+		os.Exit(1)
+	}
+	if err = mgr.Add(podSpecAllowlistLoader); err != nil {
+		setupLog.Error(err, "unable to create PodSpec allowlist loader")
+		os.Exit(1)
+	}
+
+	var rolloutMetrics corecontrollers.RolloutMetrics
+	if rolloutMetricsURL != "" {
+		rolloutMetrics = corecontrollers.NewPrometheusRolloutMetrics(rolloutMetricsURL)
+	}
+	certificateGVK := certmanagerv1alpha2.GroupVersion.WithKind("Certificate")
+	_, err = mgr.GetRESTMapper().RESTMapping(certificateGVK.GroupKind(), certificateGVK.Version)
+	certManager := err == nil
+	if !certManager {
+		setupLog.Info("cert-manager is not installed, certificates will not be issued for deployers")
+	}
+	httpRouteGVK := gatewayv1beta1.GroupVersion.WithKind("HTTPRoute")
+	_, err = mgr.GetRESTMapper().RESTMapping(httpRouteGVK.GroupKind(), httpRouteGVK.Version)
+	gatewayAPI := err == nil
+	if !gatewayAPI {
+		setupLog.Info("the Gateway API is not installed, deployers will be routed by ingress")
+	}
 	if err = corecontrollers.DeployerReconciler(
 		controllers.Config{
 			Client:   mgr.GetClient(),
@@ -83,6 +157,12 @@ func main() {
 			Scheme:   mgr.GetScheme(),
 			Tracker:  tracker.New(syncPeriod, ctrl.Log.WithName("controllers").WithName("Deployer").WithName("tracker")),
 		},
+		rolloutMetrics,
+		corecontrollers.OptionalAPIs{
+			CertManager: certManager,
+			GatewayAPI:  gatewayAPI,
+		},
+		networkPolicy,
 	).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Deployer")
 		os.Exit(1)
@@ -91,6 +171,10 @@ func main() {
 		setupLog.Error(err, "unable to create webhook", "webhook", "Deployer")
 		os.Exit(1)
 	}
+	if err = (&corecontrollers.DeployerRouteValidator{Client: mgr.GetClient()}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "DeployerRoutes")
+		os.Exit(1)
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("default", func(_ *http.Request) error { return nil }); err != nil {
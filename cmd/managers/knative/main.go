@@ -20,9 +20,13 @@ import (
 	"flag"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -31,9 +35,11 @@ import (
 	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	knativev1alpha1 "github.com/projectriff/system/pkg/apis/knative/v1alpha1"
 	servingv1 "github.com/projectriff/system/pkg/apis/thirdparty/knative/serving/v1"
+	servingv1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/knative/serving/v1alpha1"
 	"github.com/projectriff/system/pkg/controllers"
 	knativecontrollers "github.com/projectriff/system/pkg/controllers/knative"
 	"github.com/projectriff/system/pkg/tracker"
+	"github.com/projectriff/system/pkg/validation"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -49,6 +55,7 @@ func init() {
 	_ = knativev1alpha1.AddToScheme(scheme)
 	_ = buildv1alpha1.AddToScheme(scheme)
 	_ = servingv1.AddToScheme(scheme)
+	_ = servingv1alpha1.AddToScheme(scheme)
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -60,7 +67,23 @@ func main() {
 	flag.StringVar(&probesAddr, "probes-addr", ":8081", "The address health probes bind to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	var networkPolicy controllers.NetworkPolicyConfig
+	var namespaceSelectors, ingressNamespaceSelectors controllers.LabelSelectorsFlag
+	flag.BoolVar(&networkPolicy.Enabled, "network-policy", false,
+		"Restrict network traffic to deployers with NetworkPolicies according to their ingress policy. Requires a network plugin that enforces NetworkPolicies.")
+	flag.Var(&namespaceSelectors, "network-policy-namespace-selector",
+		"A label selector for namespaces admitted to all deployers, in addition to the deployer's namespace. May be repeated.")
+	flag.Var(&ingressNamespaceSelectors, "network-policy-ingress-namespace-selector",
+		"A label selector for the namespaces of ingress controllers admitted to External deployers. May be repeated.")
+	var imagePolicy string
+	flag.StringVar(&imagePolicy, "image-policy", "riff-image-policy",
+		"The name of the ConfigMap in the riff-system namespace that restricts the images workloads may run. All images are allowed when the ConfigMap does not exist.")
+	var adapterTargetKinds string
+	flag.StringVar(&adapterTargetKinds, "adapter-target-kinds", "Deployment.apps,StatefulSet.apps,DaemonSet.apps,CronJob.batch,Rollout.argoproj.io",
+		"A comma separated list of workload kinds, as Kind.group, adapters may target in addition to Knative Services and Configurations. The manager must be granted access to get, list, watch and update each kind.")
 	flag.Parse()
+	networkPolicy.NamespaceSelectors = namespaceSelectors
+	networkPolicy.IngressNamespaceSelectors = ingressNamespaceSelectors
 
 	ctrl.SetLogger(zap.Logger(true))
 
@@ -77,6 +100,37 @@ func main() {
 		os.Exit(1)
 	}
 
+	coreClient := kubernetes.NewForConfigOrDie(mgr.GetConfig()).CoreV1()
+
+	imagePolicyLoader := &controllers.ConfigMapLoader{
+		Client: coreClient,
+		Log:    ctrl.Log.WithName("webhooks").WithName("ImagePolicy"),
+		Key:    types.NamespacedName{Namespace: "riff-system", Name: imagePolicy},
+		Load:   validation.LoadImagePolicy,
+	}
+	if err = imagePolicyLoader.Sync(); err != nil {
+		setupLog.Error(err, "unable to load image policy")
+		os.Exit(1)
+	}
+	if err = mgr.Add(imagePolicyLoader); err != nil {
+		setupLog.Error(err, "unable to create image policy loader")
+		os.Exit(1)
+	}
+
+	targetKinds := []knativev1alpha1.AdapterTargetKind{}
+	for _, name := range strings.Split(adapterTargetKinds, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		gk := schema.ParseGroupKind(name)
+		mapping, err := mgr.GetRESTMapper().RESTMapping(gk)
+		if err != nil {
+			setupLog.Info("adapter target kind is not installed, adapters will not target it", "kind", gk.String())
+			continue
+		}
+		targetKinds = append(targetKinds, knativev1alpha1.NewAdapterTargetKind(mapping.GroupVersionKind))
+	}
+	knativev1alpha1.SetAllowedAdapterTargetKinds(targetKinds)
 	if err = knativecontrollers.AdapterReconciler(
 		controllers.Config{
 			Client:   mgr.GetClient(),
@@ -85,6 +139,7 @@ func main() {
 			Scheme:   mgr.GetScheme(),
 			Tracker:  tracker.New(syncPeriod, ctrl.Log.WithName("controllers").WithName("Adapter").WithName("tracker")),
 		},
+		targetKinds,
 	).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Adapter")
 		os.Exit(1)
@@ -93,6 +148,12 @@ func main() {
 		setupLog.Error(err, "unable to create webhook", "webhook", "Adapter")
 		os.Exit(1)
 	}
+	domainMappingGVK := servingv1alpha1.GroupVersion.WithKind("DomainMapping")
+	_, err = mgr.GetRESTMapper().RESTMapping(domainMappingGVK.GroupKind(), domainMappingGVK.Version)
+	domainMapping := err == nil
+	if !domainMapping {
+		setupLog.Info("Knative DomainMappings are not installed, domains will not be mapped to deployers")
+	}
 	if err = knativecontrollers.DeployerReconciler(
 		controllers.Config{
 			Client:   mgr.GetClient(),
@@ -101,6 +162,10 @@ func main() {
 			Scheme:   mgr.GetScheme(),
 			Tracker:  tracker.New(syncPeriod, ctrl.Log.WithName("controllers").WithName("Deployer").WithName("tracker")),
 		},
+		knativecontrollers.OptionalAPIs{
+			DomainMapping: domainMapping,
+		},
+		networkPolicy,
 	).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Deployer")
 		os.Exit(1)
@@ -23,6 +23,8 @@ import (
 	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -35,6 +37,7 @@ import (
 	"github.com/projectriff/system/pkg/controllers"
 	streamingcontrollers "github.com/projectriff/system/pkg/controllers/streaming"
 	"github.com/projectriff/system/pkg/tracker"
+	"github.com/projectriff/system/pkg/validation"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -62,6 +65,12 @@ func main() {
 	flag.StringVar(&probesAddr, "probes-addr", ":8081", "The address health probes bind to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	var podSpecAllowlist string
+	flag.StringVar(&podSpecAllowlist, "podspec-allowlist", "riff-streaming-podspec-allowlist",
+		"The name of the ConfigMap in the system namespace that tightens or loosens the PodSpec fields allowed in processor templates.")
+	var imagePolicy string
+	flag.StringVar(&imagePolicy, "image-policy", "riff-image-policy",
+		"The name of the ConfigMap in the system namespace that restricts the images workloads may run. All images are allowed when the ConfigMap does not exist.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.Logger(true))
@@ -79,6 +88,38 @@ func main() {
 		os.Exit(1)
 	}
 
+	coreClient := kubernetes.NewForConfigOrDie(mgr.GetConfig()).CoreV1()
+
+	imagePolicyLoader := &controllers.ConfigMapLoader{
+		Client: coreClient,
+		Log:    ctrl.Log.WithName("webhooks").WithName("ImagePolicy"),
+		Key:    types.NamespacedName{Namespace: namespace, Name: imagePolicy},
+		Load:   validation.LoadImagePolicy,
+	}
+	if err = imagePolicyLoader.Sync(); err != nil {
+		setupLog.Error(err, "unable to load image policy")
+		os.Exit(1)
+	}
+	if err = mgr.Add(imagePolicyLoader); err != nil {
+		setupLog.Error(err, "unable to create image policy loader")
+		os.Exit(1)
+	}
+
+	podSpecAllowlistLoader := &controllers.ConfigMapLoader{
+		Client: coreClient,
+		Log:    ctrl.Log.WithName("webhooks").WithName("PodSpecAllowlist"),
+		Key:    types.NamespacedName{Namespace: namespace, Name: podSpecAllowlist},
+		Load:   validation.LoadPodSpecAllowlist,
+	}
+	if err = podSpecAllowlistLoader.Sync(); err != nil {
+		setupLog.Error(err, "unable to load PodSpec allowlist")
+		os.Exit(1)
+	}
+	if err = mgr.Add(podSpecAllowlistLoader); err != nil {
+		setupLog.Error(err, "unable to create PodSpec allowlist loader")
+		os.Exit(1)
+	}
+
 	if err = (&streamingcontrollers.KafkaProviderReconciler{
 		Client:    mgr.GetClient(),
 		Recorder:  mgr.GetEventRecorderFor("KafkaProvider"),
@@ -11,4 +11,5 @@ require (
 	k8s.io/client-go v0.16.4
 	k8s.io/code-generator v0.16.4
 	sigs.k8s.io/controller-runtime v0.4.0
+	sigs.k8s.io/yaml v1.1.0
 )
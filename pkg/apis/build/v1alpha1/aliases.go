@@ -39,3 +39,6 @@ const BuildNumber = kpackv1alpha1.BuildNumber
 
 // +k8s:deepcopy-gen=false
 type ImageBuild = kpackv1alpha1.ImageBuild
+
+// +k8s:deepcopy-gen=false
+type BuildpackMetadata = kpackv1alpha1.BuildpackMetadata
@@ -31,4 +31,15 @@ func (s *ApplicationSpec) Default() {
 	if s.Image == "" {
 		s.Image = "_"
 	}
+	if s.Strategy == "" {
+		s.Strategy = BuildStrategyBuildpacks
+	}
+	if s.Strategy == BuildStrategyDockerfile {
+		if s.Dockerfile == nil {
+			s.Dockerfile = &DockerfileBuild{}
+		}
+		if s.Dockerfile.Path == "" {
+			s.Dockerfile.Path = "Dockerfile"
+		}
+	}
 }
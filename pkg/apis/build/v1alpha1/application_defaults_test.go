@@ -32,7 +32,24 @@ func TestApplicationDefault(t *testing.T) {
 		in:   &Application{},
 		want: &Application{
 			Spec: ApplicationSpec{
-				Image: "_",
+				Image:    "_",
+				Strategy: BuildStrategyBuildpacks,
+			},
+		},
+	}, {
+		name: "dockerfile strategy",
+		in: &Application{
+			Spec: ApplicationSpec{
+				Strategy: BuildStrategyDockerfile,
+			},
+		},
+		want: &Application{
+			Spec: ApplicationSpec{
+				Image:    "_",
+				Strategy: BuildStrategyDockerfile,
+				Dockerfile: &DockerfileBuild{
+					Path: "Dockerfile",
+				},
 			},
 		},
 	}}
@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 
 	"github.com/projectriff/system/pkg/apis"
@@ -24,13 +25,15 @@ import (
 )
 
 const (
-	ApplicationConditionReady                              = apis.ConditionReady
-	ApplicationConditionKpackImageReady apis.ConditionType = "KpackImageReady"
-	ApplicationConditionImageResolved   apis.ConditionType = "ImageResolved"
+	ApplicationConditionReady                                   = apis.ConditionReady
+	ApplicationConditionKpackImageReady      apis.ConditionType = "KpackImageReady"
+	ApplicationConditionDockerfileBuildReady apis.ConditionType = "DockerfileBuildReady"
+	ApplicationConditionImageResolved        apis.ConditionType = "ImageResolved"
 )
 
 var applicationCondSet = apis.NewLivingConditionSet(
 	ApplicationConditionKpackImageReady,
+	ApplicationConditionDockerfileBuildReady,
 	ApplicationConditionImageResolved,
 )
 
@@ -60,6 +63,23 @@ func (as *ApplicationStatus) MarkBuildNotUsed() {
 	applicationCondSet.Manage(as).MarkTrue(ApplicationConditionKpackImageReady)
 }
 
+func (as *ApplicationStatus) MarkDockerfileBuildNotUsed() {
+	as.JobRef = nil
+	applicationCondSet.Manage(as).MarkTrue(ApplicationConditionDockerfileBuildReady)
+}
+
+func (as *ApplicationStatus) MarkDockerfileBuildFailed(summary string) {
+	applicationCondSet.Manage(as).MarkFalse(ApplicationConditionDockerfileBuildReady, "BuildFailed", "%s", summary)
+}
+
+func (as *ApplicationStatus) MarkDockerfileBuildRevisionUnresolved(message string) {
+	applicationCondSet.Manage(as).MarkFalse(ApplicationConditionDockerfileBuildReady, "RevisionUnresolved", "%s", message)
+}
+
+func (as *ApplicationStatus) MarkDockerfileBuildDigestMissing(name string) {
+	applicationCondSet.Manage(as).MarkFalse(ApplicationConditionDockerfileBuildReady, "DigestMissing", "build job %q completed without reporting an image digest", name)
+}
+
 func (as *ApplicationStatus) MarkImageDefaultPrefixMissing(message string) {
 	applicationCondSet.Manage(as).MarkFalse(ApplicationConditionImageResolved, "DefaultImagePrefixMissing", message)
 }
@@ -72,6 +92,10 @@ func (as *ApplicationStatus) MarkImageResolved() {
 	applicationCondSet.Manage(as).MarkTrue(ApplicationConditionImageResolved)
 }
 
+func (as *ApplicationStatus) MarkKpackBuildFailed(summary string) {
+	applicationCondSet.Manage(as).MarkFalse(ApplicationConditionKpackImageReady, "BuildFailed", "%s", summary)
+}
+
 func (as *ApplicationStatus) PropagateKpackImageStatus(is *kpackbuildv1alpha1.ImageStatus) {
 	sc := is.GetCondition(apis.ConditionReady)
 	if sc == nil {
@@ -86,3 +110,20 @@ func (as *ApplicationStatus) PropagateKpackImageStatus(is *kpackbuildv1alpha1.Im
 		applicationCondSet.Manage(as).MarkFalse(ApplicationConditionKpackImageReady, sc.Reason, sc.Message)
 	}
 }
+
+func (as *ApplicationStatus) PropagateJobStatus(js *batchv1.JobStatus) {
+	for _, c := range js.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			applicationCondSet.Manage(as).MarkTrue(ApplicationConditionDockerfileBuildReady)
+			return
+		case batchv1.JobFailed:
+			applicationCondSet.Manage(as).MarkFalse(ApplicationConditionDockerfileBuildReady, c.Reason, c.Message)
+			return
+		}
+	}
+	applicationCondSet.Manage(as).MarkUnknown(ApplicationConditionDockerfileBuildReady, "Building", "")
+}
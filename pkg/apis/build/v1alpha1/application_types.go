@@ -22,6 +22,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	apis "github.com/projectriff/system/pkg/apis"
+	"github.com/projectriff/system/pkg/refs"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -52,6 +53,15 @@ type ApplicationSpec struct {
 	// Source location. Required for on cluster builds.
 	Source *Source `json:"source,omitempty"`
 
+	// Strategy used to build images from source, either 'buildpacks' or
+	// 'dockerfile'. Defaults to 'buildpacks'.
+	// +optional
+	Strategy BuildStrategy `json:"strategy,omitempty"`
+
+	// Dockerfile configures builds with the 'dockerfile' strategy.
+	// +optional
+	Dockerfile *DockerfileBuild `json:"dockerfile,omitempty"`
+
 	// +optional
 	// +nullable
 	FailedBuildHistoryLimit *int64 `json:"failedBuildHistoryLimit,omitempty"`
@@ -64,6 +74,41 @@ type ApplicationSpec struct {
 	Build ImageBuild `json:"build,omitempty"`
 }
 
+// BuildStrategy describes how images are built from source.
+type BuildStrategy string
+
+const (
+	// BuildStrategyBuildpacks builds images with Cloud Native Buildpacks via a
+	// kpack Image.
+	BuildStrategyBuildpacks BuildStrategy = "buildpacks"
+	// BuildStrategyDockerfile builds images from a Dockerfile within the
+	// source via a Job running a daemonless builder.
+	BuildStrategyDockerfile BuildStrategy = "dockerfile"
+)
+
+type DockerfileBuild struct {
+	// Path to the Dockerfile, relative to the source. Defaults to
+	// 'Dockerfile'.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Target stage to build within a multi-stage Dockerfile.
+	// +optional
+	Target string `json:"target,omitempty"`
+
+	// BuildArgs are passed to the build as build-time variables.
+	// +optional
+	BuildArgs []DockerfileBuildArg `json:"buildArgs,omitempty"`
+}
+
+type DockerfileBuildArg struct {
+	// Name of the build-time variable.
+	Name string `json:"name"`
+
+	// Value of the build-time variable.
+	Value string `json:"value,omitempty"`
+}
+
 // ApplicationStatus defines the observed state of Application
 type ApplicationStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
@@ -71,6 +116,10 @@ type ApplicationStatus struct {
 
 	apis.Status `json:",inline"`
 	BuildStatus `json:",inline"`
+
+	// JobRef is a reference to the Job running the most recent build with the
+	// dockerfile strategy.
+	JobRef *refs.TypedLocalObjectReference `json:"jobRef,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"strings"
+
 	"k8s.io/apimachinery/pkg/api/equality"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -64,11 +66,45 @@ func (s *ApplicationSpec) Validate() validation.FieldErrors {
 
 	if s.Image == "" {
 		errs = errs.Also(validation.ErrMissingField("image"))
+	} else if !strings.HasPrefix(s.Image, "_") {
+		// images relative to the default image prefix are resolved by the
+		// controller, the resolved image is checked when it is deployed
+		errs = errs.Also(validation.ActiveImagePolicy().ValidateRepository(s.Image, "image"))
 	}
 
 	if s.Source != nil {
 		errs = errs.Also(s.Source.Validate().ViaField("source"))
 	}
 
+	switch s.Strategy {
+	case "", BuildStrategyBuildpacks:
+		if s.Dockerfile != nil {
+			errs = errs.Also(validation.ErrDisallowedFields("dockerfile", "only valid with the dockerfile strategy"))
+		}
+	case BuildStrategyDockerfile:
+		if s.Source == nil {
+			errs = errs.Also(validation.ErrMissingField("source"))
+		} else if s.Source.Registry != nil {
+			errs = errs.Also(validation.ErrDisallowedFields("source.registry", "not supported by the dockerfile strategy"))
+		}
+		if s.Dockerfile != nil {
+			errs = errs.Also(s.Dockerfile.Validate().ViaField("dockerfile"))
+		}
+	default:
+		errs = errs.Also(validation.ErrInvalidValue(s.Strategy, "strategy"))
+	}
+
+	return errs
+}
+
+func (d *DockerfileBuild) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	for i, arg := range d.BuildArgs {
+		if arg.Name == "" {
+			errs = errs.Also(validation.ErrMissingField("name").ViaFieldIndex("buildArgs", i))
+		}
+	}
+
 	return errs
 }
@@ -102,6 +102,77 @@ func TestValidateApplicationSpec(t *testing.T) {
 			Source: &Source{},
 		},
 		expected: validation.ErrMissingField("source"),
+	}, {
+		name: "dockerfile strategy",
+		target: &ApplicationSpec{
+			Image:    "test-image",
+			Strategy: BuildStrategyDockerfile,
+			Source: &Source{
+				Git: &Git{
+					URL:      "https://example.com/repo.git",
+					Revision: "master",
+				},
+			},
+			Dockerfile: &DockerfileBuild{
+				Path: "Dockerfile",
+				BuildArgs: []DockerfileBuildArg{
+					{Name: "VERSION", Value: "1.0.0"},
+				},
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "dockerfile strategy, requires source",
+		target: &ApplicationSpec{
+			Image:    "test-image",
+			Strategy: BuildStrategyDockerfile,
+		},
+		expected: validation.ErrMissingField("source"),
+	}, {
+		name: "dockerfile strategy, registry source",
+		target: &ApplicationSpec{
+			Image:    "test-image",
+			Strategy: BuildStrategyDockerfile,
+			Source: &Source{
+				Registry: &Registry{
+					Image: "registry.example.com/source",
+				},
+			},
+		},
+		expected: validation.ErrDisallowedFields("source.registry", "not supported by the dockerfile strategy"),
+	}, {
+		name: "dockerfile strategy, requires build arg name",
+		target: &ApplicationSpec{
+			Image:    "test-image",
+			Strategy: BuildStrategyDockerfile,
+			Source: &Source{
+				Git: &Git{
+					URL:      "https://example.com/repo.git",
+					Revision: "master",
+				},
+			},
+			Dockerfile: &DockerfileBuild{
+				BuildArgs: []DockerfileBuildArg{
+					{Value: "1.0.0"},
+				},
+			},
+		},
+		expected: validation.ErrMissingField("dockerfile.buildArgs[0].name"),
+	}, {
+		name: "buildpacks strategy, disallows dockerfile",
+		target: &ApplicationSpec{
+			Image:      "test-image",
+			Strategy:   BuildStrategyBuildpacks,
+			Dockerfile: &DockerfileBuild{},
+		},
+		expected: validation.ErrDisallowedFields("dockerfile", "only valid with the dockerfile strategy"),
+	}, {
+		name: "invalid strategy",
+		target: &ApplicationSpec{
+			Image:    "test-image",
+			Strategy: "bogus",
+		},
+		expected: validation.ErrInvalidValue(BuildStrategy("bogus"), "strategy"),
 	}} {
 		t.Run(c.name, func(t *testing.T) {
 			actual := c.target.Validate()
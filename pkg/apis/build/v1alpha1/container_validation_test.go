@@ -75,3 +75,46 @@ func TestValidateContainerSpec(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateContainerSpec_ImagePolicy(t *testing.T) {
+	policy, err := validation.NewImagePolicyFromData(map[string]string{
+		"allowedImages": "registry.example.com/**",
+		"requireDigest": "true",
+	})
+	if err != nil {
+		t.Fatalf("NewImagePolicyFromData() unexpected error: %v", err)
+	}
+	validation.SetImagePolicy(policy)
+	defer validation.SetImagePolicy(nil)
+
+	for _, c := range []struct {
+		name     string
+		target   *ContainerSpec
+		expected validation.FieldErrors
+	}{{
+		name: "allowed repository",
+		target: &ContainerSpec{
+			Image: "registry.example.com/test-image",
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "default image prefix",
+		target: &ContainerSpec{
+			Image: "_/test-image",
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "disallowed repository",
+		target: &ContainerSpec{
+			Image: "test-image",
+		},
+		expected: validation.ErrDisallowedFields("image", `image "test-image" is not from an allowed repository`),
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			actual := c.target.Validate()
+			if diff := cmp.Diff(c.expected, actual); diff != "" {
+				t.Errorf("validateContainerSpec(%s) (-expected, +actual) = %v", c.name, diff)
+			}
+		})
+	}
+}
@@ -72,6 +72,10 @@ func (fs *FunctionStatus) MarkImageResolved() {
 	functionCondSet.Manage(fs).MarkTrue(FunctionConditionImageResolved)
 }
 
+func (fs *FunctionStatus) MarkKpackBuildFailed(summary string) {
+	functionCondSet.Manage(fs).MarkFalse(FunctionConditionKpackImageReady, "BuildFailed", "%s", summary)
+}
+
 func (fs *FunctionStatus) PropagateKpackImageStatus(is *kpackbuildv1alpha1.ImageStatus) {
 	sc := is.GetCondition(apis.ConditionReady)
 	if sc == nil {
@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"strings"
+
 	"k8s.io/apimachinery/pkg/api/equality"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -64,6 +66,10 @@ func (s *FunctionSpec) Validate() validation.FieldErrors {
 
 	if s.Image == "" {
 		errs = errs.Also(validation.ErrMissingField("image"))
+	} else if !strings.HasPrefix(s.Image, "_") {
+		// images relative to the default image prefix are resolved by the
+		// controller, the resolved image is checked when it is deployed
+		errs = errs.Also(validation.ActiveImagePolicy().ValidateRepository(s.Image, "image"))
 	}
 
 	if s.Source != nil {
@@ -0,0 +1,171 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// PromoteAnnotationKey advances the build pin of a resource consuming a
+	// build. The value is either a build number or an image digest.
+	PromoteAnnotationKey = GroupVersion.Group + "/promote"
+)
+
+// BuildPin selects a specific build from a build resource's history, rather
+// than following the latest image. Exactly one field may be set.
+type BuildPin struct {
+	// BuildNumber of a build in the build resource's history.
+	BuildNumber int64 `json:"buildNumber,omitempty"`
+
+	// Digest of an image produced by the build resource, for example
+	// 'sha256:...'.
+	Digest string `json:"digest,omitempty"`
+}
+
+// String describes the pin by its build number or digest
+func (p *BuildPin) String() string {
+	if p.BuildNumber != 0 {
+		return fmt.Sprintf("build number %d", p.BuildNumber)
+	}
+	return fmt.Sprintf("digest %s", p.Digest)
+}
+
+// ResolvedBuildStatus is the status of a resource rolling out the images of a
+// build resource.
+type ResolvedBuildStatus struct {
+	// LatestImage is the most recent image resolved from the build
+	LatestImage string `json:"latestImage,omitempty"`
+
+	// PinnedBuild is the build the pin resolved to.
+	PinnedBuild *BuildPin `json:"pinnedBuild,omitempty"`
+
+	// AvailableImage is the most recent image produced by the build when it
+	// differs from the pinned image being rolled out.
+	AvailableImage string `json:"availableImage,omitempty"`
+}
+
+// PropagateBuildStatus resolves the image to roll out from a build resource,
+// honoring the pin. The current image is retained when the pin does not match
+// a build, in which case false is returned.
+func (rs *ResolvedBuildStatus) PropagateBuildStatus(pin *BuildPin, bs *BuildStatus) bool {
+	image, pinned, available := bs.ResolveImage(pin)
+	rs.PinnedBuild = pinned
+	rs.AvailableImage = available
+	if image == "" {
+		return pin == nil
+	}
+	rs.LatestImage = image
+	return true
+}
+
+// ParseBuildPin parses the value of a promote annotation as a build number
+// or an image digest.
+func ParseBuildPin(value string) (*BuildPin, bool) {
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if n <= 0 {
+			return nil, false
+		}
+		return &BuildPin{BuildNumber: n}, true
+	}
+	if isDigest(value) {
+		return &BuildPin{Digest: value}, true
+	}
+	return nil, false
+}
+
+// PromoteBuild returns the pin named by the resource's promote annotation,
+// removing the annotation once consumed. The current pin is returned when
+// there is nothing to promote. Invalid annotation values are left in place to
+// be rejected by validation.
+func PromoteBuild(annotations map[string]string, pin *BuildPin) *BuildPin {
+	value, ok := annotations[PromoteAnnotationKey]
+	if !ok {
+		return pin
+	}
+	promoted, ok := ParseBuildPin(value)
+	if !ok {
+		return pin
+	}
+	delete(annotations, PromoteAnnotationKey)
+	return promoted
+}
+
+// ResolvePin finds the build matching the pin within the build history. The
+// latest image is also considered for digest pins, as not every build
+// resource records a history. Nil is returned when no build matches.
+func (bs *BuildStatus) ResolvePin(pin *BuildPin) *BuildRecord {
+	if pin == nil {
+		return nil
+	}
+	for i := range bs.Builds {
+		build := &bs.Builds[i]
+		if pin.BuildNumber != 0 && build.BuildNumber == pin.BuildNumber {
+			return build.DeepCopy()
+		}
+		if pin.Digest != "" && imageDigest(build.Image) == pin.Digest {
+			return build.DeepCopy()
+		}
+	}
+	if pin.Digest != "" && imageDigest(bs.LatestImage) == pin.Digest {
+		return &BuildRecord{Image: bs.LatestImage}
+	}
+	return nil
+}
+
+// ResolveImage returns the image to roll out for the pin, the build the pin
+// resolved to, and the latest image when it is available but not rolled out.
+// Without a pin the latest image is rolled out. An empty image is returned
+// when the pin does not match a build.
+func (bs *BuildStatus) ResolveImage(pin *BuildPin) (string, *BuildPin, string) {
+	if pin == nil {
+		return bs.LatestImage, nil, ""
+	}
+	build := bs.ResolvePin(pin)
+	if build == nil {
+		return "", nil, bs.LatestImage
+	}
+	available := ""
+	if build.Image != bs.LatestImage {
+		available = bs.LatestImage
+	}
+	return build.Image, build.ResolvedPin(), available
+}
+
+// ResolvedPin describes the build a pin resolved to, with both the build
+// number (when known) and digest populated.
+func (r *BuildRecord) ResolvedPin() *BuildPin {
+	return &BuildPin{
+		BuildNumber: r.BuildNumber,
+		Digest:      imageDigest(r.Image),
+	}
+}
+
+func imageDigest(image string) string {
+	i := strings.LastIndex(image, "@")
+	if i == -1 {
+		return ""
+	}
+	return image[i+1:]
+}
+
+func isDigest(value string) bool {
+	parts := strings.SplitN(value, ":", 2)
+	return len(parts) == 2 && parts[0] != "" && parts[1] != "" && !strings.ContainsAny(value, "@/")
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuildStatusResolveImage(t *testing.T) {
+	oldImage := "example.com/repo@sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	newImage := "example.com/repo@sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	status := &BuildStatus{
+		LatestImage: newImage,
+		Builds: []BuildRecord{
+			{Name: "build-2", BuildNumber: 2, Image: newImage},
+			{Name: "build-1", BuildNumber: 1, Image: oldImage},
+		},
+	}
+
+	for _, c := range []struct {
+		name              string
+		pin               *BuildPin
+		expectedImage     string
+		expectedPin       *BuildPin
+		expectedAvailable string
+	}{{
+		name:          "unpinned",
+		expectedImage: newImage,
+	}, {
+		name:              "build number",
+		pin:               &BuildPin{BuildNumber: 1},
+		expectedImage:     oldImage,
+		expectedPin:       &BuildPin{BuildNumber: 1, Digest: "sha256:1111111111111111111111111111111111111111111111111111111111111111"},
+		expectedAvailable: newImage,
+	}, {
+		name:          "digest of latest",
+		pin:           &BuildPin{Digest: "sha256:2222222222222222222222222222222222222222222222222222222222222222"},
+		expectedImage: newImage,
+		expectedPin:   &BuildPin{BuildNumber: 2, Digest: "sha256:2222222222222222222222222222222222222222222222222222222222222222"},
+	}, {
+		name:              "not found",
+		pin:               &BuildPin{BuildNumber: 3},
+		expectedAvailable: newImage,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			image, pin, available := status.ResolveImage(c.pin)
+			if diff := cmp.Diff(c.expectedImage, image); diff != "" {
+				t.Errorf("ResolveImage(%s) image (-expected, +actual) = %v", c.name, diff)
+			}
+			if diff := cmp.Diff(c.expectedPin, pin); diff != "" {
+				t.Errorf("ResolveImage(%s) pin (-expected, +actual) = %v", c.name, diff)
+			}
+			if diff := cmp.Diff(c.expectedAvailable, available); diff != "" {
+				t.Errorf("ResolveImage(%s) available (-expected, +actual) = %v", c.name, diff)
+			}
+		})
+	}
+}
+
+func TestResolvedBuildStatusPropagateBuildStatus(t *testing.T) {
+	oldImage := "example.com/repo@sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	newImage := "example.com/repo@sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	status := &BuildStatus{
+		LatestImage: newImage,
+		Builds: []BuildRecord{
+			{Name: "build-2", BuildNumber: 2, Image: newImage},
+			{Name: "build-1", BuildNumber: 1, Image: oldImage},
+		},
+	}
+
+	for _, c := range []struct {
+		name             string
+		pin              *BuildPin
+		expected         ResolvedBuildStatus
+		expectedResolved bool
+	}{{
+		name:             "unpinned",
+		expected:         ResolvedBuildStatus{LatestImage: newImage},
+		expectedResolved: true,
+	}, {
+		name: "pinned",
+		pin:  &BuildPin{BuildNumber: 1},
+		expected: ResolvedBuildStatus{
+			LatestImage:    oldImage,
+			PinnedBuild:    &BuildPin{BuildNumber: 1, Digest: "sha256:1111111111111111111111111111111111111111111111111111111111111111"},
+			AvailableImage: newImage,
+		},
+		expectedResolved: true,
+	}, {
+		name: "pin not found retains image",
+		pin:  &BuildPin{BuildNumber: 3},
+		expected: ResolvedBuildStatus{
+			LatestImage:    oldImage,
+			AvailableImage: newImage,
+		},
+		expectedResolved: false,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			actual := &ResolvedBuildStatus{
+				LatestImage: oldImage,
+				PinnedBuild: &BuildPin{BuildNumber: 1},
+			}
+			resolved := actual.PropagateBuildStatus(c.pin, status)
+			if resolved != c.expectedResolved {
+				t.Errorf("PropagateBuildStatus(%s) = %v, expected %v", c.name, resolved, c.expectedResolved)
+			}
+			if diff := cmp.Diff(&c.expected, actual); diff != "" {
+				t.Errorf("PropagateBuildStatus(%s) (-expected, +actual) = %v", c.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"github.com/projectriff/system/pkg/validation"
+)
+
+func (p *BuildPin) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if p.BuildNumber == 0 && p.Digest == "" {
+		errs = errs.Also(validation.ErrMissingOneOf("buildNumber", "digest"))
+	} else if p.BuildNumber != 0 && p.Digest != "" {
+		errs = errs.Also(validation.ErrMultipleOneOf("buildNumber", "digest"))
+	}
+	if p.BuildNumber < 0 {
+		errs = errs.Also(validation.ErrInvalidValue(p.BuildNumber, "buildNumber"))
+	}
+	if p.Digest != "" && !isDigest(p.Digest) {
+		errs = errs.Also(validation.ErrInvalidValue(p.Digest, "digest"))
+	}
+
+	return errs
+}
+
+// ValidatePromoteAnnotation rejects promote annotations that do not name a
+// build, and promotions of resources that do not consume a build.
+func ValidatePromoteAnnotation(annotations map[string]string, hasBuild bool) validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	value, ok := annotations[PromoteAnnotationKey]
+	if !ok {
+		return errs
+	}
+	field := "annotations[" + PromoteAnnotationKey + "]"
+	if !hasBuild {
+		errs = errs.Also(validation.ErrDisallowedFields(field, "promotion requires a build"))
+	} else if _, ok := ParseBuildPin(value); !ok {
+		errs = errs.Also(validation.ErrInvalidValue(value, field))
+	}
+
+	return errs
+}
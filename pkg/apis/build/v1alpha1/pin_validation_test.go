@@ -0,0 +1,75 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/projectriff/system/pkg/validation"
+)
+
+func TestValidateBuildPin(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		target   *BuildPin
+		expected validation.FieldErrors
+	}{{
+		name:     "empty",
+		target:   &BuildPin{},
+		expected: validation.ErrMissingOneOf("buildNumber", "digest"),
+	}, {
+		name: "valid build number",
+		target: &BuildPin{
+			BuildNumber: 1,
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "valid digest",
+		target: &BuildPin{
+			Digest: "sha256:cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e",
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "multiple",
+		target: &BuildPin{
+			BuildNumber: 1,
+			Digest:      "sha256:cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e",
+		},
+		expected: validation.ErrMultipleOneOf("buildNumber", "digest"),
+	}, {
+		name: "invalid build number",
+		target: &BuildPin{
+			BuildNumber: -1,
+		},
+		expected: validation.ErrInvalidValue(int64(-1), "buildNumber"),
+	}, {
+		name: "invalid digest",
+		target: &BuildPin{
+			Digest: "example.com/repo@sha256:cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e",
+		},
+		expected: validation.ErrInvalidValue("example.com/repo@sha256:cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e", "digest"),
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			actual := c.target.Validate()
+			if diff := cmp.Diff(c.expected, actual); diff != "" {
+				t.Errorf("validateBuildPin(%s) (-expected, +actual) = %v", c.name, diff)
+			}
+		})
+	}
+}
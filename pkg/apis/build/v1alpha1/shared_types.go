@@ -17,8 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
-	"fmt"
-	"strings"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/projectriff/system/pkg/apis"
 	"github.com/projectriff/system/pkg/refs"
@@ -44,6 +43,47 @@ type BuildStatus struct {
 	// TargetImage is the resolved image repository where built images are
 	// pushed.
 	TargetImage string `json:"targetImage,omitempty"`
+
+	// Builds is a bounded history of successful builds, most recent first.
+	Builds []BuildRecord `json:"builds,omitempty"`
+
+	// BuildLogRef is a reference to the ConfigMap holding a redacted excerpt
+	// of the log from the failed step of the most recent build. It is only
+	// set while the most recent build has failed.
+	BuildLogRef *refs.TypedLocalObjectReference `json:"buildLogRef,omitempty"`
+}
+
+// BuildRecord describes a successful build and the source it was produced
+// from.
+type BuildRecord struct {
+	// Name of the kpack Build, or of the Job for the dockerfile strategy. For
+	// a Container, the name of the Container and the build number.
+	Name string `json:"name"`
+
+	// BuildNumber is the sequence number of the build for the kpack Image,
+	// for the Application's Jobs with the dockerfile strategy, or of the
+	// distinct images resolved by a Container.
+	BuildNumber int64 `json:"buildNumber,omitempty"`
+
+	// Reason the build was triggered, for example CONFIG, COMMIT, BUILDPACK
+	// or STACK.
+	Reason string `json:"reason,omitempty"`
+
+	// Source is the resolved source location, including the git revision or
+	// blob URL, that was built.
+	Source *Source `json:"source,omitempty"`
+
+	// Image is the digest qualified image produced by the build.
+	Image string `json:"image"`
+
+	// Buildpacks that participated in the build.
+	Buildpacks []BuildpackMetadata `json:"buildpacks,omitempty"`
+
+	// StartTime is when the build was created.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the build finished.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
 }
 
 // +k8s:deepcopy-gen=false
@@ -51,28 +91,3 @@ type ImageResource interface {
 	apis.Object
 	GetImage() string
 }
-
-// ResolveDefaultImage applies the default image prefix as needed to an image.
-//
-// The default image prefix may apply to either a repository whose value is '_'
-// or a repository with a leading '_/'.
-//
-// For a leading '_/', the underscore is replaced with the default image prefix.
-// For a repository of '_', the default image prefix is combined with the name
-// of the build resource.
-func ResolveDefaultImage(resource ImageResource, defaultImagePrefix string) (string, error) {
-	if defaultImagePrefix == "" {
-		return "", fmt.Errorf("invalid default image prefix %q", defaultImagePrefix)
-	}
-	image := resource.GetImage()
-	if image == "_" {
-		// combine registry prefix and application name
-		image = fmt.Sprintf("%s/%s", defaultImagePrefix, resource.GetName())
-	} else if strings.HasPrefix(image, "_/") {
-		// add the prefix to the specified image name
-		image = strings.Replace(image, "_", defaultImagePrefix, 1)
-	} else {
-		return "", fmt.Errorf("unable to default registry")
-	}
-	return image, nil
-}
@@ -98,6 +98,11 @@ func (in *ApplicationSpec) DeepCopyInto(out *ApplicationSpec) {
 		*out = new(buildv1alpha1.SourceConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Dockerfile != nil {
+		in, out := &in.Dockerfile, &out.Dockerfile
+		*out = new(DockerfileBuild)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.FailedBuildHistoryLimit != nil {
 		in, out := &in.FailedBuildHistoryLimit, &out.FailedBuildHistoryLimit
 		*out = new(int64)
@@ -126,6 +131,10 @@ func (in *ApplicationStatus) DeepCopyInto(out *ApplicationStatus) {
 	*out = *in
 	in.Status.DeepCopyInto(&out.Status)
 	in.BuildStatus.DeepCopyInto(&out.BuildStatus)
+	if in.JobRef != nil {
+		in, out := &in.JobRef, &out.JobRef
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationStatus.
@@ -138,6 +147,54 @@ func (in *ApplicationStatus) DeepCopy() *ApplicationStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildPin) DeepCopyInto(out *BuildPin) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildPin.
+func (in *BuildPin) DeepCopy() *BuildPin {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildPin)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildRecord) DeepCopyInto(out *BuildRecord) {
+	*out = *in
+	if in.Source != nil {
+		in, out := &in.Source, &out.Source
+		*out = new(buildv1alpha1.SourceConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Buildpacks != nil {
+		in, out := &in.Buildpacks, &out.Buildpacks
+		*out = make([]buildv1alpha1.BuildpackMetadata, len(*in))
+		copy(*out, *in)
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildRecord.
+func (in *BuildRecord) DeepCopy() *BuildRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BuildStatus) DeepCopyInto(out *BuildStatus) {
 	*out = *in
@@ -149,6 +206,17 @@ func (in *BuildStatus) DeepCopyInto(out *BuildStatus) {
 		in, out := &in.KpackImageRef, &out.KpackImageRef
 		*out = (*in).DeepCopy()
 	}
+	if in.Builds != nil {
+		in, out := &in.Builds, &out.Builds
+		*out = make([]BuildRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BuildLogRef != nil {
+		in, out := &in.BuildLogRef, &out.BuildLogRef
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildStatus.
@@ -252,6 +320,41 @@ func (in *ContainerStatus) DeepCopy() *ContainerStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DockerfileBuild) DeepCopyInto(out *DockerfileBuild) {
+	*out = *in
+	if in.BuildArgs != nil {
+		in, out := &in.BuildArgs, &out.BuildArgs
+		*out = make([]DockerfileBuildArg, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DockerfileBuild.
+func (in *DockerfileBuild) DeepCopy() *DockerfileBuild {
+	if in == nil {
+		return nil
+	}
+	out := new(DockerfileBuild)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DockerfileBuildArg) DeepCopyInto(out *DockerfileBuildArg) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DockerfileBuildArg.
+func (in *DockerfileBuildArg) DeepCopy() *DockerfileBuildArg {
+	if in == nil {
+		return nil
+	}
+	out := new(DockerfileBuildArg)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Function) DeepCopyInto(out *Function) {
 	*out = *in
@@ -363,3 +466,23 @@ func (in *FunctionStatus) DeepCopy() *FunctionStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResolvedBuildStatus) DeepCopyInto(out *ResolvedBuildStatus) {
+	*out = *in
+	if in.PinnedBuild != nil {
+		in, out := &in.PinnedBuild, &out.PinnedBuild
+		*out = new(BuildPin)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResolvedBuildStatus.
+func (in *ResolvedBuildStatus) DeepCopy() *ResolvedBuildStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ResolvedBuildStatus)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -17,8 +17,14 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 )
 
 // +kubebuilder:webhook:path=/mutate-core-projectriff-io-v1alpha1-deployer,mutating=true,failurePolicy=fail,groups=core.projectriff.io,resources=deployers,verbs=create;update,versions=v1alpha1,name=deployers.core.projectriff.io
@@ -28,6 +34,12 @@ var _ webhook.Defaulter = &Deployer{}
 // Default implements webhook.Defaulter so a webhook will be registered for the type
 func (r *Deployer) Default() {
 	r.Spec.Default()
+	if r.Spec.Build != nil {
+		r.Spec.Build.Pin = buildv1alpha1.PromoteBuild(r.Annotations, r.Spec.Build.Pin)
+	}
+	if r.Spec.Rollout != nil {
+		r.Spec.Rollout.PromotedImage = PromoteRollout(r.Annotations, r.Status.Rollout, r.Spec.Rollout.PromotedImage)
+	}
 }
 
 func (s *DeployerSpec) Default() {
@@ -61,7 +73,77 @@ func (s *DeployerSpec) Default() {
 	if s.Template.Spec.Containers[0].Ports[0].ContainerPort == 0 {
 		s.Template.Spec.Containers[0].Ports[0].ContainerPort = 8080
 	}
+	for i := range s.Ports {
+		if s.Ports[i].Protocol == "" {
+			s.Ports[i].Protocol = PortProtocolHTTP1
+		}
+	}
 	if s.IngressPolicy == "" {
 		s.IngressPolicy = IngressPolicyClusterLocal
 	}
+	s.Scale.Default()
+	if s.Rollout != nil {
+		s.Rollout.Default()
+	}
+	for i := range s.Routes {
+		if s.Routes[i].Path == "" {
+			s.Routes[i].Path = "/"
+		}
+	}
+	if s.TLS != nil && s.TLS.IssuerRef != nil && s.TLS.IssuerRef.Kind == "" {
+		s.TLS.IssuerRef.Kind = "ClusterIssuer"
+	}
+	if s.Availability != nil {
+		s.Availability.Default()
+	}
+}
+
+func (s *Scale) Default() {
+	if s.Max == nil {
+		// autoscaling is disabled
+		return
+	}
+	if s.Min == nil {
+		s.Min = int32Ptr(1)
+	}
+	if s.TargetCPUUtilization == nil && s.TargetMemoryUtilization == nil && len(s.Metrics) == 0 {
+		s.TargetCPUUtilization = int32Ptr(80)
+	}
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func (a *Availability) Default() {
+	if a.MinAvailable == nil && a.MaxUnavailable == nil {
+		maxUnavailable := intstr.FromInt(1)
+		a.MaxUnavailable = &maxUnavailable
+	}
+	if a.ZoneSpread == nil {
+		a.ZoneSpread = &TopologySpread{}
+	}
+	a.ZoneSpread.Default()
+	if a.NodeSpread == nil {
+		a.NodeSpread = &TopologySpread{}
+	}
+	a.NodeSpread.Default()
+}
+
+func (t *TopologySpread) Default() {
+	if t.MaxSkew == nil {
+		t.MaxSkew = int32Ptr(1)
+	}
+	if t.WhenUnsatisfiable == "" {
+		t.WhenUnsatisfiable = corev1.ScheduleAnyway
+	}
+}
+
+func (r *Rollout) Default() {
+	if r.ProgressDeadline == nil {
+		r.ProgressDeadline = &metav1.Duration{Duration: 10 * time.Minute}
+	}
+	if r.BlueGreen != nil && r.BlueGreen.PreviewHeader == "" {
+		r.BlueGreen.PreviewHeader = "X-Riff-Candidate"
+	}
 }
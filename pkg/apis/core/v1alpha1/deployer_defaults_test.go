@@ -18,10 +18,14 @@ package v1alpha1
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 )
 
 func TestDeployerDefault(t *testing.T) {
@@ -53,6 +57,158 @@ func TestDeployerDefault(t *testing.T) {
 				IngressPolicy: IngressPolicyClusterLocal,
 			},
 		},
+	}, {
+		name: "promote build",
+		in: &Deployer{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					buildv1alpha1.PromoteAnnotationKey: "sha256:cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e",
+				},
+			},
+			Spec: DeployerSpec{
+				Build: &Build{
+					FunctionRef: "my-function",
+					Pin: &buildv1alpha1.BuildPin{
+						BuildNumber: 1,
+					},
+				},
+			},
+		},
+		want: &Deployer{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{},
+			},
+			Spec: DeployerSpec{
+				Build: &Build{
+					FunctionRef: "my-function",
+					Pin: &buildv1alpha1.BuildPin{
+						Digest: "sha256:cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e",
+					},
+				},
+				Template: &corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{},
+						Labels:      map[string]string{},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name: "handler",
+								Ports: []corev1.ContainerPort{
+									{Name: "http", Protocol: corev1.ProtocolTCP, ContainerPort: 8080},
+								},
+							},
+						},
+					},
+				},
+				IngressPolicy: IngressPolicyClusterLocal,
+			},
+		},
+	}, {
+		name: "promote rollout candidate",
+		in: &Deployer{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					RolloutPromoteAnnotationKey: "true",
+				},
+			},
+			Spec: DeployerSpec{
+				Rollout: &Rollout{
+					BlueGreen: &BlueGreenRollout{},
+				},
+			},
+			Status: DeployerStatus{
+				Rollout: &RolloutStatus{
+					StableImage:    "example.com/repo:stable",
+					CandidateImage: "example.com/repo:candidate",
+				},
+			},
+		},
+		want: &Deployer{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{},
+			},
+			Spec: DeployerSpec{
+				Template: &corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{},
+						Labels:      map[string]string{},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name: "handler",
+								Ports: []corev1.ContainerPort{
+									{Name: "http", Protocol: corev1.ProtocolTCP, ContainerPort: 8080},
+								},
+							},
+						},
+					},
+				},
+				IngressPolicy: IngressPolicyClusterLocal,
+				Rollout: &Rollout{
+					BlueGreen: &BlueGreenRollout{
+						PreviewHeader: "X-Riff-Candidate",
+					},
+					ProgressDeadline: &metav1.Duration{Duration: 10 * time.Minute},
+					PromotedImage:    "example.com/repo:candidate",
+				},
+			},
+			Status: DeployerStatus{
+				Rollout: &RolloutStatus{
+					StableImage:    "example.com/repo:stable",
+					CandidateImage: "example.com/repo:candidate",
+				},
+			},
+		},
+	}, {
+		name: "promote rollout, without candidate",
+		in: &Deployer{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					RolloutPromoteAnnotationKey: "true",
+				},
+			},
+			Spec: DeployerSpec{
+				Rollout: &Rollout{
+					Canary: &CanaryRollout{
+						Steps: []CanaryStep{{Weight: 50}},
+					},
+				},
+			},
+		},
+		want: &Deployer{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					RolloutPromoteAnnotationKey: "true",
+				},
+			},
+			Spec: DeployerSpec{
+				Template: &corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{},
+						Labels:      map[string]string{},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name: "handler",
+								Ports: []corev1.ContainerPort{
+									{Name: "http", Protocol: corev1.ProtocolTCP, ContainerPort: 8080},
+								},
+							},
+						},
+					},
+				},
+				IngressPolicy: IngressPolicyClusterLocal,
+				Rollout: &Rollout{
+					Canary: &CanaryRollout{
+						Steps: []CanaryStep{{Weight: 50}},
+					},
+					ProgressDeadline: &metav1.Duration{Duration: 10 * time.Minute},
+				},
+			},
+		},
 	}}
 
 	for _, test := range tests {
@@ -177,6 +333,308 @@ func TestDeployerSpecDefault(t *testing.T) {
 			},
 			IngressPolicy: IngressPolicyExternal,
 		},
+	}, {
+		name: "scale, disabled",
+		in: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{},
+			Scale: Scale{
+				Min: int32Ptr(2),
+			},
+		},
+		want: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{},
+					Labels:      map[string]string{},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "handler",
+							Ports: []corev1.ContainerPort{
+								{Name: "http", Protocol: corev1.ProtocolTCP, ContainerPort: 8080},
+							},
+						},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyClusterLocal,
+			Scale: Scale{
+				Min: int32Ptr(2),
+			},
+		},
+	}, {
+		name: "scale, default min and target",
+		in: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{},
+			Scale: Scale{
+				Max: int32Ptr(5),
+			},
+		},
+		want: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{},
+					Labels:      map[string]string{},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "handler",
+							Ports: []corev1.ContainerPort{
+								{Name: "http", Protocol: corev1.ProtocolTCP, ContainerPort: 8080},
+							},
+						},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyClusterLocal,
+			Scale: Scale{
+				Min:                  int32Ptr(1),
+				Max:                  int32Ptr(5),
+				TargetCPUUtilization: int32Ptr(80),
+			},
+		},
+	}, {
+		name: "scale, preserve memory target",
+		in: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{},
+			Scale: Scale{
+				Min:                     int32Ptr(2),
+				Max:                     int32Ptr(5),
+				TargetMemoryUtilization: int32Ptr(70),
+			},
+		},
+		want: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{},
+					Labels:      map[string]string{},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "handler",
+							Ports: []corev1.ContainerPort{
+								{Name: "http", Protocol: corev1.ProtocolTCP, ContainerPort: 8080},
+							},
+						},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyClusterLocal,
+			Scale: Scale{
+				Min:                     int32Ptr(2),
+				Max:                     int32Ptr(5),
+				TargetMemoryUtilization: int32Ptr(70),
+			},
+		},
+	}, {
+		name: "routes, default path",
+		in: &DeployerSpec{
+			Template:      &corev1.PodTemplateSpec{},
+			IngressPolicy: IngressPolicyExternal,
+			Routes: []Route{
+				{Hosts: []string{"api.example.com"}},
+				{Hosts: []string{"api.example.com"}, Path: "/orders"},
+			},
+		},
+		want: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{},
+					Labels:      map[string]string{},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "handler",
+							Ports: []corev1.ContainerPort{
+								{Name: "http", Protocol: corev1.ProtocolTCP, ContainerPort: 8080},
+							},
+						},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyExternal,
+			Routes: []Route{
+				{Hosts: []string{"api.example.com"}, Path: "/"},
+				{Hosts: []string{"api.example.com"}, Path: "/orders"},
+			},
+		},
+	}, {
+		name: "tls, default issuer kind",
+		in: &DeployerSpec{
+			Template:      &corev1.PodTemplateSpec{},
+			IngressPolicy: IngressPolicyExternal,
+			TLS: &DeployerTLS{
+				IssuerRef: &CertificateIssuerRef{Name: "letsencrypt"},
+			},
+		},
+		want: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{},
+					Labels:      map[string]string{},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "handler",
+							Ports: []corev1.ContainerPort{
+								{Name: "http", Protocol: corev1.ProtocolTCP, ContainerPort: 8080},
+							},
+						},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyExternal,
+			TLS: &DeployerTLS{
+				IssuerRef: &CertificateIssuerRef{Name: "letsencrypt", Kind: "ClusterIssuer"},
+			},
+		},
+	}, {
+		name: "ports, default protocol",
+		in: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Ports: []corev1.ContainerPort{
+								{Name: "grpc", ContainerPort: 9090},
+								{Name: "admin", ContainerPort: 8081},
+							},
+						},
+					},
+				},
+			},
+			Ports: []DeployerPort{
+				{Name: "grpc", Protocol: PortProtocolGRPC},
+				{Name: "admin"},
+			},
+		},
+		want: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{},
+					Labels:      map[string]string{},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "handler",
+							Ports: []corev1.ContainerPort{
+								{Name: "grpc", Protocol: corev1.ProtocolTCP, ContainerPort: 9090},
+								{Name: "admin", ContainerPort: 8081},
+							},
+						},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyClusterLocal,
+			Ports: []DeployerPort{
+				{Name: "grpc", Protocol: PortProtocolGRPC},
+				{Name: "admin", Protocol: PortProtocolHTTP1},
+			},
+		},
+	}, {
+		name: "availability, default budget and spread",
+		in: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{},
+					},
+				},
+			},
+			Availability: &Availability{
+				NodeSpread: &TopologySpread{
+					WhenUnsatisfiable: corev1.DoNotSchedule,
+				},
+			},
+		},
+		want: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{},
+					Labels:      map[string]string{},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "handler",
+							Ports: []corev1.ContainerPort{
+								{
+									Name:          "http",
+									ContainerPort: 8080,
+									Protocol:      corev1.ProtocolTCP,
+								},
+							},
+						},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyClusterLocal,
+			Availability: &Availability{
+				MaxUnavailable: intstrPtr(intstr.FromInt(1)),
+				ZoneSpread: &TopologySpread{
+					MaxSkew:           int32Ptr(1),
+					WhenUnsatisfiable: corev1.ScheduleAnyway,
+				},
+				NodeSpread: &TopologySpread{
+					MaxSkew:           int32Ptr(1),
+					WhenUnsatisfiable: corev1.DoNotSchedule,
+				},
+			},
+		},
+	}, {
+		name: "availability, preserve min available",
+		in: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{},
+					},
+				},
+			},
+			Availability: &Availability{
+				MinAvailable: intstrPtr(intstr.FromString("50%")),
+			},
+		},
+		want: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{},
+					Labels:      map[string]string{},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "handler",
+							Ports: []corev1.ContainerPort{
+								{
+									Name:          "http",
+									ContainerPort: 8080,
+									Protocol:      corev1.ProtocolTCP,
+								},
+							},
+						},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyClusterLocal,
+			Availability: &Availability{
+				MinAvailable: intstrPtr(intstr.FromString("50%")),
+				ZoneSpread: &TopologySpread{
+					MaxSkew:           int32Ptr(1),
+					WhenUnsatisfiable: corev1.ScheduleAnyway,
+				},
+				NodeSpread: &TopologySpread{
+					MaxSkew:           int32Ptr(1),
+					WhenUnsatisfiable: corev1.ScheduleAnyway,
+				},
+			},
+		},
 	}}
 
 	for _, test := range tests {
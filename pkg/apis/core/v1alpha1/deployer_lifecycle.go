@@ -17,11 +17,18 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
+
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	apis "github.com/projectriff/system/pkg/apis"
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+	certmanagerv1alpha2 "github.com/projectriff/system/pkg/apis/thirdparty/certmanager/v1alpha2"
+	gatewayv1beta1 "github.com/projectriff/system/pkg/apis/thirdparty/gateway/v1beta1"
 )
 
 const (
@@ -29,6 +36,31 @@ const (
 	DeployerConditionDeploymentReady apis.ConditionType = "DeploymentReady"
 	DeployerConditionServiceReady    apis.ConditionType = "ServiceReady"
 	DeployerConditionIngressReady    apis.ConditionType = "IngressReady"
+
+	// DeployerConditionRolloutReady reports the progress of a rollout. The
+	// condition is informational, a deployer is ready while the stable image
+	// is ready.
+	DeployerConditionRolloutReady apis.ConditionType = "RolloutReady"
+
+	// DeployerConditionCertificateReady reports whether the certificate for
+	// the ingress host is issued. The condition is informational, the ingress
+	// serves plain HTTP until the certificate is ready.
+	DeployerConditionCertificateReady apis.ConditionType = "CertificateReady"
+
+	// DeployerConditionNetworkPolicyReady reports whether network traffic to
+	// the workload is restricted by the ingress policy. The condition is
+	// informational and only present when enforcement is enabled.
+	DeployerConditionNetworkPolicyReady apis.ConditionType = "NetworkPolicyReady"
+
+	// DeployerConditionImageAllowed reports a resolved image that is rejected
+	// by the image policy. The condition is informational and only present
+	// while the image is rejected, the previous image remains rolled out.
+	DeployerConditionImageAllowed apis.ConditionType = "ImageAllowed"
+
+	// DeployerConditionBuildReady reports a build pin that does not match a build
+	// of the build resource. The condition is informational and only present
+	// while the pin does not match, the current image remains rolled out.
+	DeployerConditionBuildReady apis.ConditionType = "BuildReady"
 )
 
 var deployerCondSet = apis.NewLivingConditionSet(
@@ -57,6 +89,29 @@ func (ds *DeployerStatus) InitializeConditions() {
 	deployerCondSet.Manage(ds).InitializeConditions()
 }
 
+// PropagateBuildStatus resolves the image to roll out from a build resource,
+// honoring the pin. The current image is retained when the pin does not match
+// a build.
+func (ds *DeployerStatus) PropagateBuildStatus(pin *buildv1alpha1.BuildPin, bs *buildv1alpha1.BuildStatus) {
+	if !ds.ResolvedBuildStatus.PropagateBuildStatus(pin, bs) {
+		ds.MarkBuildPinNotFound(pin)
+		return
+	}
+	_ = deployerCondSet.Manage(ds).ClearCondition(DeployerConditionBuildReady)
+}
+
+func (ds *DeployerStatus) MarkBuildPinNotFound(pin *buildv1alpha1.BuildPin) {
+	// set directly, marking an informational condition may overwrite the
+	// reason of the ready condition
+	deployerCondSet.Manage(ds).SetCondition(apis.Condition{
+		Type:     DeployerConditionBuildReady,
+		Status:   corev1.ConditionFalse,
+		Severity: apis.ConditionSeverityInfo,
+		Reason:   "PinNotFound",
+		Message:  fmt.Sprintf("The pinned %s does not match a build.", pin),
+	})
+}
+
 func (ds *DeployerStatus) PropagateDeploymentStatus(cds *appsv1.DeploymentStatus) {
 	var available, progressing *appsv1.DeploymentCondition
 	for i := range cds.Conditions {
@@ -85,6 +140,111 @@ func (ds *DeployerStatus) PropagateDeploymentStatus(cds *appsv1.DeploymentStatus
 	}
 }
 
+func (ds *DeployerStatus) PropagateHorizontalPodAutoscalerStatus(hs *autoscalingv2beta2.HorizontalPodAutoscalerStatus) {
+	ds.CurrentReplicas = hs.CurrentReplicas
+	ds.DesiredReplicas = hs.DesiredReplicas
+}
+
+func (ds *DeployerStatus) MarkHorizontalPodAutoscalerNotUsed() {
+	ds.HorizontalPodAutoscalerRef = nil
+	ds.CurrentReplicas = 0
+	ds.DesiredReplicas = 0
+}
+
+func (ds *DeployerStatus) MarkRolloutNotUsed() {
+	ds.Rollout = nil
+	_ = deployerCondSet.Manage(ds).ClearCondition(DeployerConditionRolloutReady)
+}
+
+func (ds *DeployerStatus) MarkRolloutReady() {
+	ds.setRolloutCondition(corev1.ConditionTrue, "", "")
+}
+
+func (ds *DeployerStatus) MarkRolloutProgressing(reason, messageFormat string, messageA ...interface{}) {
+	ds.setRolloutCondition(corev1.ConditionUnknown, reason, fmt.Sprintf(messageFormat, messageA...))
+}
+
+func (ds *DeployerStatus) MarkRolloutFailed(reason, messageFormat string, messageA ...interface{}) {
+	ds.setRolloutCondition(corev1.ConditionFalse, reason, fmt.Sprintf(messageFormat, messageA...))
+}
+
+func (ds *DeployerStatus) setRolloutCondition(status corev1.ConditionStatus, reason, message string) {
+	// set directly, marking an informational condition may overwrite the
+	// reason of the ready condition
+	deployerCondSet.Manage(ds).SetCondition(apis.Condition{
+		Type:     DeployerConditionRolloutReady,
+		Status:   status,
+		Reason:   reason,
+		Message:  message,
+		Severity: apis.ConditionSeverityInfo,
+	})
+}
+
+func (ds *DeployerStatus) MarkCertificateNotUsed() {
+	_ = deployerCondSet.Manage(ds).ClearCondition(DeployerConditionCertificateReady)
+}
+
+func (ds *DeployerStatus) MarkCertificateReady() {
+	ds.setCertificateCondition(corev1.ConditionTrue, "", "")
+}
+
+func (ds *DeployerStatus) MarkCertificateNotSupported() {
+	ds.setCertificateCondition(corev1.ConditionFalse, "CertManagerNotInstalled", "Issuing certificates requires cert-manager to be installed.")
+}
+
+func (ds *DeployerStatus) PropagateCertificateStatus(cs *certmanagerv1alpha2.CertificateStatus) {
+	for _, c := range cs.Conditions {
+		if c.Type != certmanagerv1alpha2.CertificateConditionReady {
+			continue
+		}
+		ds.setCertificateCondition(corev1.ConditionStatus(c.Status), c.Reason, c.Message)
+		return
+	}
+	ds.setCertificateCondition(corev1.ConditionUnknown, "Issuing", "The certificate has not been issued.")
+}
+
+func (ds *DeployerStatus) setCertificateCondition(status corev1.ConditionStatus, reason, message string) {
+	// set directly, marking an informational condition may overwrite the
+	// reason of the ready condition
+	deployerCondSet.Manage(ds).SetCondition(apis.Condition{
+		Type:     DeployerConditionCertificateReady,
+		Status:   status,
+		Reason:   reason,
+		Message:  message,
+		Severity: apis.ConditionSeverityInfo,
+	})
+}
+
+func (ds *DeployerStatus) MarkImageAllowed() {
+	_ = deployerCondSet.Manage(ds).ClearCondition(DeployerConditionImageAllowed)
+}
+
+func (ds *DeployerStatus) MarkImageNotAllowed(message string) {
+	// set directly, marking an informational condition may overwrite the
+	// reason of the ready condition
+	deployerCondSet.Manage(ds).SetCondition(apis.Condition{
+		Type:     DeployerConditionImageAllowed,
+		Status:   corev1.ConditionFalse,
+		Severity: apis.ConditionSeverityInfo,
+		Reason:   "ImageNotAllowed",
+		Message:  message,
+	})
+}
+
+func (ds *DeployerStatus) MarkNetworkPolicyNotUsed() {
+	_ = deployerCondSet.Manage(ds).ClearCondition(DeployerConditionNetworkPolicyReady)
+}
+
+func (ds *DeployerStatus) MarkNetworkPolicyReady() {
+	// set directly, marking an informational condition may overwrite the
+	// reason of the ready condition
+	deployerCondSet.Manage(ds).SetCondition(apis.Condition{
+		Type:     DeployerConditionNetworkPolicyReady,
+		Status:   corev1.ConditionTrue,
+		Severity: apis.ConditionSeverityInfo,
+	})
+}
+
 func (ds *DeployerStatus) PropagateServiceStatus(ss *corev1.ServiceStatus) {
 	// services don't have meaningful status
 	deployerCondSet.Manage(ds).MarkTrue(DeployerConditionServiceReady)
@@ -101,6 +261,47 @@ func (ds *DeployerStatus) PropagateIngressStatus(is *networkingv1beta1.IngressSt
 	deployerCondSet.Manage(ds).MarkTrue(DeployerConditionIngressReady)
 }
 
+// PropagateHTTPRouteStatus updates the DeployerConditionIngressReady condition
+// from the Accepted and ResolvedRefs conditions the gateways report for the
+// route.
+func (ds *DeployerStatus) PropagateHTTPRouteStatus(rs *gatewayv1beta1.HTTPRouteStatus) {
+	if len(rs.Parents) == 0 {
+		deployerCondSet.Manage(ds).MarkUnknown(DeployerConditionIngressReady, "NotAttached", "The route is not attached to a gateway.")
+		return
+	}
+	ready := true
+	for _, parent := range rs.Parents {
+		for _, t := range []gatewayv1beta1.RouteConditionType{gatewayv1beta1.RouteConditionAccepted, gatewayv1beta1.RouteConditionResolvedRefs} {
+			var cond *gatewayv1beta1.Condition
+			for i := range parent.Conditions {
+				if parent.Conditions[i].Type == string(t) {
+					cond = &parent.Conditions[i]
+				}
+			}
+			switch {
+			case cond == nil:
+				deployerCondSet.Manage(ds).MarkUnknown(DeployerConditionIngressReady, string(t), "The gateway has not reported %s for the route.", t)
+				ready = false
+			case cond.Status == metav1.ConditionFalse:
+				deployerCondSet.Manage(ds).MarkFalse(DeployerConditionIngressReady, cond.Reason, cond.Message)
+				return
+			case cond.Status != metav1.ConditionTrue:
+				deployerCondSet.Manage(ds).MarkUnknown(DeployerConditionIngressReady, cond.Reason, cond.Message)
+				ready = false
+			}
+		}
+	}
+	if ready {
+		deployerCondSet.Manage(ds).MarkTrue(DeployerConditionIngressReady)
+	}
+}
+
 func (ds *DeployerStatus) MarkIngressNotRequired() {
 	deployerCondSet.Manage(ds).MarkTrue(DeployerConditionIngressReady)
 }
+
+// MarkRoutesNotSupported reports routes the routing backend is unable to
+// represent.
+func (ds *DeployerStatus) MarkRoutesNotSupported(messageFormat string, messageA ...interface{}) {
+	deployerCondSet.Manage(ds).MarkFalse(DeployerConditionIngressReady, "RoutesNotSupported", messageFormat, messageA...)
+}
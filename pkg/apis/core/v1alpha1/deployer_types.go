@@ -17,11 +17,14 @@ limitations under the License.
 package v1alpha1
 
 import (
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"github.com/projectriff/system/pkg/apis"
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	"github.com/projectriff/system/pkg/refs"
 )
 
@@ -30,6 +33,19 @@ import (
 
 var (
 	DeployerLabelKey = GroupVersion.Group + "/deployer"
+
+	// RolloutTrackLabelKey distinguishes the stable and candidate workloads of
+	// a deployer during a rollout.
+	RolloutTrackLabelKey = GroupVersion.Group + "/rollout-track"
+
+	// RolloutPromoteAnnotationKey promotes the candidate image of a rollout to
+	// receive all traffic. The annotation is removed once applied.
+	RolloutPromoteAnnotationKey = GroupVersion.Group + "/promote"
+)
+
+const (
+	RolloutTrackStable    = "stable"
+	RolloutTrackCandidate = "candidate"
 )
 
 var (
@@ -54,6 +70,113 @@ type DeployerSpec struct {
 	// IngressPolicy defines whether the workload should be reachable from
 	// outside the cluster
 	IngressPolicy IngressPolicy `json:"ingressPolicy,omitempty"`
+
+	// Scale configures horizontal autoscaling of the workload. When set, a
+	// HorizontalPodAutoscaler manages the number of replicas.
+	// +optional
+	Scale Scale `json:"scale,omitempty"`
+
+	// Rollout progressively shifts traffic to new images. Without a rollout,
+	// a new image replaces the current image with a rolling update of the
+	// deployment.
+	// +optional
+	Rollout *Rollout `json:"rollout,omitempty"`
+
+	// Routes expose the workload at custom hosts and path prefixes. Without
+	// routes, the workload is exposed at the root of
+	// <name>.<namespace>.<defaultDomain>. Requires the External ingress
+	// policy.
+	// +optional
+	Routes []Route `json:"routes,omitempty"`
+
+	// TLS serves the ingress over HTTPS. Without TLS, the cluster default
+	// certificate from the riff-core-settings ConfigMap is used, if any.
+	// Requires the External ingress policy.
+	// +optional
+	TLS *DeployerTLS `json:"tls,omitempty"`
+
+	// Ports describe the application protocol of the handler container's
+	// ports. Container ports without an entry serve HTTP/1.1.
+	// +optional
+	Ports []DeployerPort `json:"ports,omitempty"`
+
+	// PublicPort is the name of the handler container port exposed on port 80
+	// of the service and routed by ingress, defaults to the first container
+	// port. Other container ports are exposed by the service on the same
+	// port number.
+	// +optional
+	PublicPort string `json:"publicPort,omitempty"`
+
+	// Availability keeps replicas serving through voluntary disruptions, like
+	// node drains, and spreads them across zones and nodes.
+	// +optional
+	Availability *Availability `json:"availability,omitempty"`
+}
+
+// DeployerPort describes a port of the handler container.
+type DeployerPort struct {
+	// Name of the handler container port.
+	Name string `json:"name"`
+
+	// Protocol is the application protocol served on the port, defaults to
+	// http1.
+	// +optional
+	Protocol PortProtocol `json:"protocol,omitempty"`
+
+	// GRPCHealthCheck probes the readiness of the container with the gRPC
+	// health checking protocol on this port, unless the container defines
+	// its own readiness probe. The image must provide the grpc_health_probe
+	// command. Requires the grpc protocol.
+	// +optional
+	GRPCHealthCheck bool `json:"grpcHealthCheck,omitempty"`
+}
+
+// PortProtocol is the application protocol of a port.
+type PortProtocol string
+
+const (
+	PortProtocolHTTP1 PortProtocol = "http1"
+	PortProtocolH2C   PortProtocol = "h2c"
+	PortProtocolGRPC  PortProtocol = "grpc"
+)
+
+// Availability bounds the replicas that may be evicted at once and how
+// replicas are spread across failure domains. Only one of MinAvailable or
+// MaxUnavailable may be specified, defaults to a MaxUnavailable of 1.
+type Availability struct {
+	// MinAvailable is the number or percentage of replicas that must remain
+	// available during a voluntary disruption.
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+
+	// MaxUnavailable is the number or percentage of replicas that may be
+	// unavailable during a voluntary disruption.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+
+	// ZoneSpread spreads replicas across the zones of the cluster. A topology
+	// spread constraint in the template for the same topology key takes
+	// precedence.
+	// +optional
+	ZoneSpread *TopologySpread `json:"zoneSpread,omitempty"`
+
+	// NodeSpread spreads replicas across the nodes of the cluster. A topology
+	// spread constraint in the template for the same topology key takes
+	// precedence.
+	// +optional
+	NodeSpread *TopologySpread `json:"nodeSpread,omitempty"`
+}
+
+type TopologySpread struct {
+	// MaxSkew is the largest permitted difference in the number of replicas
+	// between two domains, defaults to 1.
+	// +optional
+	MaxSkew *int32 `json:"maxSkew,omitempty"`
+
+	// WhenUnsatisfiable is either DoNotSchedule or ScheduleAnyway, defaults to
+	// ScheduleAnyway.
+	// +optional
+	WhenUnsatisfiable corev1.UnsatisfiableConstraintAction `json:"whenUnsatisfiable,omitempty"`
 }
 
 type Build struct {
@@ -65,6 +188,13 @@ type Build struct {
 
 	// FunctionRef references an application in this namespace.
 	FunctionRef string `json:"functionRef,omitempty"`
+
+	// Pin rolls out a specific build from the referenced resource's history
+	// instead of its latest image. Newer images are reported in status as
+	// available until promoted with the build.projectriff.io/promote
+	// annotation.
+	// +optional
+	Pin *buildv1alpha1.BuildPin `json:"pin,omitempty"`
 }
 
 // IngressPolicy describes whether the container should be exposed via
@@ -78,6 +208,187 @@ const (
 	IngressPolicyExternal     IngressPolicy = "External"
 )
 
+// Route claims a path prefix on one or more hosts. The paths claimed on a host
+// by different deployers may not overlap, "/orders" overlaps "/orders/" and
+// "/orders/archive" but not "/orders-archive".
+type Route struct {
+	// Hosts are the host names the route matches.
+	Hosts []string `json:"hosts"`
+
+	// Path is the path prefix the route matches, defaults to "/".
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Rewrite replaces the matched path prefix before the request is sent to
+	// the workload. The ingress controller applies a single rewrite to all
+	// routes of a deployer, routes must use the same rewrite.
+	// +optional
+	Rewrite string `json:"rewrite,omitempty"`
+}
+
+// DeployerTLS sources the certificate for the ingress host. Only one of
+// SecretRef or IssuerRef may be specified.
+type DeployerTLS struct {
+	// SecretRef is the name of an existing kubernetes.io/tls Secret in this
+	// namespace.
+	// +optional
+	SecretRef string `json:"secretRef,omitempty"`
+
+	// IssuerRef requests a certificate for the ingress host from a
+	// cert-manager issuer. Requires cert-manager to be installed.
+	// +optional
+	IssuerRef *CertificateIssuerRef `json:"issuerRef,omitempty"`
+}
+
+type CertificateIssuerRef struct {
+	// Name of the cert-manager issuer.
+	Name string `json:"name"`
+
+	// Kind of the cert-manager issuer, either Issuer or ClusterIssuer,
+	// defaults to ClusterIssuer.
+	// +optional
+	Kind string `json:"kind,omitempty"`
+}
+
+// Scale bounds the number of replicas and the targets used to scale between
+// the bounds. Without any targets, the workload scales on CPU utilization.
+//
+// Autoscaling uses the autoscaling/v2beta2 HorizontalPodAutoscaler, the newest
+// version of the API supported by the Kubernetes 1.16 client. The v2beta2 and
+// v2 MetricSpec are field for field the same, so adopting autoscaling/v2
+// with a newer client does not change this API.
+type Scale struct {
+	// Min is the lower bound for the number of replicas, defaults to 1.
+	// +optional
+	Min *int32 `json:"min,omitempty"`
+
+	// Max is the upper bound for the number of replicas, required to enable
+	// autoscaling.
+	// +optional
+	Max *int32 `json:"max,omitempty"`
+
+	// TargetCPUUtilization is the target average CPU utilization across the
+	// replicas, as a percentage of the requested CPU.
+	// +optional
+	TargetCPUUtilization *int32 `json:"targetCPUUtilization,omitempty"`
+
+	// TargetMemoryUtilization is the target average memory utilization across
+	// the replicas, as a percentage of the requested memory.
+	// +optional
+	TargetMemoryUtilization *int32 `json:"targetMemoryUtilization,omitempty"`
+
+	// Metrics are additional pod, object or external metric targets.
+	// +optional
+	Metrics []autoscalingv2beta2.MetricSpec `json:"metrics,omitempty"`
+}
+
+// Rollout runs a new image as a candidate alongside the stable image, shifting
+// traffic to the candidate as it proves healthy. Only one of Canary or
+// BlueGreen may be specified.
+type Rollout struct {
+	// Canary shifts a growing share of ingress traffic to the candidate in
+	// steps.
+	// +optional
+	Canary *CanaryRollout `json:"canary,omitempty"`
+
+	// BlueGreen runs the candidate without traffic until it is promoted.
+	// +optional
+	BlueGreen *BlueGreenRollout `json:"blueGreen,omitempty"`
+
+	// ProgressDeadline is how long the candidate may take to become ready
+	// before it is rolled back, defaults to 10 minutes.
+	// +optional
+	ProgressDeadline *metav1.Duration `json:"progressDeadline,omitempty"`
+
+	// MaxErrorRate is the highest percentage of failed ingress requests
+	// served by the candidate before it is rolled back. Canary candidates are
+	// checked while they receive traffic, blue/green candidates are checked
+	// on the requests previewing them. The check is skipped unless the
+	// manager is configured with a metrics endpoint.
+	// +optional
+	MaxErrorRate *int32 `json:"maxErrorRate,omitempty"`
+
+	// PromotedImage is the candidate image approved to receive all traffic,
+	// skipping any remaining steps. Typically set with the
+	// core.projectriff.io/promote annotation.
+	// +optional
+	PromotedImage string `json:"promotedImage,omitempty"`
+}
+
+type CanaryRollout struct {
+	// Steps are applied in order once the candidate is ready. The candidate
+	// is promoted after the last step.
+	Steps []CanaryStep `json:"steps"`
+}
+
+type CanaryStep struct {
+	// Weight is the percentage of ingress traffic sent to the candidate.
+	Weight int32 `json:"weight"`
+
+	// Pause is how long to hold the weight before the next step. Without a
+	// pause, the step is held until the candidate is promoted.
+	// +optional
+	Pause *metav1.Duration `json:"pause,omitempty"`
+}
+
+type BlueGreenRollout struct {
+	// PreviewHeader is the request header that routes ingress requests to the
+	// candidate before it is promoted, defaults to "X-Riff-Candidate". Send
+	// the header with the value "always" to preview the candidate.
+	// +optional
+	PreviewHeader string `json:"previewHeader,omitempty"`
+}
+
+// RolloutStatus reports the progress of a rollout.
+type RolloutStatus struct {
+	// StableImage is the image serving traffic outside of a rollout.
+	StableImage string `json:"stableImage,omitempty"`
+
+	// CandidateImage is the image being rolled out.
+	CandidateImage string `json:"candidateImage,omitempty"`
+
+	// Step is the index of the current canary step.
+	Step int32 `json:"step,omitempty"`
+
+	// Weight is the percentage of ingress traffic sent to the candidate.
+	Weight int32 `json:"weight,omitempty"`
+
+	// FailedImage is the last candidate image that was rolled back. It is not
+	// rolled out again.
+	FailedImage string `json:"failedImage,omitempty"`
+
+	// ErrorRateCheckFailure describes why the error rate of the candidate
+	// could not be checked. It is cleared once a check succeeds.
+	ErrorRateCheckFailure string `json:"errorRateCheckFailure,omitempty"`
+
+	// LastTransitionTime is when the rollout last started, advanced or
+	// finished a step.
+	LastTransitionTime apis.VolatileTime `json:"lastTransitionTime,omitempty"`
+
+	CandidateDeploymentRef *refs.TypedLocalObjectReference `json:"candidateDeploymentRef,omitempty"`
+	CandidateServiceRef    *refs.TypedLocalObjectReference `json:"candidateServiceRef,omitempty"`
+	CandidateIngressRef    *refs.TypedLocalObjectReference `json:"candidateIngressRef,omitempty"`
+}
+
+// PromoteRollout returns the image named by the resource's promote annotation,
+// removing the annotation once consumed. The annotation value is the image to
+// promote, or "true" for the current candidate. The currently promoted image
+// is returned when there is nothing to promote.
+func PromoteRollout(annotations map[string]string, status *RolloutStatus, promoted string) string {
+	value, ok := annotations[RolloutPromoteAnnotationKey]
+	if !ok {
+		return promoted
+	}
+	if value == "true" {
+		if status == nil || status.CandidateImage == "" {
+			return promoted
+		}
+		value = status.CandidateImage
+	}
+	delete(annotations, RolloutPromoteAnnotationKey)
+	return value
+}
+
 // DeployerStatus defines the observed state of Deployer
 type DeployerStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
@@ -85,18 +396,56 @@ type DeployerStatus struct {
 
 	apis.Status `json:",inline"`
 
-	// LatestImage is the most recent image resolved from the build
-	LatestImage string `json:"latestImage,omitempty"`
+	buildv1alpha1.ResolvedBuildStatus `json:",inline"`
 
 	DeploymentRef *refs.TypedLocalObjectReference `json:"deploymentRef,omitempty"`
 	ServiceRef    *refs.TypedLocalObjectReference `json:"serviceRef,omitempty"`
 	IngressRef    *refs.TypedLocalObjectReference `json:"ingressRef,omitempty"`
 
+	// HTTPRouteRef is a reference to the Gateway API route exposing the
+	// workload, when the gateway routing backend is configured.
+	HTTPRouteRef *refs.TypedLocalObjectReference `json:"httpRouteRef,omitempty"`
+
+	// CertificateRef is a reference to the cert-manager Certificate issuing
+	// the certificate for the ingress host.
+	CertificateRef *refs.TypedLocalObjectReference `json:"certificateRef,omitempty"`
+
+	// TLSSecretRef is a reference to the copy of the cluster default
+	// certificate used by the ingress.
+	TLSSecretRef *refs.TypedLocalObjectReference `json:"tlsSecretRef,omitempty"`
+
+	// NetworkPolicyRef is a reference to the NetworkPolicy enforcing the
+	// ingress policy, when enforcement is enabled.
+	NetworkPolicyRef *refs.TypedLocalObjectReference `json:"networkPolicyRef,omitempty"`
+
+	// PodDisruptionBudgetRef is a reference to the budget limiting voluntary
+	// disruptions of the workload, when availability is configured.
+	PodDisruptionBudgetRef *refs.TypedLocalObjectReference `json:"podDisruptionBudgetRef,omitempty"`
+
+	// HorizontalPodAutoscalerRef is a reference to the autoscaler managing
+	// the replicas of the deployment.
+	HorizontalPodAutoscalerRef *refs.TypedLocalObjectReference `json:"horizontalPodAutoscalerRef,omitempty"`
+
+	// CurrentReplicas is the number of replicas last observed by the
+	// autoscaler.
+	CurrentReplicas int32 `json:"currentReplicas,omitempty"`
+
+	// DesiredReplicas is the number of replicas last calculated by the
+	// autoscaler.
+	DesiredReplicas int32 `json:"desiredReplicas,omitempty"`
+
+	// Rollout reports the progress of rolling out a new image, when a rollout
+	// is configured.
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+
 	// Address to target this deployer internally
 	Address *apis.Addressable `json:"address,omitempty"`
 
 	// URL to target this deployer publicly
 	URL string `json:"url,omitempty"`
+
+	// URLs are all of the public URLs for the routes of this deployer
+	URLs []string `json:"urls,omitempty"`
 }
 
 // +kubebuilder:object:root=true
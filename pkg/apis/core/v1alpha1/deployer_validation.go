@@ -18,18 +18,26 @@ package v1alpha1
 
 import (
 	"fmt"
+	"regexp"
 
-	"github.com/google/go-cmp/cmp"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	"github.com/projectriff/system/pkg/validation"
 )
 
 // +kubebuilder:webhook:path=/validate-core-projectriff-io-v1alpha1-deployer,mutating=false,failurePolicy=fail,groups=core.projectriff.io,resources=deployers,verbs=create;update,versions=v1alpha1,name=deployers.core.projectriff.io
 
+// routePathPattern matches absolute paths without characters that are
+// meaningful to the ingress controller's regular expressions
+var routePathPattern = regexp.MustCompile(`^/[A-Za-z0-9/._~%-]*$`)
+
 var (
 	_ webhook.Validator         = &Deployer{}
 	_ validation.FieldValidator = &Deployer{}
@@ -54,6 +62,10 @@ func (r *Deployer) ValidateDelete() error {
 func (r *Deployer) Validate() validation.FieldErrors {
 	errs := validation.FieldErrors{}
 
+	errs = errs.Also(buildv1alpha1.ValidatePromoteAnnotation(r.Annotations, r.Spec.Build != nil).ViaField("metadata"))
+	if _, ok := r.Annotations[RolloutPromoteAnnotationKey]; ok && r.Spec.Rollout == nil {
+		errs = errs.Also(validation.ErrDisallowedFields("annotations["+RolloutPromoteAnnotationKey+"]", "promotion requires a rollout").ViaField("metadata"))
+	}
 	errs = errs.Also(r.Spec.Validate().ViaField("spec"))
 
 	return errs
@@ -66,15 +78,8 @@ func (s *DeployerSpec) Validate() validation.FieldErrors {
 
 	errs := validation.FieldErrors{}
 
-	if diff := cmp.Diff(&corev1.PodSpec{
-		// add supported PodSpec fields here, otherwise their usage will be rejected
-		ServiceAccountName: s.Template.Spec.ServiceAccountName,
-		// the defaulter guarantees at least one container
-		Containers: filterInvalidContainers(s.Template.Spec.Containers[:1]),
-		Volumes:    filterInvalidVolumes(s.Template.Spec.Volumes),
-	}, &s.Template.Spec); diff != "" {
-		errs = errs.Also(validation.ErrDisallowedFields("template.spec", fmt.Sprintf("limited PodSpec fields may be set (-want, +got) = %v", diff)))
-	}
+	errs = errs.Also(validation.ActivePodSpecAllowlist().ValidatePodSpec(&s.Template.Spec).ViaField("template.spec"))
+	errs = errs.Also(validation.ActiveImagePolicy().ValidatePodSpecImages(&s.Template.Spec).ViaField("template.spec"))
 
 	for i, env := range s.Template.Spec.Containers[0].Env {
 		if env.Name == "PORT" {
@@ -94,6 +99,151 @@ func (s *DeployerSpec) Validate() validation.FieldErrors {
 		errs = errs.Also(validation.ErrInvalidValue(s.IngressPolicy, "ingressPolicy"))
 	}
 
+	errs = errs.Also(s.Scale.Validate().ViaField("scale"))
+	if s.Rollout != nil {
+		errs = errs.Also(s.Rollout.Validate().ViaField("rollout"))
+	}
+	if len(s.Routes) != 0 {
+		if s.IngressPolicy != IngressPolicyExternal {
+			errs = errs.Also(validation.ErrDisallowedFields("routes", "routes require the External ingress policy"))
+		}
+		claimed := map[string]bool{}
+		for i, route := range s.Routes {
+			errs = errs.Also(route.Validate().ViaFieldIndex("routes", i))
+			if route.Rewrite != s.Routes[0].Rewrite {
+				errs = errs.Also(validation.ErrInvalidValue(route.Rewrite, "rewrite").ViaFieldIndex("routes", i))
+			}
+			for j, host := range route.Hosts {
+				claim := host + route.Path
+				if claimed[claim] {
+					errs = errs.Also(validation.ErrDuplicateValue(claim, fmt.Sprintf("hosts[%d]", j)).ViaFieldIndex("routes", i))
+				}
+				claimed[claim] = true
+			}
+		}
+	}
+	errs = errs.Also(s.validatePorts())
+	if s.Availability != nil {
+		errs = errs.Also(s.Availability.Validate().ViaField("availability"))
+	}
+	if s.TLS != nil {
+		if s.IngressPolicy != IngressPolicyExternal {
+			errs = errs.Also(validation.ErrDisallowedFields("tls", "TLS requires the External ingress policy"))
+		}
+		errs = errs.Also(s.TLS.Validate().ViaField("tls"))
+	}
+
+	return errs
+}
+
+// validatePorts checks the handler container ports are uniquely named and
+// described by at most one port entry.
+func (s *DeployerSpec) validatePorts() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	containerPorts := s.Template.Spec.Containers[0].Ports
+	named := map[string]corev1.ContainerPort{}
+	for i, port := range containerPorts {
+		field := fmt.Sprintf("template.spec.containers[0].ports[%d]", i)
+		if port.Name == "" {
+			if len(containerPorts) > 1 {
+				errs = errs.Also(validation.ErrMissingField(field + ".name"))
+			}
+			continue
+		}
+		if _, ok := named[port.Name]; ok {
+			errs = errs.Also(validation.ErrDuplicateValue(port.Name, field+".name"))
+		}
+		named[port.Name] = port
+		public := port.Name == s.PublicPort || (s.PublicPort == "" && i == 0)
+		if !public && port.ContainerPort == 80 {
+			// port 80 of the service is reserved for the public port
+			errs = errs.Also(validation.ErrInvalidValue(port.ContainerPort, field+".containerPort"))
+		}
+	}
+	if s.PublicPort != "" {
+		if _, ok := named[s.PublicPort]; !ok {
+			errs = errs.Also(validation.ErrInvalidValue(s.PublicPort, "publicPort"))
+		}
+	}
+
+	described := map[string]bool{}
+	healthChecks := 0
+	for i, port := range s.Ports {
+		if port.Name == "" {
+			errs = errs.Also(validation.ErrMissingField("name").ViaFieldIndex("ports", i))
+		} else if _, ok := named[port.Name]; !ok {
+			errs = errs.Also(validation.ErrInvalidValue(port.Name, "name").ViaFieldIndex("ports", i))
+		} else if described[port.Name] {
+			errs = errs.Also(validation.ErrDuplicateValue(port.Name, "name").ViaFieldIndex("ports", i))
+		}
+		described[port.Name] = true
+		switch port.Protocol {
+		case "", PortProtocolHTTP1, PortProtocolH2C, PortProtocolGRPC:
+		default:
+			errs = errs.Also(validation.ErrInvalidValue(port.Protocol, "protocol").ViaFieldIndex("ports", i))
+		}
+		if port.GRPCHealthCheck {
+			healthChecks++
+			if port.Protocol != PortProtocolGRPC {
+				errs = errs.Also(validation.ErrDisallowedFields("grpcHealthCheck", "the gRPC health check requires the grpc protocol").ViaFieldIndex("ports", i))
+			} else if healthChecks > 1 {
+				errs = errs.Also(validation.ErrDisallowedFields("grpcHealthCheck", "only one port may be health checked").ViaFieldIndex("ports", i))
+			}
+		}
+	}
+
+	return errs
+}
+
+func (s *Scale) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if equality.Semantic.DeepEqual(s, &Scale{}) {
+		// autoscaling is disabled
+		return errs
+	}
+
+	if s.Max == nil {
+		errs = errs.Also(validation.ErrMissingField("max"))
+	} else if *s.Max < int32(1) {
+		errs = errs.Also(validation.ErrInvalidValue(*s.Max, "max"))
+	}
+	if s.Min != nil && *s.Min < int32(1) {
+		errs = errs.Also(validation.ErrInvalidValue(*s.Min, "min"))
+	}
+	if s.Min != nil && s.Max != nil && *s.Min > *s.Max {
+		errs = errs.Also(validation.ErrInvalidValue(*s.Max, "max"))
+	}
+	if s.TargetCPUUtilization != nil && *s.TargetCPUUtilization < int32(1) {
+		errs = errs.Also(validation.ErrInvalidValue(*s.TargetCPUUtilization, "targetCPUUtilization"))
+	}
+	if s.TargetMemoryUtilization != nil && *s.TargetMemoryUtilization < int32(1) {
+		errs = errs.Also(validation.ErrInvalidValue(*s.TargetMemoryUtilization, "targetMemoryUtilization"))
+	}
+	for i, metric := range s.Metrics {
+		switch metric.Type {
+		case autoscalingv2beta2.PodsMetricSourceType:
+			if metric.Pods == nil {
+				errs = errs.Also(validation.ErrMissingField("pods").ViaFieldIndex("metrics", i))
+			}
+		case autoscalingv2beta2.ObjectMetricSourceType:
+			if metric.Object == nil {
+				errs = errs.Also(validation.ErrMissingField("object").ViaFieldIndex("metrics", i))
+			}
+		case autoscalingv2beta2.ExternalMetricSourceType:
+			if metric.External == nil {
+				errs = errs.Also(validation.ErrMissingField("external").ViaFieldIndex("metrics", i))
+			}
+		case autoscalingv2beta2.ResourceMetricSourceType:
+			if metric.Resource == nil {
+				errs = errs.Also(validation.ErrMissingField("resource").ViaFieldIndex("metrics", i))
+			}
+		default:
+			errs = errs.Also(validation.ErrInvalidValue(metric.Type, "type").ViaFieldIndex("metrics", i))
+		}
+	}
+
 	return errs
 }
 
@@ -130,15 +280,133 @@ func (b *Build) Validate() validation.FieldErrors {
 		errs = errs.Also(validation.ErrMultipleOneOf(used...))
 	}
 
+	if b.Pin != nil {
+		errs = errs.Also(b.Pin.Validate().ViaField("pin"))
+	}
+
 	return errs
 }
 
-func filterInvalidContainers(containers []corev1.Container) []corev1.Container {
-	// TODO remove unsupported fields
-	return containers
+func (a *Availability) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if a.MinAvailable != nil && a.MaxUnavailable != nil {
+		errs = errs.Also(validation.ErrMultipleOneOf("minAvailable", "maxUnavailable"))
+	}
+	if a.MinAvailable != nil && !validIntOrPercent(*a.MinAvailable) {
+		errs = errs.Also(validation.ErrInvalidValue(a.MinAvailable.String(), "minAvailable"))
+	}
+	if a.MaxUnavailable != nil && !validIntOrPercent(*a.MaxUnavailable) {
+		errs = errs.Also(validation.ErrInvalidValue(a.MaxUnavailable.String(), "maxUnavailable"))
+	}
+	if a.ZoneSpread != nil {
+		errs = errs.Also(a.ZoneSpread.Validate().ViaField("zoneSpread"))
+	}
+	if a.NodeSpread != nil {
+		errs = errs.Also(a.NodeSpread.Validate().ViaField("nodeSpread"))
+	}
+
+	return errs
+}
+
+// validIntOrPercent accepts non-negative integers and percentages
+func validIntOrPercent(v intstr.IntOrString) bool {
+	if v.Type == intstr.Int {
+		return v.IntVal >= 0
+	}
+	return len(k8svalidation.IsValidPercent(v.StrVal)) == 0
+}
+
+func (t *TopologySpread) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if t.MaxSkew != nil && *t.MaxSkew < 1 {
+		errs = errs.Also(validation.ErrInvalidValue(*t.MaxSkew, "maxSkew"))
+	}
+	switch t.WhenUnsatisfiable {
+	case "", corev1.DoNotSchedule, corev1.ScheduleAnyway:
+	default:
+		errs = errs.Also(validation.ErrInvalidValue(t.WhenUnsatisfiable, "whenUnsatisfiable"))
+	}
+
+	return errs
 }
 
-func filterInvalidVolumes(volumes []corev1.Volume) []corev1.Volume {
-	// TODO remove unsupported fields
-	return volumes
+func (r *Rollout) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if r.Canary == nil && r.BlueGreen == nil {
+		errs = errs.Also(validation.ErrMissingOneOf("canary", "blueGreen"))
+	} else if r.Canary != nil && r.BlueGreen != nil {
+		errs = errs.Also(validation.ErrMultipleOneOf("canary", "blueGreen"))
+	} else if r.Canary != nil {
+		errs = errs.Also(r.Canary.Validate().ViaField("canary"))
+	}
+
+	if r.ProgressDeadline != nil && r.ProgressDeadline.Duration <= 0 {
+		errs = errs.Also(validation.ErrInvalidValue(r.ProgressDeadline.Duration.String(), "progressDeadline"))
+	}
+	if r.MaxErrorRate != nil && (*r.MaxErrorRate < 0 || *r.MaxErrorRate > 100) {
+		errs = errs.Also(validation.ErrInvalidValue(*r.MaxErrorRate, "maxErrorRate"))
+	}
+
+	return errs
+}
+
+func (c *CanaryRollout) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if len(c.Steps) == 0 {
+		errs = errs.Also(validation.ErrMissingField("steps"))
+	}
+	for i, step := range c.Steps {
+		if step.Weight < 1 || step.Weight > 100 {
+			errs = errs.Also(validation.ErrInvalidValue(step.Weight, "weight").ViaFieldIndex("steps", i))
+		}
+		if step.Pause != nil && step.Pause.Duration < 0 {
+			errs = errs.Also(validation.ErrInvalidValue(step.Pause.Duration.String(), "pause").ViaFieldIndex("steps", i))
+		}
+	}
+
+	return errs
+}
+
+func (r *Route) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if len(r.Hosts) == 0 {
+		errs = errs.Also(validation.ErrMissingField("hosts"))
+	}
+	for i, host := range r.Hosts {
+		if msgs := k8svalidation.IsDNS1123Subdomain(host); len(msgs) != 0 {
+			errs = errs.Also(validation.ErrInvalidArrayValue(host, "hosts", i))
+		}
+	}
+	if r.Path != "" && !routePathPattern.MatchString(r.Path) {
+		errs = errs.Also(validation.ErrInvalidValue(r.Path, "path"))
+	}
+	if r.Rewrite != "" && !routePathPattern.MatchString(r.Rewrite) {
+		errs = errs.Also(validation.ErrInvalidValue(r.Rewrite, "rewrite"))
+	}
+
+	return errs
+}
+
+func (t *DeployerTLS) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if t.SecretRef == "" && t.IssuerRef == nil {
+		errs = errs.Also(validation.ErrMissingOneOf("secretRef", "issuerRef"))
+	} else if t.SecretRef != "" && t.IssuerRef != nil {
+		errs = errs.Also(validation.ErrMultipleOneOf("secretRef", "issuerRef"))
+	} else if t.IssuerRef != nil {
+		if t.IssuerRef.Name == "" {
+			errs = errs.Also(validation.ErrMissingField("issuerRef.name"))
+		}
+		if t.IssuerRef.Kind != "" && t.IssuerRef.Kind != "Issuer" && t.IssuerRef.Kind != "ClusterIssuer" {
+			errs = errs.Also(validation.ErrInvalidValue(t.IssuerRef.Kind, "issuerRef.kind"))
+		}
+	}
+
+	return errs
 }
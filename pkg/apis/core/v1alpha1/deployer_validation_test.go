@@ -18,10 +18,15 @@ package v1alpha1
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	"github.com/projectriff/system/pkg/validation"
 )
 
@@ -51,6 +56,66 @@ func TestValidateDeployer(t *testing.T) {
 			},
 		},
 		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid promote annotation",
+		target: &Deployer{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					buildv1alpha1.PromoteAnnotationKey: "latest",
+				},
+			},
+			Spec: DeployerSpec{
+				Build: &Build{
+					FunctionRef: "my-function",
+				},
+				Template: &corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{},
+						},
+					},
+				},
+			},
+		},
+		expected: validation.ErrInvalidValue("latest", "metadata.annotations[build.projectriff.io/promote]"),
+	}, {
+		name: "promote annotation without build",
+		target: &Deployer{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					buildv1alpha1.PromoteAnnotationKey: "2",
+				},
+			},
+			Spec: DeployerSpec{
+				Template: &corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{Image: "my-image"},
+						},
+					},
+				},
+			},
+		},
+		expected: validation.ErrDisallowedFields("metadata.annotations[build.projectriff.io/promote]", "promotion requires a build"),
+	}, {
+		name: "rollout promote annotation without rollout",
+		target: &Deployer{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					RolloutPromoteAnnotationKey: "true",
+				},
+			},
+			Spec: DeployerSpec{
+				Template: &corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{Image: "my-image"},
+						},
+					},
+				},
+			},
+		},
+		expected: validation.ErrDisallowedFields("metadata.annotations[core.projectriff.io/promote]", "promotion requires a rollout"),
 	}} {
 		t.Run(c.name, func(t *testing.T) {
 			actual := c.target.Validate()
@@ -85,6 +150,79 @@ func TestValidateDeployerSpec(t *testing.T) {
 			},
 		},
 		expected: validation.FieldErrors{},
+	}, {
+		name: "valid, sidecar and pod security context",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+						{Name: "sidecar", Image: "my-sidecar"},
+					},
+					InitContainers: []corev1.Container{
+						{Name: "init", Image: "my-init"},
+					},
+					SecurityContext: &corev1.PodSecurityContext{
+						SupplementalGroups: []int64{1000},
+					},
+					ImagePullSecrets: []corev1.LocalObjectReference{
+						{Name: "my-registry"},
+					},
+				},
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid, pod spec fields outside the allowlist",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+					HostNetwork: true,
+					HostPID:     true,
+				},
+			},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrDisallowedFields("template.spec.hostNetwork", "not in the v1 PodSpec allowlist"),
+			validation.ErrDisallowedFields("template.spec.hostPID", "not in the v1 PodSpec allowlist"),
+		),
+	}, {
+		name: "valid, pinned build",
+		target: &DeployerSpec{
+			Build: &Build{
+				FunctionRef: "my-function",
+				Pin: &buildv1alpha1.BuildPin{
+					BuildNumber: 2,
+				},
+			},
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{},
+					},
+				},
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid, pinned build",
+		target: &DeployerSpec{
+			Build: &Build{
+				FunctionRef: "my-function",
+				Pin:         &buildv1alpha1.BuildPin{},
+			},
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{},
+					},
+				},
+			},
+		},
+		expected: validation.ErrMissingOneOf("buildNumber", "digest").ViaField("pin").ViaField("build"),
 	}, {
 		name: "valid, container image",
 		target: &DeployerSpec{
@@ -143,6 +281,609 @@ func TestValidateDeployerSpec(t *testing.T) {
 			IngressPolicy: "bogus",
 		},
 		expected: validation.ErrInvalidValue(IngressPolicy("bogus"), "ingressPolicy"),
+	}, {
+		name: "valid, scale",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-iamge"},
+					},
+				},
+			},
+			Scale: Scale{
+				Min:                  int32Ptr(1),
+				Max:                  int32Ptr(5),
+				TargetCPUUtilization: int32Ptr(80),
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid, scale missing max",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-iamge"},
+					},
+				},
+			},
+			Scale: Scale{
+				Min: int32Ptr(1),
+			},
+		},
+		expected: validation.ErrMissingField("scale.max"),
+	}, {
+		name: "invalid, scale min greater than max",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-iamge"},
+					},
+				},
+			},
+			Scale: Scale{
+				Min: int32Ptr(4),
+				Max: int32Ptr(2),
+			},
+		},
+		expected: validation.ErrInvalidValue(int32(2), "scale.max"),
+	}, {
+		name: "invalid, scale targets",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-iamge"},
+					},
+				},
+			},
+			Scale: Scale{
+				Min:                     int32Ptr(0),
+				Max:                     int32Ptr(2),
+				TargetCPUUtilization:    int32Ptr(0),
+				TargetMemoryUtilization: int32Ptr(0),
+			},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrInvalidValue(int32(0), "scale.min"),
+			validation.ErrInvalidValue(int32(0), "scale.targetCPUUtilization"),
+			validation.ErrInvalidValue(int32(0), "scale.targetMemoryUtilization"),
+		),
+	}, {
+		name: "invalid, scale metrics",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-iamge"},
+					},
+				},
+			},
+			Scale: Scale{
+				Max: int32Ptr(2),
+				Metrics: []autoscalingv2beta2.MetricSpec{
+					{Type: autoscalingv2beta2.PodsMetricSourceType},
+					{Type: "bogus"},
+				},
+			},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrMissingField("scale.metrics[0].pods"),
+			validation.ErrInvalidValue(autoscalingv2beta2.MetricSourceType("bogus"), "scale.metrics[1].type"),
+		),
+	}, {
+		name: "valid, canary rollout",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-iamge"},
+					},
+				},
+			},
+			Rollout: &Rollout{
+				Canary: &CanaryRollout{
+					Steps: []CanaryStep{
+						{Weight: 10, Pause: &metav1.Duration{Duration: time.Minute}},
+						{Weight: 50},
+					},
+				},
+				MaxErrorRate: int32Ptr(5),
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid, rollout without strategy",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-iamge"},
+					},
+				},
+			},
+			Rollout: &Rollout{},
+		},
+		expected: validation.ErrMissingOneOf("canary", "blueGreen").ViaField("rollout"),
+	}, {
+		name: "invalid, rollout with multiple strategies",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-iamge"},
+					},
+				},
+			},
+			Rollout: &Rollout{
+				Canary: &CanaryRollout{
+					Steps: []CanaryStep{{Weight: 50}},
+				},
+				BlueGreen: &BlueGreenRollout{},
+			},
+		},
+		expected: validation.ErrMultipleOneOf("canary", "blueGreen").ViaField("rollout"),
+	}, {
+		name: "invalid, canary rollout",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-iamge"},
+					},
+				},
+			},
+			Rollout: &Rollout{
+				Canary: &CanaryRollout{
+					Steps: []CanaryStep{
+						{Weight: 0},
+						{Weight: 50, Pause: &metav1.Duration{Duration: -time.Minute}},
+					},
+				},
+				ProgressDeadline: &metav1.Duration{},
+				MaxErrorRate:     int32Ptr(101),
+			},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrInvalidValue(int32(0), "rollout.canary.steps[0].weight"),
+			validation.ErrInvalidValue("-1m0s", "rollout.canary.steps[1].pause"),
+			validation.ErrInvalidValue("0s", "rollout.progressDeadline"),
+			validation.ErrInvalidValue(int32(101), "rollout.maxErrorRate"),
+		),
+	}, {
+		name: "invalid, canary rollout without steps",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-iamge"},
+					},
+				},
+			},
+			Rollout: &Rollout{
+				Canary: &CanaryRollout{},
+			},
+		},
+		expected: validation.ErrMissingField("rollout.canary.steps"),
+	}, {
+		name: "valid, routes",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-iamge"},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyExternal,
+			Routes: []Route{
+				{Hosts: []string{"api.example.com"}, Path: "/orders", Rewrite: "/"},
+				{Hosts: []string{"orders.example.com"}, Path: "/", Rewrite: "/"},
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid, routes without external ingress",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-iamge"},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyClusterLocal,
+			Routes: []Route{
+				{Hosts: []string{"api.example.com"}, Path: "/orders"},
+			},
+		},
+		expected: validation.ErrDisallowedFields("routes", "routes require the External ingress policy"),
+	}, {
+		name: "invalid, routes",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-iamge"},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyExternal,
+			Routes: []Route{
+				{Path: "orders"},
+				{Hosts: []string{"API_EXAMPLE"}, Path: "/orders.*", Rewrite: "/"},
+			},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrMissingField("routes[0].hosts"),
+			validation.ErrInvalidValue("orders", "routes[0].path"),
+			validation.ErrInvalidArrayValue("API_EXAMPLE", "routes[1].hosts", 0),
+			validation.ErrInvalidValue("/orders.*", "routes[1].path"),
+			validation.ErrInvalidValue("/", "routes[1].rewrite"),
+		),
+	}, {
+		name: "invalid, duplicate routes",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-iamge"},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyExternal,
+			Routes: []Route{
+				{Hosts: []string{"api.example.com"}, Path: "/orders"},
+				{Hosts: []string{"www.example.com", "api.example.com"}, Path: "/orders"},
+			},
+		},
+		expected: validation.ErrDuplicateValue("api.example.com/orders", "routes[1].hosts[1]"),
+	}, {
+		name: "valid, tls secret",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-iamge"},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyExternal,
+			TLS: &DeployerTLS{
+				SecretRef: "my-tls",
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "valid, tls issuer",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-iamge"},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyExternal,
+			TLS: &DeployerTLS{
+				IssuerRef: &CertificateIssuerRef{Name: "letsencrypt", Kind: "Issuer"},
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid, tls without external ingress",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-iamge"},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyClusterLocal,
+			TLS: &DeployerTLS{
+				SecretRef: "my-tls",
+			},
+		},
+		expected: validation.ErrDisallowedFields("tls", "TLS requires the External ingress policy"),
+	}, {
+		name: "invalid, tls without source",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-iamge"},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyExternal,
+			TLS:           &DeployerTLS{},
+		},
+		expected: validation.ErrMissingOneOf("secretRef", "issuerRef").ViaField("tls"),
+	}, {
+		name: "invalid, tls with multiple sources",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-iamge"},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyExternal,
+			TLS: &DeployerTLS{
+				SecretRef: "my-tls",
+				IssuerRef: &CertificateIssuerRef{Name: "letsencrypt"},
+			},
+		},
+		expected: validation.ErrMultipleOneOf("secretRef", "issuerRef").ViaField("tls"),
+	}, {
+		name: "invalid, tls issuer",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-iamge"},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyExternal,
+			TLS: &DeployerTLS{
+				IssuerRef: &CertificateIssuerRef{Kind: "Bogus"},
+			},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrMissingField("tls.issuerRef.name"),
+			validation.ErrInvalidValue("Bogus", "tls.issuerRef.kind"),
+		),
+	}, {
+		name: "valid, ports",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Image: "my-iamge",
+							Ports: []corev1.ContainerPort{
+								{Name: "grpc", ContainerPort: 9090},
+								{Name: "admin", ContainerPort: 8081},
+							},
+						},
+					},
+				},
+			},
+			PublicPort: "grpc",
+			Ports: []DeployerPort{
+				{Name: "grpc", Protocol: PortProtocolGRPC, GRPCHealthCheck: true},
+				{Name: "admin", Protocol: PortProtocolHTTP1},
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid, unnamed container ports",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Image: "my-iamge",
+							Ports: []corev1.ContainerPort{
+								{ContainerPort: 9090},
+								{Name: "admin", ContainerPort: 8081},
+							},
+						},
+					},
+				},
+			},
+		},
+		expected: validation.ErrMissingField("template.spec.containers[0].ports[0].name"),
+	}, {
+		name: "invalid, container port 80 reserved for the public port",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Image: "my-iamge",
+							Ports: []corev1.ContainerPort{
+								{Name: "http", ContainerPort: 8080},
+								{Name: "admin", ContainerPort: 80},
+							},
+						},
+					},
+				},
+			},
+		},
+		expected: validation.ErrInvalidValue(int32(80), "template.spec.containers[0].ports[1].containerPort"),
+	}, {
+		name: "invalid, unknown public port",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Image: "my-iamge",
+							Ports: []corev1.ContainerPort{
+								{Name: "grpc", ContainerPort: 9090},
+								{Name: "admin", ContainerPort: 8081},
+							},
+						},
+					},
+				},
+			},
+			PublicPort: "bogus",
+		},
+		expected: validation.ErrInvalidValue("bogus", "publicPort"),
+	}, {
+		name: "invalid, port entries",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Image: "my-iamge",
+							Ports: []corev1.ContainerPort{
+								{Name: "grpc", ContainerPort: 9090},
+								{Name: "admin", ContainerPort: 8081},
+							},
+						},
+					},
+				},
+			},
+			Ports: []DeployerPort{
+				{Name: "bogus"},
+				{Name: "grpc", Protocol: "bogus"},
+				{Name: "grpc", Protocol: PortProtocolGRPC},
+				{Name: "admin", Protocol: PortProtocolH2C, GRPCHealthCheck: true},
+			},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrInvalidValue("bogus", "ports[0].name"),
+			validation.ErrInvalidValue(PortProtocol("bogus"), "ports[1].protocol"),
+			validation.ErrDuplicateValue("grpc", "ports[2].name"),
+			validation.ErrDisallowedFields("ports[3].grpcHealthCheck", "the gRPC health check requires the grpc protocol"),
+		),
+	}, {
+		name: "valid, availability",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-iamge"},
+					},
+					Affinity: &corev1.Affinity{
+						NodeAffinity: &corev1.NodeAffinity{
+							RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+								NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+									MatchExpressions: []corev1.NodeSelectorRequirement{{
+										Key:      "kubernetes.io/arch",
+										Operator: corev1.NodeSelectorOpIn,
+										Values:   []string{"amd64"},
+									}},
+								}},
+							},
+						},
+					},
+					TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{
+						MaxSkew:           2,
+						TopologyKey:       "topology.kubernetes.io/region",
+						WhenUnsatisfiable: corev1.DoNotSchedule,
+					}},
+				},
+			},
+			Availability: &Availability{
+				MinAvailable: intstrPtr(intstr.FromString("50%")),
+				ZoneSpread: &TopologySpread{
+					MaxSkew:           int32Ptr(1),
+					WhenUnsatisfiable: corev1.DoNotSchedule,
+				},
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid, availability",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-iamge"},
+					},
+				},
+			},
+			Availability: &Availability{
+				MinAvailable:   intstrPtr(intstr.FromString("half")),
+				MaxUnavailable: intstrPtr(intstr.FromInt(-1)),
+				ZoneSpread: &TopologySpread{
+					MaxSkew: int32Ptr(0),
+				},
+				NodeSpread: &TopologySpread{
+					WhenUnsatisfiable: "Bogus",
+				},
+			},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrMultipleOneOf("minAvailable", "maxUnavailable").ViaField("availability"),
+			validation.ErrInvalidValue("half", "availability.minAvailable"),
+			validation.ErrInvalidValue("-1", "availability.maxUnavailable"),
+			validation.ErrInvalidValue(int32(0), "availability.zoneSpread.maxSkew"),
+			validation.ErrInvalidValue(corev1.UnsatisfiableConstraintAction("Bogus"), "availability.nodeSpread.whenUnsatisfiable"),
+		),
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			actual := c.target.Validate()
+			if diff := cmp.Diff(c.expected, actual); diff != "" {
+				t.Errorf("validateDeployerSpec(%s) (-expected, +actual) = %v", c.name, diff)
+			}
+		})
+	}
+}
+
+func intstrPtr(v intstr.IntOrString) *intstr.IntOrString {
+	return &v
+}
+
+func TestValidateDeployerSpec_ImagePolicy(t *testing.T) {
+	policy, err := validation.NewImagePolicyFromData(map[string]string{
+		"allowedImages": "registry.example.com/**",
+	})
+	if err != nil {
+		t.Fatalf("NewImagePolicyFromData() unexpected error: %v", err)
+	}
+	validation.SetImagePolicy(policy)
+	defer validation.SetImagePolicy(nil)
+
+	for _, c := range []struct {
+		name     string
+		target   *DeployerSpec
+		expected validation.FieldErrors
+	}{{
+		name: "allowed image",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "registry.example.com/my-image"},
+					},
+				},
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "build",
+		target: &DeployerSpec{
+			Build: &Build{
+				FunctionRef: "my-function",
+			},
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{},
+					},
+				},
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "disallowed images",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+						{Name: "sidecar", Image: "registry.example.com/my-sidecar"},
+					},
+					InitContainers: []corev1.Container{
+						{Name: "init", Image: "my-init"},
+					},
+				},
+			},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrDisallowedFields("template.spec.initContainers[0].image", `image "my-init" is not from an allowed repository`),
+			validation.ErrDisallowedFields("template.spec.containers[0].image", `image "my-image" is not from an allowed repository`),
+		),
 	}} {
 		t.Run(c.name, func(t *testing.T) {
 			actual := c.target.Validate()
@@ -16,7 +16,11 @@ limitations under the License.
 
 package v1alpha1
 
-import "sigs.k8s.io/controller-runtime/pkg/webhook"
+import (
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+)
 
 // +kubebuilder:webhook:path=/mutate-knative-projectriff-io-v1alpha1-adapter,mutating=true,failurePolicy=fail,groups=knative.projectriff.io,resources=adapters,verbs=create;update,versions=v1alpha1,name=adapters.knative.projectriff.io
 
@@ -25,8 +29,29 @@ var _ webhook.Defaulter = &Adapter{}
 // Default implements webhook.Defaulter so a webhook will be registered for the type
 func (r *Adapter) Default() {
 	r.Spec.Default()
+	r.Spec.Build.Pin = buildv1alpha1.PromoteBuild(r.Annotations, r.Spec.Build.Pin)
+
+	approvedBy := r.Annotations[AdapterApprovedByAnnotationKey]
+	if p := r.Spec.UpdatePolicy; p != nil && p.Approval == AdapterApprovalManual {
+		if approved := ApproveAdapterImage(r.Annotations, &r.Status, p.ApprovedImage); approved != p.ApprovedImage {
+			p.ApprovedImage = approved
+			p.ApprovedBy = approvedBy
+		}
+	}
+	if reverted := RevertAdapterImage(r.Annotations, &r.Status, r.Spec.RevertedImage); reverted != r.Spec.RevertedImage {
+		r.Spec.RevertedImage = reverted
+		r.Spec.RevertedBy = approvedBy
+	}
+	_, approving := r.Annotations[AdapterApproveAnnotationKey]
+	_, reverting := r.Annotations[AdapterRevertAnnotationKey]
+	if !approving && !reverting {
+		// the approver is recorded with the approval or revert it names
+		delete(r.Annotations, AdapterApprovedByAnnotationKey)
+	}
 }
 
 func (s *AdapterSpec) Default() {
-	// nothing to do
+	if s.UpdatePolicy != nil && s.UpdatePolicy.Approval == "" {
+		s.UpdatePolicy.Approval = AdapterApprovalAutomatic
+	}
 }
@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestAdapterDefault(t *testing.T) {
@@ -31,6 +32,157 @@ func TestAdapterDefault(t *testing.T) {
 		name: "empty",
 		in:   &Adapter{},
 		want: &Adapter{},
+	}, {
+		name: "approve pending image",
+		in: &Adapter{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					AdapterApproveAnnotationKey:    "true",
+					AdapterApprovedByAnnotationKey: "jane",
+				},
+			},
+			Spec: AdapterSpec{
+				UpdatePolicy: &AdapterUpdatePolicy{
+					Approval: AdapterApprovalManual,
+				},
+			},
+			Status: AdapterStatus{
+				PendingImage: "example.com/app:2",
+			},
+		},
+		want: &Adapter{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{},
+			},
+			Spec: AdapterSpec{
+				UpdatePolicy: &AdapterUpdatePolicy{
+					Approval:      AdapterApprovalManual,
+					ApprovedImage: "example.com/app:2",
+					ApprovedBy:    "jane",
+				},
+			},
+			Status: AdapterStatus{
+				PendingImage: "example.com/app:2",
+			},
+		},
+	}, {
+		name: "approve image",
+		in: &Adapter{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					AdapterApproveAnnotationKey: "example.com/app:3",
+				},
+			},
+			Spec: AdapterSpec{
+				UpdatePolicy: &AdapterUpdatePolicy{
+					Approval:      AdapterApprovalManual,
+					ApprovedImage: "example.com/app:2",
+					ApprovedBy:    "jane",
+				},
+			},
+		},
+		want: &Adapter{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{},
+			},
+			Spec: AdapterSpec{
+				UpdatePolicy: &AdapterUpdatePolicy{
+					Approval:      AdapterApprovalManual,
+					ApprovedImage: "example.com/app:3",
+				},
+			},
+		},
+	}, {
+		name: "approve without a pending image",
+		in: &Adapter{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					AdapterApproveAnnotationKey:    "true",
+					AdapterApprovedByAnnotationKey: "jane",
+				},
+			},
+			Spec: AdapterSpec{
+				UpdatePolicy: &AdapterUpdatePolicy{
+					Approval: AdapterApprovalManual,
+				},
+			},
+		},
+		want: &Adapter{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					AdapterApproveAnnotationKey:    "true",
+					AdapterApprovedByAnnotationKey: "jane",
+				},
+			},
+			Spec: AdapterSpec{
+				UpdatePolicy: &AdapterUpdatePolicy{
+					Approval: AdapterApprovalManual,
+				},
+			},
+		},
+	}, {
+		name: "revert latest update",
+		in: &Adapter{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					AdapterRevertAnnotationKey:     "true",
+					AdapterApprovedByAnnotationKey: "jane",
+				},
+			},
+			Status: AdapterStatus{
+				Updates: []AdapterUpdate{
+					{PreviousImage: "example.com/app:1", Image: "example.com/app:2"},
+				},
+			},
+		},
+		want: &Adapter{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{},
+			},
+			Spec: AdapterSpec{
+				RevertedImage: "example.com/app:2",
+				RevertedBy:    "jane",
+			},
+			Status: AdapterStatus{
+				Updates: []AdapterUpdate{
+					{PreviousImage: "example.com/app:1", Image: "example.com/app:2"},
+				},
+			},
+		},
+	}, {
+		name: "revert after a revert",
+		in: &Adapter{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					AdapterRevertAnnotationKey: "true",
+				},
+			},
+			Spec: AdapterSpec{
+				RevertedImage: "example.com/app:2",
+			},
+			Status: AdapterStatus{
+				Updates: []AdapterUpdate{
+					{PreviousImage: "example.com/app:2", Image: "example.com/app:1", Revert: true},
+					{PreviousImage: "example.com/app:1", Image: "example.com/app:2"},
+				},
+			},
+		},
+		want: &Adapter{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					AdapterRevertAnnotationKey: "true",
+				},
+			},
+			Spec: AdapterSpec{
+				RevertedImage: "example.com/app:2",
+			},
+			Status: AdapterStatus{
+				Updates: []AdapterUpdate{
+					{PreviousImage: "example.com/app:2", Image: "example.com/app:1", Revert: true},
+					{PreviousImage: "example.com/app:1", Image: "example.com/app:2"},
+				},
+			},
+		},
 	}}
 
 	for _, test := range tests {
@@ -53,6 +205,16 @@ func TestAdapterSpecDefault(t *testing.T) {
 		name: "empty",
 		in:   &AdapterSpec{},
 		want: &AdapterSpec{},
+	}, {
+		name: "update policy",
+		in: &AdapterSpec{
+			UpdatePolicy: &AdapterUpdatePolicy{},
+		},
+		want: &AdapterSpec{
+			UpdatePolicy: &AdapterUpdatePolicy{
+				Approval: AdapterApprovalAutomatic,
+			},
+		},
 	}}
 
 	for _, test := range tests {
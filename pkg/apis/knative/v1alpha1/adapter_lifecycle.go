@@ -17,13 +17,30 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
 	apis "github.com/projectriff/system/pkg/apis"
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+	servingv1 "github.com/projectriff/system/pkg/apis/thirdparty/knative/serving/v1"
 )
 
 const (
 	AdapterConditionReady                          = apis.ConditionReady
 	AdapterConditionBuildReady  apis.ConditionType = "BuildReady"
 	AdapterConditionTargetFound apis.ConditionType = "TargetFound"
+
+	// AdapterConditionUpdatesApplied reports whether images are waiting to be
+	// applied to the target. The condition is informational and only present
+	// when updates are gated by an update policy or a revert.
+	AdapterConditionUpdatesApplied apis.ConditionType = "UpdatesApplied"
+
+	// AdapterConditionTargetReady reports whether the latest revision of a
+	// Knative Service or Configuration target is ready. The condition is
+	// informational and only present for Knative targets.
+	AdapterConditionTargetReady apis.ConditionType = "TargetReady"
 )
 
 var adapterCondSet = apis.NewLivingConditionSet(
@@ -51,6 +68,19 @@ func (as *AdapterStatus) InitializeConditions() {
 	adapterCondSet.Manage(as).InitializeConditions()
 }
 
+// PropagateBuildStatus resolves the image to roll out from a build resource,
+// honoring the pin. The current image is retained when the pin does not match
+// a build.
+func (as *AdapterStatus) PropagateBuildStatus(pin *buildv1alpha1.BuildPin, bs *buildv1alpha1.BuildStatus) {
+	if !as.ResolvedBuildStatus.PropagateBuildStatus(pin, bs) {
+		as.MarkBuildPinNotFound(pin)
+		return
+	}
+	if as.LatestImage != "" {
+		as.MarkBuildReady()
+	}
+}
+
 func (as *AdapterStatus) MarkBuildNotFound(kind, name string) {
 	adapterCondSet.Manage(as).MarkFalse(AdapterConditionBuildReady, "NotFound",
 		"The %s %q was not found.", kind, name)
@@ -61,6 +91,11 @@ func (as *AdapterStatus) MarkBuildLatestImageMissing(kind, name string) {
 		"The %s %q is missing the latest image.", kind, name)
 }
 
+func (as *AdapterStatus) MarkBuildPinNotFound(pin *buildv1alpha1.BuildPin) {
+	adapterCondSet.Manage(as).MarkFalse(AdapterConditionBuildReady, "PinNotFound",
+		"The pinned %s does not match a build.", pin)
+}
+
 func (as *AdapterStatus) MarkBuildReady() {
 	adapterCondSet.Manage(as).MarkTrue(AdapterConditionBuildReady)
 }
@@ -75,6 +110,138 @@ func (as *AdapterStatus) MarkTargetInvalid(kind, name string, err error) {
 		"The %s %q errored: %s", kind, name, err)
 }
 
+func (as *AdapterStatus) MarkTargetKindNotAllowed(kind string) {
+	adapterCondSet.Manage(as).MarkFalse(AdapterConditionTargetFound, "NotAllowed",
+		"The %s kind is not an allowed target.", kind)
+}
+
 func (as *AdapterStatus) MarkTargetFound() {
 	adapterCondSet.Manage(as).MarkTrue(AdapterConditionTargetFound)
 }
+
+func (as *AdapterStatus) MarkUpdatesApplied() {
+	as.PendingImage = ""
+	// set directly, marking an informational condition may overwrite the
+	// reason of the ready condition
+	adapterCondSet.Manage(as).SetCondition(apis.Condition{
+		Type:     AdapterConditionUpdatesApplied,
+		Status:   corev1.ConditionTrue,
+		Severity: apis.ConditionSeverityInfo,
+	})
+}
+
+func (as *AdapterStatus) MarkUpdateAwaitingApproval(image string) {
+	as.PendingImage = image
+	// set directly, marking an informational condition may overwrite the
+	// reason of the ready condition
+	adapterCondSet.Manage(as).SetCondition(apis.Condition{
+		Type:     AdapterConditionUpdatesApplied,
+		Status:   corev1.ConditionUnknown,
+		Severity: apis.ConditionSeverityInfo,
+		Reason:   "AwaitingApproval",
+		Message:  fmt.Sprintf("Image %q is awaiting approval.", image),
+	})
+}
+
+func (as *AdapterStatus) MarkUpdateAwaitingMaintenanceWindow(image string, next time.Time) {
+	as.PendingImage = image
+	message := fmt.Sprintf("Image %q is awaiting a maintenance window.", image)
+	if !next.IsZero() {
+		message = fmt.Sprintf("Image %q is awaiting the maintenance window opening at %s.", image, next.UTC().Format(time.RFC3339))
+	}
+	// set directly, marking an informational condition may overwrite the
+	// reason of the ready condition
+	adapterCondSet.Manage(as).SetCondition(apis.Condition{
+		Type:     AdapterConditionUpdatesApplied,
+		Status:   corev1.ConditionUnknown,
+		Severity: apis.ConditionSeverityInfo,
+		Reason:   "AwaitingMaintenanceWindow",
+		Message:  message,
+	})
+}
+
+func (as *AdapterStatus) MarkUpdateReverted(image string) {
+	as.PendingImage = ""
+	// set directly, marking an informational condition may overwrite the
+	// reason of the ready condition
+	adapterCondSet.Manage(as).SetCondition(apis.Condition{
+		Type:     AdapterConditionUpdatesApplied,
+		Status:   corev1.ConditionTrue,
+		Severity: apis.ConditionSeverityInfo,
+		Reason:   "Reverted",
+		Message:  fmt.Sprintf("Image %q was reverted.", image),
+	})
+}
+
+func (as *AdapterStatus) MarkUpdateRevertUnknown(image string) {
+	// set directly, marking an informational condition may overwrite the
+	// reason of the ready condition
+	adapterCondSet.Manage(as).SetCondition(apis.Condition{
+		Type:     AdapterConditionUpdatesApplied,
+		Status:   corev1.ConditionFalse,
+		Severity: apis.ConditionSeverityInfo,
+		Reason:   "RevertUnknown",
+		Message:  fmt.Sprintf("Image %q was not applied by the adapter and cannot be reverted.", image),
+	})
+}
+
+func (as *AdapterStatus) MarkUpdatesNotGated() {
+	as.PendingImage = ""
+	_ = adapterCondSet.Manage(as).ClearCondition(AdapterConditionUpdatesApplied)
+}
+
+// PropagateTargetRevisionStatus follows the revisions of a Knative Service or
+// Configuration target. The target is ready once the latest revision created
+// for the target's current generation is ready.
+func (as *AdapterStatus) PropagateTargetRevisionStatus(kind, name string, generation int64, ts apis.ResourceStatus, fields *servingv1.ConfigurationStatusFields) {
+	if ts.GetObservedGeneration() < generation || fields.LatestCreatedRevisionName == "" {
+		as.MarkTargetReconciling(kind, name)
+		return
+	}
+	if fields.LatestReadyRevisionName == fields.LatestCreatedRevisionName {
+		as.TargetRevisionName = fields.LatestReadyRevisionName
+		// set directly, marking an informational condition may overwrite the
+		// reason of the ready condition
+		adapterCondSet.Manage(as).SetCondition(apis.Condition{
+			Type:     AdapterConditionTargetReady,
+			Status:   corev1.ConditionTrue,
+			Severity: apis.ConditionSeverityInfo,
+		})
+		return
+	}
+	as.TargetRevisionName = ""
+	cond := apis.Condition{
+		Type:     AdapterConditionTargetReady,
+		Status:   corev1.ConditionUnknown,
+		Severity: apis.ConditionSeverityInfo,
+		Reason:   "RevisionNotReady",
+		Message:  fmt.Sprintf("The revision %q is not ready.", fields.LatestCreatedRevisionName),
+	}
+	if tc := ts.GetCondition(ts.GetReadyConditionType()); tc != nil && tc.IsFalse() {
+		// the latest revision failed
+		cond.Status = corev1.ConditionFalse
+		cond.Reason = tc.Reason
+		cond.Message = tc.Message
+	}
+	// set directly, marking an informational condition may overwrite the
+	// reason of the ready condition
+	adapterCondSet.Manage(as).SetCondition(cond)
+}
+
+func (as *AdapterStatus) MarkTargetReconciling(kind, name string) {
+	as.TargetRevisionName = ""
+	// set directly, marking an informational condition may overwrite the
+	// reason of the ready condition
+	adapterCondSet.Manage(as).SetCondition(apis.Condition{
+		Type:     AdapterConditionTargetReady,
+		Status:   corev1.ConditionUnknown,
+		Severity: apis.ConditionSeverityInfo,
+		Reason:   "Reconciling",
+		Message:  fmt.Sprintf("The %s %q is reconciling.", kind, name),
+	})
+}
+
+func (as *AdapterStatus) MarkTargetRevisionsNotUsed() {
+	as.TargetRevisionName = ""
+	_ = adapterCondSet.Manage(as).ClearCondition(AdapterConditionTargetReady)
+}
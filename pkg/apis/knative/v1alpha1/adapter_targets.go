@@ -0,0 +1,122 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/projectriff/system/pkg/fieldpath"
+	"github.com/projectriff/system/pkg/refs"
+)
+
+// AdapterTargetKind is a kind of workload adapters may target by reference.
+// +kubebuilder:object:generate=false
+type AdapterTargetKind struct {
+	schema.GroupVersionKind
+
+	// PodTemplatePath is the JSONPath to the pod template of the workload.
+	PodTemplatePath string
+}
+
+// DefaultAdapterTargetKinds are the workload kinds adapters may target unless
+// the runtime is configured otherwise.
+var DefaultAdapterTargetKinds = []AdapterTargetKind{
+	{GroupVersionKind: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "DaemonSet"}, PodTemplatePath: ".spec.template"},
+	{GroupVersionKind: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, PodTemplatePath: ".spec.template"},
+	{GroupVersionKind: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"}, PodTemplatePath: ".spec.template"},
+	{GroupVersionKind: schema.GroupVersionKind{Group: "batch", Version: "v1beta1", Kind: "CronJob"}, PodTemplatePath: ".spec.jobTemplate.spec.template"},
+	{GroupVersionKind: schema.GroupVersionKind{Group: "argoproj.io", Version: "v1alpha1", Kind: "Rollout"}, PodTemplatePath: ".spec.template"},
+}
+
+// NewAdapterTargetKind describes a workload kind at the version served by
+// the cluster. The pod template of default kinds is known, other kinds are
+// expected to define their pod template at `.spec.template`.
+func NewAdapterTargetKind(gvk schema.GroupVersionKind) AdapterTargetKind {
+	for _, kind := range DefaultAdapterTargetKinds {
+		if kind.GroupKind() == gvk.GroupKind() {
+			return AdapterTargetKind{GroupVersionKind: gvk, PodTemplatePath: kind.PodTemplatePath}
+		}
+	}
+	return AdapterTargetKind{GroupVersionKind: gvk, PodTemplatePath: ".spec.template"}
+}
+
+// ImagePath resolves the path to the image field of the target.
+func (k AdapterTargetKind) ImagePath(target *AdapterTarget) (fieldpath.Path, error) {
+	if target.ImagePath != "" {
+		return fieldpath.Parse(target.ImagePath)
+	}
+	container := "[0]"
+	if target.ContainerName != "" {
+		container = fmt.Sprintf("[?(@.name==%q)]", target.ContainerName)
+	}
+	return fieldpath.Parse(fmt.Sprintf("%s.spec.containers%s.image", k.PodTemplatePath, container))
+}
+
+var allowedAdapterTargetKinds atomic.Value
+
+// AllowedAdapterTargetKinds are the workload kinds adapters may target.
+func AllowedAdapterTargetKinds() []AdapterTargetKind {
+	if kinds, ok := allowedAdapterTargetKinds.Load().([]AdapterTargetKind); ok {
+		return kinds
+	}
+	return DefaultAdapterTargetKinds
+}
+
+// SetAllowedAdapterTargetKinds restricts the workload kinds adapters may
+// target, nil restores the default kinds.
+func SetAllowedAdapterTargetKinds(kinds []AdapterTargetKind) {
+	if kinds == nil {
+		kinds = DefaultAdapterTargetKinds
+	}
+	allowedAdapterTargetKinds.Store(kinds)
+}
+
+// LookupAdapterTargetKind finds the kind of a target reference in kinds.
+func LookupAdapterTargetKind(kinds []AdapterTargetKind, ref *refs.TypedLocalObjectReference) (AdapterTargetKind, bool) {
+	gk := refGroupKind(ref)
+	for _, kind := range kinds {
+		if kind.GroupKind() == gk {
+			return kind, true
+		}
+	}
+	return AdapterTargetKind{}, false
+}
+
+func hasAdapterTargetKind(kinds []AdapterTargetKind, ref *refs.TypedLocalObjectReference) bool {
+	_, ok := LookupAdapterTargetKind(kinds, ref)
+	return ok
+}
+
+func refGroupKind(ref *refs.TypedLocalObjectReference) schema.GroupKind {
+	gk := schema.GroupKind{Kind: ref.Kind}
+	if ref.APIGroup != nil {
+		gk.Group = *ref.APIGroup
+	}
+	return gk
+}
+
+func adapterTargetKindNames(kinds []AdapterTargetKind) string {
+	names := make([]string, len(kinds))
+	for i, kind := range kinds {
+		names[i] = kind.GroupKind().String()
+	}
+	return strings.Join(names, ", ")
+}
@@ -17,10 +17,15 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"time"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	apis "github.com/projectriff/system/pkg/apis"
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+	"github.com/projectriff/system/pkg/cron"
+	"github.com/projectriff/system/pkg/refs"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -28,6 +33,27 @@ import (
 
 var (
 	AdapterLabelKey = GroupVersion.Group + "/adapter"
+
+	// AdapterApproveAnnotationKey approves an image to be applied to the
+	// target of an adapter with manual approval. The value is the image to
+	// approve, or "true" for the pending image. The annotation is removed
+	// once applied.
+	AdapterApproveAnnotationKey = GroupVersion.Group + "/approve"
+
+	// AdapterApprovedByAnnotationKey names who approved or reverted an image,
+	// recorded with the update. The annotation is removed once applied.
+	AdapterApprovedByAnnotationKey = GroupVersion.Group + "/approved-by"
+
+	// AdapterRevertAnnotationKey reverts an image applied to the target of an
+	// adapter. The value is the image to revert, or "true" for the most
+	// recently applied image. The annotation is removed once applied.
+	AdapterRevertAnnotationKey = GroupVersion.Group + "/revert"
+)
+
+const (
+	// AdapterUpdateHistoryLimit is the number of updates recorded in an
+	// adapter's status.
+	AdapterUpdateHistoryLimit = 10
 )
 
 var (
@@ -44,8 +70,102 @@ type AdapterSpec struct {
 	// handler.
 	Build Build `json:"build"`
 
-	// Target Knative resource
+	// Target resource whose image is kept up to date
 	Target AdapterTarget `json:"target"`
+
+	// UpdatePolicy gates when new images are applied to the target. By
+	// default, images are applied as soon as they are available.
+	// +optional
+	UpdatePolicy *AdapterUpdatePolicy `json:"updatePolicy,omitempty"`
+
+	// RevertedImage is an image applied to the target that is reverted. The
+	// target is returned to the image the update replaced, and the reverted
+	// image is not applied again. Typically set with the
+	// knative.projectriff.io/revert annotation.
+	// +optional
+	RevertedImage string `json:"revertedImage,omitempty"`
+
+	// RevertedBy names who reverted the image. Typically set with the
+	// knative.projectriff.io/approved-by annotation.
+	// +optional
+	RevertedBy string `json:"revertedBy,omitempty"`
+}
+
+type AdapterApproval string
+
+const (
+	AdapterApprovalAutomatic AdapterApproval = "Automatic"
+	AdapterApprovalManual    AdapterApproval = "Manual"
+)
+
+type AdapterUpdatePolicy struct {
+	// Approval of new images, either Automatic or Manual, defaults to
+	// Automatic. Manually approved images are applied once they match the
+	// approved image.
+	// +optional
+	Approval AdapterApproval `json:"approval,omitempty"`
+
+	// ApprovedImage is the image approved to be applied to the target.
+	// Typically set with the knative.projectriff.io/approve annotation.
+	// +optional
+	ApprovedImage string `json:"approvedImage,omitempty"`
+
+	// ApprovedBy names who approved the image. Typically set with the
+	// knative.projectriff.io/approved-by annotation.
+	// +optional
+	ApprovedBy string `json:"approvedBy,omitempty"`
+
+	// MaintenanceWindows restrict when images are applied. Outside of every
+	// window, images wait for the next window to open. Without windows,
+	// images are applied at any time.
+	// +optional
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+}
+
+// MaintenanceWindowOpen is true when images may be applied at the time.
+func (p *AdapterUpdatePolicy) MaintenanceWindowOpen(t time.Time) bool {
+	if len(p.MaintenanceWindows) == 0 {
+		return true
+	}
+	for _, w := range p.MaintenanceWindows {
+		schedule, err := cron.Parse(w.Schedule)
+		if err != nil {
+			// rejected by validation
+			continue
+		}
+		window := cron.Window{Schedule: schedule, Duration: w.Duration.Duration}
+		if window.Open(t.UTC()) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextMaintenanceWindow returns when the next maintenance window opens after
+// the time, or the zero time if no window will open.
+func (p *AdapterUpdatePolicy) NextMaintenanceWindow(t time.Time) time.Time {
+	var next time.Time
+	for _, w := range p.MaintenanceWindows {
+		schedule, err := cron.Parse(w.Schedule)
+		if err != nil {
+			// rejected by validation
+			continue
+		}
+		window := cron.Window{Schedule: schedule, Duration: w.Duration.Duration}
+		if open := window.NextOpen(t.UTC()); !open.IsZero() && (next.IsZero() || open.Before(next)) {
+			next = open
+		}
+	}
+	return next
+}
+
+type MaintenanceWindow struct {
+	// Schedule is a cron expression, evaluated in UTC, for when the window
+	// opens. For example, "0 2 * * 6" opens the window every Saturday at 2am.
+	Schedule string `json:"schedule"`
+
+	// Duration the window stays open.
+	Duration metav1.Duration `json:"duration"`
 }
 
 type AdapterTarget struct {
@@ -54,6 +174,23 @@ type AdapterTarget struct {
 
 	// ConfigurationRef references a Knative Configuration in this namespace.
 	ConfigurationRef string `json:"configurationRef,omitempty"`
+
+	// Ref references a workload in this namespace. The kind of the workload
+	// must be allowed by the knative runtime.
+	Ref *refs.TypedLocalObjectReference `json:"ref,omitempty"`
+
+	// ContainerName is the name of the container in the workload's pod
+	// template to update, defaults to the first container. Requires ref.
+	// +optional
+	ContainerName string `json:"containerName,omitempty"`
+
+	// ImagePath is a JSONPath to the image field of the workload, for
+	// images outside of the pod template. Fields, array indexes and equality
+	// filters are supported, for example
+	// `.spec.containers[?(@.name=="app")].image`. Mutually exclusive with
+	// containerName. Requires ref.
+	// +optional
+	ImagePath string `json:"imagePath,omitempty"`
 }
 
 // AdapterStatus defines the observed state of Adapter
@@ -63,9 +200,96 @@ type AdapterStatus struct {
 
 	apis.Status `json:",inline"`
 
-	// LatestImage is the most recent image resolved from the build and applied
-	// to the target
-	LatestImage string `json:"latestImage,omitempty"`
+	// ResolvedBuildStatus holds the image resolved from the build, the latest
+	// image is applied to the target
+	buildv1alpha1.ResolvedBuildStatus `json:",inline"`
+
+	// PendingImage is the latest image while it waits for approval or a
+	// maintenance window before being applied to the target.
+	PendingImage string `json:"pendingImage,omitempty"`
+
+	// Updates are the images most recently applied to the target, newest
+	// first.
+	Updates []AdapterUpdate `json:"updates,omitempty"`
+
+	// TargetRevisionName is the ready revision of a Knative Service or
+	// Configuration target running the image applied to the target.
+	TargetRevisionName string `json:"targetRevisionName,omitempty"`
+}
+
+// AdapterUpdate records an image applied to the target of an adapter.
+type AdapterUpdate struct {
+	// PreviousImage is the image the update replaced.
+	PreviousImage string `json:"previousImage,omitempty"`
+
+	// Image applied to the target.
+	Image string `json:"image"`
+
+	// Time the update was applied.
+	Time apis.VolatileTime `json:"time,omitempty"`
+
+	// Approver named by the approval or revert, if any.
+	Approver string `json:"approver,omitempty"`
+
+	// Revert is true when the update reverted a previous update.
+	Revert bool `json:"revert,omitempty"`
+}
+
+// FindUpdate returns the most recent update that applied the image, or nil.
+func (as *AdapterStatus) FindUpdate(image string) *AdapterUpdate {
+	for i := range as.Updates {
+		if as.Updates[i].Image == image {
+			return &as.Updates[i]
+		}
+	}
+	return nil
+}
+
+// RecordUpdate records an update as the most recent, keeping the history
+// within AdapterUpdateHistoryLimit.
+func (as *AdapterStatus) RecordUpdate(update AdapterUpdate) {
+	as.Updates = append([]AdapterUpdate{update}, as.Updates...)
+	if len(as.Updates) > AdapterUpdateHistoryLimit {
+		as.Updates = as.Updates[:AdapterUpdateHistoryLimit]
+	}
+}
+
+// ApproveAdapterImage returns the image named by the resource's approve
+// annotation, removing the annotation once consumed. The annotation value is
+// the image to approve, or "true" for the pending image. The currently
+// approved image is returned when there is nothing to approve.
+func ApproveAdapterImage(annotations map[string]string, status *AdapterStatus, approved string) string {
+	value, ok := annotations[AdapterApproveAnnotationKey]
+	if !ok {
+		return approved
+	}
+	if value == "true" {
+		if status.PendingImage == "" {
+			return approved
+		}
+		value = status.PendingImage
+	}
+	delete(annotations, AdapterApproveAnnotationKey)
+	return value
+}
+
+// RevertAdapterImage returns the image named by the resource's revert
+// annotation, removing the annotation once consumed. The annotation value is
+// the image to revert, or "true" for the most recently applied image. The
+// currently reverted image is returned when there is nothing to revert.
+func RevertAdapterImage(annotations map[string]string, status *AdapterStatus, reverted string) string {
+	value, ok := annotations[AdapterRevertAnnotationKey]
+	if !ok {
+		return reverted
+	}
+	if value == "true" {
+		if len(status.Updates) == 0 || status.Updates[0].Revert {
+			return reverted
+		}
+		value = status.Updates[0].Image
+	}
+	delete(annotations, AdapterRevertAnnotationKey)
+	return value
 }
 
 // +kubebuilder:object:root=true
@@ -17,10 +17,15 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
+
 	"k8s.io/apimachinery/pkg/api/equality"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+	"github.com/projectriff/system/pkg/cron"
+	"github.com/projectriff/system/pkg/fieldpath"
 	"github.com/projectriff/system/pkg/validation"
 )
 
@@ -50,6 +55,10 @@ func (r *Adapter) ValidateDelete() error {
 func (r *Adapter) Validate() validation.FieldErrors {
 	errs := validation.FieldErrors{}
 
+	errs = errs.Also(buildv1alpha1.ValidatePromoteAnnotation(r.Annotations, true).ViaField("metadata"))
+	if _, ok := r.Annotations[AdapterApproveAnnotationKey]; ok && (r.Spec.UpdatePolicy == nil || r.Spec.UpdatePolicy.Approval != AdapterApprovalManual) {
+		errs = errs.Also(validation.ErrDisallowedFields("annotations["+AdapterApproveAnnotationKey+"]", "approval requires manual approval").ViaField("metadata"))
+	}
 	errs = errs.Also(r.Spec.Validate().ViaField("spec"))
 
 	return errs
@@ -64,6 +73,50 @@ func (s AdapterSpec) Validate() validation.FieldErrors {
 
 	errs = errs.Also(s.Build.Validate().ViaField("build"))
 	errs = errs.Also(s.Target.Validate().ViaField("target"))
+	if s.UpdatePolicy != nil {
+		errs = errs.Also(s.UpdatePolicy.Validate().ViaField("updatePolicy"))
+	}
+	if s.RevertedBy != "" && s.RevertedImage == "" {
+		errs = errs.Also(validation.ErrDisallowedFields("revertedBy", "requires revertedImage"))
+	}
+
+	return errs
+}
+
+func (p *AdapterUpdatePolicy) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	switch p.Approval {
+	case AdapterApprovalAutomatic, AdapterApprovalManual:
+	default:
+		errs = errs.Also(validation.ErrInvalidValue(p.Approval, "approval"))
+	}
+	if p.Approval != AdapterApprovalManual {
+		if p.ApprovedImage != "" {
+			errs = errs.Also(validation.ErrDisallowedFields("approvedImage", "requires manual approval"))
+		}
+		if p.ApprovedBy != "" {
+			errs = errs.Also(validation.ErrDisallowedFields("approvedBy", "requires manual approval"))
+		}
+	}
+	for i, window := range p.MaintenanceWindows {
+		errs = errs.Also(window.Validate().ViaFieldIndex("maintenanceWindows", i))
+	}
+
+	return errs
+}
+
+func (w MaintenanceWindow) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if w.Schedule == "" {
+		errs = errs.Also(validation.ErrMissingField("schedule"))
+	} else if _, err := cron.Parse(w.Schedule); err != nil {
+		errs = errs.Also(validation.ErrInvalidValue(w.Schedule, "schedule"))
+	}
+	if w.Duration.Duration <= 0 {
+		errs = errs.Also(validation.ErrInvalidValue(w.Duration.Duration.String(), "duration"))
+	}
 
 	return errs
 }
@@ -89,11 +142,43 @@ func (t *AdapterTarget) Validate() validation.FieldErrors {
 		unused = append(unused, "configurationRef")
 	}
 
+	if t.Ref != nil {
+		used = append(used, "ref")
+	} else {
+		unused = append(unused, "ref")
+	}
+
 	if len(used) == 0 {
 		errs = errs.Also(validation.ErrMissingOneOf(unused...))
 	} else if len(used) > 1 {
 		errs = errs.Also(validation.ErrMultipleOneOf(used...))
 	}
 
+	if t.Ref == nil {
+		if t.ContainerName != "" {
+			errs = errs.Also(validation.ErrDisallowedFields("containerName", "requires ref"))
+		}
+		if t.ImagePath != "" {
+			errs = errs.Also(validation.ErrDisallowedFields("imagePath", "requires ref"))
+		}
+		return errs
+	}
+
+	if t.Ref.Kind == "" {
+		errs = errs.Also(validation.ErrMissingField("ref.kind"))
+	} else if kinds := AllowedAdapterTargetKinds(); !hasAdapterTargetKind(kinds, t.Ref) {
+		errs = errs.Also(validation.ErrDisallowedFields("ref.kind", fmt.Sprintf("%s is not an allowed target kind, allowed kinds are: %s", refGroupKind(t.Ref), adapterTargetKindNames(kinds))))
+	}
+	if t.Ref.Name == "" {
+		errs = errs.Also(validation.ErrMissingField("ref.name"))
+	}
+	if t.ContainerName != "" && t.ImagePath != "" {
+		errs = errs.Also(validation.ErrMultipleOneOf("containerName", "imagePath"))
+	} else if t.ImagePath != "" {
+		if _, err := fieldpath.Parse(t.ImagePath); err != nil {
+			errs = errs.Also(validation.ErrInvalidValue(t.ImagePath, "imagePath"))
+		}
+	}
+
 	return errs
 }
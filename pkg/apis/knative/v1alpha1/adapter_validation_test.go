@@ -18,9 +18,13 @@ package v1alpha1
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
+	"github.com/projectriff/system/pkg/refs"
 	"github.com/projectriff/system/pkg/validation"
 )
 
@@ -46,6 +50,45 @@ func TestValidateAdapter(t *testing.T) {
 			},
 		},
 		expected: validation.FieldErrors{},
+	}, {
+		name: "approve annotation",
+		target: &Adapter{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					AdapterApproveAnnotationKey: "true",
+				},
+			},
+			Spec: AdapterSpec{
+				Build: Build{
+					FunctionRef: "my-function",
+				},
+				Target: AdapterTarget{
+					ServiceRef: "my-service",
+				},
+				UpdatePolicy: &AdapterUpdatePolicy{
+					Approval: AdapterApprovalManual,
+				},
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "approve annotation without manual approval",
+		target: &Adapter{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					AdapterApproveAnnotationKey: "true",
+				},
+			},
+			Spec: AdapterSpec{
+				Build: Build{
+					FunctionRef: "my-function",
+				},
+				Target: AdapterTarget{
+					ServiceRef: "my-service",
+				},
+			},
+		},
+		expected: validation.ErrDisallowedFields("metadata.annotations["+AdapterApproveAnnotationKey+"]", "approval requires manual approval"),
 	}} {
 		t.Run(c.name, func(t *testing.T) {
 			actual := c.target.Validate()
@@ -94,6 +137,43 @@ func TestValidateAdapterSpec(t *testing.T) {
 			Target: AdapterTarget{},
 		},
 		expected: validation.ErrMissingField("target"),
+	}, {
+		name: "reverted image",
+		target: &AdapterSpec{
+			Build: Build{
+				FunctionRef: "my-function",
+			},
+			Target: AdapterTarget{
+				ServiceRef: "my-service",
+			},
+			RevertedImage: "example.com/app:2",
+			RevertedBy:    "jane",
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "reverted by without reverted image",
+		target: &AdapterSpec{
+			Build: Build{
+				FunctionRef: "my-function",
+			},
+			Target: AdapterTarget{
+				ServiceRef: "my-service",
+			},
+			RevertedBy: "jane",
+		},
+		expected: validation.ErrDisallowedFields("revertedBy", "requires revertedImage"),
+	}, {
+		name: "invalid update policy",
+		target: &AdapterSpec{
+			Build: Build{
+				FunctionRef: "my-function",
+			},
+			Target: AdapterTarget{
+				ServiceRef: "my-service",
+			},
+			UpdatePolicy: &AdapterUpdatePolicy{},
+		},
+		expected: validation.ErrInvalidValue(AdapterApproval(""), "updatePolicy.approval"),
 	}} {
 		t.Run(c.name, func(t *testing.T) {
 			actual := c.target.Validate()
@@ -103,3 +183,175 @@ func TestValidateAdapterSpec(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateAdapterUpdatePolicy(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		target   *AdapterUpdatePolicy
+		expected validation.FieldErrors
+	}{{
+		name: "automatic",
+		target: &AdapterUpdatePolicy{
+			Approval: AdapterApprovalAutomatic,
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "manual",
+		target: &AdapterUpdatePolicy{
+			Approval:      AdapterApprovalManual,
+			ApprovedImage: "example.com/app:2",
+			ApprovedBy:    "jane",
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid approval",
+		target: &AdapterUpdatePolicy{
+			Approval: "Sometimes",
+		},
+		expected: validation.ErrInvalidValue(AdapterApproval("Sometimes"), "approval"),
+	}, {
+		name: "approved image without manual approval",
+		target: &AdapterUpdatePolicy{
+			Approval:      AdapterApprovalAutomatic,
+			ApprovedImage: "example.com/app:2",
+			ApprovedBy:    "jane",
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrDisallowedFields("approvedImage", "requires manual approval"),
+			validation.ErrDisallowedFields("approvedBy", "requires manual approval"),
+		),
+	}, {
+		name: "maintenance windows",
+		target: &AdapterUpdatePolicy{
+			Approval: AdapterApprovalAutomatic,
+			MaintenanceWindows: []MaintenanceWindow{
+				{Schedule: "0 2 * * 6", Duration: metav1.Duration{Duration: 2 * time.Hour}},
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid maintenance windows",
+		target: &AdapterUpdatePolicy{
+			Approval: AdapterApprovalAutomatic,
+			MaintenanceWindows: []MaintenanceWindow{
+				{Duration: metav1.Duration{Duration: time.Hour}},
+				{Schedule: "0 2 * *", Duration: metav1.Duration{Duration: time.Hour}},
+				{Schedule: "0 2 * * 6"},
+			},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrMissingField("schedule").ViaFieldIndex("maintenanceWindows", 0),
+			validation.ErrInvalidValue("0 2 * *", "schedule").ViaFieldIndex("maintenanceWindows", 1),
+			validation.ErrInvalidValue("0s", "duration").ViaFieldIndex("maintenanceWindows", 2),
+		),
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			actual := c.target.Validate()
+			if diff := cmp.Diff(c.expected, actual); diff != "" {
+				t.Errorf("validateAdapterUpdatePolicy(%s) (-expected, +actual) = %v", c.name, diff)
+			}
+		})
+	}
+}
+
+func TestValidateAdapterTarget(t *testing.T) {
+	apps := "apps"
+	example := "example.com"
+
+	for _, c := range []struct {
+		name     string
+		kinds    []AdapterTargetKind
+		target   *AdapterTarget
+		expected validation.FieldErrors
+	}{{
+		name:     "empty",
+		target:   &AdapterTarget{},
+		expected: validation.ErrMissingField(validation.CurrentField),
+	}, {
+		name: "valid, service",
+		target: &AdapterTarget{
+			ServiceRef: "my-service",
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "valid, ref",
+		target: &AdapterTarget{
+			Ref: &refs.TypedLocalObjectReference{APIGroup: &apps, Kind: "Deployment", Name: "my-deployment"},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "valid, ref with container name",
+		target: &AdapterTarget{
+			Ref:           &refs.TypedLocalObjectReference{APIGroup: &apps, Kind: "StatefulSet", Name: "my-statefulset"},
+			ContainerName: "app",
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "valid, ref with image path",
+		target: &AdapterTarget{
+			Ref:       &refs.TypedLocalObjectReference{APIGroup: &apps, Kind: "Deployment", Name: "my-deployment"},
+			ImagePath: `.spec.template.spec.initContainers[?(@.name=="init")].image`,
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name:  "valid, configured kind",
+		kinds: []AdapterTargetKind{NewAdapterTargetKind(schema.GroupVersionKind{Group: example, Version: "v1", Kind: "Workload"})},
+		target: &AdapterTarget{
+			Ref: &refs.TypedLocalObjectReference{APIGroup: &example, Kind: "Workload", Name: "my-workload"},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid, kind not allowed",
+		target: &AdapterTarget{
+			Ref: &refs.TypedLocalObjectReference{Kind: "Pod", Name: "my-pod"},
+		},
+		expected: validation.ErrDisallowedFields("ref.kind", "Pod is not an allowed target kind, allowed kinds are: DaemonSet.apps, Deployment.apps, StatefulSet.apps, CronJob.batch, Rollout.argoproj.io"),
+	}, {
+		name: "invalid, multiple targets",
+		target: &AdapterTarget{
+			ServiceRef: "my-service",
+			Ref:        &refs.TypedLocalObjectReference{APIGroup: &apps, Kind: "Deployment", Name: "my-deployment"},
+		},
+		expected: validation.ErrMultipleOneOf("serviceRef", "ref"),
+	}, {
+		name: "invalid, incomplete ref",
+		target: &AdapterTarget{
+			Ref:           &refs.TypedLocalObjectReference{},
+			ContainerName: "app",
+			ImagePath:     ".spec.image",
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrMissingField("ref.kind"),
+			validation.ErrMissingField("ref.name"),
+			validation.ErrMultipleOneOf("containerName", "imagePath"),
+		),
+	}, {
+		name: "invalid, image path",
+		target: &AdapterTarget{
+			Ref:       &refs.TypedLocalObjectReference{APIGroup: &apps, Kind: "Deployment", Name: "my-deployment"},
+			ImagePath: ".spec.containers[*].image",
+		},
+		expected: validation.ErrInvalidValue(".spec.containers[*].image", "imagePath"),
+	}, {
+		name: "invalid, container name without ref",
+		target: &AdapterTarget{
+			ConfigurationRef: "my-configuration",
+			ContainerName:    "app",
+			ImagePath:        ".spec.image",
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrDisallowedFields("containerName", "requires ref"),
+			validation.ErrDisallowedFields("imagePath", "requires ref"),
+		),
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			SetAllowedAdapterTargetKinds(c.kinds)
+			defer SetAllowedAdapterTargetKinds(nil)
+
+			actual := c.target.Validate()
+			if diff := cmp.Diff(c.expected, actual); diff != "" {
+				t.Errorf("validateAdapterTarget(%s) (-expected, +actual) = %v", c.name, diff)
+			}
+		})
+	}
+}
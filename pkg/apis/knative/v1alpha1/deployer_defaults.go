@@ -17,8 +17,13 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 )
 
 // +kubebuilder:webhook:path=/mutate-knative-projectriff-io-v1alpha1-deployer,mutating=true,failurePolicy=fail,groups=knative.projectriff.io,resources=deployers,verbs=create;update,versions=v1alpha1,name=deployers.knative.projectriff.io
@@ -28,6 +33,9 @@ var _ webhook.Defaulter = &Deployer{}
 // Default implements webhook.Defaulter so a webhook will be registered for the type
 func (r *Deployer) Default() {
 	r.Spec.Default()
+	if r.Spec.Build != nil {
+		r.Spec.Build.Pin = buildv1alpha1.PromoteBuild(r.Annotations, r.Spec.Build.Pin)
+	}
 }
 
 func (s *DeployerSpec) Default() {
@@ -46,4 +54,13 @@ func (s *DeployerSpec) Default() {
 	if s.IngressPolicy == "" {
 		s.IngressPolicy = IngressPolicyClusterLocal
 	}
+	if s.Progression != nil {
+		s.Progression.Default()
+	}
+}
+
+func (p *TrafficProgression) Default() {
+	if p.Interval == nil {
+		p.Interval = &metav1.Duration{Duration: time.Minute}
+	}
 }
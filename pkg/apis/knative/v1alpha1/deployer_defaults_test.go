@@ -18,6 +18,7 @@ package v1alpha1
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
@@ -83,6 +84,31 @@ func TestDeployerSpecDefault(t *testing.T) {
 			},
 			IngressPolicy: IngressPolicyClusterLocal,
 		},
+	}, {
+		name: "progression interval",
+		in: &DeployerSpec{
+			Progression: &TrafficProgression{
+				Steps: []int64{10, 50},
+			},
+		},
+		want: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{},
+					Labels:      map[string]string{},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyClusterLocal,
+			Progression: &TrafficProgression{
+				Steps:    []int64{10, 50},
+				Interval: &metav1.Duration{Duration: time.Minute},
+			},
+		},
 	}}
 
 	for _, test := range tests {
@@ -17,16 +17,40 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
 
 	apis "github.com/projectriff/system/pkg/apis"
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	servingv1 "github.com/projectriff/system/pkg/apis/thirdparty/knative/serving/v1"
+	servingv1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/knative/serving/v1alpha1"
 )
 
 const (
 	DeployerConditionReady                                 = apis.ConditionReady
 	DeployerConditionConfigurationReady apis.ConditionType = "ConfigurationReady"
 	DeployerConditionRouteReady         apis.ConditionType = "RouteReady"
+
+	// DeployerConditionNetworkPolicyReady reports whether network traffic to
+	// the workload is restricted by the ingress policy. The condition is
+	// informational and only present when enforcement is enabled.
+	DeployerConditionNetworkPolicyReady apis.ConditionType = "NetworkPolicyReady"
+
+	// DeployerConditionImageAllowed reports a resolved image that is rejected
+	// by the image policy. The condition is informational and only present
+	// while the image is rejected, the previous image remains rolled out.
+	DeployerConditionImageAllowed apis.ConditionType = "ImageAllowed"
+
+	// DeployerConditionDomainMappingsReady reports whether the custom domains
+	// are mapped to the deployer. The condition is informational and only
+	// present when domains are defined.
+	DeployerConditionDomainMappingsReady apis.ConditionType = "DomainMappingsReady"
+
+	// DeployerConditionBuildReady reports a build pin that does not match a build
+	// of the build resource. The condition is informational and only present
+	// while the pin does not match, the current image remains rolled out.
+	DeployerConditionBuildReady apis.ConditionType = "BuildReady"
 )
 
 var deployerCondSet = apis.NewLivingConditionSet(
@@ -54,7 +78,32 @@ func (ds *DeployerStatus) InitializeConditions() {
 	deployerCondSet.Manage(ds).InitializeConditions()
 }
 
+// PropagateBuildStatus resolves the image to roll out from a build resource,
+// honoring the pin. The current image is retained when the pin does not match
+// a build.
+func (ds *DeployerStatus) PropagateBuildStatus(pin *buildv1alpha1.BuildPin, bs *buildv1alpha1.BuildStatus) {
+	if !ds.ResolvedBuildStatus.PropagateBuildStatus(pin, bs) {
+		ds.MarkBuildPinNotFound(pin)
+		return
+	}
+	_ = deployerCondSet.Manage(ds).ClearCondition(DeployerConditionBuildReady)
+}
+
+func (ds *DeployerStatus) MarkBuildPinNotFound(pin *buildv1alpha1.BuildPin) {
+	// set directly, marking an informational condition may overwrite the
+	// reason of the ready condition
+	deployerCondSet.Manage(ds).SetCondition(apis.Condition{
+		Type:     DeployerConditionBuildReady,
+		Status:   corev1.ConditionFalse,
+		Severity: apis.ConditionSeverityInfo,
+		Reason:   "PinNotFound",
+		Message:  fmt.Sprintf("The pinned %s does not match a build.", pin),
+	})
+}
+
 func (ds *DeployerStatus) PropagateConfigurationStatus(kcs *servingv1.ConfigurationStatus) {
+	ds.LatestReadyRevisionName = kcs.LatestReadyRevisionName
+
 	sc := kcs.GetCondition(servingv1.ConfigurationConditionReady)
 	if sc == nil {
 		return
@@ -72,6 +121,16 @@ func (ds *DeployerStatus) PropagateConfigurationStatus(kcs *servingv1.Configurat
 func (ds *DeployerStatus) PropagateRouteStatus(rs *servingv1.RouteStatus) {
 	ds.Address = rs.Address
 	ds.URL = rs.URL
+	ds.Traffic = nil
+	for _, target := range rs.Traffic {
+		ds.Traffic = append(ds.Traffic, TrafficTargetStatus{
+			Tag:            target.Tag,
+			RevisionName:   target.RevisionName,
+			LatestRevision: target.LatestRevision,
+			Percent:        target.Percent,
+			URL:            target.URL,
+		})
+	}
 
 	sc := rs.GetCondition(servingv1.RouteConditionReady)
 	if sc == nil {
@@ -90,3 +149,112 @@ func (ds *DeployerStatus) PropagateRouteStatus(rs *servingv1.RouteStatus) {
 func (ds *DeployerStatus) MarkRouteNotOwned(name string) {
 	deployerCondSet.Manage(ds).MarkFalse(DeployerConditionRouteReady, "NotOwned", "There is an existing Route %q that the Deployer does not own.", name)
 }
+
+// PropagateDomainMappingStatuses lists the mapped domains and aggregates the
+// readiness of their domain mappings, in the order the domains are defined.
+func (ds *DeployerStatus) PropagateDomainMappingStatuses(dms []servingv1alpha1.DomainMapping) {
+	ds.Domains = nil
+	cond := apis.Condition{
+		Type:     DeployerConditionDomainMappingsReady,
+		Status:   corev1.ConditionTrue,
+		Severity: apis.ConditionSeverityInfo,
+	}
+	for i := range dms {
+		dm := &dms[i]
+		ds.Domains = append(ds.Domains, DomainStatus{
+			Name: dm.Name,
+			URL:  dm.Status.URL,
+		})
+		sc := dm.Status.GetCondition(servingv1alpha1.DomainMappingConditionReady)
+		if sc == nil {
+			sc = &apis.Condition{
+				Status:  corev1.ConditionUnknown,
+				Reason:  "Reconciling",
+				Message: fmt.Sprintf("DomainMapping %q is reconciling.", dm.Name),
+			}
+		}
+		// the first failure wins over the first unknown, which wins over ready
+		if (cond.Status == corev1.ConditionTrue && sc.Status != corev1.ConditionTrue) ||
+			(cond.Status == corev1.ConditionUnknown && sc.Status == corev1.ConditionFalse) {
+			cond.Status = sc.Status
+			cond.Reason = sc.Reason
+			cond.Message = sc.Message
+		}
+	}
+	// set directly, marking an informational condition may overwrite the
+	// reason of the ready condition
+	deployerCondSet.Manage(ds).SetCondition(cond)
+}
+
+func (ds *DeployerStatus) MarkDomainMappingNotOwned(name string) {
+	// set directly, marking an informational condition may overwrite the
+	// reason of the ready condition
+	deployerCondSet.Manage(ds).SetCondition(apis.Condition{
+		Type:     DeployerConditionDomainMappingsReady,
+		Status:   corev1.ConditionFalse,
+		Severity: apis.ConditionSeverityInfo,
+		Reason:   "NotOwned",
+		Message:  fmt.Sprintf("There is an existing DomainMapping %q that the Deployer does not own.", name),
+	})
+}
+
+func (ds *DeployerStatus) MarkDomainMappingsNotSupported() {
+	ds.Domains = nil
+	// set directly, marking an informational condition may overwrite the
+	// reason of the ready condition
+	deployerCondSet.Manage(ds).SetCondition(apis.Condition{
+		Type:     DeployerConditionDomainMappingsReady,
+		Status:   corev1.ConditionFalse,
+		Severity: apis.ConditionSeverityInfo,
+		Reason:   "DomainMappingNotInstalled",
+		Message:  "Mapping domains requires Knative DomainMappings to be installed.",
+	})
+}
+
+func (ds *DeployerStatus) MarkDomainMappingsRouteMissing() {
+	ds.Domains = nil
+	// set directly, marking an informational condition may overwrite the
+	// reason of the ready condition
+	deployerCondSet.Manage(ds).SetCondition(apis.Condition{
+		Type:     DeployerConditionDomainMappingsReady,
+		Status:   corev1.ConditionUnknown,
+		Severity: apis.ConditionSeverityInfo,
+		Reason:   "RouteMissing",
+		Message:  "Domains are mapped once the Route is created.",
+	})
+}
+
+func (ds *DeployerStatus) MarkDomainMappingsNotUsed() {
+	ds.Domains = nil
+	_ = deployerCondSet.Manage(ds).ClearCondition(DeployerConditionDomainMappingsReady)
+}
+
+func (ds *DeployerStatus) MarkImageAllowed() {
+	_ = deployerCondSet.Manage(ds).ClearCondition(DeployerConditionImageAllowed)
+}
+
+func (ds *DeployerStatus) MarkImageNotAllowed(message string) {
+	// set directly, marking an informational condition may overwrite the
+	// reason of the ready condition
+	deployerCondSet.Manage(ds).SetCondition(apis.Condition{
+		Type:     DeployerConditionImageAllowed,
+		Status:   corev1.ConditionFalse,
+		Severity: apis.ConditionSeverityInfo,
+		Reason:   "ImageNotAllowed",
+		Message:  message,
+	})
+}
+
+func (ds *DeployerStatus) MarkNetworkPolicyNotUsed() {
+	_ = deployerCondSet.Manage(ds).ClearCondition(DeployerConditionNetworkPolicyReady)
+}
+
+func (ds *DeployerStatus) MarkNetworkPolicyReady() {
+	// set directly, marking an informational condition may overwrite the
+	// reason of the ready condition
+	deployerCondSet.Manage(ds).SetCondition(apis.Condition{
+		Type:     DeployerConditionNetworkPolicyReady,
+		Status:   corev1.ConditionTrue,
+		Severity: apis.ConditionSeverityInfo,
+	})
+}
@@ -17,11 +17,14 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/projectriff/system/pkg/apis"
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	"github.com/projectriff/system/pkg/refs"
 )
 
@@ -30,6 +33,7 @@ import (
 
 var (
 	DeployerLabelKey = GroupVersion.Group + "/deployer"
+	DomainLabelKey   = GroupVersion.Group + "/domain"
 )
 
 var (
@@ -59,6 +63,22 @@ type DeployerSpec struct {
 	// IngressPolicy defines whether the workload should be reachable from
 	// outside the cluster
 	IngressPolicy IngressPolicy `json:"ingressPolicy,omitempty"`
+
+	// Traffic splits requests across revisions of the deployer. Without
+	// traffic targets, all requests are sent to the latest ready revision.
+	// +optional
+	Traffic []TrafficTarget `json:"traffic,omitempty"`
+
+	// Progression gradually shifts the traffic of latest revision targets to
+	// each new revision as it becomes ready. Without a progression, new
+	// revisions receive their traffic as soon as they are ready.
+	// +optional
+	Progression *TrafficProgression `json:"progression,omitempty"`
+
+	// Domains are custom domain names mapped to the deployer, in addition to
+	// its generated URL. Requires the External ingress policy.
+	// +optional
+	Domains []Domain `json:"domains,omitempty"`
 }
 
 // IngressPolicy describes whether the container should be exposed via
@@ -72,9 +92,150 @@ const (
 	IngressPolicyExternal     IngressPolicy = "External"
 )
 
+type TrafficTarget struct {
+	// Tag exposes the target on a dedicated URL, in addition to its share of
+	// the deployer's traffic.
+	// +optional
+	Tag string `json:"tag,omitempty"`
+
+	// RevisionName sends traffic to a specific revision. Mutually exclusive
+	// with LatestRevision.
+	// +optional
+	RevisionName string `json:"revisionName,omitempty"`
+
+	// LatestRevision sends traffic to the latest ready revision. Mutually
+	// exclusive with RevisionName.
+	// +optional
+	LatestRevision *bool `json:"latestRevision,omitempty"`
+
+	// Percent of the deployer's traffic sent to the target. The percentages
+	// of all targets must add up to 100.
+	// +optional
+	Percent *int64 `json:"percent,omitempty"`
+}
+
+type Domain struct {
+	// Name is the fully qualified domain name to map.
+	Name string `json:"name"`
+
+	// SecretRef is the name of a TLS Secret, in the deployer's namespace,
+	// used to terminate TLS traffic for the domain.
+	// +optional
+	SecretRef string `json:"secretRef,omitempty"`
+}
+
+type TrafficProgression struct {
+	// Steps are the percentages of the latest revision targets' traffic sent
+	// to a new revision, in order. The new revision receives all of the
+	// traffic after the last step.
+	Steps []int64 `json:"steps"`
+
+	// Interval is how long each step is held, defaults to 1 minute. Steps
+	// are advanced whenever the deployer is reconciled after the interval.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
 type Scale struct {
 	Min *int32 `json:"min,omitempty"`
 	Max *int32 `json:"max,omitempty"`
+
+	// Initial is the number of pods a new revision starts with before it is
+	// ready.
+	// +optional
+	Initial *int32 `json:"initial,omitempty"`
+
+	// Class of the autoscaler, defaults to the cluster's configured class.
+	// +optional
+	Class AutoscalerClass `json:"class,omitempty"`
+
+	// Metric the autoscaler scales on. The concurrency and rps metrics
+	// require the KPA class, the cpu metric requires the HPA class.
+	// +optional
+	Metric AutoscalerMetric `json:"metric,omitempty"`
+
+	// Target value of the metric per pod.
+	// +optional
+	Target *int32 `json:"target,omitempty"`
+
+	// TargetUtilizationPercentage is the percentage of the target the
+	// autoscaler aims for, leaving headroom for bursts.
+	// +optional
+	TargetUtilizationPercentage *int32 `json:"targetUtilizationPercentage,omitempty"`
+
+	// Window is the stable window the metric is averaged over, between 6
+	// seconds and 1 hour. Requires the KPA class.
+	// +optional
+	Window *metav1.Duration `json:"window,omitempty"`
+
+	// PanicWindowPercentage is the length of the panic window as a
+	// percentage of the stable window. Requires the KPA class.
+	// +optional
+	PanicWindowPercentage *int32 `json:"panicWindowPercentage,omitempty"`
+
+	// ScaleDownDelay is how long demand must stay low before pods are
+	// removed, up to 1 hour. Requires the KPA class.
+	// +optional
+	ScaleDownDelay *metav1.Duration `json:"scaleDownDelay,omitempty"`
+}
+
+type AutoscalerClass string
+
+const (
+	AutoscalerClassKPA AutoscalerClass = "KPA"
+	AutoscalerClassHPA AutoscalerClass = "HPA"
+)
+
+type AutoscalerMetric string
+
+const (
+	AutoscalerMetricConcurrency AutoscalerMetric = "concurrency"
+	AutoscalerMetricRPS         AutoscalerMetric = "rps"
+	AutoscalerMetricCPU         AutoscalerMetric = "cpu"
+)
+
+const (
+	autoscalingAnnotationPrefix = "autoscaling.knative.dev/"
+)
+
+// Annotations are the Knative revision template annotations configuring the
+// autoscaler for the scale.
+func (s Scale) Annotations() map[string]string {
+	annotations := map[string]string{}
+	if s.Min != nil {
+		annotations[autoscalingAnnotationPrefix+"minScale"] = fmt.Sprintf("%d", *s.Min)
+	}
+	if s.Max != nil {
+		annotations[autoscalingAnnotationPrefix+"maxScale"] = fmt.Sprintf("%d", *s.Max)
+	}
+	if s.Initial != nil {
+		annotations[autoscalingAnnotationPrefix+"initialScale"] = fmt.Sprintf("%d", *s.Initial)
+	}
+	switch s.Class {
+	case AutoscalerClassKPA:
+		annotations[autoscalingAnnotationPrefix+"class"] = "kpa.autoscaling.knative.dev"
+	case AutoscalerClassHPA:
+		annotations[autoscalingAnnotationPrefix+"class"] = "hpa.autoscaling.knative.dev"
+	}
+	if s.Metric != "" {
+		annotations[autoscalingAnnotationPrefix+"metric"] = string(s.Metric)
+	}
+	if s.Target != nil {
+		annotations[autoscalingAnnotationPrefix+"target"] = fmt.Sprintf("%d", *s.Target)
+	}
+	if s.TargetUtilizationPercentage != nil {
+		annotations[autoscalingAnnotationPrefix+"targetUtilizationPercentage"] = fmt.Sprintf("%d", *s.TargetUtilizationPercentage)
+	}
+	if s.Window != nil {
+		annotations[autoscalingAnnotationPrefix+"window"] = s.Window.Duration.String()
+	}
+	if s.PanicWindowPercentage != nil {
+		annotations[autoscalingAnnotationPrefix+"panicWindowPercentage"] = fmt.Sprintf("%d", *s.PanicWindowPercentage)
+	}
+	if s.ScaleDownDelay != nil {
+		annotations[autoscalingAnnotationPrefix+"scaleDownDelay"] = s.ScaleDownDelay.Duration.String()
+	}
+	return annotations
 }
 
 // DeployerStatus defines the observed state of Deployer
@@ -84,8 +245,7 @@ type DeployerStatus struct {
 
 	apis.Status `json:",inline"`
 
-	// LatestImage is the most recent image resolved from the build
-	LatestImage string `json:"latestImage,omitempty"`
+	buildv1alpha1.ResolvedBuildStatus `json:",inline"`
 
 	// ConfigurationRef is a reference to the Knative Serving configuration
 	// backing this deployer.
@@ -95,6 +255,25 @@ type DeployerStatus struct {
 	// deployer.
 	RouteRef *refs.TypedLocalObjectReference `json:"routeRef,omitempty"`
 
+	// NetworkPolicyRef is a reference to the NetworkPolicy enforcing the
+	// ingress policy, when enforcement is enabled.
+	NetworkPolicyRef *refs.TypedLocalObjectReference `json:"networkPolicyRef,omitempty"`
+
+	// LatestReadyRevisionName is the most recent revision of the
+	// configuration that is ready to serve traffic.
+	LatestReadyRevisionName string `json:"latestReadyRevisionName,omitempty"`
+
+	// Traffic is the distribution of traffic across revisions, as observed on
+	// the route.
+	Traffic []TrafficTargetStatus `json:"traffic,omitempty"`
+
+	// Progression reports the progress of shifting traffic to a new
+	// revision, when a progression is defined.
+	Progression *TrafficProgressionStatus `json:"progression,omitempty"`
+
+	// Domains are the custom domains mapped to this deployer.
+	Domains []DomainStatus `json:"domains,omitempty"`
+
 	// Address to target this deployer internally
 	Address *apis.Addressable `json:"address,omitempty"`
 
@@ -102,6 +281,53 @@ type DeployerStatus struct {
 	URL string `json:"url,omitempty"`
 }
 
+type TrafficTargetStatus struct {
+	// Tag of the target, if any.
+	Tag string `json:"tag,omitempty"`
+
+	// RevisionName is the revision receiving the target's traffic.
+	RevisionName string `json:"revisionName,omitempty"`
+
+	// LatestRevision is true when the target follows the latest ready
+	// revision.
+	LatestRevision *bool `json:"latestRevision,omitempty"`
+
+	// Percent of the deployer's traffic sent to the revision.
+	Percent *int64 `json:"percent,omitempty"`
+
+	// URL to target the tagged revision exclusively.
+	URL string `json:"url,omitempty"`
+}
+
+type DomainStatus struct {
+	// Name of the mapped domain.
+	Name string `json:"name"`
+
+	// URL to target this deployer on the domain, once the domain mapping
+	// is reconciled.
+	URL string `json:"url,omitempty"`
+}
+
+type TrafficProgressionStatus struct {
+	// StableRevisionName is the revision serving the traffic of latest
+	// revision targets outside of a progression.
+	StableRevisionName string `json:"stableRevisionName,omitempty"`
+
+	// CandidateRevisionName is the revision traffic is shifting to.
+	CandidateRevisionName string `json:"candidateRevisionName,omitempty"`
+
+	// Step is the index of the current progression step.
+	Step int32 `json:"step,omitempty"`
+
+	// Percent of the latest revision targets' traffic sent to the
+	// candidate.
+	Percent int64 `json:"percent,omitempty"`
+
+	// LastTransitionTime is when the progression last started or advanced a
+	// step.
+	LastTransitionTime apis.VolatileTime `json:"lastTransitionTime,omitempty"`
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:categories="riff"
@@ -18,13 +18,17 @@ package v1alpha1
 
 import (
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	"github.com/projectriff/system/pkg/validation"
 )
 
@@ -58,6 +62,7 @@ func (r *Deployer) ValidateDelete() error {
 func (c *Deployer) Validate() validation.FieldErrors {
 	errs := validation.FieldErrors{}
 
+	errs = errs.Also(buildv1alpha1.ValidatePromoteAnnotation(c.Annotations, c.Spec.Build != nil).ViaField("metadata"))
 	errs = errs.Also(c.Spec.Validate().ViaField("spec"))
 
 	return errs
@@ -79,6 +84,7 @@ func (s DeployerSpec) Validate() validation.FieldErrors {
 	}, &s.Template.Spec); diff != "" {
 		errs = errs.Also(validation.ErrDisallowedFields("template.spec", fmt.Sprintf("limited Template fields may be set (-want, +got) = %v", diff)))
 	}
+	errs = errs.Also(validation.ActiveImagePolicy().ValidatePodSpecImages(&s.Template.Spec).ViaField("template.spec"))
 
 	if s.Build == nil && s.Template.Spec.Containers[0].Image == "" {
 		errs = errs.Also(validation.ErrMissingOneOf("build", "template.spec.containers[0].image"))
@@ -99,6 +105,133 @@ func (s DeployerSpec) Validate() validation.FieldErrors {
 	}
 
 	errs = errs.Also(s.Scale.Validate().ViaField("scale"))
+	errs = errs.Also(s.validateScaleAnnotations())
+
+	errs = errs.Also(s.validateTraffic())
+	if s.Progression != nil {
+		errs = errs.Also(s.Progression.Validate().ViaField("progression"))
+	}
+
+	if len(s.Domains) != 0 {
+		if s.IngressPolicy != IngressPolicyExternal {
+			errs = errs.Also(validation.ErrDisallowedFields("domains", "domains require the External ingress policy"))
+		}
+		names := map[string]bool{}
+		for i, domain := range s.Domains {
+			errs = errs.Also(domain.Validate().ViaFieldIndex("domains", i))
+			if names[domain.Name] {
+				errs = errs.Also(validation.ErrDuplicateValue(domain.Name, "name").ViaFieldIndex("domains", i))
+			}
+			names[domain.Name] = true
+		}
+	}
+
+	return errs
+}
+
+// validateScaleAnnotations rejects template annotations that configure the
+// autoscaler differently from the scale.
+func (s DeployerSpec) validateScaleAnnotations() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	keys := []string{}
+	for key := range s.Scale.Annotations() {
+		if _, ok := s.Template.Annotations[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		errs = errs.Also(validation.ErrDisallowedFields(fmt.Sprintf("template.metadata.annotations[%s]", key), "conflicts with scale"))
+	}
+
+	return errs
+}
+
+func (s DeployerSpec) validateTraffic() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if len(s.Traffic) == 0 {
+		// all traffic is sent to the latest revision
+		return errs
+	}
+
+	var total int64
+	latest := false
+	tags := map[string]bool{}
+	for i, target := range s.Traffic {
+		errs = errs.Also(target.Validate().ViaFieldIndex("traffic", i))
+		if target.Percent != nil {
+			total += *target.Percent
+		}
+		if target.LatestRevision != nil && *target.LatestRevision {
+			latest = true
+		}
+		if target.Tag != "" {
+			if tags[target.Tag] {
+				errs = errs.Also(validation.ErrDuplicateValue(target.Tag, "tag").ViaFieldIndex("traffic", i))
+			}
+			tags[target.Tag] = true
+		}
+	}
+	if total != 100 {
+		errs = errs.Also(validation.ErrDisallowedFields("traffic", fmt.Sprintf("percentages must add up to 100, got %d", total)))
+	}
+	if s.Progression != nil && !latest {
+		errs = errs.Also(validation.ErrDisallowedFields("progression", "requires a traffic target for the latest revision"))
+	}
+
+	return errs
+}
+
+func (t *TrafficTarget) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	latest := t.LatestRevision != nil && *t.LatestRevision
+	if t.RevisionName == "" && !latest {
+		errs = errs.Also(validation.ErrMissingOneOf("revisionName", "latestRevision"))
+	} else if t.RevisionName != "" && latest {
+		errs = errs.Also(validation.ErrMultipleOneOf("revisionName", "latestRevision"))
+	}
+	if t.Tag != "" && len(k8svalidation.IsDNS1123Label(t.Tag)) != 0 {
+		errs = errs.Also(validation.ErrInvalidValue(t.Tag, "tag"))
+	}
+	if t.Percent != nil && (*t.Percent < 0 || *t.Percent > 100) {
+		errs = errs.Also(validation.ErrInvalidValue(*t.Percent, "percent"))
+	}
+
+	return errs
+}
+
+func (d *Domain) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if d.Name == "" {
+		errs = errs.Also(validation.ErrMissingField("name"))
+	} else if len(k8svalidation.IsDNS1123Subdomain(d.Name)) != 0 {
+		errs = errs.Also(validation.ErrInvalidValue(d.Name, "name"))
+	}
+	if d.SecretRef != "" && len(k8svalidation.IsDNS1123Subdomain(d.SecretRef)) != 0 {
+		errs = errs.Also(validation.ErrInvalidValue(d.SecretRef, "secretRef"))
+	}
+
+	return errs
+}
+
+func (p *TrafficProgression) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if len(p.Steps) == 0 {
+		errs = errs.Also(validation.ErrMissingField("steps"))
+	}
+	for i, step := range p.Steps {
+		if step < 1 || step > 100 {
+			errs = errs.Also(validation.ErrInvalidArrayValue(step, "steps", i))
+		}
+	}
+	if p.Interval != nil && p.Interval.Duration <= 0 {
+		errs = errs.Also(validation.ErrInvalidValue(p.Interval.Duration.String(), "interval"))
+	}
 
 	return errs
 }
@@ -126,6 +259,57 @@ func (s Scale) Validate() validation.FieldErrors {
 	if s.Min != nil && s.Max != nil && *s.Min > *s.Max {
 		errs = errs.Also(validation.ErrInvalidValue(*s.Max, "max"))
 	}
+	if s.Initial != nil && *s.Initial < int32(0) {
+		errs = errs.Also(validation.ErrInvalidValue(*s.Initial, "initial"))
+	}
+
+	switch s.Class {
+	case "", AutoscalerClassKPA, AutoscalerClassHPA:
+	default:
+		errs = errs.Also(validation.ErrInvalidValue(s.Class, "class"))
+	}
+	switch s.Metric {
+	case "":
+	case AutoscalerMetricConcurrency, AutoscalerMetricRPS:
+		if s.Class == AutoscalerClassHPA {
+			errs = errs.Also(validation.ErrDisallowedFields("metric", fmt.Sprintf("%s is not supported by the HPA class", s.Metric)))
+		}
+	case AutoscalerMetricCPU:
+		if s.Class != AutoscalerClassHPA {
+			errs = errs.Also(validation.ErrDisallowedFields("metric", "cpu requires the HPA class"))
+		}
+	default:
+		errs = errs.Also(validation.ErrInvalidValue(s.Metric, "metric"))
+	}
+	if s.Target != nil && *s.Target < int32(1) {
+		errs = errs.Also(validation.ErrInvalidValue(*s.Target, "target"))
+	}
+	if s.TargetUtilizationPercentage != nil && (*s.TargetUtilizationPercentage < int32(1) || *s.TargetUtilizationPercentage > int32(100)) {
+		errs = errs.Also(validation.ErrInvalidValue(*s.TargetUtilizationPercentage, "targetUtilizationPercentage"))
+	}
+
+	// windows and delays are only honored by the KPA
+	if s.Window != nil {
+		if s.Class == AutoscalerClassHPA {
+			errs = errs.Also(validation.ErrDisallowedFields("window", "not supported by the HPA class"))
+		} else if s.Window.Duration < 6*time.Second || s.Window.Duration > time.Hour {
+			errs = errs.Also(validation.ErrInvalidValue(s.Window.Duration.String(), "window"))
+		}
+	}
+	if s.PanicWindowPercentage != nil {
+		if s.Class == AutoscalerClassHPA {
+			errs = errs.Also(validation.ErrDisallowedFields("panicWindowPercentage", "not supported by the HPA class"))
+		} else if *s.PanicWindowPercentage < int32(1) || *s.PanicWindowPercentage > int32(100) {
+			errs = errs.Also(validation.ErrInvalidValue(*s.PanicWindowPercentage, "panicWindowPercentage"))
+		}
+	}
+	if s.ScaleDownDelay != nil {
+		if s.Class == AutoscalerClassHPA {
+			errs = errs.Also(validation.ErrDisallowedFields("scaleDownDelay", "not supported by the HPA class"))
+		} else if s.ScaleDownDelay.Duration < 0 || s.ScaleDownDelay.Duration > time.Hour {
+			errs = errs.Also(validation.ErrInvalidValue(s.ScaleDownDelay.Duration.String(), "scaleDownDelay"))
+		}
+	}
 
 	return errs
 }
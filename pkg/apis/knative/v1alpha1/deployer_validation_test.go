@@ -18,9 +18,11 @@ package v1alpha1
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/projectriff/system/pkg/validation"
 )
@@ -65,6 +67,12 @@ func TestValidateDeployerSpec(t *testing.T) {
 	negativeOne := int32(-1)
 	negativeNumber := int64(-1)
 	bigNumber := MaxContainerConcurrency + 1
+	ninety := int64(90)
+	ten := int64(10)
+	hundred := int64(100)
+	latest := true
+	one := int32(1)
+	ten32 := int32(10)
 
 	for _, c := range []struct {
 		name     string
@@ -171,6 +179,137 @@ func TestValidateDeployerSpec(t *testing.T) {
 			},
 		},
 		expected: validation.ErrInvalidValue(negativeOne, "scale.min"),
+	}, {
+		name: "valid, traffic and progression",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Traffic: []TrafficTarget{
+				{Tag: "current", RevisionName: "my-revision", Percent: &ninety},
+				{Tag: "latest", LatestRevision: &latest, Percent: &ten},
+			},
+			Progression: &TrafficProgression{
+				Steps: []int64{10, 50},
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid, traffic",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Traffic: []TrafficTarget{
+				{Tag: "Current", RevisionName: "my-revision", LatestRevision: &latest, Percent: &ninety},
+				{Tag: "Current", Percent: &ninety},
+			},
+			Progression: &TrafficProgression{
+				Steps: []int64{0},
+			},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrMultipleOneOf("revisionName", "latestRevision").ViaFieldIndex("traffic", 0),
+			validation.ErrInvalidValue("Current", "traffic[0].tag"),
+			validation.ErrMissingOneOf("revisionName", "latestRevision").ViaFieldIndex("traffic", 1),
+			validation.ErrInvalidValue("Current", "traffic[1].tag"),
+			validation.ErrDuplicateValue("Current", "traffic[1].tag"),
+			validation.ErrDisallowedFields("traffic", "percentages must add up to 100, got 180"),
+			validation.ErrInvalidArrayValue(int64(0), "progression.steps", 0),
+		),
+	}, {
+		name: "invalid, progression without latest revision",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Traffic: []TrafficTarget{
+				{RevisionName: "my-revision", Percent: &hundred},
+			},
+			Progression: &TrafficProgression{
+				Steps: []int64{50},
+			},
+		},
+		expected: validation.ErrDisallowedFields("progression", "requires a traffic target for the latest revision"),
+	}, {
+		name: "valid, domains",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyExternal,
+			Domains: []Domain{
+				{Name: "example.com"},
+				{Name: "www.example.com", SecretRef: "example-tls"},
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid, domains",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Domains: []Domain{
+				{Name: "Example.com", SecretRef: "Example_TLS"},
+				{},
+				{Name: "Example.com"},
+			},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrDisallowedFields("domains", "domains require the External ingress policy"),
+			validation.ErrInvalidValue("Example.com", "domains[0].name"),
+			validation.ErrInvalidValue("Example_TLS", "domains[0].secretRef"),
+			validation.ErrMissingField("domains[1].name"),
+			validation.ErrInvalidValue("Example.com", "domains[2].name"),
+			validation.ErrDuplicateValue("Example.com", "domains[2].name"),
+		),
+	}, {
+		name: "invalid, template annotations conflict with scale",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"autoscaling.knative.dev/minScale": "2",
+						"autoscaling.knative.dev/target":   "10",
+						"autoscaling.knative.dev/window":   "60s",
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Scale: Scale{
+				Min:    &one,
+				Target: &ten32,
+			},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrDisallowedFields("template.metadata.annotations[autoscaling.knative.dev/minScale]", "conflicts with scale"),
+			validation.ErrDisallowedFields("template.metadata.annotations[autoscaling.knative.dev/target]", "conflicts with scale"),
+		),
 	}} {
 		t.Run(c.name, func(t *testing.T) {
 			actual := c.target.Validate()
@@ -186,6 +325,8 @@ func TestValidateScale(t *testing.T) {
 	zero := int32(0)
 	one := int32(1)
 	five := int32(5)
+	seventy := int32(70)
+	oneHundredOne := int32(101)
 
 	for _, c := range []struct {
 		name     string
@@ -233,6 +374,70 @@ func TestValidateScale(t *testing.T) {
 			Max: &one,
 		},
 		expected: validation.ErrInvalidValue(one, "max"),
+	}, {
+		name: "valid, kpa autoscaler",
+		target: &Scale{
+			Initial:                     &zero,
+			Class:                       AutoscalerClassKPA,
+			Metric:                      AutoscalerMetricConcurrency,
+			Target:                      &five,
+			TargetUtilizationPercentage: &seventy,
+			Window:                      &metav1.Duration{Duration: time.Minute},
+			PanicWindowPercentage:       &five,
+			ScaleDownDelay:              &metav1.Duration{Duration: 15 * time.Minute},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "valid, hpa autoscaler",
+		target: &Scale{
+			Class:  AutoscalerClassHPA,
+			Metric: AutoscalerMetricCPU,
+			Target: &seventy,
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid, autoscaler values",
+		target: &Scale{
+			Initial:                     &negativeOne,
+			Class:                       "Bogus",
+			Metric:                      "memory",
+			Target:                      &zero,
+			TargetUtilizationPercentage: &oneHundredOne,
+			Window:                      &metav1.Duration{Duration: time.Second},
+			PanicWindowPercentage:       &zero,
+			ScaleDownDelay:              &metav1.Duration{Duration: 2 * time.Hour},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrInvalidValue(negativeOne, "initial"),
+			validation.ErrInvalidValue(AutoscalerClass("Bogus"), "class"),
+			validation.ErrInvalidValue(AutoscalerMetric("memory"), "metric"),
+			validation.ErrInvalidValue(zero, "target"),
+			validation.ErrInvalidValue(oneHundredOne, "targetUtilizationPercentage"),
+			validation.ErrInvalidValue("1s", "window"),
+			validation.ErrInvalidValue(zero, "panicWindowPercentage"),
+			validation.ErrInvalidValue("2h0m0s", "scaleDownDelay"),
+		),
+	}, {
+		name: "invalid, cpu metric requires hpa",
+		target: &Scale{
+			Metric: AutoscalerMetricCPU,
+		},
+		expected: validation.ErrDisallowedFields("metric", "cpu requires the HPA class"),
+	}, {
+		name: "invalid, kpa settings with hpa",
+		target: &Scale{
+			Class:                 AutoscalerClassHPA,
+			Metric:                AutoscalerMetricRPS,
+			Window:                &metav1.Duration{Duration: time.Minute},
+			PanicWindowPercentage: &five,
+			ScaleDownDelay:        &metav1.Duration{Duration: time.Minute},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrDisallowedFields("metric", "rps is not supported by the HPA class"),
+			validation.ErrDisallowedFields("window", "not supported by the HPA class"),
+			validation.ErrDisallowedFields("panicWindowPercentage", "not supported by the HPA class"),
+			validation.ErrDisallowedFields("scaleDownDelay", "not supported by the HPA class"),
+		),
 	}} {
 		t.Run(c.name, func(t *testing.T) {
 			actual := c.target.Validate()
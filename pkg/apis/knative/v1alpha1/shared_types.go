@@ -16,6 +16,10 @@ limitations under the License.
 
 package v1alpha1
 
+import (
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+)
+
 type Build struct {
 	// ApplicationRef references an application in this namespace.
 	ApplicationRef string `json:"applicationRef,omitempty"`
@@ -25,4 +29,11 @@ type Build struct {
 
 	// FunctionRef references an application in this namespace.
 	FunctionRef string `json:"functionRef,omitempty"`
+
+	// Pin rolls out a specific build from the referenced resource's history
+	// instead of its latest image. Newer images are reported in status as
+	// available until promoted with the build.projectriff.io/promote
+	// annotation.
+	// +optional
+	Pin *buildv1alpha1.BuildPin `json:"pin,omitempty"`
 }
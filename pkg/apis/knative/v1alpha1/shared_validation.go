@@ -55,5 +55,9 @@ func (b *Build) Validate() validation.FieldErrors {
 		errs = errs.Also(validation.ErrMultipleOneOf(used...))
 	}
 
+	if b.Pin != nil {
+		errs = errs.Also(b.Pin.Validate().ViaField("pin"))
+	}
+
 	return errs
 }
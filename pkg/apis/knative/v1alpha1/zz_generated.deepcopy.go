@@ -22,9 +22,11 @@ package v1alpha1
 
 import (
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/projectriff/system/pkg/apis"
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -32,7 +34,7 @@ func (in *Adapter) DeepCopyInto(out *Adapter) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -89,8 +91,13 @@ func (in *AdapterList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AdapterSpec) DeepCopyInto(out *AdapterSpec) {
 	*out = *in
-	out.Build = in.Build
-	out.Target = in.Target
+	in.Build.DeepCopyInto(&out.Build)
+	in.Target.DeepCopyInto(&out.Target)
+	if in.UpdatePolicy != nil {
+		in, out := &in.UpdatePolicy, &out.UpdatePolicy
+		*out = new(AdapterUpdatePolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdapterSpec.
@@ -107,6 +114,14 @@ func (in *AdapterSpec) DeepCopy() *AdapterSpec {
 func (in *AdapterStatus) DeepCopyInto(out *AdapterStatus) {
 	*out = *in
 	in.Status.DeepCopyInto(&out.Status)
+	in.ResolvedBuildStatus.DeepCopyInto(&out.ResolvedBuildStatus)
+	if in.Updates != nil {
+		in, out := &in.Updates, &out.Updates
+		*out = make([]AdapterUpdate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdapterStatus.
@@ -122,6 +137,10 @@ func (in *AdapterStatus) DeepCopy() *AdapterStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AdapterTarget) DeepCopyInto(out *AdapterTarget) {
 	*out = *in
+	if in.Ref != nil {
+		in, out := &in.Ref, &out.Ref
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdapterTarget.
@@ -134,9 +153,50 @@ func (in *AdapterTarget) DeepCopy() *AdapterTarget {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdapterUpdate) DeepCopyInto(out *AdapterUpdate) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdapterUpdate.
+func (in *AdapterUpdate) DeepCopy() *AdapterUpdate {
+	if in == nil {
+		return nil
+	}
+	out := new(AdapterUpdate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdapterUpdatePolicy) DeepCopyInto(out *AdapterUpdatePolicy) {
+	*out = *in
+	if in.MaintenanceWindows != nil {
+		in, out := &in.MaintenanceWindows, &out.MaintenanceWindows
+		*out = make([]MaintenanceWindow, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdapterUpdatePolicy.
+func (in *AdapterUpdatePolicy) DeepCopy() *AdapterUpdatePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(AdapterUpdatePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Build) DeepCopyInto(out *Build) {
 	*out = *in
+	if in.Pin != nil {
+		in, out := &in.Pin, &out.Pin
+		*out = new(buildv1alpha1.BuildPin)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Build.
@@ -214,7 +274,7 @@ func (in *DeployerSpec) DeepCopyInto(out *DeployerSpec) {
 	if in.Build != nil {
 		in, out := &in.Build, &out.Build
 		*out = new(Build)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.ContainerConcurrency != nil {
 		in, out := &in.ContainerConcurrency, &out.ContainerConcurrency
@@ -227,6 +287,23 @@ func (in *DeployerSpec) DeepCopyInto(out *DeployerSpec) {
 		*out = new(v1.PodTemplateSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Traffic != nil {
+		in, out := &in.Traffic, &out.Traffic
+		*out = make([]TrafficTarget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Progression != nil {
+		in, out := &in.Progression, &out.Progression
+		*out = new(TrafficProgression)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Domains != nil {
+		in, out := &in.Domains, &out.Domains
+		*out = make([]Domain, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeployerSpec.
@@ -243,6 +320,7 @@ func (in *DeployerSpec) DeepCopy() *DeployerSpec {
 func (in *DeployerStatus) DeepCopyInto(out *DeployerStatus) {
 	*out = *in
 	in.Status.DeepCopyInto(&out.Status)
+	in.ResolvedBuildStatus.DeepCopyInto(&out.ResolvedBuildStatus)
 	if in.ConfigurationRef != nil {
 		in, out := &in.ConfigurationRef, &out.ConfigurationRef
 		*out = (*in).DeepCopy()
@@ -251,6 +329,27 @@ func (in *DeployerStatus) DeepCopyInto(out *DeployerStatus) {
 		in, out := &in.RouteRef, &out.RouteRef
 		*out = (*in).DeepCopy()
 	}
+	if in.NetworkPolicyRef != nil {
+		in, out := &in.NetworkPolicyRef, &out.NetworkPolicyRef
+		*out = (*in).DeepCopy()
+	}
+	if in.Traffic != nil {
+		in, out := &in.Traffic, &out.Traffic
+		*out = make([]TrafficTargetStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Progression != nil {
+		in, out := &in.Progression, &out.Progression
+		*out = new(TrafficProgressionStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Domains != nil {
+		in, out := &in.Domains, &out.Domains
+		*out = make([]DomainStatus, len(*in))
+		copy(*out, *in)
+	}
 	if in.Address != nil {
 		in, out := &in.Address, &out.Address
 		*out = new(apis.Addressable)
@@ -268,6 +367,52 @@ func (in *DeployerStatus) DeepCopy() *DeployerStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Domain) DeepCopyInto(out *Domain) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Domain.
+func (in *Domain) DeepCopy() *Domain {
+	if in == nil {
+		return nil
+	}
+	out := new(Domain)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainStatus) DeepCopyInto(out *DomainStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainStatus.
+func (in *DomainStatus) DeepCopy() *DomainStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Scale) DeepCopyInto(out *Scale) {
 	*out = *in
@@ -281,6 +426,36 @@ func (in *Scale) DeepCopyInto(out *Scale) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.Initial != nil {
+		in, out := &in.Initial, &out.Initial
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Target != nil {
+		in, out := &in.Target, &out.Target
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TargetUtilizationPercentage != nil {
+		in, out := &in.TargetUtilizationPercentage, &out.TargetUtilizationPercentage
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Window != nil {
+		in, out := &in.Window, &out.Window
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.PanicWindowPercentage != nil {
+		in, out := &in.PanicWindowPercentage, &out.PanicWindowPercentage
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ScaleDownDelay != nil {
+		in, out := &in.ScaleDownDelay, &out.ScaleDownDelay
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Scale.
@@ -292,3 +467,94 @@ func (in *Scale) DeepCopy() *Scale {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrafficProgression) DeepCopyInto(out *TrafficProgression) {
+	*out = *in
+	if in.Steps != nil {
+		in, out := &in.Steps, &out.Steps
+		*out = make([]int64, len(*in))
+		copy(*out, *in)
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrafficProgression.
+func (in *TrafficProgression) DeepCopy() *TrafficProgression {
+	if in == nil {
+		return nil
+	}
+	out := new(TrafficProgression)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrafficProgressionStatus) DeepCopyInto(out *TrafficProgressionStatus) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrafficProgressionStatus.
+func (in *TrafficProgressionStatus) DeepCopy() *TrafficProgressionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TrafficProgressionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrafficTarget) DeepCopyInto(out *TrafficTarget) {
+	*out = *in
+	if in.LatestRevision != nil {
+		in, out := &in.LatestRevision, &out.LatestRevision
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Percent != nil {
+		in, out := &in.Percent, &out.Percent
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrafficTarget.
+func (in *TrafficTarget) DeepCopy() *TrafficTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(TrafficTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrafficTargetStatus) DeepCopyInto(out *TrafficTargetStatus) {
+	*out = *in
+	if in.LatestRevision != nil {
+		in, out := &in.LatestRevision, &out.LatestRevision
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Percent != nil {
+		in, out := &in.Percent, &out.Percent
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrafficTargetStatus.
+func (in *TrafficTargetStatus) DeepCopy() *TrafficTargetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TrafficTargetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
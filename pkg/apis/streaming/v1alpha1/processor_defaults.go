@@ -18,6 +18,8 @@ package v1alpha1
 import (
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 )
 
 // +kubebuilder:webhook:path=/mutate-streaming-projectriff-io-v1alpha1-processor,mutating=true,failurePolicy=fail,groups=streaming.projectriff.io,resources=processors,verbs=create;update,versions=v1alpha1,name=processors.streaming.projectriff.io
@@ -27,6 +29,9 @@ var _ webhook.Defaulter = &Processor{}
 // Default implements webhook.Defaulter so a webhook will be registered for the type
 func (r *Processor) Default() {
 	r.Spec.Default()
+	if r.Spec.Build != nil {
+		r.Spec.Build.Pin = buildv1alpha1.PromoteBuild(r.Annotations, r.Spec.Build.Pin)
+	}
 }
 
 func (s *ProcessorSpec) Default() {
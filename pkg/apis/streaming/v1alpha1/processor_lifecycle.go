@@ -16,12 +16,15 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
+
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 
 	kedav1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/keda/v1alpha1"
 
 	"github.com/projectriff/system/pkg/apis"
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 )
 
 const (
@@ -31,6 +34,16 @@ const (
 	ProcessorConditionStreamsReady      apis.ConditionType = "StreamsReady"
 	ProcessorConditionDeploymentReady   apis.ConditionType = "DeploymentReady"
 	ProcessorConditionScaledObjectReady apis.ConditionType = "ScaledObjectReady"
+
+	// ProcessorConditionImageAllowed reports a resolved image that is rejected
+	// by the image policy. The condition is informational and only present
+	// while the image is rejected, the previous image remains rolled out.
+	ProcessorConditionImageAllowed apis.ConditionType = "ImageAllowed"
+
+	// ProcessorConditionBuildReady reports a build pin that does not match a build
+	// of the build resource. The condition is informational and only present
+	// while the pin does not match, the current image remains rolled out.
+	ProcessorConditionBuildReady apis.ConditionType = "BuildReady"
 )
 
 var processorCondSet = apis.NewLivingConditionSet(
@@ -59,6 +72,45 @@ func (ps *ProcessorStatus) InitializeConditions() {
 	processorCondSet.Manage(ps).InitializeConditions()
 }
 
+// PropagateBuildStatus resolves the image to roll out from a build resource,
+// honoring the pin. The current image is retained when the pin does not match
+// a build.
+func (ps *ProcessorStatus) PropagateBuildStatus(pin *buildv1alpha1.BuildPin, bs *buildv1alpha1.BuildStatus) {
+	if !ps.ResolvedBuildStatus.PropagateBuildStatus(pin, bs) {
+		ps.MarkBuildPinNotFound(pin)
+		return
+	}
+	_ = processorCondSet.Manage(ps).ClearCondition(ProcessorConditionBuildReady)
+}
+
+func (ps *ProcessorStatus) MarkBuildPinNotFound(pin *buildv1alpha1.BuildPin) {
+	// set directly, marking an informational condition may overwrite the
+	// reason of the ready condition
+	processorCondSet.Manage(ps).SetCondition(apis.Condition{
+		Type:     ProcessorConditionBuildReady,
+		Status:   corev1.ConditionFalse,
+		Severity: apis.ConditionSeverityInfo,
+		Reason:   "PinNotFound",
+		Message:  fmt.Sprintf("The pinned %s does not match a build.", pin),
+	})
+}
+
+func (ps *ProcessorStatus) MarkImageAllowed() {
+	_ = processorCondSet.Manage(ps).ClearCondition(ProcessorConditionImageAllowed)
+}
+
+func (ps *ProcessorStatus) MarkImageNotAllowed(message string) {
+	// set directly, marking an informational condition may overwrite the
+	// reason of the ready condition
+	processorCondSet.Manage(ps).SetCondition(apis.Condition{
+		Type:     ProcessorConditionImageAllowed,
+		Status:   corev1.ConditionFalse,
+		Severity: apis.ConditionSeverityInfo,
+		Reason:   "ImageNotAllowed",
+		Message:  message,
+	})
+}
+
 func (ps *ProcessorStatus) MarkStreamsReady() {
 	processorCondSet.Manage(ps).MarkTrue(ProcessorConditionStreamsReady)
 }
@@ -22,6 +22,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/projectriff/system/pkg/apis"
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	"github.com/projectriff/system/pkg/refs"
 )
 
@@ -64,6 +65,13 @@ type Build struct {
 
 	// FunctionRef references an application in this namespace.
 	FunctionRef string `json:"functionRef,omitempty"`
+
+	// Pin rolls out a specific build from the referenced resource's history
+	// instead of its latest image. Newer images are reported in status as
+	// available until promoted with the build.projectriff.io/promote
+	// annotation.
+	// +optional
+	Pin *buildv1alpha1.BuildPin `json:"pin,omitempty"`
 }
 
 type OutputStreamBinding struct {
@@ -101,7 +109,8 @@ type ProcessorStatus struct {
 
 	DeploymentRef   *refs.TypedLocalObjectReference `json:"deploymentRef,omitempty"`
 	ScaledObjectRef *refs.TypedLocalObjectReference `json:"scaledObjectRef,omitempty"`
-	LatestImage     string                          `json:"latestImage,omitempty"`
+
+	buildv1alpha1.ResolvedBuildStatus `json:",inline"`
 }
 
 // +kubebuilder:object:root=true
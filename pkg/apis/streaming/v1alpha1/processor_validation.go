@@ -19,12 +19,11 @@ package v1alpha1
 import (
 	"fmt"
 
-	"github.com/google/go-cmp/cmp"
-	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	"github.com/projectriff/system/pkg/validation"
 )
 
@@ -54,6 +53,7 @@ func (r *Processor) ValidateDelete() error {
 func (r *Processor) Validate() validation.FieldErrors {
 	errs := validation.FieldErrors{}
 
+	errs = errs.Also(buildv1alpha1.ValidatePromoteAnnotation(r.Annotations, r.Spec.Build != nil).ViaField("metadata"))
 	errs = errs.Also(r.Spec.Validate().ViaField("spec"))
 
 	return errs
@@ -66,15 +66,8 @@ func (s *ProcessorSpec) Validate() validation.FieldErrors {
 
 	errs := validation.FieldErrors{}
 
-	if diff := cmp.Diff(&corev1.PodSpec{
-		// add supported PodSpec fields here, otherwise their usage will be rejected
-		ServiceAccountName: s.Template.Spec.ServiceAccountName,
-		// the defaulter guarantees at least one container
-		Containers: filterInvalidContainers(s.Template.Spec.Containers[:1]),
-		Volumes:    filterInvalidVolumes(s.Template.Spec.Volumes),
-	}, &s.Template.Spec); diff != "" {
-		errs = errs.Also(validation.ErrDisallowedFields("template.spec", fmt.Sprintf("limited PodSpec fields may be set (-want, +got) = %v", diff)))
-	}
+	errs = errs.Also(validation.ActivePodSpecAllowlist().ValidatePodSpec(&s.Template.Spec).ViaField("template.spec"))
+	errs = errs.Also(validation.ActiveImagePolicy().ValidatePodSpecImages(&s.Template.Spec).ViaField("template.spec"))
 	if s.Template.Spec.Containers[0].Name != "function" {
 		errs = errs.Also(validation.ErrInvalidValue(s.Template.Spec.Containers[0].Name, "template.spec.containers[0].name"))
 	}
@@ -177,15 +170,9 @@ func (b *Build) Validate() validation.FieldErrors {
 		errs = errs.Also(validation.ErrMultipleOneOf(used...))
 	}
 
-	return errs
-}
-
-func filterInvalidContainers(containers []corev1.Container) []corev1.Container {
-	// TODO remove unsupported fields
-	return containers
-}
+	if b.Pin != nil {
+		errs = errs.Also(b.Pin.Validate().ViaField("pin"))
+	}
 
-func filterInvalidVolumes(volumes []corev1.Volume) []corev1.Volume {
-	// TODO remove unsupported fields
-	return volumes
+	return errs
 }
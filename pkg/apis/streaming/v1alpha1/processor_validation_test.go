@@ -106,6 +106,49 @@ func TestValidateProcessorSpec(t *testing.T) {
 			},
 		},
 		expected: validation.ErrMissingOneOf("build", "template.spec.containers[0].image"),
+	}, {
+		name: "allows sidecars and scheduling fields",
+		target: &ProcessorSpec{
+			Build: &Build{
+				FunctionRef: "my-func",
+			},
+			Inputs: []InputStreamBinding{
+				{Stream: "my-stream", Alias: "in"},
+			},
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "function"},
+						{Name: "sidecar", Image: "my-sidecar"},
+					},
+					NodeSelector: map[string]string{"kubernetes.io/arch": "amd64"},
+					Tolerations: []corev1.Toleration{
+						{Key: "dedicated", Operator: corev1.TolerationOpExists},
+					},
+					PriorityClassName: "high",
+				},
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "forbids pod spec fields outside the allowlist",
+		target: &ProcessorSpec{
+			Build: &Build{
+				FunctionRef: "my-func",
+			},
+			Inputs: []InputStreamBinding{
+				{Stream: "my-stream", Alias: "in"},
+			},
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "function"},
+					},
+					HostNetwork: true,
+				},
+			},
+		},
+		expected: validation.ErrDisallowedFields("template.spec.hostNetwork", "not in the v1 PodSpec allowlist"),
 	}, {
 		name: "forbids both function ref and container image",
 		target: &ProcessorSpec{
@@ -25,6 +25,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/projectriff/system/pkg/apis"
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -47,6 +48,11 @@ func (in *BindingReference) DeepCopy() *BindingReference {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Build) DeepCopyInto(out *Build) {
 	*out = *in
+	if in.Pin != nil {
+		in, out := &in.Pin, &out.Pin
+		*out = new(buildv1alpha1.BuildPin)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Build.
@@ -677,7 +683,7 @@ func (in *ProcessorSpec) DeepCopyInto(out *ProcessorSpec) {
 	if in.Build != nil {
 		in, out := &in.Build, &out.Build
 		*out = new(Build)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.Inputs != nil {
 		in, out := &in.Inputs, &out.Inputs
@@ -718,6 +724,7 @@ func (in *ProcessorStatus) DeepCopyInto(out *ProcessorStatus) {
 		in, out := &in.ScaledObjectRef, &out.ScaledObjectRef
 		*out = (*in).DeepCopy()
 	}
+	in.ResolvedBuildStatus.DeepCopyInto(&out.ResolvedBuildStatus)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProcessorStatus.
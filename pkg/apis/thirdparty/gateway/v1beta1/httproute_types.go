@@ -0,0 +1,327 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+
+// HTTPRoute provides a way to route HTTP requests. This includes the capability
+// to match requests by hostname, path, header, or query param. Filters can be
+// used to specify additional processing steps. Backends specify where matching
+// requests should be routed.
+type HTTPRoute struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec defines the desired state of HTTPRoute.
+	Spec HTTPRouteSpec `json:"spec"`
+
+	// Status defines the current state of HTTPRoute.
+	Status HTTPRouteStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// HTTPRouteList contains a list of HTTPRoute.
+type HTTPRouteList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HTTPRoute `json:"items"`
+}
+
+// HTTPRouteSpec defines the desired state of HTTPRoute
+type HTTPRouteSpec struct {
+	// ParentRefs references the resources (usually Gateways) that a Route
+	// wants to be attached to.
+	// +optional
+	ParentRefs []ParentReference `json:"parentRefs,omitempty"`
+
+	// Hostnames defines a set of hostname that should match against the HTTP
+	// Host header to select a HTTPRoute to process the request.
+	// +optional
+	Hostnames []string `json:"hostnames,omitempty"`
+
+	// Rules are a list of HTTP matchers, filters and actions.
+	// +optional
+	Rules []HTTPRouteRule `json:"rules,omitempty"`
+}
+
+// ParentReference identifies an API object (usually a Gateway) that can be
+// considered a parent of this resource (usually a route).
+type ParentReference struct {
+	// Group is the group of the referent.
+	// +optional
+	Group *string `json:"group,omitempty"`
+
+	// Kind is kind of the referent.
+	// +optional
+	Kind *string `json:"kind,omitempty"`
+
+	// Namespace is the namespace of the referent. When unspecified, this
+	// refers to the local namespace of the Route.
+	// +optional
+	Namespace *string `json:"namespace,omitempty"`
+
+	// Name is the name of the referent.
+	Name string `json:"name"`
+
+	// SectionName is the name of a section within the target resource.
+	// +optional
+	SectionName *string `json:"sectionName,omitempty"`
+}
+
+// HTTPRouteRule defines semantics for matching an HTTP request based on
+// conditions (matches), processing it (filters), and forwarding the request to
+// an API object (backendRefs).
+type HTTPRouteRule struct {
+	// Matches define conditions used for matching the rule against incoming
+	// HTTP requests.
+	// +optional
+	Matches []HTTPRouteMatch `json:"matches,omitempty"`
+
+	// Filters define the filters that are applied to requests that match
+	// this rule.
+	// +optional
+	Filters []HTTPRouteFilter `json:"filters,omitempty"`
+
+	// BackendRefs defines the backend(s) where matching requests should be
+	// sent.
+	// +optional
+	BackendRefs []HTTPBackendRef `json:"backendRefs,omitempty"`
+}
+
+// PathMatchType specifies the semantics of how HTTP paths should be compared.
+type PathMatchType string
+
+const (
+	// Matches based on a URL path prefix split by `/`.
+	PathMatchPathPrefix PathMatchType = "PathPrefix"
+)
+
+// HTTPPathMatch describes how to select a HTTP route by matching the HTTP
+// request path.
+type HTTPPathMatch struct {
+	// Type specifies how to match against the path Value.
+	// +optional
+	Type *PathMatchType `json:"type,omitempty"`
+
+	// Value of the HTTP path to match against.
+	// +optional
+	Value *string `json:"value,omitempty"`
+}
+
+// HeaderMatchType specifies the semantics of how HTTP header values should be
+// compared.
+type HeaderMatchType string
+
+const (
+	HeaderMatchExact HeaderMatchType = "Exact"
+)
+
+// HTTPHeaderMatch describes how to select a HTTP route by matching HTTP
+// request headers.
+type HTTPHeaderMatch struct {
+	// Type specifies how to match against the value of the header.
+	// +optional
+	Type *HeaderMatchType `json:"type,omitempty"`
+
+	// Name is the name of the HTTP Header to be matched.
+	Name string `json:"name"`
+
+	// Value is the value of HTTP Header to be matched.
+	Value string `json:"value"`
+}
+
+// HTTPRouteMatch defines the predicate used to match requests to a given
+// action. Multiple match types are ANDed together.
+type HTTPRouteMatch struct {
+	// Path specifies a HTTP request path matcher.
+	// +optional
+	Path *HTTPPathMatch `json:"path,omitempty"`
+
+	// Headers specifies HTTP request header matchers.
+	// +optional
+	Headers []HTTPHeaderMatch `json:"headers,omitempty"`
+}
+
+// HTTPRouteFilterType identifies a type of HTTPRoute filter.
+type HTTPRouteFilterType string
+
+const (
+	// HTTPRouteFilterURLRewrite can be used to modify a request during
+	// forwarding.
+	HTTPRouteFilterURLRewrite HTTPRouteFilterType = "URLRewrite"
+)
+
+// HTTPRouteFilter defines processing steps that must be completed during the
+// request or response lifecycle.
+type HTTPRouteFilter struct {
+	// Type identifies the type of filter to apply.
+	Type HTTPRouteFilterType `json:"type"`
+
+	// URLRewrite defines a schema for a filter that modifies a request during
+	// forwarding.
+	// +optional
+	URLRewrite *HTTPURLRewriteFilter `json:"urlRewrite,omitempty"`
+}
+
+// HTTPURLRewriteFilter defines a filter that modifies a request during
+// forwarding.
+type HTTPURLRewriteFilter struct {
+	// Path defines a path rewrite.
+	// +optional
+	Path *HTTPPathModifier `json:"path,omitempty"`
+}
+
+// HTTPPathModifierType defines the type of path redirect or rewrite.
+type HTTPPathModifierType string
+
+const (
+	// This type of modifier indicates that any prefix path matches will be
+	// replaced by the substitution value.
+	PrefixMatchHTTPPathModifier HTTPPathModifierType = "ReplacePrefixMatch"
+)
+
+// HTTPPathModifier defines configuration for path modifiers.
+type HTTPPathModifier struct {
+	// Type defines the type of path modifier.
+	Type HTTPPathModifierType `json:"type"`
+
+	// ReplacePrefixMatch specifies the value with which to replace the prefix
+	// match of a request during a rewrite or redirect.
+	// +optional
+	ReplacePrefixMatch *string `json:"replacePrefixMatch,omitempty"`
+}
+
+// HTTPBackendRef defines how a HTTPRoute should forward an HTTP request.
+type HTTPBackendRef struct {
+	BackendRef `json:",inline"`
+}
+
+// BackendRef defines how a Route should forward a request to a Kubernetes
+// resource.
+type BackendRef struct {
+	BackendObjectReference `json:",inline"`
+
+	// Weight specifies the proportion of requests forwarded to the referenced
+	// backend.
+	// +optional
+	Weight *int32 `json:"weight,omitempty"`
+}
+
+// BackendObjectReference defines how an ObjectReference that is specific to
+// BackendRef.
+type BackendObjectReference struct {
+	// Group is the group of the referent.
+	// +optional
+	Group *string `json:"group,omitempty"`
+
+	// Kind is kind of the referent, defaults to Service.
+	// +optional
+	Kind *string `json:"kind,omitempty"`
+
+	// Name is the name of the referent.
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the backend.
+	// +optional
+	Namespace *string `json:"namespace,omitempty"`
+
+	// Port specifies the destination port number to use for this resource.
+	// +optional
+	Port *int32 `json:"port,omitempty"`
+}
+
+// HTTPRouteStatus defines the observed state of HTTPRoute.
+type HTTPRouteStatus struct {
+	RouteStatus `json:",inline"`
+}
+
+// RouteStatus defines the common attributes that all Routes MUST include
+// within their status.
+type RouteStatus struct {
+	// Parents is a list of parent resources (usually Gateways) that are
+	// associated with the route, and the status of the route with respect to
+	// each parent.
+	Parents []RouteParentStatus `json:"parents"`
+}
+
+// RouteParentStatus describes the status of a route with respect to an
+// associated Parent.
+type RouteParentStatus struct {
+	// ParentRef corresponds with a ParentRef in the spec that this
+	// RouteParentStatus struct describes the status of.
+	ParentRef ParentReference `json:"parentRef"`
+
+	// ControllerName is a domain/path string that indicates the name of the
+	// controller that wrote this status.
+	ControllerName string `json:"controllerName"`
+
+	// Conditions describes the status of the route with respect to the
+	// Gateway.
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// RouteConditionType is a type of condition for a route.
+type RouteConditionType string
+
+const (
+	// This condition indicates whether the route has been accepted or
+	// rejected by a Gateway, and why.
+	RouteConditionAccepted RouteConditionType = "Accepted"
+
+	// This condition indicates whether the controller was able to resolve all
+	// the object references for the Route.
+	RouteConditionResolvedRefs RouteConditionType = "ResolvedRefs"
+)
+
+// Condition contains details for one aspect of the current state of this API
+// Resource. Mirrors metav1.Condition, which is not available in this version
+// of apimachinery.
+type Condition struct {
+	// Type of condition in CamelCase.
+	Type string `json:"type"`
+
+	// Status of the condition, one of True, False, Unknown.
+	Status metav1.ConditionStatus `json:"status"`
+
+	// ObservedGeneration represents the .metadata.generation that the
+	// condition was set based upon.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastTransitionTime is the last time the condition transitioned from
+	// one status to another.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+
+	// Reason contains a programmatic identifier indicating the reason for
+	// the condition's last transition.
+	Reason string `json:"reason"`
+
+	// Message is a human readable message indicating details about the
+	// transition.
+	// +optional
+	Message string `json:"message"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HTTPRoute{}, &HTTPRouteList{})
+}
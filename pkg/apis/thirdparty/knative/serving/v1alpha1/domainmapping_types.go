@@ -0,0 +1,130 @@
+/*
+Copyright 2020 The Knative Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	apis "github.com/projectriff/system/pkg/apis"
+)
+
+var (
+	_ apis.Resource = (*DomainMapping)(nil)
+)
+
+// DomainMappingSpec describes the DomainMapping the user wishes to exist.
+type DomainMappingSpec struct {
+	// Ref specifies which Kubernetes object should be addressable via the
+	// DomainMapping's name. The referenced object must be in the same
+	// namespace as the DomainMapping.
+	Ref KReference `json:"ref"`
+
+	// TLS allows the DomainMapping to terminate TLS traffic with an existing secret.
+	// +optional
+	TLS *SecretTLS `json:"tls,omitempty"`
+}
+
+// KReference contains enough information to refer to another object.
+type KReference struct {
+	// Kind of the referent.
+	Kind string `json:"kind"`
+
+	// Namespace of the referent.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name of the referent.
+	Name string `json:"name"`
+
+	// API version of the referent.
+	// +optional
+	APIVersion string `json:"apiVersion,omitempty"`
+}
+
+// SecretTLS wrapper for TLS SecretName.
+type SecretTLS struct {
+	// SecretName is the name of the existing secret used to terminate TLS traffic.
+	SecretName string `json:"secretName"`
+}
+
+// DomainMappingStatus describes the current state of the DomainMapping.
+type DomainMappingStatus struct {
+	apis.Status `json:",inline"`
+
+	// URL is the URL of this DomainMapping.
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// Address holds the information needed for a DomainMapping to be the target of an event.
+	// +optional
+	Address *apis.Addressable `json:"address,omitempty"`
+}
+
+const (
+	// DomainMappingConditionReady is set when the DomainMapping is configured
+	// and the ingress is ready.
+	DomainMappingConditionReady = apis.ConditionReady
+)
+
+func (dms *DomainMappingStatus) GetObservedGeneration() int64 {
+	return dms.ObservedGeneration
+}
+
+func (dms *DomainMappingStatus) IsReady() bool {
+	return dms.GetCondition(dms.GetReadyConditionType()).IsTrue()
+}
+
+func (*DomainMappingStatus) GetReadyConditionType() apis.ConditionType {
+	return DomainMappingConditionReady
+}
+
+func (dms *DomainMappingStatus) GetCondition(t apis.ConditionType) *apis.Condition {
+	return dms.Status.GetCondition(t)
+}
+
+// +kubebuilder:object:root=true
+
+// DomainMapping is a mapping from a custom hostname to an Addressable.
+type DomainMapping struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DomainMappingSpec   `json:"spec,omitempty"`
+	Status DomainMappingStatus `json:"status,omitempty"`
+}
+
+func (*DomainMapping) GetGroupVersionKind() schema.GroupVersionKind {
+	return GroupVersion.WithKind("DomainMapping")
+}
+
+func (dm *DomainMapping) GetStatus() apis.ResourceStatus {
+	return &dm.Status
+}
+
+// +kubebuilder:object:root=true
+
+// DomainMappingList contains a list of DomainMapping
+type DomainMappingList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DomainMapping `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DomainMapping{}, &DomainMappingList{})
+}
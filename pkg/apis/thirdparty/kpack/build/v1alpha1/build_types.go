@@ -26,6 +26,12 @@ import (
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
+const (
+	BuildNumberLabel      = "image.build.pivotal.io/buildNumber"
+	ImageLabel            = "image.build.pivotal.io/image"
+	BuildReasonAnnotation = "image.build.pivotal.io/reason"
+)
+
 // BuildSpec is the spec for a Build resource.
 type BuildSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
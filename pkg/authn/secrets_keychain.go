@@ -12,7 +12,10 @@ type DockerSecretsKeychain struct {
 	regAuths map[string]*ggcrauthn.Basic
 }
 
-const DockerSecretAnnotation = "build.pivotal.io/docker"
+const (
+	DockerSecretAnnotation = "build.pivotal.io/docker"
+	GitSecretAnnotation    = "build.pivotal.io/git"
+)
 
 func NewSecretsKeychain(secrets []corev1.Secret) ggcrauthn.Keychain {
 	k := &DockerSecretsKeychain{
@@ -19,13 +19,20 @@ package build
 import (
 	"context"
 	"fmt"
+	"strconv"
 
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	kpackbuildv1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/kpack/build/v1alpha1"
+	"github.com/projectriff/system/pkg/authn"
 	"github.com/projectriff/system/pkg/controllers"
 	"github.com/projectriff/system/pkg/refs"
 )
@@ -33,9 +40,13 @@ import (
 // +kubebuilder:rbac:groups=build.projectriff.io,resources=applications,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=build.projectriff.io,resources=applications/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=build.pivotal.io,resources=images,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=build.pivotal.io,resources=builds,verbs=get;list;watch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list
+// +kubebuilder:rbac:groups=core,resources=secrets;serviceaccounts,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;update;patch;delete
 
-func ApplicationReconciler(c controllers.Config) *controllers.ParentReconciler {
+func ApplicationReconciler(c controllers.Config, logs PodLogReader, revisions GitRevisionResolver) *controllers.ParentReconciler {
 	c.Log = c.Log.WithName("Application")
 
 	return &controllers.ParentReconciler{
@@ -43,6 +54,9 @@ func ApplicationReconciler(c controllers.Config) *controllers.ParentReconciler {
 		SubReconcilers: []controllers.SubReconciler{
 			ApplicationTargetImageReconciler(c),
 			ApplicationChildImageReconciler(c),
+			ApplicationDockerfileBuildReconciler(c, revisions),
+			ApplicationBuildHistoryReconciler(c),
+			ApplicationBuildLogReconciler(c, logs),
 		},
 
 		Config: c,
@@ -54,7 +68,7 @@ func ApplicationTargetImageReconciler(c controllers.Config) controllers.SubRecon
 
 	return &controllers.SyncReconciler{
 		Sync: func(ctx context.Context, parent *buildv1alpha1.Application) error {
-			targetImage, err := resolveTargetImage(ctx, c.Client, parent)
+			targetImage, err := resolveTargetImage(ctx, c.Client, c.Scheme, parent)
 			if err != nil {
 				if err == errMissingDefaultPrefix {
 					parent.Status.MarkImageDefaultPrefixMissing(err.Error())
@@ -81,7 +95,7 @@ func ApplicationChildImageReconciler(c controllers.Config) controllers.SubReconc
 		ChildListType: &kpackbuildv1alpha1.ImageList{},
 
 		DesiredChild: func(parent *buildv1alpha1.Application) (*kpackbuildv1alpha1.Image, error) {
-			if parent.Spec.Source == nil {
+			if parent.Spec.Source == nil || parent.Spec.Strategy == buildv1alpha1.BuildStrategyDockerfile {
 				return nil, nil
 			}
 
@@ -116,8 +130,10 @@ func ApplicationChildImageReconciler(c controllers.Config) controllers.SubReconc
 		},
 		ReflectChildStatusOnParent: func(parent *buildv1alpha1.Application, child *kpackbuildv1alpha1.Image, err error) {
 			if child == nil {
-				// TODO resolve to a digest?
-				parent.Status.LatestImage = parent.Status.TargetImage
+				if parent.Spec.Source == nil || parent.Spec.Strategy != buildv1alpha1.BuildStrategyDockerfile {
+					// TODO resolve to a digest?
+					parent.Status.LatestImage = parent.Status.TargetImage
+				}
 				parent.Status.MarkBuildNotUsed()
 			} else {
 				parent.Status.KpackImageRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
@@ -142,3 +158,188 @@ func ApplicationChildImageReconciler(c controllers.Config) controllers.SubReconc
 		},
 	}
 }
+
+func ApplicationDockerfileBuildReconciler(c controllers.Config, revisions GitRevisionResolver) controllers.SubReconciler {
+	c.Log = c.Log.WithName("DockerfileBuild")
+
+	return &controllers.SyncReconciler{
+		Setup: func(mgr controllers.Manager, bldr *controllers.Builder) error {
+			bldr.Owns(&batchv1.Job{})
+			return nil
+		},
+		Sync: func(ctx context.Context, parent *buildv1alpha1.Application) (ctrl.Result, error) {
+			var jobs batchv1.JobList
+			if err := c.List(ctx, &jobs, client.InNamespace(parent.Namespace), client.MatchingLabels{buildv1alpha1.ApplicationLabelKey: parent.Name}); err != nil {
+				return ctrl.Result{}, err
+			}
+
+			var desired *batchv1.Job
+			var commit string
+			result := ctrl.Result{}
+			if parent.Spec.Source != nil && parent.Spec.Strategy == buildv1alpha1.BuildStrategyDockerfile {
+				credentials, err := resolveBuildCredentials(ctx, c.Client, parent.Namespace, authn.DockerSecretAnnotation)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+				var gitCredentials []corev1.Secret
+				if git := parent.Spec.Source.Git; git != nil {
+					gitCredentials, err = resolveBuildCredentials(ctx, c.Client, parent.Namespace, authn.GitSecretAnnotation)
+					if err != nil {
+						return ctrl.Result{}, err
+					}
+					// branches and tags move, poll for new commits
+					commit, err = revisions.ResolveRevision(ctx, git, gitCredentials)
+					if err != nil {
+						parent.Status.MarkDockerfileBuildRevisionUnresolved(err.Error())
+						return ctrl.Result{}, err
+					}
+					result.RequeueAfter = gitPollingInterval
+				}
+				desired = newDockerfileJob(parent, commit, credentials, gitCredentials)
+			}
+
+			// jobs are immutable, a changed build replaces prior jobs
+			var actual *batchv1.Job
+			buildNumber := int64(0)
+			for _, build := range parent.Status.Builds {
+				if build.BuildNumber > buildNumber {
+					buildNumber = build.BuildNumber
+				}
+			}
+			for i := range jobs.Items {
+				job := &jobs.Items[i]
+				if !metav1.IsControlledBy(job, parent) {
+					continue
+				}
+				if n, err := strconv.ParseInt(job.Annotations[dockerfileBuildNumberAnnotationKey], 10, 64); err == nil && n > buildNumber {
+					buildNumber = n
+				}
+				if desired != nil && actual == nil && job.Annotations[dockerfileBuildHashAnnotationKey] == desired.Annotations[dockerfileBuildHashAnnotationKey] {
+					actual = job
+					continue
+				}
+				c.Log.Info("deleting stale job", "job", job.Name)
+				if err := c.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+					c.Recorder.Eventf(parent, corev1.EventTypeWarning, "DeleteFailed",
+						"Failed to delete Job %q: %v", job.Name, err)
+					return ctrl.Result{}, err
+				}
+				c.Recorder.Eventf(parent, corev1.EventTypeNormal, "Deleted",
+					"Deleted Job %q", job.Name)
+			}
+
+			if desired == nil {
+				parent.Status.MarkDockerfileBuildNotUsed()
+				return ctrl.Result{}, nil
+			}
+
+			if actual == nil {
+				if err := ctrl.SetControllerReference(parent, desired, c.Scheme); err != nil {
+					return ctrl.Result{}, err
+				}
+				desired.Annotations[dockerfileBuildNumberAnnotationKey] = strconv.FormatInt(buildNumber+1, 10)
+				desired.Annotations[dockerfileBuildReasonAnnotationKey] = dockerfileBuildReason(parent, commit)
+				c.Log.Info("creating job", "job", desired.Spec)
+				if err := c.Create(ctx, desired); err != nil {
+					c.Recorder.Eventf(parent, corev1.EventTypeWarning, "CreationFailed",
+						"Failed to create Job %q: %v", desired.GenerateName, err)
+					return ctrl.Result{}, err
+				}
+				c.Recorder.Eventf(parent, corev1.EventTypeNormal, "Created",
+					"Created Job %q", desired.Name)
+				actual = desired
+			}
+
+			parent.Status.JobRef = refs.NewTypedLocalObjectReferenceForObject(actual, c.Scheme)
+			parent.Status.PropagateJobStatus(&actual.Status)
+			if !parent.Status.GetCondition(buildv1alpha1.ApplicationConditionDockerfileBuildReady).IsTrue() {
+				return result, nil
+			}
+			image, err := resolveDockerfileImage(ctx, c.APIReader, actual, parent.Status.TargetImage)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if image == "" {
+				parent.Status.MarkDockerfileBuildDigestMissing(actual.Name)
+				return result, nil
+			}
+			parent.Status.LatestImage = image
+			// jobs are replaced by later builds, the history is kept on the status
+			parent.Status.Builds = mergeBuildHistory(parent.Status.Builds, newDockerfileBuildRecord(actual, parent.Spec.Source, commit, image))
+			return result, nil
+		},
+
+		Config: c,
+	}
+}
+
+func ApplicationBuildHistoryReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("BuildHistory")
+
+	return &controllers.SyncReconciler{
+		Setup: func(mgr controllers.Manager, bldr *controllers.Builder) error {
+			// kpack copies the Image's labels to each Build
+			bldr.Watches(&source.Kind{Type: &kpackbuildv1alpha1.Build{}}, controllers.EnqueueLabeled(buildv1alpha1.ApplicationLabelKey))
+			return nil
+		},
+		Sync: func(ctx context.Context, parent *buildv1alpha1.Application) error {
+			if parent.Status.KpackImageRef == nil {
+				if parent.Status.JobRef == nil {
+					parent.Status.Builds = nil
+				}
+				return nil
+			}
+			builds, err := resolveBuildHistory(ctx, c.Client, parent.Namespace, parent.Status.KpackImageRef.Name, parent.Status.Builds)
+			if err != nil {
+				return err
+			}
+			parent.Status.Builds = builds
+			return nil
+		},
+
+		Config: c,
+	}
+}
+
+func ApplicationBuildLogReconciler(c controllers.Config, logs PodLogReader) controllers.SubReconciler {
+	c.Log = c.Log.WithName("BuildLog")
+
+	return &controllers.SyncReconciler{
+		Setup: func(mgr controllers.Manager, bldr *controllers.Builder) error {
+			bldr.Owns(&corev1.ConfigMap{})
+			return nil
+		},
+		Sync: func(ctx context.Context, parent *buildv1alpha1.Application) error {
+			var build string
+			var pod *corev1.Pod
+			var err error
+			switch {
+			case parent.Status.KpackImageRef != nil:
+				build, pod, err = resolveFailedKpackBuild(ctx, c, parent.Namespace, parent.Status.KpackImageRef.Name)
+			case parent.Status.JobRef != nil && parent.Status.GetCondition(buildv1alpha1.ApplicationConditionDockerfileBuildReady).IsFalse():
+				build = parent.Status.JobRef.Name
+				pod, err = resolveDockerfileJobPod(ctx, c.APIReader, parent.Namespace, build)
+			}
+			if err != nil {
+				return err
+			}
+
+			summary, ref, err := reconcileBuildLog(ctx, c, logs, parent, buildv1alpha1.ApplicationLabelKey, build, pod)
+			if err != nil {
+				return err
+			}
+			parent.Status.BuildLogRef = ref
+			if summary == "" {
+				return nil
+			}
+			if parent.Status.JobRef != nil {
+				parent.Status.MarkDockerfileBuildFailed(summary)
+			} else {
+				parent.Status.MarkKpackBuildFailed(summary)
+			}
+			return nil
+		},
+
+		Config: c,
+	}
+}
@@ -17,14 +17,23 @@ limitations under the License.
 package build_test
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -45,6 +54,7 @@ func TestApplicationReconciler(t *testing.T) {
 	testImagePrefix := "example.com/repo"
 	testGitUrl := "git@example.com:repo.git"
 	testGitRevision := "master"
+	testGitCommit := "6c3b0e3d5d1e8a3b2ad7e2f3f4a5b6c7d8e9f0a1"
 	testSha256 := "cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e"
 	testConditionReason := "TestReason"
 	testConditionMessage := "meaningful, yet concise"
@@ -52,6 +62,7 @@ func TestApplicationReconciler(t *testing.T) {
 	testLabelValue := "test-label-value"
 	testBuildCacheName := "test-build-cache-000"
 
+	applicationConditionDockerfileBuildReady := factories.Condition().Type(buildv1alpha1.ApplicationConditionDockerfileBuildReady)
 	applicationConditionImageResolved := factories.Condition().Type(buildv1alpha1.ApplicationConditionImageResolved)
 	applicationConditionKpackImageReady := factories.Condition().Type(buildv1alpha1.ApplicationConditionKpackImageReady)
 	applicationConditionReady := factories.Condition().Type(buildv1alpha1.ApplicationConditionReady)
@@ -89,10 +100,156 @@ func TestApplicationReconciler(t *testing.T) {
 		}).
 		StatusObservedGeneration(1)
 
+	kpackBuildGiven := factories.KpackBuild().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace).
+				AddLabel(buildv1alpha1.ApplicationLabelKey, testName).
+				Created(1)
+		}).
+		Image(kpackImageGiven.Create().GetName()).
+		SourceGit(testGitUrl, testGitCommit)
+
 	cmImagePrefix := factories.ConfigMap().
 		NamespaceName(testNamespace, "riff-build").
 		AddData("default-image-prefix", "")
 
+	testGitCommitNext := "0d9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f1a"
+	gitRevisions := testGitRevisionResolver{
+		"https://example.com/repo.git#master": testGitCommit,
+		"https://example.com/repo.git#main":   testGitCommitNext,
+	}
+
+	dockerfileJobSpec := func(commit string) batchv1.JobSpec {
+		return batchv1.JobSpec{
+			BackoffLimit: rtesting.Int32Ptr(0),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						buildv1alpha1.ApplicationLabelKey: testName,
+					},
+				},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: "riff-build",
+					RestartPolicy:      corev1.RestartPolicyNever,
+					InitContainers: []corev1.Container{
+						{
+							Name:  "source",
+							Image: "alpine/git:v2.24.1",
+							Command: []string{"/bin/sh", "-c", `set -e
+git init -q .
+git remote add origin "$GIT_URL"
+git fetch -q --depth=1 origin "$GIT_COMMIT" || git fetch -q origin
+git checkout -q "$GIT_COMMIT"`},
+							Env: []corev1.EnvVar{
+								{Name: "GIT_URL", Value: "https://example.com/repo.git"},
+								{Name: "GIT_COMMIT", Value: commit},
+							},
+							WorkingDir: "/workspace",
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "workspace", MountPath: "/workspace"},
+							},
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:  "build",
+							Image: "gcr.io/kaniko-project/executor:debug-v0.17.1",
+							Command: []string{"/busybox/sh", "-c", `set -e
+mkdir -p /kaniko/.docker
+printf '{"auths":{}}' > /kaniko/.docker/config.json
+exec /kaniko/executor "$@"`, "--"},
+							Args: []string{
+								"--context=dir:///workspace",
+								"--dockerfile=Dockerfile",
+								fmt.Sprintf("--destination=%s/%s", testImagePrefix, testName),
+								"--digest-file=/dev/termination-log",
+							},
+							Env:        []corev1.EnvVar{},
+							WorkingDir: "/workspace",
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "workspace", MountPath: "/workspace"},
+							},
+							TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+						},
+					},
+					Volumes: []corev1.Volume{
+						{Name: "workspace", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+					},
+				},
+			},
+		}
+	}
+	dockerfileJobHash := func(spec batchv1.JobSpec) string {
+		b, _ := json.Marshal(spec)
+		return fmt.Sprintf("%x", sha256.Sum256(b))
+	}
+
+	appDockerfile := appMinimal.
+		Image("%s/%s", testImagePrefix, testName).
+		SourceGit("https://example.com/repo.git", testGitRevision).
+		Strategy(buildv1alpha1.BuildStrategyDockerfile)
+	dockerfileJobCreate := factories.Job(&batchv1.Job{Spec: dockerfileJobSpec(testGitCommit)}).
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace).
+				GenerateName("%s-dockerfile-", testName).
+				AddLabel(buildv1alpha1.ApplicationLabelKey, testName).
+				AddAnnotation("build.projectriff.io/dockerfile-build-hash", dockerfileJobHash(dockerfileJobSpec(testGitCommit))).
+				AddAnnotation("build.projectriff.io/dockerfile-build-number", "1").
+				AddAnnotation("build.projectriff.io/dockerfile-build-reason", "CONFIG").
+				ControlledBy(appMinimal, scheme)
+		})
+	dockerfileJobGiven := dockerfileJobCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Name("%s-dockerfile-001", testName).
+				Created(1)
+		})
+	dockerfileBuildRecord := buildv1alpha1.BuildRecord{
+		Name:        fmt.Sprintf("%s-dockerfile-001", testName),
+		BuildNumber: 1,
+		Reason:      "CONFIG",
+		Source: &buildv1alpha1.Source{
+			Git: &buildv1alpha1.Git{URL: "https://example.com/repo.git", Revision: testGitCommit},
+		},
+		Image:     fmt.Sprintf("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+		StartTime: &metav1.Time{Time: time.Unix(1, 0)},
+	}
+
+	dockerfileBuildRecordPrevious := *dockerfileBuildRecord.DeepCopy()
+	dockerfileBuildRecordPrevious.Name = fmt.Sprintf("%s-dockerfile-000", testName)
+
+	// the init container authenticates to the git host with the credential
+	dockerfileJobSpecGitCredential := dockerfileJobSpec(testGitCommit)
+	dockerfileJobSpecGitCredential.Template.Spec.InitContainers[0].Command[2] = `set -e
+git config --global "http.${GIT_HOST_0}.extraHeader" "Authorization: Basic $(printf '%s:%s' "$(cat /var/build-secrets/git/git-credential/username)" "$(cat /var/build-secrets/git/git-credential/password)" | base64 | tr -d '\n')"
+git init -q .
+git remote add origin "$GIT_URL"
+git fetch -q --depth=1 origin "$GIT_COMMIT" || git fetch -q origin
+git checkout -q "$GIT_COMMIT"`
+	dockerfileJobSpecGitCredential.Template.Spec.InitContainers[0].Env = append(dockerfileJobSpecGitCredential.Template.Spec.InitContainers[0].Env,
+		corev1.EnvVar{Name: "GIT_HOST_0", Value: "https://example.com/"},
+	)
+	dockerfileJobSpecGitCredential.Template.Spec.InitContainers[0].VolumeMounts = append(dockerfileJobSpecGitCredential.Template.Spec.InitContainers[0].VolumeMounts,
+		corev1.VolumeMount{Name: "git-credential-0", MountPath: "/var/build-secrets/git/git-credential", ReadOnly: true},
+	)
+	dockerfileJobSpecGitCredential.Template.Spec.Volumes = append(dockerfileJobSpecGitCredential.Template.Spec.Volumes,
+		corev1.Volume{Name: "git-credential-0", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "git-credential"}}},
+	)
+	dockerfilePodGiven := factories.Pod().
+		NamespaceName(testNamespace, fmt.Sprintf("%s-dockerfile-001-abcde", testName)).
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.AddLabel("job-name", dockerfileJobGiven.Create().GetName())
+		}).
+		StatusContainerTerminated("build", 0, fmt.Sprintf("sha256:%s", testSha256))
+
+	dockerfileBuildLogSummary := `build step "build" failed with exit code 1: error building image: error building stage: failed to execute command: exit status 127`
+	podLogs := testPodLogReader{
+		fmt.Sprintf("%s/%s-dockerfile-001-failed/build", testNamespace, testName): strings.Join([]string{
+			"INFO[0001] RUN make",
+			"/bin/sh: make: not found",
+			"error building image: error building stage: failed to execute command: exit status 127",
+		}, "\n"),
+	}
+
 	table := rtesting.Table{{
 		Name: "application does not exist",
 		Key:  testKey,
@@ -130,6 +287,7 @@ func TestApplicationReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			appMinimal.
 				StatusConditions(
+					applicationConditionDockerfileBuildReady.True(),
 					applicationConditionImageResolved.True(),
 					applicationConditionKpackImageReady.Unknown(),
 					applicationConditionReady.Unknown(),
@@ -157,6 +315,7 @@ func TestApplicationReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			appMinimal.
 				StatusConditions(
+					applicationConditionDockerfileBuildReady.True(),
 					applicationConditionImageResolved.True(),
 					applicationConditionKpackImageReady.Unknown(),
 					applicationConditionReady.Unknown(),
@@ -188,6 +347,7 @@ func TestApplicationReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			appMinimal.
 				StatusConditions(
+					applicationConditionDockerfileBuildReady.True(),
 					applicationConditionImageResolved.True(),
 					applicationConditionKpackImageReady.Unknown(),
 					applicationConditionReady.Unknown(),
@@ -216,6 +376,7 @@ func TestApplicationReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			appMinimal.
 				StatusConditions(
+					applicationConditionDockerfileBuildReady.True(),
 					applicationConditionImageResolved.True(),
 					applicationConditionKpackImageReady.Unknown(),
 					applicationConditionReady.Unknown(),
@@ -223,6 +384,61 @@ func TestApplicationReconciler(t *testing.T) {
 				StatusKpackImageRef("%s-application-001", testName).
 				StatusTargetImage("%s/%s", testImagePrefix, testName),
 		},
+	}, {
+		Name: "default image, template",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			cmImagePrefix.
+				AddData("default-image-prefix", testImagePrefix).
+				AddData("default-image-template", "{{prefix}}/{{namespace}}/{{kind}}-{{name}}"),
+			appMinimal.
+				SourceGit(testGitUrl, testGitRevision),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(appValid, scheme, corev1.EventTypeNormal, "Created",
+				`Created Image "%s-application-001"`, testName),
+			rtesting.NewEvent(appValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			kpackImageCreate.
+				Tag("%s/%s/application-%s", testImagePrefix, testNamespace, testName),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			appMinimal.
+				StatusConditions(
+					applicationConditionDockerfileBuildReady.True(),
+					applicationConditionImageResolved.True(),
+					applicationConditionKpackImageReady.Unknown(),
+					applicationConditionReady.Unknown(),
+				).
+				StatusKpackImageRef("%s-application-001", testName).
+				StatusTargetImage("%s/%s/application-%s", testImagePrefix, testNamespace, testName),
+		},
+	}, {
+		Name: "default image, invalid template",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			cmImagePrefix.
+				AddData("default-image-prefix", testImagePrefix).
+				AddData("default-image-template", "{{prefix}}/{{NAME}}"),
+			appMinimal.
+				SourceGit(testGitUrl, testGitRevision),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(appValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			appMinimal.
+				StatusConditions(
+					applicationConditionDockerfileBuildReady.Unknown(),
+					applicationConditionImageResolved.False().Reason("ImageInvalid", `invalid default-image-template: template: default-image-template:1: function "NAME" not defined`),
+					applicationConditionKpackImageReady.Unknown(),
+					applicationConditionReady.False().Reason("ImageInvalid", `invalid default-image-template: template: default-image-template:1: function "NAME" not defined`),
+				),
+		},
+		ShouldErr: true,
 	}, {
 		Name: "default image, missing",
 		Key:  testKey,
@@ -237,6 +453,7 @@ func TestApplicationReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			appMinimal.
 				StatusConditions(
+					applicationConditionDockerfileBuildReady.Unknown(),
 					applicationConditionImageResolved.False().Reason("DefaultImagePrefixMissing", "missing default image prefix"),
 					applicationConditionKpackImageReady.Unknown(),
 					applicationConditionReady.False().Reason("DefaultImagePrefixMissing", "missing default image prefix"),
@@ -258,6 +475,7 @@ func TestApplicationReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			appMinimal.
 				StatusConditions(
+					applicationConditionDockerfileBuildReady.Unknown(),
 					applicationConditionImageResolved.False().Reason("DefaultImagePrefixMissing", "missing default image prefix"),
 					applicationConditionKpackImageReady.Unknown(),
 					applicationConditionReady.False().Reason("DefaultImagePrefixMissing", "missing default image prefix"),
@@ -283,6 +501,7 @@ func TestApplicationReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			appMinimal.
 				StatusConditions(
+					applicationConditionDockerfileBuildReady.Unknown(),
 					applicationConditionImageResolved.False().Reason("ImageInvalid", "inducing failure for get ConfigMap"),
 					applicationConditionKpackImageReady.Unknown(),
 					applicationConditionReady.False().Reason("ImageInvalid", "inducing failure for get ConfigMap"),
@@ -304,6 +523,7 @@ func TestApplicationReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			appMinimal.
 				StatusConditions(
+					applicationConditionDockerfileBuildReady.True(),
 					applicationConditionImageResolved.True(),
 					applicationConditionKpackImageReady.True(),
 					applicationConditionReady.True(),
@@ -329,6 +549,7 @@ func TestApplicationReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			appMinimal.
 				StatusConditions(
+					applicationConditionDockerfileBuildReady.True(),
 					applicationConditionImageResolved.True(),
 					applicationConditionKpackImageReady.True(),
 					applicationConditionReady.True(),
@@ -356,6 +577,7 @@ func TestApplicationReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			appMinimal.
 				StatusConditions(
+					applicationConditionDockerfileBuildReady.True(),
 					applicationConditionImageResolved.True(),
 					applicationConditionKpackImageReady.False().Reason(testConditionReason, testConditionMessage),
 					applicationConditionReady.False().Reason(testConditionReason, testConditionMessage),
@@ -364,6 +586,175 @@ func TestApplicationReconciler(t *testing.T) {
 				StatusTargetImage("%s/%s", testImagePrefix, testName).
 				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
 		},
+	}, {
+		Name: "kpack image ready, build history",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			appValid,
+			kpackImageGiven.
+				StatusReady().
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+			kpackBuildGiven.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Name("%s-build-1", kpackImageGiven.Create().GetName())
+				}).
+				BuildNumber(1).
+				Reason("CONFIG").
+				StatusSucceeded(2).
+				StatusBuildpack("io.projectriff.example", "0.1.0").
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, "0000000000000000000000000000000000000000000000000000000000000001"),
+			kpackBuildGiven.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Name("%s-build-2", kpackImageGiven.Create().GetName())
+				}).
+				BuildNumber(2).
+				Reason("COMMIT").
+				StatusConditions(
+					factories.Condition().Type(apis.ConditionSucceeded).False().Reason(testConditionReason, testConditionMessage),
+				),
+			kpackBuildGiven.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Name("%s-build-3", kpackImageGiven.Create().GetName())
+				}).
+				BuildNumber(3).
+				Reason("COMMIT").
+				StatusSucceeded(4).
+				StatusBuildpack("io.projectriff.example", "0.1.0").
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+			kpackBuildGiven.
+				NamespaceName(testNamespace, "other-build-1").
+				Image("other").
+				BuildNumber(1).
+				StatusSucceeded(2).
+				StatusLatestImage("%s/other@sha256:%s", testImagePrefix, testSha256),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(appValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			appMinimal.
+				StatusConditions(
+					applicationConditionDockerfileBuildReady.True(),
+					applicationConditionImageResolved.True(),
+					applicationConditionKpackImageReady.True(),
+					applicationConditionReady.True(),
+				).
+				StatusKpackImageRef(kpackImageGiven.Create().GetName()).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256).
+				StatusBuilds(
+					buildv1alpha1.BuildRecord{
+						Name:        fmt.Sprintf("%s-build-3", kpackImageGiven.Create().GetName()),
+						BuildNumber: 3,
+						Reason:      "COMMIT",
+						Source: &buildv1alpha1.Source{
+							Git: &buildv1alpha1.Git{URL: testGitUrl, Revision: testGitCommit},
+						},
+						Image:          fmt.Sprintf("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+						Buildpacks:     []buildv1alpha1.BuildpackMetadata{{ID: "io.projectriff.example", Version: "0.1.0"}},
+						StartTime:      &metav1.Time{Time: time.Unix(1, 0)},
+						CompletionTime: &metav1.Time{Time: time.Unix(4, 0)},
+					},
+					buildv1alpha1.BuildRecord{
+						Name:        fmt.Sprintf("%s-build-1", kpackImageGiven.Create().GetName()),
+						BuildNumber: 1,
+						Reason:      "CONFIG",
+						Source: &buildv1alpha1.Source{
+							Git: &buildv1alpha1.Git{URL: testGitUrl, Revision: testGitCommit},
+						},
+						Image:          fmt.Sprintf("%s/%s@sha256:%s", testImagePrefix, testName, "0000000000000000000000000000000000000000000000000000000000000001"),
+						Buildpacks:     []buildv1alpha1.BuildpackMetadata{{ID: "io.projectriff.example", Version: "0.1.0"}},
+						StartTime:      &metav1.Time{Time: time.Unix(1, 0)},
+						CompletionTime: &metav1.Time{Time: time.Unix(2, 0)},
+					},
+				),
+		},
+	}, {
+		Name: "kpack image ready, build history retains pruned builds",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			appValid.
+				StatusBuilds(
+					buildv1alpha1.BuildRecord{
+						Name:        fmt.Sprintf("%s-build-1", kpackImageGiven.Create().GetName()),
+						BuildNumber: 1,
+						Image:       fmt.Sprintf("%s/%s@sha256:%s", testImagePrefix, testName, "0000000000000000000000000000000000000000000000000000000000000001"),
+					},
+				),
+			kpackImageGiven.
+				StatusReady().
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+			kpackBuildGiven.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Name("%s-build-2", kpackImageGiven.Create().GetName())
+				}).
+				BuildNumber(2).
+				StatusSucceeded(4).
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(appValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			appMinimal.
+				StatusConditions(
+					applicationConditionDockerfileBuildReady.True(),
+					applicationConditionImageResolved.True(),
+					applicationConditionKpackImageReady.True(),
+					applicationConditionReady.True(),
+				).
+				StatusKpackImageRef(kpackImageGiven.Create().GetName()).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256).
+				StatusBuilds(
+					buildv1alpha1.BuildRecord{
+						Name:        fmt.Sprintf("%s-build-2", kpackImageGiven.Create().GetName()),
+						BuildNumber: 2,
+						Source: &buildv1alpha1.Source{
+							Git: &buildv1alpha1.Git{URL: testGitUrl, Revision: testGitCommit},
+						},
+						Image:          fmt.Sprintf("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+						StartTime:      &metav1.Time{Time: time.Unix(1, 0)},
+						CompletionTime: &metav1.Time{Time: time.Unix(4, 0)},
+					},
+					buildv1alpha1.BuildRecord{
+						Name:        fmt.Sprintf("%s-build-1", kpackImageGiven.Create().GetName()),
+						BuildNumber: 1,
+						Image:       fmt.Sprintf("%s/%s@sha256:%s", testImagePrefix, testName, "0000000000000000000000000000000000000000000000000000000000000001"),
+					},
+				),
+		},
+	}, {
+		Name: "kpack build list error",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("list", "BuildList"),
+		},
+		GivenObjects: []rtesting.Factory{
+			appValid,
+			kpackImageGiven.
+				StatusReady().
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(appValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			appMinimal.
+				StatusConditions(
+					applicationConditionDockerfileBuildReady.True(),
+					applicationConditionImageResolved.True(),
+					applicationConditionKpackImageReady.True(),
+					applicationConditionReady.True(),
+				).
+				StatusKpackImageRef(kpackImageGiven.Create().GetName()).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+		},
 	}, {
 		Name: "kpack image create error",
 		Key:  testKey,
@@ -386,6 +777,7 @@ func TestApplicationReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			appValid.
 				StatusConditions(
+					applicationConditionDockerfileBuildReady.Unknown(),
 					applicationConditionImageResolved.True(),
 					applicationConditionKpackImageReady.Unknown(),
 					applicationConditionReady.Unknown(),
@@ -412,6 +804,7 @@ func TestApplicationReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			appValid.
 				StatusConditions(
+					applicationConditionDockerfileBuildReady.True(),
 					applicationConditionImageResolved.True(),
 					applicationConditionKpackImageReady.Unknown(),
 					applicationConditionReady.Unknown(),
@@ -444,6 +837,7 @@ func TestApplicationReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			appValid.
 				StatusConditions(
+					applicationConditionDockerfileBuildReady.True(),
 					applicationConditionImageResolved.True(),
 					applicationConditionKpackImageReady.Unknown(),
 					applicationConditionReady.Unknown(),
@@ -475,6 +869,7 @@ func TestApplicationReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			appValid.
 				StatusConditions(
+					applicationConditionDockerfileBuildReady.Unknown(),
 					applicationConditionImageResolved.True(),
 					applicationConditionKpackImageReady.Unknown(),
 					applicationConditionReady.Unknown(),
@@ -498,6 +893,7 @@ func TestApplicationReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			appValid.
 				StatusConditions(
+					applicationConditionDockerfileBuildReady.Unknown(),
 					applicationConditionImageResolved.True(),
 					applicationConditionKpackImageReady.Unknown(),
 					applicationConditionReady.Unknown(),
@@ -525,6 +921,7 @@ func TestApplicationReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			appMinimal.
 				StatusConditions(
+					applicationConditionDockerfileBuildReady.True(),
 					applicationConditionImageResolved.True(),
 					applicationConditionKpackImageReady.Unknown(),
 					applicationConditionReady.Unknown(),
@@ -563,6 +960,7 @@ func TestApplicationReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			appMinimal.
 				StatusConditions(
+					applicationConditionDockerfileBuildReady.True(),
 					applicationConditionImageResolved.True(),
 					applicationConditionKpackImageReady.Unknown(),
 					applicationConditionReady.Unknown(),
@@ -596,6 +994,7 @@ func TestApplicationReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			appMinimal.
 				StatusConditions(
+					applicationConditionDockerfileBuildReady.Unknown(),
 					applicationConditionImageResolved.True(),
 					applicationConditionKpackImageReady.Unknown(),
 					applicationConditionReady.Unknown(),
@@ -616,6 +1015,7 @@ func TestApplicationReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			appMinimal.
 				StatusConditions(
+					applicationConditionDockerfileBuildReady.True(),
 					applicationConditionImageResolved.True(),
 					applicationConditionKpackImageReady.True(),
 					applicationConditionReady.True(),
@@ -644,6 +1044,7 @@ func TestApplicationReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			appMinimal.
 				StatusConditions(
+					applicationConditionDockerfileBuildReady.True(),
 					applicationConditionImageResolved.True(),
 					applicationConditionKpackImageReady.True(),
 					applicationConditionReady.True(),
@@ -676,20 +1077,410 @@ func TestApplicationReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			appMinimal.
 				StatusConditions(
+					applicationConditionDockerfileBuildReady.Unknown(),
 					applicationConditionImageResolved.True(),
 					applicationConditionKpackImageReady.Unknown(),
 					applicationConditionReady.Unknown(),
 				).
 				StatusTargetImage("%s/%s", testImagePrefix, testName),
 		},
+	}, {
+		Name: "dockerfile build, create job",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			appDockerfile,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(appDockerfile, scheme, corev1.EventTypeNormal, "Created",
+				`Created Job "%s-dockerfile-001"`, testName),
+			rtesting.NewEvent(appDockerfile, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			dockerfileJobCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			appMinimal.
+				StatusConditions(
+					applicationConditionDockerfileBuildReady.Unknown().Reason("Building", ""),
+					applicationConditionImageResolved.True(),
+					applicationConditionKpackImageReady.True(),
+					applicationConditionReady.Unknown().Reason("Building", ""),
+				).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusJobRef("%s-dockerfile-001", testName),
+		},
+		ExpectedResult: ctrl.Result{RequeueAfter: time.Minute},
+	}, {
+		Name: "dockerfile build, create job error",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			appDockerfile,
+		},
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("create", "Job"),
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(appDockerfile, scheme, corev1.EventTypeWarning, "CreationFailed",
+				`Failed to create Job "%s-dockerfile-": inducing failure for create Job`, testName),
+			rtesting.NewEvent(appDockerfile, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			dockerfileJobCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			appMinimal.
+				StatusConditions(
+					applicationConditionDockerfileBuildReady.Unknown(),
+					applicationConditionImageResolved.True(),
+					applicationConditionKpackImageReady.True(),
+					applicationConditionReady.Unknown(),
+				).
+				StatusTargetImage("%s/%s", testImagePrefix, testName),
+		},
+	}, {
+		Name: "dockerfile build, job complete",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			appDockerfile,
+			dockerfileJobGiven.
+				StatusComplete(),
+			dockerfilePodGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(appDockerfile, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			appMinimal.
+				StatusConditions(
+					applicationConditionDockerfileBuildReady.True(),
+					applicationConditionImageResolved.True(),
+					applicationConditionKpackImageReady.True(),
+					applicationConditionReady.True(),
+				).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusJobRef("%s-dockerfile-001", testName).
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256).
+				StatusBuilds(dockerfileBuildRecord),
+		},
+		ExpectedResult: ctrl.Result{RequeueAfter: time.Minute},
+	}, {
+		Name: "dockerfile build, job complete without digest",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			appDockerfile,
+			dockerfileJobGiven.
+				StatusComplete(),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(appDockerfile, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			appMinimal.
+				StatusConditions(
+					applicationConditionDockerfileBuildReady.False().Reason("DigestMissing", `build job "test-application-dockerfile-001" completed without reporting an image digest`),
+					applicationConditionImageResolved.True(),
+					applicationConditionKpackImageReady.True(),
+					applicationConditionReady.False().Reason("DigestMissing", `build job "test-application-dockerfile-001" completed without reporting an image digest`),
+				).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusJobRef("%s-dockerfile-001", testName),
+		},
+		ExpectedResult: ctrl.Result{RequeueAfter: time.Minute},
+	}, {
+		Name: "dockerfile build, job failed",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			appDockerfile,
+			dockerfileJobGiven.
+				StatusFailed(testConditionReason, testConditionMessage),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(appDockerfile, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			appMinimal.
+				StatusConditions(
+					applicationConditionDockerfileBuildReady.False().Reason(testConditionReason, testConditionMessage),
+					applicationConditionImageResolved.True(),
+					applicationConditionKpackImageReady.True(),
+					applicationConditionReady.False().Reason(testConditionReason, testConditionMessage),
+				).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusJobRef("%s-dockerfile-001", testName),
+		},
+		ExpectedResult: ctrl.Result{RequeueAfter: time.Minute},
+	}, {
+		Name: "dockerfile build, job failed, records build log",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			appDockerfile,
+			dockerfileJobGiven.
+				StatusFailed(testConditionReason, testConditionMessage),
+			factories.Pod().
+				NamespaceName(testNamespace, fmt.Sprintf("%s-dockerfile-001-failed", testName)).
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddLabel("job-name", dockerfileJobGiven.Create().GetName())
+				}).
+				StatusContainerTerminated("build", 1, ""),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(appDockerfile, scheme, corev1.EventTypeNormal, "Created",
+				`Created ConfigMap "%s-build-log-001"`, testName),
+			rtesting.NewEvent(appDockerfile, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			factories.ConfigMap().
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Namespace(testNamespace).
+						GenerateName("%s-build-log-", testName).
+						AddLabel(buildv1alpha1.ApplicationLabelKey, testName).
+						ControlledBy(appMinimal, scheme)
+				}).
+				AddData("build", dockerfileJobGiven.Create().GetName()).
+				AddData("pod", fmt.Sprintf("%s-dockerfile-001-failed", testName)).
+				AddData("step", "build").
+				AddData("summary", dockerfileBuildLogSummary).
+				AddData("log", podLogs[fmt.Sprintf("%s/%s-dockerfile-001-failed/build", testNamespace, testName)]),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			appMinimal.
+				StatusConditions(
+					applicationConditionDockerfileBuildReady.False().Reason("BuildFailed", dockerfileBuildLogSummary),
+					applicationConditionImageResolved.True(),
+					applicationConditionKpackImageReady.True(),
+					applicationConditionReady.False().Reason("BuildFailed", dockerfileBuildLogSummary),
+				).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusJobRef("%s-dockerfile-001", testName).
+				StatusBuildLogRef("%s-build-log-001", testName),
+		},
+		ExpectedResult: ctrl.Result{RequeueAfter: time.Minute},
+	}, {
+		Name: "dockerfile build, replaces stale job",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			appDockerfile,
+			dockerfileJobGiven.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Name("%s-dockerfile-000", testName).
+						AddAnnotation("build.projectriff.io/dockerfile-build-hash", "stale")
+				}).
+				StatusComplete(),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(appDockerfile, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Job "%s-dockerfile-000"`, testName),
+			rtesting.NewEvent(appDockerfile, scheme, corev1.EventTypeNormal, "Created",
+				`Created Job "%s-dockerfile-001"`, testName),
+			rtesting.NewEvent(appDockerfile, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "batch", Kind: "Job", Namespace: testNamespace, Name: fmt.Sprintf("%s-dockerfile-000", testName)},
+		},
+		ExpectCreates: []rtesting.Factory{
+			dockerfileJobCreate.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation("build.projectriff.io/dockerfile-build-number", "2")
+				}),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			appMinimal.
+				StatusConditions(
+					applicationConditionDockerfileBuildReady.Unknown().Reason("Building", ""),
+					applicationConditionImageResolved.True(),
+					applicationConditionKpackImageReady.True(),
+					applicationConditionReady.Unknown().Reason("Building", ""),
+				).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusJobRef("%s-dockerfile-001", testName),
+		},
+		ExpectedResult: ctrl.Result{RequeueAfter: time.Minute},
+	}, {
+		Name: "dockerfile build, new commit replaces job",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			appDockerfile.
+				SourceGit("https://example.com/repo.git", "main").
+				StatusBuilds(dockerfileBuildRecordPrevious),
+			dockerfileJobGiven.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Name("%s-dockerfile-000", testName)
+				}).
+				StatusComplete(),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(appDockerfile, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Job "%s-dockerfile-000"`, testName),
+			rtesting.NewEvent(appDockerfile, scheme, corev1.EventTypeNormal, "Created",
+				`Created Job "%s-dockerfile-001"`, testName),
+			rtesting.NewEvent(appDockerfile, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "batch", Kind: "Job", Namespace: testNamespace, Name: fmt.Sprintf("%s-dockerfile-000", testName)},
+		},
+		ExpectCreates: []rtesting.Factory{
+			factories.Job(&batchv1.Job{Spec: dockerfileJobSpec(testGitCommitNext)}).
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Namespace(testNamespace).
+						GenerateName("%s-dockerfile-", testName).
+						AddLabel(buildv1alpha1.ApplicationLabelKey, testName).
+						AddAnnotation("build.projectriff.io/dockerfile-build-hash", dockerfileJobHash(dockerfileJobSpec(testGitCommitNext))).
+						AddAnnotation("build.projectriff.io/dockerfile-build-number", "2").
+						AddAnnotation("build.projectriff.io/dockerfile-build-reason", "COMMIT").
+						ControlledBy(appMinimal, scheme)
+				}),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			appMinimal.
+				StatusConditions(
+					applicationConditionDockerfileBuildReady.Unknown().Reason("Building", ""),
+					applicationConditionImageResolved.True(),
+					applicationConditionKpackImageReady.True(),
+					applicationConditionReady.Unknown().Reason("Building", ""),
+				).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusJobRef("%s-dockerfile-001", testName).
+				StatusBuilds(dockerfileBuildRecordPrevious),
+		},
+		ExpectedResult: ctrl.Result{RequeueAfter: time.Minute},
+	}, {
+		Name: "dockerfile build, revision not found",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			appDockerfile.
+				SourceGit("https://example.com/repo.git", "missing"),
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(appDockerfile, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			appMinimal.
+				StatusConditions(
+					applicationConditionDockerfileBuildReady.False().Reason("RevisionUnresolved", `revision "missing" not found in git repository "https://example.com/repo.git"`),
+					applicationConditionImageResolved.True(),
+					applicationConditionKpackImageReady.True(),
+					applicationConditionReady.False().Reason("RevisionUnresolved", `revision "missing" not found in git repository "https://example.com/repo.git"`),
+				).
+				StatusTargetImage("%s/%s", testImagePrefix, testName),
+		},
+	}, {
+		Name: "dockerfile build, git credentials",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			appDockerfile,
+			factories.ServiceAccount().
+				NamespaceName(testNamespace, "riff-build").
+				Secrets("git-credential"),
+			factories.Secret().
+				NamespaceName(testNamespace, "git-credential").
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation("build.pivotal.io/git", "https://example.com")
+				}).
+				Type(corev1.SecretTypeBasicAuth).
+				AddData("username", "projectriff").
+				AddData("password", "hunter2"),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(appDockerfile, scheme, corev1.EventTypeNormal, "Created",
+				`Created Job "%s-dockerfile-001"`, testName),
+			rtesting.NewEvent(appDockerfile, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			factories.Job(&batchv1.Job{Spec: dockerfileJobSpecGitCredential}).
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Namespace(testNamespace).
+						GenerateName("%s-dockerfile-", testName).
+						AddLabel(buildv1alpha1.ApplicationLabelKey, testName).
+						AddAnnotation("build.projectriff.io/dockerfile-build-hash", dockerfileJobHash(dockerfileJobSpecGitCredential)).
+						AddAnnotation("build.projectriff.io/dockerfile-build-number", "1").
+						AddAnnotation("build.projectriff.io/dockerfile-build-reason", "CONFIG").
+						ControlledBy(appMinimal, scheme)
+				}),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			appMinimal.
+				StatusConditions(
+					applicationConditionDockerfileBuildReady.Unknown().Reason("Building", ""),
+					applicationConditionImageResolved.True(),
+					applicationConditionKpackImageReady.True(),
+					applicationConditionReady.Unknown().Reason("Building", ""),
+				).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusJobRef("%s-dockerfile-001", testName),
+		},
+		ExpectedResult: ctrl.Result{RequeueAfter: time.Minute},
+	}, {
+		Name: "buildpacks strategy, removes dockerfile job",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			appMinimal.
+				Image("%s/%s", testImagePrefix, testName),
+			dockerfileJobGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(appValid, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Job "%s-dockerfile-001"`, testName),
+			rtesting.NewEvent(appValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "batch", Kind: "Job", Namespace: testNamespace, Name: fmt.Sprintf("%s-dockerfile-001", testName)},
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			appMinimal.
+				StatusConditions(
+					applicationConditionDockerfileBuildReady.True(),
+					applicationConditionImageResolved.True(),
+					applicationConditionKpackImageReady.True(),
+					applicationConditionReady.True(),
+				).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				// TODO resolve to a digest
+				StatusLatestImage("%s/%s", testImagePrefix, testName),
+		},
 	}}
 
 	table.Test(t, scheme, func(t *testing.T, row *rtesting.Testcase, client client.Client, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) reconcile.Reconciler {
 		return build.ApplicationReconciler(controllers.Config{
-			Client:   client,
-			Recorder: recorder,
-			Scheme:   scheme,
-			Log:      log,
-		})
+			Client:    client,
+			APIReader: client,
+			Recorder:  recorder,
+			Scheme:    scheme,
+			Log:       log,
+		}, podLogs, gitRevisions)
 	})
 }
+
+// testGitRevisionResolver serves commits keyed by url#revision
+type testGitRevisionResolver map[string]string
+
+func (r testGitRevisionResolver) ResolveRevision(ctx context.Context, git *buildv1alpha1.Git, credentials []corev1.Secret) (string, error) {
+	commit, ok := r[fmt.Sprintf("%s#%s", git.URL, git.Revision)]
+	if !ok {
+		return "", fmt.Errorf("revision %q not found in git repository %q", git.Revision, git.URL)
+	}
+	return commit, nil
+}
+
+// testPodLogReader serves container logs keyed by namespace/pod/container
+type testPodLogReader map[string]string
+
+func (r testPodLogReader) TailLog(ctx context.Context, namespace, pod, container string, lines int64) (string, error) {
+	log, ok := r[fmt.Sprintf("%s/%s/%s", namespace, pod, container)]
+	if !ok {
+		return "", fmt.Errorf("log not found for container %q in pod %q", container, pod)
+	}
+	return log, nil
+}
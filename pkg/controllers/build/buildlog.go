@@ -0,0 +1,307 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/projectriff/system/pkg/apis"
+	kpackbuildv1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/kpack/build/v1alpha1"
+	"github.com/projectriff/system/pkg/controllers"
+	"github.com/projectriff/system/pkg/refs"
+)
+
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods/log,verbs=get
+
+const (
+	// buildLogTailLines is the number of lines read from the end of the failed
+	// step's log
+	buildLogTailLines = 200
+	// buildLogMaxBytes bounds the log excerpt stored in the ConfigMap
+	buildLogMaxBytes = 16 * 1024
+	// buildLogReadLimitBytes bounds the log read from the API server before
+	// the excerpt is trimmed
+	buildLogReadLimitBytes = 1024 * 1024
+	// buildLogSummaryMaxLength bounds the summary added to the Ready condition
+	buildLogSummaryMaxLength = 256
+
+	buildLogBuildKey   = "build"
+	buildLogPodKey     = "pod"
+	buildLogStepKey    = "step"
+	buildLogSummaryKey = "summary"
+	buildLogLogKey     = "log"
+
+	redacted = "[REDACTED]"
+)
+
+var (
+	ansiEscapePattern = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+	buildpackPattern  = regexp.MustCompile(`(?m)^(?:-----> )?(\S[^\n]*? (?:Buildpack|Invoker))(?: v?\d[\w.+-]*)?[ \t]*$`)
+
+	// redactions are applied in order to each log read from a build pod, before
+	// it is stored in a ConfigMap
+	redactions = []struct {
+		pattern     *regexp.Regexp
+		replacement string
+	}{
+		{regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?(-----END [A-Z ]*PRIVATE KEY-----|$)`), "[REDACTED PRIVATE KEY]"},
+		{regexp.MustCompile(`(://)[^/\s:@]+:[^/\s@]+@`), "${1}" + redacted + "@"},
+		{regexp.MustCompile(`(?i)((?:proxy-)?authorization["']?\s*[:=]\s*["']?(?:bearer\s+|basic\s+|token\s+)?)[^\s"',]+`), "${1}" + redacted},
+		{regexp.MustCompile(`(?i)((?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key)["']?\s*[:=]\s*["']?)[^\s"',]+`), "${1}" + redacted},
+		{regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`), redacted},
+		{regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`), redacted},
+	}
+)
+
+// PodLogReader reads the most recent lines logged by a container.
+type PodLogReader interface {
+	TailLog(ctx context.Context, namespace, pod, container string, lines int64) (string, error)
+}
+
+// NewPodLogReader creates a PodLogReader backed by the pods/log subresource.
+func NewPodLogReader(pods corev1client.PodsGetter) PodLogReader {
+	return &podLogReader{pods: pods}
+}
+
+type podLogReader struct {
+	pods corev1client.PodsGetter
+}
+
+func (r *podLogReader) TailLog(ctx context.Context, namespace, pod, container string, lines int64) (string, error) {
+	limitBytes := int64(buildLogReadLimitBytes)
+	b, err := r.pods.Pods(namespace).GetLogs(pod, &corev1.PodLogOptions{
+		Container:  container,
+		TailLines:  &lines,
+		LimitBytes: &limitBytes,
+	}).Context(ctx).Do().Raw()
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// buildFailure describes the step that failed within a build pod.
+type buildFailure struct {
+	Build    string
+	Pod      string
+	Step     string
+	ExitCode int32
+	Log      string
+	Summary  string
+}
+
+// resolveFailedKpackBuild returns the pod of the most recent kpack Build for
+// an Image, if that build failed. The pod is nil when the build succeeded, is
+// still running or when the pod was already removed. Pods are not watched,
+// the pod is read with the APIReader.
+func resolveFailedKpackBuild(ctx context.Context, c controllers.Config, namespace, imageName string) (string, *corev1.Pod, error) {
+	var builds kpackbuildv1alpha1.BuildList
+	if err := c.List(ctx, &builds, client.InNamespace(namespace), client.MatchingLabels{kpackbuildv1alpha1.ImageLabel: imageName}); err != nil {
+		return "", nil, err
+	}
+	if len(builds.Items) == 0 {
+		return "", nil, nil
+	}
+	sort.Slice(builds.Items, func(i, j int) bool {
+		ni, _ := strconv.ParseInt(builds.Items[i].Labels[kpackbuildv1alpha1.BuildNumberLabel], 10, 64)
+		nj, _ := strconv.ParseInt(builds.Items[j].Labels[kpackbuildv1alpha1.BuildNumberLabel], 10, 64)
+		if ni != nj {
+			return ni > nj
+		}
+		return builds.Items[i].Name > builds.Items[j].Name
+	})
+	latest := &builds.Items[0]
+	if succeeded := latest.Status.GetCondition(apis.ConditionSucceeded); succeeded == nil || !succeeded.IsFalse() {
+		return "", nil, nil
+	}
+	if latest.Status.PodName == "" {
+		return latest.Name, nil, nil
+	}
+	var pod corev1.Pod
+	if err := c.APIReader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: latest.Status.PodName}, &pod); err != nil {
+		if apierrs.IsNotFound(err) {
+			return latest.Name, nil, nil
+		}
+		return "", nil, err
+	}
+	return latest.Name, &pod, nil
+}
+
+// reconcileBuildLog keeps a ConfigMap with an excerpt of the failed step's log
+// for the failed build, deleting excerpts for prior builds. The log is only
+// read once per failed build, the summary of an existing excerpt is reused.
+// An empty build indicates the most recent build did not fail.
+func reconcileBuildLog(ctx context.Context, c controllers.Config, logs PodLogReader, parent apis.Object, labelKey, build string, pod *corev1.Pod) (string, *refs.TypedLocalObjectReference, error) {
+	var configMaps corev1.ConfigMapList
+	if err := c.List(ctx, &configMaps, client.InNamespace(parent.GetNamespace()), client.MatchingLabels{labelKey: parent.GetName()}); err != nil {
+		return "", nil, err
+	}
+	var actual *corev1.ConfigMap
+	for i := range configMaps.Items {
+		configMap := &configMaps.Items[i]
+		if !metav1.IsControlledBy(configMap, parent) {
+			continue
+		}
+		if build != "" && actual == nil && configMap.Data[buildLogBuildKey] == build {
+			actual = configMap
+			continue
+		}
+		c.Log.Info("deleting stale build log", "configmap", configMap.Name)
+		if err := c.Delete(ctx, configMap); err != nil {
+			c.Recorder.Eventf(parent, corev1.EventTypeWarning, "DeleteFailed",
+				"Failed to delete ConfigMap %q: %v", configMap.Name, err)
+			return "", nil, err
+		}
+		c.Recorder.Eventf(parent, corev1.EventTypeNormal, "Deleted",
+			"Deleted ConfigMap %q", configMap.Name)
+	}
+
+	if build == "" {
+		return "", nil, nil
+	}
+	if actual != nil {
+		return actual.Data[buildLogSummaryKey], refs.NewTypedLocalObjectReferenceForObject(actual, c.Scheme), nil
+	}
+	if pod == nil {
+		return "", nil, nil
+	}
+	failure := resolveBuildFailure(ctx, c.Log, logs, build, pod)
+	if failure == nil {
+		return "", nil, nil
+	}
+
+	desired := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: controllers.MergeMaps(parent.GetLabels(), map[string]string{
+				labelKey: parent.GetName(),
+			}),
+			GenerateName: fmt.Sprintf("%s-build-log-", parent.GetName()),
+			Namespace:    parent.GetNamespace(),
+		},
+		Data: map[string]string{
+			buildLogBuildKey:   failure.Build,
+			buildLogPodKey:     failure.Pod,
+			buildLogStepKey:    failure.Step,
+			buildLogSummaryKey: failure.Summary,
+			buildLogLogKey:     failure.Log,
+		},
+	}
+	if err := ctrl.SetControllerReference(parent, desired, c.Scheme); err != nil {
+		return "", nil, err
+	}
+	c.Log.Info("creating build log", "build", build)
+	if err := c.Create(ctx, desired); err != nil {
+		c.Recorder.Eventf(parent, corev1.EventTypeWarning, "CreationFailed",
+			"Failed to create ConfigMap %q: %v", desired.GenerateName, err)
+		return "", nil, err
+	}
+	c.Recorder.Eventf(parent, corev1.EventTypeNormal, "Created",
+		"Created ConfigMap %q", desired.Name)
+
+	return failure.Summary, refs.NewTypedLocalObjectReferenceForObject(desired, c.Scheme), nil
+}
+
+// resolveBuildFailure finds the first step in the pod that terminated
+// unsuccessfully and captures a redacted, bounded tail of its log. A log that
+// cannot be read does not prevent summarizing the failure.
+func resolveBuildFailure(ctx context.Context, log logr.Logger, logs PodLogReader, build string, pod *corev1.Pod) *buildFailure {
+	var failed *corev1.ContainerStatus
+	for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for i := range statuses {
+			if terminated := statuses[i].State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+				failed = &statuses[i]
+				break
+			}
+		}
+		if failed != nil {
+			break
+		}
+	}
+	if failed == nil {
+		return nil
+	}
+
+	tail, err := logs.TailLog(ctx, pod.Namespace, pod.Name, failed.Name, buildLogTailLines)
+	if err != nil {
+		log.Error(err, "unable to read build log", "pod", pod.Name, "container", failed.Name)
+		tail = ""
+	}
+	tail = truncateBuildLog(redactBuildLog(ansiEscapePattern.ReplaceAllString(tail, "")), buildLogMaxBytes)
+
+	return &buildFailure{
+		Build:    build,
+		Pod:      pod.Name,
+		Step:     failed.Name,
+		ExitCode: failed.State.Terminated.ExitCode,
+		Log:      tail,
+		Summary:  summarizeBuildFailure(failed.Name, failed.State.Terminated.ExitCode, tail),
+	}
+}
+
+// summarizeBuildFailure describes the failed step, the buildpack that was
+// running and the final line logged.
+func summarizeBuildFailure(step string, exitCode int32, log string) string {
+	summary := fmt.Sprintf("build step %q failed", step)
+	if matches := buildpackPattern.FindAllStringSubmatch(log, -1); len(matches) != 0 {
+		summary = fmt.Sprintf("%s in %q", summary, matches[len(matches)-1][1])
+	}
+	summary = fmt.Sprintf("%s with exit code %d", summary, exitCode)
+	lines := strings.Split(strings.TrimSpace(log), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		summary = fmt.Sprintf("%s: %s", summary, last)
+	}
+	if len(summary) > buildLogSummaryMaxLength {
+		summary = strings.ToValidUTF8(summary[:buildLogSummaryMaxLength-3], "") + "..."
+	}
+	return summary
+}
+
+// redactBuildLog masks common forms of credentials in a log.
+func redactBuildLog(log string) string {
+	for _, r := range redactions {
+		log = r.pattern.ReplaceAllString(log, r.replacement)
+	}
+	return log
+}
+
+// truncateBuildLog keeps the end of the log within maxBytes, dropping any
+// partial first line.
+func truncateBuildLog(log string, maxBytes int) string {
+	if len(log) <= maxBytes {
+		return log
+	}
+	log = log[len(log)-maxBytes:]
+	if i := strings.Index(log, "\n"); i >= 0 {
+		log = log[i+1:]
+	}
+	return strings.ToValidUTF8(log, "")
+}
@@ -19,41 +19,103 @@ package build
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
-	apierrs "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/projectriff/system/pkg/apis"
 	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+	kpackbuildv1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/kpack/build/v1alpha1"
 )
 
 const riffBuildServiceAccount = "riff-build"
 
+// buildHistoryLimit is the maximum number of successful builds recorded on a
+// build resource's status
+const buildHistoryLimit = 10
+
 var errMissingDefaultPrefix = fmt.Errorf("missing default image prefix")
 
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
 
-func resolveTargetImage(ctx context.Context, client client.Client, build buildv1alpha1.ImageResource) (string, error) {
+func resolveTargetImage(ctx context.Context, client client.Client, scheme *runtime.Scheme, build buildv1alpha1.ImageResource) (string, error) {
 	if !strings.HasPrefix(build.GetImage(), "_") {
 		return build.GetImage(), nil
 	}
+	return resolveDefaultImage(ctx, client, scheme, build)
+}
 
-	var riffBuildConfig corev1.ConfigMap
-	if err := client.Get(ctx, types.NamespacedName{Namespace: build.GetNamespace(), Name: riffBuildServiceAccount}, &riffBuildConfig); err != nil {
-		if apierrs.IsNotFound(err) {
-			return "", errMissingDefaultPrefix
+// resolveBuildHistory merges the successful kpack Builds for an Image into the
+// existing history. Builds that kpack has since pruned are retained, while the
+// history is capped at buildHistoryLimit records, most recent first.
+func resolveBuildHistory(ctx context.Context, c client.Client, namespace, imageName string, history []buildv1alpha1.BuildRecord) ([]buildv1alpha1.BuildRecord, error) {
+	var builds kpackbuildv1alpha1.BuildList
+	if err := c.List(ctx, &builds, client.InNamespace(namespace), client.MatchingLabels{kpackbuildv1alpha1.ImageLabel: imageName}); err != nil {
+		return nil, err
+	}
+
+	records := []buildv1alpha1.BuildRecord{}
+	for i := range builds.Items {
+		build := &builds.Items[i]
+		succeeded := build.Status.GetCondition(apis.ConditionSucceeded)
+		if succeeded == nil || succeeded.Status != corev1.ConditionTrue || build.Status.LatestImage == "" {
+			continue
 		}
-		return "", err
+		records = append(records, newBuildRecord(build))
+	}
+	return mergeBuildHistory(history, records...), nil
+}
+
+// mergeBuildHistory adds the records to the history, replacing prior records
+// of the same build. The most recent builds are kept, up to the limit.
+func mergeBuildHistory(history []buildv1alpha1.BuildRecord, updates ...buildv1alpha1.BuildRecord) []buildv1alpha1.BuildRecord {
+	records := map[string]buildv1alpha1.BuildRecord{}
+	for _, record := range history {
+		records[record.Name] = record
+	}
+	for _, record := range updates {
+		records[record.Name] = record
+	}
+
+	if len(records) == 0 {
+		return nil
+	}
+	merged := make([]buildv1alpha1.BuildRecord, 0, len(records))
+	for _, record := range records {
+		merged = append(merged, record)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].BuildNumber != merged[j].BuildNumber {
+			return merged[i].BuildNumber > merged[j].BuildNumber
+		}
+		return merged[i].Name > merged[j].Name
+	})
+	if len(merged) > buildHistoryLimit {
+		merged = merged[:buildHistoryLimit]
+	}
+	return merged
+}
+
+func newBuildRecord(build *kpackbuildv1alpha1.Build) buildv1alpha1.BuildRecord {
+	record := buildv1alpha1.BuildRecord{
+		Name:       build.Name,
+		Reason:     build.Annotations[kpackbuildv1alpha1.BuildReasonAnnotation],
+		Source:     build.Spec.Source.DeepCopy(),
+		Image:      build.Status.LatestImage,
+		Buildpacks: build.Status.BuildMetadata,
+	}
+	if buildNumber, err := strconv.ParseInt(build.Labels[kpackbuildv1alpha1.BuildNumberLabel], 10, 64); err == nil {
+		record.BuildNumber = buildNumber
 	}
-	defaultPrefix := riffBuildConfig.Data["default-image-prefix"]
-	if defaultPrefix == "" {
-		return "", errMissingDefaultPrefix
+	if !build.CreationTimestamp.IsZero() {
+		record.StartTime = build.CreationTimestamp.DeepCopy()
 	}
-	image, err := buildv1alpha1.ResolveDefaultImage(build, defaultPrefix)
-	if err != nil {
-		return "", err
+	if succeeded := build.Status.GetCondition(apis.ConditionSucceeded); succeeded != nil && !succeeded.LastTransitionTime.Inner.IsZero() {
+		record.CompletionTime = succeeded.LastTransitionTime.Inner.DeepCopy()
 	}
-	return image, nil
+	return record
 }
@@ -23,33 +23,21 @@ import (
 	"time"
 
 	"github.com/go-logr/logr"
-	"github.com/google/go-cmp/cmp"
 	gauthn "github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/equality"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	"github.com/projectriff/system/pkg/authn"
+	"github.com/projectriff/system/pkg/controllers"
 )
 
-// ContainerReconciler reconciles a Container object
-type ContainerReconciler struct {
-	client.Client
-	Recorder record.EventRecorder
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-}
-
 var containerPollingInterval = 1 * time.Minute
 
 // +kubebuilder:rbac:groups=build.projectriff.io,resources=containers,verbs=get;list;watch;create;update;patch;delete
@@ -59,81 +47,70 @@ var containerPollingInterval = 1 * time.Minute
 // +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;update;patch;delete
 
-func (r *ContainerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
-	ctx := context.Background()
-	log := r.Log.WithValues("container", req.NamespacedName)
+func ContainerReconciler(c controllers.Config) *controllers.ParentReconciler {
+	c.Log = c.Log.WithName("Container")
 
-	var originalContainer buildv1alpha1.Container
-	if err := r.Get(ctx, req.NamespacedName, &originalContainer); err != nil {
-		if apierrs.IsNotFound(err) {
-			// we'll ignore not-found errors, since they can't be fixed by an immediate
-			// requeue (we'll need to wait for a new notification), and we can get them
-			// on deleted requests.
-			return ctrl.Result{}, nil
-		}
-		log.Error(err, "unable to fetch Container")
-		return ctrl.Result{}, err
-	}
-	container := *(originalContainer.DeepCopy())
-
-	container.Default()
-	container.Status.InitializeConditions()
-
-	result, err := r.reconcile(ctx, log, &container)
-
-	// check if status has changed before updating, unless requeued
-	if !equality.Semantic.DeepEqual(container.Status, originalContainer.Status) && container.GetDeletionTimestamp() == nil {
-		// update status
-		log.Info("updating container status", "diff", cmp.Diff(originalContainer.Status, container.Status))
-		if updateErr := r.Status().Update(ctx, &container); updateErr != nil {
-			log.Error(updateErr, "unable to update Container status", "container", container)
-			r.Recorder.Eventf(&container, corev1.EventTypeWarning, "StatusUpdateFailed",
-				"Failed to update status: %v", updateErr)
-			return ctrl.Result{Requeue: true}, updateErr
-		}
-		r.Recorder.Eventf(&container, corev1.EventTypeNormal, "StatusUpdated",
-			"Updated status")
-	}
+	return &controllers.ParentReconciler{
+		Type: &buildv1alpha1.Container{},
+		SubReconcilers: []controllers.SubReconciler{
+			ContainerImageReconciler(c),
+		},
 
-	// return original reconcile result
-	return result, err
-}
-
-func (r *ContainerReconciler) reconcile(ctx context.Context, log logr.Logger, container *buildv1alpha1.Container) (ctrl.Result, error) {
-	if container.GetDeletionTimestamp() != nil {
-		return ctrl.Result{}, nil
+		Config: c,
 	}
+}
 
-	// resolve target image
-	targetImageRef, err := r.resolveTargetImage(ctx, log, container)
-	if err != nil {
-		if err == errMissingDefaultPrefix {
-			container.Status.MarkImageDefaultPrefixMissing(err.Error())
-		} else {
-			container.Status.MarkImageInvalid(err.Error())
-		}
-		return ctrl.Result{}, err
-	}
-	container.Status.TargetImage = targetImageRef.Name()
+func ContainerImageReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("Image")
+
+	r := &containerImageResolver{Config: c}
+
+	return &controllers.SyncReconciler{
+		Setup: func(mgr controllers.Manager, bldr *controllers.Builder) error {
+			bldr.Watches(&source.Kind{Type: &corev1.Secret{}}, handler.Funcs{})
+			bldr.Watches(&source.Kind{Type: &corev1.ServiceAccount{}}, handler.Funcs{})
+			return nil
+		},
+		Sync: func(ctx context.Context, parent *buildv1alpha1.Container) (ctrl.Result, error) {
+			log := c.Log.WithValues("container", types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name})
+
+			// resolve target image
+			targetImageRef, err := r.resolveTargetImage(ctx, log, parent)
+			if err != nil {
+				if err == errMissingDefaultPrefix {
+					parent.Status.MarkImageDefaultPrefixMissing(err.Error())
+				} else {
+					parent.Status.MarkImageInvalid(err.Error())
+				}
+				return ctrl.Result{}, err
+			}
+			parent.Status.TargetImage = targetImageRef.Name()
 
-	latestImage, err := r.resolveDigestReference(ctx, log, targetImageRef, container)
-	if err != nil {
-		container.Status.MarkImageInvalid(err.Error())
-		return ctrl.Result{}, err
-	}
+			latestImage, err := r.resolveDigestReference(ctx, log, targetImageRef, parent)
+			if err != nil {
+				parent.Status.MarkImageInvalid(err.Error())
+				return ctrl.Result{}, err
+			}
 
-	container.Status.MarkImageResolved()
+			parent.Status.MarkImageResolved()
+			parent.Status.LatestImage = latestImage
+			parent.Status.Builds = recordContainerImage(parent.Name, parent.Status.Builds, latestImage)
 
-	container.Status.LatestImage = latestImage
+			// tags may move in the registry without notice, poll for changes
+			return ctrl.Result{
+				RequeueAfter: containerPollingInterval,
+			}, nil
+		},
 
-	container.Status.ObservedGeneration = container.Generation
+		Config: c,
+	}
+}
 
-	return ctrl.Result{
-		RequeueAfter: containerPollingInterval,
-	}, nil
+type containerImageResolver struct {
+	controllers.Config
 }
 
-func (r *ContainerReconciler) resolveTargetImage(ctx context.Context, log logr.Logger, container *buildv1alpha1.Container) (name.Reference, error) {
+func (r *containerImageResolver) resolveTargetImage(ctx context.Context, log logr.Logger, container *buildv1alpha1.Container) (name.Reference, error) {
 	image := container.Spec.Image
 	var err error
 	if strings.HasPrefix(container.Spec.Image, "_") {
@@ -151,26 +128,11 @@ func (r *ContainerReconciler) resolveTargetImage(ctx context.Context, log logr.L
 	return ref, nil
 }
 
-func (r *ContainerReconciler) interpolatePrefix(ctx context.Context, log logr.Logger, container *buildv1alpha1.Container) (string, error) {
-	var riffBuildConfig corev1.ConfigMap
-	if err := r.Get(ctx, types.NamespacedName{Namespace: container.Namespace, Name: riffBuildServiceAccount}, &riffBuildConfig); err != nil {
-		if apierrs.IsNotFound(err) {
-			return "", errMissingDefaultPrefix
-		}
-		return "", err
-	}
-	defaultPrefix := riffBuildConfig.Data["default-image-prefix"]
-	if defaultPrefix == "" {
-		return "", errMissingDefaultPrefix
-	}
-	image, err := buildv1alpha1.ResolveDefaultImage(container, defaultPrefix)
-	if err != nil {
-		return "", err
-	}
-	return image, nil
+func (r *containerImageResolver) interpolatePrefix(ctx context.Context, log logr.Logger, container *buildv1alpha1.Container) (string, error) {
+	return resolveDefaultImage(ctx, r.Client, r.Scheme, container)
 }
 
-func (r *ContainerReconciler) resolveDigestReference(ctx context.Context, log logr.Logger, ref name.Reference, container *buildv1alpha1.Container) (string, error) {
+func (r *containerImageResolver) resolveDigestReference(ctx context.Context, log logr.Logger, ref name.Reference, container *buildv1alpha1.Container) (string, error) {
 	keychain, err := r.constructKeychain(ctx, log, container)
 	if err != nil {
 		return "", err
@@ -197,7 +159,7 @@ func (r *ContainerReconciler) resolveDigestReference(ctx context.Context, log lo
 	return fmt.Sprintf("%s@%s", ref.Context().Name(), digest), nil
 }
 
-func (r *ContainerReconciler) constructKeychain(ctx context.Context, log logr.Logger, container *buildv1alpha1.Container) (gauthn.Keychain, error) {
+func (r *containerImageResolver) constructKeychain(ctx context.Context, log logr.Logger, container *buildv1alpha1.Container) (gauthn.Keychain, error) {
 	var serviceAccount corev1.ServiceAccount
 	if err := r.Get(ctx, types.NamespacedName{Namespace: container.Namespace, Name: riffBuildServiceAccount}, &serviceAccount); err != nil {
 		if apierrs.IsNotFound(err) {
@@ -216,7 +178,7 @@ func (r *ContainerReconciler) constructKeychain(ctx context.Context, log logr.Lo
 	return gauthn.NewMultiKeychain(authn.NewSecretsKeychain(secrets), gauthn.DefaultKeychain), nil
 }
 
-func (r *ContainerReconciler) fetchSecrets(serviceAccount corev1.ServiceAccount, ctx context.Context, log logr.Logger) ([]corev1.Secret, error) {
+func (r *containerImageResolver) fetchSecrets(serviceAccount corev1.ServiceAccount, ctx context.Context, log logr.Logger) ([]corev1.Secret, error) {
 	var secrets []corev1.Secret
 	for _, secretRef := range serviceAccount.Secrets {
 		var secret corev1.Secret
@@ -233,11 +195,3 @@ func (r *ContainerReconciler) fetchSecrets(serviceAccount corev1.ServiceAccount,
 	}
 	return secrets, nil
 }
-
-func (r *ContainerReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&buildv1alpha1.Container{}).
-		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.Funcs{}).
-		Watches(&source.Kind{Type: &corev1.ServiceAccount{}}, handler.Funcs{}).
-		Complete(r)
-}
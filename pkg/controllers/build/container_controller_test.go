@@ -18,6 +18,7 @@ package build_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
@@ -25,11 +26,13 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	kpackbuildv1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/kpack/build/v1alpha1"
+	"github.com/projectriff/system/pkg/controllers"
 	"github.com/projectriff/system/pkg/controllers/build"
 	rtesting "github.com/projectriff/system/pkg/controllers/testing"
 	"github.com/projectriff/system/pkg/controllers/testing/factories"
@@ -97,6 +100,7 @@ func TestContainerReconciler(t *testing.T) {
 				StatusTargetImage("%s/%s", testImagePrefix, testName).
 				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
 		},
+		ExpectedResult: ctrl.Result{RequeueAfter: time.Minute},
 	}, {
 		Name: "container get error",
 		Key:  testKey,
@@ -127,6 +131,7 @@ func TestContainerReconciler(t *testing.T) {
 				).
 				StatusTargetImage("%s/%s", testImagePrefix, testName),
 		},
+		ExpectedResult: ctrl.Result{RequeueAfter: time.Minute},
 	}, {
 		Name: "default image, missing",
 		Key:  testKey,
@@ -214,11 +219,11 @@ func TestContainerReconciler(t *testing.T) {
 	}}
 
 	table.Test(t, scheme, func(t *testing.T, row *rtesting.Testcase, client client.Client, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) reconcile.Reconciler {
-		return &build.ContainerReconciler{
+		return build.ContainerReconciler(controllers.Config{
 			Client:   client,
 			Recorder: recorder,
 			Scheme:   scheme,
 			Log:      log,
-		}
+		})
 	})
 }
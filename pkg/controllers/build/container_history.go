@@ -0,0 +1,41 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"fmt"
+
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+)
+
+// recordContainerImage adds a record to the history when the image differs
+// from the most recently resolved image. Each record is numbered in sequence
+// and named after the container, so that deployers may pin a prior image.
+func recordContainerImage(name string, history []buildv1alpha1.BuildRecord, image string) []buildv1alpha1.BuildRecord {
+	if len(history) != 0 && history[0].Image == image {
+		return history
+	}
+	buildNumber := int64(1)
+	if len(history) != 0 {
+		buildNumber = history[0].BuildNumber + 1
+	}
+	return mergeBuildHistory(history, buildv1alpha1.BuildRecord{
+		Name:        fmt.Sprintf("%s-%05d", name, buildNumber),
+		BuildNumber: buildNumber,
+		Image:       image,
+	})
+}
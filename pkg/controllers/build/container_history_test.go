@@ -0,0 +1,83 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+)
+
+func TestRecordContainerImage(t *testing.T) {
+	testImage := "example.com/repo@sha256:cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e"
+	testOtherImage := "example.com/repo@sha256:1f5bb6e2a5ff56d6ecbb7c0b3d5d0fed9c5ef2b7a9c0e6e1e25b0d8bbff2d1c4"
+
+	tests := []struct {
+		name     string
+		history  []buildv1alpha1.BuildRecord
+		image    string
+		expected []buildv1alpha1.BuildRecord
+	}{{
+		name:  "first image",
+		image: testImage,
+		expected: []buildv1alpha1.BuildRecord{
+			{Name: "test-container-00001", BuildNumber: 1, Image: testImage},
+		},
+	}, {
+		name: "same image",
+		history: []buildv1alpha1.BuildRecord{
+			{Name: "test-container-00001", BuildNumber: 1, Image: testImage},
+		},
+		image: testImage,
+		expected: []buildv1alpha1.BuildRecord{
+			{Name: "test-container-00001", BuildNumber: 1, Image: testImage},
+		},
+	}, {
+		name: "new image",
+		history: []buildv1alpha1.BuildRecord{
+			{Name: "test-container-00001", BuildNumber: 1, Image: testImage},
+		},
+		image: testOtherImage,
+		expected: []buildv1alpha1.BuildRecord{
+			{Name: "test-container-00002", BuildNumber: 2, Image: testOtherImage},
+			{Name: "test-container-00001", BuildNumber: 1, Image: testImage},
+		},
+	}, {
+		name: "prior image again",
+		history: []buildv1alpha1.BuildRecord{
+			{Name: "test-container-00002", BuildNumber: 2, Image: testOtherImage},
+			{Name: "test-container-00001", BuildNumber: 1, Image: testImage},
+		},
+		image: testImage,
+		expected: []buildv1alpha1.BuildRecord{
+			{Name: "test-container-00003", BuildNumber: 3, Image: testImage},
+			{Name: "test-container-00002", BuildNumber: 2, Image: testOtherImage},
+			{Name: "test-container-00001", BuildNumber: 1, Image: testImage},
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := recordContainerImage("test-container", test.history, test.image)
+			if diff := cmp.Diff(test.expected, actual); diff != "" {
+				t.Errorf("recordContainerImage() (-expected, +actual): %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,359 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+	"github.com/projectriff/system/pkg/authn"
+	"github.com/projectriff/system/pkg/controllers"
+)
+
+const (
+	// kanikoImage is the daemonless builder used for the dockerfile strategy.
+	// The debug variant provides a shell to prepare the source and
+	// credentials before the build.
+	kanikoImage = "gcr.io/kaniko-project/executor:debug-v0.17.1"
+	// gitImage checks out the commit of a git source before the build.
+	gitImage = "alpine/git:v2.24.1"
+
+	dockerfileBuildContainerName  = "build"
+	dockerfileSourceContainerName = "source"
+	dockerfileWorkspacePath       = "/workspace"
+	dockerfileSecretsPath         = "/var/build-secrets"
+)
+
+var (
+	dockerfileBuildHashAnnotationKey   = buildv1alpha1.GroupVersion.Group + "/dockerfile-build-hash"
+	dockerfileBuildNumberAnnotationKey = buildv1alpha1.GroupVersion.Group + "/dockerfile-build-number"
+	dockerfileBuildReasonAnnotationKey = buildv1alpha1.GroupVersion.Group + "/dockerfile-build-reason"
+)
+
+// gitPollingInterval is how often the revision of a git source is resolved
+// to detect new commits.
+var gitPollingInterval = 1 * time.Minute
+
+// resolveBuildCredentials returns the basic auth credentials bound to the
+// riff-build service account with the annotation, sorted by name. The
+// annotation is either authn.DockerSecretAnnotation for registries or
+// authn.GitSecretAnnotation for git repositories.
+func resolveBuildCredentials(ctx context.Context, c client.Client, namespace, annotation string) ([]corev1.Secret, error) {
+	var serviceAccount corev1.ServiceAccount
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: riffBuildServiceAccount}, &serviceAccount); err != nil {
+		if apierrs.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	secrets := []corev1.Secret{}
+	for _, ref := range serviceAccount.Secrets {
+		var secret corev1.Secret
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, &secret); err != nil {
+			if apierrs.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		if secret.Type != corev1.SecretTypeBasicAuth || secret.Annotations[annotation] == "" {
+			continue
+		}
+		secrets = append(secrets, secret)
+	}
+	sort.Slice(secrets, func(i, j int) bool {
+		return secrets[i].Name < secrets[j].Name
+	})
+	return secrets, nil
+}
+
+// newDockerfileJob creates a Job that builds the application's Dockerfile and
+// pushes the image to the target image. A git source is checked out at the
+// resolved commit. The image digest is reported as the termination message of
+// the build container.
+func newDockerfileJob(parent *buildv1alpha1.Application, commit string, credentials, gitCredentials []corev1.Secret) *batchv1.Job {
+	source := parent.Spec.Source
+	dockerfile := parent.Spec.Dockerfile
+	if dockerfile == nil {
+		dockerfile = &buildv1alpha1.DockerfileBuild{Path: "Dockerfile"}
+	}
+
+	script := []string{"set -e"}
+	env := []corev1.EnvVar{}
+	volumes := []corev1.Volume{
+		{Name: "workspace", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+	}
+	volumeMounts := []corev1.VolumeMount{
+		{Name: "workspace", MountPath: dockerfileWorkspacePath},
+	}
+
+	// assemble a docker config from the basic auth credentials
+	authFormat := []string{}
+	authArgs := []string{}
+	for i, secret := range credentials {
+		volume := fmt.Sprintf("credential-%d", i)
+		path := fmt.Sprintf("%s/%s", dockerfileSecretsPath, secret.Name)
+		volumes = append(volumes, corev1.Volume{
+			Name:         volume,
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: secret.Name}},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: volume, MountPath: path, ReadOnly: true})
+		env = append(env, corev1.EnvVar{
+			Name:  fmt.Sprintf("REGISTRY_%d", i),
+			Value: secret.Annotations[authn.DockerSecretAnnotation],
+		})
+		authFormat = append(authFormat, `"%s":{"auth":"%s"}`)
+		authArgs = append(authArgs, fmt.Sprintf(`"$REGISTRY_%d" "$(printf '%%s:%%s' "$(cat %s/%s)" "$(cat %s/%s)" | base64 | tr -d '\n')"`,
+			i, path, corev1.BasicAuthUsernameKey, path, corev1.BasicAuthPasswordKey))
+	}
+	printConfig := []string{fmt.Sprintf(`printf '{"auths":{%s}}'`, strings.Join(authFormat, ","))}
+	printConfig = append(printConfig, authArgs...)
+	printConfig = append(printConfig, "> /kaniko/.docker/config.json")
+	script = append(script,
+		"mkdir -p /kaniko/.docker",
+		strings.Join(printConfig, " "),
+	)
+
+	initContainers := []corev1.Container{}
+	switch {
+	case source.Git != nil:
+		initContainers = append(initContainers, newGitSourceContainer(source.Git, commit, gitCredentials))
+		volumes = append(volumes, newGitCredentialVolumes(gitCredentials)...)
+	case source.Blob != nil:
+		env = append(env, corev1.EnvVar{Name: "SOURCE_URL", Value: source.Blob.URL})
+		script = append(script,
+			`wget -q -O /tmp/source "$SOURCE_URL"`,
+			fmt.Sprintf(`tar -xzf /tmp/source -C %[1]s 2>/dev/null || tar -xf /tmp/source -C %[1]s 2>/dev/null || unzip -q /tmp/source -d %[1]s`, dockerfileWorkspacePath),
+		)
+	}
+	args := []string{
+		fmt.Sprintf("--context=dir://%s", strings.TrimRight(dockerfileWorkspacePath+"/"+source.SubPath, "/")),
+		fmt.Sprintf("--dockerfile=%s", dockerfile.Path),
+		fmt.Sprintf("--destination=%s", parent.Status.TargetImage),
+		"--digest-file=/dev/termination-log",
+	}
+	if dockerfile.Target != "" {
+		args = append(args, fmt.Sprintf("--target=%s", dockerfile.Target))
+	}
+	for _, arg := range dockerfile.BuildArgs {
+		args = append(args, fmt.Sprintf("--build-arg=%s=%s", arg.Name, arg.Value))
+	}
+	script = append(script, `exec /kaniko/executor "$@"`)
+
+	backoffLimit := int32(0)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: controllers.MergeMaps(parent.Labels, map[string]string{
+				buildv1alpha1.ApplicationLabelKey: parent.Name,
+			}),
+			Annotations:  map[string]string{},
+			GenerateName: fmt.Sprintf("%s-dockerfile-", parent.Name),
+			Namespace:    parent.Namespace,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						buildv1alpha1.ApplicationLabelKey: parent.Name,
+					},
+				},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: riffBuildServiceAccount,
+					RestartPolicy:      corev1.RestartPolicyNever,
+					InitContainers:     initContainers,
+					Containers: []corev1.Container{
+						{
+							Name:                     dockerfileBuildContainerName,
+							Image:                    kanikoImage,
+							Command:                  []string{"/busybox/sh", "-c", strings.Join(script, "\n"), "--"},
+							Args:                     args,
+							Env:                      env,
+							WorkingDir:               dockerfileWorkspacePath,
+							VolumeMounts:             volumeMounts,
+							TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+						},
+					},
+					Volumes: volumes,
+				},
+			},
+		},
+	}
+	job.Annotations[dockerfileBuildHashAnnotationKey] = dockerfileJobHash(job)
+
+	return job
+}
+
+// newGitSourceContainer creates a container that checks out the commit of the
+// git source into the workspace. Credentials are sent as an authorization
+// header to the matching host.
+func newGitSourceContainer(git *buildv1alpha1.Git, commit string, credentials []corev1.Secret) corev1.Container {
+	// the revision was resolved against the same location
+	repository := git.URL
+	if u, err := gitHTTPURL(git.URL); err == nil {
+		repository = u.String()
+	}
+	script := []string{"set -e"}
+	env := []corev1.EnvVar{
+		{Name: "GIT_URL", Value: repository},
+		{Name: "GIT_COMMIT", Value: commit},
+	}
+	volumeMounts := []corev1.VolumeMount{
+		{Name: "workspace", MountPath: dockerfileWorkspacePath},
+	}
+	for i, secret := range credentials {
+		path := fmt.Sprintf("%s/git/%s", dockerfileSecretsPath, secret.Name)
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: fmt.Sprintf("git-credential-%d", i), MountPath: path, ReadOnly: true})
+		host := secret.Annotations[authn.GitSecretAnnotation]
+		if !strings.Contains(host, "://") {
+			host = "https://" + host
+		}
+		env = append(env, corev1.EnvVar{
+			Name:  fmt.Sprintf("GIT_HOST_%d", i),
+			Value: strings.TrimSuffix(host, "/") + "/",
+		})
+		script = append(script, fmt.Sprintf(`git config --global "http.${GIT_HOST_%d}.extraHeader" "Authorization: Basic $(printf '%%s:%%s' "$(cat %s/%s)" "$(cat %s/%s)" | base64 | tr -d '\n')"`,
+			i, path, corev1.BasicAuthUsernameKey, path, corev1.BasicAuthPasswordKey))
+	}
+	script = append(script,
+		"git init -q .",
+		`git remote add origin "$GIT_URL"`,
+		// not every server allows fetching a commit directly
+		`git fetch -q --depth=1 origin "$GIT_COMMIT" || git fetch -q origin`,
+		`git checkout -q "$GIT_COMMIT"`,
+	)
+	return corev1.Container{
+		Name:         dockerfileSourceContainerName,
+		Image:        gitImage,
+		Command:      []string{"/bin/sh", "-c", strings.Join(script, "\n")},
+		Env:          env,
+		WorkingDir:   dockerfileWorkspacePath,
+		VolumeMounts: volumeMounts,
+	}
+}
+
+// newGitCredentialVolumes creates the volumes mounted by the git source
+// container.
+func newGitCredentialVolumes(credentials []corev1.Secret) []corev1.Volume {
+	volumes := []corev1.Volume{}
+	for i, secret := range credentials {
+		volumes = append(volumes, corev1.Volume{
+			Name:         fmt.Sprintf("git-credential-%d", i),
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: secret.Name}},
+		})
+	}
+	return volumes
+}
+
+// newDockerfileBuildRecord describes the completed build job. The source is
+// recorded with the commit that was built.
+func newDockerfileBuildRecord(job *batchv1.Job, source *buildv1alpha1.Source, commit, image string) buildv1alpha1.BuildRecord {
+	record := buildv1alpha1.BuildRecord{
+		Name:   job.Name,
+		Reason: job.Annotations[dockerfileBuildReasonAnnotationKey],
+		Source: source.DeepCopy(),
+		Image:  image,
+	}
+	if record.Source.Git != nil && commit != "" {
+		record.Source.Git.Revision = commit
+	}
+	if buildNumber, err := strconv.ParseInt(job.Annotations[dockerfileBuildNumberAnnotationKey], 10, 64); err == nil {
+		record.BuildNumber = buildNumber
+	}
+	if !job.CreationTimestamp.IsZero() {
+		record.StartTime = job.CreationTimestamp.DeepCopy()
+	}
+	if job.Status.CompletionTime != nil {
+		record.CompletionTime = job.Status.CompletionTime.DeepCopy()
+	}
+	return record
+}
+
+// dockerfileBuildReason describes why a build job is created, in the terms
+// of kpack. A new commit of the most recently built repository is a COMMIT,
+// other changes are a CONFIG.
+func dockerfileBuildReason(parent *buildv1alpha1.Application, commit string) string {
+	if len(parent.Status.Builds) != 0 && commit != "" {
+		source := parent.Status.Builds[0].Source
+		if source != nil && source.Git != nil && source.Git.URL == parent.Spec.Source.Git.URL && source.Git.Revision != commit {
+			return "COMMIT"
+		}
+	}
+	return "CONFIG"
+}
+
+func dockerfileJobHash(job *batchv1.Job) string {
+	// errors are not possible for this type
+	b, _ := json.Marshal(job.Spec)
+	return fmt.Sprintf("%x", sha256.Sum256(b))
+}
+
+// resolveDockerfileImage returns the digest qualified image pushed by a
+// completed build job, or an empty string if the digest is not available.
+func resolveDockerfileImage(ctx context.Context, pods client.Reader, job *batchv1.Job, targetImage string) (string, error) {
+	ref, err := name.ParseReference(targetImage)
+	if err != nil {
+		return "", err
+	}
+	var jobPods corev1.PodList
+	if err := pods.List(ctx, &jobPods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return "", err
+	}
+	for _, pod := range jobPods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name != dockerfileBuildContainerName || status.State.Terminated == nil || status.State.Terminated.ExitCode != 0 {
+				continue
+			}
+			digest := strings.TrimSpace(status.State.Terminated.Message)
+			if !strings.HasPrefix(digest, "sha256:") {
+				continue
+			}
+			return fmt.Sprintf("%s@%s", ref.Context().Name(), digest), nil
+		}
+	}
+	return "", nil
+}
+
+// resolveDockerfileJobPod returns the most recent pod created for a build job.
+func resolveDockerfileJobPod(ctx context.Context, pods client.Reader, namespace, jobName string) (*corev1.Pod, error) {
+	var jobPods corev1.PodList
+	if err := pods.List(ctx, &jobPods, client.InNamespace(namespace), client.MatchingLabels{"job-name": jobName}); err != nil {
+		return nil, err
+	}
+	var latest *corev1.Pod
+	for i := range jobPods.Items {
+		pod := &jobPods.Items[i]
+		if latest == nil || latest.CreationTimestamp.Before(&pod.CreationTimestamp) {
+			latest = pod
+		}
+	}
+	return latest, nil
+}
@@ -24,6 +24,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	kpackbuildv1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/kpack/build/v1alpha1"
@@ -34,9 +35,10 @@ import (
 // +kubebuilder:rbac:groups=build.projectriff.io,resources=functions,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=build.projectriff.io,resources=functions/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=build.pivotal.io,resources=images,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=build.pivotal.io,resources=builds,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;update;patch;delete
 
-func FunctionReconciler(c controllers.Config) *controllers.ParentReconciler {
+func FunctionReconciler(c controllers.Config, logs PodLogReader) *controllers.ParentReconciler {
 	c.Log = c.Log.WithName("Function")
 
 	return &controllers.ParentReconciler{
@@ -44,6 +46,8 @@ func FunctionReconciler(c controllers.Config) *controllers.ParentReconciler {
 		SubReconcilers: []controllers.SubReconciler{
 			FunctionTargetImageReconciler(c),
 			FunctionChildImageReconciler(c),
+			FunctionBuildHistoryReconciler(c),
+			FunctionBuildLogReconciler(c, logs),
 		},
 
 		Config: c,
@@ -55,7 +59,7 @@ func FunctionTargetImageReconciler(c controllers.Config) controllers.SubReconcil
 
 	return &controllers.SyncReconciler{
 		Sync: func(ctx context.Context, parent *buildv1alpha1.Function) error {
-			targetImage, err := resolveTargetImage(ctx, c.Client, parent)
+			targetImage, err := resolveTargetImage(ctx, c.Client, c.Scheme, parent)
 			if err != nil {
 				if err == errMissingDefaultPrefix {
 					parent.Status.MarkImageDefaultPrefixMissing(err.Error())
@@ -149,3 +153,63 @@ func FunctionChildImageReconciler(c controllers.Config) controllers.SubReconcile
 		},
 	}
 }
+
+func FunctionBuildHistoryReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("BuildHistory")
+
+	return &controllers.SyncReconciler{
+		Setup: func(mgr controllers.Manager, bldr *controllers.Builder) error {
+			// kpack copies the Image's labels to each Build
+			bldr.Watches(&source.Kind{Type: &kpackbuildv1alpha1.Build{}}, controllers.EnqueueLabeled(buildv1alpha1.FunctionLabelKey))
+			return nil
+		},
+		Sync: func(ctx context.Context, parent *buildv1alpha1.Function) error {
+			if parent.Status.KpackImageRef == nil {
+				parent.Status.Builds = nil
+				return nil
+			}
+			builds, err := resolveBuildHistory(ctx, c.Client, parent.Namespace, parent.Status.KpackImageRef.Name, parent.Status.Builds)
+			if err != nil {
+				return err
+			}
+			parent.Status.Builds = builds
+			return nil
+		},
+
+		Config: c,
+	}
+}
+
+func FunctionBuildLogReconciler(c controllers.Config, logs PodLogReader) controllers.SubReconciler {
+	c.Log = c.Log.WithName("BuildLog")
+
+	return &controllers.SyncReconciler{
+		Setup: func(mgr controllers.Manager, bldr *controllers.Builder) error {
+			bldr.Owns(&corev1.ConfigMap{})
+			return nil
+		},
+		Sync: func(ctx context.Context, parent *buildv1alpha1.Function) error {
+			var build string
+			var pod *corev1.Pod
+			if parent.Status.KpackImageRef != nil {
+				var err error
+				build, pod, err = resolveFailedKpackBuild(ctx, c, parent.Namespace, parent.Status.KpackImageRef.Name)
+				if err != nil {
+					return err
+				}
+			}
+
+			summary, ref, err := reconcileBuildLog(ctx, c, logs, parent, buildv1alpha1.FunctionLabelKey, build, pod)
+			if err != nil {
+				return err
+			}
+			parent.Status.BuildLogRef = ref
+			if summary != "" {
+				parent.Status.MarkKpackBuildFailed(summary)
+			}
+			return nil
+		},
+
+		Config: c,
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+	"github.com/projectriff/system/pkg/authn"
+)
+
+var gitCommitPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// GitRevisionResolver resolves the revision of a git source to a commit.
+type GitRevisionResolver interface {
+	ResolveRevision(ctx context.Context, git *buildv1alpha1.Git, credentials []corev1.Secret) (string, error)
+}
+
+// NewGitRevisionResolver creates a GitRevisionResolver that lists the refs of
+// the remote repository with the git smart HTTP protocol, like git ls-remote.
+func NewGitRevisionResolver(client *http.Client) GitRevisionResolver {
+	return &gitRevisionResolver{client: client}
+}
+
+type gitRevisionResolver struct {
+	client *http.Client
+}
+
+// ResolveRevision returns the commit for the revision. A commit is returned
+// unchanged. Otherwise, the revision is matched as a full ref, a branch or a
+// tag, in that order. Annotated tags resolve to the tagged commit.
+func (r *gitRevisionResolver) ResolveRevision(ctx context.Context, git *buildv1alpha1.Git, credentials []corev1.Secret) (string, error) {
+	if gitCommitPattern.MatchString(git.Revision) {
+		return git.Revision, nil
+	}
+	remote, err := gitHTTPURL(git.URL)
+	if err != nil {
+		return "", err
+	}
+	refs, err := r.listRefs(ctx, remote, credentials)
+	if err != nil {
+		return "", err
+	}
+	for _, ref := range gitRevisionRefs(git.Revision) {
+		// the peeled value of an annotated tag is the tagged commit
+		if commit, ok := refs[ref+"^{}"]; ok {
+			return commit, nil
+		}
+		if commit, ok := refs[ref]; ok {
+			return commit, nil
+		}
+	}
+	return "", fmt.Errorf("revision %q not found in git repository %q", git.Revision, git.URL)
+}
+
+func (r *gitRevisionResolver) listRefs(ctx context.Context, remote *url.URL, credentials []corev1.Secret) (map[string]string, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(remote.String(), "/")+"/info/refs?service=git-upload-pack", nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if secret := gitCredentialFor(remote, credentials); secret != nil {
+		req.SetBasicAuth(string(secret.Data[corev1.BasicAuthUsernameKey]), string(secret.Data[corev1.BasicAuthPasswordKey]))
+	}
+	res, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to list refs for git repository %q: %s", remote.Host+remote.Path, res.Status)
+	}
+	return parseGitRefs(res.Body)
+}
+
+// parseGitRefs reads the ref advertisement of the git smart HTTP protocol.
+// Each packet line holds a commit and a ref name, the first ref is followed
+// by the capabilities of the server.
+func parseGitRefs(r io.Reader) (map[string]string, error) {
+	refs := map[string]string{}
+	reader := bufio.NewReader(r)
+	for {
+		size := make([]byte, 4)
+		if _, err := io.ReadFull(reader, size); err != nil {
+			if err == io.EOF {
+				return refs, nil
+			}
+			return nil, err
+		}
+		n, err := strconv.ParseUint(string(size), 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid git packet line length %q", size)
+		}
+		if n < 4 {
+			// flush packet
+			continue
+		}
+		line := make([]byte, n-4)
+		if _, err := io.ReadFull(reader, line); err != nil {
+			return nil, err
+		}
+		text := strings.TrimSuffix(string(line), "\n")
+		if strings.HasPrefix(text, "#") {
+			// service announcement
+			continue
+		}
+		if i := strings.IndexByte(text, 0); i != -1 {
+			text = text[:i]
+		}
+		fields := strings.SplitN(text, " ", 2)
+		if len(fields) != 2 || !gitCommitPattern.MatchString(fields[0]) {
+			continue
+		}
+		refs[fields[1]] = fields[0]
+	}
+}
+
+// gitRevisionRefs returns the refs a revision may name, in order of
+// preference. An empty revision is the default branch.
+func gitRevisionRefs(revision string) []string {
+	switch {
+	case revision == "" || revision == "HEAD":
+		return []string{"HEAD"}
+	case strings.HasPrefix(revision, "refs/"):
+		return []string{revision}
+	default:
+		return []string{"refs/heads/" + revision, "refs/tags/" + revision}
+	}
+}
+
+// gitHTTPURL returns the HTTP location of a git repository. The git protocol
+// is served over HTTPS by the common hosts.
+func gitHTTPURL(repository string) (*url.URL, error) {
+	u, err := url.Parse(repository)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("git repository %q must be an http, https or git URL", repository)
+	}
+	switch u.Scheme {
+	case "http", "https":
+	case "git":
+		u.Scheme = "https"
+	default:
+		return nil, fmt.Errorf("git repository %q must be an http, https or git URL", repository)
+	}
+	return u, nil
+}
+
+// gitCredentialFor returns the credential whose git annotation matches the
+// host of the repository.
+func gitCredentialFor(repository *url.URL, credentials []corev1.Secret) *corev1.Secret {
+	for i := range credentials {
+		secret := &credentials[i]
+		value := secret.Annotations[authn.GitSecretAnnotation]
+		if !strings.Contains(value, "://") {
+			value = "https://" + value
+		}
+		if u, err := url.Parse(value); err == nil && u.Host == repository.Host {
+			return secret
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+)
+
+func TestGitRevisionResolver(t *testing.T) {
+	mainCommit := "1111111111111111111111111111111111111111"
+	branchCommit := "2222222222222222222222222222222222222222"
+	lightweightTagCommit := "3333333333333333333333333333333333333333"
+	annotatedTag := "4444444444444444444444444444444444444444"
+	annotatedTagCommit := "5555555555555555555555555555555555555555"
+
+	pktLine := func(line string) string {
+		return fmt.Sprintf("%04x%s", len(line)+4, line)
+	}
+	advertisement := strings.Join([]string{
+		pktLine("# service=git-upload-pack\n"),
+		"0000",
+		pktLine(mainCommit + " HEAD\x00multi_ack symref=HEAD:refs/heads/main\n"),
+		pktLine(mainCommit + " refs/heads/main\n"),
+		pktLine(branchCommit + " refs/heads/v1\n"),
+		pktLine(lightweightTagCommit + " refs/tags/v1\n"),
+		pktLine(annotatedTag + " refs/tags/v2\n"),
+		pktLine(annotatedTagCommit + " refs/tags/v2^{}\n"),
+		"0000",
+	}, "")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("service") != "git-upload-pack" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/public.git/info/refs":
+		case "/private.git/info/refs":
+			if username, password, ok := r.BasicAuth(); !ok || username != "projectriff" || password != "hunter2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
+		fmt.Fprint(w, advertisement)
+	}))
+	defer server.Close()
+
+	credential := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "git-credential",
+			Annotations: map[string]string{
+				"build.pivotal.io/git": server.URL,
+			},
+		},
+		Type: corev1.SecretTypeBasicAuth,
+		Data: map[string][]byte{
+			corev1.BasicAuthUsernameKey: []byte("projectriff"),
+			corev1.BasicAuthPasswordKey: []byte("hunter2"),
+		},
+	}
+
+	tests := []struct {
+		name        string
+		git         *buildv1alpha1.Git
+		credentials []corev1.Secret
+		expected    string
+		expectedErr string
+	}{{
+		name:     "commit",
+		git:      &buildv1alpha1.Git{URL: "https://example.com/unreachable.git", Revision: "6c3b0e3d5d1e8a3b2ad7e2f3f4a5b6c7d8e9f0a1"},
+		expected: "6c3b0e3d5d1e8a3b2ad7e2f3f4a5b6c7d8e9f0a1",
+	}, {
+		name:     "branch",
+		git:      &buildv1alpha1.Git{URL: server.URL + "/public.git", Revision: "main"},
+		expected: mainCommit,
+	}, {
+		name:     "branch preferred over tag",
+		git:      &buildv1alpha1.Git{URL: server.URL + "/public.git", Revision: "v1"},
+		expected: branchCommit,
+	}, {
+		name:     "tag",
+		git:      &buildv1alpha1.Git{URL: server.URL + "/public.git", Revision: "refs/tags/v1"},
+		expected: lightweightTagCommit,
+	}, {
+		name:     "annotated tag",
+		git:      &buildv1alpha1.Git{URL: server.URL + "/public.git", Revision: "v2"},
+		expected: annotatedTagCommit,
+	}, {
+		name:     "default branch",
+		git:      &buildv1alpha1.Git{URL: server.URL + "/public.git"},
+		expected: mainCommit,
+	}, {
+		name:        "not found",
+		git:         &buildv1alpha1.Git{URL: server.URL + "/public.git", Revision: "v3"},
+		expectedErr: fmt.Sprintf("revision %q not found in git repository %q", "v3", server.URL+"/public.git"),
+	}, {
+		name:        "private",
+		git:         &buildv1alpha1.Git{URL: server.URL + "/private.git", Revision: "main"},
+		credentials: []corev1.Secret{credential},
+		expected:    mainCommit,
+	}, {
+		name:        "private without credentials",
+		git:         &buildv1alpha1.Git{URL: server.URL + "/private.git", Revision: "main"},
+		expectedErr: fmt.Sprintf("unable to list refs for git repository %q: 401 Unauthorized", strings.TrimPrefix(server.URL, "http://")+"/private.git"),
+	}, {
+		name:        "ssh",
+		git:         &buildv1alpha1.Git{URL: "git@example.com:repo.git", Revision: "main"},
+		expectedErr: `git repository "git@example.com:repo.git" must be an http, https or git URL`,
+	}}
+
+	resolver := NewGitRevisionResolver(server.Client())
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := resolver.ResolveRevision(context.Background(), test.git, test.credentials)
+			if test.expectedErr != "" {
+				if err == nil || err.Error() != test.expectedErr {
+					t.Errorf("ResolveRevision() error = %v, expected %q", err, test.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveRevision() unexpected error: %v", err)
+			}
+			if actual != test.expected {
+				t.Errorf("ResolveRevision() = %q, expected %q", actual, test.expected)
+			}
+		})
+	}
+}
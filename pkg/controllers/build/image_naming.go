@@ -0,0 +1,224 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+)
+
+const (
+	// defaultImagePrefixKey is the riff-build ConfigMap key for the value of
+	// the {{prefix}} template function
+	defaultImagePrefixKey = "default-image-prefix"
+	// defaultImageTemplateKey is the riff-build ConfigMap key for the template
+	// used when no rule matches
+	defaultImageTemplateKey = "default-image-template"
+	// defaultImageTemplatesKey is the riff-build ConfigMap key for an ordered
+	// list of rules overriding the template by kind or labels, the first
+	// matching rule is used
+	defaultImageTemplatesKey = "default-image-templates"
+
+	defaultImageTemplate = "{{prefix}}/{{name}}"
+)
+
+// imageNamingRule selects a template for build resources of a kind and/or
+// matching a label selector.
+type imageNamingRule struct {
+	Kind     string                `json:"kind,omitempty"`
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	Template string                `json:"template"`
+
+	selector labels.Selector
+	template *template.Template
+}
+
+// imageNaming renders default image names for build resources whose image is
+// '_' or has a leading '_/'.
+//
+// Templates use the Go template syntax with the functions:
+// - prefix: the default image prefix
+// - namespace: the namespace of the build resource
+// - kind: the lowercase kind of the build resource
+// - name: the name of the build resource
+// - label: the value of a label on the build resource, e.g. {{label "team"}}
+type imageNaming struct {
+	prefix   string
+	template *template.Template
+	rules    []imageNamingRule
+}
+
+// newImageNaming parses the image naming configuration from the riff-build
+// ConfigMap.
+func newImageNaming(data map[string]string) (*imageNaming, error) {
+	naming := &imageNaming{
+		prefix: data[defaultImagePrefixKey],
+	}
+
+	source := data[defaultImageTemplateKey]
+	if source == "" {
+		source = defaultImageTemplate
+	}
+	tmpl, err := parseImageTemplate(defaultImageTemplateKey, source)
+	if err != nil {
+		return nil, err
+	}
+	naming.template = tmpl
+
+	if data[defaultImageTemplatesKey] != "" {
+		if err := yaml.Unmarshal([]byte(data[defaultImageTemplatesKey]), &naming.rules); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", defaultImageTemplatesKey, err)
+		}
+	}
+	for i := range naming.rules {
+		rule := &naming.rules[i]
+		key := fmt.Sprintf("%s[%d]", defaultImageTemplatesKey, i)
+		if rule.Template == "" {
+			return nil, fmt.Errorf("invalid %s: missing template", key)
+		}
+		if rule.Kind == "" && rule.Selector == nil {
+			return nil, fmt.Errorf("invalid %s: one of kind or selector is required", key)
+		}
+		if rule.Selector != nil {
+			if rule.selector, err = metav1.LabelSelectorAsSelector(rule.Selector); err != nil {
+				return nil, fmt.Errorf("invalid %s: %v", key, err)
+			}
+		}
+		if rule.template, err = parseImageTemplate(key, rule.Template); err != nil {
+			return nil, err
+		}
+	}
+
+	return naming, nil
+}
+
+func parseImageTemplate(key, source string) (*template.Template, error) {
+	// functions are bound to each resource when executed
+	tmpl, err := template.New(key).Funcs(imageTemplateFuncs("", nil, "", new(bool))).Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", key, err)
+	}
+	return tmpl, nil
+}
+
+func imageTemplateFuncs(prefix string, resource buildv1alpha1.ImageResource, kind string, prefixMissing *bool) template.FuncMap {
+	return template.FuncMap{
+		"prefix": func() (string, error) {
+			if prefix == "" {
+				*prefixMissing = true
+				return "", errMissingDefaultPrefix
+			}
+			return prefix, nil
+		},
+		"namespace": func() string { return resource.GetNamespace() },
+		"kind":      func() string { return kind },
+		"name":      func() string { return resource.GetName() },
+		"label": func(key string) (string, error) {
+			value, ok := resource.GetLabels()[key]
+			if !ok {
+				return "", fmt.Errorf("missing label %q", key)
+			}
+			return value, nil
+		},
+	}
+}
+
+// resolve renders the image for the build resource. The rendered image must
+// be a valid image reference.
+func (n *imageNaming) resolve(resource buildv1alpha1.ImageResource, kind string) (string, error) {
+	image := resource.GetImage()
+	switch {
+	case image == "_":
+		tmpl := n.template
+		for _, rule := range n.rules {
+			if rule.matches(resource, kind) {
+				tmpl = rule.template
+				break
+			}
+		}
+		prefixMissing := false
+		funcs := imageTemplateFuncs(n.prefix, resource, strings.ToLower(kind), &prefixMissing)
+		var buf bytes.Buffer
+		if err := template.Must(tmpl.Clone()).Funcs(funcs).Execute(&buf, nil); err != nil {
+			if prefixMissing {
+				return "", errMissingDefaultPrefix
+			}
+			return "", fmt.Errorf("unable to render image name: %v", err)
+		}
+		image = buf.String()
+	case strings.HasPrefix(image, "_/"):
+		// add the prefix to the specified image name
+		if n.prefix == "" {
+			return "", errMissingDefaultPrefix
+		}
+		image = strings.Replace(image, "_", n.prefix, 1)
+	default:
+		return "", fmt.Errorf("unable to default registry")
+	}
+
+	if _, err := name.ParseReference(image); err != nil {
+		return "", fmt.Errorf("invalid default image %q: %v", image, err)
+	}
+	return image, nil
+}
+
+func (r *imageNamingRule) matches(resource buildv1alpha1.ImageResource, kind string) bool {
+	if r.Kind != "" && !strings.EqualFold(r.Kind, kind) {
+		return false
+	}
+	if r.selector != nil && !r.selector.Matches(labels.Set(resource.GetLabels())) {
+		return false
+	}
+	return true
+}
+
+// resolveDefaultImage applies the image naming configuration from the
+// namespace's riff-build ConfigMap to the build resource.
+func resolveDefaultImage(ctx context.Context, c client.Client, scheme *runtime.Scheme, resource buildv1alpha1.ImageResource) (string, error) {
+	var riffBuildConfig corev1.ConfigMap
+	if err := c.Get(ctx, types.NamespacedName{Namespace: resource.GetNamespace(), Name: riffBuildServiceAccount}, &riffBuildConfig); err != nil {
+		if apierrs.IsNotFound(err) {
+			return "", errMissingDefaultPrefix
+		}
+		return "", err
+	}
+	naming, err := newImageNaming(riffBuildConfig.Data)
+	if err != nil {
+		return "", err
+	}
+	gvk, err := apiutil.GVKForObject(resource, scheme)
+	if err != nil {
+		return "", err
+	}
+	return naming.resolve(resource, gvk.Kind)
+}
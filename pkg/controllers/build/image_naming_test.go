@@ -0,0 +1,191 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+)
+
+func TestImageNaming(t *testing.T) {
+	application := &buildv1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "test-namespace",
+			Name:      "test-name",
+			Labels: map[string]string{
+				"team": "blue",
+			},
+		},
+		Spec: buildv1alpha1.ApplicationSpec{
+			Image: "_",
+		},
+	}
+
+	tests := []struct {
+		name     string
+		data     map[string]string
+		resource *buildv1alpha1.Application
+		kind     string
+		expected string
+		err      string
+	}{{
+		name: "default template",
+		data: map[string]string{
+			"default-image-prefix": "registry.example.com/repo",
+		},
+		expected: "registry.example.com/repo/test-name",
+	}, {
+		name: "prefixed image",
+		data: map[string]string{
+			"default-image-prefix": "registry.example.com/repo",
+		},
+		resource: &buildv1alpha1.Application{
+			ObjectMeta: application.ObjectMeta,
+			Spec:       buildv1alpha1.ApplicationSpec{Image: "_/custom:v1"},
+		},
+		expected: "registry.example.com/repo/custom:v1",
+	}, {
+		name: "missing prefix",
+		data: map[string]string{},
+		err:  "missing default image prefix",
+	}, {
+		name: "prefixed image, missing prefix",
+		data: map[string]string{},
+		resource: &buildv1alpha1.Application{
+			ObjectMeta: application.ObjectMeta,
+			Spec:       buildv1alpha1.ApplicationSpec{Image: "_/custom"},
+		},
+		err: "missing default image prefix",
+	}, {
+		name: "custom template",
+		data: map[string]string{
+			"default-image-prefix":   "registry.example.com",
+			"default-image-template": "{{prefix}}/{{namespace}}/{{kind}}-{{name}}",
+		},
+		expected: "registry.example.com/test-namespace/application-test-name",
+	}, {
+		name: "template without prefix",
+		data: map[string]string{
+			"default-image-template": "registry.example.com/{{label \"team\"}}/{{name}}",
+		},
+		expected: "registry.example.com/blue/test-name",
+	}, {
+		name: "kind rule",
+		data: map[string]string{
+			"default-image-prefix": "registry.example.com",
+			"default-image-templates": `
+- kind: Function
+  template: "{{prefix}}/functions/{{name}}"
+- kind: Application
+  template: "{{prefix}}/apps/{{name}}"
+`,
+		},
+		expected: "registry.example.com/apps/test-name",
+	}, {
+		name: "selector rule",
+		data: map[string]string{
+			"default-image-prefix": "registry.example.com",
+			"default-image-templates": `
+- selector:
+    matchLabels:
+      team: red
+  template: "{{prefix}}/red/{{name}}"
+- selector:
+    matchLabels:
+      team: blue
+  template: "{{prefix}}/blue/{{name}}"
+`,
+		},
+		expected: "registry.example.com/blue/test-name",
+	}, {
+		name: "no matching rule",
+		data: map[string]string{
+			"default-image-prefix": "registry.example.com",
+			"default-image-templates": `
+- kind: Function
+  template: "{{prefix}}/functions/{{name}}"
+`,
+		},
+		expected: "registry.example.com/test-name",
+	}, {
+		name: "missing label",
+		data: map[string]string{
+			"default-image-template": "registry.example.com/{{label \"owner\"}}/{{name}}",
+		},
+		err: `missing label "owner"`,
+	}, {
+		name: "invalid template",
+		data: map[string]string{
+			"default-image-template": "{{prefix}/{{name}}",
+		},
+		err: "invalid default-image-template: ",
+	}, {
+		name: "unknown function",
+		data: map[string]string{
+			"default-image-template": "{{registry}}/{{name}}",
+		},
+		err: `function "registry" not defined`,
+	}, {
+		name: "invalid rule",
+		data: map[string]string{
+			"default-image-templates": `
+- template: "{{prefix}}/{{name}}"
+`,
+		},
+		err: "invalid default-image-templates[0]: one of kind or selector is required",
+	}, {
+		name: "invalid rendered image",
+		data: map[string]string{
+			"default-image-template": "registry.example.com/{{namespace}}/UPPER/{{name}}",
+		},
+		err: `invalid default image "registry.example.com/test-namespace/UPPER/test-name"`,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resource := test.resource
+			if resource == nil {
+				resource = application
+			}
+			kind := test.kind
+			if kind == "" {
+				kind = "Application"
+			}
+			var actual string
+			naming, err := newImageNaming(test.data)
+			if err == nil {
+				actual, err = naming.resolve(resource, kind)
+			}
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != test.expected {
+				t.Errorf("resolve() = %q, expected %q", actual, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// ConfigMapLoader keeps configuration enforced by the validating webhooks and
+// controllers, like the PodSpec allowlist, in sync with a ConfigMap.
+//
+// Sync loads the ConfigMap before the manager starts, failing when the
+// ConfigMap is invalid so the manager never runs with a configuration other
+// than the one requested. Once started, only the ConfigMap is watched and
+// changes are applied as they arrive. An invalid change is logged and the
+// previous configuration remains in effect.
+//
+// Every replica of a manager serves webhooks, the loader runs without leader
+// election.
+type ConfigMapLoader struct {
+	Client corev1client.ConfigMapsGetter
+	Log    logr.Logger
+	// Key of the ConfigMap holding the configuration
+	Key types.NamespacedName
+	// Load applies the data of the ConfigMap. The data is nil when the
+	// ConfigMap does not exist.
+	Load func(data map[string]string) error
+}
+
+var (
+	_ manager.Runnable               = (*ConfigMapLoader)(nil)
+	_ manager.LeaderElectionRunnable = (*ConfigMapLoader)(nil)
+)
+
+func (l *ConfigMapLoader) NeedLeaderElection() bool {
+	return false
+}
+
+// Sync reads the ConfigMap from the API server and loads its data. Call
+// before starting the manager.
+func (l *ConfigMapLoader) Sync() error {
+	var data map[string]string
+	configMap, err := l.Client.ConfigMaps(l.Key.Namespace).Get(l.Key.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrs.IsNotFound(err) {
+			return err
+		}
+	} else {
+		data = configMap.Data
+	}
+	if err := l.Load(data); err != nil {
+		return fmt.Errorf("invalid configuration in ConfigMap %s: %v", l.Key, err)
+	}
+	l.Log.Info("loaded configuration", "configmap", l.Key, "found", data != nil)
+	return nil
+}
+
+func (l *ConfigMapLoader) Start(stop <-chan struct{}) error {
+	// watch the single ConfigMap rather than every ConfigMap in the cluster
+	selector := fields.OneTermEqualSelector("metadata.name", l.Key.Name).String()
+	configMaps := l.Client.ConfigMaps(l.Key.Namespace)
+	lw := &toolscache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = selector
+			return configMaps.List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = selector
+			return configMaps.Watch(options)
+		},
+	}
+	_, informer := toolscache.NewInformer(lw, &corev1.ConfigMap{}, 0, toolscache.ResourceEventHandlerFuncs{
+		AddFunc: l.load,
+		UpdateFunc: func(_, obj interface{}) {
+			l.load(obj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if configMap, ok := obj.(*corev1.ConfigMap); ok && l.matches(configMap) {
+				l.apply(nil)
+			}
+		},
+	})
+	go informer.Run(stop)
+	if !toolscache.WaitForCacheSync(stop, informer.HasSynced) {
+		return fmt.Errorf("unable to sync ConfigMap %s", l.Key)
+	}
+	<-stop
+	return nil
+}
+
+func (l *ConfigMapLoader) load(obj interface{}) {
+	if configMap, ok := obj.(*corev1.ConfigMap); ok && l.matches(configMap) {
+		l.apply(configMap.Data)
+	}
+}
+
+func (l *ConfigMapLoader) apply(data map[string]string) {
+	if err := l.Load(data); err != nil {
+		l.Log.Error(err, "invalid configuration, keeping the previous configuration", "configmap", l.Key)
+		return
+	}
+	l.Log.Info("loaded configuration", "configmap", l.Key, "deleted", data == nil)
+}
+
+func (l *ConfigMapLoader) matches(configMap *corev1.ConfigMap) bool {
+	return configMap.Namespace == l.Key.Namespace && configMap.Name == l.Key.Name
+}
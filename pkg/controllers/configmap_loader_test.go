@@ -0,0 +1,161 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/projectriff/system/pkg/controllers"
+)
+
+func TestConfigMapLoader_Sync(t *testing.T) {
+	key := types.NamespacedName{Namespace: "riff-system", Name: "my-config"}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+		Data:       map[string]string{"key": "value"},
+	}
+
+	tests := []struct {
+		name        string
+		given       []*corev1.ConfigMap
+		invalid     bool
+		expected    map[string]string
+		expectedErr string
+	}{{
+		name:     "missing",
+		expected: nil,
+	}, {
+		name:     "found",
+		given:    []*corev1.ConfigMap{configMap},
+		expected: map[string]string{"key": "value"},
+	}, {
+		name:        "invalid",
+		given:       []*corev1.ConfigMap{configMap},
+		invalid:     true,
+		expectedErr: "invalid configuration in ConfigMap riff-system/my-config: invalid value",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset()
+			for _, given := range test.given {
+				clientset.Tracker().Add(given.DeepCopy())
+			}
+			var loaded map[string]string
+			loader := &controllers.ConfigMapLoader{
+				Client: clientset.CoreV1(),
+				Log:    ctrl.Log,
+				Key:    key,
+				Load: func(data map[string]string) error {
+					if test.invalid {
+						return fmt.Errorf("invalid value")
+					}
+					loaded = data
+					return nil
+				},
+			}
+			err := loader.Sync()
+			if test.expectedErr != "" {
+				if err == nil || err.Error() != test.expectedErr {
+					t.Errorf("Sync() error = %v, expected %q", err, test.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Sync() unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(test.expected, loaded); diff != "" {
+				t.Errorf("Sync() loaded (-expected, +actual): %s", diff)
+			}
+		})
+	}
+}
+
+func TestConfigMapLoader_Start(t *testing.T) {
+	key := types.NamespacedName{Namespace: "riff-system", Name: "my-config"}
+	clientset := fake.NewSimpleClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: "other-config"},
+			Data:       map[string]string{"key": "other"},
+		},
+	)
+
+	loaded := make(chan map[string]string, 10)
+	loader := &controllers.ConfigMapLoader{
+		Client: clientset.CoreV1(),
+		Log:    ctrl.Log,
+		Key:    key,
+		Load: func(data map[string]string) error {
+			if data["key"] == "invalid" {
+				return fmt.Errorf("invalid value")
+			}
+			loaded <- data
+			return nil
+		},
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go loader.Start(stop)
+
+	expectLoaded := func(expected map[string]string) {
+		t.Helper()
+		select {
+		case actual := <-loaded:
+			if diff := cmp.Diff(expected, actual); diff != "" {
+				t.Errorf("Start() loaded (-expected, +actual): %s", diff)
+			}
+		case <-time.After(wait.ForeverTestTimeout):
+			t.Fatalf("Start() did not load %v", expected)
+		}
+	}
+
+	configMaps := clientset.CoreV1().ConfigMaps(key.Namespace)
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+		Data:       map[string]string{"key": "created"},
+	}
+	if _, err := configMaps.Create(configMap); err != nil {
+		t.Fatalf("unable to create ConfigMap: %v", err)
+	}
+	expectLoaded(map[string]string{"key": "created"})
+
+	// invalid configuration is skipped
+	configMap.Data = map[string]string{"key": "invalid"}
+	if _, err := configMaps.Update(configMap); err != nil {
+		t.Fatalf("unable to update ConfigMap: %v", err)
+	}
+	configMap.Data = map[string]string{"key": "updated"}
+	if _, err := configMaps.Update(configMap); err != nil {
+		t.Fatalf("unable to update ConfigMap: %v", err)
+	}
+	expectLoaded(map[string]string{"key": "updated"})
+
+	if err := configMaps.Delete(key.Name, &metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("unable to delete ConfigMap: %v", err)
+	}
+	expectLoaded(nil)
+}
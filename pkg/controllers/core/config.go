@@ -24,4 +24,14 @@ const (
 	settingsConfigMapName = kustomizePrefix + "-settings"
 	defaultDomainKey      = "defaultDomain"
 	defaultDomain         = "example.com"
+	defaultTLSSecretKey   = "defaultTLSSecret"
+
+	// routingBackendKey selects how requests are routed to deployers, either
+	// with an Ingress or a Gateway API HTTPRoute
+	routingBackendKey     = "routingBackend"
+	routingBackendIngress = "ingress"
+	routingBackendGateway = "gateway"
+	// gatewayKey references the parent Gateway of routes as
+	// <namespace>/<name>, the namespace defaults to the system namespace
+	gatewayKey = "gateway"
 )
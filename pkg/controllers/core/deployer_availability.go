@@ -0,0 +1,151 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	"github.com/projectriff/system/pkg/controllers"
+	"github.com/projectriff/system/pkg/refs"
+)
+
+// zoneTopologyKey is the well-known node label for the zone of a node
+const zoneTopologyKey = "topology.kubernetes.io/zone"
+
+// DeployerChildPodDisruptionBudgetReconciler limits voluntary disruptions of
+// the deployer's pods, stable and candidate, when availability is configured.
+func DeployerChildPodDisruptionBudgetReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ChildPodDisruptionBudget")
+
+	return &controllers.ChildReconciler{
+		ParentType:    &corev1alpha1.Deployer{},
+		ChildType:     &policyv1beta1.PodDisruptionBudget{},
+		ChildListType: &policyv1beta1.PodDisruptionBudgetList{},
+
+		DesiredChild: func(parent *corev1alpha1.Deployer) (*policyv1beta1.PodDisruptionBudget, error) {
+			availability := parent.Spec.Availability
+			if availability == nil {
+				// availability not configured, skip
+				return nil, nil
+			}
+
+			child := &policyv1beta1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: controllers.MergeMaps(parent.Labels, map[string]string{
+						corev1alpha1.DeployerLabelKey: parent.Name,
+					}),
+					Annotations:  make(map[string]string),
+					GenerateName: fmt.Sprintf("%s-deployer-", parent.Name),
+					Namespace:    parent.Namespace,
+				},
+				Spec: policyv1beta1.PodDisruptionBudgetSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							corev1alpha1.DeployerLabelKey: parent.Name,
+						},
+					},
+					MinAvailable:   availability.MinAvailable,
+					MaxUnavailable: availability.MaxUnavailable,
+				},
+			}
+
+			return child, nil
+		},
+		ReflectChildStatusOnParent: func(parent *corev1alpha1.Deployer, child *policyv1beta1.PodDisruptionBudget, err error) {
+			if err != nil {
+				return
+			}
+			if child == nil {
+				parent.Status.PodDisruptionBudgetRef = nil
+			} else {
+				parent.Status.PodDisruptionBudgetRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
+			}
+		},
+		MergeBeforeUpdate: func(current, desired *policyv1beta1.PodDisruptionBudget) {
+			current.Labels = desired.Labels
+			current.Spec = desired.Spec
+		},
+		SemanticEquals: func(a1, a2 *policyv1beta1.PodDisruptionBudget) bool {
+			return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+		},
+
+		Config:     c,
+		IndexField: ".metadata.podDisruptionBudgetController",
+		Sanitize: func(child *policyv1beta1.PodDisruptionBudget) interface{} {
+			return child.Spec
+		},
+	}
+}
+
+// deployerTopologySpreadConstraints adds the zone and node spread of the
+// deployer's availability to the constraints of the pod template. Constraints
+// in the template take precedence over the availability for the same topology
+// key.
+func deployerTopologySpreadConstraints(parent *corev1alpha1.Deployer, constraints []corev1.TopologySpreadConstraint) []corev1.TopologySpreadConstraint {
+	availability := parent.Spec.Availability
+	if availability == nil {
+		return constraints
+	}
+
+	for _, spread := range []struct {
+		topologyKey string
+		spread      *corev1alpha1.TopologySpread
+	}{
+		{topologyKey: zoneTopologyKey, spread: availability.ZoneSpread},
+		{topologyKey: corev1.LabelHostname, spread: availability.NodeSpread},
+	} {
+		if spread.spread == nil || hasTopologySpreadConstraint(constraints, spread.topologyKey) {
+			continue
+		}
+		maxSkew := int32(1)
+		if spread.spread.MaxSkew != nil {
+			maxSkew = *spread.spread.MaxSkew
+		}
+		whenUnsatisfiable := spread.spread.WhenUnsatisfiable
+		if whenUnsatisfiable == "" {
+			whenUnsatisfiable = corev1.ScheduleAnyway
+		}
+		constraints = append(constraints, corev1.TopologySpreadConstraint{
+			MaxSkew:           maxSkew,
+			TopologyKey:       spread.topologyKey,
+			WhenUnsatisfiable: whenUnsatisfiable,
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					corev1alpha1.DeployerLabelKey: parent.Name,
+				},
+			},
+		})
+	}
+
+	return constraints
+}
+
+func hasTopologySpreadConstraint(constraints []corev1.TopologySpreadConstraint, topologyKey string) bool {
+	for _, constraint := range constraints {
+		if constraint.TopologyKey == topologyKey {
+			return true
+		}
+	}
+	return false
+}
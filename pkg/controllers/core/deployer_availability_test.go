@@ -0,0 +1,313 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core_test
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	"github.com/projectriff/system/pkg/controllers"
+	corecontrollers "github.com/projectriff/system/pkg/controllers/core"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+	"github.com/projectriff/system/pkg/controllers/testing/factories"
+	"github.com/projectriff/system/pkg/tracker"
+)
+
+func TestDeployerAvailability(t *testing.T) {
+	testNamespace := "test-namespace"
+	testName := "test-deployer"
+	testKey := types.NamespacedName{Namespace: testNamespace, Name: testName}
+	testImage := "example.com/repo@sha256:cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e"
+
+	deployerConditionDeploymentReady := factories.Condition().Type(corev1alpha1.DeployerConditionDeploymentReady)
+	deployerConditionIngressReady := factories.Condition().Type(corev1alpha1.DeployerConditionIngressReady)
+	deployerConditionReady := factories.Condition().Type(corev1alpha1.DeployerConditionReady)
+	deployerConditionServiceReady := factories.Condition().Type(corev1alpha1.DeployerConditionServiceReady)
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = corev1alpha1.AddToScheme(scheme)
+
+	spreadSelector := &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			corev1alpha1.DeployerLabelKey: testName,
+		},
+	}
+	zoneSpread := corev1.TopologySpreadConstraint{
+		MaxSkew:           1,
+		TopologyKey:       "topology.kubernetes.io/zone",
+		WhenUnsatisfiable: corev1.ScheduleAnyway,
+		LabelSelector:     spreadSelector,
+	}
+	nodeSpread := corev1.TopologySpreadConstraint{
+		MaxSkew:           1,
+		TopologyKey:       "kubernetes.io/hostname",
+		WhenUnsatisfiable: corev1.DoNotSchedule,
+		LabelSelector:     spreadSelector,
+	}
+	availability := corev1alpha1.Availability{
+		MaxUnavailable: intstrPtr(intstr.FromInt(1)),
+		ZoneSpread: &corev1alpha1.TopologySpread{
+			MaxSkew:           rtesting.Int32Ptr(1),
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+		},
+		NodeSpread: &corev1alpha1.TopologySpread{
+			MaxSkew:           rtesting.Int32Ptr(1),
+			WhenUnsatisfiable: corev1.DoNotSchedule,
+		},
+	}
+
+	deployerMinimal := factories.DeployerCore().
+		NamespaceName(testNamespace, testName)
+	deployerValid := deployerMinimal.
+		Image(testImage).
+		IngressPolicy(corev1alpha1.IngressPolicyClusterLocal).
+		StatusLatestImage(testImage)
+
+	deploymentCreate := factories.Deployment().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.GenerateName("%s-deployer-", testName)
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.ControlledBy(deployerMinimal, scheme)
+		}).
+		AddSelectorLabel(corev1alpha1.DeployerLabelKey, testName).
+		AddSelectorExpression(corev1alpha1.RolloutTrackLabelKey, metav1.LabelSelectorOpNotIn, corev1alpha1.RolloutTrackCandidate).
+		HandlerContainer(func(container *corev1.Container) {
+			container.Image = testImage
+			container.Ports = []corev1.ContainerPort{
+				{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+			}
+			container.Env = []corev1.EnvVar{
+				{Name: "PORT", Value: "8080"},
+			}
+			container.ReadinessProbe = &corev1.Probe{
+				Handler: corev1.Handler{
+					TCPSocket: &corev1.TCPSocketAction{
+						Port: intstr.FromInt(8080),
+					},
+				},
+			}
+		})
+	deploymentGiven := deploymentCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Name("%s%s", om.Create().GenerateName, "000")
+			om.Created(1)
+		})
+
+	pdbCreate := factories.PodDisruptionBudget().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.GenerateName("%s-deployer-", testName)
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.ControlledBy(deployerMinimal, scheme)
+		}).
+		AddSelectorLabel(corev1alpha1.DeployerLabelKey, testName).
+		MaxUnavailable(intstr.FromInt(1))
+	pdbGiven := pdbCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Name("%s%s", om.Create().GenerateName, "001")
+			om.Created(1)
+		})
+
+	table := rtesting.Table{{
+		Name: "create pod disruption budget and spread replicas",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerValid.
+				Availability(availability),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Deployment "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created PodDisruptionBudget "%s-deployer-002"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			deploymentCreate.
+				PodTemplateSpec(func(pts factories.PodTemplateSpec) {
+					pts.AddTopologySpreadConstraint(zoneSpread)
+					pts.AddTopologySpreadConstraint(nodeSpread)
+				}),
+			pdbCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.Unknown(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-001", testName).
+				StatusPodDisruptionBudgetRef("%s-deployer-002", testName),
+		},
+	}, {
+		Name: "create deployment, template spread takes precedence",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerValid.
+				Availability(availability).
+				PodTemplateSpec(func(pts factories.PodTemplateSpec) {
+					pts.AddTopologySpreadConstraint(corev1.TopologySpreadConstraint{
+						MaxSkew:           2,
+						TopologyKey:       "topology.kubernetes.io/zone",
+						WhenUnsatisfiable: corev1.DoNotSchedule,
+						LabelSelector:     spreadSelector,
+					})
+				}).
+				StatusPodDisruptionBudgetRef("%s-deployer-001", testName),
+			pdbGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Deployment "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			deploymentCreate.
+				PodTemplateSpec(func(pts factories.PodTemplateSpec) {
+					pts.AddTopologySpreadConstraint(corev1.TopologySpreadConstraint{
+						MaxSkew:           2,
+						TopologyKey:       "topology.kubernetes.io/zone",
+						WhenUnsatisfiable: corev1.DoNotSchedule,
+						LabelSelector:     spreadSelector,
+					})
+					pts.AddTopologySpreadConstraint(nodeSpread)
+				}),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.Unknown(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-001", testName).
+				StatusPodDisruptionBudgetRef("%s-deployer-001", testName),
+		},
+	}, {
+		Name: "update pod disruption budget, min available",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerValid.
+				Availability(corev1alpha1.Availability{
+					MinAvailable: intstrPtr(intstr.FromString("50%")),
+					ZoneSpread:   availability.ZoneSpread,
+					NodeSpread:   availability.NodeSpread,
+				}).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.Unknown(),
+				).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusPodDisruptionBudgetRef("%s-deployer-001", testName),
+			deploymentGiven.
+				PodTemplateSpec(func(pts factories.PodTemplateSpec) {
+					pts.AddTopologySpreadConstraint(zoneSpread)
+					pts.AddTopologySpreadConstraint(nodeSpread)
+				}),
+			pdbGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated PodDisruptionBudget "%s-deployer-001"`, testName),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			pdbGiven.
+				MinAvailable(intstr.FromString("50%")),
+		},
+	}, {
+		Name: "delete pod disruption budget, availability removed",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerValid.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.Unknown(),
+				).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusPodDisruptionBudgetRef("%s-deployer-001", testName),
+			deploymentGiven,
+			pdbGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted PodDisruptionBudget "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "policy", Kind: "PodDisruptionBudget", Namespace: testNamespace, Name: pdbGiven.Create().GetName()},
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.Unknown(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", testName),
+		},
+	}}
+
+	table.Test(t, scheme, func(t *testing.T, row *rtesting.Testcase, client client.Client, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) reconcile.Reconciler {
+		c := controllers.Config{
+			Client:   client,
+			Recorder: recorder,
+			Scheme:   scheme,
+			Log:      log,
+			Tracker:  tracker,
+		}
+		return &controllers.ParentReconciler{
+			Type: &corev1alpha1.Deployer{},
+			SubReconcilers: []controllers.SubReconciler{
+				corecontrollers.DeployerChildDeploymentReconciler(c),
+				corecontrollers.DeployerChildPodDisruptionBudgetReconciler(c),
+			},
+
+			Config: c,
+		}
+	})
+}
+
+func intstrPtr(v intstr.IntOrString) *intstr.IntOrString {
+	return &v
+}
@@ -21,14 +21,17 @@ import (
 	"fmt"
 
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1beta1 "k8s.io/api/networking/v1beta1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/projectriff/system/pkg/apis"
@@ -37,26 +40,59 @@ import (
 	"github.com/projectriff/system/pkg/controllers"
 	"github.com/projectriff/system/pkg/refs"
 	"github.com/projectriff/system/pkg/tracker"
+	"github.com/projectriff/system/pkg/validation"
 )
 
 // +kubebuilder:rbac:groups=core.projectriff.io,resources=deployers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core.projectriff.io,resources=deployers/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=build.projectriff.io,resources=applications;containers;functions,verbs=get;list;watch
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list;watch;update
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;update;patch;delete
 
-func DeployerReconciler(c controllers.Config) *controllers.ParentReconciler {
+// deployerFieldManager owns the fields of children applied with server-side
+// apply
+const deployerFieldManager = "riff-core-deployer"
+
+// OptionalAPIs are the APIs installed in the cluster that deployers may use
+// when available.
+type OptionalAPIs struct {
+	// CertManager issues certificates for deployer ingress TLS.
+	CertManager bool
+	// GatewayAPI routes requests to deployers with HTTPRoutes.
+	GatewayAPI bool
+}
+
+func DeployerReconciler(c controllers.Config, metrics RolloutMetrics, apis OptionalAPIs, networkPolicy controllers.NetworkPolicyConfig) *controllers.ParentReconciler {
 	c.Log = c.Log.WithName("Deployer")
 
 	return &controllers.ParentReconciler{
 		Type: &corev1alpha1.Deployer{},
 		SubReconcilers: []controllers.SubReconciler{
 			DeployerBuildRefReconciler(c),
+			DeployerRolloutReconciler(c, metrics),
+			DeployerStableSelectorMigrationReconciler(c),
 			DeployerChildDeploymentReconciler(c),
+			DeployerChildHorizontalPodAutoscalerReconciler(c),
+			DeployerChildPodDisruptionBudgetReconciler(c),
+			DeployerChildCandidateDeploymentReconciler(c),
 			DeployerChildServiceReconciler(c),
+			DeployerChildCandidateServiceReconciler(c),
+			DeployerChildNetworkPolicyReconciler(c, networkPolicy),
+			DeployerCertificateReconciler(c, apis.CertManager),
+			DeployerChildTLSSecretReconciler(c),
 			DeployerChildIngressReconciler(c),
+			DeployerChildCandidateIngressReconciler(c),
+			DeployerRoutingReconciler(c, apis.GatewayAPI),
 		},
 
 		Config: c,
@@ -68,73 +104,20 @@ func DeployerBuildRefReconciler(c controllers.Config) controllers.SubReconciler
 
 	return &controllers.SyncReconciler{
 		Sync: func(ctx context.Context, parent *corev1alpha1.Deployer) error {
-			build := parent.Spec.Build
-			if build == nil {
-				parent.Status.LatestImage = parent.Spec.Template.Spec.Containers[0].Image
-				return nil
+			previous := parent.Status.LatestImage
+			if err := resolveDeployerImage(ctx, c, parent); err != nil {
+				return err
 			}
-
-			switch {
-			case build.ApplicationRef != "":
-				var application buildv1alpha1.Application
-				key := types.NamespacedName{Namespace: parent.Namespace, Name: build.ApplicationRef}
-				// track application for new images
-				c.Tracker.Track(
-					tracker.NewKey(application.GetGroupVersionKind(), key),
-					types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
-				)
-				if err := c.Get(ctx, key, &application); err != nil {
-					if apierrs.IsNotFound(err) {
-						return nil
-					}
-					return err
-				}
-				if application.Status.LatestImage != "" {
-					parent.Status.LatestImage = application.Status.LatestImage
-				}
-				return nil
-
-			case build.ContainerRef != "":
-				var container buildv1alpha1.Container
-				key := types.NamespacedName{Namespace: parent.Namespace, Name: build.ContainerRef}
-				// track container for new images
-				c.Tracker.Track(
-					tracker.NewKey(container.GetGroupVersionKind(), key),
-					types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
-				)
-				if err := c.Get(ctx, key, &container); err != nil {
-					if apierrs.IsNotFound(err) {
-						return nil
-					}
-					return err
-				}
-				if container.Status.LatestImage != "" {
-					parent.Status.LatestImage = container.Status.LatestImage
+			if image := parent.Status.LatestImage; image != "" {
+				if err := validation.ActiveImagePolicy().CheckImage(image); err != nil {
+					// keep rolling out the previous image
+					parent.Status.LatestImage = previous
+					parent.Status.MarkImageNotAllowed(err.Error())
+					return nil
 				}
-				return nil
-
-			case build.FunctionRef != "":
-				var function buildv1alpha1.Function
-				key := types.NamespacedName{Namespace: parent.Namespace, Name: build.FunctionRef}
-				// track function for new images
-				c.Tracker.Track(
-					tracker.NewKey(function.GetGroupVersionKind(), key),
-					types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
-				)
-				if err := c.Get(ctx, key, &function); err != nil {
-					if apierrs.IsNotFound(err) {
-						return nil
-					}
-					return err
-				}
-				if function.Status.LatestImage != "" {
-					parent.Status.LatestImage = function.Status.LatestImage
-				}
-				return nil
-
 			}
-
-			return fmt.Errorf("invalid build")
+			parent.Status.MarkImageAllowed()
+			return nil
 		},
 
 		Config: c,
@@ -147,6 +130,74 @@ func DeployerBuildRefReconciler(c controllers.Config) controllers.SubReconciler
 	}
 }
 
+// resolveDeployerImage resolves the latest image for the deployer from the
+// template or the referenced build resource.
+func resolveDeployerImage(ctx context.Context, c controllers.Config, parent *corev1alpha1.Deployer) error {
+	build := parent.Spec.Build
+	if build == nil {
+		parent.Status.LatestImage = parent.Spec.Template.Spec.Containers[0].Image
+		parent.Status.PinnedBuild = nil
+		parent.Status.AvailableImage = ""
+		return nil
+	}
+
+	switch {
+	case build.ApplicationRef != "":
+		var application buildv1alpha1.Application
+		key := types.NamespacedName{Namespace: parent.Namespace, Name: build.ApplicationRef}
+		// track application for new images
+		c.Tracker.Track(
+			tracker.NewKey(application.GetGroupVersionKind(), key),
+			types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+		)
+		if err := c.Get(ctx, key, &application); err != nil {
+			if apierrs.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		parent.Status.PropagateBuildStatus(build.Pin, &application.Status.BuildStatus)
+		return nil
+
+	case build.ContainerRef != "":
+		var container buildv1alpha1.Container
+		key := types.NamespacedName{Namespace: parent.Namespace, Name: build.ContainerRef}
+		// track container for new images
+		c.Tracker.Track(
+			tracker.NewKey(container.GetGroupVersionKind(), key),
+			types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+		)
+		if err := c.Get(ctx, key, &container); err != nil {
+			if apierrs.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		parent.Status.PropagateBuildStatus(build.Pin, &container.Status.BuildStatus)
+		return nil
+
+	case build.FunctionRef != "":
+		var function buildv1alpha1.Function
+		key := types.NamespacedName{Namespace: parent.Namespace, Name: build.FunctionRef}
+		// track function for new images
+		c.Tracker.Track(
+			tracker.NewKey(function.GetGroupVersionKind(), key),
+			types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+		)
+		if err := c.Get(ctx, key, &function); err != nil {
+			if apierrs.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		parent.Status.PropagateBuildStatus(build.Pin, &function.Status.BuildStatus)
+		return nil
+
+	}
+
+	return fmt.Errorf("invalid build")
+}
+
 func DeployerChildDeploymentReconciler(c controllers.Config) controllers.SubReconciler {
 	c.Log = c.Log.WithName("ChildDeployment")
 
@@ -155,52 +206,22 @@ func DeployerChildDeploymentReconciler(c controllers.Config) controllers.SubReco
 		ChildType:     &appsv1.Deployment{},
 		ChildListType: &appsv1.DeploymentList{},
 
-		DesiredChild: func(parent *corev1alpha1.Deployer) (*appsv1.Deployment, error) {
-			if parent.Status.LatestImage == "" {
+		DesiredChild: func(ctx context.Context, parent *corev1alpha1.Deployer) (*appsv1.Deployment, error) {
+			image := stableImage(parent)
+			if image == "" {
 				// no image, skip
 				return nil, nil
 			}
 
-			labels := controllers.MergeMaps(parent.Labels, map[string]string{
-				corev1alpha1.DeployerLabelKey: parent.Name,
-			})
-
-			template := *parent.Spec.Template.DeepCopy()
-			template.Labels = controllers.MergeMaps(template.Labels, labels)
-			targetPort := template.Spec.Containers[0].Ports[0]
-
-			template.Spec.Containers[0].Env = append(template.Spec.Containers[0].Env, corev1.EnvVar{
-				Name:  "PORT",
-				Value: fmt.Sprintf("%d", targetPort.ContainerPort),
-			})
-			if template.Spec.Containers[0].ReadinessProbe == nil {
-				template.Spec.Containers[0].ReadinessProbe = &corev1.Probe{
-					Handler: corev1.Handler{
-						TCPSocket: &corev1.TCPSocketAction{
-							Port: intstr.FromInt(int(targetPort.ContainerPort)),
-						},
-					},
-				}
-			}
-
-			child := &appsv1.Deployment{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels:       labels,
-					Annotations:  make(map[string]string),
-					GenerateName: fmt.Sprintf("%s-deployer-", parent.Name),
-					Namespace:    parent.Namespace,
-				},
-				Spec: appsv1.DeploymentSpec{
-					Selector: &metav1.LabelSelector{
-						MatchLabels: map[string]string{
-							corev1alpha1.DeployerLabelKey: parent.Name,
-						},
-					},
-					Template: template,
-				},
+			track := ""
+			if parent.Spec.Rollout != nil {
+				track = corev1alpha1.RolloutTrackStable
 			}
-			if child.Spec.Template.Spec.Containers[0].Image == "" {
-				child.Spec.Template.Spec.Containers[0].Image = parent.Status.LatestImage
+			child := desiredDeployment(parent, image, track)
+			child.Spec.Selector = stableDeploymentSelector(parent)
+			if replicas, ok := controllers.RetrieveValue(ctx, stableReplicasStashKey).(*int32); ok {
+				// carry over the replicas of the deployment being replaced
+				child.Spec.Replicas = replicas
 			}
 
 			return child, nil
@@ -217,7 +238,13 @@ func DeployerChildDeploymentReconciler(c controllers.Config) controllers.SubReco
 			}
 		},
 		HarmonizeImmutableFields: func(current, desired *appsv1.Deployment) {
+			// replicas are managed by the autoscaler or by users scaling the
+			// deployment directly
 			desired.Spec.Replicas = current.Spec.Replicas
+			// selectors are immutable, deployments with a legacy selector are
+			// replaced once a rollout needs it, see
+			// DeployerStableSelectorMigrationReconciler
+			desired.Spec.Selector = current.Spec.Selector
 		},
 		MergeBeforeUpdate: func(current, desired *appsv1.Deployment) {
 			current.Labels = desired.Labels
@@ -228,6 +255,13 @@ func DeployerChildDeploymentReconciler(c controllers.Config) controllers.SubReco
 				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
 		},
 
+		OurChild: func(child *appsv1.Deployment) bool {
+			// a deployment pending deletion is being replaced, see
+			// DeployerStableSelectorMigrationReconciler
+			return child.Labels[corev1alpha1.RolloutTrackLabelKey] != corev1alpha1.RolloutTrackCandidate &&
+				child.DeletionTimestamp == nil
+		},
+
 		Config:     c,
 		IndexField: ".metadata.deploymentController",
 		Sanitize: func(child *appsv1.Deployment) interface{} {
@@ -236,6 +270,257 @@ func DeployerChildDeploymentReconciler(c controllers.Config) controllers.SubReco
 	}
 }
 
+// stableReplicasStashKey holds the replicas of a stable deployment replaced by
+// DeployerStableSelectorMigrationReconciler
+const stableReplicasStashKey controllers.StashKey = "stable-replicas"
+
+// DeployerStableSelectorMigrationReconciler replaces stable deployments whose
+// selector also matches the pods of the rollout candidate. Selectors are
+// immutable, so once a rollout is configured the deployment is deleted
+// orphaning its replica sets and the replicas are carried over to the
+// replacement. The orphaned replica sets are adopted by the replacement, which
+// scales them down as it rolls out without restarting pods up front.
+func DeployerStableSelectorMigrationReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("StableSelectorMigration")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *corev1alpha1.Deployer) error {
+			var deployments appsv1.DeploymentList
+			if err := c.List(ctx, &deployments, client.InNamespace(parent.Namespace), client.MatchingField(".metadata.deploymentController", parent.Name)); err != nil {
+				return err
+			}
+			selector := stableDeploymentSelector(parent)
+			var stable *appsv1.Deployment
+			for i := range deployments.Items {
+				deployment := &deployments.Items[i]
+				if !metav1.IsControlledBy(deployment, parent) ||
+					deployment.Labels[corev1alpha1.RolloutTrackLabelKey] == corev1alpha1.RolloutTrackCandidate ||
+					deployment.DeletionTimestamp != nil {
+					continue
+				}
+				if equality.Semantic.DeepEqual(deployment.Spec.Selector, selector) {
+					stable = deployment
+					continue
+				}
+				if parent.Spec.Rollout == nil {
+					// the legacy selector only overlaps with the pods of a
+					// rollout candidate
+					continue
+				}
+				if deployment.Spec.Replicas != nil {
+					controllers.StashValue(ctx, stableReplicasStashKey, deployment.Spec.Replicas)
+				}
+				c.Log.Info("deleting deployment with legacy selector", "deployment", deployment.Name)
+				if err := c.Delete(ctx, deployment, client.PropagationPolicy(metav1.DeletePropagationOrphan)); err != nil {
+					c.Recorder.Eventf(parent, corev1.EventTypeWarning, "DeleteFailed",
+						"Failed to delete Deployment %q: %v", deployment.Name, err)
+					return err
+				}
+				c.Recorder.Eventf(parent, corev1.EventTypeNormal, "Deleted",
+					"Deleted Deployment %q", deployment.Name)
+			}
+			if stable == nil {
+				return nil
+			}
+			return adoptOrphanedReplicaSets(ctx, c, parent, stable)
+		},
+
+		Config: c,
+	}
+}
+
+// adoptOrphanedReplicaSets sets the stable deployment as the controller of
+// the deployer's replica sets that were orphaned by a replaced deployment.
+func adoptOrphanedReplicaSets(ctx context.Context, c controllers.Config, parent *corev1alpha1.Deployer, stable *appsv1.Deployment) error {
+	selector, err := metav1.LabelSelectorAsSelector(stable.Spec.Selector)
+	if err != nil {
+		return err
+	}
+	var replicaSets appsv1.ReplicaSetList
+	if err := c.List(ctx, &replicaSets, client.InNamespace(parent.Namespace), client.MatchingLabels{corev1alpha1.DeployerLabelKey: parent.Name}); err != nil {
+		return err
+	}
+	for i := range replicaSets.Items {
+		replicaSet := replicaSets.Items[i].DeepCopy()
+		if metav1.GetControllerOf(replicaSet) != nil ||
+			replicaSet.DeletionTimestamp != nil ||
+			!selector.Matches(labels.Set(replicaSet.Labels)) {
+			continue
+		}
+		if err := ctrl.SetControllerReference(stable, replicaSet, c.Scheme); err != nil {
+			return err
+		}
+		c.Log.Info("adopting orphaned replica set", "replicaSet", replicaSet.Name)
+		if err := c.Update(ctx, replicaSet); err != nil {
+			c.Recorder.Eventf(parent, corev1.EventTypeWarning, "UpdateFailed",
+				"Failed to update ReplicaSet %q: %v", replicaSet.Name, err)
+			return err
+		}
+		c.Recorder.Eventf(parent, corev1.EventTypeNormal, "Updated",
+			"Updated ReplicaSet %q", replicaSet.Name)
+	}
+	return nil
+}
+
+// stableDeploymentSelector matches the pods of the deployer, excluding pods of
+// the rollout candidate so the stable deployment and its autoscaler only count
+// stable pods.
+func stableDeploymentSelector(parent *corev1alpha1.Deployer) *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			corev1alpha1.DeployerLabelKey: parent.Name,
+		},
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{
+				Key:      corev1alpha1.RolloutTrackLabelKey,
+				Operator: metav1.LabelSelectorOpNotIn,
+				Values:   []string{corev1alpha1.RolloutTrackCandidate},
+			},
+		},
+	}
+}
+
+// stableImage is the image receiving traffic outside of a rollout
+func stableImage(parent *corev1alpha1.Deployer) string {
+	if parent.Status.Rollout != nil && parent.Status.Rollout.StableImage != "" {
+		return parent.Status.Rollout.StableImage
+	}
+	return parent.Status.LatestImage
+}
+
+// desiredDeployment runs the image with the deployer's pod template. The
+// track label is set when a rollout is configured.
+func desiredDeployment(parent *corev1alpha1.Deployer, image, track string) *appsv1.Deployment {
+	labels := controllers.MergeMaps(parent.Labels, map[string]string{
+		corev1alpha1.DeployerLabelKey: parent.Name,
+	})
+	if track != "" {
+		labels[corev1alpha1.RolloutTrackLabelKey] = track
+	}
+
+	template := *parent.Spec.Template.DeepCopy()
+	template.Labels = controllers.MergeMaps(template.Labels, labels)
+	targetPort := deployerPublicPort(parent)
+
+	template.Spec.Containers[0].Image = image
+	template.Spec.Containers[0].Env = append(template.Spec.Containers[0].Env, corev1.EnvVar{
+		Name:  "PORT",
+		Value: fmt.Sprintf("%d", targetPort.ContainerPort),
+	})
+	if template.Spec.Containers[0].ReadinessProbe == nil {
+		template.Spec.Containers[0].ReadinessProbe = deployerReadinessProbe(parent)
+	}
+	template.Spec.TopologySpreadConstraints = deployerTopologySpreadConstraints(parent, template.Spec.TopologySpreadConstraints)
+
+	child := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:       labels,
+			Annotations:  make(map[string]string),
+			GenerateName: fmt.Sprintf("%s-deployer-", parent.Name),
+			Namespace:    parent.Namespace,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Template: template,
+		},
+	}
+	if parent.Spec.Scale.Max != nil {
+		// start at the lower bound, the autoscaler manages replicas from here
+		child.Spec.Replicas = parent.Spec.Scale.Min
+	}
+
+	return child
+}
+
+func DeployerChildHorizontalPodAutoscalerReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ChildHorizontalPodAutoscaler")
+
+	return &controllers.ChildReconciler{
+		ParentType:    &corev1alpha1.Deployer{},
+		ChildType:     &autoscalingv2beta2.HorizontalPodAutoscaler{},
+		ChildListType: &autoscalingv2beta2.HorizontalPodAutoscalerList{},
+
+		DesiredChild: func(parent *corev1alpha1.Deployer) (*autoscalingv2beta2.HorizontalPodAutoscaler, error) {
+			if parent.Status.DeploymentRef == nil || parent.Spec.Scale.Max == nil {
+				// no deployment or autoscaling disabled, skip
+				return nil, nil
+			}
+
+			scale := parent.Spec.Scale
+			metrics := []autoscalingv2beta2.MetricSpec{}
+			if scale.TargetCPUUtilization != nil {
+				metrics = append(metrics, resourceUtilizationMetric(corev1.ResourceCPU, *scale.TargetCPUUtilization))
+			}
+			if scale.TargetMemoryUtilization != nil {
+				metrics = append(metrics, resourceUtilizationMetric(corev1.ResourceMemory, *scale.TargetMemoryUtilization))
+			}
+			for _, metric := range scale.Metrics {
+				metrics = append(metrics, *metric.DeepCopy())
+			}
+
+			child := &autoscalingv2beta2.HorizontalPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: controllers.MergeMaps(parent.Labels, map[string]string{
+						corev1alpha1.DeployerLabelKey: parent.Name,
+					}),
+					Annotations:  make(map[string]string),
+					GenerateName: fmt.Sprintf("%s-deployer-", parent.Name),
+					Namespace:    parent.Namespace,
+				},
+				Spec: autoscalingv2beta2.HorizontalPodAutoscalerSpec{
+					ScaleTargetRef: autoscalingv2beta2.CrossVersionObjectReference{
+						APIVersion: "apps/v1",
+						Kind:       "Deployment",
+						Name:       parent.Status.DeploymentRef.Name,
+					},
+					MinReplicas: scale.Min,
+					MaxReplicas: *scale.Max,
+					Metrics:     metrics,
+				},
+			}
+
+			return child, nil
+		},
+		ReflectChildStatusOnParent: func(parent *corev1alpha1.Deployer, child *autoscalingv2beta2.HorizontalPodAutoscaler, err error) {
+			if err != nil {
+				return
+			}
+			if child == nil {
+				parent.Status.MarkHorizontalPodAutoscalerNotUsed()
+			} else {
+				parent.Status.HorizontalPodAutoscalerRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
+				parent.Status.PropagateHorizontalPodAutoscalerStatus(&child.Status)
+			}
+		},
+		MergeBeforeUpdate: func(current, desired *autoscalingv2beta2.HorizontalPodAutoscaler) {
+			current.Labels = desired.Labels
+			current.Spec = desired.Spec
+		},
+		SemanticEquals: func(a1, a2 *autoscalingv2beta2.HorizontalPodAutoscaler) bool {
+			return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+		},
+
+		Config:     c,
+		IndexField: ".metadata.horizontalPodAutoscalerController",
+		Sanitize: func(child *autoscalingv2beta2.HorizontalPodAutoscaler) interface{} {
+			return child.Spec
+		},
+	}
+}
+
+func resourceUtilizationMetric(name corev1.ResourceName, utilization int32) autoscalingv2beta2.MetricSpec {
+	return autoscalingv2beta2.MetricSpec{
+		Type: autoscalingv2beta2.ResourceMetricSourceType,
+		Resource: &autoscalingv2beta2.ResourceMetricSource{
+			Name: name,
+			Target: autoscalingv2beta2.MetricTarget{
+				Type:               autoscalingv2beta2.UtilizationMetricType,
+				AverageUtilization: &utilization,
+			},
+		},
+	}
+}
+
 func DeployerChildServiceReconciler(c controllers.Config) controllers.SubReconciler {
 	c.Log = c.Log.WithName("ChildService")
 
@@ -250,7 +535,12 @@ func DeployerChildServiceReconciler(c controllers.Config) controllers.SubReconci
 				return nil, nil
 			}
 
-			targetPort := parent.Spec.Template.Spec.Containers[0].Ports[0]
+			selector := map[string]string{
+				corev1alpha1.DeployerLabelKey: parent.Name,
+			}
+			if parent.Spec.Rollout != nil {
+				selector[corev1alpha1.RolloutTrackLabelKey] = corev1alpha1.RolloutTrackStable
+			}
 
 			child := &corev1.Service{
 				ObjectMeta: metav1.ObjectMeta{
@@ -262,12 +552,8 @@ func DeployerChildServiceReconciler(c controllers.Config) controllers.SubReconci
 					Name:        parent.Name,
 				},
 				Spec: corev1.ServiceSpec{
-					Ports: []corev1.ServicePort{
-						{Name: targetPort.Name, Port: 80, TargetPort: intstr.FromInt(int(targetPort.ContainerPort))},
-					},
-					Selector: map[string]string{
-						corev1alpha1.DeployerLabelKey: parent.Name,
-					},
+					Ports:    deployerServicePorts(parent),
+					Selector: selector,
 				},
 			}
 
@@ -290,20 +576,22 @@ func DeployerChildServiceReconciler(c controllers.Config) controllers.SubReconci
 				parent.Status.PropagateServiceStatus(&child.Status)
 			}
 		},
-		HarmonizeImmutableFields: func(current, desired *corev1.Service) {
-			desired.Spec.ClusterIP = current.Spec.ClusterIP
-		},
-		MergeBeforeUpdate: func(current, desired *corev1.Service) {
-			current.Labels = desired.Labels
-			current.Spec = desired.Spec
-		},
 		SemanticEquals: func(a1, a2 *corev1.Service) bool {
-			return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
+			// the cluster IP and other defaulted fields are not applied
+			return servicePortsEqual(a1.Spec.Ports, a2.Spec.Ports) &&
+				equality.Semantic.DeepEqual(a1.Spec.Selector, a2.Spec.Selector) &&
 				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
 		},
 
-		Config:     c,
-		IndexField: ".metadata.serviceController",
+		OurChild: func(child *corev1.Service) bool {
+			return child.Labels[corev1alpha1.RolloutTrackLabelKey] != corev1alpha1.RolloutTrackCandidate
+		},
+
+		Config:       c,
+		IndexField:   ".metadata.serviceController",
+		FieldManager: deployerFieldManager,
+		// services of earlier releases were updated rather than applied
+		ForceOwnership: true,
 		Sanitize: func(child *corev1.Service) interface{} {
 			return child.Spec
 		},
@@ -328,50 +616,34 @@ func DeployerChildIngressReconciler(c controllers.Config) controllers.SubReconci
 				return nil, nil
 			}
 
-			coreSettings := &corev1.ConfigMap{}
-			coreSettingsKey := types.NamespacedName{Namespace: systemNamespace, Name: settingsConfigMapName}
-
-			// track config map
-			c.Tracker.Track(
-				tracker.NewKey(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, coreSettingsKey),
-				types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
-			)
-			if err := c.Get(context.TODO(), coreSettingsKey, coreSettings); err != nil {
-				c.Log.Error(err, fmt.Sprintf("unable to fetch resource with reference: %s", coreSettingsKey.String()))
+			coreSettings, err := deployerSettings(c, parent)
+			if err != nil {
 				return nil, err
 			}
-
-			domain := defaultDomain
-			if d := coreSettings.Data[defaultDomainKey]; d != "" {
-				domain = d
+			if deployerRoutingBackend(coreSettings) != routingBackendIngress {
+				// routed by another backend
+				return nil, nil
 			}
-			host := fmt.Sprintf("%s.%s.%s", parent.Name, parent.Namespace, domain)
+			routes := deployerRoutes(coreSettings, parent)
+			spec, annotations := deployerIngressSpec(routes, parent.Status.ServiceRef.Name)
+			annotations = controllers.MergeMaps(annotations, deployerBackendProtocolAnnotations(parent))
 
 			child := &networkingv1beta1.Ingress{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: controllers.MergeMaps(parent.Labels, map[string]string{
 						corev1alpha1.DeployerLabelKey: parent.Name,
 					}),
-					Annotations:  make(map[string]string),
+					Annotations:  annotations,
 					GenerateName: fmt.Sprintf("%s-deployer-", parent.Name),
 					Namespace:    parent.Namespace,
 				},
-				Spec: networkingv1beta1.IngressSpec{
-					Rules: []networkingv1beta1.IngressRule{{
-						Host: host,
-						IngressRuleValue: networkingv1beta1.IngressRuleValue{
-							HTTP: &networkingv1beta1.HTTPIngressRuleValue{
-								Paths: []networkingv1beta1.HTTPIngressPath{{
-									Path: "/",
-									Backend: networkingv1beta1.IngressBackend{
-										ServiceName: parent.Status.ServiceRef.Name,
-										ServicePort: intstr.FromInt(80),
-									},
-								}},
-							},
-						},
-					}},
-				},
+				Spec: spec,
+			}
+			if secretName := deployerTLSSecretName(parent); secretName != "" {
+				child.Spec.TLS = []networkingv1beta1.IngressTLS{{
+					Hosts:      deployerRouteHosts(routes),
+					SecretName: secretName,
+				}}
 			}
 
 			return child, nil
@@ -383,22 +655,30 @@ func DeployerChildIngressReconciler(c controllers.Config) controllers.SubReconci
 			if child == nil {
 				parent.Status.IngressRef = nil
 				parent.Status.URL = ""
+				parent.Status.URLs = nil
 				if parent.Spec.IngressPolicy == corev1alpha1.IngressPolicyClusterLocal {
 					parent.Status.MarkIngressNotRequired()
 				}
 			} else {
 				parent.Status.IngressRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
-				parent.Status.URL = fmt.Sprintf("http://%s", child.Spec.Rules[0].Host)
+				parent.Status.URLs = deployerIngressURLs(child)
+				parent.Status.URL = parent.Status.URLs[0]
 				parent.Status.PropagateIngressStatus(&child.Status)
 			}
 		},
 		MergeBeforeUpdate: func(current, desired *networkingv1beta1.Ingress) {
 			current.Labels = desired.Labels
+			current.Annotations = mergeManagedIngressAnnotations(current.Annotations, desired.Annotations)
 			current.Spec = desired.Spec
 		},
 		SemanticEquals: func(a1, a2 *networkingv1beta1.Ingress) bool {
 			return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
-				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels) &&
+				equality.Semantic.DeepEqual(managedIngressAnnotations(a1.Annotations), managedIngressAnnotations(a2.Annotations))
+		},
+
+		OurChild: func(child *networkingv1beta1.Ingress) bool {
+			return child.Labels[corev1alpha1.RolloutTrackLabelKey] != corev1alpha1.RolloutTrackCandidate
 		},
 
 		Config:     c,
@@ -408,3 +688,35 @@ func DeployerChildIngressReconciler(c controllers.Config) controllers.SubReconci
 		},
 	}
 }
+
+// deployerIngressHost resolves the public host name for the deployer from the
+// default domain in the core settings.
+func deployerIngressHost(coreSettings *corev1.ConfigMap, parent *corev1alpha1.Deployer) string {
+	return fmt.Sprintf("%s.%s.%s", parent.Name, parent.Namespace, deployerIngressDomain(coreSettings))
+}
+
+// deployerIngressDomain returns the domain of the default deployer hosts.
+func deployerIngressDomain(coreSettings *corev1.ConfigMap) string {
+	if domain := coreSettings.Data[defaultDomainKey]; domain != "" {
+		return domain
+	}
+	return defaultDomain
+}
+
+// deployerSettings fetches the core settings, tracking the config map for
+// changes.
+func deployerSettings(c controllers.Config, parent *corev1alpha1.Deployer) (*corev1.ConfigMap, error) {
+	coreSettings := &corev1.ConfigMap{}
+	coreSettingsKey := types.NamespacedName{Namespace: systemNamespace, Name: settingsConfigMapName}
+
+	// track config map
+	c.Tracker.Track(
+		tracker.NewKey(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, coreSettingsKey),
+		types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+	)
+	if err := c.Get(context.TODO(), coreSettingsKey, coreSettings); err != nil {
+		c.Log.Error(err, fmt.Sprintf("unable to fetch resource with reference: %s", coreSettingsKey.String()))
+		return nil, err
+	}
+	return coreSettings, nil
+}
@@ -17,23 +17,31 @@ limitations under the License.
 package core_test
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	certmanagerv1alpha2 "github.com/projectriff/system/pkg/apis/thirdparty/certmanager/v1alpha2"
+	gatewayv1beta1 "github.com/projectriff/system/pkg/apis/thirdparty/gateway/v1beta1"
 	"github.com/projectriff/system/pkg/controllers"
 
 	corecontrollers "github.com/projectriff/system/pkg/controllers/core"
@@ -43,12 +51,17 @@ import (
 )
 
 func TestDeployerReconciler(t *testing.T) {
+	// timestamps lose their fraction of a second when stored by the client
+	testNow := time.Now().Truncate(time.Second)
+
 	testNamespace := "test-namespace"
 	testName := "test-deployer"
 	testKey := types.NamespacedName{Namespace: testNamespace, Name: testName}
 	testImagePrefix := "example.com/repo"
 	testSha256 := "cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e"
 	testImage := fmt.Sprintf("%s@sha256:%s", testImagePrefix, testSha256)
+	testNewSha256 := "a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f90"
+	testNewImage := fmt.Sprintf("%s@sha256:%s", testImagePrefix, testNewSha256)
 	testConditionReason := "TestReason"
 	testConditionMessage := "meaningful, yet concise"
 	testDomain := "example.com"
@@ -58,15 +71,19 @@ func TestDeployerReconciler(t *testing.T) {
 	testLabelKey := "test-label-key"
 	testLabelValue := "test-label-value"
 
+	deployerConditionBuildReady := factories.Condition().Type(corev1alpha1.DeployerConditionBuildReady)
 	deployerConditionDeploymentReady := factories.Condition().Type(corev1alpha1.DeployerConditionDeploymentReady)
 	deployerConditionIngressReady := factories.Condition().Type(corev1alpha1.DeployerConditionIngressReady)
 	deployerConditionReady := factories.Condition().Type(corev1alpha1.DeployerConditionReady)
+	deployerConditionRolloutReady := factories.Condition().Type(corev1alpha1.DeployerConditionRolloutReady)
 	deployerConditionServiceReady := factories.Condition().Type(corev1alpha1.DeployerConditionServiceReady)
 
 	scheme := runtime.NewScheme()
 	_ = clientgoscheme.AddToScheme(scheme)
 	_ = buildv1alpha1.AddToScheme(scheme)
 	_ = corev1alpha1.AddToScheme(scheme)
+	_ = certmanagerv1alpha2.AddToScheme(scheme)
+	_ = gatewayv1beta1.AddToScheme(scheme)
 
 	deployerMinimal := factories.DeployerCore().
 		NamespaceName(testNamespace, testName)
@@ -74,7 +91,21 @@ func TestDeployerReconciler(t *testing.T) {
 		Image(testImage).
 		IngressPolicy(corev1alpha1.IngressPolicyClusterLocal)
 
-	deploymentCreate := factories.Deployment().
+	deployerPorts := deployerMinimal.
+		Image(testImage).
+		HandlerContainer(func(container *corev1.Container) {
+			container.Ports = []corev1.ContainerPort{
+				{Name: "grpc", ContainerPort: 9090, Protocol: corev1.ProtocolTCP},
+				{Name: "admin", ContainerPort: 8081, Protocol: corev1.ProtocolTCP},
+			}
+		}).
+		Ports(
+			corev1alpha1.DeployerPort{Name: "grpc", Protocol: corev1alpha1.PortProtocolGRPC, GRPCHealthCheck: true},
+			corev1alpha1.DeployerPort{Name: "admin", Protocol: corev1alpha1.PortProtocolHTTP1},
+		).
+		PublicPort("grpc")
+
+	deploymentTemplate := factories.Deployment().
 		ObjectMeta(func(om factories.ObjectMeta) {
 			om.Namespace(testNamespace)
 			om.GenerateName("%s-deployer-", deployerMinimal.Create().GetName())
@@ -98,6 +129,8 @@ func TestDeployerReconciler(t *testing.T) {
 				},
 			}
 		})
+	deploymentCreate := deploymentTemplate.
+		AddSelectorExpression(corev1alpha1.RolloutTrackLabelKey, metav1.LabelSelectorOpNotIn, corev1alpha1.RolloutTrackCandidate)
 	deploymentGiven := deploymentCreate.
 		ObjectMeta(func(om factories.ObjectMeta) {
 			om.Name("%s%s", om.Create().GenerateName, "000")
@@ -118,10 +151,63 @@ func TestDeployerReconciler(t *testing.T) {
 				TargetPort: intstr.FromInt(8080),
 			},
 		)
+	// serviceApplied are the fields of the service applied by the deployer
+	serviceApplied := func(selector string) metav1.ManagedFieldsEntry {
+		return metav1.ManagedFieldsEntry{
+			Manager:    "riff-core-deployer",
+			Operation:  metav1.ManagedFieldsOperationApply,
+			APIVersion: "v1",
+			FieldsType: "FieldsV1",
+			FieldsV1: &metav1.FieldsV1{
+				Raw: []byte(fmt.Sprintf(`{"f:metadata":{"f:labels":{"f:%s":{}},"f:name":{},"f:namespace":{},"f:ownerReferences":{}},"f:spec":{"f:ports":{},"f:selector":{%s}}}`, corev1alpha1.DeployerLabelKey, selector)),
+			},
+		}
+	}
 	serviceGiven := serviceCreate.
 		ObjectMeta(func(om factories.ObjectMeta) {
 			om.Created(1)
 			om.ControlledBy(deployerMinimal, scheme)
+			om.ManagedFields(serviceApplied(fmt.Sprintf(`"f:%s":{}`, corev1alpha1.DeployerLabelKey)))
+		})
+
+	portsDeploymentCreate := deploymentCreate.
+		HandlerContainer(func(container *corev1.Container) {
+			container.Ports = []corev1.ContainerPort{
+				{Name: "grpc", ContainerPort: 9090, Protocol: corev1.ProtocolTCP},
+				{Name: "admin", ContainerPort: 8081, Protocol: corev1.ProtocolTCP},
+			}
+			container.Env = []corev1.EnvVar{
+				{Name: "PORT", Value: "9090"},
+			}
+			container.ReadinessProbe = &corev1.Probe{
+				Handler: corev1.Handler{
+					Exec: &corev1.ExecAction{
+						Command: []string{"grpc_health_probe", "-addr=:9090"},
+					},
+				},
+			}
+		})
+	portsDeploymentGiven := portsDeploymentCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Name("%s%s", om.Create().GenerateName, "000")
+			om.Created(1)
+		})
+	portsServiceCreate := serviceCreate.
+		Ports(
+			corev1.ServicePort{
+				Name:       "grpc",
+				Port:       80,
+				TargetPort: intstr.FromInt(9090),
+			},
+			corev1.ServicePort{
+				Name:       "http-admin",
+				Port:       8081,
+				TargetPort: intstr.FromInt(8081),
+			},
+		)
+	portsServiceGiven := portsServiceCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Created(1)
 		})
 
 	ingressCreate := factories.Ingress().
@@ -138,6 +224,194 @@ func TestDeployerReconciler(t *testing.T) {
 			om.Created(1)
 		})
 
+	testScale := corev1alpha1.Scale{
+		Min:                  rtesting.Int32Ptr(1),
+		Max:                  rtesting.Int32Ptr(3),
+		TargetCPUUtilization: rtesting.Int32Ptr(80),
+	}
+	hpaCreate := factories.HorizontalPodAutoscaler().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.GenerateName("%s-deployer-", deployerMinimal.Create().GetName())
+			om.AddLabel(corev1alpha1.DeployerLabelKey, deployerMinimal.Create().GetName())
+			om.ControlledBy(deployerMinimal, scheme)
+		}).
+		ScaleTargetDeployment(deploymentGiven.Create().GetName()).
+		Replicas(1, 3).
+		AddResourceUtilizationMetric(corev1.ResourceCPU, 80)
+	hpaGiven := hpaCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Name("%s%s", om.Create().GenerateName, "000")
+			om.Created(1)
+		})
+
+	testCanary := corev1alpha1.Rollout{
+		Canary: &corev1alpha1.CanaryRollout{
+			Steps: []corev1alpha1.CanaryStep{
+				{Weight: 20, Pause: &metav1.Duration{Duration: time.Minute}},
+				{Weight: 50, Pause: &metav1.Duration{Duration: time.Minute}},
+			},
+		},
+	}
+	testBlueGreen := corev1alpha1.Rollout{
+		BlueGreen: &corev1alpha1.BlueGreenRollout{},
+	}
+	testCandidateServiceName := fmt.Sprintf("%s-candidate", testName)
+
+	deployerRollout := deployerValid.
+		Rollout(testCanary).
+		StatusConditions(
+			deployerConditionDeploymentReady.Unknown(),
+			deployerConditionIngressReady.True(),
+			deployerConditionReady.Unknown(),
+			deployerConditionRolloutReady.True().Info(),
+			deployerConditionServiceReady.True(),
+		).
+		StatusLatestImage(testImage).
+		StatusDeploymentRef("%s-deployer-000", testName).
+		StatusServiceRef(testName).
+		StatusAddressURL(testAddressURL).
+		StatusRolloutImages(testImage, "")
+	deployerCandidate := deployerRollout.
+		Image(testNewImage).
+		StatusConditions(
+			deployerConditionDeploymentReady.Unknown(),
+			deployerConditionIngressReady.True(),
+			deployerConditionReady.Unknown(),
+			deployerConditionRolloutReady.Unknown().Info().Reason("CandidateNotReady", fmt.Sprintf("Image %q is starting", testNewImage)),
+			deployerConditionServiceReady.True(),
+		).
+		StatusLatestImage(testNewImage).
+		StatusRolloutImages(testImage, testNewImage).
+		StatusCandidateDeploymentRef("%s-deployer-candidate-000", testName).
+		StatusCandidateServiceRef(testCandidateServiceName)
+
+	stableDeploymentGiven := deploymentGiven.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.AddLabel(corev1alpha1.RolloutTrackLabelKey, corev1alpha1.RolloutTrackStable)
+		}).
+		PodTemplateSpec(func(pts factories.PodTemplateSpec) {
+			pts.AddLabel(corev1alpha1.RolloutTrackLabelKey, corev1alpha1.RolloutTrackStable)
+		})
+	// orphanedReplicaSet was orphaned by a stable deployment replaced to change
+	// its selector
+	orphanedReplicaSet := factories.ReplicaSet().
+		NamespaceName(testNamespace, fmt.Sprintf("%s-deployer-000-xyz", testName)).
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.AddLabel(corev1alpha1.RolloutTrackLabelKey, corev1alpha1.RolloutTrackStable)
+			om.Created(1)
+		}).
+		Replicas(3)
+	stableServiceCreate := serviceCreate.
+		AddSelectorLabel(corev1alpha1.RolloutTrackLabelKey, corev1alpha1.RolloutTrackStable)
+	stableServiceGiven := serviceGiven.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.ManagedFields(serviceApplied(fmt.Sprintf(`"f:%s":{},"f:%s":{}`, corev1alpha1.DeployerLabelKey, corev1alpha1.RolloutTrackLabelKey)))
+		}).
+		AddSelectorLabel(corev1alpha1.RolloutTrackLabelKey, corev1alpha1.RolloutTrackStable)
+	candidateDeploymentCreate := deploymentTemplate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.GenerateName("%s-deployer-candidate-", testName)
+			om.AddLabel(corev1alpha1.RolloutTrackLabelKey, corev1alpha1.RolloutTrackCandidate)
+		}).
+		AddSelectorLabel(corev1alpha1.RolloutTrackLabelKey, corev1alpha1.RolloutTrackCandidate).
+		ProgressDeadlineSeconds(600).
+		HandlerContainer(func(container *corev1.Container) {
+			container.Image = testNewImage
+		})
+	candidateDeploymentGiven := candidateDeploymentCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Name("%s%s", om.Create().GenerateName, "000")
+			om.Created(1)
+		})
+	candidateServiceCreate := serviceCreate.
+		NamespaceName(testNamespace, testCandidateServiceName).
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.AddLabel(corev1alpha1.RolloutTrackLabelKey, corev1alpha1.RolloutTrackCandidate)
+		}).
+		AddSelectorLabel(corev1alpha1.RolloutTrackLabelKey, corev1alpha1.RolloutTrackCandidate)
+	candidateServiceGiven := candidateServiceCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Created(1)
+		})
+	candidateIngressCreate := factories.Ingress().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.GenerateName("%s-deployer-candidate-", testName)
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.AddLabel(corev1alpha1.RolloutTrackLabelKey, corev1alpha1.RolloutTrackCandidate)
+			om.AddAnnotation("nginx.ingress.kubernetes.io/canary", "true")
+			om.AddAnnotation("nginx.ingress.kubernetes.io/canary-weight", "0")
+			om.AddAnnotation("nginx.ingress.kubernetes.io/canary-by-header", "X-Riff-Candidate")
+			om.ControlledBy(deployerMinimal, scheme)
+		}).
+		HostToService(testHost, testCandidateServiceName)
+	candidateIngressGiven := candidateIngressCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Name("%s%s", om.Create().GenerateName, "000")
+			om.Created(1)
+		})
+
+	testIssuer := "test-issuer"
+	testTLSSecretName := "test-tls"
+	testHTTPSURL := fmt.Sprintf("https://%s", testHost)
+	deployerConditionCertificateReady := factories.Condition().Type(corev1alpha1.DeployerConditionCertificateReady)
+	deployerExternal := deployerMinimal.
+		Image(testImage).
+		IngressPolicy(corev1alpha1.IngressPolicyExternal)
+	httpRouteCreate := factories.GatewayHTTPRoute().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.GenerateName("%s-deployer-", testName)
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.ControlledBy(deployerMinimal, scheme)
+		}).
+		Gateway("riff-system", "riff-gateway").
+		Hostnames(testHost).
+		AddRulePath("/", testName)
+	httpRouteGiven := httpRouteCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Name("%s%s", om.Create().GenerateName, "000")
+			om.Created(1)
+		})
+	certificateCreate := factories.CertManagerCertificate().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.GenerateName("%s-deployer-", testName)
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.ControlledBy(deployerMinimal, scheme)
+		}).
+		Host(testHost).
+		SecretName("%s-deployer-tls", testName).
+		IssuerRef("ClusterIssuer", testIssuer)
+	certificateGiven := certificateCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Name("%s%s", om.Create().GenerateName, "000")
+			om.Created(1)
+		})
+	defaultTLSSecret := factories.Secret().
+		NamespaceName("riff-system", "wildcard-tls").
+		Type(corev1.SecretTypeTLS).
+		AddData("tls.crt", "test-certificate").
+		AddData("tls.key", "test-key")
+	tlsSecretCreate := defaultTLSSecret.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.Name("")
+			om.GenerateName("%s-deployer-tls-", testName)
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.ControlledBy(deployerMinimal, scheme)
+		})
+
+	ingressRoutesCreate := factories.Ingress().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.GenerateName("%s-deployer-", testName)
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.ControlledBy(deployerMinimal, scheme)
+		})
+
 	testApplication := factories.Application().
 		NamespaceName(testNamespace, "my-application").
 		StatusLatestImage(testImage)
@@ -151,6 +425,13 @@ func TestDeployerReconciler(t *testing.T) {
 	testSettings := factories.ConfigMap().
 		NamespaceName("riff-system", "riff-core-settings").
 		AddData("defaultDomain", "example.com")
+	testGatewaySettings := testSettings.
+		AddData("routingBackend", "gateway").
+		AddData("gateway", "riff-system/riff-gateway")
+
+	rolloutMetrics := testRolloutMetrics{
+		fmt.Sprintf("%s/%s", testNamespace, testCandidateServiceName): 12.5,
+	}
 
 	table := rtesting.Table{{
 		Name: "deployer does not exist",
@@ -192,6 +473,8 @@ func TestDeployerReconciler(t *testing.T) {
 		},
 		ExpectCreates: []rtesting.Factory{
 			deploymentCreate,
+		},
+		ExpectApplies: []rtesting.Factory{
 			serviceCreate,
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
@@ -255,6 +538,136 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.Unknown(),
 				),
 		},
+	}, {
+		Name: "create resources, from application, pinned build",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				ApplicationRef(testApplication.Create().GetName()).
+				BuildPin(&buildv1alpha1.BuildPin{BuildNumber: 1}),
+			testApplication.
+				StatusLatestImage(testNewImage).
+				StatusBuilds(
+					buildv1alpha1.BuildRecord{Name: "my-application-build-2", BuildNumber: 2, Image: testNewImage},
+					buildv1alpha1.BuildRecord{Name: "my-application-build-1", BuildNumber: 1, Image: testImage},
+				),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testApplication, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Deployment "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Service "%s"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			deploymentCreate,
+		},
+		ExpectApplies: []rtesting.Factory{
+			serviceCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusPinnedBuild(&buildv1alpha1.BuildPin{BuildNumber: 1, Digest: "sha256:" + testSha256}).
+				StatusAvailableImage(testNewImage).
+				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+		},
+	}, {
+		Name: "update deployment, promote pinned build",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation(buildv1alpha1.PromoteAnnotationKey, "2")
+				}).
+				ApplicationRef(testApplication.Create().GetName()).
+				BuildPin(&buildv1alpha1.BuildPin{BuildNumber: 1}).
+				StatusLatestImage(testImage).
+				StatusPinnedBuild(&buildv1alpha1.BuildPin{BuildNumber: 1, Digest: "sha256:" + testSha256}).
+				StatusAvailableImage(testNewImage).
+				StatusDeploymentRef(deploymentGiven.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()),
+			testApplication.
+				StatusLatestImage(testNewImage).
+				StatusBuilds(
+					buildv1alpha1.BuildRecord{Name: "my-application-build-2", BuildNumber: 2, Image: testNewImage},
+					buildv1alpha1.BuildRecord{Name: "my-application-build-1", BuildNumber: 1, Image: testImage},
+				),
+			deploymentGiven,
+			serviceGiven,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testApplication, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Deployment "%s"`, deploymentGiven.Create().GetName()),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			deploymentGiven.
+				HandlerContainer(func(container *corev1.Container) {
+					container.Image = testNewImage
+				}),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testNewImage).
+				StatusPinnedBuild(&buildv1alpha1.BuildPin{BuildNumber: 2, Digest: "sha256:" + testNewSha256}).
+				StatusDeploymentRef(deploymentGiven.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+		},
+	}, {
+		Name: "create resources, from application, pinned build not found",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				ApplicationRef(testApplication.Create().GetName()).
+				BuildPin(&buildv1alpha1.BuildPin{BuildNumber: 3}),
+			testApplication.
+				StatusLatestImage(testNewImage).
+				StatusBuilds(
+					buildv1alpha1.BuildRecord{Name: "my-application-build-2", BuildNumber: 2, Image: testNewImage},
+				),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testApplication, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionBuildReady.False().Info().Reason("PinNotFound", "The pinned build number 3 does not match a build."),
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.Unknown(),
+				).
+				StatusAvailableImage(testNewImage),
+		},
 	}, {
 		Name: "create resources, from function",
 		Key:  testKey,
@@ -276,6 +689,8 @@ func TestDeployerReconciler(t *testing.T) {
 		},
 		ExpectCreates: []rtesting.Factory{
 			deploymentCreate,
+		},
+		ExpectApplies: []rtesting.Factory{
 			serviceCreate,
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
@@ -360,6 +775,8 @@ func TestDeployerReconciler(t *testing.T) {
 		},
 		ExpectCreates: []rtesting.Factory{
 			deploymentCreate,
+		},
+		ExpectApplies: []rtesting.Factory{
 			serviceCreate,
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
@@ -440,6 +857,8 @@ func TestDeployerReconciler(t *testing.T) {
 		},
 		ExpectCreates: []rtesting.Factory{
 			deploymentCreate,
+		},
+		ExpectApplies: []rtesting.Factory{
 			serviceCreate,
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
@@ -455,6 +874,39 @@ func TestDeployerReconciler(t *testing.T) {
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
 		},
+	}, {
+		Name: "create resources, with grpc and admin ports",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerPorts,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Deployment "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Service "%s"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			portsDeploymentCreate,
+		},
+		ExpectApplies: []rtesting.Factory{
+			portsServiceCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+		},
 	}, {
 		Name: "create deployment, error",
 		Key:  testKey,
@@ -489,7 +941,7 @@ func TestDeployerReconciler(t *testing.T) {
 		Name: "create service, error",
 		Key:  testKey,
 		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("create", "Service"),
+			rtesting.InduceFailure("patch", "Service"),
 		},
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
@@ -500,12 +952,14 @@ func TestDeployerReconciler(t *testing.T) {
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
 				`Created Deployment "%s-deployer-001"`, testName),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "CreationFailed",
-				`Failed to create Service "%s": inducing failure for create Service`, testName),
+				`Failed to create Service "%s": inducing failure for patch Service`, testName),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
 		ExpectCreates: []rtesting.Factory{
 			deploymentCreate,
+		},
+		ExpectApplies: []rtesting.Factory{
 			serviceCreate,
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
@@ -523,7 +977,7 @@ func TestDeployerReconciler(t *testing.T) {
 		Name: "create service, conflicted",
 		Key:  testKey,
 		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("create", "Service", rtesting.InduceFailureOpts{
+			rtesting.InduceFailure("patch", "Service", rtesting.InduceFailureOpts{
 				Error: apierrs.NewAlreadyExists(schema.GroupResource{}, testName),
 			}),
 		},
@@ -541,6 +995,8 @@ func TestDeployerReconciler(t *testing.T) {
 		},
 		ExpectCreates: []rtesting.Factory{
 			deploymentCreate,
+		},
+		ExpectApplies: []rtesting.Factory{
 			serviceCreate,
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
@@ -585,11 +1041,8 @@ func TestDeployerReconciler(t *testing.T) {
 			deploymentGiven,
 		},
 	}, {
-		Name: "update deployment, update error",
+		Name: "update deployment, keep legacy selector without rollout",
 		Key:  testKey,
-		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("update", "Deployment"),
-		},
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
 				Image(testImage).
@@ -603,23 +1056,49 @@ func TestDeployerReconciler(t *testing.T) {
 				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
-			deploymentGiven.
-				HandlerContainer(func(container *corev1.Container) {
-					// change to reverse
-					container.Env = nil
+			deploymentTemplate.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Name("%s%s", om.Create().GenerateName, "000")
+					om.Created(1)
 				}),
 			serviceGiven,
 		},
-		ShouldErr: true,
-		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "UpdateFailed",
-				`Failed to update Deployment "%s": inducing failure for update Deployment`, deploymentGiven.Create().GetName()),
-		},
-		ExpectUpdates: []rtesting.Factory{
-			deploymentGiven,
-		},
 	}, {
-		Name: "update deployment, list deployments failed",
+		Name: "update deployment, update error",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("update", "Deployment"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+			deploymentGiven.
+				HandlerContainer(func(container *corev1.Container) {
+					// change to reverse
+					container.Env = nil
+				}),
+			serviceGiven,
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "UpdateFailed",
+				`Failed to update Deployment "%s": inducing failure for update Deployment`, deploymentGiven.Create().GetName()),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			deploymentGiven,
+		},
+	}, {
+		Name: "update deployment, list deployments failed",
 		Key:  testKey,
 		WithReactors: []rtesting.ReactionFunc{
 			rtesting.InduceFailure("list", "DeploymentList"),
@@ -670,14 +1149,14 @@ func TestDeployerReconciler(t *testing.T) {
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
 				`Updated Service "%s"`, serviceGiven.Create().GetName()),
 		},
-		ExpectUpdates: []rtesting.Factory{
-			serviceGiven,
+		ExpectApplies: []rtesting.Factory{
+			serviceCreate,
 		},
 	}, {
 		Name: "update service, update error",
 		Key:  testKey,
 		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("update", "Service"),
+			rtesting.InduceFailure("patch", "Service"),
 		},
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
@@ -700,17 +1179,14 @@ func TestDeployerReconciler(t *testing.T) {
 		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "UpdateFailed",
-				`Failed to update Service "%s": inducing failure for update Service`, serviceGiven.Create().GetName()),
+				`Failed to update Service "%s": inducing failure for patch Service`, serviceGiven.Create().GetName()),
 		},
-		ExpectUpdates: []rtesting.Factory{
-			serviceGiven,
+		ExpectApplies: []rtesting.Factory{
+			serviceCreate,
 		},
 	}, {
-		Name: "update service, list services failed",
+		Name: "update service, migrate fields updated by an earlier release",
 		Key:  testKey,
-		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("list", "ServiceList"),
-		},
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
 				Image(testImage).
@@ -726,13 +1202,35 @@ func TestDeployerReconciler(t *testing.T) {
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
 			deploymentGiven,
 			serviceGiven.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.ManagedFields(metav1.ManagedFieldsEntry{
+						Manager:   "manager",
+						Operation: metav1.ManagedFieldsOperationUpdate,
+					})
+				}).
 				// change to reverse
 				Ports(),
 		},
-		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Service "%s"`, serviceGiven.Create().GetName()),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			serviceGiven.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.ManagedFields(metav1.ManagedFieldsEntry{})
+				}).
+				Ports(),
+		},
+		ExpectApplies: []rtesting.Factory{
+			serviceCreate,
+		},
 	}, {
-		Name: "cleanup extra deployments",
+		Name: "update service, migrate fields error",
 		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("update", "Service"),
+		},
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
 				Image(testImage).
@@ -743,35 +1241,62 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
-			deploymentGiven.
-				NamespaceName(testNamespace, "extra-deployment-1"),
-			deploymentGiven.
-				NamespaceName(testNamespace, "extra-deployment-2"),
-			serviceGiven,
+			deploymentGiven,
+			serviceGiven.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.ManagedFields()
+				}).
+				// change to reverse
+				Ports(),
 		},
+		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
-				`Deleted Deployment "%s"`, "extra-deployment-1"),
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
-				`Deleted Deployment "%s"`, "extra-deployment-2"),
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
-				`Created Deployment "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "UpdateFailed",
+				`Failed to update Service "%s": inducing failure for update Service`, serviceGiven.Create().GetName()),
 		},
-		ExpectDeletes: []rtesting.DeleteRef{
-			{Group: "apps", Kind: "Deployment", Namespace: testNamespace, Name: "extra-deployment-1"},
-			{Group: "apps", Kind: "Deployment", Namespace: testNamespace, Name: "extra-deployment-2"},
+		ExpectUpdates: []rtesting.Factory{
+			serviceGiven.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.ManagedFields(metav1.ManagedFieldsEntry{})
+				}).
+				Ports(),
 		},
-		ExpectCreates: []rtesting.Factory{
-			deploymentCreate,
+	}, {
+		Name: "update service, unchanged with defaults",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+			deploymentGiven,
+			serviceGiven.
+				ClusterIP("10.0.0.1").
+				Ports(
+					corev1.ServicePort{
+						Name:       "http",
+						Protocol:   corev1.ProtocolTCP,
+						Port:       80,
+						TargetPort: intstr.FromInt(8080),
+					},
+				),
 		},
 	}, {
-		Name: "cleanup extra deployments, delete deployment failed",
+		Name: "update service, list services failed",
 		Key:  testKey,
 		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("delete", "Deployment"),
+			rtesting.InduceFailure("list", "ServiceList"),
 		},
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
@@ -783,29 +1308,36 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+			deploymentGiven,
+			serviceGiven.
+				// change to reverse
+				Ports(),
+		},
+		ShouldErr: true,
+	}, {
+		Name: "create horizontal pod autoscaler",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerValid.
+				Scale(testScale),
 			deploymentGiven.
-				NamespaceName(testNamespace, "extra-deployment-1"),
-			deploymentGiven.
-				NamespaceName(testNamespace, "extra-deployment-2"),
+				Replicas(1),
 			serviceGiven,
 		},
-		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "DeleteFailed",
-				`Failed to delete Deployment "%s": inducing failure for delete Deployment`, "extra-deployment-1"),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created HorizontalPodAutoscaler "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
 		},
-		ExpectDeletes: []rtesting.DeleteRef{
-			{Group: "apps", Kind: "Deployment", Namespace: testNamespace, Name: "extra-deployment-1"},
+		ExpectCreates: []rtesting.Factory{
+			hpaCreate,
 		},
-	}, {
-		Name: "cleanup extra services",
-		Key:  testKey,
-		GivenObjects: []rtesting.Factory{
+		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
-				Image(testImage).
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
 					deployerConditionIngressReady.True(),
@@ -814,38 +1346,108 @@ func TestDeployerReconciler(t *testing.T) {
 				).
 				StatusLatestImage(testImage).
 				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusHorizontalPodAutoscalerRef("%s-deployer-001", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
-			deploymentGiven,
-			serviceGiven.
-				NamespaceName(testNamespace, "extra-service-1"),
-			serviceGiven.
-				NamespaceName(testNamespace, "extra-service-2"),
+		},
+	}, {
+		Name: "create resources, with autoscaling",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerValid.
+				Scale(corev1alpha1.Scale{
+					Min: rtesting.Int32Ptr(2),
+					Max: rtesting.Int32Ptr(5),
+				}),
 		},
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
-				`Deleted Service "%s"`, "extra-service-1"),
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
-				`Deleted Service "%s"`, "extra-service-2"),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Deployment "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created HorizontalPodAutoscaler "%s-deployer-002"`, testName),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
 				`Created Service "%s"`, testName),
-		},
-		ExpectDeletes: []rtesting.DeleteRef{
-			{Kind: "Service", Namespace: testNamespace, Name: "extra-service-1"},
-			{Kind: "Service", Namespace: testNamespace, Name: "extra-service-2"},
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
 		},
 		ExpectCreates: []rtesting.Factory{
+			deploymentCreate.
+				Replicas(2),
+			hpaCreate.
+				ScaleTargetDeployment(fmt.Sprintf("%s-deployer-001", testName)).
+				Replicas(2, 5),
+		},
+		ExpectApplies: []rtesting.Factory{
 			serviceCreate,
 		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
+				StatusHorizontalPodAutoscalerRef("%s-deployer-002", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+		},
 	}, {
-		Name: "cleanup extra services, delete service failed",
+		Name: "create horizontal pod autoscaler, with custom metrics",
 		Key:  testKey,
-		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("delete", "Service"),
-		},
 		GivenObjects: []rtesting.Factory{
+			deployerValid.
+				Scale(corev1alpha1.Scale{
+					Min:                     rtesting.Int32Ptr(1),
+					Max:                     rtesting.Int32Ptr(3),
+					TargetMemoryUtilization: rtesting.Int32Ptr(70),
+					Metrics: []autoscalingv2beta2.MetricSpec{{
+						Type: autoscalingv2beta2.PodsMetricSourceType,
+						Pods: &autoscalingv2beta2.PodsMetricSource{
+							Metric: autoscalingv2beta2.MetricIdentifier{Name: "requests_per_second"},
+							Target: autoscalingv2beta2.MetricTarget{
+								Type:         autoscalingv2beta2.AverageValueMetricType,
+								AverageValue: resource.NewQuantity(100, resource.DecimalSI),
+							},
+						},
+					}},
+				}),
+			deploymentGiven.
+				Replicas(1),
+			serviceGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created HorizontalPodAutoscaler "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			factories.HorizontalPodAutoscaler().
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Namespace(testNamespace)
+					om.GenerateName("%s-deployer-", deployerMinimal.Create().GetName())
+					om.AddLabel(corev1alpha1.DeployerLabelKey, deployerMinimal.Create().GetName())
+					om.ControlledBy(deployerMinimal, scheme)
+				}).
+				ScaleTargetDeployment(deploymentGiven.Create().GetName()).
+				Replicas(1, 3).
+				AddResourceUtilizationMetric(corev1.ResourceMemory, 70).
+				AddMetric(autoscalingv2beta2.MetricSpec{
+					Type: autoscalingv2beta2.PodsMetricSourceType,
+					Pods: &autoscalingv2beta2.PodsMetricSource{
+						Metric: autoscalingv2beta2.MetricIdentifier{Name: "requests_per_second"},
+						Target: autoscalingv2beta2.MetricTarget{
+							Type:         autoscalingv2beta2.AverageValueMetricType,
+							AverageValue: resource.NewQuantity(100, resource.DecimalSI),
+						},
+					},
+				}),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
-				Image(testImage).
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
 					deployerConditionIngressReady.True(),
@@ -854,372 +1456,2071 @@ func TestDeployerReconciler(t *testing.T) {
 				).
 				StatusLatestImage(testImage).
 				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusHorizontalPodAutoscalerRef("%s-deployer-001", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
-			deploymentGiven,
-			serviceGiven.
-				NamespaceName(testNamespace, "extra-service-1"),
-			serviceGiven.
-				NamespaceName(testNamespace, "extra-service-2"),
-		},
-		ShouldErr: true,
-		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "DeleteFailed",
-				`Failed to delete Service "%s": inducing failure for delete Service`, "extra-service-1"),
-		},
-		ExpectDeletes: []rtesting.DeleteRef{
-			{Kind: "Service", Namespace: testNamespace, Name: "extra-service-1"},
 		},
 	}, {
-		Name: "create ingress",
+		Name: "update horizontal pod autoscaler, preserves autoscaled replicas",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
-			deployerMinimal.
-				Image(testImage).
-				IngressPolicy(corev1alpha1.IngressPolicyExternal),
-			deploymentGiven,
+			deployerValid.
+				Scale(corev1alpha1.Scale{
+					Min:                  rtesting.Int32Ptr(1),
+					Max:                  rtesting.Int32Ptr(10),
+					TargetCPUUtilization: rtesting.Int32Ptr(80),
+				}),
+			deploymentGiven.
+				Replicas(4),
+			hpaGiven.
+				StatusReplicas(4, 6),
 			serviceGiven,
-			testSettings,
-		},
-		ExpectTracks: []rtesting.TrackRequest{
-			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
-				`Created Ingress "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated HorizontalPodAutoscaler "%s"`, hpaGiven.Create().GetName()),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
-		ExpectCreates: []rtesting.Factory{
-			ingressCreate,
+		ExpectUpdates: []rtesting.Factory{
+			hpaGiven.
+				Replicas(1, 10).
+				StatusReplicas(4, 6),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusHorizontalPodAutoscalerRef(hpaGiven.Create().GetName()).
+				StatusReplicas(4, 6).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+		},
+	}, {
+		Name: "delete horizontal pod autoscaler, autoscaling disabled",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerValid.
+				StatusHorizontalPodAutoscalerRef(hpaGiven.Create().GetName()).
+				StatusReplicas(4, 6),
+			deploymentGiven.
+				Replicas(4),
+			hpaGiven.
+				StatusReplicas(4, 6),
+			serviceGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted HorizontalPodAutoscaler "%s"`, hpaGiven.Create().GetName()),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "autoscaling", Kind: "HorizontalPodAutoscaler", Namespace: testNamespace, Name: hpaGiven.Create().GetName()},
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+		},
+	}, {
+		Name: "create horizontal pod autoscaler, error",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("create", "HorizontalPodAutoscaler"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerValid.
+				Scale(testScale),
+			deploymentGiven.
+				Replicas(1),
+			serviceGiven,
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "CreationFailed",
+				`Failed to create HorizontalPodAutoscaler "": inducing failure for create HorizontalPodAutoscaler`),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			hpaCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.Unknown(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()),
+		},
+	}, {
+		Name: "rollout, adopt stable image",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerValid.
+				Rollout(testCanary).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusAddressURL(testAddressURL),
+			deploymentGiven,
+			serviceGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Deployment "%s-deployer-000"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Service "%s"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			stableDeploymentGiven,
+		},
+		ExpectApplies: []rtesting.Factory{
+			stableServiceCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerRollout,
+		},
+	}, {
+		Name: "rollout, replace legacy stable selector",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerRollout,
+			deploymentTemplate.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Name("%s%s", om.Create().GenerateName, "000")
+					om.Created(1)
+					om.AddLabel(corev1alpha1.RolloutTrackLabelKey, corev1alpha1.RolloutTrackStable)
+				}).
+				PodTemplateSpec(func(pts factories.PodTemplateSpec) {
+					pts.AddLabel(corev1alpha1.RolloutTrackLabelKey, corev1alpha1.RolloutTrackStable)
+				}).
+				Replicas(3),
+			stableServiceGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Deployment "%s-deployer-000"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Deployment "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "apps", Kind: "Deployment", Namespace: testNamespace, Name: fmt.Sprintf("%s-deployer-000", testName)},
+		},
+		ExpectCreates: []rtesting.Factory{
+			deploymentCreate.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddLabel(corev1alpha1.RolloutTrackLabelKey, corev1alpha1.RolloutTrackStable)
+				}).
+				PodTemplateSpec(func(pts factories.PodTemplateSpec) {
+					pts.AddLabel(corev1alpha1.RolloutTrackLabelKey, corev1alpha1.RolloutTrackStable)
+				}).
+				Replicas(3),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerRollout.
+				StatusDeploymentRef("%s-deployer-001", testName),
+		},
+	}, {
+		Name: "rollout, adopt replica sets orphaned by a replaced deployment",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerRollout,
+			stableDeploymentGiven,
+			stableServiceGiven,
+			orphanedReplicaSet,
+			orphanedReplicaSet.
+				NamespaceName(testNamespace, fmt.Sprintf("%s-deployer-candidate-000-abc", testName)).
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddLabel(corev1alpha1.RolloutTrackLabelKey, corev1alpha1.RolloutTrackCandidate)
+				}),
+			orphanedReplicaSet.
+				NamespaceName(testNamespace, fmt.Sprintf("%s-deployer-000-def", testName)).
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.ControlledBy(stableDeploymentGiven, scheme)
+				}),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated ReplicaSet "%s"`, orphanedReplicaSet.Create().GetName()),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			orphanedReplicaSet.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.ControlledBy(stableDeploymentGiven, scheme)
+				}),
+		},
+	}, {
+		Name: "rollout, adopt replica sets update error",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("update", "ReplicaSet"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerRollout,
+			stableDeploymentGiven,
+			stableServiceGiven,
+			orphanedReplicaSet,
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "UpdateFailed",
+				`Failed to update ReplicaSet "%s": inducing failure for update ReplicaSet`, orphanedReplicaSet.Create().GetName()),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			orphanedReplicaSet.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.ControlledBy(stableDeploymentGiven, scheme)
+				}),
+		},
+	}, {
+		Name: "rollout, start candidate",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerRollout.
+				Image(testNewImage),
+			stableDeploymentGiven,
+			stableServiceGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Deployment "%s-deployer-candidate-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Service "%s"`, testCandidateServiceName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			candidateDeploymentCreate,
+			candidateServiceCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerCandidate.
+				StatusCandidateDeploymentRef("%s-deployer-candidate-001", testName),
+		},
+	}, {
+		Name: "rollout, candidate not ready",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerCandidate,
+			stableDeploymentGiven,
+			stableServiceGiven,
+			candidateDeploymentGiven.
+				StatusReplicas(1, 1, 0),
+			candidateServiceGiven,
+		},
+	}, {
+		Name: "rollout, candidate not ready within progress deadline",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerCandidate.
+				StatusRolloutTransitioned(testNow),
+			stableDeploymentGiven,
+			stableServiceGiven,
+			candidateDeploymentGiven.
+				StatusReplicas(1, 1, 0),
+			candidateServiceGiven,
+		},
+		ExpectedResult: ctrl.Result{RequeueAfter: time.Until(testNow.Add(10 * time.Minute))},
+	}, {
+		Name: "rollout, candidate ready starts canary",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerCandidate,
+			stableDeploymentGiven,
+			stableServiceGiven,
+			candidateDeploymentGiven.
+				StatusReplicas(1, 1, 1),
+			candidateServiceGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerCandidate.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRolloutReady.Unknown().Info().Reason("Canary", fmt.Sprintf("Image %q is receiving 20%% of traffic at step 1 of 2", testNewImage)),
+					deployerConditionServiceReady.True(),
+				).
+				StatusRolloutStep(0, 20),
+		},
+		ExpectedResult: ctrl.Result{RequeueAfter: time.Minute},
+	}, {
+		Name: "rollout, canary paused",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerCandidate.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRolloutReady.Unknown().Info().Reason("Canary", fmt.Sprintf("Image %q is receiving 20%% of traffic at step 1 of 2", testNewImage)),
+					deployerConditionServiceReady.True(),
+				).
+				StatusRolloutStep(0, 20).
+				StatusRolloutTransitioned(testNow),
+			stableDeploymentGiven,
+			stableServiceGiven,
+			candidateDeploymentGiven.
+				StatusReplicas(1, 1, 1),
+			candidateServiceGiven,
+		},
+		ExpectedResult: ctrl.Result{RequeueAfter: time.Until(testNow.Add(time.Minute))},
+	}, {
+		Name: "rollout, canary advances after pause",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerCandidate.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRolloutReady.Unknown().Info().Reason("Canary", fmt.Sprintf("Image %q is receiving 20%% of traffic at step 1 of 2", testNewImage)),
+					deployerConditionServiceReady.True(),
+				).
+				StatusRolloutStep(0, 20).
+				StatusRolloutTransitioned(testNow.Add(-time.Hour)),
+			stableDeploymentGiven,
+			stableServiceGiven,
+			candidateDeploymentGiven.
+				StatusReplicas(1, 1, 1),
+			candidateServiceGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerCandidate.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRolloutReady.Unknown().Info().Reason("Canary", fmt.Sprintf("Image %q is receiving 50%% of traffic at step 2 of 2", testNewImage)),
+					deployerConditionServiceReady.True(),
+				).
+				StatusRolloutStep(1, 50),
+		},
+		ExpectedResult: ctrl.Result{RequeueAfter: time.Minute},
+	}, {
+		Name: "rollout, canary promotes after last step",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerCandidate.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRolloutReady.Unknown().Info().Reason("Canary", fmt.Sprintf("Image %q is receiving 50%% of traffic at step 2 of 2", testNewImage)),
+					deployerConditionServiceReady.True(),
+				).
+				StatusRolloutStep(1, 50).
+				StatusRolloutTransitioned(testNow.Add(-time.Hour)),
+			stableDeploymentGiven,
+			stableServiceGiven,
+			candidateDeploymentGiven.
+				StatusReplicas(1, 1, 1),
+			candidateServiceGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Promoted",
+				`Promoted image %q`, testNewImage),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Deployment "%s-deployer-000"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			stableDeploymentGiven.
+				HandlerContainer(func(container *corev1.Container) {
+					container.Image = testNewImage
+				}),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerCandidate.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRolloutReady.Unknown().Info().Reason("Promoting", fmt.Sprintf("Image %q is replacing the stable deployment", testNewImage)),
+					deployerConditionServiceReady.True(),
+				).
+				StatusRolloutImages(testNewImage, testNewImage).
+				StatusRolloutStep(1, 100),
+		},
+	}, {
+		Name: "rollout, promotion completes",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerCandidate.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRolloutReady.Unknown().Info().Reason("Promoting", fmt.Sprintf("Image %q is replacing the stable deployment", testNewImage)),
+					deployerConditionServiceReady.True(),
+				).
+				StatusRolloutImages(testNewImage, testNewImage).
+				StatusRolloutStep(1, 100),
+			stableDeploymentGiven.
+				HandlerContainer(func(container *corev1.Container) {
+					container.Image = testNewImage
+				}).
+				StatusReplicas(1, 1, 1),
+			stableServiceGiven,
+			candidateDeploymentGiven.
+				StatusReplicas(1, 1, 1),
+			candidateServiceGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Deployment "%s-deployer-candidate-000"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Service "%s"`, testCandidateServiceName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "apps", Kind: "Deployment", Namespace: testNamespace, Name: candidateDeploymentGiven.Create().GetName()},
+			{Group: "", Kind: "Service", Namespace: testNamespace, Name: testCandidateServiceName},
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerRollout.
+				StatusLatestImage(testNewImage).
+				StatusRolloutImages(testNewImage, ""),
+		},
+	}, {
+		Name: "rollout, rollback candidate that misses progress deadline",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerCandidate,
+			stableDeploymentGiven,
+			stableServiceGiven,
+			candidateDeploymentGiven.
+				StatusReplicas(1, 1, 0).
+				StatusConditions(
+					factories.Condition().Type("Progressing").False().Reason("ProgressDeadlineExceeded", "ReplicaSet has timed out progressing."),
+				),
+			candidateServiceGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "RolledBack",
+				`Rolled back image %q: ReplicaSet has timed out progressing.`, testNewImage),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Deployment "%s-deployer-candidate-000"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Service "%s"`, testCandidateServiceName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "apps", Kind: "Deployment", Namespace: testNamespace, Name: candidateDeploymentGiven.Create().GetName()},
+			{Group: "", Kind: "Service", Namespace: testNamespace, Name: testCandidateServiceName},
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerRollout.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRolloutReady.False().Info().Reason("RolledBack", fmt.Sprintf("Image %q was rolled back: ReplicaSet has timed out progressing.", testNewImage)),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testNewImage).
+				StatusRolloutFailedImage(testNewImage),
+		},
+	}, {
+		Name: "rollout, rollback candidate with high error rate",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerCandidate.
+				Rollout(corev1alpha1.Rollout{
+					Canary:       testCanary.Canary,
+					MaxErrorRate: rtesting.Int32Ptr(5),
+				}).
+				StatusRolloutStep(0, 20).
+				StatusRolloutTransitioned(testNow),
+			stableDeploymentGiven,
+			stableServiceGiven,
+			candidateDeploymentGiven.
+				StatusReplicas(1, 1, 1),
+			candidateServiceGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "RolledBack",
+				`Rolled back image %q: error rate 12.5%% exceeds 5%%`, testNewImage),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Deployment "%s-deployer-candidate-000"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Service "%s"`, testCandidateServiceName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "apps", Kind: "Deployment", Namespace: testNamespace, Name: candidateDeploymentGiven.Create().GetName()},
+			{Group: "", Kind: "Service", Namespace: testNamespace, Name: testCandidateServiceName},
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerRollout.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRolloutReady.False().Info().Reason("RolledBack", fmt.Sprintf("Image %q was rolled back: error rate 12.5%% exceeds 5%%", testNewImage)),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testNewImage).
+				StatusRolloutFailedImage(testNewImage),
+		},
+	}, {
+		Name: "rollout, candidate error rate checked again",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerCandidate.
+				Rollout(corev1alpha1.Rollout{
+					Canary:       testCanary.Canary,
+					MaxErrorRate: rtesting.Int32Ptr(20),
+				}).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRolloutReady.Unknown().Info().Reason("Canary", fmt.Sprintf("Image %q is receiving 20%% of traffic at step 1 of 2", testNewImage)),
+					deployerConditionServiceReady.True(),
+				).
+				StatusRolloutStep(0, 20).
+				StatusRolloutTransitioned(testNow),
+			stableDeploymentGiven,
+			stableServiceGiven,
+			candidateDeploymentGiven.
+				StatusReplicas(1, 1, 1),
+			candidateServiceGiven,
+		},
+		ExpectedResult: ctrl.Result{RequeueAfter: 30 * time.Second},
+	}, {
+		Name: "rollout, candidate error rate check failed",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerCandidate.
+				Rollout(corev1alpha1.Rollout{
+					Canary:       testCanary.Canary,
+					MaxErrorRate: rtesting.Int32Ptr(20),
+				}).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRolloutReady.Unknown().Info().Reason("Canary", fmt.Sprintf("Image %q is receiving 20%% of traffic at step 1 of 2", testNewImage)),
+					deployerConditionServiceReady.True(),
+				).
+				StatusCandidateServiceRef("%s-unmeasured", testName).
+				StatusRolloutStep(0, 20).
+				StatusRolloutTransitioned(testNow),
+			stableDeploymentGiven,
+			stableServiceGiven,
+			candidateDeploymentGiven.
+				StatusReplicas(1, 1, 1),
+			candidateServiceGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerCandidate.
+				Rollout(corev1alpha1.Rollout{
+					Canary:       testCanary.Canary,
+					MaxErrorRate: rtesting.Int32Ptr(20),
+				}).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRolloutReady.Unknown().Info().Reason("Canary", fmt.Sprintf("Image %q is receiving 20%% of traffic at step 1 of 2", testNewImage)),
+					deployerConditionServiceReady.True(),
+				).
+				StatusRolloutStep(0, 20).
+				StatusRolloutTransitioned(testNow).
+				StatusRolloutErrorRateCheckFailure(fmt.Sprintf(`Unable to check the error rate: metrics not found for service "%s-unmeasured"`, testName)),
+		},
+		ExpectedResult: ctrl.Result{RequeueAfter: 30 * time.Second},
+	}, {
+		Name: "rollout, rollback blue/green candidate with high error rate on preview requests",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerCandidate.
+				Rollout(corev1alpha1.Rollout{
+					BlueGreen:    testBlueGreen.BlueGreen,
+					MaxErrorRate: rtesting.Int32Ptr(5),
+				}),
+			stableDeploymentGiven,
+			stableServiceGiven,
+			candidateDeploymentGiven.
+				StatusReplicas(1, 1, 1),
+			candidateServiceGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "RolledBack",
+				`Rolled back image %q: error rate 12.5%% exceeds 5%%`, testNewImage),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Deployment "%s-deployer-candidate-000"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Service "%s"`, testCandidateServiceName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "apps", Kind: "Deployment", Namespace: testNamespace, Name: candidateDeploymentGiven.Create().GetName()},
+			{Group: "", Kind: "Service", Namespace: testNamespace, Name: testCandidateServiceName},
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerRollout.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRolloutReady.False().Info().Reason("RolledBack", fmt.Sprintf("Image %q was rolled back: error rate 12.5%% exceeds 5%%", testNewImage)),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testNewImage).
+				StatusRolloutFailedImage(testNewImage),
+		},
+	}, {
+		Name: "rollout, failed image is not rolled out again",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerRollout.
+				Image(testNewImage).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRolloutReady.False().Info().Reason("RolledBack", fmt.Sprintf("Image %q was rolled back: ReplicaSet has timed out progressing.", testNewImage)),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testNewImage).
+				StatusRolloutFailedImage(testNewImage),
+			stableDeploymentGiven,
+			stableServiceGiven,
+		},
+	}, {
+		Name: "rollout, blue/green candidate awaits promotion",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerCandidate.
+				Rollout(testBlueGreen).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal).
+				StatusIngressRef("%s-deployer-000", testName).
+				StatusURLs(testURL),
+			stableDeploymentGiven,
+			stableServiceGiven,
+			ingressGiven,
+			candidateDeploymentGiven.
+				StatusReplicas(1, 1, 1),
+			candidateServiceGiven,
+			testSettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Ingress "%s-deployer-candidate-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			candidateIngressCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerCandidate.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRolloutReady.Unknown().Info().Reason("AwaitingPromotion", fmt.Sprintf("Image %q is ready to be promoted", testNewImage)),
+					deployerConditionServiceReady.True(),
+				).
+				StatusIngressRef("%s-deployer-000", testName).
+				StatusURLs(testURL).
+				StatusCandidateIngressRef("%s-deployer-candidate-001", testName),
+		},
+	}, {
+		Name: "rollout, blue/green candidate promoted by annotation",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerCandidate.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation(corev1alpha1.RolloutPromoteAnnotationKey, "true")
+				}).
+				Rollout(testBlueGreen).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRolloutReady.Unknown().Info().Reason("AwaitingPromotion", fmt.Sprintf("Image %q is ready to be promoted", testNewImage)),
+					deployerConditionServiceReady.True(),
+				).
+				StatusIngressRef("%s-deployer-000", testName).
+				StatusURLs(testURL).
+				StatusCandidateIngressRef("%s-deployer-candidate-000", testName),
+			stableDeploymentGiven,
+			stableServiceGiven,
+			ingressGiven,
+			candidateDeploymentGiven.
+				StatusReplicas(1, 1, 1),
+			candidateServiceGiven,
+			candidateIngressGiven,
+			testSettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Promoted",
+				`Promoted image %q`, testNewImage),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Deployment "%s-deployer-000"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Ingress "%s-deployer-candidate-000"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			stableDeploymentGiven.
+				HandlerContainer(func(container *corev1.Container) {
+					container.Image = testNewImage
+				}),
+			candidateIngressGiven.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation("nginx.ingress.kubernetes.io/canary-weight", "100")
+				}),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerCandidate.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRolloutReady.Unknown().Info().Reason("Promoting", fmt.Sprintf("Image %q is replacing the stable deployment", testNewImage)),
+					deployerConditionServiceReady.True(),
+				).
+				StatusIngressRef("%s-deployer-000", testName).
+				StatusURLs(testURL).
+				StatusRolloutImages(testNewImage, testNewImage).
+				StatusRolloutStep(0, 100).
+				StatusCandidateIngressRef("%s-deployer-candidate-000", testName),
+		},
+	}, {
+		Name: "rollout, removed",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerValid.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRolloutReady.Unknown().Info().Reason("CandidateNotReady", fmt.Sprintf("Image %q is starting", testNewImage)),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusAddressURL(testAddressURL).
+				StatusRolloutImages(testImage, testNewImage).
+				StatusCandidateDeploymentRef("%s-deployer-candidate-000", testName).
+				StatusCandidateServiceRef(testCandidateServiceName),
+			stableDeploymentGiven,
+			stableServiceGiven,
+			candidateDeploymentGiven,
+			candidateServiceGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Deployment "%s-deployer-000"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Deployment "%s-deployer-candidate-000"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Service "%s"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Service "%s"`, testCandidateServiceName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			deploymentGiven,
+		},
+		ExpectApplies: []rtesting.Factory{
+			serviceCreate,
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "apps", Kind: "Deployment", Namespace: testNamespace, Name: candidateDeploymentGiven.Create().GetName()},
+			{Group: "", Kind: "Service", Namespace: testNamespace, Name: testCandidateServiceName},
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusAddressURL(testAddressURL),
+		},
+	}, {
+		Name: "cleanup extra deployments",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+			deploymentGiven.
+				NamespaceName(testNamespace, "extra-deployment-1"),
+			deploymentGiven.
+				NamespaceName(testNamespace, "extra-deployment-2"),
+			serviceGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Deployment "%s"`, "extra-deployment-1"),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Deployment "%s"`, "extra-deployment-2"),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Deployment "%s-deployer-001"`, testName),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "apps", Kind: "Deployment", Namespace: testNamespace, Name: "extra-deployment-1"},
+			{Group: "apps", Kind: "Deployment", Namespace: testNamespace, Name: "extra-deployment-2"},
+		},
+		ExpectCreates: []rtesting.Factory{
+			deploymentCreate,
+		},
+	}, {
+		Name: "cleanup extra deployments, delete deployment failed",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("delete", "Deployment"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+			deploymentGiven.
+				NamespaceName(testNamespace, "extra-deployment-1"),
+			deploymentGiven.
+				NamespaceName(testNamespace, "extra-deployment-2"),
+			serviceGiven,
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "DeleteFailed",
+				`Failed to delete Deployment "%s": inducing failure for delete Deployment`, "extra-deployment-1"),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "apps", Kind: "Deployment", Namespace: testNamespace, Name: "extra-deployment-1"},
+		},
+	}, {
+		Name: "cleanup extra services",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+			deploymentGiven,
+			serviceGiven.
+				NamespaceName(testNamespace, "extra-service-1"),
+			serviceGiven.
+				NamespaceName(testNamespace, "extra-service-2"),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Service "%s"`, "extra-service-1"),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Service "%s"`, "extra-service-2"),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Service "%s"`, testName),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Kind: "Service", Namespace: testNamespace, Name: "extra-service-1"},
+			{Kind: "Service", Namespace: testNamespace, Name: "extra-service-2"},
+		},
+		ExpectApplies: []rtesting.Factory{
+			serviceCreate,
+		},
+	}, {
+		Name: "cleanup extra services, delete service failed",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("delete", "Service"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+			deploymentGiven,
+			serviceGiven.
+				NamespaceName(testNamespace, "extra-service-1"),
+			serviceGiven.
+				NamespaceName(testNamespace, "extra-service-2"),
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "DeleteFailed",
+				`Failed to delete Service "%s": inducing failure for delete Service`, "extra-service-1"),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Kind: "Service", Namespace: testNamespace, Name: "extra-service-1"},
+		},
+	}, {
+		Name: "create ingress",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deploymentGiven,
+			serviceGiven,
+			testSettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Ingress "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			ingressCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusIngressRef("%s-deployer-001", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURLs(testURL),
+		},
+	}, {
+		Name: "create ingress, grpc public port",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerPorts.
+				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			portsDeploymentGiven,
+			portsServiceGiven,
+			testSettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Ingress "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			ingressCreate.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation("nginx.ingress.kubernetes.io/backend-protocol", "GRPC")
+				}),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusIngressRef("%s-deployer-001", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURLs(testURL),
+		},
+	}, {
+		Name: "create ingress, create failed",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("create", "Ingress"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deploymentGiven,
+			serviceGiven,
+			testSettings,
+		},
+		ShouldErr: true,
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "CreationFailed",
+				`Failed to create Ingress "": inducing failure for create Ingress`),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			ingressCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL(testAddressURL),
+		},
+	}, {
+		Name: "delete ingress",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyClusterLocal),
+			deploymentGiven,
+			serviceGiven,
+			ingressGiven,
+			testSettings.
+				AddData("defaultDomain", "not.example.com"),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Ingress "%s"`, ingressGiven.Create().GetName()),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "networking.k8s.io", Kind: "Ingress", Namespace: testNamespace, Name: ingressGiven.Create().GetName()},
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL(testAddressURL),
+		},
+	}, {
+		Name: "delete ingress, delete failed",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("delete", "Ingress"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyClusterLocal),
+			deploymentGiven,
+			serviceGiven,
+			ingressGiven,
+			testSettings.
+				AddData("defaultDomain", "not.example.com"),
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "DeleteFailed",
+				`Failed to delete Ingress "%s": inducing failure for delete Ingress`, ingressGiven.Create().GetName()),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "networking.k8s.io", Kind: "Ingress", Namespace: testNamespace, Name: ingressGiven.Create().GetName()},
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL(testAddressURL),
+		},
+	}, {
+		Name: "update ingress",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deploymentGiven,
+			serviceGiven,
+			ingressGiven,
+			testSettings.
+				AddData("defaultDomain", "not.example.com"),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Ingress "%s"`, ingressGiven.Create().GetName()),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			ingressGiven.
+				HostToService(fmt.Sprintf("%s.%s.%s", testName, testNamespace, "not.example.com"), serviceGiven.Create().GetName()),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusIngressRef("%s-deployer-000", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURLs(fmt.Sprintf("http://%s.%s.%s", testName, testNamespace, "not.example.com")),
+		},
+	}, {
+		Name: "update ingress, update failed",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("update", "Ingress"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deploymentGiven,
+			serviceGiven,
+			ingressGiven,
+			testSettings.
+				AddData("defaultDomain", "not.example.com"),
+		},
+		ShouldErr: true,
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "UpdateFailed",
+				`Failed to update Ingress "%s": inducing failure for update Ingress`, ingressGiven.Create().GetName()),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			ingressGiven.
+				HostToService(fmt.Sprintf("%s.%s.%s", testName, testNamespace, "not.example.com"), serviceGiven.Create().GetName()),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL(testAddressURL),
+		},
+	}, {
+		Name: "remove extra ingress",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deploymentGiven,
+			serviceGiven,
+			ingressGiven.
+				NamespaceName(testNamespace, "extra-ingress-1"),
+			ingressGiven.
+				NamespaceName(testNamespace, "extra-ingress-2"),
+			testSettings,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Ingress "%s"`, "extra-ingress-1"),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Ingress "%s"`, "extra-ingress-2"),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Ingress "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "networking.k8s.io", Kind: "Ingress", Namespace: testNamespace, Name: "extra-ingress-1"},
+			{Group: "networking.k8s.io", Kind: "Ingress", Namespace: testNamespace, Name: "extra-ingress-2"},
+		},
+		ExpectCreates: []rtesting.Factory{
+			ingressCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusIngressRef("%s-deployer-001", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURLs(testURL),
+		},
+	}, {
+		Name: "remove extra ingress, listing failed",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("list", "IngressList"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deploymentGiven,
+			serviceGiven,
+			ingressGiven.
+				NamespaceName(testNamespace, "extra-ingress-1"),
+			ingressGiven.
+				NamespaceName(testNamespace, "extra-ingress-2"),
+			testSettings,
+		},
+		ShouldErr: true,
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+		},
+	}, {
+		Name: "remove extra ingress, delete failed",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("delete", "Ingress"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deploymentGiven,
+			serviceGiven,
+			ingressGiven.
+				NamespaceName(testNamespace, "extra-ingress-1"),
+			ingressGiven.
+				NamespaceName(testNamespace, "extra-ingress-2"),
+			testSettings,
+		},
+		ShouldErr: true,
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "DeleteFailed",
+				`Failed to delete Ingress "%s": inducing failure for delete Ingress`, "extra-ingress-1"),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "networking.k8s.io", Kind: "Ingress", Namespace: testNamespace, Name: "extra-ingress-1"},
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+		},
+	}, {
+		Name: "create ingress, with routes",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerExternal.
+				Routes(
+					corev1alpha1.Route{Hosts: []string{"api.example.com"}, Path: "/orders"},
+					corev1alpha1.Route{Hosts: []string{"orders.example.com", "api.example.com"}, Path: "/"},
+				),
+			deploymentGiven,
+			serviceGiven,
+			testSettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Ingress "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			ingressRoutesCreate.
+				AddRulePath("api.example.com", "/orders", testName).
+				AddRulePath("orders.example.com", "/", testName).
+				AddRulePath("api.example.com", "/", testName),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusIngressRef("%s-deployer-001", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURLs(
+					"http://api.example.com/orders",
+					"http://api.example.com",
+					"http://orders.example.com",
+				),
+		},
+	}, {
+		Name: "create ingress, with rewrite",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerExternal.
+				Routes(
+					corev1alpha1.Route{Hosts: []string{"api.example.com"}, Path: "/orders", Rewrite: "/"},
+				),
+			deploymentGiven,
+			serviceGiven,
+			testSettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Ingress "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			ingressRoutesCreate.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation("nginx.ingress.kubernetes.io/use-regex", "true")
+					om.AddAnnotation("nginx.ingress.kubernetes.io/rewrite-target", "/$2")
+				}).
+				AddRulePath("api.example.com", "/orders(/|$)(.*)", testName),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusIngressRef("%s-deployer-001", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURLs("http://api.example.com/orders"),
+		},
+	}, {
+		Name: "update ingress, remove rewrite",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerExternal,
+			deploymentGiven,
+			serviceGiven,
+			ingressGiven.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation("nginx.ingress.kubernetes.io/use-regex", "true")
+					om.AddAnnotation("nginx.ingress.kubernetes.io/rewrite-target", "/$2")
+					om.AddAnnotation("example.com/preserved", "true")
+				}),
+			testSettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Ingress "%s-deployer-000"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			ingressGiven.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation("example.com/preserved", "true")
+				}),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusIngressRef("%s-deployer-000", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURLs(testURL),
+		},
+	}, {
+		Name: "create httproute, gateway routing backend",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerExternal,
+			deploymentGiven,
+			serviceGiven,
+			ingressGiven,
+			testGatewaySettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Ingress "%s-deployer-000"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created HTTPRoute "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "networking.k8s.io", Kind: "Ingress", Namespace: testNamespace, Name: fmt.Sprintf("%s-deployer-000", testName)},
+		},
+		ExpectCreates: []rtesting.Factory{
+			httpRouteCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown().Reason("NotAttached", "The route is not attached to a gateway."),
+					deployerConditionReady.Unknown().Reason("NotAttached", "The route is not attached to a gateway."),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusHTTPRouteRef("%s-deployer-001", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURLs(testURL),
+		},
+	}, {
+		Name: "update httproute, with rewrite",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerExternal.
+				Routes(corev1alpha1.Route{Hosts: []string{"api.example.com"}, Path: "/v1", Rewrite: "/"}),
+			deploymentGiven,
+			serviceGiven,
+			httpRouteGiven,
+			testGatewaySettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated HTTPRoute "%s-deployer-000"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			factories.GatewayHTTPRoute(httpRouteGiven.Create().(*gatewayv1beta1.HTTPRoute)).
+				Hostnames("api.example.com").
+				ClearRules().
+				AddRulePath("/v1", testName).
+				Rewrite("/"),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				Routes(corev1alpha1.Route{Hosts: []string{"api.example.com"}, Path: "/v1", Rewrite: "/"}).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown().Reason("NotAttached", "The route is not attached to a gateway."),
+					deployerConditionReady.Unknown().Reason("NotAttached", "The route is not attached to a gateway."),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusHTTPRouteRef("%s-deployer-000", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURLs("http://api.example.com/v1"),
+		},
+	}, {
+		Name: "update httproute, routes with different hosts are not supported",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerExternal.
+				Routes(
+					corev1alpha1.Route{Hosts: []string{"api.example.com"}, Path: "/"},
+					corev1alpha1.Route{Hosts: []string{"www.example.com"}, Path: "/api"},
+				),
+			deploymentGiven,
+			serviceGiven,
+			httpRouteGiven,
+			testGatewaySettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted HTTPRoute "%s-deployer-000"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "gateway.networking.k8s.io", Kind: "HTTPRoute", Namespace: testNamespace, Name: fmt.Sprintf("%s-deployer-000", testName)},
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				Routes(
+					corev1alpha1.Route{Hosts: []string{"api.example.com"}, Path: "/"},
+					corev1alpha1.Route{Hosts: []string{"www.example.com"}, Path: "/api"},
+				).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.False().Reason("RoutesNotSupported", "The gateway routing backend requires all routes to use the same hosts."),
+					deployerConditionReady.False().Reason("RoutesNotSupported", "The gateway routing backend requires all routes to use the same hosts."),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusAddressURL(testAddressURL),
+		},
+	}, {
+		Name: "ready, with httproute accepted",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerExternal.
+				StatusHTTPRouteRef("%s-deployer-000", testName),
+			deploymentGiven.
+				StatusConditions(
+					factories.Condition().Type("Available").True(),
+					factories.Condition().Type("Progressing").True(),
+				),
+			serviceGiven,
+			httpRouteGiven.
+				StatusParentConditions(
+					factories.GatewayCondition(gatewayv1beta1.RouteConditionAccepted, metav1.ConditionTrue, "Accepted", ""),
+					factories.GatewayCondition(gatewayv1beta1.RouteConditionResolvedRefs, metav1.ConditionTrue, "ResolvedRefs", ""),
+				),
+			testGatewaySettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
 				StatusConditions(
-					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionDeploymentReady.True(),
 					deployerConditionIngressReady.True(),
-					deployerConditionReady.Unknown(),
+					deployerConditionReady.True(),
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
-				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusIngressRef("%s-deployer-001", testName).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusHTTPRouteRef("%s-deployer-000", testName).
 				StatusAddressURL(testAddressURL).
-				StatusURL(testURL),
+				StatusURLs(testURL),
 		},
 	}, {
-		Name: "create ingress, create failed",
+		Name: "update httproute, not accepted by gateway",
 		Key:  testKey,
-		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("create", "Ingress"),
-		},
 		GivenObjects: []rtesting.Factory{
-			deployerMinimal.
-				Image(testImage).
-				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deployerExternal,
 			deploymentGiven,
 			serviceGiven,
-			testSettings,
+			httpRouteGiven.
+				StatusParentConditions(
+					factories.GatewayCondition(gatewayv1beta1.RouteConditionAccepted, metav1.ConditionFalse, "NotAllowedByListeners", testConditionMessage),
+					factories.GatewayCondition(gatewayv1beta1.RouteConditionResolvedRefs, metav1.ConditionTrue, "ResolvedRefs", ""),
+				),
+			testGatewaySettings,
 		},
-		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
-			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "CreationFailed",
-				`Failed to create Ingress "": inducing failure for create Ingress`),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
-		ExpectCreates: []rtesting.Factory{
-			ingressCreate,
-		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
-					deployerConditionIngressReady.Unknown(),
-					deployerConditionReady.Unknown(),
+					deployerConditionIngressReady.False().Reason("NotAllowedByListeners", testConditionMessage),
+					deployerConditionReady.False().Reason("NotAllowedByListeners", testConditionMessage),
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
-				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusAddressURL(testAddressURL),
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusHTTPRouteRef("%s-deployer-000", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURLs(testURL),
 		},
 	}, {
-		Name: "delete ingress",
+		Name: "update httproute, canary splits traffic with the candidate",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
-			deployerMinimal.
-				Image(testImage).
-				IngressPolicy(corev1alpha1.IngressPolicyClusterLocal),
-			deploymentGiven,
-			serviceGiven,
-			ingressGiven,
-			testSettings.
-				AddData("defaultDomain", "not.example.com"),
-		},
-		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
-				`Deleted Ingress "%s"`, ingressGiven.Create().GetName()),
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
-				`Updated status`),
-		},
-		ExpectDeletes: []rtesting.DeleteRef{
-			{Group: "networking.k8s.io", Kind: "Ingress", Namespace: testNamespace, Name: ingressGiven.Create().GetName()},
-		},
-		ExpectStatusUpdates: []rtesting.Factory{
-			deployerMinimal.
+			deployerCandidate.
+				IngressPolicy(corev1alpha1.IngressPolicyExternal).
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
 					deployerConditionIngressReady.True(),
 					deployerConditionReady.Unknown(),
+					deployerConditionRolloutReady.Unknown().Info().Reason("Canary", fmt.Sprintf("Image %q is receiving 20%% of traffic at step 1 of 2", testNewImage)),
 					deployerConditionServiceReady.True(),
 				).
-				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
-				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusAddressURL(testAddressURL),
-		},
-	}, {
-		Name: "delete ingress, delete failed",
-		Key:  testKey,
-		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("delete", "Ingress"),
+				StatusRolloutStep(0, 20).
+				StatusRolloutTransitioned(testNow).
+				StatusHTTPRouteRef("%s-deployer-000", testName).
+				StatusURLs(testURL),
+			stableDeploymentGiven,
+			stableServiceGiven,
+			candidateDeploymentGiven.
+				StatusReplicas(1, 1, 1),
+			candidateServiceGiven,
+			httpRouteGiven.
+				StatusParentConditions(
+					factories.GatewayCondition(gatewayv1beta1.RouteConditionAccepted, metav1.ConditionTrue, "Accepted", ""),
+					factories.GatewayCondition(gatewayv1beta1.RouteConditionResolvedRefs, metav1.ConditionTrue, "ResolvedRefs", ""),
+				),
+			testGatewaySettings,
 		},
-		GivenObjects: []rtesting.Factory{
-			deployerMinimal.
-				Image(testImage).
-				IngressPolicy(corev1alpha1.IngressPolicyClusterLocal),
-			deploymentGiven,
-			serviceGiven,
-			ingressGiven,
-			testSettings.
-				AddData("defaultDomain", "not.example.com"),
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
 		},
-		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "DeleteFailed",
-				`Failed to delete Ingress "%s": inducing failure for delete Ingress`, ingressGiven.Create().GetName()),
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
-				`Updated status`),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated HTTPRoute "%s-deployer-000"`, testName),
 		},
-		ExpectDeletes: []rtesting.DeleteRef{
-			{Group: "networking.k8s.io", Kind: "Ingress", Namespace: testNamespace, Name: ingressGiven.Create().GetName()},
+		ExpectUpdates: []rtesting.Factory{
+			httpRouteGiven.
+				ClearRules().
+				AddRuleWeighted("/", testName, 80, testCandidateServiceName, 20).
+				StatusParentConditions(
+					factories.GatewayCondition(gatewayv1beta1.RouteConditionAccepted, metav1.ConditionTrue, "Accepted", ""),
+					factories.GatewayCondition(gatewayv1beta1.RouteConditionResolvedRefs, metav1.ConditionTrue, "ResolvedRefs", ""),
+				),
 		},
-		ExpectStatusUpdates: []rtesting.Factory{
-			deployerMinimal.
+		ExpectedResult: ctrl.Result{RequeueAfter: time.Until(testNow.Add(time.Minute))},
+	}, {
+		Name: "update httproute, blue/green candidate previewed by header",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerCandidate.
+				IngressPolicy(corev1alpha1.IngressPolicyExternal).
+				Rollout(testBlueGreen).
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
-					deployerConditionIngressReady.Unknown(),
+					deployerConditionIngressReady.True(),
 					deployerConditionReady.Unknown(),
+					deployerConditionRolloutReady.Unknown().Info().Reason("AwaitingPromotion", fmt.Sprintf("Image %q is ready to be promoted", testNewImage)),
 					deployerConditionServiceReady.True(),
 				).
-				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
-				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusAddressURL(testAddressURL),
+				StatusHTTPRouteRef("%s-deployer-000", testName).
+				StatusURLs(testURL),
+			stableDeploymentGiven,
+			stableServiceGiven,
+			candidateDeploymentGiven.
+				StatusReplicas(1, 1, 1),
+			candidateServiceGiven,
+			httpRouteGiven.
+				StatusParentConditions(
+					factories.GatewayCondition(gatewayv1beta1.RouteConditionAccepted, metav1.ConditionTrue, "Accepted", ""),
+					factories.GatewayCondition(gatewayv1beta1.RouteConditionResolvedRefs, metav1.ConditionTrue, "ResolvedRefs", ""),
+				),
+			testGatewaySettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testGatewaySettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated HTTPRoute "%s-deployer-000"`, testName),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			httpRouteGiven.
+				ClearRules().
+				AddRulePreview("/", "X-Riff-Candidate", testCandidateServiceName).
+				AddRuleWeighted("/", testName, 100, testCandidateServiceName, 0).
+				StatusParentConditions(
+					factories.GatewayCondition(gatewayv1beta1.RouteConditionAccepted, metav1.ConditionTrue, "Accepted", ""),
+					factories.GatewayCondition(gatewayv1beta1.RouteConditionResolvedRefs, metav1.ConditionTrue, "ResolvedRefs", ""),
+				),
 		},
 	}, {
-		Name: "update ingress",
+		Name: "create ingress, with tls secret",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
-			deployerMinimal.
-				Image(testImage).
-				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deployerExternal.
+				TLS(corev1alpha1.DeployerTLS{SecretRef: testTLSSecretName}),
 			deploymentGiven,
 			serviceGiven,
-			ingressGiven,
-			testSettings.
-				AddData("defaultDomain", "not.example.com"),
+			testSettings,
 		},
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
-				`Updated Ingress "%s"`, ingressGiven.Create().GetName()),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Ingress "%s-deployer-001"`, testName),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
-		ExpectUpdates: []rtesting.Factory{
-			ingressGiven.
-				HostToService(fmt.Sprintf("%s.%s.%s", testName, testNamespace, "not.example.com"), serviceGiven.Create().GetName()),
+		ExpectCreates: []rtesting.Factory{
+			ingressCreate.
+				TLS(testHost, testTLSSecretName),
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
 				StatusConditions(
+					deployerConditionCertificateReady.True().Info(),
 					deployerConditionDeploymentReady.Unknown(),
 					deployerConditionIngressReady.True(),
 					deployerConditionReady.Unknown(),
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
-				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusIngressRef("%s-deployer-000", testName).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusIngressRef("%s-deployer-001", testName).
 				StatusAddressURL(testAddressURL).
-				StatusURL("http://%s.%s.%s", testName, testNamespace, "not.example.com"),
+				StatusURLs(testHTTPSURL),
 		},
 	}, {
-		Name: "update ingress, update failed",
+		Name: "create certificate",
 		Key:  testKey,
-		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("update", "Ingress"),
-		},
 		GivenObjects: []rtesting.Factory{
-			deployerMinimal.
-				Image(testImage).
-				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deployerExternal.
+				TLS(corev1alpha1.DeployerTLS{
+					IssuerRef: &corev1alpha1.CertificateIssuerRef{Name: testIssuer, Kind: "ClusterIssuer"},
+				}),
 			deploymentGiven,
 			serviceGiven,
-			ingressGiven,
-			testSettings.
-				AddData("defaultDomain", "not.example.com"),
+			testSettings,
 		},
-		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "UpdateFailed",
-				`Failed to update Ingress "%s": inducing failure for update Ingress`, ingressGiven.Create().GetName()),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Certificate "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Ingress "%s-deployer-002"`, testName),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
-		ExpectUpdates: []rtesting.Factory{
-			ingressGiven.
-				HostToService(fmt.Sprintf("%s.%s.%s", testName, testNamespace, "not.example.com"), serviceGiven.Create().GetName()),
+		ExpectCreates: []rtesting.Factory{
+			certificateCreate,
+			ingressCreate,
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
 				StatusConditions(
+					deployerConditionCertificateReady.Unknown().Info().Reason("Issuing", "The certificate has not been issued."),
 					deployerConditionDeploymentReady.Unknown(),
-					deployerConditionIngressReady.Unknown(),
+					deployerConditionIngressReady.True(),
 					deployerConditionReady.Unknown(),
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
-				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusAddressURL(testAddressURL),
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusCertificateRef("%s-deployer-001", testName).
+				StatusIngressRef("%s-deployer-002", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURLs(testURL),
 		},
 	}, {
-		Name: "remove extra ingress",
+		Name: "update ingress, certificate issued",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
-			deployerMinimal.
-				Image(testImage).
-				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deployerExternal.
+				TLS(corev1alpha1.DeployerTLS{
+					IssuerRef: &corev1alpha1.CertificateIssuerRef{Name: testIssuer, Kind: "ClusterIssuer"},
+				}),
 			deploymentGiven,
 			serviceGiven,
-			ingressGiven.
-				NamespaceName(testNamespace, "extra-ingress-1"),
-			ingressGiven.
-				NamespaceName(testNamespace, "extra-ingress-2"),
+			certificateGiven.
+				StatusReady(certmanagerv1alpha2.ConditionTrue, "Ready", "Certificate is up to date and has not expired"),
+			ingressGiven,
 			testSettings,
 		},
-		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
-				`Deleted Ingress "%s"`, "extra-ingress-1"),
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
-				`Deleted Ingress "%s"`, "extra-ingress-2"),
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
-				`Created Ingress "%s-deployer-001"`, testName),
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
-				`Updated status`),
-		},
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
 		},
-		ExpectDeletes: []rtesting.DeleteRef{
-			{Group: "networking.k8s.io", Kind: "Ingress", Namespace: testNamespace, Name: "extra-ingress-1"},
-			{Group: "networking.k8s.io", Kind: "Ingress", Namespace: testNamespace, Name: "extra-ingress-2"},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Ingress "%s-deployer-000"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
 		},
-		ExpectCreates: []rtesting.Factory{
-			ingressCreate,
+		ExpectUpdates: []rtesting.Factory{
+			ingressGiven.
+				TLS(testHost, fmt.Sprintf("%s-deployer-tls", testName)),
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
 				StatusConditions(
+					deployerConditionCertificateReady.True().Info().Reason("Ready", "Certificate is up to date and has not expired"),
 					deployerConditionDeploymentReady.Unknown(),
 					deployerConditionIngressReady.True(),
 					deployerConditionReady.Unknown(),
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
-				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusIngressRef("%s-deployer-001", testName).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusCertificateRef("%s-deployer-000", testName).
+				StatusIngressRef("%s-deployer-000", testName).
 				StatusAddressURL(testAddressURL).
-				StatusURL(testURL),
+				StatusURLs(testHTTPSURL),
 		},
 	}, {
-		Name: "remove extra ingress, listing failed",
+		Name: "create tls secret, from cluster default",
 		Key:  testKey,
-		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("list", "IngressList"),
-		},
 		GivenObjects: []rtesting.Factory{
-			deployerMinimal.
-				Image(testImage).
-				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deployerExternal,
 			deploymentGiven,
 			serviceGiven,
-			ingressGiven.
-				NamespaceName(testNamespace, "extra-ingress-1"),
-			ingressGiven.
-				NamespaceName(testNamespace, "extra-ingress-2"),
-			testSettings,
+			testSettings.
+				AddData("defaultTLSSecret", "wildcard-tls"),
+			defaultTLSSecret,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(defaultTLSSecret, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
 		},
-		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Secret "%s-deployer-tls-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Ingress "%s-deployer-002"`, testName),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
+		ExpectCreates: []rtesting.Factory{
+			tlsSecretCreate,
+			ingressCreate.
+				TLS(testHost, fmt.Sprintf("%s-deployer-tls-001", testName)),
+		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
 				StatusConditions(
+					deployerConditionCertificateReady.True().Info(),
 					deployerConditionDeploymentReady.Unknown(),
-					deployerConditionIngressReady.Unknown(),
+					deployerConditionIngressReady.True(),
 					deployerConditionReady.Unknown(),
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
-				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusTLSSecretRef("%s-deployer-tls-001", testName).
+				StatusIngressRef("%s-deployer-002", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURLs(testHTTPSURL),
 		},
 	}, {
-		Name: "remove extra ingress, delete failed",
+		Name: "create tls secret, cluster default not found",
 		Key:  testKey,
-		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("delete", "Ingress"),
-		},
 		GivenObjects: []rtesting.Factory{
-			deployerMinimal.
-				Image(testImage).
-				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deployerExternal,
 			deploymentGiven,
 			serviceGiven,
-			ingressGiven.
-				NamespaceName(testNamespace, "extra-ingress-1"),
-			ingressGiven.
-				NamespaceName(testNamespace, "extra-ingress-2"),
-			testSettings,
+			testSettings.
+				AddData("defaultTLSSecret", "wildcard-tls"),
 		},
 		ShouldErr: true,
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(defaultTLSSecret, deployerMinimal, scheme),
+		},
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "DeleteFailed",
-				`Failed to delete Ingress "%s": inducing failure for delete Ingress`, "extra-ingress-1"),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
-		ExpectDeletes: []rtesting.DeleteRef{
-			{Group: "networking.k8s.io", Kind: "Ingress", Namespace: testNamespace, Name: "extra-ingress-1"},
-		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
 				StatusConditions(
@@ -1229,9 +3530,9 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
-				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusAddressURL(testAddressURL),
 		},
 	}, {
 		Name: "propagate labels",
@@ -1254,7 +3555,7 @@ func TestDeployerReconciler(t *testing.T) {
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusIngressRef(ingressGiven.Create().GetName()).
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()).
-				StatusURL(testURL),
+				StatusURLs(testURL),
 			deploymentGiven,
 			serviceGiven,
 			ingressGiven,
@@ -1262,6 +3563,8 @@ func TestDeployerReconciler(t *testing.T) {
 		},
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
@@ -1279,11 +3582,13 @@ func TestDeployerReconciler(t *testing.T) {
 				PodTemplateSpec(func(pts factories.PodTemplateSpec) {
 					pts.AddLabel(testLabelKey, testLabelValue)
 				}),
-			serviceGiven.
+			ingressGiven.
 				ObjectMeta(func(om factories.ObjectMeta) {
 					om.AddLabel(testLabelKey, testLabelValue)
 				}),
-			ingressGiven.
+		},
+		ExpectApplies: []rtesting.Factory{
+			serviceCreate.
 				ObjectMeta(func(om factories.ObjectMeta) {
 					om.AddLabel(testLabelKey, testLabelValue)
 				}),
@@ -1339,6 +3644,8 @@ func TestDeployerReconciler(t *testing.T) {
 		},
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
@@ -1357,7 +3664,7 @@ func TestDeployerReconciler(t *testing.T) {
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusIngressRef("%s-deployer-000", deployerMinimal.Create().GetName()).
 				StatusAddressURL(testAddressURL).
-				StatusURL(testURL),
+				StatusURLs(testURL),
 		},
 	}, {
 		Name: "not ready",
@@ -1459,6 +3766,20 @@ func TestDeployerReconciler(t *testing.T) {
 				Log:      log,
 				Tracker:  tracker,
 			},
+			rolloutMetrics,
+			corecontrollers.OptionalAPIs{CertManager: true, GatewayAPI: true},
+			controllers.NetworkPolicyConfig{},
 		)
 	})
 }
+
+// testRolloutMetrics serves error rates keyed by namespace/service
+type testRolloutMetrics map[string]float64
+
+func (m testRolloutMetrics) ErrorRate(ctx context.Context, namespace, service string) (float64, error) {
+	rate, ok := m[fmt.Sprintf("%s/%s", namespace, service)]
+	if !ok {
+		return 0, fmt.Errorf("metrics not found for service %q", service)
+	}
+	return rate, nil
+}
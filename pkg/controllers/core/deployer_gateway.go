@@ -0,0 +1,261 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	gatewayv1beta1 "github.com/projectriff/system/pkg/apis/thirdparty/gateway/v1beta1"
+	"github.com/projectriff/system/pkg/controllers"
+	"github.com/projectriff/system/pkg/refs"
+)
+
+// DeployerRoutingReconciler routes requests to deployers with a Gateway API
+// HTTPRoute when the gateway routing backend is configured. Without the
+// Gateway API installed, only the ingress routing backend is available.
+func DeployerRoutingReconciler(c controllers.Config, gatewayAPI bool) controllers.SubReconciler {
+	if gatewayAPI {
+		return DeployerChildHTTPRouteReconciler(c)
+	}
+
+	c.Log = c.Log.WithName("Routing")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *corev1alpha1.Deployer) error {
+			parent.Status.HTTPRouteRef = nil
+			if !deployerIngressRequired(parent) {
+				return nil
+			}
+			coreSettings, err := deployerSettings(c, parent)
+			if err != nil {
+				return err
+			}
+			if deployerRoutingBackend(coreSettings) == routingBackendGateway {
+				parent.Status.MarkRoutesNotSupported("The gateway routing backend requires the Gateway API to be installed.")
+			}
+			return nil
+		},
+
+		Config: c,
+	}
+}
+
+func DeployerChildHTTPRouteReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ChildHTTPRoute")
+
+	return &controllers.ChildReconciler{
+		ParentType:    &corev1alpha1.Deployer{},
+		ChildType:     &gatewayv1beta1.HTTPRoute{},
+		ChildListType: &gatewayv1beta1.HTTPRouteList{},
+
+		DesiredChild: func(parent *corev1alpha1.Deployer) (*gatewayv1beta1.HTTPRoute, error) {
+			if !deployerIngressRequired(parent) {
+				return nil, nil
+			}
+
+			coreSettings, err := deployerSettings(c, parent)
+			if err != nil {
+				return nil, err
+			}
+			if deployerRoutingBackend(coreSettings) != routingBackendGateway {
+				// routed by another backend
+				return nil, nil
+			}
+			gateway, err := deployerGatewayRef(coreSettings)
+			if err != nil {
+				return nil, err
+			}
+			routes := deployerRoutes(coreSettings, parent)
+			hosts := routes[0].Hosts
+			for _, route := range routes {
+				if !equality.Semantic.DeepEqual(route.Hosts, hosts) {
+					// a route's hostnames apply to all of its rules
+					parent.Status.MarkRoutesNotSupported("The gateway routing backend requires all routes to use the same hosts.")
+					return nil, nil
+				}
+			}
+
+			child := &gatewayv1beta1.HTTPRoute{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: controllers.MergeMaps(parent.Labels, map[string]string{
+						corev1alpha1.DeployerLabelKey: parent.Name,
+					}),
+					GenerateName: fmt.Sprintf("%s-deployer-", parent.Name),
+					Namespace:    parent.Namespace,
+				},
+				Spec: gatewayv1beta1.HTTPRouteSpec{
+					ParentRefs: []gatewayv1beta1.ParentReference{gateway},
+					Hostnames:  hosts,
+					Rules:      deployerHTTPRouteRules(parent, routes),
+				},
+			}
+
+			return child, nil
+		},
+		ReflectChildStatusOnParent: func(parent *corev1alpha1.Deployer, child *gatewayv1beta1.HTTPRoute, err error) {
+			if err != nil {
+				return
+			}
+			if child == nil {
+				// the ingress reflects the url when routed by ingress
+				parent.Status.HTTPRouteRef = nil
+			} else {
+				parent.Status.HTTPRouteRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
+				parent.Status.URLs = deployerHTTPRouteURLs(child)
+				parent.Status.URL = parent.Status.URLs[0]
+				parent.Status.PropagateHTTPRouteStatus(&child.Status)
+			}
+		},
+		MergeBeforeUpdate: func(current, desired *gatewayv1beta1.HTTPRoute) {
+			current.Labels = desired.Labels
+			current.Spec = desired.Spec
+		},
+		SemanticEquals: func(a1, a2 *gatewayv1beta1.HTTPRoute) bool {
+			return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+		},
+
+		Config:     c,
+		IndexField: ".metadata.httpRouteController",
+		Sanitize: func(child *gatewayv1beta1.HTTPRoute) interface{} {
+			return child.Spec
+		},
+	}
+}
+
+// deployerGatewayRef resolves the parent Gateway for routes from the core
+// settings.
+func deployerGatewayRef(coreSettings *corev1.ConfigMap) (gatewayv1beta1.ParentReference, error) {
+	gateway := coreSettings.Data[gatewayKey]
+	if gateway == "" {
+		return gatewayv1beta1.ParentReference{}, fmt.Errorf("the gateway routing backend requires the %q setting", gatewayKey)
+	}
+	namespace, name := systemNamespace, gateway
+	if i := strings.Index(gateway, "/"); i != -1 {
+		namespace, name = gateway[:i], gateway[i+1:]
+	}
+	group, kind := gatewayv1beta1.GroupVersion.Group, "Gateway"
+	return gatewayv1beta1.ParentReference{
+		Group:     &group,
+		Kind:      &kind,
+		Namespace: &namespace,
+		Name:      name,
+	}, nil
+}
+
+// deployerHTTPRouteRules routes each path to the deployer's service. During a
+// rollout, traffic is split by weight with the candidate and blue/green
+// candidates are also reached with the preview header.
+func deployerHTTPRouteRules(parent *corev1alpha1.Deployer, routes []corev1alpha1.Route) []gatewayv1beta1.HTTPRouteRule {
+	backendRefs := []gatewayv1beta1.HTTPBackendRef{deployerHTTPBackendRef(parent.Status.ServiceRef.Name, nil)}
+	var previewBackendRefs []gatewayv1beta1.HTTPBackendRef
+	var previewHeader string
+	if rollout := parent.Status.Rollout; rollout != nil && rollout.CandidateServiceRef != nil {
+		stableWeight, candidateWeight := 100-rollout.Weight, rollout.Weight
+		backendRefs = []gatewayv1beta1.HTTPBackendRef{
+			deployerHTTPBackendRef(parent.Status.ServiceRef.Name, &stableWeight),
+			deployerHTTPBackendRef(rollout.CandidateServiceRef.Name, &candidateWeight),
+		}
+		if parent.Spec.Rollout != nil && parent.Spec.Rollout.BlueGreen != nil {
+			previewHeader = parent.Spec.Rollout.BlueGreen.PreviewHeader
+			previewBackendRefs = []gatewayv1beta1.HTTPBackendRef{deployerHTTPBackendRef(rollout.CandidateServiceRef.Name, nil)}
+		}
+	}
+
+	rules := []gatewayv1beta1.HTTPRouteRule{}
+	for _, route := range routes {
+		pathType, path := gatewayv1beta1.PathMatchPathPrefix, route.Path
+		var filters []gatewayv1beta1.HTTPRouteFilter
+		if route.Rewrite != "" {
+			modifierType, rewrite := gatewayv1beta1.PrefixMatchHTTPPathModifier, route.Rewrite
+			filters = []gatewayv1beta1.HTTPRouteFilter{{
+				Type: gatewayv1beta1.HTTPRouteFilterURLRewrite,
+				URLRewrite: &gatewayv1beta1.HTTPURLRewriteFilter{
+					Path: &gatewayv1beta1.HTTPPathModifier{
+						Type:               modifierType,
+						ReplacePrefixMatch: &rewrite,
+					},
+				},
+			}}
+		}
+		if previewHeader != "" {
+			headerType := gatewayv1beta1.HeaderMatchExact
+			rules = append(rules, gatewayv1beta1.HTTPRouteRule{
+				Matches: []gatewayv1beta1.HTTPRouteMatch{{
+					Path: &gatewayv1beta1.HTTPPathMatch{Type: &pathType, Value: &path},
+					Headers: []gatewayv1beta1.HTTPHeaderMatch{{
+						Type:  &headerType,
+						Name:  previewHeader,
+						Value: "always",
+					}},
+				}},
+				Filters:     filters,
+				BackendRefs: previewBackendRefs,
+			})
+		}
+		rules = append(rules, gatewayv1beta1.HTTPRouteRule{
+			Matches: []gatewayv1beta1.HTTPRouteMatch{{
+				Path: &gatewayv1beta1.HTTPPathMatch{Type: &pathType, Value: &path},
+			}},
+			Filters:     filters,
+			BackendRefs: backendRefs,
+		})
+	}
+	return rules
+}
+
+func deployerHTTPBackendRef(serviceName string, weight *int32) gatewayv1beta1.HTTPBackendRef {
+	port := int32(80)
+	return gatewayv1beta1.HTTPBackendRef{
+		BackendRef: gatewayv1beta1.BackendRef{
+			BackendObjectReference: gatewayv1beta1.BackendObjectReference{
+				Name: serviceName,
+				Port: &port,
+			},
+			Weight: weight,
+		},
+	}
+}
+
+// deployerHTTPRouteURLs returns the urls served by the route, one for each
+// host and path.
+func deployerHTTPRouteURLs(route *gatewayv1beta1.HTTPRoute) []string {
+	urls := []string{}
+	seen := map[string]bool{}
+	for _, host := range route.Spec.Hostnames {
+		for _, rule := range route.Spec.Rules {
+			for _, match := range rule.Matches {
+				if match.Path == nil || match.Path.Value == nil {
+					continue
+				}
+				url := fmt.Sprintf("http://%s%s", host, strings.TrimSuffix(*match.Path.Value, "/"))
+				if !seen[url] {
+					seen[url] = true
+					urls = append(urls, url)
+				}
+			}
+		}
+	}
+	return urls
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core_test
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	"github.com/projectriff/system/pkg/controllers"
+	corecontrollers "github.com/projectriff/system/pkg/controllers/core"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+	"github.com/projectriff/system/pkg/controllers/testing/factories"
+	"github.com/projectriff/system/pkg/tracker"
+	"github.com/projectriff/system/pkg/validation"
+)
+
+func TestDeployerImagePolicy(t *testing.T) {
+	testNamespace := "test-namespace"
+	testName := "test-deployer"
+	testKey := types.NamespacedName{Namespace: testNamespace, Name: testName}
+	testAllowedImage := "registry.example.com/repo@sha256:cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e"
+	testDisallowedImage := "example.com/repo@sha256:cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e"
+
+	policy, err := validation.NewImagePolicyFromData(map[string]string{
+		"allowedImages": "registry.example.com/**",
+	})
+	if err != nil {
+		t.Fatalf("NewImagePolicyFromData() unexpected error: %v", err)
+	}
+	validation.SetImagePolicy(policy)
+	defer validation.SetImagePolicy(nil)
+
+	deployerConditionDeploymentReady := factories.Condition().Type(corev1alpha1.DeployerConditionDeploymentReady)
+	deployerConditionImageAllowed := factories.Condition().Type(corev1alpha1.DeployerConditionImageAllowed)
+	deployerConditionIngressReady := factories.Condition().Type(corev1alpha1.DeployerConditionIngressReady)
+	deployerConditionReady := factories.Condition().Type(corev1alpha1.DeployerConditionReady)
+	deployerConditionServiceReady := factories.Condition().Type(corev1alpha1.DeployerConditionServiceReady)
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = buildv1alpha1.AddToScheme(scheme)
+	_ = corev1alpha1.AddToScheme(scheme)
+
+	deployerMinimal := factories.DeployerCore().
+		NamespaceName(testNamespace, testName)
+	deployerContainer := deployerMinimal.
+		ContainerRef("my-container")
+
+	testContainer := factories.Container().
+		NamespaceName(testNamespace, "my-container")
+
+	table := rtesting.Table{{
+		Name: "allowed image",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerContainer,
+			testContainer.
+				StatusLatestImage(testAllowedImage),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerContainer.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.Unknown(),
+				).
+				StatusLatestImage(testAllowedImage),
+		},
+	}, {
+		Name: "disallowed image keeps the previous image",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerContainer.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.Unknown(),
+				).
+				StatusLatestImage(testAllowedImage),
+			testContainer.
+				StatusLatestImage(testDisallowedImage),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerContainer.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionImageAllowed.False().Info().Reason("ImageNotAllowed", `image "example.com/repo@sha256:cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e" is not from an allowed repository`),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.Unknown(),
+				).
+				StatusLatestImage(testAllowedImage),
+		},
+	}, {
+		Name: "allowed image clears the condition",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerContainer.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionImageAllowed.False().Info().Reason("ImageNotAllowed", `image "example.com/repo@sha256:cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e" is not from an allowed repository`),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.Unknown(),
+				),
+			testContainer.
+				StatusLatestImage(testAllowedImage),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerContainer.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.Unknown(),
+				).
+				StatusLatestImage(testAllowedImage),
+		},
+	}}
+
+	table.Test(t, scheme, func(t *testing.T, row *rtesting.Testcase, client client.Client, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) reconcile.Reconciler {
+		c := controllers.Config{
+			Client:   client,
+			Recorder: recorder,
+			Scheme:   scheme,
+			Log:      log,
+			Tracker:  tracker,
+		}
+		return &controllers.ParentReconciler{
+			Type: &corev1alpha1.Deployer{},
+			SubReconcilers: []controllers.SubReconciler{
+				corecontrollers.DeployerBuildRefReconciler(c),
+			},
+
+			Config: c,
+		}
+	})
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"fmt"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	"github.com/projectriff/system/pkg/controllers"
+	"github.com/projectriff/system/pkg/refs"
+)
+
+// DeployerChildNetworkPolicyReconciler restricts network traffic to the
+// deployer's pods, stable and candidate, according to the ingress policy.
+func DeployerChildNetworkPolicyReconciler(c controllers.Config, networkPolicy controllers.NetworkPolicyConfig) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ChildNetworkPolicy")
+
+	return &controllers.ChildReconciler{
+		ParentType:    &corev1alpha1.Deployer{},
+		ChildType:     &networkingv1.NetworkPolicy{},
+		ChildListType: &networkingv1.NetworkPolicyList{},
+
+		DesiredChild: func(parent *corev1alpha1.Deployer) (*networkingv1.NetworkPolicy, error) {
+			if !networkPolicy.Enabled {
+				return nil, nil
+			}
+
+			child := &networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: controllers.MergeMaps(parent.Labels, map[string]string{
+						corev1alpha1.DeployerLabelKey: parent.Name,
+					}),
+					GenerateName: fmt.Sprintf("%s-deployer-", parent.Name),
+					Namespace:    parent.Namespace,
+				},
+				Spec: networkingv1.NetworkPolicySpec{
+					PodSelector: metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							corev1alpha1.DeployerLabelKey: parent.Name,
+						},
+					},
+					Ingress:     networkPolicy.IngressRules(parent.Spec.IngressPolicy == corev1alpha1.IngressPolicyExternal),
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+				},
+			}
+
+			return child, nil
+		},
+		ReflectChildStatusOnParent: func(parent *corev1alpha1.Deployer, child *networkingv1.NetworkPolicy, err error) {
+			if err != nil {
+				return
+			}
+			if child == nil {
+				parent.Status.NetworkPolicyRef = nil
+				parent.Status.MarkNetworkPolicyNotUsed()
+			} else {
+				parent.Status.NetworkPolicyRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
+				parent.Status.MarkNetworkPolicyReady()
+			}
+		},
+		MergeBeforeUpdate: func(current, desired *networkingv1.NetworkPolicy) {
+			current.Labels = desired.Labels
+			current.Spec = desired.Spec
+		},
+		SemanticEquals: func(a1, a2 *networkingv1.NetworkPolicy) bool {
+			return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+		},
+
+		Config:     c,
+		IndexField: ".metadata.networkPolicyController",
+		Sanitize: func(child *networkingv1.NetworkPolicy) interface{} {
+			return child.Spec
+		},
+	}
+}
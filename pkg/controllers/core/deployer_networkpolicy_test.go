@@ -0,0 +1,211 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core_test
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	"github.com/projectriff/system/pkg/controllers"
+	corecontrollers "github.com/projectriff/system/pkg/controllers/core"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+	"github.com/projectriff/system/pkg/controllers/testing/factories"
+	"github.com/projectriff/system/pkg/tracker"
+)
+
+func TestDeployerChildNetworkPolicyReconciler(t *testing.T) {
+	testNamespace := "test-namespace"
+	testName := "test-deployer"
+	testKey := types.NamespacedName{Namespace: testNamespace, Name: testName}
+	testImage := "example.com/repo@sha256:cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e"
+
+	monitoringSelector := metav1.LabelSelector{MatchLabels: map[string]string{"team": "monitoring"}}
+	ingressSelector := metav1.LabelSelector{MatchLabels: map[string]string{"app.kubernetes.io/name": "ingress-nginx"}}
+	networkPolicyConfig := controllers.NetworkPolicyConfig{
+		Enabled:                   true,
+		NamespaceSelectors:        []metav1.LabelSelector{monitoringSelector},
+		IngressNamespaceSelectors: []metav1.LabelSelector{ingressSelector},
+	}
+
+	deployerConditionDeploymentReady := factories.Condition().Type(corev1alpha1.DeployerConditionDeploymentReady)
+	deployerConditionIngressReady := factories.Condition().Type(corev1alpha1.DeployerConditionIngressReady)
+	deployerConditionNetworkPolicyReady := factories.Condition().Type(corev1alpha1.DeployerConditionNetworkPolicyReady)
+	deployerConditionReady := factories.Condition().Type(corev1alpha1.DeployerConditionReady)
+	deployerConditionServiceReady := factories.Condition().Type(corev1alpha1.DeployerConditionServiceReady)
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = corev1alpha1.AddToScheme(scheme)
+
+	deployerMinimal := factories.DeployerCore().
+		NamespaceName(testNamespace, testName)
+	deployerClusterLocal := deployerMinimal.
+		Image(testImage).
+		IngressPolicy(corev1alpha1.IngressPolicyClusterLocal)
+	deployerExternal := deployerMinimal.
+		Image(testImage).
+		IngressPolicy(corev1alpha1.IngressPolicyExternal)
+
+	networkPolicyCreate := factories.NetworkPolicy().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.GenerateName("%s-deployer-", testName)
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.ControlledBy(deployerMinimal, scheme)
+		}).
+		AddPodSelectorLabel(corev1alpha1.DeployerLabelKey, testName).
+		IngressFrom(monitoringSelector)
+	networkPolicyGiven := networkPolicyCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Name("%s%s", om.Create().GenerateName, "000")
+			om.Created(1)
+		})
+
+	table := rtesting.Table{{
+		Name: "create network policy, cluster local",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerClusterLocal,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created NetworkPolicy "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			networkPolicyCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionNetworkPolicyReady.True().Info(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.Unknown(),
+				).
+				StatusNetworkPolicyRef("%s-deployer-001", testName),
+		},
+	}, {
+		Name: "create network policy, external admits ingress controllers",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerExternal,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created NetworkPolicy "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			networkPolicyCreate.
+				IngressFrom(monitoringSelector, ingressSelector),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionNetworkPolicyReady.True().Info(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.Unknown(),
+				).
+				StatusNetworkPolicyRef("%s-deployer-001", testName),
+		},
+	}, {
+		Name: "update network policy, ingress policy changed",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerClusterLocal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionNetworkPolicyReady.True().Info(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.Unknown(),
+				).
+				StatusNetworkPolicyRef("%s-deployer-000", testName),
+			networkPolicyGiven.
+				IngressFrom(monitoringSelector, ingressSelector),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated NetworkPolicy "%s-deployer-000"`, testName),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			networkPolicyGiven,
+		},
+	}, {
+		Name: "create network policy, create failed",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("create", "NetworkPolicy"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerClusterLocal,
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "CreationFailed",
+				`Failed to create NetworkPolicy "": inducing failure for create NetworkPolicy`),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			networkPolicyCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.Unknown(),
+				),
+		},
+	}}
+
+	table.Test(t, scheme, func(t *testing.T, row *rtesting.Testcase, client client.Client, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) reconcile.Reconciler {
+		c := controllers.Config{
+			Client:   client,
+			Recorder: recorder,
+			Scheme:   scheme,
+			Log:      log,
+			Tracker:  tracker,
+		}
+		return &controllers.ParentReconciler{
+			Type: &corev1alpha1.Deployer{},
+			SubReconcilers: []controllers.SubReconciler{
+				corecontrollers.DeployerChildNetworkPolicyReconciler(c, networkPolicyConfig),
+			},
+
+			Config: c,
+		}
+	})
+}
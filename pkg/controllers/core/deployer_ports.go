@@ -0,0 +1,158 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+)
+
+const (
+	backendProtocolAnnotationKey = "nginx.ingress.kubernetes.io/backend-protocol"
+)
+
+// deployerPublicPort returns the handler container port exposed on port 80 of
+// the service and routed by ingress.
+func deployerPublicPort(parent *corev1alpha1.Deployer) corev1.ContainerPort {
+	ports := parent.Spec.Template.Spec.Containers[0].Ports
+	for _, port := range ports {
+		if port.Name == parent.Spec.PublicPort {
+			return port
+		}
+	}
+	return ports[0]
+}
+
+// deployerPortProtocol returns the application protocol of the named handler
+// container port, defaults to http1.
+func deployerPortProtocol(parent *corev1alpha1.Deployer, name string) corev1alpha1.PortProtocol {
+	for _, port := range parent.Spec.Ports {
+		if port.Name == name && port.Protocol != "" {
+			return port.Protocol
+		}
+	}
+	return corev1alpha1.PortProtocolHTTP1
+}
+
+// deployerServicePorts exposes each handler container port. The public port
+// is exposed on port 80, other ports on their container port number. Port
+// names are prefixed with the application protocol so that service meshes
+// and gateways select the protocol.
+func deployerServicePorts(parent *corev1alpha1.Deployer) []corev1.ServicePort {
+	public := deployerPublicPort(parent)
+	ports := []corev1.ServicePort{}
+	for _, port := range parent.Spec.Template.Spec.Containers[0].Ports {
+		servicePort := corev1.ServicePort{
+			Name:       servicePortName(port.Name, deployerPortProtocol(parent, port.Name)),
+			Port:       port.ContainerPort,
+			TargetPort: intstr.FromInt(int(port.ContainerPort)),
+		}
+		if port.Name == public.Name {
+			servicePort.Port = 80
+		}
+		ports = append(ports, servicePort)
+	}
+	return ports
+}
+
+// servicePortsEqual compares the ports of two services, the fields defaulted
+// by the API server are equal to their default values.
+func servicePortsEqual(ports1, ports2 []corev1.ServicePort) bool {
+	if len(ports1) != len(ports2) {
+		return false
+	}
+	for i := range ports1 {
+		p1, p2 := defaultServicePort(ports1[i]), defaultServicePort(ports2[i])
+		// node ports are allocated by the API server
+		p1.NodePort, p2.NodePort = 0, 0
+		if !equality.Semantic.DeepEqual(p1, p2) {
+			return false
+		}
+	}
+	return true
+}
+
+func defaultServicePort(port corev1.ServicePort) corev1.ServicePort {
+	if port.Protocol == "" {
+		port.Protocol = corev1.ProtocolTCP
+	}
+	if port.TargetPort == (intstr.IntOrString{}) {
+		port.TargetPort = intstr.FromInt(int(port.Port))
+	}
+	return port
+}
+
+func servicePortName(name string, protocol corev1alpha1.PortProtocol) string {
+	prefix := "http"
+	switch protocol {
+	case corev1alpha1.PortProtocolH2C:
+		prefix = "http2"
+	case corev1alpha1.PortProtocolGRPC:
+		prefix = "grpc"
+	}
+	if name == "" || name == prefix {
+		return prefix
+	}
+	if strings.HasPrefix(name, prefix+"-") {
+		return name
+	}
+	return fmt.Sprintf("%s-%s", prefix, name)
+}
+
+// deployerReadinessProbe checks the gRPC health of the health checked port,
+// or that the public port accepts connections.
+func deployerReadinessProbe(parent *corev1alpha1.Deployer) *corev1.Probe {
+	for _, port := range parent.Spec.Ports {
+		if !port.GRPCHealthCheck {
+			continue
+		}
+		for _, containerPort := range parent.Spec.Template.Spec.Containers[0].Ports {
+			if containerPort.Name == port.Name {
+				return &corev1.Probe{
+					Handler: corev1.Handler{
+						Exec: &corev1.ExecAction{
+							Command: []string{"grpc_health_probe", fmt.Sprintf("-addr=:%d", containerPort.ContainerPort)},
+						},
+					},
+				}
+			}
+		}
+	}
+	return &corev1.Probe{
+		Handler: corev1.Handler{
+			TCPSocket: &corev1.TCPSocketAction{
+				Port: intstr.FromInt(int(deployerPublicPort(parent).ContainerPort)),
+			},
+		},
+	}
+}
+
+// deployerBackendProtocolAnnotations configures the ingress to proxy HTTP/2
+// to public ports serving h2c or gRPC.
+func deployerBackendProtocolAnnotations(parent *corev1alpha1.Deployer) map[string]string {
+	switch deployerPortProtocol(parent, deployerPublicPort(parent).Name) {
+	case corev1alpha1.PortProtocolH2C, corev1alpha1.PortProtocolGRPC:
+		return map[string]string{backendProtocolAnnotationKey: "GRPC"}
+	}
+	return map[string]string{}
+}
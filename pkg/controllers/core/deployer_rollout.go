@@ -0,0 +1,488 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/projectriff/system/pkg/apis"
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	"github.com/projectriff/system/pkg/controllers"
+	"github.com/projectriff/system/pkg/refs"
+)
+
+const (
+	canaryAnnotationKey         = "nginx.ingress.kubernetes.io/canary"
+	canaryWeightAnnotationKey   = "nginx.ingress.kubernetes.io/canary-weight"
+	canaryByHeaderAnnotationKey = "nginx.ingress.kubernetes.io/canary-by-header"
+)
+
+// rolloutMetricsInterval is how often the error rate of a candidate receiving
+// traffic is checked
+var rolloutMetricsInterval = 30 * time.Second
+
+// DeployerRolloutReconciler moves new images through the configured rollout.
+// Each rollout starts a candidate, shifts traffic to it as it proves healthy
+// and finally promotes it to become the stable image. Candidates that fail to
+// become ready, or serve too many errors, are rolled back.
+//
+// The deployer is requeued to advance each canary step once its pause elapses,
+// to check a starting candidate once its progress deadline elapses and to check
+// the error rate of a candidate receiving traffic.
+func DeployerRolloutReconciler(c controllers.Config, metrics RolloutMetrics) controllers.SubReconciler {
+	c.Log = c.Log.WithName("Rollout")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *corev1alpha1.Deployer) (ctrl.Result, error) {
+			rollout := parent.Spec.Rollout
+			if rollout == nil {
+				parent.Status.MarkRolloutNotUsed()
+				return ctrl.Result{}, nil
+			}
+			latest := parent.Status.LatestImage
+			if latest == "" {
+				// no image, skip
+				return ctrl.Result{}, nil
+			}
+			if parent.Status.Rollout == nil {
+				// the first image is stable, there is nothing to roll out from
+				parent.Status.Rollout = &corev1alpha1.RolloutStatus{
+					StableImage:        latest,
+					LastTransitionTime: apis.VolatileTime{Inner: metav1.Now()},
+				}
+				parent.Status.MarkRolloutReady()
+			}
+			status := parent.Status.Rollout
+
+			switch {
+			case status.CandidateImage != "" && status.CandidateImage == status.StableImage:
+				// promoted, the candidate serves traffic until the stable
+				// deployment is updated
+				stable, err := getDeployment(ctx, c, parent.Namespace, parent.Status.DeploymentRef)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+				if stable == nil || !deploymentRolledOut(stable, status.StableImage) {
+					parent.Status.MarkRolloutProgressing("Promoting", "Image %q is replacing the stable deployment", status.StableImage)
+					return ctrl.Result{}, nil
+				}
+				resetRolloutCandidate(status)
+				parent.Status.MarkRolloutReady()
+				return ctrl.Result{}, nil
+
+			case latest == status.StableImage:
+				// nothing to roll out, abandon any candidate
+				if status.CandidateImage != "" || status.FailedImage != "" {
+					resetRolloutCandidate(status)
+					status.FailedImage = ""
+				}
+				parent.Status.MarkRolloutReady()
+				return ctrl.Result{}, nil
+
+			case latest == status.FailedImage:
+				// keep the stable image until a new image is available
+				if status.CandidateImage != "" {
+					resetRolloutCandidate(status)
+				}
+				return ctrl.Result{}, nil
+
+			case latest != status.CandidateImage:
+				resetRolloutCandidate(status)
+				status.CandidateImage = latest
+				parent.Status.MarkRolloutProgressing("CandidateNotReady", "Image %q is starting", latest)
+				return ctrl.Result{}, nil
+			}
+
+			candidate, err := getDeployment(ctx, c, parent.Namespace, status.CandidateDeploymentRef)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if candidate == nil {
+				parent.Status.MarkRolloutProgressing("CandidateNotReady", "Image %q is starting", status.CandidateImage)
+				return ctrl.Result{}, nil
+			}
+			if message := deploymentFailure(candidate); message != "" {
+				rollbackRolloutCandidate(c, parent, message)
+				return ctrl.Result{}, nil
+			}
+			if !deploymentRolledOut(candidate, status.CandidateImage) {
+				parent.Status.MarkRolloutProgressing("CandidateNotReady", "Image %q is starting", status.CandidateImage)
+				if candidate.Spec.ProgressDeadlineSeconds != nil {
+					deadline := time.Duration(*candidate.Spec.ProgressDeadlineSeconds) * time.Second
+					if remaining := deadline - time.Since(status.LastTransitionTime.Inner.Time); remaining > 0 {
+						// check back once the candidate may have missed its deadline
+						return ctrl.Result{RequeueAfter: remaining}, nil
+					}
+				}
+				return ctrl.Result{}, nil
+			}
+			result := ctrl.Result{}
+			// canary candidates receive traffic once the first step starts,
+			// blue/green candidates receive the requests previewing them
+			receivesTraffic := status.Weight > 0 || rollout.BlueGreen != nil
+			if rollout.MaxErrorRate != nil && metrics != nil && receivesTraffic && status.CandidateServiceRef != nil {
+				// check the error rate again while the candidate receives traffic
+				result.RequeueAfter = rolloutMetricsInterval
+				rate, err := metrics.ErrorRate(ctx, parent.Namespace, status.CandidateServiceRef.Name)
+				if err != nil {
+					// an unavailable metrics source should not roll back healthy
+					// candidates, the failure is reported until a check succeeds
+					c.Log.Error(err, "unable to check candidate error rate", "service", status.CandidateServiceRef.Name)
+					status.ErrorRateCheckFailure = fmt.Sprintf("Unable to check the error rate: %v", err)
+				} else if rate > float64(*rollout.MaxErrorRate) {
+					rollbackRolloutCandidate(c, parent, fmt.Sprintf("error rate %.1f%% exceeds %d%%", rate, *rollout.MaxErrorRate))
+					return ctrl.Result{}, nil
+				} else {
+					status.ErrorRateCheckFailure = ""
+				}
+			}
+
+			if rollout.PromotedImage == status.CandidateImage {
+				promoteRolloutCandidate(c, parent)
+				return ctrl.Result{}, nil
+			}
+
+			if rollout.BlueGreen != nil {
+				parent.Status.MarkRolloutProgressing("AwaitingPromotion", "Image %q is ready to be promoted", status.CandidateImage)
+				return result, nil
+			}
+
+			steps := rollout.Canary.Steps
+			if status.Weight == 0 {
+				// the candidate is ready, start the first step
+				status.Step = 0
+				status.Weight = steps[0].Weight
+				status.LastTransitionTime = apis.VolatileTime{Inner: metav1.Now()}
+			} else if pause := steps[status.Step].Pause; pause != nil && time.Since(status.LastTransitionTime.Inner.Time) >= pause.Duration {
+				if int(status.Step)+1 >= len(steps) {
+					promoteRolloutCandidate(c, parent)
+					return ctrl.Result{}, nil
+				}
+				status.Step++
+				status.Weight = steps[status.Step].Weight
+				status.LastTransitionTime = apis.VolatileTime{Inner: metav1.Now()}
+			}
+			pause := steps[status.Step].Pause
+			if pause == nil {
+				parent.Status.MarkRolloutProgressing("AwaitingPromotion", "Image %q is receiving %d%% of traffic at step %d of %d, awaiting promotion", status.CandidateImage, status.Weight, status.Step+1, len(steps))
+				return result, nil
+			}
+			parent.Status.MarkRolloutProgressing("Canary", "Image %q is receiving %d%% of traffic at step %d of %d", status.CandidateImage, status.Weight, status.Step+1, len(steps))
+			if remaining := pause.Duration - time.Since(status.LastTransitionTime.Inner.Time); remaining > 0 && (result.RequeueAfter == 0 || remaining < result.RequeueAfter) {
+				// check back once the pause elapses
+				result.RequeueAfter = remaining
+			}
+			return result, nil
+		},
+
+		Config: c,
+	}
+}
+
+func resetRolloutCandidate(status *corev1alpha1.RolloutStatus) {
+	status.CandidateImage = ""
+	status.Step = 0
+	status.Weight = 0
+	status.ErrorRateCheckFailure = ""
+	status.LastTransitionTime = apis.VolatileTime{Inner: metav1.Now()}
+}
+
+func promoteRolloutCandidate(c controllers.Config, parent *corev1alpha1.Deployer) {
+	status := parent.Status.Rollout
+	status.StableImage = status.CandidateImage
+	status.Weight = 100
+	status.LastTransitionTime = apis.VolatileTime{Inner: metav1.Now()}
+	c.Recorder.Eventf(parent, corev1.EventTypeNormal, "Promoted",
+		"Promoted image %q", status.StableImage)
+	parent.Status.MarkRolloutProgressing("Promoting", "Image %q is replacing the stable deployment", status.StableImage)
+}
+
+func rollbackRolloutCandidate(c controllers.Config, parent *corev1alpha1.Deployer, message string) {
+	status := parent.Status.Rollout
+	image := status.CandidateImage
+	status.FailedImage = image
+	resetRolloutCandidate(status)
+	c.Recorder.Eventf(parent, corev1.EventTypeWarning, "RolledBack",
+		"Rolled back image %q: %s", image, message)
+	parent.Status.MarkRolloutFailed("RolledBack", "Image %q was rolled back: %s", image, message)
+}
+
+func getDeployment(ctx context.Context, c controllers.Config, namespace string, ref *refs.TypedLocalObjectReference) (*appsv1.Deployment, error) {
+	if ref == nil {
+		return nil, nil
+	}
+	var deployment appsv1.Deployment
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, &deployment); err != nil {
+		if apierrs.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &deployment, nil
+}
+
+// deploymentRolledOut is true once every replica of the deployment runs the
+// image and is available
+func deploymentRolledOut(deployment *appsv1.Deployment, image string) bool {
+	if deployment.Spec.Template.Spec.Containers[0].Image != image {
+		return false
+	}
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return false
+	}
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	return deployment.Status.UpdatedReplicas >= replicas &&
+		deployment.Status.Replicas == deployment.Status.UpdatedReplicas &&
+		deployment.Status.AvailableReplicas >= replicas
+}
+
+// deploymentFailure describes why the deployment is unable to make progress,
+// or is empty
+func deploymentFailure(deployment *appsv1.Deployment) string {
+	for _, cond := range deployment.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing && cond.Status == corev1.ConditionFalse && cond.Reason == "ProgressDeadlineExceeded" {
+			return cond.Message
+		}
+	}
+	return ""
+}
+
+func DeployerChildCandidateDeploymentReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ChildCandidateDeployment")
+
+	return &controllers.ChildReconciler{
+		ParentType:    &corev1alpha1.Deployer{},
+		ChildType:     &appsv1.Deployment{},
+		ChildListType: &appsv1.DeploymentList{},
+
+		DesiredChild: func(parent *corev1alpha1.Deployer) (*appsv1.Deployment, error) {
+			if parent.Spec.Rollout == nil || parent.Status.Rollout == nil || parent.Status.Rollout.CandidateImage == "" {
+				// no candidate, skip
+				return nil, nil
+			}
+
+			child := desiredDeployment(parent, parent.Status.Rollout.CandidateImage, corev1alpha1.RolloutTrackCandidate)
+			child.GenerateName = fmt.Sprintf("%s-deployer-candidate-", parent.Name)
+			child.Spec.Selector = &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					corev1alpha1.DeployerLabelKey:     parent.Name,
+					corev1alpha1.RolloutTrackLabelKey: corev1alpha1.RolloutTrackCandidate,
+				},
+			}
+			if deadline := parent.Spec.Rollout.ProgressDeadline; deadline != nil {
+				seconds := int32(deadline.Duration.Seconds())
+				child.Spec.ProgressDeadlineSeconds = &seconds
+			}
+
+			return child, nil
+		},
+		ReflectChildStatusOnParent: func(parent *corev1alpha1.Deployer, child *appsv1.Deployment, err error) {
+			if err != nil || parent.Status.Rollout == nil {
+				return
+			}
+			if child == nil {
+				parent.Status.Rollout.CandidateDeploymentRef = nil
+			} else {
+				parent.Status.Rollout.CandidateDeploymentRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
+			}
+		},
+		HarmonizeImmutableFields: func(current, desired *appsv1.Deployment) {
+			// replicas may be scaled directly by users
+			desired.Spec.Replicas = current.Spec.Replicas
+		},
+		MergeBeforeUpdate: func(current, desired *appsv1.Deployment) {
+			current.Labels = desired.Labels
+			current.Spec = desired.Spec
+		},
+		SemanticEquals: func(a1, a2 *appsv1.Deployment) bool {
+			return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+		},
+		OurChild: func(child *appsv1.Deployment) bool {
+			return child.Labels[corev1alpha1.RolloutTrackLabelKey] == corev1alpha1.RolloutTrackCandidate
+		},
+
+		Config:     c,
+		IndexField: ".metadata.candidateDeploymentController",
+		Sanitize: func(child *appsv1.Deployment) interface{} {
+			return child.Spec
+		},
+	}
+}
+
+func DeployerChildCandidateServiceReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ChildCandidateService")
+
+	return &controllers.ChildReconciler{
+		ParentType:    &corev1alpha1.Deployer{},
+		ChildType:     &corev1.Service{},
+		ChildListType: &corev1.ServiceList{},
+
+		DesiredChild: func(parent *corev1alpha1.Deployer) (*corev1.Service, error) {
+			if parent.Status.Rollout == nil || parent.Status.Rollout.CandidateDeploymentRef == nil {
+				// no candidate, skip
+				return nil, nil
+			}
+
+			child := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: controllers.MergeMaps(parent.Labels, map[string]string{
+						corev1alpha1.DeployerLabelKey:     parent.Name,
+						corev1alpha1.RolloutTrackLabelKey: corev1alpha1.RolloutTrackCandidate,
+					}),
+					Annotations: make(map[string]string),
+					Namespace:   parent.Namespace,
+					Name:        fmt.Sprintf("%s-candidate", parent.Name),
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: deployerServicePorts(parent),
+					Selector: map[string]string{
+						corev1alpha1.DeployerLabelKey:     parent.Name,
+						corev1alpha1.RolloutTrackLabelKey: corev1alpha1.RolloutTrackCandidate,
+					},
+				},
+			}
+
+			return child, nil
+		},
+		ReflectChildStatusOnParent: func(parent *corev1alpha1.Deployer, child *corev1.Service, err error) {
+			if err != nil || parent.Status.Rollout == nil {
+				return
+			}
+			if child == nil {
+				parent.Status.Rollout.CandidateServiceRef = nil
+			} else {
+				parent.Status.Rollout.CandidateServiceRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
+			}
+		},
+		HarmonizeImmutableFields: func(current, desired *corev1.Service) {
+			desired.Spec.ClusterIP = current.Spec.ClusterIP
+		},
+		MergeBeforeUpdate: func(current, desired *corev1.Service) {
+			current.Labels = desired.Labels
+			current.Spec = desired.Spec
+		},
+		SemanticEquals: func(a1, a2 *corev1.Service) bool {
+			return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+		},
+		OurChild: func(child *corev1.Service) bool {
+			return child.Labels[corev1alpha1.RolloutTrackLabelKey] == corev1alpha1.RolloutTrackCandidate
+		},
+
+		Config:     c,
+		IndexField: ".metadata.candidateServiceController",
+		Sanitize: func(child *corev1.Service) interface{} {
+			return child.Spec
+		},
+	}
+}
+
+func DeployerChildCandidateIngressReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ChildCandidateIngress")
+
+	return &controllers.ChildReconciler{
+		ParentType:    &corev1alpha1.Deployer{},
+		ChildType:     &networkingv1beta1.Ingress{},
+		ChildListType: &networkingv1beta1.IngressList{},
+
+		DesiredChild: func(parent *corev1alpha1.Deployer) (*networkingv1beta1.Ingress, error) {
+			if parent.Status.Rollout == nil || parent.Status.Rollout.CandidateServiceRef == nil || parent.Spec.IngressPolicy == corev1alpha1.IngressPolicyClusterLocal {
+				// no candidate service, skip
+				return nil, nil
+			}
+
+			coreSettings, err := deployerSettings(c, parent)
+			if err != nil {
+				return nil, err
+			}
+			if deployerRoutingBackend(coreSettings) != routingBackendIngress {
+				// the route splits traffic
+				return nil, nil
+			}
+			routes := deployerRoutes(coreSettings, parent)
+			spec, annotations := deployerIngressSpec(routes, parent.Status.Rollout.CandidateServiceRef.Name)
+			annotations = controllers.MergeMaps(annotations, deployerBackendProtocolAnnotations(parent))
+
+			// the ingress controller splits traffic for each host and path
+			// between the stable ingress and this canary ingress
+			annotations[canaryAnnotationKey] = "true"
+			annotations[canaryWeightAnnotationKey] = fmt.Sprintf("%d", parent.Status.Rollout.Weight)
+			if parent.Spec.Rollout.BlueGreen != nil {
+				annotations[canaryByHeaderAnnotationKey] = parent.Spec.Rollout.BlueGreen.PreviewHeader
+			}
+
+			child := &networkingv1beta1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: controllers.MergeMaps(parent.Labels, map[string]string{
+						corev1alpha1.DeployerLabelKey:     parent.Name,
+						corev1alpha1.RolloutTrackLabelKey: corev1alpha1.RolloutTrackCandidate,
+					}),
+					Annotations:  annotations,
+					GenerateName: fmt.Sprintf("%s-deployer-candidate-", parent.Name),
+					Namespace:    parent.Namespace,
+				},
+				Spec: spec,
+			}
+
+			return child, nil
+		},
+		ReflectChildStatusOnParent: func(parent *corev1alpha1.Deployer, child *networkingv1beta1.Ingress, err error) {
+			if err != nil || parent.Status.Rollout == nil {
+				return
+			}
+			if child == nil {
+				parent.Status.Rollout.CandidateIngressRef = nil
+			} else {
+				parent.Status.Rollout.CandidateIngressRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
+			}
+		},
+		MergeBeforeUpdate: func(current, desired *networkingv1beta1.Ingress) {
+			current.Labels = desired.Labels
+			current.Annotations = desired.Annotations
+			current.Spec = desired.Spec
+		},
+		SemanticEquals: func(a1, a2 *networkingv1beta1.Ingress) bool {
+			return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels) &&
+				equality.Semantic.DeepEqual(a1.Annotations, a2.Annotations)
+		},
+		OurChild: func(child *networkingv1beta1.Ingress) bool {
+			return child.Labels[corev1alpha1.RolloutTrackLabelKey] == corev1alpha1.RolloutTrackCandidate
+		},
+
+		Config:     c,
+		IndexField: ".metadata.candidateIngressController",
+		Sanitize: func(child *networkingv1beta1.Ingress) interface{} {
+			return child.Spec
+		},
+	}
+}
@@ -0,0 +1,331 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	"github.com/projectriff/system/pkg/controllers"
+)
+
+const (
+	useRegexAnnotationKey      = "nginx.ingress.kubernetes.io/use-regex"
+	rewriteTargetAnnotationKey = "nginx.ingress.kubernetes.io/rewrite-target"
+)
+
+// deployerRoutes resolves the routes exposing the deployer. Without routes,
+// the deployer is exposed at the root of its default host.
+func deployerRoutes(coreSettings *corev1.ConfigMap, parent *corev1alpha1.Deployer) []corev1alpha1.Route {
+	if len(parent.Spec.Routes) != 0 {
+		return parent.Spec.Routes
+	}
+	return []corev1alpha1.Route{{Hosts: []string{deployerIngressHost(coreSettings, parent)}, Path: "/"}}
+}
+
+// deployerRoutingBackend returns the backend configured to route requests to
+// deployers, defaults to ingress.
+func deployerRoutingBackend(coreSettings *corev1.ConfigMap) string {
+	if backend := coreSettings.Data[routingBackendKey]; backend != "" {
+		return backend
+	}
+	return routingBackendIngress
+}
+
+// deployerRouteHosts returns the unique hosts of the routes, in order.
+func deployerRouteHosts(routes []corev1alpha1.Route) []string {
+	hosts := []string{}
+	seen := map[string]bool{}
+	for _, route := range routes {
+		for _, host := range route.Hosts {
+			if !seen[host] {
+				seen[host] = true
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	return hosts
+}
+
+// deployerIngressSpec routes requests for each host and path to the service.
+// The returned annotations configure the rewrite shared by the routes.
+func deployerIngressSpec(routes []corev1alpha1.Route, serviceName string) (networkingv1beta1.IngressSpec, map[string]string) {
+	annotations := map[string]string{}
+	rewrite := routes[0].Rewrite
+	if rewrite != "" {
+		// the prefix is matched as a regular expression, the remainder of
+		// the path is appended to the rewrite
+		annotations[useRegexAnnotationKey] = "true"
+		annotations[rewriteTargetAnnotationKey] = strings.TrimSuffix(rewrite, "/") + "/$2"
+	}
+
+	spec := networkingv1beta1.IngressSpec{}
+	rules := map[string]*networkingv1beta1.HTTPIngressRuleValue{}
+	for _, host := range deployerRouteHosts(routes) {
+		rules[host] = &networkingv1beta1.HTTPIngressRuleValue{}
+		spec.Rules = append(spec.Rules, networkingv1beta1.IngressRule{
+			Host: host,
+			IngressRuleValue: networkingv1beta1.IngressRuleValue{
+				HTTP: rules[host],
+			},
+		})
+	}
+	for _, route := range routes {
+		path := route.Path
+		if rewrite != "" {
+			path = strings.TrimSuffix(path, "/") + "(/|$)(.*)"
+		}
+		for _, host := range route.Hosts {
+			rules[host].Paths = append(rules[host].Paths, networkingv1beta1.HTTPIngressPath{
+				Path: path,
+				Backend: networkingv1beta1.IngressBackend{
+					ServiceName: serviceName,
+					ServicePort: intstr.FromInt(80),
+				},
+			})
+		}
+	}
+
+	return spec, annotations
+}
+
+// deployerIngressURLs lists the public URL for each host and path of the
+// ingress.
+func deployerIngressURLs(ingress *networkingv1beta1.Ingress) []string {
+	scheme := "http"
+	if len(ingress.Spec.TLS) != 0 {
+		scheme = "https"
+	}
+	urls := []string{}
+	for _, rule := range ingress.Spec.Rules {
+		for _, path := range rule.HTTP.Paths {
+			prefix := strings.TrimSuffix(path.Path, "(/|$)(.*)")
+			urls = append(urls, fmt.Sprintf("%s://%s%s", scheme, rule.Host, strings.TrimSuffix(prefix, "/")))
+		}
+	}
+	return urls
+}
+
+// managedIngressAnnotationKeys are the ingress annotations owned by the
+// deployer, other annotations are preserved.
+var managedIngressAnnotationKeys = []string{useRegexAnnotationKey, rewriteTargetAnnotationKey, backendProtocolAnnotationKey}
+
+// managedIngressAnnotations returns the annotations of an ingress that are
+// owned by the deployer.
+func managedIngressAnnotations(annotations map[string]string) map[string]string {
+	managed := map[string]string{}
+	for _, key := range managedIngressAnnotationKeys {
+		if value, ok := annotations[key]; ok {
+			managed[key] = value
+		}
+	}
+	return managed
+}
+
+// mergeManagedIngressAnnotations replaces the managed annotations of the
+// current ingress, preserving all other annotations.
+func mergeManagedIngressAnnotations(current, desired map[string]string) map[string]string {
+	merged := map[string]string{}
+	for key, value := range current {
+		merged[key] = value
+	}
+	for _, key := range managedIngressAnnotationKeys {
+		delete(merged, key)
+	}
+	return controllers.MergeMaps(merged, managedIngressAnnotations(desired))
+}
+
+// +kubebuilder:webhook:path=/validate-core-projectriff-io-v1alpha1-deployer-routes,mutating=false,failurePolicy=fail,groups=core.projectriff.io,resources=deployers,verbs=create;update,versions=v1alpha1,name=routes.deployers.core.projectriff.io
+
+const (
+	deployerRoutesWebhookPath = "/validate-core-projectriff-io-v1alpha1-deployer-routes"
+	// deployerRouteHostIndexField indexes deployers by the hosts of their
+	// routes. Deployers without routes are indexed by their default host
+	// without the domain, as the domain is a setting that may change.
+	deployerRouteHostIndexField = ".spec.routes.hosts"
+)
+
+// DeployerRouteValidator rejects deployers claiming a host and path that
+// overlaps a host and path claimed by another deployer.
+type DeployerRouteValidator struct {
+	client.Client
+	decoder *admission.Decoder
+}
+
+var (
+	_ admission.Handler         = (*DeployerRouteValidator)(nil)
+	_ admission.DecoderInjector = (*DeployerRouteValidator)(nil)
+)
+
+// SetupWithManager indexes deployers by host and registers the validator with
+// the manager's webhook server.
+func (v *DeployerRouteValidator) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(&corev1alpha1.Deployer{}, deployerRouteHostIndexField, func(rawObj runtime.Object) []string {
+		deployer := rawObj.(*corev1alpha1.Deployer)
+		if len(deployer.Spec.Routes) == 0 {
+			return []string{deployerDefaultHostIndexKey(deployer.Namespace, deployer.Name)}
+		}
+		return deployerRouteHosts(deployer.Spec.Routes)
+	}); err != nil {
+		return err
+	}
+	mgr.GetWebhookServer().Register(deployerRoutesWebhookPath, &webhook.Admission{Handler: v})
+	return nil
+}
+
+func (v *DeployerRouteValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+func (v *DeployerRouteValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	deployer := &corev1alpha1.Deployer{}
+	if err := v.decoder.Decode(req, deployer); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	deployer.Default()
+
+	coreSettings := &corev1.ConfigMap{}
+	coreSettingsKey := types.NamespacedName{Namespace: systemNamespace, Name: settingsConfigMapName}
+	if err := v.Get(ctx, coreSettingsKey, coreSettings); err != nil && !apierrs.IsNotFound(err) {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	claims := deployerRouteClaims(coreSettings, deployer)
+	if len(claims) == 0 {
+		return admission.Allowed("")
+	}
+
+	others, err := v.deployersClaimingHosts(ctx, coreSettings, claims)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	for _, other := range others {
+		if other.Namespace == deployer.Namespace && other.Name == deployer.Name {
+			continue
+		}
+		for _, otherClaim := range deployerRouteClaims(coreSettings, other) {
+			for _, claim := range claims {
+				if claim.overlaps(otherClaim) {
+					return admission.Denied(fmt.Sprintf("route %q overlaps route %q claimed by deployer %s/%s", claim, otherClaim, other.Namespace, other.Name))
+				}
+			}
+		}
+	}
+
+	return admission.Allowed("")
+}
+
+// deployersClaimingHosts lists the deployers that may claim a host of the
+// claims, in order of their first claimed host.
+func (v *DeployerRouteValidator) deployersClaimingHosts(ctx context.Context, coreSettings *corev1.ConfigMap, claims []deployerRouteClaim) ([]*corev1alpha1.Deployer, error) {
+	domainSuffix := "." + deployerIngressDomain(coreSettings)
+	keys := []string{}
+	seenKeys := map[string]bool{}
+	for _, claim := range claims {
+		hostKeys := []string{claim.Host}
+		if strings.HasSuffix(claim.Host, domainSuffix) {
+			// the default host of a deployer without routes
+			hostKeys = append(hostKeys, strings.TrimSuffix(claim.Host, domainSuffix))
+		}
+		for _, key := range hostKeys {
+			if !seenKeys[key] {
+				seenKeys[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	deployers := []*corev1alpha1.Deployer{}
+	seen := map[types.NamespacedName]bool{}
+	for _, key := range keys {
+		list := &corev1alpha1.DeployerList{}
+		if err := v.List(ctx, list, client.MatchingField(deployerRouteHostIndexField, key)); err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			deployer := &list.Items[i]
+			name := types.NamespacedName{Namespace: deployer.Namespace, Name: deployer.Name}
+			if !seen[name] {
+				seen[name] = true
+				deployers = append(deployers, deployer)
+			}
+		}
+	}
+	return deployers, nil
+}
+
+// deployerDefaultHostIndexKey is the index key of the default host of a
+// deployer, without the domain.
+func deployerDefaultHostIndexKey(namespace, name string) string {
+	return fmt.Sprintf("%s.%s", name, namespace)
+}
+
+// deployerRouteClaim is a host and normalized path routed to a deployer.
+type deployerRouteClaim struct {
+	Host string
+	Path string
+}
+
+func (c deployerRouteClaim) String() string {
+	return c.Host + c.Path
+}
+
+// overlaps is true when requests for one claim may be routed by the other.
+// Paths are matched by prefix, element by element.
+func (c deployerRouteClaim) overlaps(other deployerRouteClaim) bool {
+	if c.Host != other.Host {
+		return false
+	}
+	return c.Path == other.Path || c.Path == "/" || other.Path == "/" ||
+		strings.HasPrefix(c.Path, other.Path+"/") || strings.HasPrefix(other.Path, c.Path+"/")
+}
+
+// deployerRouteClaims returns the host and path combinations routed to the
+// deployer. Only deployers with an external ingress policy claim routes.
+func deployerRouteClaims(coreSettings *corev1.ConfigMap, deployer *corev1alpha1.Deployer) []deployerRouteClaim {
+	claims := []deployerRouteClaim{}
+	if deployer.Spec.IngressPolicy != corev1alpha1.IngressPolicyExternal {
+		return claims
+	}
+	for _, route := range deployerRoutes(coreSettings, deployer) {
+		for _, host := range route.Hosts {
+			claims = append(claims, deployerRouteClaim{Host: host, Path: normalizeRoutePath(route.Path)})
+		}
+	}
+	return claims
+}
+
+// normalizeRoutePath returns the path with a leading and without a trailing
+// slash, the empty path is the root.
+func normalizeRoutePath(path string) string {
+	return "/" + strings.Trim(path, "/")
+}
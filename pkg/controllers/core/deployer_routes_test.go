@@ -0,0 +1,197 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	corecontrollers "github.com/projectriff/system/pkg/controllers/core"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+	"github.com/projectriff/system/pkg/controllers/testing/factories"
+)
+
+func TestDeployerRouteValidator(t *testing.T) {
+	testNamespace := "test-namespace"
+	testName := "test-deployer"
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = corev1alpha1.AddToScheme(scheme)
+
+	testSettings := factories.ConfigMap().
+		NamespaceName("riff-system", "riff-core-settings").
+		AddData("defaultDomain", "example.com")
+
+	deployer := factories.DeployerCore().
+		NamespaceName(testNamespace, testName).
+		Image("example.com/repo").
+		IngressPolicy(corev1alpha1.IngressPolicyExternal)
+	other := factories.DeployerCore().
+		NamespaceName(testNamespace, "other-deployer").
+		Image("example.com/repo").
+		IngressPolicy(corev1alpha1.IngressPolicyExternal)
+
+	ordersRoute := corev1alpha1.Route{Hosts: []string{"api.example.com"}, Path: "/orders"}
+
+	for _, c := range []struct {
+		name    string
+		given   []rtesting.Factory
+		target  rtesting.Factory
+		allowed bool
+	}{{
+		name: "no other deployers",
+		given: []rtesting.Factory{
+			testSettings,
+		},
+		target: deployer.
+			Routes(ordersRoute),
+		allowed: true,
+	}, {
+		name: "distinct routes",
+		given: []rtesting.Factory{
+			testSettings,
+			other.
+				Routes(corev1alpha1.Route{Hosts: []string{"api.example.com"}, Path: "/customers"}),
+		},
+		target: deployer.
+			Routes(ordersRoute),
+		allowed: true,
+	}, {
+		name: "distinct routes sharing a path prefix",
+		given: []rtesting.Factory{
+			testSettings,
+			other.
+				Routes(corev1alpha1.Route{Hosts: []string{"api.example.com"}, Path: "/orders-archive"}),
+		},
+		target: deployer.
+			Routes(ordersRoute),
+		allowed: true,
+	}, {
+		name: "route claimed with trailing slash by other deployer",
+		given: []rtesting.Factory{
+			testSettings,
+			other.
+				Routes(corev1alpha1.Route{Hosts: []string{"api.example.com"}, Path: "/orders/"}),
+		},
+		target: deployer.
+			Routes(ordersRoute),
+		allowed: false,
+	}, {
+		name: "route within route of other deployer",
+		given: []rtesting.Factory{
+			testSettings,
+			other.
+				Routes(corev1alpha1.Route{Hosts: []string{"api.example.com"}, Path: "/"}),
+		},
+		target: deployer.
+			Routes(ordersRoute),
+		allowed: false,
+	}, {
+		name: "route contains route of other deployer",
+		given: []rtesting.Factory{
+			testSettings,
+			other.
+				Routes(corev1alpha1.Route{Hosts: []string{"api.example.com"}, Path: "/orders/archive"}),
+		},
+		target: deployer.
+			Routes(ordersRoute),
+		allowed: false,
+	}, {
+		name: "route claimed by other deployer",
+		given: []rtesting.Factory{
+			testSettings,
+			other.
+				Routes(corev1alpha1.Route{Hosts: []string{"www.example.com", "api.example.com"}, Path: "/orders"}),
+		},
+		target: deployer.
+			Routes(ordersRoute),
+		allowed: false,
+	}, {
+		name: "route claims default host of other deployer",
+		given: []rtesting.Factory{
+			testSettings,
+			other,
+		},
+		target: deployer.
+			Routes(corev1alpha1.Route{Hosts: []string{"other-deployer.test-namespace.example.com"}}),
+		allowed: false,
+	}, {
+		name: "route claimed by cluster local deployer",
+		given: []rtesting.Factory{
+			testSettings,
+			other.
+				IngressPolicy(corev1alpha1.IngressPolicyClusterLocal).
+				Routes(ordersRoute),
+		},
+		target: deployer.
+			Routes(ordersRoute),
+		allowed: true,
+	}, {
+		name: "update own routes",
+		given: []rtesting.Factory{
+			testSettings,
+			deployer.
+				Routes(ordersRoute),
+		},
+		target: deployer.
+			Routes(ordersRoute),
+		allowed: true,
+	}, {
+		name: "cluster local deployer",
+		given: []rtesting.Factory{
+			other.
+				Routes(ordersRoute),
+		},
+		target: deployer.
+			IngressPolicy(corev1alpha1.IngressPolicyClusterLocal),
+		allowed: true,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			given := []runtime.Object{}
+			for _, f := range c.given {
+				given = append(given, f.Create())
+			}
+			raw, err := json.Marshal(c.target.Create())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			decoder, _ := admission.NewDecoder(scheme)
+			validator := &corecontrollers.DeployerRouteValidator{
+				Client: fakeclient.NewFakeClientWithScheme(scheme, given...),
+			}
+			_ = validator.InjectDecoder(decoder)
+
+			response := validator.Handle(context.TODO(), admission.Request{
+				AdmissionRequest: admissionv1beta1.AdmissionRequest{
+					Object: runtime.RawExtension{Raw: raw},
+				},
+			})
+			if actual := response.Allowed; actual != c.allowed {
+				t.Errorf("Handle(%s) allowed = %v, expected %v: %v", c.name, actual, c.allowed, response.Result)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	certmanagerv1alpha2 "github.com/projectriff/system/pkg/apis/thirdparty/certmanager/v1alpha2"
+	"github.com/projectriff/system/pkg/controllers"
+	"github.com/projectriff/system/pkg/refs"
+	"github.com/projectriff/system/pkg/tracker"
+)
+
+// DeployerCertificateReconciler issues certificates for deployers with a TLS
+// issuer. Without cert-manager installed, deployers requesting a certificate
+// are marked as not supported.
+func DeployerCertificateReconciler(c controllers.Config, certManager bool) controllers.SubReconciler {
+	if certManager {
+		return DeployerChildCertificateReconciler(c)
+	}
+
+	c.Log = c.Log.WithName("Certificate")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *corev1alpha1.Deployer) error {
+			parent.Status.CertificateRef = nil
+			if deployerCertificateRequested(parent) {
+				parent.Status.MarkCertificateNotSupported()
+			}
+			return nil
+		},
+
+		Config: c,
+	}
+}
+
+func DeployerChildCertificateReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ChildCertificate")
+
+	return &controllers.ChildReconciler{
+		ParentType:    &corev1alpha1.Deployer{},
+		ChildType:     &certmanagerv1alpha2.Certificate{},
+		ChildListType: &certmanagerv1alpha2.CertificateList{},
+
+		DesiredChild: func(parent *corev1alpha1.Deployer) (*certmanagerv1alpha2.Certificate, error) {
+			if !deployerCertificateRequested(parent) {
+				return nil, nil
+			}
+
+			coreSettings, err := deployerSettings(c, parent)
+			if err != nil {
+				return nil, err
+			}
+			hosts := deployerRouteHosts(deployerRoutes(coreSettings, parent))
+
+			issuer := parent.Spec.TLS.IssuerRef
+			child := &certmanagerv1alpha2.Certificate{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: controllers.MergeMaps(parent.Labels, map[string]string{
+						corev1alpha1.DeployerLabelKey: parent.Name,
+					}),
+					Annotations:  make(map[string]string),
+					GenerateName: fmt.Sprintf("%s-deployer-", parent.Name),
+					Namespace:    parent.Namespace,
+				},
+				Spec: certmanagerv1alpha2.CertificateSpec{
+					CommonName: hosts[0],
+					DNSNames:   hosts,
+					SecretName: deployerCertificateSecretName(parent),
+					IssuerRef: certmanagerv1alpha2.ObjectReference{
+						Name:  issuer.Name,
+						Kind:  issuer.Kind,
+						Group: certmanagerv1alpha2.GroupVersion.Group,
+					},
+				},
+			}
+
+			return child, nil
+		},
+		ReflectChildStatusOnParent: func(parent *corev1alpha1.Deployer, child *certmanagerv1alpha2.Certificate, err error) {
+			if err != nil {
+				return
+			}
+			if child == nil {
+				parent.Status.CertificateRef = nil
+			} else {
+				parent.Status.CertificateRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
+				parent.Status.PropagateCertificateStatus(&child.Status)
+			}
+		},
+		MergeBeforeUpdate: func(current, desired *certmanagerv1alpha2.Certificate) {
+			current.Labels = desired.Labels
+			current.Spec = desired.Spec
+		},
+		SemanticEquals: func(a1, a2 *certmanagerv1alpha2.Certificate) bool {
+			return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+		},
+
+		Config:     c,
+		IndexField: ".metadata.certificateController",
+		Sanitize: func(child *certmanagerv1alpha2.Certificate) interface{} {
+			return child.Spec
+		},
+	}
+}
+
+// DeployerChildTLSSecretReconciler copies the cluster default certificate into
+// the deployer's namespace for deployers without their own TLS configuration.
+// The default is named by the defaultTLSSecret key of the core settings and
+// references a kubernetes.io/tls Secret in the system namespace.
+func DeployerChildTLSSecretReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ChildTLSSecret")
+
+	return &controllers.ChildReconciler{
+		ParentType:    &corev1alpha1.Deployer{},
+		ChildType:     &corev1.Secret{},
+		ChildListType: &corev1.SecretList{},
+
+		Setup: func(mgr controllers.Manager, bldr *controllers.Builder) error {
+			bldr.Watches(&source.Kind{Type: &corev1.Secret{}}, controllers.EnqueueTracked(&corev1.Secret{}, c.Tracker, c.Scheme))
+			return nil
+		},
+		DesiredChild: func(parent *corev1alpha1.Deployer) (*corev1.Secret, error) {
+			if !deployerIngressRequired(parent) || parent.Spec.TLS != nil {
+				return nil, nil
+			}
+
+			coreSettings, err := deployerSettings(c, parent)
+			if err != nil {
+				return nil, err
+			}
+			name := coreSettings.Data[defaultTLSSecretKey]
+			if name == "" {
+				// no cluster default
+				return nil, nil
+			}
+
+			defaultSecret := &corev1.Secret{}
+			defaultSecretKey := types.NamespacedName{Namespace: systemNamespace, Name: name}
+			// track default secret
+			c.Tracker.Track(
+				tracker.NewKey(schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, defaultSecretKey),
+				types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+			)
+			if err := c.Get(context.TODO(), defaultSecretKey, defaultSecret); err != nil {
+				c.Log.Error(err, fmt.Sprintf("unable to fetch resource with reference: %s", defaultSecretKey.String()))
+				return nil, err
+			}
+
+			child := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: controllers.MergeMaps(parent.Labels, map[string]string{
+						corev1alpha1.DeployerLabelKey: parent.Name,
+					}),
+					Annotations:  make(map[string]string),
+					GenerateName: fmt.Sprintf("%s-deployer-tls-", parent.Name),
+					Namespace:    parent.Namespace,
+				},
+				Type: defaultSecret.Type,
+				Data: defaultSecret.Data,
+			}
+
+			return child, nil
+		},
+		ReflectChildStatusOnParent: func(parent *corev1alpha1.Deployer, child *corev1.Secret, err error) {
+			if err != nil {
+				return
+			}
+			if child != nil {
+				parent.Status.TLSSecretRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
+				parent.Status.MarkCertificateReady()
+				return
+			}
+			parent.Status.TLSSecretRef = nil
+			switch {
+			case deployerCertificateRequested(parent):
+				// reflected from the certificate
+			case deployerIngressRequired(parent) && parent.Spec.TLS != nil && parent.Spec.TLS.SecretRef != "":
+				parent.Status.MarkCertificateReady()
+			default:
+				parent.Status.MarkCertificateNotUsed()
+			}
+		},
+		MergeBeforeUpdate: func(current, desired *corev1.Secret) {
+			current.Labels = desired.Labels
+			current.Data = desired.Data
+		},
+		SemanticEquals: func(a1, a2 *corev1.Secret) bool {
+			return equality.Semantic.DeepEqual(a1.Data, a2.Data) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+		},
+
+		Config:     c,
+		IndexField: ".metadata.tlsSecretController",
+		Sanitize: func(child *corev1.Secret) interface{} {
+			// never log secret values
+			keys := []string{}
+			for key := range child.Data {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			return keys
+		},
+	}
+}
+
+// deployerIngressRequired returns true when the deployer is exposed with an
+// ingress.
+func deployerIngressRequired(parent *corev1alpha1.Deployer) bool {
+	return parent.Status.ServiceRef != nil && parent.Spec.IngressPolicy == corev1alpha1.IngressPolicyExternal
+}
+
+func deployerCertificateRequested(parent *corev1alpha1.Deployer) bool {
+	return deployerIngressRequired(parent) && parent.Spec.TLS != nil && parent.Spec.TLS.IssuerRef != nil
+}
+
+func deployerCertificateSecretName(parent *corev1alpha1.Deployer) string {
+	return fmt.Sprintf("%s-deployer-tls", parent.Name)
+}
+
+// deployerTLSSecretName resolves the Secret holding the certificate for the
+// deployer's ingress host. An empty name is returned when the ingress is
+// served over plain HTTP.
+func deployerTLSSecretName(parent *corev1alpha1.Deployer) string {
+	switch {
+	case parent.Spec.TLS != nil && parent.Spec.TLS.SecretRef != "":
+		return parent.Spec.TLS.SecretRef
+	case parent.Status.CertificateRef != nil:
+		if cond := parent.Status.GetCondition(corev1alpha1.DeployerConditionCertificateReady); cond == nil || !cond.IsTrue() {
+			// wait for the certificate to be issued
+			return ""
+		}
+		return deployerCertificateSecretName(parent)
+	case parent.Status.TLSSecretRef != nil:
+		return parent.Status.TLSSecretRef.Name
+	}
+	return ""
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errorRateQuery is the percentage of requests for a service answered with a
+// server error by the NGINX ingress controller over the last minute
+const errorRateQuery = `sum(rate(nginx_ingress_controller_requests{namespace=%q,service=%q,status=~"5.."}[1m])) / sum(rate(nginx_ingress_controller_requests{namespace=%q,service=%q}[1m])) * 100`
+
+// RolloutMetrics reports the health of rollout candidates.
+type RolloutMetrics interface {
+	// ErrorRate returns the percentage of failed ingress requests served by
+	// the service.
+	ErrorRate(ctx context.Context, namespace, service string) (float64, error)
+}
+
+// NewPrometheusRolloutMetrics queries the request metrics exported by the
+// NGINX ingress controller from a Prometheus server.
+func NewPrometheusRolloutMetrics(endpoint string) RolloutMetrics {
+	return &prometheusRolloutMetrics{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type prometheusRolloutMetrics struct {
+	endpoint string
+	client   *http.Client
+}
+
+func (m *prometheusRolloutMetrics) ErrorRate(ctx context.Context, namespace, service string) (float64, error) {
+	query := fmt.Sprintf(errorRateQuery, namespace, service, namespace, service)
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/v1/query?query=%s", m.endpoint, url.QueryEscape(query)), nil)
+	if err != nil {
+		return 0, err
+	}
+	res, err := m.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	body := struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			Result []struct {
+				Value []interface{} `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("unable to decode metrics response: %v", err)
+	}
+	if body.Status != "success" {
+		return 0, fmt.Errorf("unable to query metrics: %s", body.Error)
+	}
+	if len(body.Data.Result) == 0 || len(body.Data.Result[0].Value) != 2 {
+		// no requests
+		return 0, nil
+	}
+	value, ok := body.Data.Result[0].Value[1].(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected metrics value %v", body.Data.Result[0].Value[1])
+	}
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(rate) {
+		// no requests
+		return 0, nil
+	}
+	return rate, nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corecontrollers "github.com/projectriff/system/pkg/controllers/core"
+)
+
+func TestPrometheusRolloutMetrics(t *testing.T) {
+	tests := []struct {
+		name      string
+		response  string
+		expected  float64
+		shouldErr bool
+	}{{
+		name:     "error rate",
+		response: `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1581000000.0,"12.5"]}]}}`,
+		expected: 12.5,
+	}, {
+		name:     "no requests",
+		response: `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+		expected: 0,
+	}, {
+		name:     "no successful requests",
+		response: `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1581000000.0,"NaN"]}]}}`,
+		expected: 0,
+	}, {
+		name:      "query error",
+		response:  `{"status":"error","errorType":"bad_data","error":"parse error"}`,
+		shouldErr: true,
+	}, {
+		name:      "malformed response",
+		response:  `not json`,
+		shouldErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var query string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query = r.URL.Query().Get("query")
+				fmt.Fprint(w, test.response)
+			}))
+			defer server.Close()
+
+			metrics := corecontrollers.NewPrometheusRolloutMetrics(server.URL + "/")
+			actual, err := metrics.ErrorRate(context.TODO(), "my-namespace", "my-service")
+			if (err != nil) != test.shouldErr {
+				t.Fatalf("ErrorRate() error = %v, shouldErr %v", err, test.shouldErr)
+			}
+			if !strings.Contains(query, `namespace="my-namespace",service="my-service"`) {
+				t.Errorf("ErrorRate() query = %q, expected to select the service", query)
+			}
+			if actual != test.expected {
+				t.Errorf("ErrorRate() = %v, expected %v", actual, test.expected)
+			}
+		})
+	}
+}
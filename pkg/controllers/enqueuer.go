@@ -47,3 +47,20 @@ func EnqueueTracked(by runtime.Object, t tracker.Tracker, s *runtime.Scheme) *ha
 		}),
 	}
 }
+
+// EnqueueLabeled enqueues a request for the resource named by the value of the
+// label on the watched object, in the watched object's namespace. Objects
+// without the label are ignored.
+func EnqueueLabeled(label string) *handler.EnqueueRequestsFromMapFunc {
+	return &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			name, ok := a.Meta.GetLabels()[label]
+			if !ok || name == "" {
+				return nil
+			}
+			return []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Namespace: a.Meta.GetNamespace(), Name: name}},
+			}
+		}),
+	}
+}
@@ -19,13 +19,19 @@ package knative
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"k8s.io/apimachinery/pkg/api/errors"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	"github.com/projectriff/system/pkg/apis"
 	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	knativev1alpha1 "github.com/projectriff/system/pkg/apis/knative/v1alpha1"
 	servingv1 "github.com/projectriff/system/pkg/apis/thirdparty/knative/serving/v1"
@@ -37,16 +43,23 @@ import (
 // +kubebuilder:rbac:groups=knative.projectriff.io,resources=adapters/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=build.projectriff.io,resources=applications;containers;functions,verbs=get;list;watch
 // +kubebuilder:rbac:groups=serving.knative.dev,resources=configurations;services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=daemonsets;deployments;statefulsets,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=argoproj.io,resources=rollouts,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;update;patch;delete
 
-func AdapterReconciler(c controllers.Config) *controllers.ParentReconciler {
+// AdapterReconciler keeps the image of a target up to date with a build.
+// Besides Knative Services and Configurations, adapters may target workloads
+// of the given kinds. The manager must be granted access to get, list, watch
+// and update each kind.
+func AdapterReconciler(c controllers.Config, targetKinds []knativev1alpha1.AdapterTargetKind) *controllers.ParentReconciler {
 	c.Log = c.Log.WithName("Adapter")
 
 	return &controllers.ParentReconciler{
 		Type: &knativev1alpha1.Adapter{},
 		SubReconcilers: []controllers.SubReconciler{
 			AdapterBuildRefReconciler(c),
-			AdapterTargetRefReconciler(c),
+			AdapterTargetRefReconciler(c, targetKinds),
 		},
 
 		Config: c,
@@ -76,10 +89,7 @@ func AdapterBuildRefReconciler(c controllers.Config) controllers.SubReconciler {
 					}
 					return err
 				}
-				if application.Status.LatestImage != "" {
-					parent.Status.LatestImage = application.Status.LatestImage
-					parent.Status.MarkBuildReady()
-				}
+				parent.Status.PropagateBuildStatus(build.Pin, &application.Status.BuildStatus)
 				return nil
 
 			case build.ContainerRef != "":
@@ -97,10 +107,7 @@ func AdapterBuildRefReconciler(c controllers.Config) controllers.SubReconciler {
 					}
 					return err
 				}
-				if container.Status.LatestImage != "" {
-					parent.Status.LatestImage = container.Status.LatestImage
-					parent.Status.MarkBuildReady()
-				}
+				parent.Status.PropagateBuildStatus(build.Pin, &container.Status.BuildStatus)
 				return nil
 
 			case build.FunctionRef != "":
@@ -118,10 +125,7 @@ func AdapterBuildRefReconciler(c controllers.Config) controllers.SubReconciler {
 					}
 					return err
 				}
-				if function.Status.LatestImage != "" {
-					parent.Status.LatestImage = function.Status.LatestImage
-					parent.Status.MarkBuildReady()
-				}
+				parent.Status.PropagateBuildStatus(build.Pin, &function.Status.BuildStatus)
 				return nil
 			}
 
@@ -138,13 +142,20 @@ func AdapterBuildRefReconciler(c controllers.Config) controllers.SubReconciler {
 	}
 }
 
-func AdapterTargetRefReconciler(c controllers.Config) controllers.SubReconciler {
+// AdapterTargetRefReconciler applies the latest image to the target, gated by
+// the adapter's update policy. Each applied image is recorded in the adapter's
+// status. Images awaiting a maintenance window are applied once the next window
+// opens.
+//
+// The revisions of Knative Service and Configuration targets are followed to
+// report whether the applied image became ready.
+func AdapterTargetRefReconciler(c controllers.Config, targetKinds []knativev1alpha1.AdapterTargetKind) controllers.SubReconciler {
 	c.Log = c.Log.WithName("TargetRef")
 
 	return &controllers.SyncReconciler{
-		Sync: func(ctx context.Context, parent *knativev1alpha1.Adapter) error {
+		Sync: func(ctx context.Context, parent *knativev1alpha1.Adapter) (ctrl.Result, error) {
 			if parent.Status.LatestImage == "" {
-				return nil
+				return ctrl.Result{}, nil
 			}
 
 			target := parent.Spec.Target
@@ -161,22 +172,31 @@ func AdapterTargetRefReconciler(c controllers.Config) controllers.SubReconciler
 				if err := c.Get(ctx, types.NamespacedName{Namespace: parent.Namespace, Name: target.ServiceRef}, &actualService); err != nil {
 					if errors.IsNotFound(err) {
 						parent.Status.MarkTargetNotFound("service", target.ServiceRef)
-						return nil
+						parent.Status.MarkTargetRevisionsNotUsed()
+						return ctrl.Result{}, nil
 					}
-					return err
+					return ctrl.Result{}, err
 				}
 				parent.Status.MarkTargetFound()
+				parent.Status.PropagateTargetRevisionStatus("service", target.ServiceRef, actualService.Generation, &actualService.Status, &actualService.Status.ConfigurationStatusFields)
 
-				if actualService.Spec.Template.Spec.Containers[0].Image == parent.Status.LatestImage {
-					// already latest image
-					return nil
+				update, result := adapterTargetUpdate(parent, actualService.Spec.Template.Spec.Containers[0].Image, time.Now())
+				if update == nil {
+					// nothing to apply
+					return result, nil
 				}
 
 				// update service
 				service := *(actualService.DeepCopy())
-				service.Spec.Template.Spec.Containers[0].Image = parent.Status.LatestImage
+				service.Spec.Template.Spec.Containers[0].Image = update.Image
 				c.Log.Info("reconciling service", "diff", cmp.Diff(actualService.Spec, service.Spec))
-				return c.Update(ctx, &service)
+				if err := c.Update(ctx, &service); err != nil {
+					return ctrl.Result{}, err
+				}
+				parent.Status.RecordUpdate(*update)
+				// a new revision is created for the image
+				parent.Status.MarkTargetReconciling("service", target.ServiceRef)
+				return ctrl.Result{}, nil
 
 			case target.ConfigurationRef != "":
 				var actualConfiguration servingv1.Configuration
@@ -189,33 +209,180 @@ func AdapterTargetRefReconciler(c controllers.Config) controllers.SubReconciler
 				if err := c.Get(ctx, key, &actualConfiguration); err != nil {
 					if errors.IsNotFound(err) {
 						parent.Status.MarkTargetNotFound("configuration", target.ConfigurationRef)
-						return nil
+						parent.Status.MarkTargetRevisionsNotUsed()
+						return ctrl.Result{}, nil
 					}
-					return err
+					return ctrl.Result{}, err
 				}
 				parent.Status.MarkTargetFound()
+				parent.Status.PropagateTargetRevisionStatus("configuration", target.ConfigurationRef, actualConfiguration.Generation, &actualConfiguration.Status, &actualConfiguration.Status.ConfigurationStatusFields)
 
-				if actualConfiguration.Spec.Template.Spec.Containers[0].Image == parent.Status.LatestImage {
-					// already latest image
-					return nil
+				update, result := adapterTargetUpdate(parent, actualConfiguration.Spec.Template.Spec.Containers[0].Image, time.Now())
+				if update == nil {
+					// nothing to apply
+					return result, nil
 				}
 
 				// update configuration
 				configuration := *(actualConfiguration.DeepCopy())
-				configuration.Spec.Template.Spec.Containers[0].Image = parent.Status.LatestImage
+				configuration.Spec.Template.Spec.Containers[0].Image = update.Image
 				c.Log.Info("reconciling configuration", "diff", cmp.Diff(actualConfiguration.Spec, configuration.Spec))
-				return c.Update(ctx, &configuration)
+				if err := c.Update(ctx, &configuration); err != nil {
+					return ctrl.Result{}, err
+				}
+				parent.Status.RecordUpdate(*update)
+				// a new revision is created for the image
+				parent.Status.MarkTargetReconciling("configuration", target.ConfigurationRef)
+				return ctrl.Result{}, nil
+
+			case target.Ref != nil:
+				// readiness is only followed for Knative targets
+				parent.Status.MarkTargetRevisionsNotUsed()
+				kind, ok := knativev1alpha1.LookupAdapterTargetKind(targetKinds, target.Ref)
+				if !ok {
+					parent.Status.MarkTargetKindNotAllowed(target.Ref.Kind)
+					return ctrl.Result{}, nil
+				}
+				imagePath, err := kind.ImagePath(&target)
+				if err != nil {
+					parent.Status.MarkTargetInvalid(strings.ToLower(kind.Kind), target.Ref.Name, err)
+					return ctrl.Result{}, nil
+				}
+				actualWorkload := &unstructured.Unstructured{}
+				actualWorkload.SetGroupVersionKind(kind.GroupVersionKind)
+				key := types.NamespacedName{Namespace: parent.Namespace, Name: target.Ref.Name}
+				// track workload for changes
+				c.Tracker.Track(
+					tracker.NewKey(kind.GroupVersionKind, key),
+					types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+				)
+				if err := c.Get(ctx, key, actualWorkload); err != nil {
+					if errors.IsNotFound(err) {
+						parent.Status.MarkTargetNotFound(strings.ToLower(kind.Kind), target.Ref.Name)
+						return ctrl.Result{}, nil
+					}
+					return ctrl.Result{}, err
+				}
+				image, found, err := imagePath.GetString(actualWorkload.Object)
+				if err == nil && !found {
+					err = fmt.Errorf("image field %s not found", imagePath)
+				}
+				if err != nil {
+					parent.Status.MarkTargetInvalid(strings.ToLower(kind.Kind), target.Ref.Name, err)
+					return ctrl.Result{}, nil
+				}
+				parent.Status.MarkTargetFound()
+
+				update, result := adapterTargetUpdate(parent, image, time.Now())
+				if update == nil {
+					// nothing to apply
+					return result, nil
+				}
 
+				// update workload
+				workload := actualWorkload.DeepCopy()
+				if err := imagePath.SetString(workload.Object, update.Image); err != nil {
+					return ctrl.Result{}, err
+				}
+				c.Log.Info("reconciling workload", "kind", kind.GroupVersionKind, "diff", cmp.Diff(actualWorkload.Object, workload.Object))
+				if err := c.Update(ctx, workload); err != nil {
+					return ctrl.Result{}, err
+				}
+				parent.Status.RecordUpdate(*update)
+				return ctrl.Result{}, nil
 			}
 
-			return fmt.Errorf("invalid adapter target")
+			return ctrl.Result{}, fmt.Errorf("invalid adapter target")
 		},
 
 		Config: c,
 		Setup: func(mgr controllers.Manager, bldr *controllers.Builder) error {
 			bldr.Watches(&source.Kind{Type: &servingv1.Service{}}, controllers.EnqueueTracked(&servingv1.Service{}, c.Tracker, c.Scheme))
 			bldr.Watches(&source.Kind{Type: &servingv1.Configuration{}}, controllers.EnqueueTracked(&servingv1.Configuration{}, c.Tracker, c.Scheme))
+			for _, kind := range targetKinds {
+				workload := &unstructured.Unstructured{}
+				workload.SetGroupVersionKind(kind.GroupVersionKind)
+				bldr.Watches(&source.Kind{Type: workload}, controllers.EnqueueTracked(workload, c.Tracker, c.Scheme))
+			}
 			return nil
 		},
 	}
 }
+
+// adapterTargetUpdate resolves the update to apply to a target running the
+// current image, or nil when the target is to keep its image. Reverts are
+// applied immediately, while new images wait for approval and a maintenance
+// window as required by the update policy. Without an update, the result
+// requeues the adapter for when the next maintenance window opens.
+func adapterTargetUpdate(parent *knativev1alpha1.Adapter, current string, now time.Time) (*knativev1alpha1.AdapterUpdate, ctrl.Result) {
+	status := &parent.Status
+	policy := parent.Spec.UpdatePolicy
+	latest := status.LatestImage
+	revertUnknown := false
+
+	if reverted := parent.Spec.RevertedImage; reverted != "" {
+		record := status.FindUpdate(reverted)
+		switch {
+		case record == nil || record.Revert || record.PreviousImage == "":
+			status.MarkUpdateRevertUnknown(reverted)
+			if latest == reverted {
+				return nil, ctrl.Result{}
+			}
+			revertUnknown = true
+		case current == reverted:
+			status.MarkUpdateReverted(reverted)
+			return &knativev1alpha1.AdapterUpdate{
+				PreviousImage: current,
+				Image:         record.PreviousImage,
+				Time:          apis.VolatileTime{Inner: metav1.NewTime(now)},
+				Approver:      parent.Spec.RevertedBy,
+				Revert:        true,
+			}, ctrl.Result{}
+		case latest == reverted:
+			// hold the previous image until a new image is available
+			status.MarkUpdateReverted(reverted)
+			return nil, ctrl.Result{}
+		}
+	}
+
+	if latest == current {
+		markAdapterUpdatesApplied(parent, revertUnknown)
+		return nil, ctrl.Result{}
+	}
+	update := &knativev1alpha1.AdapterUpdate{
+		PreviousImage: current,
+		Image:         latest,
+		Time:          apis.VolatileTime{Inner: metav1.NewTime(now)},
+	}
+	if policy != nil {
+		if policy.Approval == knativev1alpha1.AdapterApprovalManual {
+			if policy.ApprovedImage != latest {
+				status.MarkUpdateAwaitingApproval(latest)
+				return nil, ctrl.Result{}
+			}
+			update.Approver = policy.ApprovedBy
+		}
+		if !policy.MaintenanceWindowOpen(now) {
+			next := policy.NextMaintenanceWindow(now)
+			status.MarkUpdateAwaitingMaintenanceWindow(latest, next)
+			if !next.IsZero() {
+				return nil, ctrl.Result{RequeueAfter: next.Sub(now)}
+			}
+			return nil, ctrl.Result{}
+		}
+	}
+	markAdapterUpdatesApplied(parent, revertUnknown)
+	return update, ctrl.Result{}
+}
+
+func markAdapterUpdatesApplied(parent *knativev1alpha1.Adapter, revertUnknown bool) {
+	switch {
+	case revertUnknown:
+		// keep reporting the revert that was not applied
+		parent.Status.PendingImage = ""
+	case parent.Spec.UpdatePolicy != nil || parent.Spec.RevertedImage != "":
+		parent.Status.MarkUpdatesApplied()
+	default:
+		parent.Status.MarkUpdatesNotGated()
+	}
+}
@@ -19,13 +19,16 @@ package knative_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -46,10 +49,17 @@ func TestAdapterReconciler(t *testing.T) {
 	testImagePrefix := "example.com/repo"
 	testSha256 := "cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e"
 	testImage := fmt.Sprintf("%s/%s@sha256:%s", testImagePrefix, testName, testSha256)
+	testPreviousImage := fmt.Sprintf("%s/%s@sha256:%s", testImagePrefix, testName, "0000000000000000000000000000000000000000000000000000000000000000")
+	// a window opening half a day from now is closed
+	testClosed := time.Now().UTC().Add(12 * time.Hour)
+	testClosedSchedule := fmt.Sprintf("%d %d * * *", testClosed.Minute(), testClosed.Hour())
+	testClosedRequeue := ctrl.Result{RequeueAfter: time.Until(testClosed.Truncate(time.Minute))}
 
 	adapterConditionBuildReady := factories.Condition().Type(knativev1alpha1.AdapterConditionBuildReady)
 	adapterConditionReady := factories.Condition().Type(knativev1alpha1.AdapterConditionReady)
 	adapterConditionTargetFound := factories.Condition().Type(knativev1alpha1.AdapterConditionTargetFound)
+	adapterConditionTargetReady := factories.Condition().Type(knativev1alpha1.AdapterConditionTargetReady).Info()
+	adapterConditionUpdatesApplied := factories.Condition().Type(knativev1alpha1.AdapterConditionUpdatesApplied).Info()
 
 	scheme := runtime.NewScheme()
 	_ = clientgoscheme.AddToScheme(scheme)
@@ -74,6 +84,19 @@ func TestAdapterReconciler(t *testing.T) {
 		NamespaceName(testNamespace, "my-service").
 		UserContainer(nil)
 
+	testWorkloadKind := knativev1alpha1.NewAdapterTargetKind(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Workload"})
+	testDeployment := factories.Unstructured().
+		GroupVersionKind(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}).
+		NamespaceName(testNamespace, "my-deployment").
+		NestedField([]interface{}{
+			map[string]interface{}{"name": "sidecar", "image": "envoy"},
+			map[string]interface{}{"name": "app", "image": "busybox"},
+		}, "spec", "template", "spec", "containers")
+	testWorkload := factories.Unstructured().
+		GroupVersionKind(testWorkloadKind.GroupVersionKind).
+		NamespaceName(testNamespace, "my-workload").
+		NestedField("busybox", "spec", "image")
+
 	table := rtesting.Table{{
 		Name: "adapter does not exist",
 		Key:  testKey,
@@ -132,8 +155,12 @@ func TestAdapterReconciler(t *testing.T) {
 					adapterConditionBuildReady.True(),
 					adapterConditionReady.True(),
 					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.Unknown().Reason("Reconciling", `The service "my-service" is reconciling.`),
 				).
-				StatusLatestImage(testImage),
+				StatusLatestImage(testImage).
+				StatusUpdate(knativev1alpha1.AdapterUpdate{
+					Image: testImage,
+				}),
 		},
 	}, {
 		Name: "adapt application to service",
@@ -167,8 +194,12 @@ func TestAdapterReconciler(t *testing.T) {
 					adapterConditionBuildReady.True(),
 					adapterConditionReady.True(),
 					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.Unknown().Reason("Reconciling", `The service "my-service" is reconciling.`),
 				).
-				StatusLatestImage(testImage),
+				StatusLatestImage(testImage).
+				StatusUpdate(knativev1alpha1.AdapterUpdate{
+					Image: testImage,
+				}),
 		},
 	}, {
 		Name: "adapt application to service, application not ready",
@@ -195,6 +226,35 @@ func TestAdapterReconciler(t *testing.T) {
 					adapterConditionTargetFound.Unknown(),
 				),
 		},
+	}, {
+		Name: "adapt application to service, pinned build not found",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ApplicationRef(testApplication.Create().GetName()).
+				BuildPin(&buildv1alpha1.BuildPin{BuildNumber: 3}).
+				ServiceRef(testService.Create().GetName()),
+			testApplication.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testService,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testApplication, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.False().Reason("PinNotFound", "The pinned build number 3 does not match a build."),
+					adapterConditionReady.False().Reason("PinNotFound", "The pinned build number 3 does not match a build."),
+					adapterConditionTargetFound.Unknown(),
+				).
+				StatusAvailableImage(testImage),
+		},
 	}, {
 		Name: "adapt application to service, application not found",
 		Key:  testKey,
@@ -282,8 +342,12 @@ func TestAdapterReconciler(t *testing.T) {
 					adapterConditionBuildReady.True(),
 					adapterConditionReady.True(),
 					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.Unknown().Reason("Reconciling", `The service "my-service" is reconciling.`),
 				).
-				StatusLatestImage(testImage),
+				StatusLatestImage(testImage).
+				StatusUpdate(knativev1alpha1.AdapterUpdate{
+					Image: testImage,
+				}),
 		},
 	}, {
 		Name: "adapt function to service, function not ready",
@@ -397,8 +461,12 @@ func TestAdapterReconciler(t *testing.T) {
 					adapterConditionBuildReady.True(),
 					adapterConditionReady.True(),
 					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.Unknown().Reason("Reconciling", `The service "my-service" is reconciling.`),
 				).
-				StatusLatestImage(testImage),
+				StatusLatestImage(testImage).
+				StatusUpdate(knativev1alpha1.AdapterUpdate{
+					Image: testImage,
+				}),
 		},
 	}, {
 		Name: "adapt container to service, container not ready",
@@ -552,6 +620,7 @@ func TestAdapterReconciler(t *testing.T) {
 					adapterConditionBuildReady.True(),
 					adapterConditionReady.True(),
 					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.Unknown().Reason("Reconciling", `The service "my-service" is reconciling.`),
 				).
 				StatusLatestImage(testImage),
 			testContainer.
@@ -603,6 +672,7 @@ func TestAdapterReconciler(t *testing.T) {
 					// TODO the update failed, we should not be reporting as ready
 					adapterConditionReady.True(),
 					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.Unknown().Reason("Reconciling", `The service "my-service" is reconciling.`),
 				).
 				StatusLatestImage(testImage),
 		},
@@ -638,8 +708,12 @@ func TestAdapterReconciler(t *testing.T) {
 					adapterConditionBuildReady.True(),
 					adapterConditionReady.True(),
 					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.Unknown().Reason("Reconciling", `The configuration "my-configuration" is reconciling.`),
 				).
-				StatusLatestImage(testImage),
+				StatusLatestImage(testImage).
+				StatusUpdate(knativev1alpha1.AdapterUpdate{
+					Image: testImage,
+				}),
 		},
 	}, {
 		Name: "adapt container to configuration, configuration not found",
@@ -713,6 +787,7 @@ func TestAdapterReconciler(t *testing.T) {
 					adapterConditionBuildReady.True(),
 					adapterConditionReady.True(),
 					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.Unknown().Reason("Reconciling", `The configuration "my-configuration" is reconciling.`),
 				).
 				StatusLatestImage(testImage),
 			testContainer.
@@ -764,9 +839,944 @@ func TestAdapterReconciler(t *testing.T) {
 					// TODO the update failed, we should not be reporting as ready
 					adapterConditionReady.True(),
 					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.Unknown().Reason("Reconciling", `The configuration "my-configuration" is reconciling.`),
+				).
+				StatusLatestImage(testImage),
+		},
+	}, {
+		Name: "adapt container to deployment",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				Ref("apps", "Deployment", testDeployment.Create().GetName()),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testDeployment,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testDeployment, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			testDeployment.
+				NestedField([]interface{}{
+					map[string]interface{}{"name": "sidecar", "image": testImage},
+					map[string]interface{}{"name": "app", "image": "busybox"},
+				}, "spec", "template", "spec", "containers"),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.True(),
+					adapterConditionTargetFound.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusUpdate(knativev1alpha1.AdapterUpdate{
+					PreviousImage: "envoy",
+					Image:         testImage,
+				}),
+		},
+	}, {
+		Name: "adapt container to deployment, by container name",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				Ref("apps", "Deployment", testDeployment.Create().GetName()).
+				ContainerName("app"),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testDeployment,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testDeployment, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			testDeployment.
+				NestedField([]interface{}{
+					map[string]interface{}{"name": "sidecar", "image": "envoy"},
+					map[string]interface{}{"name": "app", "image": testImage},
+				}, "spec", "template", "spec", "containers"),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.True(),
+					adapterConditionTargetFound.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusUpdate(knativev1alpha1.AdapterUpdate{
+					PreviousImage: "busybox",
+					Image:         testImage,
+				}),
+		},
+	}, {
+		Name: "adapt container to deployment, container not found",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				Ref("apps", "Deployment", testDeployment.Create().GetName()).
+				ContainerName("missing"),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testDeployment,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testDeployment, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.False().Reason("Invalid", `The deployment "my-deployment" errored: image field .spec.template.spec.containers[?(@.name=="missing")].image not found`),
+					adapterConditionTargetFound.False().Reason("Invalid", `The deployment "my-deployment" errored: image field .spec.template.spec.containers[?(@.name=="missing")].image not found`),
+				).
+				StatusLatestImage(testImage),
+		},
+	}, {
+		Name: "adapt container to deployment, deployment not found",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				Ref("apps", "Deployment", testDeployment.Create().GetName()),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testDeployment, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.False().Reason("NotFound", `The deployment "my-deployment" was not found.`),
+					adapterConditionTargetFound.False().Reason("NotFound", `The deployment "my-deployment" was not found.`),
+				).
+				StatusLatestImage(testImage),
+		},
+	}, {
+		Name: "adapt container to deployment, deployment is up to date",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				Ref("apps", "Deployment", testDeployment.Create().GetName()).
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.True(),
+					adapterConditionTargetFound.True(),
+				).
+				StatusLatestImage(testImage),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testDeployment.
+				NestedField([]interface{}{
+					map[string]interface{}{"name": "sidecar", "image": testImage},
+					map[string]interface{}{"name": "app", "image": "busybox"},
+				}, "spec", "template", "spec", "containers"),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testDeployment, testAdapter, scheme),
+		},
+	}, {
+		Name: "adapt container to workload, by image path",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				Ref("example.com", "Workload", testWorkload.Create().GetName()).
+				ImagePath(".spec.image"),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testWorkload,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testWorkload, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			testWorkload.
+				NestedField(testImage, "spec", "image"),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.True(),
+					adapterConditionTargetFound.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusUpdate(knativev1alpha1.AdapterUpdate{
+					PreviousImage: "busybox",
+					Image:         testImage,
+				}),
+		},
+	}, {
+		Name: "adapt container to workload, update workload failed",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("update", "Workload"),
+		},
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				Ref("example.com", "Workload", testWorkload.Create().GetName()).
+				ImagePath(".spec.image"),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testWorkload,
+		},
+		ShouldErr: true,
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testWorkload, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			testWorkload.
+				NestedField(testImage, "spec", "image"),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					// TODO the update failed, we should not be reporting as ready
+					adapterConditionReady.True(),
+					adapterConditionTargetFound.True(),
+				).
+				StatusLatestImage(testImage),
+		},
+	}, {
+		Name: "adapt container to pod, kind not allowed",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				Ref("", "Pod", "my-pod"),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.False().Reason("NotAllowed", `The Pod kind is not an allowed target.`),
+					adapterConditionTargetFound.False().Reason("NotAllowed", `The Pod kind is not an allowed target.`),
 				).
 				StatusLatestImage(testImage),
 		},
+	}, {
+		Name: "adapt container to service, awaiting approval",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				ServiceRef(testService.Create().GetName()).
+				UpdatePolicy(knativev1alpha1.AdapterApprovalManual),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testService.
+				UserContainer(func(uc *corev1.Container) {
+					uc.Image = testPreviousImage
+				}),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testService, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.True(),
+					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.Unknown().Reason("Reconciling", `The service "my-service" is reconciling.`),
+					adapterConditionUpdatesApplied.Unknown().Reason("AwaitingApproval", fmt.Sprintf("Image %q is awaiting approval.", testImage)),
+				).
+				StatusLatestImage(testImage).
+				StatusPendingImage(testImage),
+		},
+	}, {
+		Name: "adapt container to service, approved",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				ServiceRef(testService.Create().GetName()).
+				UpdatePolicy(knativev1alpha1.AdapterApprovalManual).
+				ApprovedImage(testImage, "jane").
+				StatusPendingImage(testImage),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testService.
+				UserContainer(func(uc *corev1.Container) {
+					uc.Image = testPreviousImage
+				}),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testService, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			testService.
+				UserContainer(func(uc *corev1.Container) {
+					uc.Image = testImage
+				}),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.True(),
+					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.Unknown().Reason("Reconciling", `The service "my-service" is reconciling.`),
+					adapterConditionUpdatesApplied.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusUpdate(knativev1alpha1.AdapterUpdate{
+					PreviousImage: testPreviousImage,
+					Image:         testImage,
+					Approver:      "jane",
+				}),
+		},
+	}, {
+		Name: "adapt container to service, approved a different image",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				ServiceRef(testService.Create().GetName()).
+				UpdatePolicy(knativev1alpha1.AdapterApprovalManual).
+				ApprovedImage(testPreviousImage, "jane"),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testService.
+				UserContainer(func(uc *corev1.Container) {
+					uc.Image = testPreviousImage
+				}),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testService, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.True(),
+					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.Unknown().Reason("Reconciling", `The service "my-service" is reconciling.`),
+					adapterConditionUpdatesApplied.Unknown().Reason("AwaitingApproval", fmt.Sprintf("Image %q is awaiting approval.", testImage)),
+				).
+				StatusLatestImage(testImage).
+				StatusPendingImage(testImage),
+		},
+	}, {
+		Name: "adapt container to service, maintenance window open",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				ServiceRef(testService.Create().GetName()).
+				UpdatePolicy(knativev1alpha1.AdapterApprovalAutomatic).
+				MaintenanceWindow(testClosedSchedule, time.Minute).
+				MaintenanceWindow("* * * * *", time.Hour),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testService.
+				UserContainer(func(uc *corev1.Container) {
+					uc.Image = testPreviousImage
+				}),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testService, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			testService.
+				UserContainer(func(uc *corev1.Container) {
+					uc.Image = testImage
+				}),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.True(),
+					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.Unknown().Reason("Reconciling", `The service "my-service" is reconciling.`),
+					adapterConditionUpdatesApplied.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusUpdate(knativev1alpha1.AdapterUpdate{
+					PreviousImage: testPreviousImage,
+					Image:         testImage,
+				}),
+		},
+	}, {
+		Name: "adapt container to service, maintenance window closed",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				ServiceRef(testService.Create().GetName()).
+				UpdatePolicy(knativev1alpha1.AdapterApprovalAutomatic).
+				MaintenanceWindow(testClosedSchedule, time.Minute),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testService.
+				UserContainer(func(uc *corev1.Container) {
+					uc.Image = testPreviousImage
+				}),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testService, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.True(),
+					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.Unknown().Reason("Reconciling", `The service "my-service" is reconciling.`),
+					adapterConditionUpdatesApplied.Unknown().Reason("AwaitingMaintenanceWindow", fmt.Sprintf("Image %q is awaiting the maintenance window opening at %s.", testImage, testClosed.Truncate(time.Minute).Format(time.RFC3339))),
+				).
+				StatusLatestImage(testImage).
+				StatusPendingImage(testImage),
+		},
+		ExpectedResult: testClosedRequeue,
+	}, {
+		Name: "adapt container to service, approved awaiting maintenance window",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				ServiceRef(testService.Create().GetName()).
+				UpdatePolicy(knativev1alpha1.AdapterApprovalManual).
+				ApprovedImage(testImage, "jane").
+				MaintenanceWindow(testClosedSchedule, time.Minute),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testService.
+				UserContainer(func(uc *corev1.Container) {
+					uc.Image = testPreviousImage
+				}),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testService, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.True(),
+					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.Unknown().Reason("Reconciling", `The service "my-service" is reconciling.`),
+					adapterConditionUpdatesApplied.Unknown().Reason("AwaitingMaintenanceWindow", fmt.Sprintf("Image %q is awaiting the maintenance window opening at %s.", testImage, testClosed.Truncate(time.Minute).Format(time.RFC3339))),
+				).
+				StatusLatestImage(testImage).
+				StatusPendingImage(testImage),
+		},
+		ExpectedResult: testClosedRequeue,
+	}, {
+		Name: "adapt container to service, with update policy, service is up to date",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				ServiceRef(testService.Create().GetName()).
+				UpdatePolicy(knativev1alpha1.AdapterApprovalManual).
+				StatusPendingImage(testImage),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testService.
+				UserContainer(func(uc *corev1.Container) {
+					uc.Image = testImage
+				}),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testService, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.True(),
+					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.Unknown().Reason("Reconciling", `The service "my-service" is reconciling.`),
+					adapterConditionUpdatesApplied.True(),
+				).
+				StatusLatestImage(testImage),
+		},
+	}, {
+		Name: "adapt container to service, revert",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				ServiceRef(testService.Create().GetName()).
+				RevertedImage(testImage, "jane").
+				StatusUpdate(knativev1alpha1.AdapterUpdate{
+					PreviousImage: testPreviousImage,
+					Image:         testImage,
+				}),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testService.
+				UserContainer(func(uc *corev1.Container) {
+					uc.Image = testImage
+				}),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testService, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			testService.
+				UserContainer(func(uc *corev1.Container) {
+					uc.Image = testPreviousImage
+				}),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.True(),
+					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.Unknown().Reason("Reconciling", `The service "my-service" is reconciling.`),
+					adapterConditionUpdatesApplied.True().Reason("Reverted", fmt.Sprintf("Image %q was reverted.", testImage)),
+				).
+				StatusLatestImage(testImage).
+				StatusUpdate(knativev1alpha1.AdapterUpdate{
+					PreviousImage: testPreviousImage,
+					Image:         testImage,
+				}).
+				StatusUpdate(knativev1alpha1.AdapterUpdate{
+					PreviousImage: testImage,
+					Image:         testPreviousImage,
+					Approver:      "jane",
+					Revert:        true,
+				}),
+		},
+	}, {
+		Name: "adapt container to service, reverted image is held",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				ServiceRef(testService.Create().GetName()).
+				RevertedImage(testImage, "jane").
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.True(),
+					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.Unknown().Reason("Reconciling", `The service "my-service" is reconciling.`),
+					adapterConditionUpdatesApplied.True().Reason("Reverted", fmt.Sprintf("Image %q was reverted.", testImage)),
+				).
+				StatusLatestImage(testImage).
+				StatusUpdate(knativev1alpha1.AdapterUpdate{
+					PreviousImage: testPreviousImage,
+					Image:         testImage,
+				}).
+				StatusUpdate(knativev1alpha1.AdapterUpdate{
+					PreviousImage: testImage,
+					Image:         testPreviousImage,
+					Approver:      "jane",
+					Revert:        true,
+				}),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testService.
+				UserContainer(func(uc *corev1.Container) {
+					uc.Image = testPreviousImage
+				}),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testService, testAdapter, scheme),
+		},
+	}, {
+		Name: "adapt container to service, reverted image is not recorded",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				ServiceRef(testService.Create().GetName()).
+				RevertedImage(testImage, "jane"),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testService.
+				UserContainer(func(uc *corev1.Container) {
+					uc.Image = testPreviousImage
+				}),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testService, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.True(),
+					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.Unknown().Reason("Reconciling", `The service "my-service" is reconciling.`),
+					adapterConditionUpdatesApplied.False().Reason("RevertUnknown", fmt.Sprintf("Image %q was not applied by the adapter and cannot be reverted.", testImage)),
+				).
+				StatusLatestImage(testImage),
+		},
+	}, {
+		Name: "adapt container to service, newer image after revert",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				ServiceRef(testService.Create().GetName()).
+				RevertedImage(testPreviousImage, "jane").
+				StatusUpdate(knativev1alpha1.AdapterUpdate{
+					PreviousImage: "busybox",
+					Image:         testPreviousImage,
+				}),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testService,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testService, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			testService.
+				UserContainer(func(uc *corev1.Container) {
+					uc.Image = testImage
+				}),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.True(),
+					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.Unknown().Reason("Reconciling", `The service "my-service" is reconciling.`),
+					adapterConditionUpdatesApplied.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusUpdate(knativev1alpha1.AdapterUpdate{
+					PreviousImage: "busybox",
+					Image:         testPreviousImage,
+				}).
+				StatusUpdate(knativev1alpha1.AdapterUpdate{
+					Image: testImage,
+				}),
+		},
+	}, {
+		Name: "adapt container to service, revision is ready",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				ServiceRef(testService.Create().GetName()),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testService.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Generation(2)
+				}).
+				UserContainer(func(uc *corev1.Container) {
+					uc.Image = testImage
+				}).
+				StatusObservedGeneration(2).
+				StatusLatestCreatedRevisionName("my-service-00002").
+				StatusLatestReadyRevisionName("my-service-00002").
+				StatusReady(),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testService, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.True(),
+					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusTargetRevisionName("my-service-00002"),
+		},
+	}, {
+		Name: "adapt container to service, revision is not ready",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				ServiceRef(testService.Create().GetName()).
+				StatusTargetRevisionName("my-service-00001"),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testService.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Generation(2)
+				}).
+				UserContainer(func(uc *corev1.Container) {
+					uc.Image = testImage
+				}).
+				StatusObservedGeneration(2).
+				StatusLatestCreatedRevisionName("my-service-00002").
+				StatusLatestReadyRevisionName("my-service-00001").
+				StatusConditions(
+					factories.Condition().Type(knativeservingv1.ServiceConditionReady).Unknown(),
+				),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testService, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.True(),
+					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.Unknown().Reason("RevisionNotReady", `The revision "my-service-00002" is not ready.`),
+				).
+				StatusLatestImage(testImage),
+		},
+	}, {
+		Name: "adapt container to service, revision failed",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				ServiceRef(testService.Create().GetName()),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testService.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Generation(2)
+				}).
+				UserContainer(func(uc *corev1.Container) {
+					uc.Image = testImage
+				}).
+				StatusObservedGeneration(2).
+				StatusLatestCreatedRevisionName("my-service-00002").
+				StatusLatestReadyRevisionName("my-service-00001").
+				StatusConditions(
+					factories.Condition().Type(knativeservingv1.ServiceConditionReady).False().Reason("RevisionFailed", `Revision "my-service-00002" failed with message: Container failed to start.`),
+				),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testService, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.True(),
+					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.False().Reason("RevisionFailed", `Revision "my-service-00002" failed with message: Container failed to start.`),
+				).
+				StatusLatestImage(testImage),
+		},
+	}, {
+		Name: "adapt container to service, generation not observed",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				ServiceRef(testService.Create().GetName()),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testService.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Generation(3)
+				}).
+				UserContainer(func(uc *corev1.Container) {
+					uc.Image = testImage
+				}).
+				StatusObservedGeneration(2).
+				StatusLatestCreatedRevisionName("my-service-00002").
+				StatusLatestReadyRevisionName("my-service-00002").
+				StatusReady(),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testService, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.True(),
+					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.Unknown().Reason("Reconciling", `The service "my-service" is reconciling.`),
+				).
+				StatusLatestImage(testImage),
+		},
+	}, {
+		Name: "adapt container to configuration, revision is ready",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testAdapter.
+				ContainerRef(testContainer.Create().GetName()).
+				ConfigurationRef(testConfiguration.Create().GetName()),
+			testContainer.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testConfiguration.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Generation(2)
+				}).
+				UserContainer(func(uc *corev1.Container) {
+					uc.Image = testImage
+				}).
+				StatusObservedGeneration(2).
+				StatusLatestCreatedRevisionName("my-configuration-00002").
+				StatusLatestReadyRevisionName("my-configuration-00002").
+				StatusReady(),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
+			rtesting.NewTrackRequest(testConfiguration, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.True(),
+					adapterConditionTargetFound.True(),
+					adapterConditionTargetReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusTargetRevisionName("my-configuration-00002"),
+		},
 	}}
 
 	table.Test(t, scheme, func(t *testing.T, row *rtesting.Testcase, client client.Client, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) reconcile.Reconciler {
@@ -778,6 +1788,7 @@ func TestAdapterReconciler(t *testing.T) {
 				Scheme:   scheme,
 				Tracker:  tracker,
 			},
+			append(knativev1alpha1.DefaultAdapterTargetKinds, testWorkloadKind),
 		)
 	})
 }
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -32,15 +33,25 @@ import (
 	"github.com/projectriff/system/pkg/controllers"
 	"github.com/projectriff/system/pkg/refs"
 	"github.com/projectriff/system/pkg/tracker"
+	"github.com/projectriff/system/pkg/validation"
 )
 
 // +kubebuilder:rbac:groups=knative.projectriff.io,resources=deployers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=knative.projectriff.io,resources=deployers/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=build.projectriff.io,resources=applications;containers;functions,verbs=get;list;watch
-// +kubebuilder:rbac:groups=serving.knative.dev,resources=configurations;routes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=serving.knative.dev,resources=configurations;domainmappings;routes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;update;patch;delete
 
-func DeployerReconciler(c controllers.Config) *controllers.ParentReconciler {
+// OptionalAPIs are the APIs installed in the cluster that deployers may use
+// when available.
+type OptionalAPIs struct {
+	// DomainMapping maps custom domains to deployers.
+	DomainMapping bool
+}
+
+func DeployerReconciler(c controllers.Config, apis OptionalAPIs, networkPolicy controllers.NetworkPolicyConfig) *controllers.ParentReconciler {
 	c.Log = c.Log.WithName("Deployer")
 
 	return &controllers.ParentReconciler{
@@ -48,7 +59,10 @@ func DeployerReconciler(c controllers.Config) *controllers.ParentReconciler {
 		SubReconcilers: []controllers.SubReconciler{
 			DeployerBuildRefReconciler(c),
 			DeployerChildConfigurationReconciler(c),
+			DeployerTrafficProgressionReconciler(c),
 			DeployerChildRouteReconciler(c),
+			DeployerDomainMappingsReconciler(c, apis.DomainMapping),
+			DeployerChildNetworkPolicyReconciler(c, networkPolicy),
 		},
 
 		Config: c,
@@ -60,73 +74,20 @@ func DeployerBuildRefReconciler(c controllers.Config) controllers.SubReconciler
 
 	return &controllers.SyncReconciler{
 		Sync: func(ctx context.Context, parent *knativev1alpha1.Deployer) error {
-			build := parent.Spec.Build
-			if build == nil {
-				parent.Status.LatestImage = parent.Spec.Template.Spec.Containers[0].Image
-				return nil
+			previous := parent.Status.LatestImage
+			if err := resolveDeployerImage(ctx, c, parent); err != nil {
+				return err
 			}
-
-			switch {
-			case build.ApplicationRef != "":
-				var application buildv1alpha1.Application
-				key := types.NamespacedName{Namespace: parent.Namespace, Name: build.ApplicationRef}
-				// track application for new images
-				c.Tracker.Track(
-					tracker.NewKey(application.GetGroupVersionKind(), key),
-					types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
-				)
-				if err := c.Get(ctx, key, &application); err != nil {
-					if apierrs.IsNotFound(err) {
-						return nil
-					}
-					return err
-				}
-				if application.Status.LatestImage != "" {
-					parent.Status.LatestImage = application.Status.LatestImage
-				}
-				return nil
-
-			case build.ContainerRef != "":
-				var container buildv1alpha1.Container
-				key := types.NamespacedName{Namespace: parent.Namespace, Name: build.ContainerRef}
-				// track container for new images
-				c.Tracker.Track(
-					tracker.NewKey(container.GetGroupVersionKind(), key),
-					types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
-				)
-				if err := c.Get(ctx, key, &container); err != nil {
-					if apierrs.IsNotFound(err) {
-						return nil
-					}
-					return err
-				}
-				if container.Status.LatestImage != "" {
-					parent.Status.LatestImage = container.Status.LatestImage
+			if image := parent.Status.LatestImage; image != "" {
+				if err := validation.ActiveImagePolicy().CheckImage(image); err != nil {
+					// keep rolling out the previous image
+					parent.Status.LatestImage = previous
+					parent.Status.MarkImageNotAllowed(err.Error())
+					return nil
 				}
-				return nil
-
-			case build.FunctionRef != "":
-				var function buildv1alpha1.Function
-				key := types.NamespacedName{Namespace: parent.Namespace, Name: build.FunctionRef}
-				// track function for new images
-				c.Tracker.Track(
-					tracker.NewKey(function.GetGroupVersionKind(), key),
-					types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
-				)
-				if err := c.Get(ctx, key, &function); err != nil {
-					if apierrs.IsNotFound(err) {
-						return nil
-					}
-					return err
-				}
-				if function.Status.LatestImage != "" {
-					parent.Status.LatestImage = function.Status.LatestImage
-				}
-				return nil
-
 			}
-
-			return fmt.Errorf("invalid build")
+			parent.Status.MarkImageAllowed()
+			return nil
 		},
 
 		Config: c,
@@ -139,6 +100,74 @@ func DeployerBuildRefReconciler(c controllers.Config) controllers.SubReconciler
 	}
 }
 
+// resolveDeployerImage resolves the latest image for the deployer from the
+// template or the referenced build resource.
+func resolveDeployerImage(ctx context.Context, c controllers.Config, parent *knativev1alpha1.Deployer) error {
+	build := parent.Spec.Build
+	if build == nil {
+		parent.Status.LatestImage = parent.Spec.Template.Spec.Containers[0].Image
+		parent.Status.PinnedBuild = nil
+		parent.Status.AvailableImage = ""
+		return nil
+	}
+
+	switch {
+	case build.ApplicationRef != "":
+		var application buildv1alpha1.Application
+		key := types.NamespacedName{Namespace: parent.Namespace, Name: build.ApplicationRef}
+		// track application for new images
+		c.Tracker.Track(
+			tracker.NewKey(application.GetGroupVersionKind(), key),
+			types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+		)
+		if err := c.Get(ctx, key, &application); err != nil {
+			if apierrs.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		parent.Status.PropagateBuildStatus(build.Pin, &application.Status.BuildStatus)
+		return nil
+
+	case build.ContainerRef != "":
+		var container buildv1alpha1.Container
+		key := types.NamespacedName{Namespace: parent.Namespace, Name: build.ContainerRef}
+		// track container for new images
+		c.Tracker.Track(
+			tracker.NewKey(container.GetGroupVersionKind(), key),
+			types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+		)
+		if err := c.Get(ctx, key, &container); err != nil {
+			if apierrs.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		parent.Status.PropagateBuildStatus(build.Pin, &container.Status.BuildStatus)
+		return nil
+
+	case build.FunctionRef != "":
+		var function buildv1alpha1.Function
+		key := types.NamespacedName{Namespace: parent.Namespace, Name: build.FunctionRef}
+		// track function for new images
+		c.Tracker.Track(
+			tracker.NewKey(function.GetGroupVersionKind(), key),
+			types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+		)
+		if err := c.Get(ctx, key, &function); err != nil {
+			if apierrs.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		parent.Status.PropagateBuildStatus(build.Pin, &function.Status.BuildStatus)
+		return nil
+
+	}
+
+	return fmt.Errorf("invalid build")
+}
+
 func DeployerChildConfigurationReconciler(c controllers.Config) controllers.SubReconciler {
 	c.Log = c.Log.WithName("ChildConfiguration")
 
@@ -155,13 +184,7 @@ func DeployerChildConfigurationReconciler(c controllers.Config) controllers.SubR
 			labels := controllers.MergeMaps(parent.Labels, map[string]string{
 				knativev1alpha1.DeployerLabelKey: parent.Name,
 			})
-			annotations := map[string]string{}
-			if parent.Spec.Scale.Min != nil {
-				annotations["autoscaling.knative.dev/minScale"] = fmt.Sprintf("%d", *parent.Spec.Scale.Min)
-			}
-			if parent.Spec.Scale.Max != nil {
-				annotations["autoscaling.knative.dev/maxScale"] = fmt.Sprintf("%d", *parent.Spec.Scale.Max)
-			}
+			annotations := parent.Spec.Scale.Annotations()
 
 			template := parent.Spec.Template.DeepCopy()
 			template.Annotations = controllers.MergeMaps(annotations, template.Annotations)
@@ -178,7 +201,7 @@ func DeployerChildConfigurationReconciler(c controllers.Config) controllers.SubR
 					Template: servingv1.RevisionTemplateSpec{
 						ObjectMeta: metav1.ObjectMeta{
 							Labels:      labels,
-							Annotations: annotations,
+							Annotations: template.Annotations,
 						},
 						Spec: servingv1.RevisionSpec{
 							PodSpec:              template.Spec,
@@ -242,7 +265,6 @@ func DeployerChildRouteReconciler(c controllers.Config) controllers.SubReconcile
 			if parent.Spec.IngressPolicy == knativev1alpha1.IngressPolicyClusterLocal {
 				labels["serving.knative.dev/visibility"] = "cluster-local"
 			}
-			var allTraffic int64 = 100
 
 			child := &servingv1.Route{
 				ObjectMeta: metav1.ObjectMeta{
@@ -252,12 +274,7 @@ func DeployerChildRouteReconciler(c controllers.Config) controllers.SubReconcile
 					Name:        parent.Name,
 				},
 				Spec: servingv1.RouteSpec{
-					Traffic: []servingv1.TrafficTarget{
-						{
-							Percent:           &allTraffic,
-							ConfigurationName: parent.Status.ConfigurationRef.Name,
-						},
-					},
+					Traffic: deployerRouteTraffic(parent),
 				},
 			}
 
@@ -294,3 +311,70 @@ func DeployerChildRouteReconciler(c controllers.Config) controllers.SubReconcile
 		},
 	}
 }
+
+// DeployerChildNetworkPolicyReconciler restricts network traffic to the
+// deployer's revision pods according to the ingress policy. Requests reach
+// the pods through the Knative gateways and activator, the namespaces of
+// these components must be admitted by the configured namespace selectors.
+func DeployerChildNetworkPolicyReconciler(c controllers.Config, networkPolicy controllers.NetworkPolicyConfig) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ChildNetworkPolicy")
+
+	return &controllers.ChildReconciler{
+		ParentType:    &knativev1alpha1.Deployer{},
+		ChildType:     &networkingv1.NetworkPolicy{},
+		ChildListType: &networkingv1.NetworkPolicyList{},
+
+		DesiredChild: func(parent *knativev1alpha1.Deployer) (*networkingv1.NetworkPolicy, error) {
+			if !networkPolicy.Enabled {
+				return nil, nil
+			}
+
+			child := &networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: controllers.MergeMaps(parent.Labels, map[string]string{
+						knativev1alpha1.DeployerLabelKey: parent.Name,
+					}),
+					GenerateName: fmt.Sprintf("%s-deployer-", parent.Name),
+					Namespace:    parent.Namespace,
+				},
+				Spec: networkingv1.NetworkPolicySpec{
+					PodSelector: metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							knativev1alpha1.DeployerLabelKey: parent.Name,
+						},
+					},
+					Ingress:     networkPolicy.IngressRules(parent.Spec.IngressPolicy == knativev1alpha1.IngressPolicyExternal),
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+				},
+			}
+
+			return child, nil
+		},
+		ReflectChildStatusOnParent: func(parent *knativev1alpha1.Deployer, child *networkingv1.NetworkPolicy, err error) {
+			if err != nil {
+				return
+			}
+			if child == nil {
+				parent.Status.NetworkPolicyRef = nil
+				parent.Status.MarkNetworkPolicyNotUsed()
+			} else {
+				parent.Status.NetworkPolicyRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
+				parent.Status.MarkNetworkPolicyReady()
+			}
+		},
+		MergeBeforeUpdate: func(current, desired *networkingv1.NetworkPolicy) {
+			current.Labels = desired.Labels
+			current.Spec = desired.Spec
+		},
+		SemanticEquals: func(a1, a2 *networkingv1.NetworkPolicy) bool {
+			return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+		},
+
+		Config:     c,
+		IndexField: ".metadata.networkPolicyController",
+		Sanitize: func(child *networkingv1.NetworkPolicy) interface{} {
+			return child.Spec
+		},
+	}
+}
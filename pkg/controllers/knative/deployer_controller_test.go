@@ -19,10 +19,12 @@ package knative_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
@@ -34,6 +36,7 @@ import (
 	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	knativev1alpha1 "github.com/projectriff/system/pkg/apis/knative/v1alpha1"
 	knativeservingv1 "github.com/projectriff/system/pkg/apis/thirdparty/knative/serving/v1"
+	knativeservingv1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/knative/serving/v1alpha1"
 	"github.com/projectriff/system/pkg/controllers"
 	"github.com/projectriff/system/pkg/controllers/knative"
 	rtesting "github.com/projectriff/system/pkg/controllers/testing"
@@ -60,6 +63,7 @@ func TestDeployerReconciler(t *testing.T) {
 	_ = buildv1alpha1.AddToScheme(scheme)
 	_ = knativev1alpha1.AddToScheme(scheme)
 	_ = knativeservingv1.AddToScheme(scheme)
+	_ = knativeservingv1alpha1.AddToScheme(scheme)
 
 	testDeployer := factories.DeployerKnative().
 		NamespaceName(testNamespace, testName)
@@ -1073,6 +1077,105 @@ func TestDeployerReconciler(t *testing.T) {
 				StatusConfigurationRef(testConfigurationGiven.Create().GetName()).
 				StatusRouteRef(testRouteGiven.Create().GetName()),
 		},
+	}, {
+		Name: "update knative resources, with autoscaler",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testDeployer.
+				Image(testImage).
+				Scale(knativev1alpha1.Scale{
+					Initial:                     rtesting.Int32Ptr(0),
+					Class:                       knativev1alpha1.AutoscalerClassKPA,
+					Metric:                      knativev1alpha1.AutoscalerMetricRPS,
+					Target:                      rtesting.Int32Ptr(150),
+					TargetUtilizationPercentage: rtesting.Int32Ptr(70),
+					Window:                      &metav1.Duration{Duration: time.Minute},
+					PanicWindowPercentage:       rtesting.Int32Ptr(10),
+					ScaleDownDelay:              &metav1.Duration{Duration: 15 * time.Minute},
+				}),
+			testConfigurationGiven,
+			testRouteGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Configuration "%s"`, testConfigurationGiven.Create().GetName()),
+			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			testConfigurationGiven.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation("autoscaling.knative.dev/initialScale", "0")
+					om.AddAnnotation("autoscaling.knative.dev/class", "kpa.autoscaling.knative.dev")
+					om.AddAnnotation("autoscaling.knative.dev/metric", "rps")
+					om.AddAnnotation("autoscaling.knative.dev/target", "150")
+					om.AddAnnotation("autoscaling.knative.dev/targetUtilizationPercentage", "70")
+					om.AddAnnotation("autoscaling.knative.dev/window", "1m0s")
+					om.AddAnnotation("autoscaling.knative.dev/panicWindowPercentage", "10")
+					om.AddAnnotation("autoscaling.knative.dev/scaleDownDelay", "15m0s")
+				}).
+				PodTemplateSpec(func(pts factories.PodTemplateSpec) {
+					pts.AddAnnotation("autoscaling.knative.dev/initialScale", "0")
+					pts.AddAnnotation("autoscaling.knative.dev/class", "kpa.autoscaling.knative.dev")
+					pts.AddAnnotation("autoscaling.knative.dev/metric", "rps")
+					pts.AddAnnotation("autoscaling.knative.dev/target", "150")
+					pts.AddAnnotation("autoscaling.knative.dev/targetUtilizationPercentage", "70")
+					pts.AddAnnotation("autoscaling.knative.dev/window", "1m0s")
+					pts.AddAnnotation("autoscaling.knative.dev/panicWindowPercentage", "10")
+					pts.AddAnnotation("autoscaling.knative.dev/scaleDownDelay", "15m0s")
+				}),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testDeployer.
+				StatusConditions(
+					deployerConditionConfigurationReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRouteReady.Unknown(),
+				).
+				StatusLatestImage(testImage).
+				StatusConfigurationRef(testConfigurationGiven.Create().GetName()).
+				StatusRouteRef(testRouteGiven.Create().GetName()),
+		},
+	}, {
+		Name: "update knative resources, with template annotations",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testDeployer.
+				Image(testImage).
+				MinScale(1).
+				PodTemplateSpec(func(pts factories.PodTemplateSpec) {
+					pts.AddAnnotation("autoscaling.knative.dev/panicThresholdPercentage", "400")
+				}),
+			testConfigurationGiven,
+			testRouteGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Configuration "%s"`, testConfigurationGiven.Create().GetName()),
+			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			testConfigurationGiven.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation("autoscaling.knative.dev/minScale", "1")
+				}).
+				PodTemplateSpec(func(pts factories.PodTemplateSpec) {
+					pts.AddAnnotation("autoscaling.knative.dev/minScale", "1")
+					pts.AddAnnotation("autoscaling.knative.dev/panicThresholdPercentage", "400")
+				}),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testDeployer.
+				StatusConditions(
+					deployerConditionConfigurationReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRouteReady.Unknown(),
+				).
+				StatusLatestImage(testImage).
+				StatusConfigurationRef(testConfigurationGiven.Create().GetName()).
+				StatusRouteRef(testRouteGiven.Create().GetName()),
+		},
 	}, {
 		Name: "ready",
 		Key:  testKey,
@@ -1182,6 +1285,149 @@ func TestDeployerReconciler(t *testing.T) {
 				Scheme:   scheme,
 				Tracker:  tracker,
 			},
+			knative.OptionalAPIs{DomainMapping: true},
+			controllers.NetworkPolicyConfig{},
 		)
 	})
 }
+
+func TestDeployerChildNetworkPolicyReconciler(t *testing.T) {
+	testNamespace := "test-namespace"
+	testName := "test-deployer"
+	testKey := types.NamespacedName{Namespace: testNamespace, Name: testName}
+	testImage := "example.com/repo@sha256:cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e"
+
+	gatewaySelector := metav1.LabelSelector{MatchLabels: map[string]string{"serving.knative.dev/release": "devel"}}
+	ingressSelector := metav1.LabelSelector{MatchLabels: map[string]string{"app.kubernetes.io/name": "ingress-gateway"}}
+	networkPolicyConfig := controllers.NetworkPolicyConfig{
+		Enabled:                   true,
+		NamespaceSelectors:        []metav1.LabelSelector{gatewaySelector},
+		IngressNamespaceSelectors: []metav1.LabelSelector{ingressSelector},
+	}
+
+	deployerConditionConfigurationReady := factories.Condition().Type(knativev1alpha1.DeployerConditionConfigurationReady)
+	deployerConditionNetworkPolicyReady := factories.Condition().Type(knativev1alpha1.DeployerConditionNetworkPolicyReady)
+	deployerConditionReady := factories.Condition().Type(knativev1alpha1.DeployerConditionReady)
+	deployerConditionRouteReady := factories.Condition().Type(knativev1alpha1.DeployerConditionRouteReady)
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = knativev1alpha1.AddToScheme(scheme)
+
+	testDeployer := factories.DeployerKnative().
+		NamespaceName(testNamespace, testName)
+	deployerClusterLocal := testDeployer.
+		Image(testImage).
+		IngressPolicy(knativev1alpha1.IngressPolicyClusterLocal)
+	deployerExternal := testDeployer.
+		Image(testImage).
+		IngressPolicy(knativev1alpha1.IngressPolicyExternal)
+
+	networkPolicyCreate := factories.NetworkPolicy().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.GenerateName("%s-deployer-", testName)
+			om.AddLabel(knativev1alpha1.DeployerLabelKey, testName)
+			om.ControlledBy(testDeployer, scheme)
+		}).
+		AddPodSelectorLabel(knativev1alpha1.DeployerLabelKey, testName).
+		IngressFrom(gatewaySelector)
+	networkPolicyGiven := networkPolicyCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Name("%s%s", om.Create().GenerateName, "000")
+			om.Created(1)
+		})
+
+	table := rtesting.Table{{
+		Name: "create network policy, cluster local",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerClusterLocal,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "Created",
+				`Created NetworkPolicy "%s-deployer-001"`, testName),
+			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			networkPolicyCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testDeployer.
+				StatusConditions(
+					deployerConditionConfigurationReady.Unknown(),
+					deployerConditionNetworkPolicyReady.True().Info(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRouteReady.Unknown(),
+				).
+				StatusNetworkPolicyRef("%s-deployer-001", testName),
+		},
+	}, {
+		Name: "create network policy, external admits ingress gateways",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerExternal,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "Created",
+				`Created NetworkPolicy "%s-deployer-001"`, testName),
+			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			networkPolicyCreate.
+				IngressFrom(gatewaySelector, ingressSelector),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testDeployer.
+				StatusConditions(
+					deployerConditionConfigurationReady.Unknown(),
+					deployerConditionNetworkPolicyReady.True().Info(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRouteReady.Unknown(),
+				).
+				StatusNetworkPolicyRef("%s-deployer-001", testName),
+		},
+	}, {
+		Name: "update network policy, ingress policy changed",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerClusterLocal.
+				StatusConditions(
+					deployerConditionConfigurationReady.Unknown(),
+					deployerConditionNetworkPolicyReady.True().Info(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRouteReady.Unknown(),
+				).
+				StatusNetworkPolicyRef("%s-deployer-000", testName),
+			networkPolicyGiven.
+				IngressFrom(gatewaySelector, ingressSelector),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated NetworkPolicy "%s-deployer-000"`, testName),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			networkPolicyGiven,
+		},
+	}}
+
+	table.Test(t, scheme, func(t *testing.T, row *rtesting.Testcase, client client.Client, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) reconcile.Reconciler {
+		c := controllers.Config{
+			Client:   client,
+			Recorder: recorder,
+			Log:      log,
+			Scheme:   scheme,
+			Tracker:  tracker,
+		}
+		return &controllers.ParentReconciler{
+			Type: &knativev1alpha1.Deployer{},
+			SubReconcilers: []controllers.SubReconciler{
+				knative.DeployerChildNetworkPolicyReconciler(c, networkPolicyConfig),
+			},
+
+			Config: c,
+		}
+	})
+}
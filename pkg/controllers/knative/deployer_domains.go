@@ -0,0 +1,158 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package knative
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	knativev1alpha1 "github.com/projectriff/system/pkg/apis/knative/v1alpha1"
+	servingv1 "github.com/projectriff/system/pkg/apis/thirdparty/knative/serving/v1"
+	servingv1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/knative/serving/v1alpha1"
+	"github.com/projectriff/system/pkg/controllers"
+)
+
+// DeployerDomainMappingsReconciler maps custom domains to deployers. Without
+// Knative DomainMappings installed, deployers with domains are marked as not
+// supported.
+func DeployerDomainMappingsReconciler(c controllers.Config, domainMapping bool) controllers.SubReconciler {
+	if domainMapping {
+		return DeployerChildDomainMappingsReconciler(c)
+	}
+
+	c.Log = c.Log.WithName("DomainMappings")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *knativev1alpha1.Deployer) error {
+			if len(parent.Spec.Domains) == 0 {
+				parent.Status.MarkDomainMappingsNotUsed()
+			} else {
+				parent.Status.MarkDomainMappingsNotSupported()
+			}
+			return nil
+		},
+
+		Config: c,
+	}
+}
+
+// DeployerChildDomainMappingsReconciler maps each custom domain to the
+// deployer's route with a Knative DomainMapping. A DomainMapping is named
+// after its domain, mappings that exist for a domain but are not controlled by
+// the deployer are left untouched.
+func DeployerChildDomainMappingsReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ChildDomainMappings")
+
+	return &controllers.ChildSetReconciler{
+		ParentType:    &knativev1alpha1.Deployer{},
+		ChildType:     &servingv1alpha1.DomainMapping{},
+		ChildListType: &servingv1alpha1.DomainMappingList{},
+
+		DesiredChildren: func(parent *knativev1alpha1.Deployer) (map[string]*servingv1alpha1.DomainMapping, error) {
+			children := map[string]*servingv1alpha1.DomainMapping{}
+			for _, domain := range parent.Spec.Domains {
+				if parent.Status.RouteRef == nil {
+					// keep existing mappings until the route is restored
+					children[domainMappingKey(domain.Name)] = nil
+					continue
+				}
+				children[domainMappingKey(domain.Name)] = newDomainMapping(parent, domain)
+			}
+			return children, nil
+		},
+		ReflectChildrenStatusOnParent: func(parent *knativev1alpha1.Deployer, children map[string]*servingv1alpha1.DomainMapping, err error) {
+			if len(parent.Spec.Domains) == 0 {
+				parent.Status.MarkDomainMappingsNotUsed()
+				return
+			}
+			if parent.Status.RouteRef == nil {
+				parent.Status.MarkDomainMappingsRouteMissing()
+				return
+			}
+			reconciled := []servingv1alpha1.DomainMapping{}
+			for _, domain := range parent.Spec.Domains {
+				if child, ok := children[domainMappingKey(domain.Name)]; ok {
+					reconciled = append(reconciled, *child)
+				}
+			}
+			parent.Status.PropagateDomainMappingStatuses(reconciled)
+			if apierrs.IsAlreadyExists(err) {
+				name := err.(apierrs.APIStatus).Status().Details.Name
+				parent.Status.MarkDomainMappingNotOwned(name)
+			}
+		},
+		MergeBeforeUpdate: func(current, desired *servingv1alpha1.DomainMapping) {
+			current.Labels = desired.Labels
+			current.Spec = desired.Spec
+		},
+		SemanticEquals: func(a1, a2 *servingv1alpha1.DomainMapping) bool {
+			return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+		},
+		OurChild: func(child *servingv1alpha1.DomainMapping) bool {
+			// mappings are reconciled by the domain they are keyed with
+			return child.Labels[knativev1alpha1.DomainLabelKey] != ""
+		},
+
+		Config:           c,
+		IndexField:       ".metadata.domainMappingController",
+		IdentityLabelKey: knativev1alpha1.DomainLabelKey,
+		Sanitize: func(child *servingv1alpha1.DomainMapping) interface{} {
+			return child.Spec
+		},
+	}
+}
+
+// domainMappingKey returns the key of the DomainMapping for a domain. Domains
+// that are not valid label values are keyed by their hash.
+func domainMappingKey(domain string) string {
+	if len(validation.IsValidLabelValue(domain)) == 0 {
+		return domain
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(domain)))[:32]
+}
+
+func newDomainMapping(parent *knativev1alpha1.Deployer, domain knativev1alpha1.Domain) *servingv1alpha1.DomainMapping {
+	mapping := &servingv1alpha1.DomainMapping{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: controllers.MergeMaps(parent.Labels, map[string]string{
+				knativev1alpha1.DeployerLabelKey: parent.Name,
+			}),
+			Namespace: parent.Namespace,
+			Name:      domain.Name,
+		},
+		Spec: servingv1alpha1.DomainMappingSpec{
+			Ref: servingv1alpha1.KReference{
+				APIVersion: servingv1.GroupVersion.String(),
+				Kind:       "Route",
+				Name:       parent.Status.RouteRef.Name,
+			},
+		},
+	}
+	if domain.SecretRef != "" {
+		mapping.Spec.TLS = &servingv1alpha1.SecretTLS{
+			SecretName: domain.SecretRef,
+		}
+	}
+	return mapping
+}
@@ -0,0 +1,449 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package knative_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	knativev1alpha1 "github.com/projectriff/system/pkg/apis/knative/v1alpha1"
+	knativeservingv1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/knative/serving/v1alpha1"
+	"github.com/projectriff/system/pkg/controllers"
+	knativecontrollers "github.com/projectriff/system/pkg/controllers/knative"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+	"github.com/projectriff/system/pkg/controllers/testing/factories"
+	"github.com/projectriff/system/pkg/tracker"
+)
+
+func TestDeployerDomainMappings(t *testing.T) {
+	testNamespace := "test-namespace"
+	testName := "test-deployer"
+	testKey := types.NamespacedName{Namespace: testNamespace, Name: testName}
+	testDomain := "example.com"
+	testOtherDomain := "www.example.com"
+	// longer than a label value
+	testLongDomain := strings.Repeat("a", 56) + ".example.com"
+
+	deployerConditionConfigurationReady := factories.Condition().Type(knativev1alpha1.DeployerConditionConfigurationReady)
+	deployerConditionDomainMappingsReady := factories.Condition().Type(knativev1alpha1.DeployerConditionDomainMappingsReady).Info()
+	deployerConditionReady := factories.Condition().Type(knativev1alpha1.DeployerConditionReady)
+	deployerConditionRouteReady := factories.Condition().Type(knativev1alpha1.DeployerConditionRouteReady)
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = knativev1alpha1.AddToScheme(scheme)
+	_ = knativeservingv1alpha1.AddToScheme(scheme)
+
+	deployerMinimal := factories.DeployerKnative().
+		NamespaceName(testNamespace, testName)
+	deployerRouteMissing := deployerMinimal.
+		IngressPolicy(knativev1alpha1.IngressPolicyExternal).
+		StatusConditions(
+			deployerConditionConfigurationReady.Unknown(),
+			deployerConditionReady.Unknown(),
+			deployerConditionRouteReady.Unknown(),
+		)
+	deployer := deployerRouteMissing.
+		StatusRouteRef(testName)
+	deployerDomains := deployer.
+		Domains(
+			knativev1alpha1.Domain{Name: testDomain},
+			knativev1alpha1.Domain{Name: testOtherDomain, SecretRef: "example-tls"},
+		)
+
+	mappingCreate := factories.KnativeDomainMapping().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.Name(testDomain)
+			om.ControlledBy(deployerMinimal, scheme)
+			om.AddLabel(knativev1alpha1.DeployerLabelKey, testName)
+			om.AddLabel(knativev1alpha1.DomainLabelKey, testDomain)
+		}).
+		RouteRef(testName)
+	otherMappingCreate := mappingCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Name(testOtherDomain)
+			om.AddLabel(knativev1alpha1.DomainLabelKey, testOtherDomain)
+		}).
+		TLSSecretName("example-tls")
+	mappingGiven := mappingCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Created(1)
+			om.Generation(1)
+		})
+	otherMappingGiven := otherMappingCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Created(1)
+			om.Generation(1)
+		})
+
+	table := rtesting.Table{{
+		Name: "domains not used",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployer,
+		},
+	}, {
+		Name: "create domain mappings",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerDomains,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created DomainMapping "%s"`, testDomain),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created DomainMapping "%s"`, testOtherDomain),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			mappingCreate,
+			otherMappingCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerDomains.
+				StatusConditions(
+					deployerConditionConfigurationReady.Unknown(),
+					deployerConditionDomainMappingsReady.Unknown().Reason("Reconciling", `DomainMapping "example.com" is reconciling.`),
+					deployerConditionReady.Unknown(),
+					deployerConditionRouteReady.Unknown(),
+				).
+				StatusDomains(
+					knativev1alpha1.DomainStatus{Name: testDomain},
+					knativev1alpha1.DomainStatus{Name: testOtherDomain},
+				),
+		},
+	}, {
+		Name: "create domain mapping, domain keyed by hash",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployer.
+				Domains(knativev1alpha1.Domain{Name: testLongDomain}),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created DomainMapping "%s"`, testLongDomain),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			mappingCreate.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Name(testLongDomain)
+					om.AddLabel(knativev1alpha1.DomainLabelKey, "fd4046e3f48f8f2d8e3e33a3234ff816")
+				}),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployer.
+				Domains(knativev1alpha1.Domain{Name: testLongDomain}).
+				StatusConditions(
+					deployerConditionConfigurationReady.Unknown(),
+					deployerConditionDomainMappingsReady.Unknown().Reason("Reconciling", fmt.Sprintf("DomainMapping %q is reconciling.", testLongDomain)),
+					deployerConditionReady.Unknown(),
+					deployerConditionRouteReady.Unknown(),
+				).
+				StatusDomains(
+					knativev1alpha1.DomainStatus{Name: testLongDomain},
+				),
+		},
+	}, {
+		Name: "domain mappings ready",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerDomains,
+			mappingGiven.
+				StatusReady().
+				StatusURL("https://example.com"),
+			otherMappingGiven.
+				StatusReady().
+				StatusURL("https://www.example.com"),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerDomains.
+				StatusConditions(
+					deployerConditionConfigurationReady.Unknown(),
+					deployerConditionDomainMappingsReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRouteReady.Unknown(),
+				).
+				StatusDomains(
+					knativev1alpha1.DomainStatus{Name: testDomain, URL: "https://example.com"},
+					knativev1alpha1.DomainStatus{Name: testOtherDomain, URL: "https://www.example.com"},
+				),
+		},
+	}, {
+		Name: "domain mapping failed",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerDomains,
+			mappingGiven.
+				StatusConditions(
+					factories.Condition().Type(knativeservingv1alpha1.DomainMappingConditionReady).Unknown().Reason("IngressNotConfigured", "Ingress has not yet been reconciled."),
+				),
+			otherMappingGiven.
+				StatusConditions(
+					factories.Condition().Type(knativeservingv1alpha1.DomainMappingConditionReady).False().Reason("CertificateNotReady", "The certificate is not ready."),
+				),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerDomains.
+				StatusConditions(
+					deployerConditionConfigurationReady.Unknown(),
+					deployerConditionDomainMappingsReady.False().Reason("CertificateNotReady", "The certificate is not ready."),
+					deployerConditionReady.Unknown(),
+					deployerConditionRouteReady.Unknown(),
+				).
+				StatusDomains(
+					knativev1alpha1.DomainStatus{Name: testDomain},
+					knativev1alpha1.DomainStatus{Name: testOtherDomain},
+				),
+		},
+	}, {
+		Name: "update domain mapping",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerDomains,
+			mappingGiven.
+				StatusReady(),
+			otherMappingGiven.
+				TLSSecretName("stale-tls").
+				StatusReady(),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated DomainMapping "%s"`, testOtherDomain),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			otherMappingGiven.
+				StatusReady(),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerDomains.
+				StatusConditions(
+					deployerConditionConfigurationReady.Unknown(),
+					deployerConditionDomainMappingsReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRouteReady.Unknown(),
+				).
+				StatusDomains(
+					knativev1alpha1.DomainStatus{Name: testDomain},
+					knativev1alpha1.DomainStatus{Name: testOtherDomain},
+				),
+		},
+	}, {
+		Name: "update domain mapping, update failed",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("update", "DomainMapping"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerDomains,
+			mappingGiven,
+			otherMappingGiven.
+				TLSSecretName("stale-tls"),
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "UpdateFailed",
+				`Failed to update DomainMapping "%s": inducing failure for update DomainMapping`, testOtherDomain),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			otherMappingGiven,
+		},
+	}, {
+		Name: "remove unwanted domain mappings",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployer.
+				Domains(knativev1alpha1.Domain{Name: testDomain}).
+				StatusConditions(
+					deployerConditionConfigurationReady.Unknown(),
+					deployerConditionDomainMappingsReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRouteReady.Unknown(),
+				).
+				StatusDomains(
+					knativev1alpha1.DomainStatus{Name: testDomain},
+					knativev1alpha1.DomainStatus{Name: testOtherDomain},
+				),
+			mappingGiven.
+				StatusReady(),
+			otherMappingGiven.
+				StatusReady(),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted DomainMapping "%s"`, testOtherDomain),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "serving.knative.dev", Kind: "DomainMapping", Namespace: testNamespace, Name: testOtherDomain},
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployer.
+				Domains(knativev1alpha1.Domain{Name: testDomain}).
+				StatusConditions(
+					deployerConditionConfigurationReady.Unknown(),
+					deployerConditionDomainMappingsReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRouteReady.Unknown(),
+				).
+				StatusDomains(
+					knativev1alpha1.DomainStatus{Name: testDomain},
+				),
+		},
+	}, {
+		Name: "remove all domain mappings",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployer.
+				StatusConditions(
+					deployerConditionConfigurationReady.Unknown(),
+					deployerConditionDomainMappingsReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRouteReady.Unknown(),
+				).
+				StatusDomains(
+					knativev1alpha1.DomainStatus{Name: testDomain},
+				),
+			mappingGiven.
+				StatusReady(),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted DomainMapping "%s"`, testDomain),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "serving.knative.dev", Kind: "DomainMapping", Namespace: testNamespace, Name: testDomain},
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployer,
+		},
+	}, {
+		Name: "ignore domain mappings not owned",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployer,
+			factories.KnativeDomainMapping().
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Namespace(testNamespace)
+					om.Name(testDomain)
+					om.AddLabel(knativev1alpha1.DeployerLabelKey, testName)
+					om.Created(1)
+				}).
+				RouteRef(testName),
+		},
+	}, {
+		Name: "domain mapping exists and is not owned",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("create", "DomainMapping", rtesting.InduceFailureOpts{
+				Error: apierrs.NewAlreadyExists(schema.GroupResource{}, testDomain),
+			}),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployer.
+				Domains(knativev1alpha1.Domain{Name: testDomain}),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "CreationFailed",
+				`Failed to create DomainMapping "%s":  "%s" already exists`, testDomain, testDomain),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			mappingCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployer.
+				Domains(knativev1alpha1.Domain{Name: testDomain}).
+				StatusConditions(
+					deployerConditionConfigurationReady.Unknown(),
+					deployerConditionDomainMappingsReady.False().Reason("NotOwned", `There is an existing DomainMapping "example.com" that the Deployer does not own.`),
+					deployerConditionReady.Unknown(),
+					deployerConditionRouteReady.Unknown(),
+				),
+		},
+	}, {
+		Name: "route missing",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerRouteMissing.
+				Domains(knativev1alpha1.Domain{Name: testDomain}),
+			mappingGiven.
+				StatusReady(),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerRouteMissing.
+				Domains(knativev1alpha1.Domain{Name: testDomain}).
+				StatusConditions(
+					deployerConditionConfigurationReady.Unknown(),
+					deployerConditionDomainMappingsReady.Unknown().Reason("RouteMissing", "Domains are mapped once the Route is created."),
+					deployerConditionReady.Unknown(),
+					deployerConditionRouteReady.Unknown(),
+				),
+		},
+	}}
+
+	table.Test(t, scheme, func(t *testing.T, row *rtesting.Testcase, client client.Client, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) reconcile.Reconciler {
+		c := controllers.Config{
+			Client:   client,
+			Recorder: recorder,
+			Scheme:   scheme,
+			Log:      log,
+			Tracker:  tracker,
+		}
+		return &controllers.ParentReconciler{
+			Type: &knativev1alpha1.Deployer{},
+			SubReconcilers: []controllers.SubReconciler{
+				knativecontrollers.DeployerDomainMappingsReconciler(c, true),
+			},
+
+			Config: c,
+		}
+	})
+}
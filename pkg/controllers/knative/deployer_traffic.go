@@ -0,0 +1,170 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package knative
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/projectriff/system/pkg/apis"
+	knativev1alpha1 "github.com/projectriff/system/pkg/apis/knative/v1alpha1"
+	servingv1 "github.com/projectriff/system/pkg/apis/thirdparty/knative/serving/v1"
+	"github.com/projectriff/system/pkg/controllers"
+)
+
+// DeployerTrafficProgressionReconciler shifts the traffic of latest revision
+// targets to each new ready revision in the steps of the deployer's
+// progression. Until the last step completes, the previous revision remains
+// the stable revision and serves the rest of the traffic.
+//
+// The deployer is requeued to advance each step once its interval elapses.
+func DeployerTrafficProgressionReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("TrafficProgression")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *knativev1alpha1.Deployer) (ctrl.Result, error) {
+			progression := parent.Spec.Progression
+			if progression == nil {
+				parent.Status.Progression = nil
+				return ctrl.Result{}, nil
+			}
+			latest := parent.Status.LatestReadyRevisionName
+			if latest == "" {
+				// no ready revision, skip
+				return ctrl.Result{}, nil
+			}
+			if parent.Status.Progression == nil {
+				// the first revision is stable, there is nothing to shift from
+				parent.Status.Progression = &knativev1alpha1.TrafficProgressionStatus{
+					StableRevisionName: latest,
+					LastTransitionTime: apis.VolatileTime{Inner: metav1.Now()},
+				}
+				return ctrl.Result{}, nil
+			}
+			status := parent.Status.Progression
+
+			switch {
+			case latest == status.StableRevisionName:
+				if status.CandidateRevisionName != "" {
+					resetTrafficProgression(status)
+				}
+				return ctrl.Result{}, nil
+
+			case latest != status.CandidateRevisionName:
+				status.CandidateRevisionName = latest
+				status.Step = 0
+				status.Percent = progression.Steps[0]
+				status.LastTransitionTime = apis.VolatileTime{Inner: metav1.Now()}
+				return ctrl.Result{RequeueAfter: progression.Interval.Duration}, nil
+			}
+
+			if remaining := progression.Interval.Duration - time.Since(status.LastTransitionTime.Inner.Time); remaining > 0 {
+				// check back once the interval elapses
+				return ctrl.Result{RequeueAfter: remaining}, nil
+			}
+			if int(status.Step)+1 >= len(progression.Steps) {
+				c.Log.Info("progression complete", "revision", status.CandidateRevisionName)
+				status.StableRevisionName = status.CandidateRevisionName
+				resetTrafficProgression(status)
+				return ctrl.Result{}, nil
+			}
+			status.Step++
+			status.Percent = progression.Steps[status.Step]
+			status.LastTransitionTime = apis.VolatileTime{Inner: metav1.Now()}
+			return ctrl.Result{RequeueAfter: progression.Interval.Duration}, nil
+		},
+
+		Config: c,
+	}
+}
+
+func resetTrafficProgression(status *knativev1alpha1.TrafficProgressionStatus) {
+	status.CandidateRevisionName = ""
+	status.Step = 0
+	status.Percent = 0
+	status.LastTransitionTime = apis.VolatileTime{Inner: metav1.Now()}
+}
+
+// deployerRouteTraffic resolves the deployer's traffic targets for the route.
+// Latest revision targets follow the configuration, unless a progression is
+// defined. A progression pins latest revision targets to the stable revision
+// and splits off the candidate's share, the tag follows the candidate.
+func deployerRouteTraffic(parent *knativev1alpha1.Deployer) []servingv1.TrafficTarget {
+	targets := parent.Spec.Traffic
+	if len(targets) == 0 {
+		var allTraffic int64 = 100
+		latestRevision := true
+		targets = []knativev1alpha1.TrafficTarget{
+			{LatestRevision: &latestRevision, Percent: &allTraffic},
+		}
+	}
+
+	progression := parent.Status.Progression
+	if parent.Spec.Progression == nil || progression == nil || progression.StableRevisionName == "" {
+		progression = nil
+	}
+
+	traffic := []servingv1.TrafficTarget{}
+	for _, target := range targets {
+		if target.RevisionName != "" {
+			traffic = append(traffic, servingv1.TrafficTarget{
+				Tag:          target.Tag,
+				RevisionName: target.RevisionName,
+				Percent:      target.Percent,
+			})
+			continue
+		}
+		if progression == nil {
+			traffic = append(traffic, servingv1.TrafficTarget{
+				Tag:               target.Tag,
+				ConfigurationName: parent.Status.ConfigurationRef.Name,
+				Percent:           target.Percent,
+			})
+			continue
+		}
+		if progression.CandidateRevisionName == "" {
+			traffic = append(traffic, servingv1.TrafficTarget{
+				Tag:          target.Tag,
+				RevisionName: progression.StableRevisionName,
+				Percent:      target.Percent,
+			})
+			continue
+		}
+		var percent int64
+		if target.Percent != nil {
+			percent = *target.Percent
+		}
+		candidatePercent := percent * progression.Percent / 100
+		stablePercent := percent - candidatePercent
+		traffic = append(traffic,
+			servingv1.TrafficTarget{
+				RevisionName: progression.StableRevisionName,
+				Percent:      &stablePercent,
+			},
+			servingv1.TrafficTarget{
+				Tag:          target.Tag,
+				RevisionName: progression.CandidateRevisionName,
+				Percent:      &candidatePercent,
+			},
+		)
+	}
+
+	return traffic
+}
@@ -0,0 +1,385 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package knative_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	knativev1alpha1 "github.com/projectriff/system/pkg/apis/knative/v1alpha1"
+	knativeservingv1 "github.com/projectriff/system/pkg/apis/thirdparty/knative/serving/v1"
+	"github.com/projectriff/system/pkg/controllers"
+	knativecontrollers "github.com/projectriff/system/pkg/controllers/knative"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+	"github.com/projectriff/system/pkg/controllers/testing/factories"
+	"github.com/projectriff/system/pkg/tracker"
+)
+
+func TestDeployerTraffic(t *testing.T) {
+	// timestamps lose their fraction of a second when stored by the client
+	testNow := time.Now().Truncate(time.Second)
+
+	testNamespace := "test-namespace"
+	testName := "test-deployer"
+	testKey := types.NamespacedName{Namespace: testNamespace, Name: testName}
+	testConfigurationName := "test-deployer-deployer-001"
+
+	deployerConditionConfigurationReady := factories.Condition().Type(knativev1alpha1.DeployerConditionConfigurationReady)
+	deployerConditionReady := factories.Condition().Type(knativev1alpha1.DeployerConditionReady)
+	deployerConditionRouteReady := factories.Condition().Type(knativev1alpha1.DeployerConditionRouteReady)
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = knativev1alpha1.AddToScheme(scheme)
+	_ = knativeservingv1.AddToScheme(scheme)
+
+	deployerMinimal := factories.DeployerKnative().
+		NamespaceName(testNamespace, testName)
+	deployer := deployerMinimal.
+		IngressPolicy(knativev1alpha1.IngressPolicyClusterLocal).
+		StatusConditions(
+			deployerConditionConfigurationReady.Unknown(),
+			deployerConditionReady.Unknown(),
+			deployerConditionRouteReady.Unknown(),
+		).
+		StatusConfigurationRef(testConfigurationName)
+	deployerProgression := deployer.
+		Progression(time.Minute, 20, 50)
+
+	routeCreate := factories.KnativeRoute().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.Name(testName)
+			om.ControlledBy(deployerMinimal, scheme)
+			om.AddLabel(knativev1alpha1.DeployerLabelKey, testName)
+			om.AddLabel("serving.knative.dev/visibility", "cluster-local")
+		})
+	routeGiven := routeCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Created(1)
+			om.Generation(1)
+		})
+
+	table := rtesting.Table{{
+		Name: "split traffic with tags",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployer.
+				Traffic(
+					knativev1alpha1.TrafficTarget{Tag: "current", RevisionName: "rev-1", Percent: rtesting.Int64Ptr(90)},
+					knativev1alpha1.TrafficTarget{Tag: "latest", LatestRevision: rtesting.BoolPtr(true), Percent: rtesting.Int64Ptr(10)},
+				),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Route "%s"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			routeCreate.
+				Traffic(
+					knativeservingv1.TrafficTarget{Tag: "current", RevisionName: "rev-1", Percent: rtesting.Int64Ptr(90)},
+					knativeservingv1.TrafficTarget{Tag: "latest", ConfigurationName: testConfigurationName, Percent: rtesting.Int64Ptr(10)},
+				),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployer.
+				StatusRouteRef(testName),
+		},
+	}, {
+		Name: "reflect route traffic",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployer.
+				StatusRouteRef(testName),
+			routeGiven.
+				Traffic(
+					knativeservingv1.TrafficTarget{ConfigurationName: testConfigurationName, Percent: rtesting.Int64Ptr(100)},
+				).
+				StatusTraffic(
+					knativeservingv1.TrafficTarget{Tag: "latest", RevisionName: "rev-2", LatestRevision: rtesting.BoolPtr(true), Percent: rtesting.Int64Ptr(100), URL: "http://latest-test-deployer.test-namespace.example.com"},
+				),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployer.
+				StatusRouteRef(testName).
+				StatusTraffic(
+					knativev1alpha1.TrafficTargetStatus{Tag: "latest", RevisionName: "rev-2", LatestRevision: rtesting.BoolPtr(true), Percent: rtesting.Int64Ptr(100), URL: "http://latest-test-deployer.test-namespace.example.com"},
+				),
+		},
+	}, {
+		Name: "progression, first revision is stable",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerProgression.
+				StatusLatestReadyRevisionName("rev-1"),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Route "%s"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			routeCreate.
+				Traffic(
+					knativeservingv1.TrafficTarget{RevisionName: "rev-1", Percent: rtesting.Int64Ptr(100)},
+				),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerProgression.
+				StatusLatestReadyRevisionName("rev-1").
+				StatusProgression("rev-1", "", 0, 0, testNow).
+				StatusRouteRef(testName),
+		},
+	}, {
+		Name: "progression, start with new revision",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerProgression.
+				Traffic(
+					knativev1alpha1.TrafficTarget{Tag: "latest", LatestRevision: rtesting.BoolPtr(true), Percent: rtesting.Int64Ptr(100)},
+				).
+				StatusLatestReadyRevisionName("rev-2").
+				StatusProgression("rev-1", "", 0, 0, testNow.Add(-time.Hour)).
+				StatusRouteRef(testName),
+			routeGiven.
+				Traffic(
+					knativeservingv1.TrafficTarget{Tag: "latest", RevisionName: "rev-1", Percent: rtesting.Int64Ptr(100)},
+				),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Route "%s"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			routeGiven.
+				Traffic(
+					knativeservingv1.TrafficTarget{RevisionName: "rev-1", Percent: rtesting.Int64Ptr(80)},
+					knativeservingv1.TrafficTarget{Tag: "latest", RevisionName: "rev-2", Percent: rtesting.Int64Ptr(20)},
+				),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerProgression.
+				Traffic(
+					knativev1alpha1.TrafficTarget{Tag: "latest", LatestRevision: rtesting.BoolPtr(true), Percent: rtesting.Int64Ptr(100)},
+				).
+				StatusLatestReadyRevisionName("rev-2").
+				StatusProgression("rev-1", "rev-2", 0, 20, testNow).
+				StatusRouteRef(testName),
+		},
+		ExpectedResult: ctrl.Result{RequeueAfter: time.Minute},
+	}, {
+		Name: "progression, hold step within interval",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerProgression.
+				StatusLatestReadyRevisionName("rev-2").
+				StatusProgression("rev-1", "rev-2", 0, 20, testNow).
+				StatusRouteRef(testName),
+			routeGiven.
+				Traffic(
+					knativeservingv1.TrafficTarget{RevisionName: "rev-1", Percent: rtesting.Int64Ptr(80)},
+					knativeservingv1.TrafficTarget{RevisionName: "rev-2", Percent: rtesting.Int64Ptr(20)},
+				),
+		},
+		ExpectedResult: ctrl.Result{RequeueAfter: time.Until(testNow.Add(time.Minute))},
+	}, {
+		Name: "progression, advance step after interval",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerProgression.
+				StatusLatestReadyRevisionName("rev-2").
+				StatusProgression("rev-1", "rev-2", 0, 20, testNow.Add(-time.Hour)).
+				StatusRouteRef(testName),
+			routeGiven.
+				Traffic(
+					knativeservingv1.TrafficTarget{RevisionName: "rev-1", Percent: rtesting.Int64Ptr(80)},
+					knativeservingv1.TrafficTarget{RevisionName: "rev-2", Percent: rtesting.Int64Ptr(20)},
+				),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Route "%s"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			routeGiven.
+				Traffic(
+					knativeservingv1.TrafficTarget{RevisionName: "rev-1", Percent: rtesting.Int64Ptr(50)},
+					knativeservingv1.TrafficTarget{RevisionName: "rev-2", Percent: rtesting.Int64Ptr(50)},
+				),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerProgression.
+				StatusLatestReadyRevisionName("rev-2").
+				StatusProgression("rev-1", "rev-2", 1, 50, testNow).
+				StatusRouteRef(testName),
+		},
+		ExpectedResult: ctrl.Result{RequeueAfter: time.Minute},
+	}, {
+		Name: "progression, complete after last step",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerProgression.
+				StatusLatestReadyRevisionName("rev-2").
+				StatusProgression("rev-1", "rev-2", 1, 50, testNow.Add(-time.Hour)).
+				StatusRouteRef(testName),
+			routeGiven.
+				Traffic(
+					knativeservingv1.TrafficTarget{RevisionName: "rev-1", Percent: rtesting.Int64Ptr(50)},
+					knativeservingv1.TrafficTarget{RevisionName: "rev-2", Percent: rtesting.Int64Ptr(50)},
+				),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Route "%s"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			routeGiven.
+				Traffic(
+					knativeservingv1.TrafficTarget{RevisionName: "rev-2", Percent: rtesting.Int64Ptr(100)},
+				),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerProgression.
+				StatusLatestReadyRevisionName("rev-2").
+				StatusProgression("rev-2", "", 0, 0, testNow).
+				StatusRouteRef(testName),
+		},
+	}}
+
+	table.Test(t, scheme, func(t *testing.T, row *rtesting.Testcase, client client.Client, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) reconcile.Reconciler {
+		c := controllers.Config{
+			Client:   client,
+			Recorder: recorder,
+			Scheme:   scheme,
+			Log:      log,
+			Tracker:  tracker,
+		}
+		return &controllers.ParentReconciler{
+			Type: &knativev1alpha1.Deployer{},
+			SubReconcilers: []controllers.SubReconciler{
+				knativecontrollers.DeployerTrafficProgressionReconciler(c),
+				knativecontrollers.DeployerChildRouteReconciler(c),
+			},
+
+			Config: c,
+		}
+	})
+}
+
+func TestDeployerTrafficProgressionReconciler(t *testing.T) {
+	// timestamps lose their fraction of a second when stored by the client
+	testNow := time.Now().Truncate(time.Second)
+
+	testNamespace := "test-namespace"
+	testName := "test-deployer"
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = knativev1alpha1.AddToScheme(scheme)
+
+	deployer := factories.DeployerKnative().
+		NamespaceName(testNamespace, testName)
+	deployerProgression := deployer.
+		Progression(time.Minute, 20, 50)
+
+	rts := rtesting.SubReconcilerTestSuite{{
+		Name:   "no progression",
+		Parent: deployer,
+	}, {
+		Name: "clear progression",
+		Parent: deployer.
+			StatusLatestReadyRevisionName("rev-1").
+			StatusProgression("rev-1", "", 0, 0, testNow),
+		ExpectParent: deployer.
+			StatusLatestReadyRevisionName("rev-1"),
+	}, {
+		Name:   "no ready revision",
+		Parent: deployerProgression,
+	}, {
+		Name: "first revision is stable",
+		Parent: deployerProgression.
+			StatusLatestReadyRevisionName("rev-1"),
+		ExpectParent: deployerProgression.
+			StatusLatestReadyRevisionName("rev-1").
+			StatusProgression("rev-1", "", 0, 0, testNow),
+	}, {
+		Name: "start with new revision",
+		Parent: deployerProgression.
+			StatusLatestReadyRevisionName("rev-2").
+			StatusProgression("rev-1", "", 0, 0, testNow.Add(-time.Hour)),
+		ExpectParent: deployerProgression.
+			StatusLatestReadyRevisionName("rev-2").
+			StatusProgression("rev-1", "rev-2", 0, 20, testNow),
+		ExpectedResult: ctrl.Result{RequeueAfter: time.Minute},
+	}, {
+		Name: "hold step for the rest of the interval",
+		Parent: deployerProgression.
+			StatusLatestReadyRevisionName("rev-2").
+			StatusProgression("rev-1", "rev-2", 0, 20, testNow.Add(-20*time.Second)),
+		ExpectedResult: ctrl.Result{RequeueAfter: time.Until(testNow.Add(40 * time.Second))},
+	}, {
+		Name: "advance step after interval",
+		Parent: deployerProgression.
+			StatusLatestReadyRevisionName("rev-2").
+			StatusProgression("rev-1", "rev-2", 0, 20, testNow.Add(-time.Hour)),
+		ExpectParent: deployerProgression.
+			StatusLatestReadyRevisionName("rev-2").
+			StatusProgression("rev-1", "rev-2", 1, 50, testNow),
+		ExpectedResult: ctrl.Result{RequeueAfter: time.Minute},
+	}, {
+		Name: "complete after last step",
+		Parent: deployerProgression.
+			StatusLatestReadyRevisionName("rev-2").
+			StatusProgression("rev-1", "rev-2", 1, 50, testNow.Add(-time.Hour)),
+		ExpectParent: deployerProgression.
+			StatusLatestReadyRevisionName("rev-2").
+			StatusProgression("rev-2", "", 0, 0, testNow),
+	}}
+
+	rts.Test(t, scheme, func(t *testing.T, rtc *rtesting.SubReconcilerTestcase, client client.Client, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) controllers.SubReconciler {
+		return knativecontrollers.DeployerTrafficProgressionReconciler(controllers.Config{
+			Client:   client,
+			Recorder: recorder,
+			Scheme:   scheme,
+			Log:      log,
+			Tracker:  tracker,
+		})
+	})
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NetworkPolicyConfig configures the NetworkPolicies that restrict network
+// traffic to workloads according to their ingress policy.
+type NetworkPolicyConfig struct {
+	// Enabled creates a NetworkPolicy for each workload. Enforcement
+	// requires a network plugin that supports NetworkPolicies.
+	Enabled bool
+
+	// NamespaceSelectors admit traffic from pods in the matching namespaces,
+	// in addition to pods in the workload's namespace.
+	NamespaceSelectors []metav1.LabelSelector
+
+	// IngressNamespaceSelectors admit traffic from pods in the namespaces of
+	// the ingress controllers and gateways to workloads with an External
+	// ingress policy.
+	IngressNamespaceSelectors []metav1.LabelSelector
+}
+
+// IngressRules admits traffic from the workload's namespace and the
+// configured namespaces. External workloads also admit traffic from the
+// ingress controllers.
+func (c NetworkPolicyConfig) IngressRules(external bool) []networkingv1.NetworkPolicyIngressRule {
+	peers := []networkingv1.NetworkPolicyPeer{
+		{PodSelector: &metav1.LabelSelector{}},
+	}
+	for i := range c.NamespaceSelectors {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{NamespaceSelector: c.NamespaceSelectors[i].DeepCopy()})
+	}
+	if external {
+		for i := range c.IngressNamespaceSelectors {
+			peers = append(peers, networkingv1.NetworkPolicyPeer{NamespaceSelector: c.IngressNamespaceSelectors[i].DeepCopy()})
+		}
+	}
+	return []networkingv1.NetworkPolicyIngressRule{{From: peers}}
+}
+
+// LabelSelectorsFlag collects label selectors from a repeated command line
+// flag.
+type LabelSelectorsFlag []metav1.LabelSelector
+
+func (f *LabelSelectorsFlag) String() string {
+	selectors := []string{}
+	for i := range *f {
+		selectors = append(selectors, metav1.FormatLabelSelector(&(*f)[i]))
+	}
+	return strings.Join(selectors, ",")
+}
+
+func (f *LabelSelectorsFlag) Set(value string) error {
+	selector, err := metav1.ParseToLabelSelector(value)
+	if err != nil {
+		return fmt.Errorf("invalid label selector %q: %v", value, err)
+	}
+	*f = append(*f, *selector)
+	return nil
+}
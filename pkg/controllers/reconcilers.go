@@ -18,14 +18,22 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/go-logr/logr"
 	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -49,6 +57,11 @@ type Config struct {
 	Log      logr.Logger
 	Scheme   *runtime.Scheme
 	Tracker  tracker.Tracker
+
+	// APIReader reads directly from the API server. Use it for resources that
+	// are not watched, reading them with the Client starts an informer that
+	// caches every resource of the type in the cluster.
+	APIReader client.Reader
 }
 
 // ParentReconciler is a controller-runtime reconciler that reconciles a given
@@ -61,13 +74,18 @@ type ParentReconciler struct {
 	Type runtime.Object
 
 	// SubReconcilers are called in order for each reconciler request. If a sub
-	// reconciler errs, further sub reconcilers are skipped.
+	// reconciler errs, further sub reconcilers are skipped. The results of the
+	// sub reconcilers are aggregated with AggregateResults.
 	SubReconcilers []SubReconciler
 
 	Config
 }
 
 func (r *ParentReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
 	bldr := ctrl.NewControllerManagedBy(mgr).For(r.Type)
 	for _, reconciler := range r.SubReconcilers {
 		err := reconciler.SetupWithManager(mgr, bldr)
@@ -78,8 +96,42 @@ func (r *ParentReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return bldr.Complete(r)
 }
 
+// Validate checks the configuration of the reconciler and each of its sub
+// reconcilers, including that the sub reconcilers accept the Type as their
+// parent. A misconfigured reconciler fails during setup rather than panicking
+// on the first reconciler request.
+func (r *ParentReconciler) Validate() error {
+	if r.Type == nil {
+		return fmt.Errorf("ParentReconciler.Type must be set")
+	}
+	if _, ok := r.Type.(apis.Object); !ok {
+		return fmt.Errorf("ParentReconciler.Type must be an apis.Object, found %s", reflect.TypeOf(r.Type))
+	}
+	parentType := reflect.TypeOf(r.Type)
+	for i, reconciler := range r.SubReconcilers {
+		if reconciler == nil {
+			return fmt.Errorf("ParentReconciler.SubReconcilers[%d] must be set", i)
+		}
+		if err := reconciler.Validate(); err != nil {
+			return fmt.Errorf("%s SubReconcilers[%d]: %v", typeName(r.Type), i, err)
+		}
+		if v, ok := reconciler.(parentValidator); ok {
+			if err := v.validateFor(parentType); err != nil {
+				return fmt.Errorf("%s SubReconcilers[%d]: %v", typeName(r.Type), i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// parentValidator is implemented by sub reconcilers that are able to check
+// they accept the type of the parent they are reconciled with.
+type parentValidator interface {
+	validateFor(parentType reflect.Type) error
+}
+
 func (r *ParentReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
-	ctx := context.Background()
+	ctx := WithStash(context.Background())
 	log := r.Log.WithValues("request", req.NamespacedName)
 
 	originalParent := r.Type.DeepCopyObject().(apis.Object)
@@ -130,15 +182,18 @@ func (r *ParentReconciler) reconcile(ctx context.Context, parent apis.Object) (c
 		return ctrl.Result{}, nil
 	}
 
+	aggregateResult := ctrl.Result{}
 	for _, reconciler := range r.SubReconcilers {
-		if _, err := reconciler.Reconcile(ctx, parent); err != nil {
+		result, err := reconciler.Reconcile(ctx, parent)
+		if err != nil {
 			return ctrl.Result{}, err
 		}
+		aggregateResult = AggregateResults(aggregateResult, result)
 	}
 
 	r.copyGeneration(parent)
 
-	return ctrl.Result{}, nil
+	return aggregateResult, nil
 }
 
 func (r *ParentReconciler) copyGeneration(obj apis.Object) {
@@ -157,12 +212,18 @@ func (r *ParentReconciler) status(obj apis.Object) interface{} {
 // status can be mutated to reflect the current state.
 type SubReconciler interface {
 	SetupWithManager(mgr ctrl.Manager, bldr *builder.Builder) error
+	Validate() error
 	Reconcile(ctx context.Context, parent apis.Object) (ctrl.Result, error)
 }
 
 var (
 	_ SubReconciler = (*SyncReconciler)(nil)
 	_ SubReconciler = (*ChildReconciler)(nil)
+	_ SubReconciler = (*ChildSetReconciler)(nil)
+
+	_ parentValidator = (*SyncReconciler)(nil)
+	_ parentValidator = (*ChildReconciler)(nil)
+	_ parentValidator = (*ChildSetReconciler)(nil)
 )
 
 // SyncReconciler is a sub reconciler for custom reconciliation logic. No
@@ -174,43 +235,86 @@ type SyncReconciler struct {
 	// +optional
 	Setup func(mgr ctrl.Manager, bldr *builder.Builder) error
 
-	// Sync does whatever work is necessary for the reconciler
+	// Sync does whatever work is necessary for the reconciler. A result may be
+	// returned to requeue the request, for example to check on the parent
+	// after a delay.
 	//
 	// Expected function signature:
 	//     func(ctx context.Context, parent apis.Object) error
+	//     func(ctx context.Context, parent apis.Object) (ctrl.Result, error)
 	Sync interface{}
 
 	Config
 }
 
 func (r *SyncReconciler) SetupWithManager(mgr ctrl.Manager, bldr *builder.Builder) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
 	if r.Setup == nil {
 		return nil
 	}
 	return r.Setup(mgr, bldr)
 }
 
+// Validate checks that Sync is a function with an expected signature.
+func (r *SyncReconciler) Validate() error {
+	return validateFunc("SyncReconciler.Sync", r.Sync,
+		funcSignature{
+			text: "func(ctx context.Context, parent apis.Object) error",
+			in:   []typeCheck{acceptsType(contextType), acceptsObject},
+			out:  []typeCheck{returnsError},
+		},
+		funcSignature{
+			text: "func(ctx context.Context, parent apis.Object) (ctrl.Result, error)",
+			in:   []typeCheck{acceptsType(contextType), acceptsObject},
+			out:  []typeCheck{returnsType(resultType), returnsError},
+		},
+	)
+}
+
+// validateFor checks that Sync accepts the parent type.
+func (r *SyncReconciler) validateFor(parentType reflect.Type) error {
+	return validateFunc("SyncReconciler.Sync", r.Sync,
+		funcSignature{
+			text: fmt.Sprintf("func(ctx context.Context, parent %s) error", parentType),
+			in:   []typeCheck{acceptsType(contextType), acceptsType(parentType)},
+			out:  []typeCheck{returnsError},
+		},
+		funcSignature{
+			text: fmt.Sprintf("func(ctx context.Context, parent %s) (ctrl.Result, error)", parentType),
+			in:   []typeCheck{acceptsType(contextType), acceptsType(parentType)},
+			out:  []typeCheck{returnsType(resultType), returnsError},
+		},
+	)
+}
+
 func (r *SyncReconciler) Reconcile(ctx context.Context, parent apis.Object) (ctrl.Result, error) {
-	err := r.sync(ctx, parent)
+	result, err := r.sync(ctx, parent)
 	if err != nil {
 		r.Log.Error(err, "unable to sync", typeName(parent), parent)
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	return result, nil
 }
 
-func (r *SyncReconciler) sync(ctx context.Context, parent apis.Object) error {
+func (r *SyncReconciler) sync(ctx context.Context, parent apis.Object) (ctrl.Result, error) {
 	fn := reflect.ValueOf(r.Sync)
 	out := fn.Call([]reflect.Value{
 		reflect.ValueOf(ctx),
 		reflect.ValueOf(parent),
 	})
+	result := ctrl.Result{}
 	var err error
-	if !out[0].IsNil() {
-		err = out[0].Interface().(error)
+	if len(out) == 2 {
+		result = out[0].Interface().(ctrl.Result)
 	}
-	return err
+	if errValue := out[len(out)-1]; !errValue.IsNil() {
+		err = errValue.Interface().(error)
+	}
+	return result, err
 }
 
 // ChildReconciler is a sub reconciler that manages a single child resource for
@@ -229,6 +333,9 @@ func (r *SyncReconciler) sync(ctx context.Context, parent apis.Object) error {
 //    - MergeBeforeUpdate
 // - ReflectChildStatusOnParent
 //
+// With a FieldManager, the desired child is applied with server-side apply
+// instead of MergeBeforeUpdate.
+//
 // During setup, the child resource type is registered to watch for changes. A
 // field indexer is configured for the owner on the IndexField.
 type ChildReconciler struct {
@@ -249,15 +356,19 @@ type ChildReconciler struct {
 	Setup func(mgr ctrl.Manager, bldr *builder.Builder) error
 
 	// DesiredChild returns the desired child object for the given parent
-	// object, or nil if the child should not exist.
+	// object, or nil if the child should not exist. The context holds values
+	// stashed by earlier sub reconcilers.
 	//
 	// Expected function signature:
 	//     func(parent apis.Object) (apis.Object, error)
+	//     func(ctx context.Context, parent apis.Object) (apis.Object, error)
 	DesiredChild interface{}
 
 	// ReflectChildStatusOnParent updates the parent object's status with values
 	// from the child. Select types of error are passed, including:
-	// - apierrs.IsConflict
+	// - apierrs.IsAlreadyExists
+	// - apierrs.IsConflict, when a server-side apply conflicts with another
+	//   field manager
 	//
 	// Expected function signature:
 	//     func(parent, child apis.Object, err error)
@@ -275,14 +386,15 @@ type ChildReconciler struct {
 
 	// MergeBeforeUpdate copies desired fields on to the current object before
 	// calling update. Typically fields to copy are the Spec, Labels and
-	// Annotations.
+	// Annotations. Not used with a FieldManager.
 	//
 	// Expected function signature:
 	//     func(current, desired apis.Object)
 	MergeBeforeUpdate interface{}
 
 	// SemanticEquals compares two child resources returning true if there is a
-	// meaningful difference that should trigger an update.
+	// meaningful difference that should trigger an update. Optional with a
+	// FieldManager, as applying an unchanged child is idempotent.
 	//
 	// Expected function signature:
 	//     func(a1, a2 apis.Object) bool
@@ -298,14 +410,49 @@ type ChildReconciler struct {
 	// +optional
 	Sanitize interface{}
 
+	// OurChild is used when there are multiple ChildReconcilers for the same
+	// ChildType controlled by the same parent. The function returns true for
+	// children managed by this reconciler, other children are ignored. The
+	// desired child must satisfy this function, otherwise it is orphaned.
+	//
+	// Expected function signature:
+	//     func(child apis.Object) bool
+	//
+	// +optional
+	OurChild interface{}
+
 	Config
 
 	// IndexField is used to index objects of the child's type based on their
 	// controlling owner. This field needs to be unique within the manager.
 	IndexField string
+
+	// FieldManager enables server-side apply of the desired child with the
+	// field manager name. The reconciler owns the fields set on the desired
+	// child, while fields set by other controllers, like the replicas of an
+	// autoscaled deployment or defaults from admission webhooks, are left
+	// untouched. The desired child must be named. Conflicts with other field
+	// managers are recorded as an ApplyConflict event and reflected on the
+	// parent.
+	//
+	// +optional
+	FieldManager string
+
+	// ForceOwnership takes over fields of the desired child that other field
+	// managers own rather than failing with a conflict. Children updated
+	// before they were applied, for example by an earlier release of the
+	// reconciler, are migrated by clearing their managed fields with the first
+	// apply, so fields no longer desired are removed by the following applies.
+	//
+	// +optional
+	ForceOwnership bool
 }
 
 func (r *ChildReconciler) SetupWithManager(mgr ctrl.Manager, bldr *builder.Builder) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
 	bldr.Owns(r.ChildType)
 
 	if err := IndexControllersOfType(mgr, r.IndexField, r.ParentType, r.ChildType, r.Scheme); err != nil {
@@ -318,6 +465,94 @@ func (r *ChildReconciler) SetupWithManager(mgr ctrl.Manager, bldr *builder.Build
 	return r.Setup(mgr, bldr)
 }
 
+// Validate checks that the types are set and that each function has the
+// expected signature for the ParentType and ChildType.
+func (r *ChildReconciler) Validate() error {
+	if err := validateTypes("ChildReconciler", r.ParentType, r.ChildType, r.ChildListType); err != nil {
+		return err
+	}
+	parentType := reflect.TypeOf(r.ParentType)
+	childType := reflect.TypeOf(r.ChildType)
+
+	if err := validateFunc("ChildReconciler.DesiredChild", r.DesiredChild,
+		funcSignature{
+			text: fmt.Sprintf("func(parent %s) (%s, error)", parentType, childType),
+			in:   []typeCheck{acceptsType(parentType)},
+			out:  []typeCheck{returnsObject(childType), returnsError},
+		},
+		funcSignature{
+			text: fmt.Sprintf("func(ctx context.Context, parent %s) (%s, error)", parentType, childType),
+			in:   []typeCheck{acceptsType(contextType), acceptsType(parentType)},
+			out:  []typeCheck{returnsObject(childType), returnsError},
+		},
+	); err != nil {
+		return err
+	}
+	if err := validateFunc("ChildReconciler.ReflectChildStatusOnParent", r.ReflectChildStatusOnParent, funcSignature{
+		text: fmt.Sprintf("func(parent %s, child %s, err error)", parentType, childType),
+		in:   []typeCheck{acceptsType(parentType), acceptsType(childType), acceptsType(errorType)},
+	}); err != nil {
+		return err
+	}
+	return r.validateSharedFuncs("ChildReconciler")
+}
+
+// validateFor checks that the ParentType accepts the parent type.
+func (r *ChildReconciler) validateFor(parentType reflect.Type) error {
+	return validateParentType("ChildReconciler", parentType, r.ParentType)
+}
+
+// validateSharedFuncs checks the signatures of the functions shared by the
+// ChildReconciler and ChildSetReconciler.
+func (r *ChildReconciler) validateSharedFuncs(reconciler string) error {
+	childType := reflect.TypeOf(r.ChildType)
+
+	if r.HarmonizeImmutableFields != nil {
+		if err := validateFunc(reconciler+".HarmonizeImmutableFields", r.HarmonizeImmutableFields, funcSignature{
+			text: fmt.Sprintf("func(current, desired %s)", childType),
+			in:   []typeCheck{acceptsType(childType), acceptsType(childType)},
+		}); err != nil {
+			return err
+		}
+	}
+	if r.FieldManager == "" || r.MergeBeforeUpdate != nil {
+		if err := validateFunc(reconciler+".MergeBeforeUpdate", r.MergeBeforeUpdate, funcSignature{
+			text: fmt.Sprintf("func(current, desired %s)", childType),
+			in:   []typeCheck{acceptsType(childType), acceptsType(childType)},
+		}); err != nil {
+			return err
+		}
+	}
+	if r.FieldManager == "" || r.SemanticEquals != nil {
+		if err := validateFunc(reconciler+".SemanticEquals", r.SemanticEquals, funcSignature{
+			text: fmt.Sprintf("func(a1, a2 %s) bool", childType),
+			in:   []typeCheck{acceptsType(childType), acceptsType(childType)},
+			out:  []typeCheck{returnsBool},
+		}); err != nil {
+			return err
+		}
+	}
+	if r.Sanitize != nil {
+		if err := validateFunc(reconciler+".Sanitize", r.Sanitize, funcSignature{
+			text: fmt.Sprintf("func(child %s) interface{}", childType),
+			in:   []typeCheck{acceptsType(childType)},
+			out:  []typeCheck{returnsInterface},
+		}); err != nil {
+			return err
+		}
+	}
+	if r.OurChild != nil {
+		if err := validateFunc(reconciler+".OurChild", r.OurChild, funcSignature{
+			text: fmt.Sprintf("func(child %s) bool", childType),
+			in:   []typeCheck{acceptsType(childType)},
+			out:  []typeCheck{returnsBool},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *ChildReconciler) Reconcile(ctx context.Context, parent apis.Object) (ctrl.Result, error) {
 	child, err := r.reconcile(ctx, parent)
 	if err != nil {
@@ -326,6 +561,11 @@ func (r *ChildReconciler) Reconcile(ctx context.Context, parent apis.Object) (ct
 			r.reflectChildStatusOnParent(parent, child, err)
 			return ctrl.Result{}, nil
 		}
+		if r.FieldManager != "" && apierrs.IsConflict(err) {
+			r.Log.Info("unable to reconcile child, apply conflicts", typeName(r.ParentType), parent, typeName(r.ChildType), r.sanitize(child))
+			r.reflectChildStatusOnParent(parent, child, err)
+			return ctrl.Result{}, nil
+		}
 		r.Log.Error(err, "unable to reconcile child", typeName(r.ParentType), parent)
 		return ctrl.Result{}, err
 	}
@@ -341,7 +581,7 @@ func (r *ChildReconciler) reconcile(ctx context.Context, parent apis.Object) (ap
 		return nil, err
 	}
 	// TODO do we need to remove resources pending deletion?
-	items := r.items(children)
+	items := r.filterChildren(r.items(children))
 	if len(items) == 1 {
 		actual = items[0]
 	} else if len(items) > 1 {
@@ -358,7 +598,7 @@ func (r *ChildReconciler) reconcile(ctx context.Context, parent apis.Object) (ap
 		}
 	}
 
-	desired, err := r.desiredChild(parent)
+	desired, err := r.desiredChild(ctx, parent)
 	if err != nil {
 		return nil, err
 	}
@@ -384,6 +624,10 @@ func (r *ChildReconciler) reconcile(ctx context.Context, parent apis.Object) (ap
 		return nil, nil
 	}
 
+	if r.FieldManager != "" {
+		return r.apply(ctx, parent, actual, desired)
+	}
+
 	// create child if it doesn't exist
 	if actual.GetName() == "" {
 		r.Log.Info("creating child", typeName(r.ChildType), r.sanitize(desired))
@@ -422,6 +666,145 @@ func (r *ChildReconciler) reconcile(ctx context.Context, parent apis.Object) (ap
 	return current, nil
 }
 
+// apply creates or updates the child with server-side apply. Fields that are
+// not set on the desired child are left to other field managers.
+func (r *ChildReconciler) apply(ctx context.Context, parent, actual, desired apis.Object) (apis.Object, error) {
+	create := actual.GetName() == ""
+	if !create {
+		desired.SetName(actual.GetName())
+
+		// overwrite fields that should not be mutated
+		r.harmonizeImmutableFields(actual, desired)
+
+		if r.SemanticEquals != nil && r.semanticEquals(desired, actual) {
+			// child is unchanged
+			return actual, nil
+		}
+	}
+	if desired.GetName() == "" {
+		return nil, fmt.Errorf("unable to apply %s, the desired child must be named", typeName(r.ChildType))
+	}
+	gvks, _, err := r.Scheme.ObjectKinds(desired)
+	if err != nil {
+		return nil, err
+	}
+	if create {
+		// applying would take over an existing resource that is not our child
+		existing := r.ChildType.DeepCopyObject().(apis.Object)
+		err := r.Get(ctx, types.NamespacedName{Namespace: desired.GetNamespace(), Name: desired.GetName()}, existing)
+		if err == nil {
+			err = apierrs.NewAlreadyExists(schema.GroupResource{Group: gvks[0].Group, Resource: strings.ToLower(gvks[0].Kind)}, desired.GetName())
+		}
+		if !apierrs.IsNotFound(err) {
+			r.Log.Error(err, "unable to create child", typeName(r.ChildType), r.sanitize(desired))
+			r.Recorder.Eventf(parent, corev1.EventTypeWarning, "CreationFailed",
+				"Failed to create %s %q: %v", typeName(r.ChildType), desired.GetName(), err)
+			return nil, err
+		}
+	}
+	opts := []client.PatchOption{client.FieldOwner(r.FieldManager)}
+	if r.ForceOwnership {
+		opts = append(opts, client.ForceOwnership)
+		if !create && !appliedBy(actual, r.FieldManager) {
+			// fields set with an update are owned by the updating manager and
+			// are retained when they are no longer applied
+			migrated := actual.DeepCopyObject().(apis.Object)
+			migrated.SetManagedFields([]metav1.ManagedFieldsEntry{{}})
+			r.Log.Info("migrating managed fields of child", typeName(r.ChildType), r.sanitize(actual))
+			if err := r.Update(ctx, migrated); err != nil {
+				r.Log.Error(err, "unable to migrate managed fields of child", typeName(r.ChildType), r.sanitize(actual))
+				r.Recorder.Eventf(parent, corev1.EventTypeWarning, "UpdateFailed",
+					"Failed to update %s %q: %v", typeName(r.ChildType), actual.GetName(), err)
+				return nil, err
+			}
+		}
+	}
+	desired.GetObjectKind().SetGroupVersionKind(gvks[0])
+
+	r.Log.Info("applying child", typeName(r.ChildType), r.sanitize(desired))
+	if err := r.Patch(ctx, desired, applyPatch, opts...); err != nil {
+		if apierrs.IsConflict(err) {
+			r.Recorder.Eventf(parent, corev1.EventTypeWarning, "ApplyConflict",
+				"Failed to apply %s %q, conflicts with another field manager: %v", typeName(r.ChildType), desired.GetName(), err)
+			if create {
+				return nil, err
+			}
+			return actual, err
+		}
+		r.Log.Error(err, "unable to apply child", typeName(r.ChildType), r.sanitize(desired))
+		if create {
+			r.Recorder.Eventf(parent, corev1.EventTypeWarning, "CreationFailed",
+				"Failed to create %s %q: %v", typeName(r.ChildType), desired.GetName(), err)
+		} else {
+			r.Recorder.Eventf(parent, corev1.EventTypeWarning, "UpdateFailed",
+				"Failed to update %s %q: %v", typeName(r.ChildType), desired.GetName(), err)
+		}
+		return nil, err
+	}
+	if create {
+		r.Recorder.Eventf(parent, corev1.EventTypeNormal, "Created",
+			"Created %s %q", typeName(r.ChildType), desired.GetName())
+	} else if desired.GetResourceVersion() != actual.GetResourceVersion() {
+		// applying an unchanged child is a no-op
+		r.Recorder.Eventf(parent, corev1.EventTypeNormal, "Updated",
+			"Updated %s %q", typeName(r.ChildType), desired.GetName())
+	}
+
+	return desired, nil
+}
+
+// appliedBy is true when the field manager has applied the object.
+func appliedBy(obj apis.Object, fieldManager string) bool {
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager == fieldManager && entry.Operation == metav1.ManagedFieldsOperationApply {
+			return true
+		}
+	}
+	return false
+}
+
+// applyPatch is a server-side apply patch of the fields set on an object. The
+// status and empty fields, like a null creationTimestamp, are left out so the
+// field manager does not claim them.
+var applyPatch client.Patch = applyConfigurationPatch{}
+
+type applyConfigurationPatch struct{}
+
+func (p applyConfigurationPatch) Type() types.PatchType {
+	return types.ApplyPatchType
+}
+
+func (p applyConfigurationPatch) Data(obj runtime.Object) ([]byte, error) {
+	fields, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	delete(fields, "status")
+	return json.Marshal(pruneEmptyFields(fields))
+}
+
+// pruneEmptyFields removes null values and objects without fields, including
+// objects within lists.
+func pruneEmptyFields(fields map[string]interface{}) map[string]interface{} {
+	for key, value := range fields {
+		switch v := value.(type) {
+		case nil:
+			delete(fields, key)
+		case map[string]interface{}:
+			if len(pruneEmptyFields(v)) == 0 {
+				delete(fields, key)
+			}
+		case []interface{}:
+			for _, item := range v {
+				if m, ok := item.(map[string]interface{}); ok {
+					pruneEmptyFields(m)
+				}
+			}
+		}
+	}
+	return fields
+}
+
 func (r *ChildReconciler) semanticEquals(a1, a2 apis.Object) bool {
 	fn := reflect.ValueOf(r.SemanticEquals)
 	out := fn.Call([]reflect.Value{
@@ -431,11 +814,15 @@ func (r *ChildReconciler) semanticEquals(a1, a2 apis.Object) bool {
 	return out[0].Bool()
 }
 
-func (r *ChildReconciler) desiredChild(parent apis.Object) (apis.Object, error) {
+func (r *ChildReconciler) desiredChild(ctx context.Context, parent apis.Object) (apis.Object, error) {
 	fn := reflect.ValueOf(r.DesiredChild)
-	out := fn.Call([]reflect.Value{
+	args := []reflect.Value{
 		reflect.ValueOf(parent),
-	})
+	}
+	if fn.Type().NumIn() == 2 {
+		args = append([]reflect.Value{reflect.ValueOf(ctx)}, args...)
+	}
+	out := fn.Call(args)
 	var obj apis.Object
 	if !out[0].IsNil() {
 		obj = out[0].Interface().(apis.Object)
@@ -503,6 +890,23 @@ func (r *ChildReconciler) sanitize(child apis.Object) interface{} {
 	return sanitized
 }
 
+func (r *ChildReconciler) filterChildren(children []apis.Object) []apis.Object {
+	if r.OurChild == nil {
+		return children
+	}
+	fn := reflect.ValueOf(r.OurChild)
+	ours := []apis.Object{}
+	for _, child := range children {
+		out := fn.Call([]reflect.Value{
+			reflect.ValueOf(child),
+		})
+		if out[0].Bool() {
+			ours = append(ours, child)
+		}
+	}
+	return ours
+}
+
 func (r *ChildReconciler) items(children runtime.Object) []apis.Object {
 	childrenValue := reflect.ValueOf(children).Elem()
 	itemsValue := childrenValue.FieldByName("Items")
@@ -513,6 +917,495 @@ func (r *ChildReconciler) items(children runtime.Object) []apis.Object {
 	return items
 }
 
+// ChildSetReconciler is a sub reconciler that manages a set of child
+// resources of the same type for a parent. Each child is identified by a key
+// that is stored on the child as the value of the IdentityLabelKey label. The
+// reconciler will ensure that the children match the desired set by:
+// - creating a child for each desired key that doesn't exist
+// - updating the existing child for each desired key
+// - removing children for keys that are no longer desired
+// - removing extra children, either without a key or with a duplicate key
+//
+// The flow for each reconciliation request is:
+// - DesiredChildren
+// - for each desired child, in order of key:
+//    - HarmonizeImmutableFields (optional)
+//    - SemanticEquals
+//    - MergeBeforeUpdate
+// - ReflectChildrenStatusOnParent
+//
+// During setup, the child resource type is registered to watch for changes. A
+// field indexer is configured for the owner on the IndexField.
+type ChildSetReconciler struct {
+	// ParentType of resource to reconcile
+	ParentType apis.Object
+	// ChildType is the resource being created/updated/deleted by the
+	// reconciler. For example, a parent Deployer would have a DomainMapping as
+	// a child for each of its domains.
+	ChildType apis.Object
+	// ChildListType is the listing type for the child type. For example,
+	// PodList is the list type for Pod
+	ChildListType runtime.Object
+
+	// Setup performs initialization on the manager and builder this reconciler
+	// will run with. It's common to setup field indexes and watch resources.
+	//
+	// +optional
+	Setup func(mgr ctrl.Manager, bldr *builder.Builder) error
+
+	// DesiredChildren returns the desired child objects for the given parent
+	// object keyed by their identity. Children that should not exist are
+	// omitted from the map. A nil child keeps the current child for the key
+	// unchanged, if there is one. Keys must be valid label values, an invalid
+	// key fails the reconciliation.
+	//
+	// Expected function signature:
+	//     func(parent apis.Object) (map[string]apis.Object, error)
+	DesiredChildren interface{}
+
+	// ReflectChildrenStatusOnParent updates the parent object's status with
+	// values from the reconciled children, keyed by their identity. Select
+	// types of error are passed, including:
+	// - apierrs.IsAlreadyExists
+	//
+	// Expected function signature:
+	//     func(parent apis.Object, children map[string]apis.Object, err error)
+	ReflectChildrenStatusOnParent interface{}
+
+	// HarmonizeImmutableFields allows fields that are immutable on the current
+	// object to be copied to the desired object in order to avoid creating
+	// updates which are guaranteed to fail.
+	//
+	// Expected function signature:
+	//     func(current, desired apis.Object)
+	//
+	// +optional
+	HarmonizeImmutableFields interface{}
+
+	// MergeBeforeUpdate copies desired fields on to the current object before
+	// calling update. Typically fields to copy are the Spec, Labels and
+	// Annotations.
+	//
+	// Expected function signature:
+	//     func(current, desired apis.Object)
+	MergeBeforeUpdate interface{}
+
+	// SemanticEquals compares two child resources returning true if there is a
+	// meaningful difference that should trigger an update.
+	//
+	// Expected function signature:
+	//     func(a1, a2 apis.Object) bool
+	SemanticEquals interface{}
+
+	// Sanitize is called with an object before logging the value. Any value may
+	// be returned. A meaningful subset of the resource is typically returned,
+	// like the Spec.
+	//
+	// Expected function signature:
+	//     func(child apis.Object) interface{}
+	//
+	// +optional
+	Sanitize interface{}
+
+	// OurChild is used when there are multiple reconcilers for the same
+	// ChildType controlled by the same parent. The function returns true for
+	// children managed by this reconciler, other children are ignored. The
+	// desired children must satisfy this function, otherwise they are
+	// orphaned.
+	//
+	// Expected function signature:
+	//     func(child apis.Object) bool
+	//
+	// +optional
+	OurChild interface{}
+
+	Config
+
+	// IndexField is used to index objects of the child's type based on their
+	// controlling owner. This field needs to be unique within the manager.
+	IndexField string
+
+	// IdentityLabelKey is the label holding the key of each child. The label
+	// is set on the desired children by the reconciler.
+	IdentityLabelKey string
+}
+
+func (r *ChildSetReconciler) SetupWithManager(mgr ctrl.Manager, bldr *builder.Builder) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	bldr.Owns(r.ChildType)
+
+	if err := IndexControllersOfType(mgr, r.IndexField, r.ParentType, r.ChildType, r.Scheme); err != nil {
+		return err
+	}
+
+	if r.Setup == nil {
+		return nil
+	}
+	return r.Setup(mgr, bldr)
+}
+
+// Validate checks that the types are set and that each function has the
+// expected signature for the ParentType and ChildType.
+func (r *ChildSetReconciler) Validate() error {
+	if err := validateTypes("ChildSetReconciler", r.ParentType, r.ChildType, r.ChildListType); err != nil {
+		return err
+	}
+	if r.IdentityLabelKey == "" {
+		return fmt.Errorf("ChildSetReconciler.IdentityLabelKey must be set")
+	}
+	parentType := reflect.TypeOf(r.ParentType)
+	childType := reflect.TypeOf(r.ChildType)
+
+	if err := validateFunc("ChildSetReconciler.DesiredChildren", r.DesiredChildren, funcSignature{
+		text: fmt.Sprintf("func(parent %s) (map[string]%s, error)", parentType, childType),
+		in:   []typeCheck{acceptsType(parentType)},
+		out:  []typeCheck{returnsObjectMap(childType), returnsError},
+	}); err != nil {
+		return err
+	}
+	if err := validateFunc("ChildSetReconciler.ReflectChildrenStatusOnParent", r.ReflectChildrenStatusOnParent, funcSignature{
+		text: fmt.Sprintf("func(parent %s, children map[string]%s, err error)", parentType, childType),
+		in:   []typeCheck{acceptsType(parentType), acceptsObjectMap(childType), acceptsType(errorType)},
+	}); err != nil {
+		return err
+	}
+	return r.childReconciler().validateSharedFuncs("ChildSetReconciler")
+}
+
+// validateFor checks that the ParentType accepts the parent type.
+func (r *ChildSetReconciler) validateFor(parentType reflect.Type) error {
+	return validateParentType("ChildSetReconciler", parentType, r.ParentType)
+}
+
+func (r *ChildSetReconciler) Reconcile(ctx context.Context, parent apis.Object) (ctrl.Result, error) {
+	children, err := r.reconcile(ctx, parent)
+	if err != nil {
+		if apierrs.IsAlreadyExists(err) {
+			r.Log.Info("unable to reconcile children, not owned", typeName(r.ParentType), parent)
+			r.reflectChildrenStatusOnParent(parent, children, err)
+			return ctrl.Result{}, nil
+		}
+		r.Log.Error(err, "unable to reconcile children", typeName(r.ParentType), parent)
+		return ctrl.Result{}, err
+	}
+	r.reflectChildrenStatusOnParent(parent, children, err)
+
+	return ctrl.Result{}, nil
+}
+
+func (r *ChildSetReconciler) reconcile(ctx context.Context, parent apis.Object) (map[string]apis.Object, error) {
+	// the single child reconciler shares the conventions for each child
+	cr := r.childReconciler()
+
+	children := r.ChildListType.DeepCopyObject().(runtime.Object)
+	if err := r.List(ctx, children, client.InNamespace(parent.GetNamespace()), client.MatchingField(r.IndexField, parent.GetName())); err != nil {
+		return nil, err
+	}
+	items := cr.filterChildren(cr.items(children))
+
+	desired, err := r.desiredChildren(parent)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(desired))
+	for key := range desired {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		child := desired[key]
+		if child == nil {
+			continue
+		}
+		// the key is stored as a label value on the child
+		if errs := validation.IsValidLabelValue(key); len(errs) != 0 {
+			return nil, fmt.Errorf("invalid key %q for %s %q, not a valid %s label value: %s", key, typeName(r.ChildType), child.GetName(), r.IdentityLabelKey, strings.Join(errs, "; "))
+		}
+		child.SetLabels(MergeMaps(child.GetLabels(), map[string]string{
+			r.IdentityLabelKey: key,
+		}))
+		if err := ctrl.SetControllerReference(parent, child, r.Scheme); err != nil {
+			return nil, err
+		}
+	}
+
+	// delete children that are no longer needed
+	actual := map[string]apis.Object{}
+	for _, child := range items {
+		key, ok := child.GetLabels()[r.IdentityLabelKey]
+		if _, wanted := desired[key]; ok && wanted && actual[key] == nil {
+			actual[key] = child
+			continue
+		}
+		r.Log.Info("deleting unwanted child", typeName(r.ChildType), cr.sanitize(child))
+		if err := r.Delete(ctx, child); err != nil {
+			r.Log.Error(err, "unable to delete unwanted child", typeName(r.ChildType), cr.sanitize(child))
+			r.Recorder.Eventf(parent, corev1.EventTypeWarning, "DeleteFailed",
+				"Failed to delete %s %q: %v", typeName(r.ChildType), child.GetName(), err)
+			return nil, err
+		}
+		r.Recorder.Eventf(parent, corev1.EventTypeNormal, "Deleted",
+			"Deleted %s %q", typeName(r.ChildType), child.GetName())
+	}
+
+	reconciled := map[string]apis.Object{}
+	var notOwned error
+	for _, key := range keys {
+		child := desired[key]
+		current, ok := actual[key]
+		if child == nil {
+			// keep the current child as is
+			if ok {
+				reconciled[key] = current
+			}
+			continue
+		}
+
+		// create child if it doesn't exist
+		if !ok {
+			r.Log.Info("creating child", typeName(r.ChildType), cr.sanitize(child))
+			if err := r.Create(ctx, child); err != nil {
+				r.Log.Error(err, "unable to create child", typeName(r.ChildType), cr.sanitize(child))
+				r.Recorder.Eventf(parent, corev1.EventTypeWarning, "CreationFailed",
+					"Failed to create %s %q: %v", typeName(r.ChildType), child.GetName(), err)
+				if apierrs.IsAlreadyExists(err) {
+					// reconcile the remaining children
+					if notOwned == nil {
+						notOwned = err
+					}
+					continue
+				}
+				return nil, err
+			}
+			r.Recorder.Eventf(parent, corev1.EventTypeNormal, "Created",
+				"Created %s %q", typeName(r.ChildType), child.GetName())
+			reconciled[key] = child
+			continue
+		}
+
+		// overwrite fields that should not be mutated
+		cr.harmonizeImmutableFields(current, child)
+
+		if cr.semanticEquals(child, current) {
+			// child is unchanged
+			reconciled[key] = current
+			continue
+		}
+
+		// update child with desired changes
+		updated := current.DeepCopyObject().(apis.Object)
+		cr.mergeBeforeUpdate(updated, child)
+		r.Log.Info("reconciling child", "diff", cmp.Diff(cr.sanitize(current), cr.sanitize(updated)))
+		if err := r.Update(ctx, updated); err != nil {
+			r.Log.Error(err, "unable to update child", typeName(r.ChildType), cr.sanitize(updated))
+			r.Recorder.Eventf(parent, corev1.EventTypeWarning, "UpdateFailed",
+				"Failed to update %s %q: %v", typeName(r.ChildType), updated.GetName(), err)
+			return nil, err
+		}
+		r.Recorder.Eventf(parent, corev1.EventTypeNormal, "Updated",
+			"Updated %s %q", typeName(r.ChildType), updated.GetName())
+		reconciled[key] = updated
+	}
+
+	return reconciled, notOwned
+}
+
+func (r *ChildSetReconciler) childReconciler() *ChildReconciler {
+	return &ChildReconciler{
+		ParentType:               r.ParentType,
+		ChildType:                r.ChildType,
+		ChildListType:            r.ChildListType,
+		HarmonizeImmutableFields: r.HarmonizeImmutableFields,
+		MergeBeforeUpdate:        r.MergeBeforeUpdate,
+		SemanticEquals:           r.SemanticEquals,
+		Sanitize:                 r.Sanitize,
+		OurChild:                 r.OurChild,
+		Config:                   r.Config,
+		IndexField:               r.IndexField,
+	}
+}
+
+func (r *ChildSetReconciler) desiredChildren(parent apis.Object) (map[string]apis.Object, error) {
+	fn := reflect.ValueOf(r.DesiredChildren)
+	out := fn.Call([]reflect.Value{
+		reflect.ValueOf(parent),
+	})
+	var err error
+	if !out[1].IsNil() {
+		err = out[1].Interface().(error)
+	}
+	children := map[string]apis.Object{}
+	iter := out[0].MapRange()
+	for iter.Next() {
+		if iter.Value().IsNil() {
+			children[iter.Key().String()] = nil
+			continue
+		}
+		children[iter.Key().String()] = iter.Value().Interface().(apis.Object)
+	}
+	return children, err
+}
+
+func (r *ChildSetReconciler) reflectChildrenStatusOnParent(parent apis.Object, children map[string]apis.Object, err error) {
+	fn := reflect.ValueOf(r.ReflectChildrenStatusOnParent)
+	childrenValue := reflect.MakeMap(fn.Type().In(1))
+	for key, child := range children {
+		childrenValue.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(child))
+	}
+	args := []reflect.Value{
+		reflect.ValueOf(parent),
+		childrenValue,
+		reflect.ValueOf(err),
+	}
+	if err == nil {
+		args[2] = reflect.New(fn.Type().In(2)).Elem()
+	}
+	fn.Call(args)
+}
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	objectType  = reflect.TypeOf((*apis.Object)(nil)).Elem()
+	resultType  = reflect.TypeOf(ctrl.Result{})
+)
+
+// typeCheck reports whether a parameter or return type of a function is
+// compatible with how the reconciler calls the function.
+type typeCheck func(t reflect.Type) bool
+
+// funcSignature describes a function signature the reconciler is able to
+// call. The text is included in errors for misconfigured functions.
+type funcSignature struct {
+	text string
+	in   []typeCheck
+	out  []typeCheck
+}
+
+func (s funcSignature) matches(t reflect.Type) bool {
+	if t.NumIn() != len(s.in) || t.NumOut() != len(s.out) || t.IsVariadic() {
+		return false
+	}
+	for i, check := range s.in {
+		if !check(t.In(i)) {
+			return false
+		}
+	}
+	for i, check := range s.out {
+		if !check(t.Out(i)) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateFunc checks that fn is a function matching one of the signatures.
+// The error names the field and the expected signatures.
+func validateFunc(field string, fn interface{}, signatures ...funcSignature) error {
+	t := reflect.TypeOf(fn)
+	if t != nil && t.Kind() == reflect.Func && !reflect.ValueOf(fn).IsNil() {
+		for _, signature := range signatures {
+			if signature.matches(t) {
+				return nil
+			}
+		}
+	}
+	expected := make([]string, len(signatures))
+	for i, signature := range signatures {
+		expected[i] = signature.text
+	}
+	found := "nil"
+	if t != nil {
+		found = t.String()
+	}
+	return fmt.Errorf("%s must have the signature %s, found %s", field, strings.Join(expected, " or "), found)
+}
+
+// validateTypes checks that the types of a child reconciler are set.
+func validateTypes(reconciler string, parentType, childType apis.Object, childListType runtime.Object) error {
+	if parentType == nil {
+		return fmt.Errorf("%s.ParentType must be set", reconciler)
+	}
+	if childType == nil {
+		return fmt.Errorf("%s.ChildType must be set", reconciler)
+	}
+	if childListType == nil {
+		return fmt.Errorf("%s.ChildListType must be set", reconciler)
+	}
+	return nil
+}
+
+// validateParentType checks that the parent type of a child reconciler accepts
+// the type of the parent it is reconciled with.
+func validateParentType(reconciler string, parentType reflect.Type, childParentType apis.Object) error {
+	if !parentType.AssignableTo(reflect.TypeOf(childParentType)) {
+		return fmt.Errorf("%s.ParentType must accept the parent %s, found %s", reconciler, parentType, reflect.TypeOf(childParentType))
+	}
+	return nil
+}
+
+// acceptsObject checks that a parameter accepts a resource. The concrete type
+// of the resource is not known.
+func acceptsObject(t reflect.Type) bool {
+	if t.Kind() == reflect.Interface {
+		return objectType.Implements(t)
+	}
+	return t.Implements(objectType)
+}
+
+// acceptsType checks that a parameter accepts values of the given type.
+func acceptsType(v reflect.Type) typeCheck {
+	return func(t reflect.Type) bool {
+		return v.AssignableTo(t)
+	}
+}
+
+// acceptsObjectMap checks that a parameter accepts a map of resources of the
+// given type, keyed by string.
+func acceptsObjectMap(v reflect.Type) typeCheck {
+	return func(t reflect.Type) bool {
+		return t.Kind() == reflect.Map && t.Key() == reflect.TypeOf("") && v.AssignableTo(t.Elem())
+	}
+}
+
+// returnsType checks that a return value is exactly the given type.
+func returnsType(v reflect.Type) typeCheck {
+	return func(t reflect.Type) bool {
+		return t == v
+	}
+}
+
+// returnsObject checks that a return value is a resource compatible with the
+// given type.
+func returnsObject(v reflect.Type) typeCheck {
+	return func(t reflect.Type) bool {
+		return t.AssignableTo(objectType) && v.AssignableTo(t)
+	}
+}
+
+// returnsObjectMap checks that a return value is a map of resources compatible
+// with the given type, keyed by string.
+func returnsObjectMap(v reflect.Type) typeCheck {
+	return func(t reflect.Type) bool {
+		return t.Kind() == reflect.Map && t.Key().Kind() == reflect.String && returnsObject(v)(t.Elem())
+	}
+}
+
+func returnsBool(t reflect.Type) bool {
+	return t.Kind() == reflect.Bool
+}
+
+func returnsError(t reflect.Type) bool {
+	return t == errorType
+}
+
+func returnsInterface(t reflect.Type) bool {
+	return t.Kind() == reflect.Interface
+}
+
 func typeName(i interface{}) string {
 	t := reflect.TypeOf(i)
 	// TODO do we need this?
@@ -522,6 +1415,20 @@ func typeName(i interface{}) string {
 	return t.Name()
 }
 
+// AggregateResults combines the results of multiple reconcilers into a single
+// result. The request is requeued if any result requeues, after the shortest
+// positive delay.
+func AggregateResults(results ...ctrl.Result) ctrl.Result {
+	aggregate := ctrl.Result{}
+	for _, result := range results {
+		aggregate.Requeue = aggregate.Requeue || result.Requeue
+		if result.RequeueAfter > 0 && (aggregate.RequeueAfter == 0 || result.RequeueAfter < aggregate.RequeueAfter) {
+			aggregate.RequeueAfter = result.RequeueAfter
+		}
+	}
+	return aggregate
+}
+
 // MergeMaps flattens a sequence of maps into a single map. Keys in latter maps
 // overwrite previous keys. None of the arguments are mutated.
 func MergeMaps(maps ...map[string]string) map[string]string {
@@ -0,0 +1,870 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/projectriff/system/pkg/apis"
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	knativev1alpha1 "github.com/projectriff/system/pkg/apis/knative/v1alpha1"
+	knativeservingv1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/knative/serving/v1alpha1"
+	"github.com/projectriff/system/pkg/controllers"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+	"github.com/projectriff/system/pkg/controllers/testing/factories"
+	"github.com/projectriff/system/pkg/refs"
+	"github.com/projectriff/system/pkg/tracker"
+)
+
+func TestChildSetReconciler(t *testing.T) {
+	testNamespace := "test-namespace"
+	testName := "test-deployer"
+	testDomain := "example.com"
+	testOtherDomain := "www.example.com"
+	testIdentityLabelKey := "test.projectriff.io/domain"
+
+	deployerConditionDomainMappingsReady := factories.Condition().Type(knativev1alpha1.DeployerConditionDomainMappingsReady).Info()
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = knativev1alpha1.AddToScheme(scheme)
+	_ = knativeservingv1alpha1.AddToScheme(scheme)
+
+	deployer := factories.DeployerKnative().
+		NamespaceName(testNamespace, testName).
+		StatusRouteRef(testName)
+	deployerDomains := deployer.
+		Domains(
+			knativev1alpha1.Domain{Name: testDomain},
+			knativev1alpha1.Domain{Name: testOtherDomain},
+		)
+	deployerDomain := deployer.
+		Domains(
+			knativev1alpha1.Domain{Name: testDomain},
+		)
+
+	mappingCreate := factories.KnativeDomainMapping().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.ControlledBy(deployer, scheme)
+		}).
+		RouteRef(testName)
+	mapping := mappingCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Name(testDomain)
+			om.Created(1)
+		})
+	otherMapping := mappingCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Name(testOtherDomain)
+			om.Created(1)
+		})
+
+	rts := rtesting.SubReconcilerTestSuite{{
+		Name:   "no children",
+		Parent: deployer,
+	}, {
+		Name:   "create children",
+		Parent: deployerDomains,
+		ExpectParent: deployerDomains.
+			StatusDomains(
+				knativev1alpha1.DomainStatus{Name: testDomain},
+				knativev1alpha1.DomainStatus{Name: testOtherDomain},
+			),
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Created",
+				`Created DomainMapping "%s"`, testDomain),
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Created",
+				`Created DomainMapping "%s"`, testOtherDomain),
+		},
+		ExpectCreates: rtesting.KeyedChildren(testIdentityLabelKey, map[string]rtesting.Factory{
+			testDomain: mappingCreate.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Name(testDomain)
+				}),
+			testOtherDomain: mappingCreate.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Name(testOtherDomain)
+				}),
+		}),
+	}, {
+		Name:   "children are unchanged",
+		Parent: deployerDomains,
+		GivenObjects: rtesting.KeyedChildren(testIdentityLabelKey, map[string]rtesting.Factory{
+			testDomain:      mapping,
+			testOtherDomain: otherMapping,
+		}),
+		ExpectParent: deployerDomains.
+			StatusDomains(
+				knativev1alpha1.DomainStatus{Name: testDomain},
+				knativev1alpha1.DomainStatus{Name: testOtherDomain},
+			),
+	}, {
+		Name: "keep children without desired state",
+		Parent: factories.DeployerKnative().
+			NamespaceName(testNamespace, testName).
+			Domains(
+				knativev1alpha1.Domain{Name: testDomain},
+				knativev1alpha1.Domain{Name: testOtherDomain},
+			),
+		GivenObjects: rtesting.KeyedChildren(testIdentityLabelKey, map[string]rtesting.Factory{
+			testDomain: mapping.
+				RouteRef("other-route"),
+		}),
+		ExpectParent: factories.DeployerKnative().
+			NamespaceName(testNamespace, testName).
+			Domains(
+				knativev1alpha1.Domain{Name: testDomain},
+				knativev1alpha1.Domain{Name: testOtherDomain},
+			).
+			StatusDomains(
+				knativev1alpha1.DomainStatus{Name: testDomain},
+			),
+	}, {
+		Name:   "update child",
+		Parent: deployerDomains,
+		GivenObjects: rtesting.KeyedChildren(testIdentityLabelKey, map[string]rtesting.Factory{
+			testDomain: mapping.
+				RouteRef("other-route"),
+			testOtherDomain: otherMapping,
+		}),
+		ExpectParent: deployerDomains.
+			StatusDomains(
+				knativev1alpha1.DomainStatus{Name: testDomain},
+				knativev1alpha1.DomainStatus{Name: testOtherDomain},
+			),
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated DomainMapping "%s"`, testDomain),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			rtesting.KeyedChild(testIdentityLabelKey, testDomain, mapping),
+		},
+	}, {
+		Name:   "update child error",
+		Parent: deployerDomains,
+		GivenObjects: rtesting.KeyedChildren(testIdentityLabelKey, map[string]rtesting.Factory{
+			testDomain: mapping.
+				RouteRef("other-route"),
+			testOtherDomain: otherMapping,
+		}),
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("update", "DomainMapping"),
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeWarning, "UpdateFailed",
+				`Failed to update DomainMapping "%s": inducing failure for update DomainMapping`, testDomain),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			rtesting.KeyedChild(testIdentityLabelKey, testDomain, mapping),
+		},
+	}, {
+		Name:   "delete unwanted children",
+		Parent: deployerDomain,
+		GivenObjects: []rtesting.Factory{
+			rtesting.KeyedChild(testIdentityLabelKey, testDomain, mapping),
+			rtesting.KeyedChild(testIdentityLabelKey, "removed.example.com", mappingCreate.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Name("removed.example.com")
+					om.Created(1)
+				})),
+			// children without a key are extra
+			otherMapping,
+		},
+		ExpectParent: deployerDomain.
+			StatusDomains(
+				knativev1alpha1.DomainStatus{Name: testDomain},
+			),
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted DomainMapping "%s"`, "removed.example.com"),
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted DomainMapping "%s"`, testOtherDomain),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "serving.knative.dev", Kind: "DomainMapping", Namespace: testNamespace, Name: "removed.example.com"},
+			{Group: "serving.knative.dev", Kind: "DomainMapping", Namespace: testNamespace, Name: testOtherDomain},
+		},
+	}, {
+		Name:   "delete duplicate children",
+		Parent: deployerDomain,
+		GivenObjects: []rtesting.Factory{
+			rtesting.KeyedChild(testIdentityLabelKey, testDomain, mapping),
+			rtesting.KeyedChild(testIdentityLabelKey, testDomain, mapping.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Name("%s-duplicate", testDomain)
+				})),
+		},
+		ExpectParent: deployerDomain.
+			StatusDomains(
+				knativev1alpha1.DomainStatus{Name: testDomain},
+			),
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted DomainMapping "%s-duplicate"`, testDomain),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "serving.knative.dev", Kind: "DomainMapping", Namespace: testNamespace, Name: testDomain + "-duplicate"},
+		},
+	}, {
+		Name:   "delete child error",
+		Parent: deployer,
+		GivenObjects: []rtesting.Factory{
+			rtesting.KeyedChild(testIdentityLabelKey, testDomain, mapping),
+		},
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("delete", "DomainMapping"),
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeWarning, "DeleteFailed",
+				`Failed to delete DomainMapping "%s": inducing failure for delete DomainMapping`, testDomain),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "serving.knative.dev", Kind: "DomainMapping", Namespace: testNamespace, Name: testDomain},
+		},
+	}, {
+		Name:   "child not owned",
+		Parent: deployerDomains,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("create", "DomainMapping", rtesting.InduceFailureOpts{
+				Name:  testDomain,
+				Error: apierrs.NewAlreadyExists(schema.GroupResource{}, testDomain),
+			}),
+		},
+		ExpectParent: deployerDomains.
+			StatusConditions(
+				deployerConditionDomainMappingsReady.False().Reason("NotOwned", `There is an existing DomainMapping "example.com" that the Deployer does not own.`),
+			).
+			StatusDomains(
+				knativev1alpha1.DomainStatus{Name: testOtherDomain},
+			),
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeWarning, "CreationFailed",
+				`Failed to create DomainMapping "%s":  "%s" already exists`, testDomain, testDomain),
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Created",
+				`Created DomainMapping "%s"`, testOtherDomain),
+		},
+		ExpectCreates: rtesting.KeyedChildren(testIdentityLabelKey, map[string]rtesting.Factory{
+			testDomain: mappingCreate.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Name(testDomain)
+				}),
+			testOtherDomain: mappingCreate.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Name(testOtherDomain)
+				}),
+		}),
+	}, {
+		Name:   "list children error",
+		Parent: deployerDomains,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("list", "DomainMappingList"),
+		},
+		ShouldErr: true,
+	}, {
+		Name: "invalid key",
+		Parent: deployer.
+			Domains(
+				knativev1alpha1.Domain{Name: "*.example.com"},
+			),
+		ShouldErr: true,
+		Verify: func(t *testing.T, result ctrl.Result, err error) {
+			expected := `invalid key "*.example.com" for DomainMapping "*.example.com", not a valid test.projectriff.io/domain label value: `
+			if err == nil || !strings.HasPrefix(err.Error(), expected) {
+				t.Errorf("expected error to start with %q, got %v", expected, err)
+			}
+		},
+	}}
+
+	rts.Test(t, scheme, func(t *testing.T, rtc *rtesting.SubReconcilerTestcase, client client.Client, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) controllers.SubReconciler {
+		return &controllers.ChildSetReconciler{
+			ParentType:    &knativev1alpha1.Deployer{},
+			ChildType:     &knativeservingv1alpha1.DomainMapping{},
+			ChildListType: &knativeservingv1alpha1.DomainMappingList{},
+
+			DesiredChildren: func(parent *knativev1alpha1.Deployer) (map[string]*knativeservingv1alpha1.DomainMapping, error) {
+				children := map[string]*knativeservingv1alpha1.DomainMapping{}
+				for _, domain := range parent.Spec.Domains {
+					if parent.Status.RouteRef == nil {
+						// keep current mappings until the route is known
+						children[domain.Name] = nil
+						continue
+					}
+					child := mappingCreate.Create().(*knativeservingv1alpha1.DomainMapping)
+					child.OwnerReferences = nil
+					child.Name = domain.Name
+					children[domain.Name] = child
+				}
+				return children, nil
+			},
+			ReflectChildrenStatusOnParent: func(parent *knativev1alpha1.Deployer, children map[string]*knativeservingv1alpha1.DomainMapping, err error) {
+				if apierrs.IsAlreadyExists(err) {
+					name := err.(apierrs.APIStatus).Status().Details.Name
+					parent.Status.MarkDomainMappingNotOwned(name)
+				}
+				parent.Status.Domains = nil
+				for key := range children {
+					parent.Status.Domains = append(parent.Status.Domains, knativev1alpha1.DomainStatus{Name: key})
+				}
+				sort.Slice(parent.Status.Domains, func(i, j int) bool {
+					return parent.Status.Domains[i].Name < parent.Status.Domains[j].Name
+				})
+			},
+			MergeBeforeUpdate: func(current, desired *knativeservingv1alpha1.DomainMapping) {
+				current.Labels = desired.Labels
+				current.Spec = desired.Spec
+			},
+			SemanticEquals: func(a1, a2 *knativeservingv1alpha1.DomainMapping) bool {
+				return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
+					equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+			},
+
+			Config: controllers.Config{
+				Client:   client,
+				Recorder: recorder,
+				Scheme:   scheme,
+				Log:      log,
+				Tracker:  tracker,
+			},
+			IndexField:       ".metadata.deployerController",
+			IdentityLabelKey: testIdentityLabelKey,
+		}
+	})
+}
+
+func TestChildReconcilerServerSideApply(t *testing.T) {
+	testNamespace := "test-namespace"
+	testName := "test-deployer"
+	testImage := "example.com/repo@sha256:cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e"
+	testFieldManager := "test-deployer-controller"
+
+	deployerConditionDeploymentReady := factories.Condition().Type(corev1alpha1.DeployerConditionDeploymentReady)
+	deployerConditionReady := factories.Condition().Type(corev1alpha1.DeployerConditionReady)
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = corev1alpha1.AddToScheme(scheme)
+
+	deployerMinimal := factories.DeployerCore().
+		NamespaceName(testNamespace, testName)
+	deployer := deployerMinimal.
+		StatusLatestImage(testImage)
+
+	deploymentDesired := factories.Deployment().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.Name(testName)
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.ControlledBy(deployer, scheme)
+		}).
+		HandlerContainer(func(container *corev1.Container) {
+			container.Image = testImage
+		})
+	deploymentGiven := deploymentDesired.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Created(1)
+		}).
+		// replicas are owned by the autoscaler
+		Replicas(3)
+
+	rts := rtesting.SubReconcilerTestSuite{{
+		Name:   "create child",
+		Parent: deployer,
+		ExpectParent: deployer.
+			StatusDeploymentRef(testName),
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Created",
+				`Created Deployment "%s"`, testName),
+		},
+		ExpectApplies: []rtesting.Factory{
+			deploymentDesired,
+		},
+	}, {
+		Name:   "apply only set fields",
+		Parent: deployer,
+		WithReactors: []rtesting.ReactionFunc{
+			func(action rtesting.Action) (bool, runtime.Object, error) {
+				patch, ok := action.(rtesting.PatchAction)
+				if !ok {
+					return false, nil, nil
+				}
+				var fields struct {
+					Metadata map[string]interface{} `json:"metadata"`
+					Spec     map[string]interface{} `json:"spec"`
+					Status   interface{}            `json:"status"`
+				}
+				if err := json.Unmarshal(patch.GetPatch(), &fields); err != nil {
+					return true, nil, err
+				}
+				if _, ok := fields.Metadata["creationTimestamp"]; ok {
+					return true, nil, fmt.Errorf("unexpected metadata.creationTimestamp in %s", patch.GetPatch())
+				}
+				if _, ok := fields.Spec["strategy"]; ok {
+					return true, nil, fmt.Errorf("unexpected spec.strategy in %s", patch.GetPatch())
+				}
+				if fields.Status != nil {
+					return true, nil, fmt.Errorf("unexpected status in %s", patch.GetPatch())
+				}
+				return false, nil, nil
+			},
+		},
+		ExpectParent: deployer.
+			StatusDeploymentRef(testName),
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Created",
+				`Created Deployment "%s"`, testName),
+		},
+		ExpectApplies: []rtesting.Factory{
+			deploymentDesired,
+		},
+	}, {
+		Name:   "apply unchanged child",
+		Parent: deployer,
+		GivenObjects: []rtesting.Factory{
+			deploymentGiven,
+		},
+		ExpectParent: deployer.
+			StatusDeploymentRef(testName),
+		ExpectApplies: []rtesting.Factory{
+			deploymentDesired,
+		},
+	}, {
+		Name:   "update child",
+		Parent: deployer,
+		GivenObjects: []rtesting.Factory{
+			deploymentGiven.
+				HandlerContainer(func(container *corev1.Container) {
+					container.Image = "example.com/repo:previous"
+				}),
+		},
+		ExpectParent: deployer.
+			StatusDeploymentRef(testName),
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Deployment "%s"`, testName),
+		},
+		ExpectApplies: []rtesting.Factory{
+			deploymentDesired,
+		},
+	}, {
+		Name:   "apply conflict",
+		Parent: deployer,
+		GivenObjects: []rtesting.Factory{
+			deploymentGiven,
+		},
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("patch", "Deployment", rtesting.InduceFailureOpts{
+				Error: apierrs.NewConflict(schema.GroupResource{Group: "apps", Resource: "deployments"}, testName, fmt.Errorf("conflict with %q: .spec.template", "kubectl")),
+			}),
+		},
+		ExpectParent: deployer.
+			StatusConditions(
+				deployerConditionDeploymentReady.False().Reason("ApplyConflict", `Operation cannot be fulfilled on deployments.apps "test-deployer": conflict with "kubectl": .spec.template`),
+				deployerConditionReady.False().Reason("ApplyConflict", `Operation cannot be fulfilled on deployments.apps "test-deployer": conflict with "kubectl": .spec.template`),
+			).
+			StatusDeploymentRef(testName),
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeWarning, "ApplyConflict",
+				`Failed to apply Deployment "%s", conflicts with another field manager: Operation cannot be fulfilled on deployments.apps "%s": conflict with "kubectl": .spec.template`, testName, testName),
+		},
+		ExpectApplies: []rtesting.Factory{
+			deploymentDesired,
+		},
+	}, {
+		Name:   "apply error",
+		Parent: deployer,
+		GivenObjects: []rtesting.Factory{
+			deploymentGiven,
+		},
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("patch", "Deployment"),
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeWarning, "UpdateFailed",
+				`Failed to update Deployment "%s": inducing failure for patch Deployment`, testName),
+		},
+		ExpectApplies: []rtesting.Factory{
+			deploymentDesired,
+		},
+	}, {
+		Name:   "create child, existing resource not owned",
+		Parent: deployer,
+		GivenObjects: []rtesting.Factory{
+			factories.Deployment().
+				NamespaceName(testNamespace, testName).
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Created(1)
+				}),
+		},
+		ExpectParent: deployer,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeWarning, "CreationFailed",
+				`Failed to create Deployment "%s": deployment.apps "%s" already exists`, testName, testName),
+		},
+	}, {
+		Name:   "delete unwanted child",
+		Parent: deployerMinimal,
+		GivenObjects: []rtesting.Factory{
+			deploymentGiven,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Deployment "%s"`, testName),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "apps", Kind: "Deployment", Namespace: testNamespace, Name: testName},
+		},
+	}}
+
+	rts.Test(t, scheme, func(t *testing.T, rtc *rtesting.SubReconcilerTestcase, client client.Client, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) controllers.SubReconciler {
+		deployerCondSet := apis.NewLivingConditionSet(
+			corev1alpha1.DeployerConditionDeploymentReady,
+		)
+
+		return &controllers.ChildReconciler{
+			ParentType:    &corev1alpha1.Deployer{},
+			ChildType:     &appsv1.Deployment{},
+			ChildListType: &appsv1.DeploymentList{},
+
+			DesiredChild: func(parent *corev1alpha1.Deployer) (*appsv1.Deployment, error) {
+				if parent.Status.LatestImage == "" {
+					return nil, nil
+				}
+				child := deploymentDesired.Create().(*appsv1.Deployment)
+				child.OwnerReferences = nil
+				return child, nil
+			},
+			ReflectChildStatusOnParent: func(parent *corev1alpha1.Deployer, child *appsv1.Deployment, err error) {
+				if apierrs.IsConflict(err) {
+					deployerCondSet.Manage(&parent.Status).MarkFalse(corev1alpha1.DeployerConditionDeploymentReady, "ApplyConflict", err.Error())
+				}
+				if child == nil {
+					parent.Status.DeploymentRef = nil
+				} else {
+					parent.Status.DeploymentRef = refs.NewTypedLocalObjectReferenceForObject(child, scheme)
+				}
+			},
+
+			Config: controllers.Config{
+				Client:   client,
+				Recorder: recorder,
+				Scheme:   scheme,
+				Log:      log,
+				Tracker:  tracker,
+			},
+			OurChild: func(child *appsv1.Deployment) bool {
+				// the fake client does not filter by the index field
+				return child.Labels[corev1alpha1.DeployerLabelKey] == testName
+			},
+			IndexField:   ".metadata.deploymentController",
+			FieldManager: testFieldManager,
+		}
+	})
+}
+
+func TestReconcilerValidate(t *testing.T) {
+	childReconciler := func(mutate func(r *controllers.ChildReconciler)) *controllers.ChildReconciler {
+		r := &controllers.ChildReconciler{
+			ParentType:    &corev1alpha1.Deployer{},
+			ChildType:     &appsv1.Deployment{},
+			ChildListType: &appsv1.DeploymentList{},
+			DesiredChild: func(parent *corev1alpha1.Deployer) (*appsv1.Deployment, error) {
+				return nil, nil
+			},
+			ReflectChildStatusOnParent: func(parent *corev1alpha1.Deployer, child *appsv1.Deployment, err error) {},
+			MergeBeforeUpdate:          func(current, desired *appsv1.Deployment) {},
+			SemanticEquals: func(a1, a2 *appsv1.Deployment) bool {
+				return true
+			},
+		}
+		if mutate != nil {
+			mutate(r)
+		}
+		return r
+	}
+	childSetReconciler := func(mutate func(r *controllers.ChildSetReconciler)) *controllers.ChildSetReconciler {
+		r := &controllers.ChildSetReconciler{
+			ParentType:    &knativev1alpha1.Deployer{},
+			ChildType:     &knativeservingv1alpha1.DomainMapping{},
+			ChildListType: &knativeservingv1alpha1.DomainMappingList{},
+			DesiredChildren: func(parent *knativev1alpha1.Deployer) (map[string]*knativeservingv1alpha1.DomainMapping, error) {
+				return nil, nil
+			},
+			ReflectChildrenStatusOnParent: func(parent *knativev1alpha1.Deployer, children map[string]*knativeservingv1alpha1.DomainMapping, err error) {
+			},
+			MergeBeforeUpdate: func(current, desired *knativeservingv1alpha1.DomainMapping) {},
+			SemanticEquals: func(a1, a2 *knativeservingv1alpha1.DomainMapping) bool {
+				return true
+			},
+			IdentityLabelKey: "test.projectriff.io/domain",
+		}
+		if mutate != nil {
+			mutate(r)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name       string
+		reconciler interface{ Validate() error }
+		expected   string
+	}{{
+		name: "sync",
+		reconciler: &controllers.SyncReconciler{
+			Sync: func(ctx context.Context, parent *corev1alpha1.Deployer) error {
+				return nil
+			},
+		},
+	}, {
+		name: "sync with result",
+		reconciler: &controllers.SyncReconciler{
+			Sync: func(ctx context.Context, parent *corev1alpha1.Deployer) (ctrl.Result, error) {
+				return ctrl.Result{}, nil
+			},
+		},
+	}, {
+		name:       "sync missing",
+		reconciler: &controllers.SyncReconciler{},
+		expected:   "SyncReconciler.Sync must have the signature func(ctx context.Context, parent apis.Object) error or func(ctx context.Context, parent apis.Object) (ctrl.Result, error), found nil",
+	}, {
+		name: "sync without context",
+		reconciler: &controllers.SyncReconciler{
+			Sync: func(parent *corev1alpha1.Deployer) error {
+				return nil
+			},
+		},
+		expected: "SyncReconciler.Sync must have the signature func(ctx context.Context, parent apis.Object) error or func(ctx context.Context, parent apis.Object) (ctrl.Result, error), found func(*v1alpha1.Deployer) error",
+	}, {
+		name:       "child",
+		reconciler: childReconciler(nil),
+	}, {
+		name: "child with optional functions",
+		reconciler: childReconciler(func(r *controllers.ChildReconciler) {
+			r.HarmonizeImmutableFields = func(current, desired *appsv1.Deployment) {}
+			r.Sanitize = func(child *appsv1.Deployment) interface{} {
+				return child.Spec
+			}
+			r.OurChild = func(child *appsv1.Deployment) bool {
+				return true
+			}
+		}),
+	}, {
+		name: "child desired with context",
+		reconciler: childReconciler(func(r *controllers.ChildReconciler) {
+			r.DesiredChild = func(ctx context.Context, parent *corev1alpha1.Deployer) (*appsv1.Deployment, error) {
+				return nil, nil
+			}
+		}),
+	}, {
+		name: "child with field manager",
+		reconciler: childReconciler(func(r *controllers.ChildReconciler) {
+			r.FieldManager = "test-manager"
+			r.MergeBeforeUpdate = nil
+			r.SemanticEquals = nil
+		}),
+	}, {
+		name: "child missing type",
+		reconciler: childReconciler(func(r *controllers.ChildReconciler) {
+			r.ChildType = nil
+		}),
+		expected: "ChildReconciler.ChildType must be set",
+	}, {
+		name: "child desired for wrong parent",
+		reconciler: childReconciler(func(r *controllers.ChildReconciler) {
+			r.DesiredChild = func(parent *corev1.ConfigMap) (*appsv1.Deployment, error) {
+				return nil, nil
+			}
+		}),
+		expected: "ChildReconciler.DesiredChild must have the signature func(parent *v1alpha1.Deployer) (*v1.Deployment, error) or func(ctx context.Context, parent *v1alpha1.Deployer) (*v1.Deployment, error), found func(*v1.ConfigMap) (*v1.Deployment, error)",
+	}, {
+		name: "child desired of wrong type",
+		reconciler: childReconciler(func(r *controllers.ChildReconciler) {
+			r.DesiredChild = func(parent *corev1alpha1.Deployer) (*corev1.Service, error) {
+				return nil, nil
+			}
+		}),
+		expected: "ChildReconciler.DesiredChild must have the signature func(parent *v1alpha1.Deployer) (*v1.Deployment, error) or func(ctx context.Context, parent *v1alpha1.Deployer) (*v1.Deployment, error), found func(*v1alpha1.Deployer) (*v1.Service, error)",
+	}, {
+		name: "child reflected without error",
+		reconciler: childReconciler(func(r *controllers.ChildReconciler) {
+			r.ReflectChildStatusOnParent = func(parent *corev1alpha1.Deployer, child *appsv1.Deployment) {}
+		}),
+		expected: "ChildReconciler.ReflectChildStatusOnParent must have the signature func(parent *v1alpha1.Deployer, child *v1.Deployment, err error), found func(*v1alpha1.Deployer, *v1.Deployment)",
+	}, {
+		name: "child missing merge",
+		reconciler: childReconciler(func(r *controllers.ChildReconciler) {
+			r.MergeBeforeUpdate = nil
+		}),
+		expected: "ChildReconciler.MergeBeforeUpdate must have the signature func(current, desired *v1.Deployment), found nil",
+	}, {
+		name: "child semantic equals without result",
+		reconciler: childReconciler(func(r *controllers.ChildReconciler) {
+			r.SemanticEquals = func(a1, a2 *appsv1.Deployment) {}
+		}),
+		expected: "ChildReconciler.SemanticEquals must have the signature func(a1, a2 *v1.Deployment) bool, found func(*v1.Deployment, *v1.Deployment)",
+	}, {
+		name: "child sanitized to value",
+		reconciler: childReconciler(func(r *controllers.ChildReconciler) {
+			r.Sanitize = func(child *appsv1.Deployment) appsv1.DeploymentSpec {
+				return child.Spec
+			}
+		}),
+		expected: "ChildReconciler.Sanitize must have the signature func(child *v1.Deployment) interface{}, found func(*v1.Deployment) v1.DeploymentSpec",
+	}, {
+		name: "child ours of wrong type",
+		reconciler: childReconciler(func(r *controllers.ChildReconciler) {
+			r.OurChild = func(child *corev1.Service) bool {
+				return true
+			}
+		}),
+		expected: "ChildReconciler.OurChild must have the signature func(child *v1.Deployment) bool, found func(*v1.Service) bool",
+	}, {
+		name:       "child set",
+		reconciler: childSetReconciler(nil),
+	}, {
+		name: "child set missing identity label",
+		reconciler: childSetReconciler(func(r *controllers.ChildSetReconciler) {
+			r.IdentityLabelKey = ""
+		}),
+		expected: "ChildSetReconciler.IdentityLabelKey must be set",
+	}, {
+		name: "child set desired as slice",
+		reconciler: childSetReconciler(func(r *controllers.ChildSetReconciler) {
+			r.DesiredChildren = func(parent *knativev1alpha1.Deployer) ([]*knativeservingv1alpha1.DomainMapping, error) {
+				return nil, nil
+			}
+		}),
+		expected: "ChildSetReconciler.DesiredChildren must have the signature func(parent *v1alpha1.Deployer) (map[string]*v1alpha1.DomainMapping, error), found func(*v1alpha1.Deployer) ([]*v1alpha1.DomainMapping, error)",
+	}, {
+		name: "child set ours of wrong type",
+		reconciler: childSetReconciler(func(r *controllers.ChildSetReconciler) {
+			r.OurChild = func(child *appsv1.Deployment) bool {
+				return true
+			}
+		}),
+		expected: "ChildSetReconciler.OurChild must have the signature func(child *v1alpha1.DomainMapping) bool, found func(*v1.Deployment) bool",
+	}, {
+		name: "parent",
+		reconciler: &controllers.ParentReconciler{
+			Type: &corev1alpha1.Deployer{},
+			SubReconcilers: []controllers.SubReconciler{
+				childReconciler(nil),
+			},
+		},
+	}, {
+		name: "parent with invalid sub reconciler",
+		reconciler: &controllers.ParentReconciler{
+			Type: &corev1alpha1.Deployer{},
+			SubReconcilers: []controllers.SubReconciler{
+				childReconciler(nil),
+				childReconciler(func(r *controllers.ChildReconciler) {
+					r.OurChild = func(child *appsv1.Deployment) {}
+				}),
+			},
+		},
+		expected: "Deployer SubReconcilers[1]: ChildReconciler.OurChild must have the signature func(child *v1.Deployment) bool, found func(*v1.Deployment)",
+	}, {
+		name: "parent with sync for any parent",
+		reconciler: &controllers.ParentReconciler{
+			Type: &corev1alpha1.Deployer{},
+			SubReconcilers: []controllers.SubReconciler{
+				&controllers.SyncReconciler{
+					Sync: func(ctx context.Context, parent apis.Object) error {
+						return nil
+					},
+				},
+			},
+		},
+	}, {
+		name: "parent with sync for another parent",
+		reconciler: &controllers.ParentReconciler{
+			Type: &corev1alpha1.Deployer{},
+			SubReconcilers: []controllers.SubReconciler{
+				&controllers.SyncReconciler{
+					Sync: func(ctx context.Context, parent *corev1.ConfigMap) error {
+						return nil
+					},
+				},
+			},
+		},
+		expected: "Deployer SubReconcilers[0]: SyncReconciler.Sync must have the signature func(ctx context.Context, parent *v1alpha1.Deployer) error or func(ctx context.Context, parent *v1alpha1.Deployer) (ctrl.Result, error), found func(context.Context, *v1.ConfigMap) error",
+	}, {
+		name: "parent with child for another parent",
+		reconciler: &controllers.ParentReconciler{
+			Type: &corev1alpha1.Deployer{},
+			SubReconcilers: []controllers.SubReconciler{
+				childReconciler(func(r *controllers.ChildReconciler) {
+					r.ParentType = &corev1.ConfigMap{}
+					r.DesiredChild = func(parent *corev1.ConfigMap) (*appsv1.Deployment, error) {
+						return nil, nil
+					}
+					r.ReflectChildStatusOnParent = func(parent *corev1.ConfigMap, child *appsv1.Deployment, err error) {}
+				}),
+			},
+		},
+		expected: "Deployer SubReconcilers[0]: ChildReconciler.ParentType must accept the parent *v1alpha1.Deployer, found *v1.ConfigMap",
+	}, {
+		name: "parent with child set for another parent",
+		reconciler: &controllers.ParentReconciler{
+			Type: &corev1alpha1.Deployer{},
+			SubReconcilers: []controllers.SubReconciler{
+				childReconciler(nil),
+				childSetReconciler(func(r *controllers.ChildSetReconciler) {
+					r.ParentType = &corev1.ConfigMap{}
+					r.DesiredChildren = func(parent *corev1.ConfigMap) (map[string]*knativeservingv1alpha1.DomainMapping, error) {
+						return nil, nil
+					}
+					r.ReflectChildrenStatusOnParent = func(parent *corev1.ConfigMap, children map[string]*knativeservingv1alpha1.DomainMapping, err error) {
+					}
+				}),
+			},
+		},
+		expected: "Deployer SubReconcilers[1]: ChildSetReconciler.ParentType must accept the parent *v1alpha1.Deployer, found *v1.ConfigMap",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := ""
+			if err := test.reconciler.Validate(); err != nil {
+				actual = err.Error()
+			}
+			if actual != test.expected {
+				t.Errorf("Validate() = %q, expected %q", actual, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+)
+
+// StashKey identifies a value stashed for the duration of a reconcile request
+type StashKey string
+
+type stashKey struct{}
+
+// WithStash returns a context able to hold values shared between the sub
+// reconcilers of a single reconcile request.
+func WithStash(ctx context.Context) context.Context {
+	return context.WithValue(ctx, stashKey{}, map[StashKey]interface{}{})
+}
+
+// StashValue holds a value for sub reconcilers that run later in the same
+// reconcile request. The context must be created with WithStash.
+func StashValue(ctx context.Context, key StashKey, value interface{}) {
+	stash, ok := ctx.Value(stashKey{}).(map[StashKey]interface{})
+	if !ok {
+		panic("context is missing a stash, use WithStash")
+	}
+	stash[key] = value
+}
+
+// RetrieveValue returns the value stashed for the key, or nil if no value is
+// stashed.
+func RetrieveValue(ctx context.Context, key StashKey) interface{} {
+	stash, _ := ctx.Value(stashKey{}).(map[StashKey]interface{})
+	return stash[key]
+}
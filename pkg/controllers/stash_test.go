@@ -0,0 +1,48 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/projectriff/system/pkg/controllers"
+)
+
+func TestStash(t *testing.T) {
+	ctx := controllers.WithStash(context.Background())
+
+	if actual := controllers.RetrieveValue(ctx, "key"); actual != nil {
+		t.Errorf("RetrieveValue() = %v, expected nil", actual)
+	}
+	controllers.StashValue(ctx, "key", "value")
+	if actual := controllers.RetrieveValue(ctx, "key"); actual != "value" {
+		t.Errorf("RetrieveValue() = %v, expected value", actual)
+	}
+	if actual := controllers.RetrieveValue(context.Background(), "key"); actual != nil {
+		t.Errorf("RetrieveValue() without stash = %v, expected nil", actual)
+	}
+}
+
+func TestStashValue_MissingStash(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("StashValue() expected to panic")
+		}
+	}()
+	controllers.StashValue(context.Background(), "key", "value")
+}
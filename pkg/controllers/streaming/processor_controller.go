@@ -46,6 +46,7 @@ import (
 	"github.com/projectriff/system/pkg/controllers"
 	"github.com/projectriff/system/pkg/refs"
 	"github.com/projectriff/system/pkg/tracker"
+	"github.com/projectriff/system/pkg/validation"
 )
 
 const (
@@ -139,6 +140,7 @@ func (r *ProcessorReconciler) reconcile(ctx context.Context, logger logr.Logger,
 	}
 
 	// resolve image
+	previousImage := processor.Status.LatestImage
 	if processor.Spec.Build != nil {
 		if processor.Spec.Build.FunctionRef != "" {
 			functionNSName := types.NamespacedName{Namespace: processor.Namespace, Name: processor.Spec.Build.FunctionRef}
@@ -155,7 +157,7 @@ func (r *ProcessorReconciler) reconcile(ctx context.Context, logger logr.Logger,
 				return ctrl.Result{Requeue: true}, err
 			}
 
-			processor.Status.LatestImage = function.Status.LatestImage
+			processor.Status.PropagateBuildStatus(processor.Spec.Build.Pin, &function.Status.BuildStatus)
 
 		} else if processor.Spec.Build.ContainerRef != "" {
 			containerNSName := types.NamespacedName{Namespace: processor.Namespace, Name: processor.Spec.Build.ContainerRef}
@@ -172,11 +174,22 @@ func (r *ProcessorReconciler) reconcile(ctx context.Context, logger logr.Logger,
 				return ctrl.Result{Requeue: true}, err
 			}
 
-			processor.Status.LatestImage = container.Status.LatestImage
+			processor.Status.PropagateBuildStatus(processor.Spec.Build.Pin, &container.Status.BuildStatus)
 		}
 	} else {
 		// defaulter guarantees a container
 		processor.Status.LatestImage = processor.Spec.Template.Spec.Containers[0].Image
+		processor.Status.PinnedBuild = nil
+		processor.Status.AvailableImage = ""
+	}
+	if image := processor.Status.LatestImage; image != "" {
+		if err := validation.ActiveImagePolicy().CheckImage(image); err != nil {
+			// keep rolling out the previous image
+			processor.Status.LatestImage = previousImage
+			processor.Status.MarkImageNotAllowed(err.Error())
+		} else {
+			processor.Status.MarkImageAllowed()
+		}
 	}
 
 	if processor.Status.LatestImage == "" {
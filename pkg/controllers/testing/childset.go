@@ -0,0 +1,66 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"sort"
+
+	"github.com/projectriff/system/pkg/apis"
+)
+
+// KeyedChild labels a child with its key in the set of children managed by a
+// ChildSetReconciler.
+func KeyedChild(identityLabelKey, key string, child Factory) Factory {
+	return &keyedChild{
+		identityLabelKey: identityLabelKey,
+		key:              key,
+		child:            child,
+	}
+}
+
+// KeyedChildren labels each child with its key in the set of children managed
+// by a ChildSetReconciler. The children are returned in order of key, which is
+// the order children are created and updated by the reconciler.
+func KeyedChildren(identityLabelKey string, children map[string]Factory) []Factory {
+	keys := make([]string, 0, len(children))
+	for key := range children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	factories := make([]Factory, len(keys))
+	for i, key := range keys {
+		factories[i] = KeyedChild(identityLabelKey, key, children[key])
+	}
+	return factories
+}
+
+type keyedChild struct {
+	identityLabelKey string
+	key              string
+	child            Factory
+}
+
+func (f *keyedChild) Create() apis.Object {
+	child := f.child.Create()
+	labels := child.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[f.identityLabelKey] = f.key
+	child.SetLabels(labels)
+	return child
+}
@@ -18,11 +18,17 @@ package testing
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"strings"
 
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	clientgotesting "k8s.io/client-go/testing"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -36,6 +42,7 @@ type clientWrapper struct {
 	createActions       []CreateAction
 	updateActions       []UpdateAction
 	deleteActions       []DeleteAction
+	applyActions        []PatchAction
 	statusUpdateActions []UpdateAction
 	genCount            int
 	reactionChain       []Reactor
@@ -50,6 +57,7 @@ func newClientWrapperWithScheme(scheme *runtime.Scheme, objs ...runtime.Object)
 		createActions:       []CreateAction{},
 		updateActions:       []UpdateAction{},
 		deleteActions:       []DeleteAction{},
+		applyActions:        []PatchAction{},
 		statusUpdateActions: []UpdateAction{},
 		genCount:            0,
 		reactionChain:       []Reactor{},
@@ -100,6 +108,10 @@ func (w *clientWrapper) objmeta(obj runtime.Object) (schema.GroupVersionResource
 	if _, ok := obj.(metav1.ListMetaAccessor); ok {
 		return gvr, "", "", nil
 	}
+	if objmeta, ok := obj.(metav1.Object); ok {
+		// unstructured objects
+		return gvr, objmeta.GetNamespace(), objmeta.GetName(), nil
+	}
 
 	return schema.GroupVersionResource{}, "", "", fmt.Errorf("invalid object")
 }
@@ -210,8 +222,186 @@ func (w *clientWrapper) Update(ctx context.Context, obj runtime.Object, opts ...
 
 	return w.client.Update(ctx, obj, opts...)
 }
+
+// Patch is only implemented for server-side apply. The applied fields are
+// merged into the existing object. Fields of nested objects the field manager
+// applied before, as recorded in the managed fields, are removed when they are
+// omitted from the applied object, other fields are retained. Conflicts
+// between field managers are not detected.
 func (w *clientWrapper) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
-	panic(fmt.Errorf("Patch() is not implemented"))
+	if patch.Type() != types.ApplyPatchType {
+		panic(fmt.Errorf("Patch() is only implemented for server-side apply"))
+	}
+	gvr, namespace, name, err := w.objmeta(obj)
+	if err != nil {
+		return err
+	}
+	data, err := patch.Data(obj)
+	if err != nil {
+		return err
+	}
+
+	// capture action
+	w.applyActions = append(w.applyActions, clientgotesting.NewPatchAction(gvr, namespace, name, patch.Type(), data))
+
+	// call reactor chain
+	err = w.react(clientgotesting.NewPatchAction(gvr, namespace, name, patch.Type(), data))
+	if err != nil {
+		return err
+	}
+
+	patchOptions := &client.PatchOptions{}
+	patchOptions.ApplyOptions(opts)
+	var appliedFields map[string]interface{}
+	if err := json.Unmarshal(data, &appliedFields); err != nil {
+		return err
+	}
+	managed, err := appliedManagedFields(patchOptions.FieldManager, appliedFields)
+	if err != nil {
+		return err
+	}
+
+	// decoding into a copy of obj would merge the desired maps into the current object
+	current := reflect.New(reflect.TypeOf(obj).Elem()).Interface().(runtime.Object)
+	if err := w.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, current); err != nil {
+		if !apierrs.IsNotFound(err) {
+			return err
+		}
+		obj.(metav1.Object).SetManagedFields([]metav1.ManagedFieldsEntry{managed})
+		return w.client.Create(ctx, obj)
+	}
+	var currentFields map[string]interface{}
+	currentData, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(currentData, &currentFields); err != nil {
+		return err
+	}
+	currentFields, err = removeUnappliedFields(currentFields, current.(metav1.Object).GetManagedFields(), patchOptions.FieldManager, appliedFields)
+	if err != nil {
+		return err
+	}
+	mergedData, err := json.Marshal(mergeAppliedFields(currentFields, appliedFields))
+	if err != nil {
+		return err
+	}
+	merged := reflect.New(reflect.TypeOf(obj).Elem()).Interface().(runtime.Object)
+	if err := json.Unmarshal(mergedData, merged); err != nil {
+		return err
+	}
+	// applying an unchanged object is a no-op
+	if !equality.Semantic.DeepEqual(current, merged) {
+		mergedMeta := merged.(metav1.Object)
+		entries := []metav1.ManagedFieldsEntry{managed}
+		for _, entry := range mergedMeta.GetManagedFields() {
+			if entry.Manager != managed.Manager || entry.Operation != managed.Operation {
+				entries = append(entries, entry)
+			}
+		}
+		mergedMeta.SetManagedFields(entries)
+		if err := w.client.Update(ctx, merged); err != nil {
+			return err
+		}
+	}
+	reflect.ValueOf(obj).Elem().Set(reflect.ValueOf(merged).Elem())
+	return nil
+}
+
+// appliedManagedFields records the applied fields of nested objects for the
+// field manager. Lists are recorded as a whole.
+func appliedManagedFields(fieldManager string, applied map[string]interface{}) (metav1.ManagedFieldsEntry, error) {
+	raw, err := json.Marshal(fieldSet(applied))
+	if err != nil {
+		return metav1.ManagedFieldsEntry{}, err
+	}
+	apiVersion, _ := applied["apiVersion"].(string)
+	return metav1.ManagedFieldsEntry{
+		Manager:    fieldManager,
+		Operation:  metav1.ManagedFieldsOperationApply,
+		APIVersion: apiVersion,
+		FieldsType: "FieldsV1",
+		FieldsV1:   &metav1.FieldsV1{Raw: raw},
+	}, nil
+}
+
+func fieldSet(fields map[string]interface{}) map[string]interface{} {
+	set := map[string]interface{}{}
+	for k, v := range fields {
+		if k == "apiVersion" || k == "kind" {
+			continue
+		}
+		if m, ok := v.(map[string]interface{}); ok {
+			set["f:"+k] = fieldSet(m)
+			continue
+		}
+		set["f:"+k] = map[string]interface{}{}
+	}
+	return set
+}
+
+// removeUnappliedFields removes the fields the field manager applied before
+// that are omitted from the applied fields.
+func removeUnappliedFields(current map[string]interface{}, entries []metav1.ManagedFieldsEntry, fieldManager string, applied map[string]interface{}) (map[string]interface{}, error) {
+	for _, entry := range entries {
+		if entry.Manager != fieldManager || entry.Operation != metav1.ManagedFieldsOperationApply || entry.FieldsV1 == nil {
+			continue
+		}
+		var owned map[string]interface{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &owned); err != nil {
+			return nil, err
+		}
+		return removeFields(current, owned, applied), nil
+	}
+	return current, nil
+}
+
+func removeFields(current, owned, applied map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for k, v := range current {
+		result[k] = v
+	}
+	for k, v := range owned {
+		if !strings.HasPrefix(k, "f:") {
+			continue
+		}
+		name := strings.TrimPrefix(k, "f:")
+		appliedValue, ok := applied[name]
+		if !ok || appliedValue == nil {
+			delete(result, name)
+			continue
+		}
+		ownedMap, _ := v.(map[string]interface{})
+		currentMap, currentOk := result[name].(map[string]interface{})
+		appliedMap, appliedOk := appliedValue.(map[string]interface{})
+		if len(ownedMap) != 0 && currentOk && appliedOk {
+			result[name] = removeFields(currentMap, ownedMap, appliedMap)
+		}
+	}
+	return result
+}
+
+// mergeAppliedFields merges the applied fields into the current fields. Nested
+// objects are merged, other values, including lists, are replaced. Null
+// applied values are not set.
+func mergeAppliedFields(current, applied map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for k, v := range current {
+		merged[k] = v
+	}
+	for k, v := range applied {
+		if v == nil {
+			continue
+		}
+		appliedMap, ok := v.(map[string]interface{})
+		currentMap, currentOk := merged[k].(map[string]interface{})
+		if ok && currentOk {
+			merged[k] = mergeAppliedFields(currentMap, appliedMap)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
 }
 
 func (w *clientWrapper) DeleteAllOf(ctx context.Context, obj runtime.Object, opts ...client.DeleteAllOfOption) error {
@@ -18,10 +18,15 @@ package factories
 
 import (
 	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/projectriff/system/pkg/apis"
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	knativev1alpha1 "github.com/projectriff/system/pkg/apis/knative/v1alpha1"
 	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+	"github.com/projectriff/system/pkg/refs"
 )
 
 type adapterKnative struct {
@@ -100,6 +105,12 @@ func (f *adapterKnative) FunctionRef(format string, a ...interface{}) *adapterKn
 	})
 }
 
+func (f *adapterKnative) BuildPin(pin *buildv1alpha1.BuildPin) *adapterKnative {
+	return f.mutation(func(adapter *knativev1alpha1.Adapter) {
+		adapter.Spec.Build.Pin = pin
+	})
+}
+
 func (f *adapterKnative) ConfigurationRef(format string, a ...interface{}) *adapterKnative {
 	return f.mutation(func(adapter *knativev1alpha1.Adapter) {
 		adapter.Spec.Target = knativev1alpha1.AdapterTarget{
@@ -116,6 +127,61 @@ func (f *adapterKnative) ServiceRef(format string, a ...interface{}) *adapterKna
 	})
 }
 
+func (f *adapterKnative) Ref(apiGroup, kind, name string) *adapterKnative {
+	return f.mutation(func(adapter *knativev1alpha1.Adapter) {
+		ref := &refs.TypedLocalObjectReference{Kind: kind, Name: name}
+		if apiGroup != "" {
+			ref.APIGroup = &apiGroup
+		}
+		adapter.Spec.Target = knativev1alpha1.AdapterTarget{
+			Ref: ref,
+		}
+	})
+}
+
+func (f *adapterKnative) ContainerName(name string) *adapterKnative {
+	return f.mutation(func(adapter *knativev1alpha1.Adapter) {
+		adapter.Spec.Target.ContainerName = name
+	})
+}
+
+func (f *adapterKnative) ImagePath(path string) *adapterKnative {
+	return f.mutation(func(adapter *knativev1alpha1.Adapter) {
+		adapter.Spec.Target.ImagePath = path
+	})
+}
+
+func (f *adapterKnative) UpdatePolicy(approval knativev1alpha1.AdapterApproval) *adapterKnative {
+	return f.mutation(func(adapter *knativev1alpha1.Adapter) {
+		adapter.Spec.UpdatePolicy = &knativev1alpha1.AdapterUpdatePolicy{
+			Approval: approval,
+		}
+	})
+}
+
+func (f *adapterKnative) ApprovedImage(image, approvedBy string) *adapterKnative {
+	return f.mutation(func(adapter *knativev1alpha1.Adapter) {
+		adapter.Spec.UpdatePolicy.ApprovedImage = image
+		adapter.Spec.UpdatePolicy.ApprovedBy = approvedBy
+	})
+}
+
+func (f *adapterKnative) MaintenanceWindow(schedule string, duration time.Duration) *adapterKnative {
+	return f.mutation(func(adapter *knativev1alpha1.Adapter) {
+		adapter.Spec.UpdatePolicy.MaintenanceWindows = append(adapter.Spec.UpdatePolicy.MaintenanceWindows, knativev1alpha1.MaintenanceWindow{
+			Schedule: schedule,
+			Duration: metav1.Duration{Duration: duration},
+		})
+	})
+}
+
+func (f *adapterKnative) RevertedImage(image, revertedBy string) *adapterKnative {
+	return f.mutation(func(adapter *knativev1alpha1.Adapter) {
+		adapter.Spec.RevertedImage = image
+		adapter.Spec.RevertedBy = revertedBy
+	})
+}
+
 func (f *adapterKnative) StatusConditions(conditions ...*condition) *adapterKnative {
 	return f.mutation(func(adapter *knativev1alpha1.Adapter) {
 		c := make([]apis.Condition, len(conditions))
@@ -137,3 +203,28 @@ func (f *adapterKnative) StatusLatestImage(format string, a ...interface{}) *ada
 		adapter.Status.LatestImage = fmt.Sprintf(format, a...)
 	})
 }
+
+func (f *adapterKnative) StatusAvailableImage(format string, a ...interface{}) *adapterKnative {
+	return f.mutation(func(adapter *knativev1alpha1.Adapter) {
+		adapter.Status.AvailableImage = fmt.Sprintf(format, a...)
+	})
+}
+
+func (f *adapterKnative) StatusPendingImage(format string, a ...interface{}) *adapterKnative {
+	return f.mutation(func(adapter *knativev1alpha1.Adapter) {
+		adapter.Status.PendingImage = fmt.Sprintf(format, a...)
+	})
+}
+
+// StatusUpdate records an update as the most recent.
+func (f *adapterKnative) StatusUpdate(update knativev1alpha1.AdapterUpdate) *adapterKnative {
+	return f.mutation(func(adapter *knativev1alpha1.Adapter) {
+		adapter.Status.RecordUpdate(update)
+	})
+}
+
+func (f *adapterKnative) StatusTargetRevisionName(name string) *adapterKnative {
+	return f.mutation(func(adapter *knativev1alpha1.Adapter) {
+		adapter.Status.TargetRevisionName = name
+	})
+}
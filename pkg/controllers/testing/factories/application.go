@@ -119,6 +119,18 @@ func (f *application) BuildCache(quantity string) *application {
 	})
 }
 
+func (f *application) Strategy(strategy buildv1alpha1.BuildStrategy) *application {
+	return f.mutation(func(app *buildv1alpha1.Application) {
+		app.Spec.Strategy = strategy
+	})
+}
+
+func (f *application) Dockerfile(dockerfile *buildv1alpha1.DockerfileBuild) *application {
+	return f.mutation(func(app *buildv1alpha1.Application) {
+		app.Spec.Dockerfile = dockerfile
+	})
+}
+
 func (f *application) StatusConditions(conditions ...*condition) *application {
 	return f.mutation(func(app *buildv1alpha1.Application) {
 		c := make([]apis.Condition, len(conditions))
@@ -151,6 +163,25 @@ func (f *application) StatusKpackImageRef(format string, a ...interface{}) *appl
 	})
 }
 
+func (f *application) StatusJobRef(format string, a ...interface{}) *application {
+	return f.mutation(func(app *buildv1alpha1.Application) {
+		app.Status.JobRef = &refs.TypedLocalObjectReference{
+			APIGroup: rtesting.StringPtr("batch"),
+			Kind:     "Job",
+			Name:     fmt.Sprintf(format, a...),
+		}
+	})
+}
+
+func (f *application) StatusBuildLogRef(format string, a ...interface{}) *application {
+	return f.mutation(func(app *buildv1alpha1.Application) {
+		app.Status.BuildLogRef = &refs.TypedLocalObjectReference{
+			Kind: "ConfigMap",
+			Name: fmt.Sprintf(format, a...),
+		}
+	})
+}
+
 func (f *application) StatusBuildCacheRef(format string, a ...interface{}) *application {
 	return f.mutation(func(app *buildv1alpha1.Application) {
 		app.Status.BuildCacheRef = &refs.TypedLocalObjectReference{
@@ -171,3 +202,9 @@ func (f *application) StatusLatestImage(format string, a ...interface{}) *applic
 		app.Status.LatestImage = fmt.Sprintf(format, a...)
 	})
 }
+
+func (f *application) StatusBuilds(builds ...buildv1alpha1.BuildRecord) *application {
+	return f.mutation(func(app *buildv1alpha1.Application) {
+		app.Status.Builds = builds
+	})
+}
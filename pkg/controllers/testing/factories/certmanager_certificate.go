@@ -0,0 +1,111 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factories
+
+import (
+	"fmt"
+
+	"github.com/projectriff/system/pkg/apis"
+	certmanagerv1alpha2 "github.com/projectriff/system/pkg/apis/thirdparty/certmanager/v1alpha2"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+)
+
+type certManagerCertificate struct {
+	target *certmanagerv1alpha2.Certificate
+}
+
+var (
+	_ rtesting.Factory = (*certManagerCertificate)(nil)
+)
+
+func CertManagerCertificate(seed ...*certmanagerv1alpha2.Certificate) *certManagerCertificate {
+	var target *certmanagerv1alpha2.Certificate
+	switch len(seed) {
+	case 0:
+		target = &certmanagerv1alpha2.Certificate{}
+	case 1:
+		target = seed[0]
+	default:
+		panic(fmt.Errorf("expected exactly zero or one seed, got %v", seed))
+	}
+	return &certManagerCertificate{
+		target: target,
+	}
+}
+
+func (f *certManagerCertificate) deepCopy() *certManagerCertificate {
+	return CertManagerCertificate(f.target.DeepCopy())
+}
+
+func (f *certManagerCertificate) Create() apis.Object {
+	return f.deepCopy().target
+}
+
+func (f *certManagerCertificate) mutation(m func(*certmanagerv1alpha2.Certificate)) *certManagerCertificate {
+	f = f.deepCopy()
+	m(f.target)
+	return f
+}
+
+func (f *certManagerCertificate) NamespaceName(namespace, name string) *certManagerCertificate {
+	return f.mutation(func(c *certmanagerv1alpha2.Certificate) {
+		c.ObjectMeta.Namespace = namespace
+		c.ObjectMeta.Name = name
+	})
+}
+
+func (f *certManagerCertificate) ObjectMeta(nf func(ObjectMeta)) *certManagerCertificate {
+	return f.mutation(func(c *certmanagerv1alpha2.Certificate) {
+		omf := objectMeta(c.ObjectMeta)
+		nf(omf)
+		c.ObjectMeta = omf.Create()
+	})
+}
+
+func (f *certManagerCertificate) Host(host string) *certManagerCertificate {
+	return f.mutation(func(c *certmanagerv1alpha2.Certificate) {
+		c.Spec.CommonName = host
+		c.Spec.DNSNames = []string{host}
+	})
+}
+
+func (f *certManagerCertificate) SecretName(format string, a ...interface{}) *certManagerCertificate {
+	return f.mutation(func(c *certmanagerv1alpha2.Certificate) {
+		c.Spec.SecretName = fmt.Sprintf(format, a...)
+	})
+}
+
+func (f *certManagerCertificate) IssuerRef(kind, name string) *certManagerCertificate {
+	return f.mutation(func(c *certmanagerv1alpha2.Certificate) {
+		c.Spec.IssuerRef = certmanagerv1alpha2.ObjectReference{
+			Name:  name,
+			Kind:  kind,
+			Group: certmanagerv1alpha2.GroupVersion.Group,
+		}
+	})
+}
+
+func (f *certManagerCertificate) StatusReady(status certmanagerv1alpha2.ConditionStatus, reason, message string) *certManagerCertificate {
+	return f.mutation(func(c *certmanagerv1alpha2.Certificate) {
+		c.Status.Conditions = []certmanagerv1alpha2.CertificateCondition{{
+			Type:    certmanagerv1alpha2.CertificateConditionReady,
+			Status:  status,
+			Reason:  reason,
+			Message: message,
+		}}
+	})
+}
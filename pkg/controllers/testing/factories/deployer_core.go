@@ -18,10 +18,13 @@ package factories
 
 import (
 	"fmt"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/projectriff/system/pkg/apis"
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
 	rtesting "github.com/projectriff/system/pkg/controllers/testing"
 	"github.com/projectriff/system/pkg/refs"
@@ -121,6 +124,12 @@ func (f *deployerCore) FunctionRef(format string, a ...interface{}) *deployerCor
 	})
 }
 
+func (f *deployerCore) BuildPin(pin *buildv1alpha1.BuildPin) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Spec.Build.Pin = pin
+	})
+}
+
 func (f *deployerCore) Image(format string, a ...interface{}) *deployerCore {
 	return f.HandlerContainer(func(container *corev1.Container) {
 		container.Image = fmt.Sprintf(format, a...)
@@ -133,6 +142,48 @@ func (f *deployerCore) IngressPolicy(policy corev1alpha1.IngressPolicy) *deploye
 	})
 }
 
+func (f *deployerCore) Scale(scale corev1alpha1.Scale) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Spec.Scale = scale
+	})
+}
+
+func (f *deployerCore) Rollout(rollout corev1alpha1.Rollout) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Spec.Rollout = &rollout
+	})
+}
+
+func (f *deployerCore) Routes(routes ...corev1alpha1.Route) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Spec.Routes = routes
+	})
+}
+
+func (f *deployerCore) Ports(ports ...corev1alpha1.DeployerPort) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Spec.Ports = ports
+	})
+}
+
+func (f *deployerCore) PublicPort(name string) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Spec.PublicPort = name
+	})
+}
+
+func (f *deployerCore) Availability(availability corev1alpha1.Availability) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Spec.Availability = &availability
+	})
+}
+
+func (f *deployerCore) TLS(tls corev1alpha1.DeployerTLS) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Spec.TLS = &tls
+	})
+}
+
 func (f *deployerCore) StatusConditions(conditions ...*condition) *deployerCore {
 	return f.mutation(func(deployer *corev1alpha1.Deployer) {
 		c := make([]apis.Condition, len(conditions))
@@ -155,6 +206,18 @@ func (f *deployerCore) StatusLatestImage(format string, a ...interface{}) *deplo
 	})
 }
 
+func (f *deployerCore) StatusPinnedBuild(pin *buildv1alpha1.BuildPin) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Status.PinnedBuild = pin
+	})
+}
+
+func (f *deployerCore) StatusAvailableImage(format string, a ...interface{}) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Status.AvailableImage = fmt.Sprintf(format, a...)
+	})
+}
+
 func (f *deployerCore) StatusDeploymentRef(format string, a ...interface{}) *deployerCore {
 	return f.mutation(func(deployer *corev1alpha1.Deployer) {
 		deployer.Status.DeploymentRef = &refs.TypedLocalObjectReference{
@@ -185,6 +248,143 @@ func (f *deployerCore) StatusIngressRef(format string, a ...interface{}) *deploy
 	})
 }
 
+func (f *deployerCore) StatusHTTPRouteRef(format string, a ...interface{}) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Status.HTTPRouteRef = &refs.TypedLocalObjectReference{
+			APIGroup: rtesting.StringPtr("gateway.networking.k8s.io"),
+			Kind:     "HTTPRoute",
+			Name:     fmt.Sprintf(format, a...),
+		}
+	})
+}
+
+func (f *deployerCore) StatusCertificateRef(format string, a ...interface{}) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Status.CertificateRef = &refs.TypedLocalObjectReference{
+			APIGroup: rtesting.StringPtr("cert-manager.io"),
+			Kind:     "Certificate",
+			Name:     fmt.Sprintf(format, a...),
+		}
+	})
+}
+
+func (f *deployerCore) StatusTLSSecretRef(format string, a ...interface{}) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Status.TLSSecretRef = &refs.TypedLocalObjectReference{
+			Kind: "Secret",
+			Name: fmt.Sprintf(format, a...),
+		}
+	})
+}
+
+func (f *deployerCore) StatusNetworkPolicyRef(format string, a ...interface{}) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Status.NetworkPolicyRef = &refs.TypedLocalObjectReference{
+			APIGroup: rtesting.StringPtr("networking.k8s.io"),
+			Kind:     "NetworkPolicy",
+			Name:     fmt.Sprintf(format, a...),
+		}
+	})
+}
+
+func (f *deployerCore) StatusPodDisruptionBudgetRef(format string, a ...interface{}) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Status.PodDisruptionBudgetRef = &refs.TypedLocalObjectReference{
+			APIGroup: rtesting.StringPtr("policy"),
+			Kind:     "PodDisruptionBudget",
+			Name:     fmt.Sprintf(format, a...),
+		}
+	})
+}
+
+func (f *deployerCore) StatusHorizontalPodAutoscalerRef(format string, a ...interface{}) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Status.HorizontalPodAutoscalerRef = &refs.TypedLocalObjectReference{
+			APIGroup: rtesting.StringPtr("autoscaling"),
+			Kind:     "HorizontalPodAutoscaler",
+			Name:     fmt.Sprintf(format, a...),
+		}
+	})
+}
+
+func (f *deployerCore) StatusReplicas(current, desired int32) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Status.CurrentReplicas = current
+		deployer.Status.DesiredReplicas = desired
+	})
+}
+
+func (f *deployerCore) statusRollout(m func(*corev1alpha1.RolloutStatus)) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		if deployer.Status.Rollout == nil {
+			deployer.Status.Rollout = &corev1alpha1.RolloutStatus{}
+		}
+		m(deployer.Status.Rollout)
+	})
+}
+
+func (f *deployerCore) StatusRolloutImages(stable, candidate string) *deployerCore {
+	return f.statusRollout(func(rollout *corev1alpha1.RolloutStatus) {
+		rollout.StableImage = stable
+		rollout.CandidateImage = candidate
+	})
+}
+
+func (f *deployerCore) StatusRolloutStep(step, weight int32) *deployerCore {
+	return f.statusRollout(func(rollout *corev1alpha1.RolloutStatus) {
+		rollout.Step = step
+		rollout.Weight = weight
+	})
+}
+
+func (f *deployerCore) StatusRolloutFailedImage(image string) *deployerCore {
+	return f.statusRollout(func(rollout *corev1alpha1.RolloutStatus) {
+		rollout.FailedImage = image
+	})
+}
+
+func (f *deployerCore) StatusRolloutErrorRateCheckFailure(message string) *deployerCore {
+	return f.statusRollout(func(rollout *corev1alpha1.RolloutStatus) {
+		rollout.ErrorRateCheckFailure = message
+	})
+}
+
+func (f *deployerCore) StatusRolloutTransitioned(t time.Time) *deployerCore {
+	return f.statusRollout(func(rollout *corev1alpha1.RolloutStatus) {
+		rollout.LastTransitionTime = apis.VolatileTime{Inner: metav1.NewTime(t)}
+	})
+}
+
+func (f *deployerCore) StatusCandidateDeploymentRef(format string, a ...interface{}) *deployerCore {
+	return f.statusRollout(func(rollout *corev1alpha1.RolloutStatus) {
+		rollout.CandidateDeploymentRef = &refs.TypedLocalObjectReference{
+			APIGroup: rtesting.StringPtr("apps"),
+			Kind:     "Deployment",
+			Name:     fmt.Sprintf(format, a...),
+		}
+	})
+}
+
+func (f *deployerCore) StatusCandidateServiceRef(format string, a ...interface{}) *deployerCore {
+	return f.statusRollout(func(rollout *corev1alpha1.RolloutStatus) {
+		rollout.CandidateServiceRef = &refs.TypedLocalObjectReference{
+			APIGroup: nil,
+			Kind:     "Service",
+			Name:     fmt.Sprintf(format, a...),
+		}
+	})
+}
+
+func (f *deployerCore) StatusCandidateIngressRef(format string, a ...interface{}) *deployerCore {
+	return f.statusRollout(func(rollout *corev1alpha1.RolloutStatus) {
+		rollout.CandidateIngressRef = &refs.TypedLocalObjectReference{
+			APIGroup: rtesting.StringPtr("networking.k8s.io"),
+			Kind:     "Ingress",
+			Name:     fmt.Sprintf(format, a...),
+		}
+	})
+}
+
 func (f *deployerCore) StatusAddressURL(format string, a ...interface{}) *deployerCore {
 	return f.mutation(func(deployer *corev1alpha1.Deployer) {
 		deployer.Status.Address = &apis.Addressable{
@@ -198,3 +398,10 @@ func (f *deployerCore) StatusURL(format string, a ...interface{}) *deployerCore
 		deployer.Status.URL = fmt.Sprintf(format, a...)
 	})
 }
+
+func (f *deployerCore) StatusURLs(urls ...string) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Status.URL = urls[0]
+		deployer.Status.URLs = urls
+	})
+}
@@ -18,8 +18,10 @@ package factories
 
 import (
 	"fmt"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/projectriff/system/pkg/apis"
 	knativev1alpha1 "github.com/projectriff/system/pkg/apis/knative/v1alpha1"
@@ -147,6 +149,33 @@ func (f *deployerKnative) MaxScale(scale int32) *deployerKnative {
 	})
 }
 
+func (f *deployerKnative) Scale(scale knativev1alpha1.Scale) *deployerKnative {
+	return f.mutation(func(deployer *knativev1alpha1.Deployer) {
+		deployer.Spec.Scale = scale
+	})
+}
+
+func (f *deployerKnative) Traffic(traffic ...knativev1alpha1.TrafficTarget) *deployerKnative {
+	return f.mutation(func(deployer *knativev1alpha1.Deployer) {
+		deployer.Spec.Traffic = traffic
+	})
+}
+
+func (f *deployerKnative) Progression(interval time.Duration, steps ...int64) *deployerKnative {
+	return f.mutation(func(deployer *knativev1alpha1.Deployer) {
+		deployer.Spec.Progression = &knativev1alpha1.TrafficProgression{
+			Steps:    steps,
+			Interval: &metav1.Duration{Duration: interval},
+		}
+	})
+}
+
+func (f *deployerKnative) Domains(domains ...knativev1alpha1.Domain) *deployerKnative {
+	return f.mutation(func(deployer *knativev1alpha1.Deployer) {
+		deployer.Spec.Domains = domains
+	})
+}
+
 func (f *deployerKnative) StatusConditions(conditions ...*condition) *deployerKnative {
 	return f.mutation(func(deployer *knativev1alpha1.Deployer) {
 		c := make([]apis.Condition, len(conditions))
@@ -189,6 +218,46 @@ func (f *deployerKnative) StatusRouteRef(format string, a ...interface{}) *deplo
 	})
 }
 
+func (f *deployerKnative) StatusNetworkPolicyRef(format string, a ...interface{}) *deployerKnative {
+	return f.mutation(func(deployer *knativev1alpha1.Deployer) {
+		deployer.Status.NetworkPolicyRef = &refs.TypedLocalObjectReference{
+			APIGroup: rtesting.StringPtr("networking.k8s.io"),
+			Kind:     "NetworkPolicy",
+			Name:     fmt.Sprintf(format, a...),
+		}
+	})
+}
+
+func (f *deployerKnative) StatusLatestReadyRevisionName(name string) *deployerKnative {
+	return f.mutation(func(deployer *knativev1alpha1.Deployer) {
+		deployer.Status.LatestReadyRevisionName = name
+	})
+}
+
+func (f *deployerKnative) StatusTraffic(traffic ...knativev1alpha1.TrafficTargetStatus) *deployerKnative {
+	return f.mutation(func(deployer *knativev1alpha1.Deployer) {
+		deployer.Status.Traffic = traffic
+	})
+}
+
+func (f *deployerKnative) StatusProgression(stable, candidate string, step int32, percent int64, transitioned time.Time) *deployerKnative {
+	return f.mutation(func(deployer *knativev1alpha1.Deployer) {
+		deployer.Status.Progression = &knativev1alpha1.TrafficProgressionStatus{
+			StableRevisionName:    stable,
+			CandidateRevisionName: candidate,
+			Step:                  step,
+			Percent:               percent,
+			LastTransitionTime:    apis.VolatileTime{Inner: metav1.NewTime(transitioned)},
+		}
+	})
+}
+
+func (f *deployerKnative) StatusDomains(domains ...knativev1alpha1.DomainStatus) *deployerKnative {
+	return f.mutation(func(deployer *knativev1alpha1.Deployer) {
+		deployer.Status.Domains = domains
+	})
+}
+
 func (f *deployerKnative) StatusAddressURL(url string) *deployerKnative {
 	return f.mutation(func(deployer *knativev1alpha1.Deployer) {
 		deployer.Status.Address = &apis.Addressable{
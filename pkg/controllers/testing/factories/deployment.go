@@ -99,6 +99,12 @@ func (f *deployment) Replicas(replicas int32) *deployment {
 	})
 }
 
+func (f *deployment) ProgressDeadlineSeconds(seconds int32) *deployment {
+	return f.mutation(func(deployment *appsv1.Deployment) {
+		deployment.Spec.ProgressDeadlineSeconds = rtesting.Int32Ptr(seconds)
+	})
+}
+
 func (f *deployment) AddSelectorLabel(key, value string) *deployment {
 	return f.mutation(func(deployment *appsv1.Deployment) {
 		if deployment.Spec.Selector == nil {
@@ -109,6 +115,19 @@ func (f *deployment) AddSelectorLabel(key, value string) *deployment {
 	})
 }
 
+func (f *deployment) AddSelectorExpression(key string, operator metav1.LabelSelectorOperator, values ...string) *deployment {
+	return f.mutation(func(deployment *appsv1.Deployment) {
+		if deployment.Spec.Selector == nil {
+			deployment.Spec.Selector = &metav1.LabelSelector{}
+		}
+		deployment.Spec.Selector.MatchExpressions = append(deployment.Spec.Selector.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key:      key,
+			Operator: operator,
+			Values:   values,
+		})
+	})
+}
+
 func (f *deployment) StatusConditions(conditions ...*condition) *deployment {
 	return f.mutation(func(deployment *appsv1.Deployment) {
 		c := make([]appsv1.DeploymentCondition, len(conditions))
@@ -124,3 +143,11 @@ func (f *deployment) StatusConditions(conditions ...*condition) *deployment {
 		deployment.Status.Conditions = c
 	})
 }
+
+func (f *deployment) StatusReplicas(replicas, updated, available int32) *deployment {
+	return f.mutation(func(deployment *appsv1.Deployment) {
+		deployment.Status.Replicas = replicas
+		deployment.Status.UpdatedReplicas = updated
+		deployment.Status.AvailableReplicas = available
+	})
+}
@@ -169,6 +169,15 @@ func (f *function) StatusKpackImageRef(format string, a ...interface{}) *functio
 	})
 }
 
+func (f *function) StatusBuildLogRef(format string, a ...interface{}) *function {
+	return f.mutation(func(fn *buildv1alpha1.Function) {
+		fn.Status.BuildLogRef = &refs.TypedLocalObjectReference{
+			Kind: "ConfigMap",
+			Name: fmt.Sprintf(format, a...),
+		}
+	})
+}
+
 func (f *function) StatusBuildCacheRef(format string, a ...interface{}) *function {
 	return f.mutation(func(fn *buildv1alpha1.Function) {
 		fn.Status.BuildCacheRef = &refs.TypedLocalObjectReference{
@@ -189,3 +198,9 @@ func (f *function) StatusLatestImage(format string, a ...interface{}) *function
 		fn.Status.LatestImage = fmt.Sprintf(format, a...)
 	})
 }
+
+func (f *function) StatusBuilds(builds ...buildv1alpha1.BuildRecord) *function {
+	return f.mutation(func(fn *buildv1alpha1.Function) {
+		fn.Status.Builds = builds
+	})
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factories
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/projectriff/system/pkg/apis"
+	gatewayv1beta1 "github.com/projectriff/system/pkg/apis/thirdparty/gateway/v1beta1"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+)
+
+type gatewayHTTPRoute struct {
+	target *gatewayv1beta1.HTTPRoute
+}
+
+var (
+	_ rtesting.Factory = (*gatewayHTTPRoute)(nil)
+)
+
+func GatewayHTTPRoute(seed ...*gatewayv1beta1.HTTPRoute) *gatewayHTTPRoute {
+	var target *gatewayv1beta1.HTTPRoute
+	switch len(seed) {
+	case 0:
+		target = &gatewayv1beta1.HTTPRoute{}
+	case 1:
+		target = seed[0]
+	default:
+		panic(fmt.Errorf("expected exactly zero or one seed, got %v", seed))
+	}
+	return &gatewayHTTPRoute{
+		target: target,
+	}
+}
+
+func (f *gatewayHTTPRoute) deepCopy() *gatewayHTTPRoute {
+	return GatewayHTTPRoute(f.target.DeepCopy())
+}
+
+func (f *gatewayHTTPRoute) Create() apis.Object {
+	return f.deepCopy().target
+}
+
+func (f *gatewayHTTPRoute) mutation(m func(*gatewayv1beta1.HTTPRoute)) *gatewayHTTPRoute {
+	f = f.deepCopy()
+	m(f.target)
+	return f
+}
+
+func (f *gatewayHTTPRoute) NamespaceName(namespace, name string) *gatewayHTTPRoute {
+	return f.mutation(func(r *gatewayv1beta1.HTTPRoute) {
+		r.ObjectMeta.Namespace = namespace
+		r.ObjectMeta.Name = name
+	})
+}
+
+func (f *gatewayHTTPRoute) ObjectMeta(nf func(ObjectMeta)) *gatewayHTTPRoute {
+	return f.mutation(func(r *gatewayv1beta1.HTTPRoute) {
+		omf := objectMeta(r.ObjectMeta)
+		nf(omf)
+		r.ObjectMeta = omf.Create()
+	})
+}
+
+func (f *gatewayHTTPRoute) Gateway(namespace, name string) *gatewayHTTPRoute {
+	return f.mutation(func(r *gatewayv1beta1.HTTPRoute) {
+		group, kind := gatewayv1beta1.GroupVersion.Group, "Gateway"
+		r.Spec.ParentRefs = []gatewayv1beta1.ParentReference{{
+			Group:     &group,
+			Kind:      &kind,
+			Namespace: &namespace,
+			Name:      name,
+		}}
+	})
+}
+
+func (f *gatewayHTTPRoute) Hostnames(hostnames ...string) *gatewayHTTPRoute {
+	return f.mutation(func(r *gatewayv1beta1.HTTPRoute) {
+		r.Spec.Hostnames = hostnames
+	})
+}
+
+func (f *gatewayHTTPRoute) ClearRules() *gatewayHTTPRoute {
+	return f.mutation(func(r *gatewayv1beta1.HTTPRoute) {
+		r.Spec.Rules = nil
+	})
+}
+
+func (f *gatewayHTTPRoute) AddRulePath(path, serviceName string) *gatewayHTTPRoute {
+	return f.mutation(func(r *gatewayv1beta1.HTTPRoute) {
+		r.Spec.Rules = append(r.Spec.Rules, gatewayv1beta1.HTTPRouteRule{
+			Matches:     []gatewayv1beta1.HTTPRouteMatch{{Path: httpPathMatch(path)}},
+			BackendRefs: []gatewayv1beta1.HTTPBackendRef{httpBackendRef(serviceName, nil)},
+		})
+	})
+}
+
+// AddRuleWeighted splits requests for the path prefix between the stable and
+// candidate services.
+func (f *gatewayHTTPRoute) AddRuleWeighted(path, stableServiceName string, stableWeight int32, candidateServiceName string, candidateWeight int32) *gatewayHTTPRoute {
+	return f.mutation(func(r *gatewayv1beta1.HTTPRoute) {
+		r.Spec.Rules = append(r.Spec.Rules, gatewayv1beta1.HTTPRouteRule{
+			Matches: []gatewayv1beta1.HTTPRouteMatch{{Path: httpPathMatch(path)}},
+			BackendRefs: []gatewayv1beta1.HTTPBackendRef{
+				httpBackendRef(stableServiceName, &stableWeight),
+				httpBackendRef(candidateServiceName, &candidateWeight),
+			},
+		})
+	})
+}
+
+// AddRulePreview routes requests for the path prefix with the header set to
+// "always" to the service.
+func (f *gatewayHTTPRoute) AddRulePreview(path, header, serviceName string) *gatewayHTTPRoute {
+	return f.mutation(func(r *gatewayv1beta1.HTTPRoute) {
+		headerType := gatewayv1beta1.HeaderMatchExact
+		r.Spec.Rules = append(r.Spec.Rules, gatewayv1beta1.HTTPRouteRule{
+			Matches: []gatewayv1beta1.HTTPRouteMatch{{
+				Path: httpPathMatch(path),
+				Headers: []gatewayv1beta1.HTTPHeaderMatch{{
+					Type:  &headerType,
+					Name:  header,
+					Value: "always",
+				}},
+			}},
+			BackendRefs: []gatewayv1beta1.HTTPBackendRef{httpBackendRef(serviceName, nil)},
+		})
+	})
+}
+
+// Rewrite replaces the matched path prefix of the last rule.
+func (f *gatewayHTTPRoute) Rewrite(rewrite string) *gatewayHTTPRoute {
+	return f.mutation(func(r *gatewayv1beta1.HTTPRoute) {
+		rule := &r.Spec.Rules[len(r.Spec.Rules)-1]
+		rule.Filters = []gatewayv1beta1.HTTPRouteFilter{{
+			Type: gatewayv1beta1.HTTPRouteFilterURLRewrite,
+			URLRewrite: &gatewayv1beta1.HTTPURLRewriteFilter{
+				Path: &gatewayv1beta1.HTTPPathModifier{
+					Type:               gatewayv1beta1.PrefixMatchHTTPPathModifier,
+					ReplacePrefixMatch: &rewrite,
+				},
+			},
+		}}
+	})
+}
+
+func (f *gatewayHTTPRoute) StatusParentConditions(conditions ...gatewayv1beta1.Condition) *gatewayHTTPRoute {
+	return f.mutation(func(r *gatewayv1beta1.HTTPRoute) {
+		if len(r.Spec.ParentRefs) == 0 {
+			panic(fmt.Errorf("a gateway is required to report parent status"))
+		}
+		r.Status.Parents = []gatewayv1beta1.RouteParentStatus{{
+			ParentRef:      r.Spec.ParentRefs[0],
+			ControllerName: "example.com/gateway-controller",
+			Conditions:     conditions,
+		}}
+	})
+}
+
+func httpPathMatch(path string) *gatewayv1beta1.HTTPPathMatch {
+	pathType := gatewayv1beta1.PathMatchPathPrefix
+	return &gatewayv1beta1.HTTPPathMatch{
+		Type:  &pathType,
+		Value: &path,
+	}
+}
+
+func httpBackendRef(serviceName string, weight *int32) gatewayv1beta1.HTTPBackendRef {
+	port := int32(80)
+	return gatewayv1beta1.HTTPBackendRef{
+		BackendRef: gatewayv1beta1.BackendRef{
+			BackendObjectReference: gatewayv1beta1.BackendObjectReference{
+				Name: serviceName,
+				Port: &port,
+			},
+			Weight: weight,
+		},
+	}
+}
+
+func GatewayCondition(conditionType gatewayv1beta1.RouteConditionType, status metav1.ConditionStatus, reason, message string) gatewayv1beta1.Condition {
+	return gatewayv1beta1.Condition{
+		Type:    string(conditionType),
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+}
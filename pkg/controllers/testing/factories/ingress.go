@@ -100,6 +100,43 @@ func (f *ingress) HostToService(host, serviceName string) *ingress {
 	})
 }
 
+// AddRulePath routes the path on the host to the service, adding a rule for
+// the host as needed.
+func (f *ingress) AddRulePath(host, path, serviceName string) *ingress {
+	return f.mutation(func(i *networkingv1beta1.Ingress) {
+		ingressPath := networkingv1beta1.HTTPIngressPath{
+			Path: path,
+			Backend: networkingv1beta1.IngressBackend{
+				ServiceName: serviceName,
+				ServicePort: intstr.FromInt(80),
+			},
+		}
+		for r := range i.Spec.Rules {
+			if i.Spec.Rules[r].Host == host {
+				i.Spec.Rules[r].HTTP.Paths = append(i.Spec.Rules[r].HTTP.Paths, ingressPath)
+				return
+			}
+		}
+		i.Spec.Rules = append(i.Spec.Rules, networkingv1beta1.IngressRule{
+			Host: host,
+			IngressRuleValue: networkingv1beta1.IngressRuleValue{
+				HTTP: &networkingv1beta1.HTTPIngressRuleValue{
+					Paths: []networkingv1beta1.HTTPIngressPath{ingressPath},
+				},
+			},
+		})
+	})
+}
+
+func (f *ingress) TLS(host, secretName string) *ingress {
+	return f.mutation(func(i *networkingv1beta1.Ingress) {
+		i.Spec.TLS = []networkingv1beta1.IngressTLS{{
+			Hosts:      []string{host},
+			SecretName: secretName,
+		}}
+	})
+}
+
 func (f *ingress) StatusLoadBalancer(ingress ...corev1.LoadBalancerIngress) *ingress {
 	return f.mutation(func(i *networkingv1beta1.Ingress) {
 		i.Status.LoadBalancer.Ingress = ingress
@@ -0,0 +1,120 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factories
+
+import (
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+
+	"github.com/projectriff/system/pkg/apis"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+)
+
+type job struct {
+	target *batchv1.Job
+}
+
+var (
+	_ rtesting.Factory = (*job)(nil)
+)
+
+func Job(seed ...*batchv1.Job) *job {
+	var target *batchv1.Job
+	switch len(seed) {
+	case 0:
+		target = &batchv1.Job{}
+	case 1:
+		target = seed[0]
+	default:
+		panic(fmt.Errorf("expected exactly zero or one seed, got %v", seed))
+	}
+	return &job{
+		target: target,
+	}
+}
+
+func (f *job) deepCopy() *job {
+	return Job(f.target.DeepCopy())
+}
+
+func (f *job) Create() apis.Object {
+	return f.deepCopy().target
+}
+
+func (f *job) mutation(m func(*batchv1.Job)) *job {
+	f = f.deepCopy()
+	m(f.target)
+	return f
+}
+
+func (f *job) NamespaceName(namespace, name string) *job {
+	return f.mutation(func(job *batchv1.Job) {
+		job.ObjectMeta.Namespace = namespace
+		job.ObjectMeta.Name = name
+	})
+}
+
+func (f *job) ObjectMeta(nf func(ObjectMeta)) *job {
+	return f.mutation(func(job *batchv1.Job) {
+		omf := objectMeta(job.ObjectMeta)
+		nf(omf)
+		job.ObjectMeta = omf.Create()
+	})
+}
+
+func (f *job) PodTemplateSpec(nf func(PodTemplateSpec)) *job {
+	return f.mutation(func(job *batchv1.Job) {
+		ptsf := podTemplateSpec(job.Spec.Template)
+		nf(ptsf)
+		job.Spec.Template = ptsf.Create()
+	})
+}
+
+func (f *job) BackoffLimit(limit int32) *job {
+	return f.mutation(func(job *batchv1.Job) {
+		job.Spec.BackoffLimit = rtesting.Int32Ptr(limit)
+	})
+}
+
+func (f *job) StatusConditions(conditions ...*condition) *job {
+	return f.mutation(func(job *batchv1.Job) {
+		c := make([]batchv1.JobCondition, len(conditions))
+		for i, cg := range conditions {
+			jc := cg.Create()
+			c[i] = batchv1.JobCondition{
+				Type:    batchv1.JobConditionType(jc.Type),
+				Status:  jc.Status,
+				Reason:  jc.Reason,
+				Message: jc.Message,
+			}
+		}
+		job.Status.Conditions = c
+	})
+}
+
+func (f *job) StatusComplete() *job {
+	return f.StatusConditions(
+		Condition().Type(apis.ConditionType(batchv1.JobComplete)).True(),
+	)
+}
+
+func (f *job) StatusFailed(reason, message string) *job {
+	return f.StatusConditions(
+		Condition().Type(apis.ConditionType(batchv1.JobFailed)).True().Reason(reason, message),
+	)
+}
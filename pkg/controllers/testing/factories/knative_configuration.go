@@ -128,3 +128,15 @@ func (f *knativeConfiguration) StatusObservedGeneration(generation int64) *knati
 		configuration.Status.ObservedGeneration = generation
 	})
 }
+
+func (f *knativeConfiguration) StatusLatestReadyRevisionName(name string) *knativeConfiguration {
+	return f.mutation(func(configuration *knativeservingv1.Configuration) {
+		configuration.Status.LatestReadyRevisionName = name
+	})
+}
+
+func (f *knativeConfiguration) StatusLatestCreatedRevisionName(name string) *knativeConfiguration {
+	return f.mutation(func(configuration *knativeservingv1.Configuration) {
+		configuration.Status.LatestCreatedRevisionName = name
+	})
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factories
+
+import (
+	"fmt"
+
+	"github.com/projectriff/system/pkg/apis"
+	knativeservingv1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/knative/serving/v1alpha1"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+)
+
+type knativeDomainMapping struct {
+	target *knativeservingv1alpha1.DomainMapping
+}
+
+var (
+	_ rtesting.Factory = (*knativeDomainMapping)(nil)
+)
+
+func KnativeDomainMapping(seed ...*knativeservingv1alpha1.DomainMapping) *knativeDomainMapping {
+	var target *knativeservingv1alpha1.DomainMapping
+	switch len(seed) {
+	case 0:
+		target = &knativeservingv1alpha1.DomainMapping{}
+	case 1:
+		target = seed[0]
+	default:
+		panic(fmt.Errorf("expected exactly zero or one seed, got %v", seed))
+	}
+	return &knativeDomainMapping{
+		target: target,
+	}
+}
+
+func (f *knativeDomainMapping) deepCopy() *knativeDomainMapping {
+	return KnativeDomainMapping(f.target.DeepCopy())
+}
+
+func (f *knativeDomainMapping) Create() apis.Object {
+	return f.deepCopy().target
+}
+
+func (f *knativeDomainMapping) mutation(m func(*knativeservingv1alpha1.DomainMapping)) *knativeDomainMapping {
+	f = f.deepCopy()
+	m(f.target)
+	return f
+}
+
+func (f *knativeDomainMapping) NamespaceName(namespace, name string) *knativeDomainMapping {
+	return f.mutation(func(mapping *knativeservingv1alpha1.DomainMapping) {
+		mapping.ObjectMeta.Namespace = namespace
+		mapping.ObjectMeta.Name = name
+	})
+}
+
+func (f *knativeDomainMapping) ObjectMeta(nf func(ObjectMeta)) *knativeDomainMapping {
+	return f.mutation(func(mapping *knativeservingv1alpha1.DomainMapping) {
+		omf := objectMeta(mapping.ObjectMeta)
+		nf(omf)
+		mapping.ObjectMeta = omf.Create()
+	})
+}
+
+func (f *knativeDomainMapping) RouteRef(name string) *knativeDomainMapping {
+	return f.mutation(func(mapping *knativeservingv1alpha1.DomainMapping) {
+		mapping.Spec.Ref = knativeservingv1alpha1.KReference{
+			APIVersion: "serving.knative.dev/v1",
+			Kind:       "Route",
+			Name:       name,
+		}
+	})
+}
+
+func (f *knativeDomainMapping) TLSSecretName(secretName string) *knativeDomainMapping {
+	return f.mutation(func(mapping *knativeservingv1alpha1.DomainMapping) {
+		mapping.Spec.TLS = &knativeservingv1alpha1.SecretTLS{
+			SecretName: secretName,
+		}
+	})
+}
+
+func (f *knativeDomainMapping) StatusConditions(conditions ...*condition) *knativeDomainMapping {
+	return f.mutation(func(mapping *knativeservingv1alpha1.DomainMapping) {
+		c := make([]apis.Condition, len(conditions))
+		for i, cg := range conditions {
+			c[i] = cg.Create()
+		}
+		mapping.Status.Conditions = c
+	})
+}
+
+func (f *knativeDomainMapping) StatusReady() *knativeDomainMapping {
+	return f.StatusConditions(
+		Condition().Type(knativeservingv1alpha1.DomainMappingConditionReady).True(),
+	)
+}
+
+func (f *knativeDomainMapping) StatusURL(url string) *knativeDomainMapping {
+	return f.mutation(func(mapping *knativeservingv1alpha1.DomainMapping) {
+		mapping.Status.URL = url
+	})
+}
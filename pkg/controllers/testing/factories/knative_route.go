@@ -117,3 +117,9 @@ func (f *knativeRoute) StatusURL(url string) *knativeRoute {
 		route.Status.URL = url
 	})
 }
+
+func (f *knativeRoute) StatusTraffic(traffic ...knativeservingv1.TrafficTarget) *knativeRoute {
+	return f.mutation(func(route *knativeservingv1.Route) {
+		route.Status.Traffic = traffic
+	})
+}
@@ -122,3 +122,15 @@ func (f *knativeService) StatusObservedGeneration(generation int64) *knativeServ
 		service.Status.ObservedGeneration = generation
 	})
 }
+
+func (f *knativeService) StatusLatestReadyRevisionName(name string) *knativeService {
+	return f.mutation(func(service *knativeservingv1.Service) {
+		service.Status.LatestReadyRevisionName = name
+	})
+}
+
+func (f *knativeService) StatusLatestCreatedRevisionName(name string) *knativeService {
+	return f.mutation(func(service *knativeservingv1.Service) {
+		service.Status.LatestCreatedRevisionName = name
+	})
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factories
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/projectriff/system/pkg/apis"
+	kpackbuildv1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/kpack/build/v1alpha1"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+)
+
+type kpackBuild struct {
+	target *kpackbuildv1alpha1.Build
+}
+
+var (
+	_ rtesting.Factory = (*kpackBuild)(nil)
+)
+
+func KpackBuild(seed ...*kpackbuildv1alpha1.Build) *kpackBuild {
+	var target *kpackbuildv1alpha1.Build
+	switch len(seed) {
+	case 0:
+		target = &kpackbuildv1alpha1.Build{}
+	case 1:
+		target = seed[0]
+	default:
+		panic(fmt.Errorf("expected exactly zero or one seed, got %v", seed))
+	}
+	return &kpackBuild{
+		target: target,
+	}
+}
+
+func (f *kpackBuild) deepCopy() *kpackBuild {
+	return KpackBuild(f.target.DeepCopy())
+}
+
+func (f *kpackBuild) Create() apis.Object {
+	return f.deepCopy().target
+}
+
+func (f *kpackBuild) mutation(m func(*kpackbuildv1alpha1.Build)) *kpackBuild {
+	f = f.deepCopy()
+	m(f.target)
+	return f
+}
+
+func (f *kpackBuild) NamespaceName(namespace, name string) *kpackBuild {
+	return f.mutation(func(build *kpackbuildv1alpha1.Build) {
+		build.ObjectMeta.Namespace = namespace
+		build.ObjectMeta.Name = name
+	})
+}
+
+func (f *kpackBuild) ObjectMeta(nf func(ObjectMeta)) *kpackBuild {
+	return f.mutation(func(build *kpackbuildv1alpha1.Build) {
+		omf := objectMeta(build.ObjectMeta)
+		nf(omf)
+		build.ObjectMeta = omf.Create()
+	})
+}
+
+func (f *kpackBuild) Image(name string) *kpackBuild {
+	return f.ObjectMeta(func(om ObjectMeta) {
+		om.AddLabel(kpackbuildv1alpha1.ImageLabel, name)
+	})
+}
+
+func (f *kpackBuild) BuildNumber(number int64) *kpackBuild {
+	return f.ObjectMeta(func(om ObjectMeta) {
+		om.AddLabel(kpackbuildv1alpha1.BuildNumberLabel, fmt.Sprintf("%d", number))
+	})
+}
+
+func (f *kpackBuild) Reason(reason string) *kpackBuild {
+	return f.ObjectMeta(func(om ObjectMeta) {
+		om.AddAnnotation(kpackbuildv1alpha1.BuildReasonAnnotation, reason)
+	})
+}
+
+func (f *kpackBuild) SourceGit(url string, revision string) *kpackBuild {
+	return f.mutation(func(build *kpackbuildv1alpha1.Build) {
+		build.Spec.Source = kpackbuildv1alpha1.SourceConfig{
+			Git: &kpackbuildv1alpha1.Git{
+				URL:      url,
+				Revision: revision,
+			},
+			SubPath: build.Spec.Source.SubPath,
+		}
+	})
+}
+
+func (f *kpackBuild) StatusConditions(conditions ...*condition) *kpackBuild {
+	return f.mutation(func(build *kpackbuildv1alpha1.Build) {
+		c := make([]apis.Condition, len(conditions))
+		for i, cg := range conditions {
+			c[i] = cg.Create()
+		}
+		build.Status.Conditions = c
+	})
+}
+
+func (f *kpackBuild) StatusSucceeded(sec int64) *kpackBuild {
+	return f.mutation(func(build *kpackbuildv1alpha1.Build) {
+		build.Status.Conditions = []apis.Condition{
+			{
+				Type:               apis.ConditionSucceeded,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: apis.VolatileTime{Inner: metav1.Unix(sec, 0)},
+			},
+		}
+	})
+}
+
+func (f *kpackBuild) StatusPodName(name string) *kpackBuild {
+	return f.mutation(func(build *kpackbuildv1alpha1.Build) {
+		build.Status.PodName = name
+	})
+}
+
+func (f *kpackBuild) StatusLatestImage(format string, a ...interface{}) *kpackBuild {
+	return f.mutation(func(build *kpackbuildv1alpha1.Build) {
+		build.Status.LatestImage = fmt.Sprintf(format, a...)
+	})
+}
+
+func (f *kpackBuild) StatusBuildpack(id, version string) *kpackBuild {
+	return f.mutation(func(build *kpackbuildv1alpha1.Build) {
+		build.Status.BuildMetadata = append(build.Status.BuildMetadata, kpackbuildv1alpha1.BuildpackMetadata{
+			ID:      id,
+			Version: version,
+		})
+	})
+}
@@ -39,6 +39,7 @@ type ObjectMeta interface {
 	ControlledBy(owner testing.Factory, scheme *runtime.Scheme) ObjectMeta
 	Created(sec int64) ObjectMeta
 	Deleted(sec int64) ObjectMeta
+	ManagedFields(entries ...metav1.ManagedFieldsEntry) ObjectMeta
 }
 
 type objectMetaImpl struct {
@@ -124,3 +125,9 @@ func (f *objectMetaImpl) Deleted(sec int64) ObjectMeta {
 		om.DeletionTimestamp = &timestamp
 	})
 }
+
+func (f *objectMetaImpl) ManagedFields(entries ...metav1.ManagedFieldsEntry) ObjectMeta {
+	return f.mutate(func(om *metav1.ObjectMeta) {
+		om.ManagedFields = entries
+	})
+}
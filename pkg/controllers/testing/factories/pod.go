@@ -0,0 +1,106 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factories
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/projectriff/system/pkg/apis"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+)
+
+type pod struct {
+	target *corev1.Pod
+}
+
+var (
+	_ rtesting.Factory = (*pod)(nil)
+)
+
+func Pod(seed ...*corev1.Pod) *pod {
+	var target *corev1.Pod
+	switch len(seed) {
+	case 0:
+		target = &corev1.Pod{}
+	case 1:
+		target = seed[0]
+	default:
+		panic(fmt.Errorf("expected exactly zero or one seed, got %v", seed))
+	}
+	return &pod{
+		target: target,
+	}
+}
+
+func (f *pod) deepCopy() *pod {
+	return Pod(f.target.DeepCopy())
+}
+
+func (f *pod) Create() apis.Object {
+	return f.deepCopy().target
+}
+
+func (f *pod) mutation(m func(*corev1.Pod)) *pod {
+	f = f.deepCopy()
+	m(f.target)
+	return f
+}
+
+func (f *pod) NamespaceName(namespace, name string) *pod {
+	return f.mutation(func(pod *corev1.Pod) {
+		pod.ObjectMeta.Namespace = namespace
+		pod.ObjectMeta.Name = name
+	})
+}
+
+func (f *pod) ObjectMeta(nf func(ObjectMeta)) *pod {
+	return f.mutation(func(pod *corev1.Pod) {
+		omf := objectMeta(pod.ObjectMeta)
+		nf(omf)
+		pod.ObjectMeta = omf.Create()
+	})
+}
+
+func (f *pod) StatusContainerTerminated(name string, exitCode int32, message string) *pod {
+	return f.mutation(func(pod *corev1.Pod) {
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{
+			Name: name,
+			State: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: exitCode,
+					Message:  message,
+				},
+			},
+		})
+	})
+}
+
+func (f *pod) StatusInitContainerTerminated(name string, exitCode int32, message string) *pod {
+	return f.mutation(func(pod *corev1.Pod) {
+		pod.Status.InitContainerStatuses = append(pod.Status.InitContainerStatuses, corev1.ContainerStatus{
+			Name: name,
+			State: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: exitCode,
+					Message:  message,
+				},
+			},
+		})
+	})
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factories
+
+import (
+	"fmt"
+
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/projectriff/system/pkg/apis"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+)
+
+type podDisruptionBudget struct {
+	target *policyv1beta1.PodDisruptionBudget
+}
+
+var (
+	_ rtesting.Factory = (*podDisruptionBudget)(nil)
+)
+
+func PodDisruptionBudget(seed ...*policyv1beta1.PodDisruptionBudget) *podDisruptionBudget {
+	var target *policyv1beta1.PodDisruptionBudget
+	switch len(seed) {
+	case 0:
+		target = &policyv1beta1.PodDisruptionBudget{}
+	case 1:
+		target = seed[0]
+	default:
+		panic(fmt.Errorf("expected exactly zero or one seed, got %v", seed))
+	}
+	return &podDisruptionBudget{
+		target: target,
+	}
+}
+
+func (f *podDisruptionBudget) deepCopy() *podDisruptionBudget {
+	return PodDisruptionBudget(f.target.DeepCopy())
+}
+
+func (f *podDisruptionBudget) Create() apis.Object {
+	return f.deepCopy().target
+}
+
+func (f *podDisruptionBudget) mutation(m func(*policyv1beta1.PodDisruptionBudget)) *podDisruptionBudget {
+	f = f.deepCopy()
+	m(f.target)
+	return f
+}
+
+func (f *podDisruptionBudget) NamespaceName(namespace, name string) *podDisruptionBudget {
+	return f.mutation(func(pdb *policyv1beta1.PodDisruptionBudget) {
+		pdb.ObjectMeta.Namespace = namespace
+		pdb.ObjectMeta.Name = name
+	})
+}
+
+func (f *podDisruptionBudget) ObjectMeta(nf func(ObjectMeta)) *podDisruptionBudget {
+	return f.mutation(func(pdb *policyv1beta1.PodDisruptionBudget) {
+		omf := objectMeta(pdb.ObjectMeta)
+		nf(omf)
+		pdb.ObjectMeta = omf.Create()
+	})
+}
+
+func (f *podDisruptionBudget) AddSelectorLabel(key, value string) *podDisruptionBudget {
+	return f.mutation(func(pdb *policyv1beta1.PodDisruptionBudget) {
+		if pdb.Spec.Selector == nil {
+			pdb.Spec.Selector = &metav1.LabelSelector{}
+		}
+		metav1.AddLabelToSelector(pdb.Spec.Selector, key, value)
+	})
+}
+
+func (f *podDisruptionBudget) MinAvailable(minAvailable intstr.IntOrString) *podDisruptionBudget {
+	return f.mutation(func(pdb *policyv1beta1.PodDisruptionBudget) {
+		pdb.Spec.MinAvailable = &minAvailable
+		pdb.Spec.MaxUnavailable = nil
+	})
+}
+
+func (f *podDisruptionBudget) MaxUnavailable(maxUnavailable intstr.IntOrString) *podDisruptionBudget {
+	return f.mutation(func(pdb *policyv1beta1.PodDisruptionBudget) {
+		pdb.Spec.MinAvailable = nil
+		pdb.Spec.MaxUnavailable = &maxUnavailable
+	})
+}
@@ -27,6 +27,7 @@ type PodTemplateSpec interface {
 	AddLabel(key, value string) PodTemplateSpec
 	AddAnnotation(key, value string) PodTemplateSpec
 	ContainerNamed(name string, cb func(*corev1.Container)) PodTemplateSpec
+	AddTopologySpreadConstraint(constraint corev1.TopologySpreadConstraint) PodTemplateSpec
 }
 
 type podTemplateSpecImpl struct {
@@ -92,3 +93,9 @@ func (f *podTemplateSpecImpl) ContainerNamed(name string, cb func(*corev1.Contai
 		}
 	})
 }
+
+func (f *podTemplateSpecImpl) AddTopologySpreadConstraint(constraint corev1.TopologySpreadConstraint) PodTemplateSpec {
+	return f.mutate(func(pts *corev1.PodTemplateSpec) {
+		pts.Spec.TopologySpreadConstraints = append(pts.Spec.TopologySpreadConstraints, constraint)
+	})
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factories
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+
+	"github.com/projectriff/system/pkg/apis"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+)
+
+type replicaSet struct {
+	target *appsv1.ReplicaSet
+}
+
+var (
+	_ rtesting.Factory = (*replicaSet)(nil)
+)
+
+func ReplicaSet(seed ...*appsv1.ReplicaSet) *replicaSet {
+	var target *appsv1.ReplicaSet
+	switch len(seed) {
+	case 0:
+		target = &appsv1.ReplicaSet{}
+	case 1:
+		target = seed[0]
+	default:
+		panic(fmt.Errorf("expected exactly zero or one seed, got %v", seed))
+	}
+	return &replicaSet{
+		target: target,
+	}
+}
+
+func (f *replicaSet) deepCopy() *replicaSet {
+	return ReplicaSet(f.target.DeepCopy())
+}
+
+func (f *replicaSet) Create() apis.Object {
+	return f.deepCopy().target
+}
+
+func (f *replicaSet) mutation(m func(*appsv1.ReplicaSet)) *replicaSet {
+	f = f.deepCopy()
+	m(f.target)
+	return f
+}
+
+func (f *replicaSet) NamespaceName(namespace, name string) *replicaSet {
+	return f.mutation(func(rs *appsv1.ReplicaSet) {
+		rs.ObjectMeta.Namespace = namespace
+		rs.ObjectMeta.Name = name
+	})
+}
+
+func (f *replicaSet) ObjectMeta(nf func(ObjectMeta)) *replicaSet {
+	return f.mutation(func(rs *appsv1.ReplicaSet) {
+		omf := objectMeta(rs.ObjectMeta)
+		nf(omf)
+		rs.ObjectMeta = omf.Create()
+	})
+}
+
+func (f *replicaSet) Replicas(replicas int32) *replicaSet {
+	return f.mutation(func(rs *appsv1.ReplicaSet) {
+		rs.Spec.Replicas = &replicas
+	})
+}
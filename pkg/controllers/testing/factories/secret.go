@@ -17,7 +17,6 @@ limitations under the License.
 package factories
 
 import (
-	"encoding/base64"
 	"fmt"
 
 	corev1 "k8s.io/api/core/v1"
@@ -89,8 +88,6 @@ func (f *secret) AddData(key, value string) *secret {
 		if s.Data == nil {
 			s.Data = map[string][]byte{}
 		}
-		encoded := []byte{}
-		base64.StdEncoding.Encode(encoded, []byte(value))
-		s.Data[key] = encoded
+		s.Data[key] = []byte(value)
 	})
 }
@@ -0,0 +1,87 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factories
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/projectriff/system/pkg/apis"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+)
+
+type unstructuredObject struct {
+	target *unstructured.Unstructured
+}
+
+var (
+	_ rtesting.Factory = (*unstructuredObject)(nil)
+)
+
+func Unstructured(seed ...*unstructured.Unstructured) *unstructuredObject {
+	var target *unstructured.Unstructured
+	switch len(seed) {
+	case 0:
+		target = &unstructured.Unstructured{Object: map[string]interface{}{}}
+	case 1:
+		target = seed[0]
+	default:
+		panic(fmt.Errorf("expected exactly zero or one seed, got %v", seed))
+	}
+	return &unstructuredObject{
+		target: target,
+	}
+}
+
+func (f *unstructuredObject) deepCopy() *unstructuredObject {
+	return Unstructured(f.target.DeepCopy())
+}
+
+func (f *unstructuredObject) Create() apis.Object {
+	return f.deepCopy().target
+}
+
+func (f *unstructuredObject) mutation(m func(*unstructured.Unstructured)) *unstructuredObject {
+	f = f.deepCopy()
+	m(f.target)
+	return f
+}
+
+func (f *unstructuredObject) GroupVersionKind(gvk schema.GroupVersionKind) *unstructuredObject {
+	return f.mutation(func(obj *unstructured.Unstructured) {
+		obj.SetGroupVersionKind(gvk)
+	})
+}
+
+func (f *unstructuredObject) NamespaceName(namespace, name string) *unstructuredObject {
+	return f.mutation(func(obj *unstructured.Unstructured) {
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+	})
+}
+
+// NestedField sets the value of a nested field, the value must be of a type
+// that may be represented in JSON.
+func (f *unstructuredObject) NestedField(value interface{}, fields ...string) *unstructuredObject {
+	return f.mutation(func(obj *unstructured.Unstructured) {
+		if err := unstructured.SetNestedField(obj.Object, value, fields...); err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/projectriff/system/pkg/apis"
+	"github.com/projectriff/system/pkg/controllers"
+	"github.com/projectriff/system/pkg/tracker"
+)
+
+// SubReconcilerTestcase holds a single row of a sub reconciler table test.
+type SubReconcilerTestcase struct {
+	// Name is a descriptive name for this test suitable as a first argument to t.Run()
+	Name string
+	// Focus is true if and only if only this and any other focussed tests are to be executed.
+	// If one or more tests are focussed, the overall table test will fail.
+	Focus bool
+	// Skip is true if and only if this test should be skipped.
+	Skip bool
+
+	// Parent is the initial object passed to the sub reconciler
+	Parent Factory
+
+	// WithReactors installs each ReactionFunc into each fake clientset. ReactionFuncs intercept
+	// each call to the clientset providing the ability to mutate the resource or inject an error.
+	WithReactors []ReactionFunc
+	// GivenObjects build the kubernetes objects which are present at the onset of reconciliation
+	GivenObjects []Factory
+
+	// ExpectParent is the expected parent as mutated after the sub reconciler, or nil if nothing
+	// has been mutated
+	ExpectParent Factory
+	// ExpectTracks holds the ordered list of Track calls expected during reconciliation
+	ExpectTracks []TrackRequest
+	// ExpectEvents holds the ordered list of events recorded during the reconciliation
+	ExpectEvents []Event
+	// ExpectCreates builds the ordered list of objects expected to be created during reconciliation
+	ExpectCreates []Factory
+	// ExpectUpdates builds the ordered list of objects expected to be updated during reconciliation
+	ExpectUpdates []Factory
+	// ExpectDeletes holds the ordered list of objects expected to be deleted during reconciliation
+	ExpectDeletes []DeleteRef
+	// ExpectApplies builds the ordered list of objects expected to be applied with server-side apply
+	// during reconciliation
+	ExpectApplies []Factory
+
+	// ShouldErr is true if and only if reconciliation is expected to return an error
+	ShouldErr bool
+	// ExpectedResult is compared to the result returned from the sub reconciler if there was no error.
+	// Delays are compared to the second, as delays computed from the current time drift while
+	// the test runs.
+	ExpectedResult controllerruntime.Result
+	// Verify provides the reconciliation Result and error for custom assertions
+	Verify VerifyFunc
+}
+
+// SubReconcilerTestSuite represents a list of sub reconciler test cases.
+type SubReconcilerTestSuite []SubReconcilerTestcase
+
+// Test executes the test case.
+func (tc *SubReconcilerTestcase) Test(t *testing.T, scheme *runtime.Scheme, factory SubReconcilerFactory) {
+	t.Helper()
+
+	parent := tc.Parent.Create()
+	expectParent := tc.ExpectParent
+	if expectParent == nil {
+		expectParent = tc.Parent
+	}
+
+	// the sub reconciler is run against the parent as a reconciler for the
+	// table test, without fetching or updating the parent
+	row := &Testcase{
+		Name:           tc.Name,
+		Skip:           tc.Skip,
+		WithReactors:   tc.WithReactors,
+		GivenObjects:   tc.GivenObjects,
+		ExpectTracks:   tc.ExpectTracks,
+		ExpectEvents:   tc.ExpectEvents,
+		ExpectCreates:  tc.ExpectCreates,
+		ExpectUpdates:  tc.ExpectUpdates,
+		ExpectDeletes:  tc.ExpectDeletes,
+		ExpectApplies:  tc.ExpectApplies,
+		ShouldErr:      tc.ShouldErr,
+		ExpectedResult: tc.ExpectedResult,
+		Verify: func(t *testing.T, result controllerruntime.Result, err error) {
+			if diff := cmp.Diff(expectParent.Create(), parent, ignoreVolatileTime, safeDeployDiff, ignoreTypeMeta, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Unexpected parent mutations(-expected, +actual): %s", diff)
+			}
+			if tc.Verify != nil {
+				tc.Verify(t, result, err)
+			}
+		},
+	}
+	row.Test(t, scheme, func(t *testing.T, _ *Testcase, client client.Client, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) reconcile.Reconciler {
+		return &subReconcilerAdapter{
+			parent:     parent,
+			reconciler: factory(t, tc, client, tracker, recorder, log),
+		}
+	})
+}
+
+// Test executes the whole suite of the sub reconciler test cases.
+func (ts SubReconcilerTestSuite) Test(t *testing.T, scheme *runtime.Scheme, factory SubReconcilerFactory) {
+	t.Helper()
+	focussed := SubReconcilerTestSuite{}
+	for _, test := range ts {
+		if test.Focus {
+			focussed = append(focussed, test)
+			break
+		}
+	}
+	testsToExecute := ts
+	if len(focussed) > 0 {
+		testsToExecute = focussed
+	}
+	for _, test := range testsToExecute {
+		t.Run(test.Name, func(t *testing.T) {
+			t.Helper()
+			test.Test(t, scheme, factory)
+		})
+	}
+	if len(focussed) > 0 {
+		t.Errorf("%d tests out of %d are still focussed, so the test suite fails", len(focussed), len(ts))
+	}
+}
+
+// SubReconcilerFactory returns a SubReconciler to perform reconciliation of a parent in a sub
+// reconciler test case.
+type SubReconcilerFactory func(t *testing.T, rtc *SubReconcilerTestcase, client client.Client, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) controllers.SubReconciler
+
+type subReconcilerAdapter struct {
+	parent     apis.Object
+	reconciler controllers.SubReconciler
+}
+
+func (a *subReconcilerAdapter) Validate() error {
+	return a.reconciler.Validate()
+}
+
+func (a *subReconcilerAdapter) Reconcile(req reconcile.Request) (reconcile.Result, error) {
+	return a.reconciler.Reconcile(controllers.WithStash(context.Background()), a.parent)
+}
@@ -17,8 +17,11 @@ limitations under the License.
 package testing
 
 import (
+	"encoding/json"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/google/go-cmp/cmp"
@@ -63,12 +66,17 @@ type Testcase struct {
 	ExpectUpdates []Factory
 	// ExpectDeletes holds the ordered list of objects expected to be deleted during reconciliation
 	ExpectDeletes []DeleteRef
+	// ExpectApplies builds the ordered list of objects expected to be applied with server-side apply
+	// during reconciliation
+	ExpectApplies []Factory
 	// ExpectStatusUpdates builds the ordered list of objects whose status is updated during reconciliation
 	ExpectStatusUpdates []Factory
 
 	// ShouldErr is true if and only if reconciliation is expected to return an error
 	ShouldErr bool
-	// ExpectedResult is compared to the result returned from the reconciler if there was no error
+	// ExpectedResult is compared to the result returned from the reconciler if there was no error.
+	// Delays are compared to the second, as delays computed from the current time drift while
+	// the test runs.
 	ExpectedResult controllerruntime.Result
 	// Verify provides the reconciliation Result and error for custom assertions
 	Verify VerifyFunc
@@ -109,6 +117,11 @@ func (tc *Testcase) Test(t *testing.T, scheme *runtime.Scheme, factory Reconcile
 	}
 	log := TestLogger(t)
 	c := factory(t, tc, clientWrapper, tracker, recorder, log)
+	if v, ok := c.(validator); ok {
+		if err := v.Validate(); err != nil {
+			t.Fatalf("Invalid reconciler: %v", err)
+		}
+	}
 
 	// Run the Reconcile we're testing.
 	result, err := c.Reconcile(reconcile.Request{
@@ -120,7 +133,7 @@ func (tc *Testcase) Test(t *testing.T, scheme *runtime.Scheme, factory Reconcile
 	}
 	if err == nil {
 		// result is only significant if there wasn't an error
-		if diff := cmp.Diff(tc.ExpectedResult, result); diff != "" {
+		if diff := cmp.Diff(tc.ExpectedResult, result, equateApproxDuration); diff != "" {
 			t.Errorf("Unexpected result (-expected, +actual): %s", diff)
 		}
 	}
@@ -170,7 +183,7 @@ func (tc *Testcase) Test(t *testing.T, scheme *runtime.Scheme, factory Reconcile
 		}
 		actual := clientWrapper.createActions[i].GetObject()
 
-		if diff := cmp.Diff(exp.Create(), actual, ignoreLastTransitionTime, safeDeployDiff, ignoreTypeMeta, cmpopts.EquateEmpty()); diff != "" {
+		if diff := cmp.Diff(exp.Create(), actual, ignoreVolatileTime, safeDeployDiff, ignoreTypeMeta, cmpopts.EquateEmpty()); diff != "" {
 			t.Errorf("Unexpected create (-expected, +actual): %s", diff)
 		}
 	}
@@ -187,7 +200,7 @@ func (tc *Testcase) Test(t *testing.T, scheme *runtime.Scheme, factory Reconcile
 		}
 		actual := clientWrapper.updateActions[i].GetObject()
 
-		if diff := cmp.Diff(exp.Create(), actual, ignoreLastTransitionTime, safeDeployDiff, ignoreTypeMeta, cmpopts.EquateEmpty()); diff != "" {
+		if diff := cmp.Diff(exp.Create(), actual, ignoreVolatileTime, safeDeployDiff, ignoreTypeMeta, cmpopts.EquateEmpty()); diff != "" {
 			t.Errorf("Unexpected update (-expected, +actual): %s", diff)
 		}
 	}
@@ -214,6 +227,28 @@ func (tc *Testcase) Test(t *testing.T, scheme *runtime.Scheme, factory Reconcile
 		}
 	}
 
+	for i, exp := range tc.ExpectApplies {
+		if i >= len(clientWrapper.applyActions) {
+			t.Errorf("Missing apply: %#v", exp.Create())
+			continue
+		}
+		expected := exp.Create()
+		actual := reflect.New(reflect.TypeOf(expected).Elem()).Interface()
+		if err := json.Unmarshal(clientWrapper.applyActions[i].GetPatch(), actual); err != nil {
+			t.Errorf("Unable to decode apply: %v", err)
+			continue
+		}
+
+		if diff := cmp.Diff(expected, actual, ignoreVolatileTime, safeDeployDiff, ignoreTypeMeta, cmpopts.EquateEmpty()); diff != "" {
+			t.Errorf("Unexpected apply (-expected, +actual): %s", diff)
+		}
+	}
+	if actual, expected := len(clientWrapper.applyActions), len(tc.ExpectApplies); actual > expected {
+		for _, extra := range clientWrapper.applyActions[expected:] {
+			t.Errorf("Extra apply: %s", extra.GetPatch())
+		}
+	}
+
 	for i, exp := range tc.ExpectStatusUpdates {
 		if i >= len(clientWrapper.statusUpdateActions) {
 			t.Errorf("Missing status update: %#v", exp.Create())
@@ -221,7 +256,7 @@ func (tc *Testcase) Test(t *testing.T, scheme *runtime.Scheme, factory Reconcile
 		}
 		actual := clientWrapper.statusUpdateActions[i].GetObject()
 
-		if diff := cmp.Diff(exp.Create(), actual, statusSubresourceOnly, ignoreLastTransitionTime, safeDeployDiff, cmpopts.EquateEmpty()); diff != "" {
+		if diff := cmp.Diff(exp.Create(), actual, statusSubresourceOnly, ignoreVolatileTime, safeDeployDiff, cmpopts.EquateEmpty()); diff != "" {
 			t.Errorf("Unexpected status update (-expected, +actual): %s", diff)
 		}
 	}
@@ -238,8 +273,9 @@ func (tc *Testcase) Test(t *testing.T, scheme *runtime.Scheme, factory Reconcile
 }
 
 var (
-	ignoreLastTransitionTime = cmp.FilterPath(func(p cmp.Path) bool {
-		return strings.HasSuffix(p.String(), "LastTransitionTime.Inner.Time")
+	ignoreVolatileTime = cmp.FilterPath(func(p cmp.Path) bool {
+		// VolatileTime fields, like LastTransitionTime
+		return strings.HasSuffix(p.String(), "Time.Inner.Time")
 	}, cmp.Ignore())
 	ignoreTypeMeta = cmp.FilterPath(func(p cmp.Path) bool {
 		return strings.HasSuffix(p.String(), "TypeMeta.APIVersion") || strings.HasSuffix(p.String(), "TypeMeta.Kind")
@@ -251,6 +287,12 @@ var (
 	}, cmp.Ignore())
 
 	safeDeployDiff = cmpopts.IgnoreUnexported(resource.Quantity{})
+
+	equateApproxDuration = cmp.Comparer(func(x, y time.Duration) bool {
+		// delays computed from the current time, like RequeueAfter
+		d := x - y
+		return -time.Second < d && d < time.Second
+	})
 )
 
 // Test executes the whole suite of the table tests.
@@ -278,6 +320,12 @@ func (tb Table) Test(t *testing.T, scheme *runtime.Scheme, factory ReconcilerFac
 	}
 }
 
+// validator is implemented by reconcilers that are able to check their
+// configuration, like the ParentReconciler.
+type validator interface {
+	Validate() error
+}
+
 // ReconcilerFactory returns a Reconciler.Interface to perform reconciliation in table test,
 // ActionRecorderList/EventList to capture k8s actions/events produced during reconciliation
 // and FakeStatsReporter to capture stats.
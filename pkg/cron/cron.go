@@ -0,0 +1,185 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression with the standard five fields: minute,
+// hour, day of month, month and day of week. Each field is a `*`, a value, a
+// range (`1-5`) or a comma separated list of them, optionally with a step
+// (`*/15`, `0-30/10`). Months and days of the week are numeric, Sunday is 0
+// or 7. When both the day of month and day of week are restricted, a time
+// matching either field matches.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+type bounds struct {
+	name     string
+	min, max int
+}
+
+var (
+	minutes = bounds{"minute", 0, 59}
+	hours   = bounds{"hour", 0, 23}
+	doms    = bounds{"day of month", 1, 31}
+	months  = bounds{"month", 1, 12}
+	dows    = bounds{"day of week", 0, 7}
+)
+
+// Parse parses a five field cron expression.
+func Parse(spec string) (*Schedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, found %d in %q", len(fields), spec)
+	}
+	s := &Schedule{}
+	var err error
+	if s.minute, err = parseField(fields[0], minutes); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], hours); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], doms); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], months); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], dows); err != nil {
+		return nil, err
+	}
+	// Sunday is both 0 and 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domStar = strings.HasPrefix(fields[2], "*")
+	s.dowStar = strings.HasPrefix(fields[4], "*")
+	return s, nil
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s field %q", part[i+1:], b.name, field)
+			}
+			rangePart, step = part[:i], n
+		}
+		start, end := b.min, b.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			i := strings.Index(rangePart, "-")
+			var err error
+			if start, err = parseValue(rangePart[:i], b); err != nil {
+				return 0, err
+			}
+			if end, err = parseValue(rangePart[i+1:], b); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("invalid range %q in %s field", rangePart, b.name)
+			}
+		default:
+			var err error
+			if start, err = parseValue(rangePart, b); err != nil {
+				return 0, err
+			}
+			end = start
+			if step > 1 {
+				// a step from a single value continues to the end of the range
+				end = b.max
+			}
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(value string, b bounds) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in %s field", value, b.name)
+	}
+	if n < b.min || n > b.max {
+		return 0, fmt.Errorf("value %d out of range %d-%d in %s field", n, b.min, b.max, b.name)
+	}
+	return n, nil
+}
+
+// Next returns the first time matching the schedule that is after t, in t's
+// location. The zero time is returned when the schedule does not match within
+// five years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Window is a period that opens on a schedule and stays open for a duration.
+type Window struct {
+	Schedule *Schedule
+	Duration time.Duration
+}
+
+// Open is true when a window opened no more than the window's duration
+// before t.
+func (w Window) Open(t time.Time) bool {
+	start := w.Schedule.Next(t.Add(-w.Duration))
+	return !start.IsZero() && !start.After(t)
+}
+
+// NextOpen returns when the window next opens after t.
+func (w Window) NextOpen(t time.Time) time.Time {
+	return w.Schedule.Next(t)
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		shouldErr bool
+	}{{
+		name: "every minute",
+		spec: "* * * * *",
+	}, {
+		name: "lists, ranges and steps",
+		spec: "0,30 1-5 */2 1-12/3 1-5",
+	}, {
+		name: "sunday as 7",
+		spec: "0 0 * * 7",
+	}, {
+		name:      "too few fields",
+		spec:      "* * * *",
+		shouldErr: true,
+	}, {
+		name:      "too many fields",
+		spec:      "* * * * * *",
+		shouldErr: true,
+	}, {
+		name:      "out of range",
+		spec:      "60 * * * *",
+		shouldErr: true,
+	}, {
+		name:      "reversed range",
+		spec:      "* 5-1 * * *",
+		shouldErr: true,
+	}, {
+		name:      "invalid step",
+		spec:      "*/0 * * * *",
+		shouldErr: true,
+	}, {
+		name:      "names",
+		spec:      "* * * JAN MON",
+		shouldErr: true,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse(test.spec)
+			if (err != nil) != test.shouldErr {
+				t.Errorf("Parse() error = %v, shouldErr %v", err, test.shouldErr)
+			}
+		})
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	// a Wednesday
+	from := time.Date(2020, time.January, 15, 10, 20, 30, 0, time.UTC)
+	tests := []struct {
+		name     string
+		spec     string
+		expected time.Time
+	}{{
+		name:     "every minute",
+		spec:     "* * * * *",
+		expected: time.Date(2020, time.January, 15, 10, 21, 0, 0, time.UTC),
+	}, {
+		name:     "every fifteen minutes",
+		spec:     "*/15 * * * *",
+		expected: time.Date(2020, time.January, 15, 10, 30, 0, 0, time.UTC),
+	}, {
+		name:     "later today",
+		spec:     "0 22 * * *",
+		expected: time.Date(2020, time.January, 15, 22, 0, 0, 0, time.UTC),
+	}, {
+		name:     "tomorrow",
+		spec:     "0 2 * * *",
+		expected: time.Date(2020, time.January, 16, 2, 0, 0, 0, time.UTC),
+	}, {
+		name:     "saturday",
+		spec:     "0 2 * * 6",
+		expected: time.Date(2020, time.January, 18, 2, 0, 0, 0, time.UTC),
+	}, {
+		name:     "sunday as 7",
+		spec:     "0 2 * * 7",
+		expected: time.Date(2020, time.January, 19, 2, 0, 0, 0, time.UTC),
+	}, {
+		name:     "day of month or day of week",
+		spec:     "0 0 1 * 5",
+		expected: time.Date(2020, time.January, 17, 0, 0, 0, 0, time.UTC),
+	}, {
+		name:     "next year",
+		spec:     "0 0 1 1 *",
+		expected: time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}, {
+		name:     "leap day",
+		spec:     "0 0 29 2 *",
+		expected: time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC),
+	}, {
+		name: "never",
+		spec: "0 0 31 2 *",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := Parse(test.spec)
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			if actual := s.Next(from); !actual.Equal(test.expected) {
+				t.Errorf("Next() = %v, expected %v", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestWindow_Open(t *testing.T) {
+	window := Window{
+		// saturdays at 2am for two hours
+		Schedule: mustParse(t, "0 2 * * 6"),
+		Duration: 2 * time.Hour,
+	}
+	tests := []struct {
+		name     string
+		at       time.Time
+		expected bool
+	}{{
+		name:     "before",
+		at:       time.Date(2020, time.January, 18, 1, 59, 0, 0, time.UTC),
+		expected: false,
+	}, {
+		name:     "opening",
+		at:       time.Date(2020, time.January, 18, 2, 0, 0, 0, time.UTC),
+		expected: true,
+	}, {
+		name:     "during",
+		at:       time.Date(2020, time.January, 18, 3, 30, 0, 0, time.UTC),
+		expected: true,
+	}, {
+		name:     "closing",
+		at:       time.Date(2020, time.January, 18, 4, 0, 0, 0, time.UTC),
+		expected: false,
+	}, {
+		name:     "another day",
+		at:       time.Date(2020, time.January, 19, 3, 0, 0, 0, time.UTC),
+		expected: false,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := window.Open(test.at); actual != test.expected {
+				t.Errorf("Open() = %v, expected %v", actual, test.expected)
+			}
+		})
+	}
+}
+
+func mustParse(t *testing.T, spec string) *Schedule {
+	s, err := Parse(spec)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	return s
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fieldpath
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Path locates a field within an unstructured object. Paths are parsed from a
+// subset of JSONPath: child fields (`.spec.template`), array indexes
+// (`.containers[0]`) and equality filters on a child field of array items
+// (`.containers[?(@.name=="app")]`). The expression may be wrapped in braces
+// and may start with the root `$`.
+type Path []Segment
+
+// Segment is a single step of a path. A field segment has a Field, an index
+// segment has an Index and a filter segment has a FilterField and
+// FilterValue.
+type Segment struct {
+	Field       string
+	Index       *int
+	FilterField string
+	FilterValue string
+}
+
+var (
+	fieldPattern  = regexp.MustCompile(`^\.([a-zA-Z0-9_-]+)`)
+	indexPattern  = regexp.MustCompile(`^\[([0-9]+)\]`)
+	filterPattern = regexp.MustCompile(`^\[\?\(@\.([a-zA-Z0-9_-]+)\s*==\s*(?:"([^"]*)"|'([^']*)')\)\]`)
+)
+
+// Parse parses a path expression.
+func Parse(expr string) (Path, error) {
+	s := strings.TrimSpace(expr)
+	if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+		s = s[1 : len(s)-1]
+	}
+	s = strings.TrimPrefix(s, "$")
+	if s == "" {
+		return nil, fmt.Errorf("empty path %q", expr)
+	}
+
+	path := Path{}
+	for s != "" {
+		if m := fieldPattern.FindStringSubmatch(s); m != nil {
+			path = append(path, Segment{Field: m[1]})
+			s = s[len(m[0]):]
+			continue
+		}
+		if m := indexPattern.FindStringSubmatch(s); m != nil {
+			i, err := strconv.Atoi(m[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid index in path %q: %v", expr, err)
+			}
+			path = append(path, Segment{Index: &i})
+			s = s[len(m[0]):]
+			continue
+		}
+		if m := filterPattern.FindStringSubmatch(s); m != nil {
+			path = append(path, Segment{FilterField: m[1], FilterValue: m[2] + m[3]})
+			s = s[len(m[0]):]
+			continue
+		}
+		return nil, fmt.Errorf("unsupported path %q at %q", expr, s)
+	}
+	if path[0].Field == "" {
+		return nil, fmt.Errorf("path %q must start with a field", expr)
+	}
+	return path, nil
+}
+
+// MustParse parses a path expression, panicking if it is invalid.
+func MustParse(expr string) Path {
+	path, err := Parse(expr)
+	if err != nil {
+		panic(err)
+	}
+	return path
+}
+
+// Child returns a new path for the expression relative to this path.
+func (p Path) Child(expr string) (Path, error) {
+	child, err := Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(Path{}, p...), child...), nil
+}
+
+func (p Path) String() string {
+	b := strings.Builder{}
+	for _, segment := range p {
+		switch {
+		case segment.Index != nil:
+			fmt.Fprintf(&b, "[%d]", *segment.Index)
+		case segment.FilterField != "":
+			fmt.Fprintf(&b, "[?(@.%s==%q)]", segment.FilterField, segment.FilterValue)
+		default:
+			fmt.Fprintf(&b, ".%s", segment.Field)
+		}
+	}
+	return b.String()
+}
+
+// GetString returns the string value at the path. Found is false when a
+// segment of the path does not exist.
+func (p Path) GetString(obj map[string]interface{}) (value string, found bool, err error) {
+	v, found, err := p.get(obj)
+	if !found || err != nil {
+		return "", found, err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", true, fmt.Errorf("%s is of type %T, expected string", p, v)
+	}
+	return s, true, nil
+}
+
+// SetString sets the string value at the path. Every segment of the path
+// except the last field must already exist, an existing value must be a
+// string.
+func (p Path) SetString(obj map[string]interface{}, value string) error {
+	last := p[len(p)-1]
+	if last.Field == "" {
+		return fmt.Errorf("%s must end with a field", p)
+	}
+	parent, found, err := p[:len(p)-1].get(obj)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%s not found", p[:len(p)-1])
+	}
+	m, ok := parent.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s is of type %T, expected object", p[:len(p)-1], parent)
+	}
+	if current, ok := m[last.Field]; ok {
+		if _, ok := current.(string); !ok {
+			return fmt.Errorf("%s is of type %T, expected string", p, current)
+		}
+	}
+	m[last.Field] = value
+	return nil
+}
+
+func (p Path) get(obj map[string]interface{}) (interface{}, bool, error) {
+	var current interface{} = obj
+	for i, segment := range p {
+		switch {
+		case segment.Field != "":
+			m, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false, fmt.Errorf("%s is of type %T, expected object", p[:i], current)
+			}
+			if current, ok = m[segment.Field]; !ok {
+				return nil, false, nil
+			}
+		default:
+			items, ok := current.([]interface{})
+			if !ok {
+				return nil, false, fmt.Errorf("%s is of type %T, expected array", p[:i], current)
+			}
+			current = nil
+			if segment.Index != nil {
+				if *segment.Index >= len(items) {
+					return nil, false, nil
+				}
+				current = items[*segment.Index]
+				break
+			}
+			for _, item := range items {
+				if m, ok := item.(map[string]interface{}); ok && m[segment.FilterField] == segment.FilterValue {
+					current = item
+					break
+				}
+			}
+			if current == nil {
+				return nil, false, nil
+			}
+		}
+	}
+	return current, true, nil
+}
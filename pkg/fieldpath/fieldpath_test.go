@@ -0,0 +1,186 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fieldpath_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/projectriff/system/pkg/fieldpath"
+)
+
+func TestParse(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		expr     string
+		expected string
+		err      bool
+	}{{
+		name:     "fields",
+		expr:     ".spec.template",
+		expected: ".spec.template",
+	}, {
+		name:     "root and braces",
+		expr:     "{$.spec.template}",
+		expected: ".spec.template",
+	}, {
+		name:     "index",
+		expr:     ".spec.containers[1].image",
+		expected: ".spec.containers[1].image",
+	}, {
+		name:     "filter",
+		expr:     `.spec.containers[?(@.name=='app')].image`,
+		expected: `.spec.containers[?(@.name=="app")].image`,
+	}, {
+		name: "empty",
+		expr: "{}",
+		err:  true,
+	}, {
+		name: "wildcard",
+		expr: ".spec.containers[*].image",
+		err:  true,
+	}, {
+		name: "must start with a field",
+		expr: "[0].image",
+		err:  true,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			path, err := fieldpath.Parse(c.expr)
+			if (err != nil) != c.err {
+				t.Fatalf("Parse(%q) error = %v, expected error %v", c.expr, err, c.err)
+			}
+			if err != nil {
+				return
+			}
+			if actual := path.String(); actual != c.expected {
+				t.Errorf("Parse(%q) = %q, expected %q", c.expr, actual, c.expected)
+			}
+		})
+	}
+}
+
+func TestPath_GetSetString(t *testing.T) {
+	newObj := func() map[string]interface{} {
+		return map[string]interface{}{
+			"spec": map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{"name": "sidecar", "image": "envoy"},
+					map[string]interface{}{"name": "app", "image": "my-image"},
+				},
+				"replicas": int64(1),
+			},
+		}
+	}
+
+	for _, c := range []struct {
+		name     string
+		path     string
+		value    string
+		found    bool
+		getErr   bool
+		setErr   bool
+		expected map[string]interface{}
+	}{{
+		name:  "filter",
+		path:  `.spec.containers[?(@.name=="app")].image`,
+		value: "my-image",
+		found: true,
+		expected: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{"name": "sidecar", "image": "envoy"},
+					map[string]interface{}{"name": "app", "image": "new-image"},
+				},
+				"replicas": int64(1),
+			},
+		},
+	}, {
+		name:  "index",
+		path:  ".spec.containers[0].image",
+		value: "envoy",
+		found: true,
+		expected: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{"name": "sidecar", "image": "new-image"},
+					map[string]interface{}{"name": "app", "image": "my-image"},
+				},
+				"replicas": int64(1),
+			},
+		},
+	}, {
+		name:  "missing field is set",
+		path:  ".spec.image",
+		found: false,
+		expected: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{"name": "sidecar", "image": "envoy"},
+					map[string]interface{}{"name": "app", "image": "my-image"},
+				},
+				"image":    "new-image",
+				"replicas": int64(1),
+			},
+		},
+	}, {
+		name:   "missing item",
+		path:   `.spec.containers[?(@.name=="other")].image`,
+		found:  false,
+		setErr: true,
+	}, {
+		name:   "index out of range",
+		path:   ".spec.containers[2].image",
+		found:  false,
+		setErr: true,
+	}, {
+		name:   "not a string",
+		path:   ".spec.replicas",
+		found:  true,
+		getErr: true,
+		setErr: true,
+	}, {
+		name:   "not an array",
+		path:   ".spec.replicas[0].image",
+		getErr: true,
+		setErr: true,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			path := fieldpath.MustParse(c.path)
+
+			obj := newObj()
+			value, found, err := path.GetString(obj)
+			if (err != nil) != c.getErr {
+				t.Fatalf("GetString() error = %v, expected error %v", err, c.getErr)
+			}
+			if value != c.value || found != c.found {
+				t.Errorf("GetString() = %q, %v, expected %q, %v", value, found, c.value, c.found)
+			}
+
+			err = path.SetString(obj, "new-image")
+			if (err != nil) != c.setErr {
+				t.Fatalf("SetString() error = %v, expected error %v", err, c.setErr)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(c.expected, obj); diff != "" {
+				t.Errorf("SetString() (-expected, +actual) = %v", diff)
+			}
+		})
+	}
+}
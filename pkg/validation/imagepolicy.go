@@ -0,0 +1,195 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// ImagePolicyAllowedImagesKey lists patterns for the repositories of
+	// allowed images, separated by commas or new lines.
+	ImagePolicyAllowedImagesKey = "allowedImages"
+	// ImagePolicyRequireDigestKey requires images to be pinned by digest when
+	// "true".
+	ImagePolicyRequireDigestKey = "requireDigest"
+)
+
+// ImagePolicy restricts the images workloads may run.
+type ImagePolicy struct {
+	// AllowedImages are patterns matching the repository of allowed images,
+	// like "gcr.io/my-project/my-image" or "gcr.io/my-project/*". A pattern
+	// ending in "/**" matches every repository under the prefix. Images on
+	// Docker Hub are matched as "index.docker.io/library/ubuntu". Without
+	// patterns, images from any repository are allowed.
+	AllowedImages []string
+
+	// RequireDigest requires images to be pinned by digest.
+	RequireDigest bool
+}
+
+// NewImagePolicyFromData parses the policy from the data of a ConfigMap.
+func NewImagePolicyFromData(data map[string]string) (*ImagePolicy, error) {
+	p := &ImagePolicy{
+		AllowedImages: []string{},
+	}
+	for _, pattern := range strings.FieldsFunc(data[ImagePolicyAllowedImagesKey], func(r rune) bool { return r == ',' || r == '\n' }) {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid image pattern %q: %v", pattern, err)
+		}
+		p.AllowedImages = append(p.AllowedImages, normalizeImagePattern(pattern))
+	}
+	if value, ok := data[ImagePolicyRequireDigestKey]; ok {
+		requireDigest, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q", ImagePolicyRequireDigestKey, value)
+		}
+		p.RequireDigest = requireDigest
+	}
+	return p, nil
+}
+
+// normalizeImagePattern matches Docker Hub patterns against the canonical
+// registry name
+func normalizeImagePattern(pattern string) string {
+	if strings.HasPrefix(pattern, "docker.io/") {
+		return "index." + pattern
+	}
+	return pattern
+}
+
+// CheckImage returns an error when the image is not from an allowed
+// repository or, when required, not pinned by digest.
+func (p *ImagePolicy) CheckImage(image string) error {
+	ref, err := p.checkRepository(image)
+	if err != nil {
+		return err
+	}
+	if _, ok := ref.(name.Digest); p.RequireDigest && !ok {
+		return fmt.Errorf("image %q must be pinned by digest", image)
+	}
+	return nil
+}
+
+// CheckRepository returns an error when the image is not from an allowed
+// repository. Images are not required to be pinned by digest, as for images
+// that are resolved or built.
+func (p *ImagePolicy) CheckRepository(image string) error {
+	_, err := p.checkRepository(image)
+	return err
+}
+
+func (p *ImagePolicy) checkRepository(image string) (name.Reference, error) {
+	ref, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image %q: %v", image, err)
+	}
+	if len(p.AllowedImages) == 0 {
+		return ref, nil
+	}
+	repository := ref.Context().Name()
+	for _, pattern := range p.AllowedImages {
+		if strings.HasSuffix(pattern, "/**") {
+			if strings.HasPrefix(repository, strings.TrimSuffix(pattern, "**")) {
+				return ref, nil
+			}
+		} else if ok, _ := path.Match(pattern, repository); ok {
+			return ref, nil
+		}
+	}
+	return nil, fmt.Errorf("image %q is not from an allowed repository", image)
+}
+
+// ValidateImage reports the image field when the image is not allowed.
+func (p *ImagePolicy) ValidateImage(image, field string) FieldErrors {
+	if err := p.CheckImage(image); err != nil {
+		return ErrDisallowedFields(field, err.Error())
+	}
+	return FieldErrors{}
+}
+
+// ValidateRepository reports the image field when the image is not from an
+// allowed repository.
+func (p *ImagePolicy) ValidateRepository(image, field string) FieldErrors {
+	if err := p.CheckRepository(image); err != nil {
+		return ErrDisallowedFields(field, err.Error())
+	}
+	return FieldErrors{}
+}
+
+// ValidatePodSpecImages reports the image of each container and init
+// container that is not allowed. Containers without an image are skipped, as
+// for images resolved from a build.
+func (p *ImagePolicy) ValidatePodSpecImages(spec *corev1.PodSpec) FieldErrors {
+	errs := FieldErrors{}
+
+	for i, container := range spec.InitContainers {
+		if container.Image != "" {
+			errs = errs.Also(p.ValidateImage(container.Image, "image").ViaFieldIndex("initContainers", i))
+		}
+	}
+	for i, container := range spec.Containers {
+		if container.Image != "" {
+			errs = errs.Also(p.ValidateImage(container.Image, "image").ViaFieldIndex("containers", i))
+		}
+	}
+
+	return errs
+}
+
+var activeImagePolicy atomic.Value
+
+// LoadImagePolicy enforces the policy in the data of a ConfigMap, every image
+// is allowed for nil data.
+func LoadImagePolicy(data map[string]string) error {
+	p, err := NewImagePolicyFromData(data)
+	if err != nil {
+		return err
+	}
+	SetImagePolicy(p)
+	return nil
+}
+
+// ActiveImagePolicy is the policy enforced for workload images, every image
+// is allowed unless replaced. The managers load the policy before starting and
+// refuse to start with an invalid policy.
+func ActiveImagePolicy() *ImagePolicy {
+	if p, ok := activeImagePolicy.Load().(*ImagePolicy); ok {
+		return p
+	}
+	return &ImagePolicy{}
+}
+
+// SetImagePolicy replaces the policy enforced for workload images. A nil
+// policy allows every image.
+func SetImagePolicy(p *ImagePolicy) {
+	if p == nil {
+		p = &ImagePolicy{}
+	}
+	activeImagePolicy.Store(p)
+}
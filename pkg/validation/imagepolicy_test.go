@@ -0,0 +1,140 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/projectriff/system/pkg/validation"
+)
+
+func TestImagePolicy_CheckImage(t *testing.T) {
+	digest := "sha256:cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e"
+
+	for _, c := range []struct {
+		name     string
+		data     map[string]string
+		image    string
+		expected error
+	}{{
+		name:  "no policy",
+		image: "ubuntu",
+	}, {
+		name:  "allowed repository",
+		data:  map[string]string{"allowedImages": "gcr.io/my-project/app"},
+		image: "gcr.io/my-project/app:v1",
+	}, {
+		name:  "allowed repository pattern",
+		data:  map[string]string{"allowedImages": "registry.example.com/*,\ngcr.io/my-project/*"},
+		image: "gcr.io/my-project/app",
+	}, {
+		name:     "pattern does not match nested repositories",
+		data:     map[string]string{"allowedImages": "gcr.io/my-project/*"},
+		image:    "gcr.io/my-project/team/app",
+		expected: fmt.Errorf(`image "gcr.io/my-project/team/app" is not from an allowed repository`),
+	}, {
+		name:  "allowed repository prefix",
+		data:  map[string]string{"allowedImages": "gcr.io/my-project/**"},
+		image: "gcr.io/my-project/team/app",
+	}, {
+		name:  "docker hub",
+		data:  map[string]string{"allowedImages": "docker.io/library/*"},
+		image: "ubuntu:18.04",
+	}, {
+		name:     "disallowed repository",
+		data:     map[string]string{"allowedImages": "gcr.io/my-project/**"},
+		image:    "example.com/app",
+		expected: fmt.Errorf(`image "example.com/app" is not from an allowed repository`),
+	}, {
+		name:     "digest required",
+		data:     map[string]string{"requireDigest": "true"},
+		image:    "gcr.io/my-project/app:v1",
+		expected: fmt.Errorf(`image "gcr.io/my-project/app:v1" must be pinned by digest`),
+	}, {
+		name:  "pinned by digest",
+		data:  map[string]string{"requireDigest": "true"},
+		image: "gcr.io/my-project/app@" + digest,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			policy, err := validation.NewImagePolicyFromData(c.data)
+			if err != nil {
+				t.Fatalf("NewImagePolicyFromData() unexpected error: %v", err)
+			}
+			actual := policy.CheckImage(c.image)
+			if fmt.Sprintf("%v", c.expected) != fmt.Sprintf("%v", actual) {
+				t.Errorf("CheckImage() expected error %v, got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestImagePolicy_ValidatePodSpecImages(t *testing.T) {
+	policy, err := validation.NewImagePolicyFromData(map[string]string{
+		"allowedImages": "gcr.io/my-project/**",
+	})
+	if err != nil {
+		t.Fatalf("NewImagePolicyFromData() unexpected error: %v", err)
+	}
+
+	expected := validation.FieldErrors{}.Also(
+		validation.ErrDisallowedFields("initContainers[0].image", `image "busybox" is not from an allowed repository`),
+		validation.ErrDisallowedFields("containers[1].image", `image "envoyproxy/envoy" is not from an allowed repository`),
+	)
+	actual := policy.ValidatePodSpecImages(&corev1.PodSpec{
+		InitContainers: []corev1.Container{
+			{Image: "busybox"},
+		},
+		Containers: []corev1.Container{
+			{Image: ""},
+			{Image: "envoyproxy/envoy"},
+			{Image: "gcr.io/my-project/app"},
+		},
+	})
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("ValidatePodSpecImages() (-expected, +actual): %s", diff)
+	}
+}
+
+func TestNewImagePolicyFromData(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		data     map[string]string
+		expected error
+	}{{
+		name: "empty",
+		data: map[string]string{},
+	}, {
+		name:     "invalid pattern",
+		data:     map[string]string{"allowedImages": "gcr.io/[a"},
+		expected: fmt.Errorf(`invalid image pattern "gcr.io/[a": syntax error in pattern`),
+	}, {
+		name:     "invalid require digest",
+		data:     map[string]string{"requireDigest": "always"},
+		expected: fmt.Errorf(`invalid requireDigest value "always"`),
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := validation.NewImagePolicyFromData(c.data)
+			if fmt.Sprintf("%v", c.expected) != fmt.Sprintf("%v", err) {
+				t.Errorf("NewImagePolicyFromData() expected error %v, got %v", c.expected, err)
+			}
+		})
+	}
+}
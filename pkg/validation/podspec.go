@@ -0,0 +1,319 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// PodSpecAllowlistVersionKey selects the built-in allowlist a ConfigMap
+	// extends, defaults to the latest version.
+	PodSpecAllowlistVersionKey = "version"
+	// PodSpecAllowlistAllowKey lists additional fields to allow, separated by
+	// commas or new lines.
+	PodSpecAllowlistAllowKey = "allow"
+	// PodSpecAllowlistDenyKey lists fields to disallow, separated by commas or
+	// new lines. Denied fields take precedence over allowed fields.
+	PodSpecAllowlistDenyKey = "deny"
+
+	PodSpecAllowlistV1 = "v1"
+)
+
+// podSpecAllowlists are the built-in allowlists by version. A version must not
+// change once released, new fields are allowed by a new version. Fields that
+// grant access to the node, like privileged containers, host ports and
+// hostPath volumes, are not allowed.
+var podSpecAllowlists = map[string][]string{
+	PodSpecAllowlistV1: append(append(
+		podSpecContainerAllowlist("containers"),
+		podSpecContainerAllowlist("initContainers")...),
+		"affinity",
+		"imagePullSecrets",
+		"nodeSelector",
+		"priorityClassName",
+		"securityContext.fsGroup",
+		"securityContext.runAsGroup",
+		"securityContext.runAsNonRoot",
+		"securityContext.runAsUser",
+		"securityContext.supplementalGroups",
+		"serviceAccountName",
+		"tolerations",
+		"topologySpreadConstraints",
+		"volumes.configMap",
+		"volumes.downwardAPI",
+		"volumes.emptyDir",
+		"volumes.name",
+		"volumes.projected",
+		"volumes.secret",
+	),
+}
+
+// podSpecContainerAllowlist are the allowed fields of each container in the
+// field
+func podSpecContainerAllowlist(field string) []string {
+	allowlist := []string{
+		"args",
+		"command",
+		"env",
+		"envFrom",
+		"image",
+		"imagePullPolicy",
+		"lifecycle",
+		"livenessProbe",
+		"name",
+		"ports.containerPort",
+		"ports.name",
+		"ports.protocol",
+		"readinessProbe",
+		"resources",
+		"securityContext.allowPrivilegeEscalation",
+		"securityContext.capabilities.drop",
+		"securityContext.readOnlyRootFilesystem",
+		"securityContext.runAsGroup",
+		"securityContext.runAsNonRoot",
+		"securityContext.runAsUser",
+		"startupProbe",
+		"terminationMessagePath",
+		"terminationMessagePolicy",
+		"volumeMounts.mountPath",
+		"volumeMounts.name",
+		"volumeMounts.readOnly",
+		"volumeMounts.subPath",
+		"volumeMounts.subPathExpr",
+		"workingDir",
+	}
+	for i := range allowlist {
+		allowlist[i] = field + "." + allowlist[i]
+	}
+	return allowlist
+}
+
+// podSpecFieldPattern matches a field path like containers[0].securityContext
+var podSpecFieldPattern = regexp.MustCompile(`^[A-Za-z]+(\[([0-9]+|\*)\])?(\.[A-Za-z]+(\[([0-9]+|\*)\])?)*$`)
+
+// PodSpecAllowlist declares the PodSpec fields that may be set in the pod
+// template of a workload. Fields are JSON paths relative to the PodSpec, like
+// "containers[0].securityContext". Allowing a field allows all of its nested
+// fields. Array items are matched at any index unless an index is given.
+type PodSpecAllowlist struct {
+	Version string
+	Allow   []string
+	Deny    []string
+
+	allow [][]string
+	deny  [][]string
+}
+
+// NewPodSpecAllowlist returns the built-in allowlist for the version, extended
+// with additional allowed and denied fields.
+func NewPodSpecAllowlist(version string, allow, deny []string) (*PodSpecAllowlist, error) {
+	base, ok := podSpecAllowlists[version]
+	if !ok {
+		return nil, fmt.Errorf("unknown PodSpec allowlist version %q", version)
+	}
+	a := &PodSpecAllowlist{
+		Version: version,
+		Allow:   append(append([]string{}, base...), allow...),
+		Deny:    append([]string{}, deny...),
+	}
+	for _, f := range a.Allow {
+		if !podSpecFieldPattern.MatchString(f) {
+			return nil, fmt.Errorf("invalid PodSpec field %q", f)
+		}
+		a.allow = append(a.allow, podSpecFieldSegments(f))
+	}
+	for _, f := range a.Deny {
+		if !podSpecFieldPattern.MatchString(f) {
+			return nil, fmt.Errorf("invalid PodSpec field %q", f)
+		}
+		a.deny = append(a.deny, podSpecFieldSegments(f))
+	}
+	return a, nil
+}
+
+// NewPodSpecAllowlistFromData parses the allowlist from the data of a
+// ConfigMap.
+func NewPodSpecAllowlistFromData(data map[string]string) (*PodSpecAllowlist, error) {
+	version := data[PodSpecAllowlistVersionKey]
+	if version == "" {
+		version = PodSpecAllowlistV1
+	}
+	return NewPodSpecAllowlist(version, splitPodSpecFields(data[PodSpecAllowlistAllowKey]), splitPodSpecFields(data[PodSpecAllowlistDenyKey]))
+}
+
+func splitPodSpecFields(value string) []string {
+	fields := []string{}
+	for _, f := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// podSpecFieldSegments splits a field path into field names and indexes
+func podSpecFieldSegments(path string) []string {
+	segments := []string{}
+	for _, part := range strings.Split(path, ".") {
+		if i := strings.Index(part, "["); i != -1 {
+			segments = append(segments, part[:i], part[i:])
+		} else {
+			segments = append(segments, part)
+		}
+	}
+	return segments
+}
+
+// ValidatePodSpec reports each field set on the PodSpec that is not allowed.
+func (a *PodSpecAllowlist) ValidatePodSpec(spec *corev1.PodSpec) FieldErrors {
+	value, err := runtime.DefaultUnstructuredConverter.ToUnstructured(spec)
+	if err != nil {
+		return ErrInvalidValue(err.Error(), CurrentField)
+	}
+	return a.validateChildren([]string{}, value)
+}
+
+func (a *PodSpecAllowlist) validateChildren(path []string, value interface{}) FieldErrors {
+	errs := FieldErrors{}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			errs = errs.Also(a.validateField(append(path[:len(path):len(path)], key), v[key]))
+		}
+	case []interface{}:
+		for i, item := range v {
+			errs = errs.Also(a.validateField(append(path[:len(path):len(path)], fmt.Sprintf("[%d]", i)), item))
+		}
+	}
+
+	return errs
+}
+
+func (a *PodSpecAllowlist) validateField(path []string, value interface{}) FieldErrors {
+	allowed, partial := false, false
+	for _, entry := range a.deny {
+		if prefix, covered := matchPodSpecField(entry, path); covered {
+			return a.errDisallowed(path)
+		} else if prefix {
+			// a nested field is denied
+			partial = true
+		}
+	}
+	for _, entry := range a.allow {
+		prefix, covered := matchPodSpecField(entry, path)
+		allowed = allowed || covered
+		partial = partial || (prefix && !covered)
+	}
+	if allowed && !partial {
+		return FieldErrors{}
+	}
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		if allowed || partial {
+			return a.validateChildren(path, value)
+		}
+	default:
+		if allowed {
+			return FieldErrors{}
+		}
+	}
+	return a.errDisallowed(path)
+}
+
+func (a *PodSpecAllowlist) errDisallowed(path []string) FieldErrors {
+	name := ""
+	for _, segment := range path {
+		if name != "" && !strings.HasPrefix(segment, "[") {
+			name += "."
+		}
+		name += segment
+	}
+	return ErrDisallowedFields(name, fmt.Sprintf("not in the %s PodSpec allowlist", a.Version))
+}
+
+// matchPodSpecField compares an allowlist entry with the path of a field. The
+// path is a prefix when it leads to the entry, the path is covered when the
+// entry is the field or one of its parents.
+func matchPodSpecField(entry, path []string) (prefix bool, covered bool) {
+	i := 0
+	for _, segment := range path {
+		if i == len(entry) {
+			return false, true
+		}
+		if strings.HasPrefix(segment, "[") {
+			if strings.HasPrefix(entry[i], "[") {
+				if entry[i] != "[*]" && entry[i] != segment {
+					return false, false
+				}
+				i++
+			}
+			// without an index, any item matches
+			continue
+		}
+		if entry[i] != segment {
+			return false, false
+		}
+		i++
+	}
+	return i < len(entry), i == len(entry)
+}
+
+var activePodSpecAllowlist atomic.Value
+
+// LoadPodSpecAllowlist enforces the allowlist in the data of a ConfigMap, the
+// latest built-in version is enforced for nil data.
+func LoadPodSpecAllowlist(data map[string]string) error {
+	a, err := NewPodSpecAllowlistFromData(data)
+	if err != nil {
+		return err
+	}
+	SetPodSpecAllowlist(a)
+	return nil
+}
+
+// ActivePodSpecAllowlist is the allowlist enforced when validating pod
+// templates, the latest built-in version unless replaced.
+func ActivePodSpecAllowlist() *PodSpecAllowlist {
+	if a, ok := activePodSpecAllowlist.Load().(*PodSpecAllowlist); ok {
+		return a
+	}
+	a, _ := NewPodSpecAllowlist(PodSpecAllowlistV1, nil, nil)
+	return a
+}
+
+// SetPodSpecAllowlist replaces the allowlist enforced when validating pod
+// templates. A nil allowlist restores the latest built-in version.
+func SetPodSpecAllowlist(a *PodSpecAllowlist) {
+	if a == nil {
+		a, _ = NewPodSpecAllowlist(PodSpecAllowlistV1, nil, nil)
+	}
+	activePodSpecAllowlist.Store(a)
+}
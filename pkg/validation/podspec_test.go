@@ -0,0 +1,263 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/projectriff/system/pkg/validation"
+)
+
+func TestPodSpecAllowlist_ValidatePodSpec(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		data     map[string]string
+		spec     *corev1.PodSpec
+		expected validation.FieldErrors
+	}{{
+		name: "valid, scheduling and security",
+		spec: &corev1.PodSpec{
+			ServiceAccountName: "my-sa",
+			PriorityClassName:  "high",
+			NodeSelector:       map[string]string{"kubernetes.io/arch": "amd64"},
+			Tolerations: []corev1.Toleration{
+				{Key: "dedicated", Operator: corev1.TolerationOpExists},
+			},
+			SecurityContext: &corev1.PodSecurityContext{RunAsNonRoot: boolPtr(true)},
+			ImagePullSecrets: []corev1.LocalObjectReference{
+				{Name: "registry"},
+			},
+			InitContainers: []corev1.Container{
+				{Name: "init", Image: "busybox"},
+			},
+			Containers: []corev1.Container{
+				{Name: "handler", Image: "my-image"},
+				{Name: "sidecar", Image: "envoy"},
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "valid, container and volume fields",
+		spec: &corev1.PodSpec{
+			SecurityContext: &corev1.PodSecurityContext{FSGroup: int64Ptr(1000)},
+			Containers: []corev1.Container{
+				{
+					Name:  "handler",
+					Image: "my-image",
+					Env: []corev1.EnvVar{
+						{Name: "MODE", Value: "fast"},
+					},
+					Ports: []corev1.ContainerPort{
+						{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+					},
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: "config", MountPath: "/etc/config", ReadOnly: true},
+					},
+					SecurityContext: &corev1.SecurityContext{
+						ReadOnlyRootFilesystem: boolPtr(true),
+						Capabilities: &corev1.Capabilities{
+							Drop: []corev1.Capability{"ALL"},
+						},
+					},
+				},
+			},
+			Volumes: []corev1.Volume{
+				{
+					Name: "config",
+					VolumeSource: corev1.VolumeSource{
+						ConfigMap: &corev1.ConfigMapVolumeSource{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "my-config"},
+						},
+					},
+				},
+				{
+					Name: "scratch",
+					VolumeSource: corev1.VolumeSource{
+						EmptyDir: &corev1.EmptyDirVolumeSource{},
+					},
+				},
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid, privileged container",
+		spec: &corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  "handler",
+					Image: "my-image",
+					SecurityContext: &corev1.SecurityContext{
+						Privileged: boolPtr(true),
+						Capabilities: &corev1.Capabilities{
+							Add: []corev1.Capability{"SYS_ADMIN"},
+						},
+					},
+				},
+			},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrDisallowedFields("containers[0].securityContext.capabilities.add", "not in the v1 PodSpec allowlist"),
+			validation.ErrDisallowedFields("containers[0].securityContext.privileged", "not in the v1 PodSpec allowlist"),
+		),
+	}, {
+		name: "invalid, hostPath volume and host port",
+		spec: &corev1.PodSpec{
+			InitContainers: []corev1.Container{
+				{
+					Name:  "init",
+					Image: "busybox",
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: "host", MountPath: "/host", MountPropagation: mountPropagationPtr(corev1.MountPropagationBidirectional)},
+					},
+				},
+			},
+			Containers: []corev1.Container{
+				{
+					Name:  "handler",
+					Image: "my-image",
+					Ports: []corev1.ContainerPort{
+						{ContainerPort: 8080, HostPort: 80},
+					},
+				},
+			},
+			Volumes: []corev1.Volume{
+				{
+					Name: "host",
+					VolumeSource: corev1.VolumeSource{
+						HostPath: &corev1.HostPathVolumeSource{Path: "/"},
+					},
+				},
+			},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrDisallowedFields("containers[0].ports[0].hostPort", "not in the v1 PodSpec allowlist"),
+			validation.ErrDisallowedFields("initContainers[0].volumeMounts[0].mountPropagation", "not in the v1 PodSpec allowlist"),
+			validation.ErrDisallowedFields("volumes[0].hostPath", "not in the v1 PodSpec allowlist"),
+		),
+	}, {
+		name: "invalid, names each disallowed field",
+		spec: &corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "handler", Image: "my-image"},
+			},
+			HostNetwork:           true,
+			ShareProcessNamespace: boolPtr(true),
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrDisallowedFields("hostNetwork", "not in the v1 PodSpec allowlist"),
+			validation.ErrDisallowedFields("shareProcessNamespace", "not in the v1 PodSpec allowlist"),
+		),
+	}, {
+		name: "deny tightens nested fields",
+		data: map[string]string{
+			"deny": "initContainers,\ncontainers.securityContext",
+		},
+		spec: &corev1.PodSpec{
+			InitContainers: []corev1.Container{
+				{Name: "init", Image: "busybox"},
+			},
+			Containers: []corev1.Container{
+				{Name: "handler", Image: "my-image"},
+				{Name: "sidecar", Image: "envoy", SecurityContext: &corev1.SecurityContext{Privileged: boolPtr(true)}},
+			},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrDisallowedFields("containers[1].securityContext", "not in the v1 PodSpec allowlist"),
+			validation.ErrDisallowedFields("initContainers", "not in the v1 PodSpec allowlist"),
+		),
+	}, {
+		name: "deny a single container",
+		data: map[string]string{
+			"deny": "containers[1]",
+		},
+		spec: &corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "handler", Image: "my-image"},
+				{Name: "sidecar", Image: "envoy"},
+			},
+		},
+		expected: validation.ErrDisallowedFields("containers[1]", "not in the v1 PodSpec allowlist"),
+	}, {
+		name: "allow loosens",
+		data: map[string]string{
+			"version": "v1",
+			"allow":   "hostAliases",
+		},
+		spec: &corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "handler", Image: "my-image"},
+			},
+			HostAliases: []corev1.HostAlias{
+				{IP: "127.0.0.1", Hostnames: []string{"local"}},
+			},
+		},
+		expected: validation.FieldErrors{},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			allowlist, err := validation.NewPodSpecAllowlistFromData(c.data)
+			if err != nil {
+				t.Fatalf("NewPodSpecAllowlistFromData() unexpected error: %v", err)
+			}
+			actual := allowlist.ValidatePodSpec(c.spec)
+			if diff := cmp.Diff(c.expected, actual); diff != "" {
+				t.Errorf("ValidatePodSpec() (-expected, +actual): %s", diff)
+			}
+		})
+	}
+}
+
+func TestNewPodSpecAllowlistFromData(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		data     map[string]string
+		expected error
+	}{{
+		name: "default version",
+		data: map[string]string{},
+	}, {
+		name:     "unknown version",
+		data:     map[string]string{"version": "v0"},
+		expected: fmt.Errorf(`unknown PodSpec allowlist version "v0"`),
+	}, {
+		name:     "invalid field",
+		data:     map[string]string{"allow": "containers[x]"},
+		expected: fmt.Errorf(`invalid PodSpec field "containers[x]"`),
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := validation.NewPodSpecAllowlistFromData(c.data)
+			if fmt.Sprintf("%v", c.expected) != fmt.Sprintf("%v", err) {
+				t.Errorf("NewPodSpecAllowlistFromData() expected error %v, got %v", c.expected, err)
+			}
+		})
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+func mountPropagationPtr(m corev1.MountPropagationMode) *corev1.MountPropagationMode {
+	return &m
+}